@@ -8,13 +8,16 @@ import (
 
 func cacheHeadersFrom(hit bool, entry cache.Entry[any]) CacheHeaders {
 	status := "MISS"
-	if hit {
+	switch {
+	case entry.Stale:
+		status = "STALE"
+	case hit:
 		status = "HIT"
 	}
 	return CacheHeaders{
 		Status:    status,
 		CachedAt:  entry.CachedAt,
 		ExpiresAt: entry.Expiry,
-		TTL:       int(time.Until(entry.Expiry).Seconds()),
+		TTL:       max(0, int(time.Until(entry.Expiry).Seconds())),
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -11,25 +12,19 @@ import (
 )
 
 func (s *Server) handleAthenaCatalogs(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("athena-catalogs", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListAthenaCatalogs(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "athena-catalogs")
 }
 
 func (s *Server) handleAthenaDatabases(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	catalog := r.URL.Query().Get("catalog")
-	s.serveCached(w, r, cacheKey("athena-databases", profile, region, catalog), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListAthenaDatabases(r.Context(), profile, region, catalog)
+	s.serveCached(w, r, cacheKey("athena-databases", profile, region, catalog), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListAthenaDatabases(ctx, profile, region, catalog)
 	})
 }
 
 func (s *Server) handleAthenaWorkgroups(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("athena-workgroups", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListAthenaWorkgroups(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "athena-workgroups")
 }
 
 func (s *Server) handleAthenaTables(w http.ResponseWriter, r *http.Request) {
@@ -40,8 +35,8 @@ func (s *Server) handleAthenaTables(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "database query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("athena-tables", profile, region, catalog, database), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListAthenaTables(r.Context(), profile, region, catalog, database)
+	s.serveCached(w, r, cacheKey("athena-tables", profile, region, catalog, database), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListAthenaTables(ctx, profile, region, catalog, database)
 	})
 }
 
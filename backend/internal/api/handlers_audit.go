@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// tagAuditCSVHeader は GET /api/audit/tags?format=csv のヘッダ行。audit.TagViolation.ToRow の列順に合わせる。
+var tagAuditCSVHeader = []string{"service", "account_id", "profile", "region", "name", "id", "missing", "disallowed"}
+
+// securityAuditCSVHeader は GET /api/audit/security?format=csv のヘッダ行。audit.SecurityFinding.ToRow の列順に合わせる。
+var securityAuditCSVHeader = []string{"severity", "check", "account_id", "profile", "region", "name", "id", "detail", "remediation"}
+
+// TagAuditResponse は GET /api/audit/tags のレスポンス。Resources は監査したリソースの数、Groups は
+// サービスとアカウントごとの違反、Errors は一覧を取得できなかったサービスと profile/region の組。
+type TagAuditResponse struct {
+	Policy    audit.TagPolicy           `json:"policy"`
+	Resources int                       `json:"resources"`
+	Groups    []audit.TagViolationGroup `json:"groups"`
+	Errors    []audit.Error             `json:"errors"`
+}
+
+// handleTagAudit は必須タグ (?require=owner,env) と許可値 (?allow=env=prod|stg、繰り返し指定可) のポリシーに
+// 違反したリソースを返す。対象は ?services= (省略時は audit.TagServices のすべて) と ?profiles= / ?regions=
+// (省略時は既定のプロファイル・リージョン) の組で、各一覧は単一の一覧と同じキーで resourceCache を通す。
+// ?format=csv では違反したリソースを CSV ファイルとして返す (取得できなかった組は writeAuditCSV が末尾に書く)。
+func (s *Server) handleTagAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeBadRequest(w, "format must be json or csv")
+		return
+	}
+	policy, err := audit.ParseTagPolicy(awsinternal.SplitFanoutList(q.Get("require")), q["allow"])
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	services := audit.TagServices
+	if v := awsinternal.SplitFanoutList(q.Get("services")); len(v) > 0 {
+		for _, service := range v {
+			if !slices.Contains(audit.TagServices, service) {
+				writeBadRequest(w, fmt.Sprintf("unknown service %q (available: %s)", service, strings.Join(audit.TagServices, ", ")))
+				return
+			}
+		}
+		services = v
+	}
+	targets, resolveErrs, ok := s.resolveFanoutRequest(w, r, s.cfg.Profile, s.cfg.Region)
+	if !ok {
+		return
+	}
+
+	resources, errs := audit.Collect(r.Context(), targets, services, s.cachedList(s.refresh(r)))
+	errs = appendResolveErrors(errs, resolveErrs)
+	violations := audit.AuditTags(resources, policy)
+
+	if format == "csv" {
+		writeAuditCSV(w, "tag-audit.csv", tagAuditCSVHeader, violations, errs)
+		return
+	}
+	if errs == nil {
+		errs = []audit.Error{}
+	}
+	writeJSON(w, TagAuditResponse{
+		Policy:    policy,
+		Resources: len(resources),
+		Groups:    audit.GroupTagViolations(violations),
+		Errors:    errs,
+	})
+}
+
+// SecurityAuditResponse は GET /api/audit/security のレスポンス。Checks は実行したチェック、Findings は該当した
+// リソース (重要度の高い順)、Counts は重要度ごとの件数、Errors は一覧を取得できなかったサービスと profile/region の組。
+type SecurityAuditResponse struct {
+	Checks   []audit.SecurityCheck   `json:"checks"`
+	Findings []audit.SecurityFinding `json:"findings"`
+	Counts   map[audit.Severity]int  `json:"counts"`
+	Errors   []audit.Error           `json:"errors"`
+}
+
+// handleSecurityAudit は audit.SecurityChecks のうち ?checks= (省略時はすべて) で選んだ、重要度が ?severity=
+// (省略時は low) 以上のチェックを実行し、該当したリソースを重要度と対処方法とともに返す。対象は ?profiles= / ?regions=
+// (省略時は既定のプロファイル・リージョン) の組で、?format=csv では CSV ファイルとして返す (取得できなかった組は
+// writeAuditCSV が末尾に書く)。
+func (s *Server) handleSecurityAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeBadRequest(w, "format must be json or csv")
+		return
+	}
+	minSeverity := audit.SeverityLow
+	if v := q.Get("severity"); v != "" {
+		var err error
+		if minSeverity, err = audit.ParseSeverity(v); err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+	}
+	checks, err := audit.SelectSecurityChecks(audit.SecurityChecks, awsinternal.SplitFanoutList(q.Get("checks")), minSeverity)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	targets, resolveErrs, ok := s.resolveFanoutRequest(w, r, s.cfg.Profile, s.cfg.Region)
+	if !ok {
+		return
+	}
+
+	findings, errs := audit.CollectSecurity(r.Context(), targets, checks, s.cachedList(s.refresh(r)), time.Now())
+	errs = appendResolveErrors(errs, resolveErrs)
+
+	if format == "csv" {
+		writeAuditCSV(w, "security-audit.csv", securityAuditCSVHeader, findings, errs)
+		return
+	}
+	if checks == nil {
+		checks = []audit.SecurityCheck{}
+	}
+	if errs == nil {
+		errs = []audit.Error{}
+	}
+	writeJSON(w, SecurityAuditResponse{
+		Checks:   checks,
+		Findings: findings,
+		Counts:   audit.CountBySeverity(findings),
+		Errors:   errs,
+	})
+}
+
+// cachedList は監査の一覧の取得関数。単一の一覧と同じキーで resourceCache を通すため、一覧の API や
+// プリウォームで取得済みの一覧はそのまま使う。
+func (s *Server) cachedList(refresh bool) audit.ListFunc {
+	return func(ctx context.Context, service, profile, region string) (any, error) {
+		entry, _, err := s.resourceCache.Load(ctx, cacheKey(service, profile, region), cacheTTL, refresh, func(ctx context.Context) (any, error) {
+			return regionalResources[service](s, ctx, profile, region)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return entry.Value, nil
+	}
+}
+
+// appendResolveErrors はアカウント ID を解決できなかった profile/region を監査のエラーに加える。
+func appendResolveErrors(errs []audit.Error, resolveErrs []awsinternal.FanoutError) []audit.Error {
+	for _, e := range resolveErrs {
+		errs = append(errs, audit.Error{Profile: e.Profile, Region: e.Region, Error: e.Error})
+	}
+	return errs
+}
+
+// writeAuditCSV は監査の結果を filename の CSV ファイルとして 1 行ずつ書き出す。一覧を取得できなかった組 (errs) が
+// あれば、X-Audit-Errors ヘッダにその件数を入れ、末尾に "error" で始まる行 (サービス、profile、region、エラー) として
+// 書く。取得できなかった組の結果が欠けた CSV を、完全な CSV と区別できるようにするため。
+func writeAuditCSV[T interface{ ToRow() []string }](w http.ResponseWriter, filename string, header []string, rows []T, errs []audit.Error) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if len(errs) > 0 {
+		w.Header().Set("X-Audit-Errors", strconv.Itoa(len(errs)))
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	for _, row := range rows {
+		_ = cw.Write(row.ToRow())
+	}
+	for _, e := range errs {
+		record := make([]string, max(len(header), 5))
+		copy(record, []string{"error", e.Service, e.Profile, e.Region, e.Error})
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// TestHandleTagAudit はタグ監査の JSON / CSV 応答を検証する。アカウント ID は ~/.aws/config の sso_account_id から
+// 解決させるため HOME を差し替え、regionalResources の ec2 / waf を差し替える (このため t.Parallel とは併用できない)。
+func TestHandleTagAudit(t *testing.T) {
+	home := t.TempDir()
+	config := "[profile prod]\nsso_account_id = 111111111111\n\n[profile dev]\nsso_account_id = 222222222222\n"
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	type row struct {
+		ID   string            `json:"id"`
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+	for service, load := range map[string]regionalLoader{
+		"ec2": func(_ *Server, _ context.Context, profile, _ string) (any, error) {
+			return []row{
+				{ID: "i-1", Name: profile + "-web", Tags: map[string]string{"owner": "a", "env": "prod"}},
+				{ID: "i-2", Name: profile + "-batch", Tags: map[string]string{"env": "qa"}},
+			}, nil
+		},
+		"waf": func(_ *Server, _ context.Context, profile, _ string) (any, error) {
+			if profile == "dev" {
+				return nil, errors.New("access denied")
+			}
+			return []row{{ID: "w-1", Name: "acl"}}, nil
+		},
+	} {
+		orig := regionalResources[service]
+		regionalResources[service] = load
+		t.Cleanup(func() { regionalResources[service] = orig })
+	}
+
+	do := func(t *testing.T, url string) *httptest.ResponseRecorder {
+		t.Helper()
+		s := newTestServer(t)
+		w := httptest.NewRecorder()
+		s.handleTagAudit(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+	const query = "?require=owner&allow=env=prod|stg&services=ec2,waf&profiles=prod,dev&regions=ap-northeast-1"
+
+	t.Run("json groups by service and account", func(t *testing.T) {
+		w := do(t, "/api/audit/tags"+query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+		}
+		var body TagAuditResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		if body.Resources != 5 {
+			t.Errorf("resources = %d, want 5", body.Resources)
+		}
+		type group struct {
+			service, account string
+			count            int
+		}
+		var got []group
+		for _, g := range body.Groups {
+			got = append(got, group{g.Service, g.AccountID, g.Count})
+		}
+		want := []group{{"ec2", "111111111111", 1}, {"ec2", "222222222222", 1}, {"waf", "111111111111", 1}}
+		if len(got) != len(want) {
+			t.Fatalf("groups = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("groups[%d] = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+		if v := body.Groups[0].Violations[0]; v.Name != "prod-batch" || len(v.Missing) != 1 || v.Disallowed["env"] != "qa" {
+			t.Errorf("violation = %+v, want prod-batch missing owner with env=qa", v)
+		}
+		if len(body.Errors) != 1 || body.Errors[0].Service != "waf" || body.Errors[0].Profile != "dev" {
+			t.Errorf("errors = %+v, want the dev waf failure", body.Errors)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := do(t, "/api/audit/tags"+query+"&format=csv")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+			t.Errorf("Content-Type = %q, want text/csv", got)
+		}
+		want := "service,account_id,profile,region,name,id,missing,disallowed\n" +
+			"ec2,111111111111,prod,ap-northeast-1,prod-batch,i-2,owner,env=qa\n" +
+			"ec2,222222222222,dev,ap-northeast-1,dev-batch,i-2,owner,env=qa\n" +
+			"waf,111111111111,prod,ap-northeast-1,acl,w-1,owner,\n" +
+			"error,waf,dev,ap-northeast-1,access denied,,,\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+		if got := w.Header().Get("X-Audit-Errors"); got != "1" {
+			t.Errorf("X-Audit-Errors = %q, want 1 for the failed dev waf list", got)
+		}
+	})
+
+	for name, url := range map[string]string{
+		"empty policy":    "/api/audit/tags",
+		"invalid allow":   "/api/audit/tags?allow=env",
+		"unknown service": "/api/audit/tags?require=owner&services=s3",
+		"unknown format":  "/api/audit/tags?require=owner&format=xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if w := do(t, url); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestHandleSecurityAudit はセキュリティチェックの JSON / CSV 応答を検証する。TestHandleTagAudit と同じく HOME と
+// regionalResources の s3 / iam / elb-security を差し替える。
+func TestHandleSecurityAudit(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte("[profile prod]\nsso_account_id = 111111111111\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	for service, load := range map[string]regionalLoader{
+		"s3": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return nil, errors.New("access denied")
+		},
+		"iam": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []awsinternal.IAMResource{{Name: "bob", ARN: "arn:user/bob", Kind: "user", LastActivity: time.Now().Format(time.RFC3339)}}, nil
+		},
+		"elb-security": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []awsinternal.ELBSecurityResource{{
+				ELBResource: awsinternal.ELBResource{ID: "arn:web", Name: "web", Type: "application", Scheme: "internet-facing"},
+				Listeners:   []awsinternal.ELBListenerResource{{Protocol: "HTTP", Port: 80, DefaultActionType: "forward"}},
+			}}, nil
+		},
+	} {
+		orig := regionalResources[service]
+		regionalResources[service] = load
+		t.Cleanup(func() { regionalResources[service] = orig })
+	}
+
+	do := func(t *testing.T, url string) *httptest.ResponseRecorder {
+		t.Helper()
+		s := newTestServer(t)
+		w := httptest.NewRecorder()
+		s.handleSecurityAudit(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+	const query = "?profiles=prod&regions=ap-northeast-1"
+
+	t.Run("json", func(t *testing.T) {
+		w := do(t, "/api/audit/security"+query+"&severity=medium")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+		}
+		var body SecurityAuditResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		var got []string
+		for _, f := range body.Findings {
+			got = append(got, string(f.Severity)+"/"+f.Check+"/"+f.Name)
+		}
+		// elb-no-waf (low) は severity=medium で除かれる
+		if want := []string{"high/iam-user-no-mfa/bob", "medium/elb-http-listener/web"}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("findings = %v, want %v", got, want)
+		}
+		if body.Counts[audit.SeverityHigh] != 1 || body.Counts[audit.SeverityLow] != 0 {
+			t.Errorf("counts = %v, want high 1 and low 0", body.Counts)
+		}
+		if len(body.Checks) != 5 {
+			t.Errorf("checks = %d, want the 5 checks of medium or higher", len(body.Checks))
+		}
+		if len(body.Errors) != 1 || body.Errors[0].Service != "s3" {
+			t.Errorf("errors = %+v, want the s3 failure", body.Errors)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := do(t, "/api/audit/security"+query+"&checks=elb-no-waf&format=csv")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="security-audit.csv"` {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+		want := strings.Join(securityAuditCSVHeader, ",") + "\n" +
+			"low,elb-no-waf,111111111111,prod,ap-northeast-1,web,arn:web,no web ACL associated," + audit.SecurityChecks[5].Remediation + "\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+		if got := w.Header().Get("X-Audit-Errors"); got != "" {
+			t.Errorf("X-Audit-Errors = %q, want none when every list succeeded", got)
+		}
+	})
+
+	t.Run("csv reports failed lists", func(t *testing.T) {
+		w := do(t, "/api/audit/security"+query+"&checks=s3-public-bucket&format=csv")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		want := strings.Join(securityAuditCSVHeader, ",") + "\n" +
+			"error,s3,prod,ap-northeast-1,access denied,,,,\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+		if got := w.Header().Get("X-Audit-Errors"); got != "1" {
+			t.Errorf("X-Audit-Errors = %q, want 1 for the failed s3 list", got)
+		}
+	})
+
+	for name, url := range map[string]string{
+		"unknown check":    "/api/audit/security?checks=ec2-open-ssh",
+		"unknown severity": "/api/audit/security?severity=critical",
+		"unknown format":   "/api/audit/security?format=xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if w := do(t, url); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
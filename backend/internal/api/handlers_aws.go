@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -22,6 +23,62 @@ func (s *Server) refresh(r *http.Request) bool {
 	return r.URL.Query().Get("refresh") == "true"
 }
 
+// regionalLoader は profile と region だけで決まるリソース一覧を取得する。
+type regionalLoader func(s *Server, ctx context.Context, profile, region string) (any, error)
+
+// regional は awsinternal の一覧関数を regionalLoader に変換する。
+func regional[T any](list func(context.Context, string, string) (T, error)) regionalLoader {
+	return func(_ *Server, ctx context.Context, profile, region string) (any, error) {
+		return list(ctx, profile, region)
+	}
+}
+
+// regionalResources はキャッシュキー cacheKey(service, profile, region) で保持するリソース一覧の取得関数。
+// ハンドラ (serveRegional)、プリウォーム (runPrewarm) と監査 (handleTagAudit / handleWaste / handleSecurityAudit) が
+// 共有し、同じキーに同じ値を書き込む。ecr-images (全リポジトリのイメージ) と elb-security (リスナーと WAF の関連付けを
+// 含むロードバランサー) は一覧の API がなく、監査でのみ使う。
+var regionalResources = map[string]regionalLoader{
+	"ec2":                 (*Server).loadEC2,
+	"rds":                 (*Server).loadRDS,
+	"elasticache":         (*Server).loadElastiCache,
+	"lambda":              regional(awsinternal.ListLambdaResources),
+	"ecs":                 regional(awsinternal.ListECSResources),
+	"ecr":                 regional(awsinternal.ListECRResources),
+	"ecr-images":          (*Server).loadECRImages,
+	"elb-security":        regional(awsinternal.ListELBSecurityResources),
+	"s3":                  regional(awsinternal.ListS3Resources),
+	"iam":                 regional(awsinternal.ListIAMResources),
+	"sso":                 regional(awsinternal.ListSSOAccounts),
+	"ssm-list":            regional(awsinternal.ListSSMParameters),
+	"secretsmanager-list": regional(awsinternal.ListSecretResources),
+	"cfn":                 regional(awsinternal.ListCFNStacks),
+	"kinesis":             (*Server).loadKinesis,
+	"cloudfront":          regional(awsinternal.ListCloudFrontResources),
+	"elb":                 regional(awsinternal.ListELBResources),
+	"dynamo":              regional(awsinternal.ListDynamoResources),
+	"apigw":               regional(awsinternal.ListAPIGatewayResources),
+	"natgw":               (*Server).loadNATGateways,
+	"sqs":                 regional(awsinternal.ListSQSResources),
+	"waf":                 (*Server).loadWAF,
+	"athena-catalogs":     regional(awsinternal.ListAthenaCatalogs),
+	"athena-workgroups":   regional(awsinternal.ListAthenaWorkgroups),
+	"cwlogs-groups":       (*Server).loadLogGroups,
+}
+
+// serveRegional は regionalResources[service] の一覧をキャッシュ経由で返す。
+// ?profiles= / ?regions= が指定された場合は複数の profile/region にわたる fan-out モード (serveRegionalFanout) になる。
+func (s *Server) serveRegional(w http.ResponseWriter, r *http.Request, service string) {
+	if isFanoutRequest(r) {
+		s.serveRegionalFanout(w, r, service)
+		return
+	}
+	profile, region := s.profileAndRegion(r)
+	load := regionalResources[service]
+	s.serveCached(w, r, cacheKey(service, profile, region), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return load(s, ctx, profile, region)
+	})
+}
+
 func (s *Server) handleListProfiles(w http.ResponseWriter, r *http.Request) {
 	profiles, err := awsinternal.ListProfiles()
 	if err != nil {
@@ -71,17 +128,29 @@ func (s *Server) handleProfileIdentity(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleEC2(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("ec2", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListEC2Resources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "ec2")
 }
 
 func (s *Server) handleRDS(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("rds", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListRDSResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "rds")
+}
+
+func (s *Server) loadEC2(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListEC2Resources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyEC2CostEstimates(resources, s.priceTableForEstimate(profile, region, "ec2"))
+	return resources, nil
+}
+
+func (s *Server) loadRDS(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListRDSResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyRDSCostEstimates(resources, s.priceTableForEstimate(profile, region, "rds"))
+	return resources, nil
 }
 
 func (s *Server) handleRDSParameters(w http.ResponseWriter, r *http.Request) {
@@ -91,8 +160,8 @@ func (s *Server) handleRDSParameters(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "group query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("rds-parameters", profile, region, group), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListRDSParameters(r.Context(), profile, region, group)
+	s.serveCached(w, r, cacheKey("rds-parameters", profile, region, group), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListRDSParameters(ctx, profile, region, group)
 	})
 }
 
@@ -103,16 +172,22 @@ func (s *Server) handleRDSClusterParameters(w http.ResponseWriter, r *http.Reque
 		writeBadRequest(w, "cluster query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("rds-cluster-parameters", profile, region, cluster), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListRDSClusterParameters(r.Context(), profile, region, cluster)
+	s.serveCached(w, r, cacheKey("rds-cluster-parameters", profile, region, cluster), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListRDSClusterParameters(ctx, profile, region, cluster)
 	})
 }
 
 func (s *Server) handleElastiCache(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("elasticache", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListElastiCacheResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "elasticache")
+}
+
+func (s *Server) loadElastiCache(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListElastiCacheResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyElastiCacheCostEstimates(resources, s.priceTableForEstimate(profile, region, "elasticache"))
+	return resources, nil
 }
 
 func (s *Server) handleElastiCacheParameters(w http.ResponseWriter, r *http.Request) {
@@ -122,30 +197,24 @@ func (s *Server) handleElastiCacheParameters(w http.ResponseWriter, r *http.Requ
 		writeBadRequest(w, "group query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("elasticache-parameters", profile, region, group), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListElastiCacheParameters(r.Context(), profile, region, group)
+	s.serveCached(w, r, cacheKey("elasticache-parameters", profile, region, group), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListElastiCacheParameters(ctx, profile, region, group)
 	})
 }
 
 func (s *Server) handleLambda(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("lambda", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListLambdaResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "lambda")
 }
 
 func (s *Server) handleECS(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("ecs", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECSResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "ecs")
 }
 
 func (s *Server) handleECSServices(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	cluster := r.PathValue("cluster")
-	s.serveCached(w, r, cacheKey("ecs-services", profile, region, cluster), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECSServices(r.Context(), profile, region, cluster)
+	s.serveCached(w, r, cacheKey("ecs-services", profile, region, cluster), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListECSServices(ctx, profile, region, cluster)
 	})
 }
 
@@ -153,8 +222,8 @@ func (s *Server) handleECSTasks(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	cluster := r.PathValue("cluster")
 	service := r.URL.Query().Get("service")
-	s.serveCached(w, r, cacheKey("ecs-tasks", profile, region, cluster, service), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECSTasks(r.Context(), profile, region, cluster, service)
+	s.serveCached(w, r, cacheKey("ecs-tasks", profile, region, cluster, service), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListECSTasks(ctx, profile, region, cluster, service)
 	})
 }
 
@@ -162,52 +231,51 @@ func (s *Server) handleECSContainers(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	cluster := r.PathValue("cluster")
 	task := r.PathValue("task")
-	s.serveCached(w, r, cacheKey("ecs-containers", profile, region, cluster, task), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECSContainers(r.Context(), profile, region, cluster, task)
+	s.serveCached(w, r, cacheKey("ecs-containers", profile, region, cluster, task), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListECSContainers(ctx, profile, region, cluster, task)
 	})
 }
 
 func (s *Server) handleECR(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("ecr", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECRResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "ecr")
 }
 
 func (s *Server) handleECRImages(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	repo := r.PathValue("repo")
-	s.serveCached(w, r, cacheKey("ecr-images", profile, region, repo), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListECRImages(r.Context(), profile, region, repo)
+	s.serveCached(w, r, cacheKey("ecr-images", profile, region, repo), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		images, err := awsinternal.ListECRImages(ctx, profile, region, repo)
+		if err != nil {
+			return nil, err
+		}
+		awsinternal.ApplyECRImageCostEstimates(images, s.priceTableForEstimate(profile, region, "ecr"))
+		return images, nil
 	})
 }
 
+func (s *Server) loadECRImages(ctx context.Context, profile, region string) (any, error) {
+	images, err := awsinternal.ListAllECRImages(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyECRImageCostEstimates(images, s.priceTableForEstimate(profile, region, "ecr"))
+	return images, nil
+}
+
 func (s *Server) handleS3(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("s3", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListS3Resources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "s3")
 }
 
 func (s *Server) handleIAM(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("iam", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListIAMResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "iam")
 }
 
 func (s *Server) handleSSO(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("sso", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListSSOAccounts(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "sso")
 }
 
 func (s *Server) handleSSMList(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("ssm-list", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListSSMParameters(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "ssm-list")
 }
 
 func (s *Server) handleSSMGet(w http.ResponseWriter, r *http.Request) {
@@ -224,24 +292,18 @@ func (s *Server) handleSSMGet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleSecretsList(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("secretsmanager-list", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListSecretResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "secretsmanager-list")
 }
 
 func (s *Server) handleCFN(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("cfn", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListCFNStacks(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "cfn")
 }
 
 func (s *Server) handleCFNStackDetail(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	stack := r.PathValue("stack")
-	s.serveCached(w, r, cacheKey("cfn-detail", profile, region, stack), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.DescribeCFNStackDetail(r.Context(), profile, region, stack)
+	s.serveCached(w, r, cacheKey("cfn-detail", profile, region, stack), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.DescribeCFNStackDetail(ctx, profile, region, stack)
 	})
 }
 
@@ -251,31 +313,34 @@ const cfnEventsCacheTTL = 30 * time.Second
 func (s *Server) handleCFNStackEvents(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	stack := r.PathValue("stack")
-	s.serveCached(w, r, cacheKey("cfn-events", profile, region, stack), cfnEventsCacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListCFNStackEvents(r.Context(), profile, region, stack)
+	s.serveCached(w, r, cacheKey("cfn-events", profile, region, stack), cfnEventsCacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListCFNStackEvents(ctx, profile, region, stack)
 	})
 }
 
 func (s *Server) handleCFNStackResources(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	stack := r.PathValue("stack")
-	s.serveCached(w, r, cacheKey("cfn-resources", profile, region, stack), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListCFNStackResources(r.Context(), profile, region, stack)
+	s.serveCached(w, r, cacheKey("cfn-resources", profile, region, stack), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListCFNStackResources(ctx, profile, region, stack)
 	})
 }
 
 func (s *Server) handleKinesis(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("kinesis", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListKinesisResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "kinesis")
+}
+
+func (s *Server) loadKinesis(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListKinesisResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyKinesisCostEstimates(resources, s.priceTableForEstimate(profile, region, "kinesis"))
+	return resources, nil
 }
 
 func (s *Server) handleCloudFront(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("cloudfront", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListCloudFrontResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "cloudfront")
 }
 
 func (s *Server) handleCloudFrontInvalidation(w http.ResponseWriter, r *http.Request) {
@@ -297,10 +362,7 @@ func (s *Server) handleCloudFrontInvalidation(w http.ResponseWriter, r *http.Req
 }
 
 func (s *Server) handleELB(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("elb", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListELBResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "elb")
 }
 
 func (s *Server) handleELBListeners(w http.ResponseWriter, r *http.Request) {
@@ -310,8 +372,8 @@ func (s *Server) handleELBListeners(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "lb_arn query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("elb-listeners", profile, region, lbArn), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListELBListeners(r.Context(), profile, region, lbArn)
+	s.serveCached(w, r, cacheKey("elb-listeners", profile, region, lbArn), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListELBListeners(ctx, profile, region, lbArn)
 	})
 }
 
@@ -322,8 +384,8 @@ func (s *Server) handleELBRules(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "listener_arn query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("elb-rules", profile, region, listenerArn), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListELBRules(r.Context(), profile, region, listenerArn)
+	s.serveCached(w, r, cacheKey("elb-rules", profile, region, listenerArn), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListELBRules(ctx, profile, region, listenerArn)
 	})
 }
 
@@ -334,8 +396,8 @@ func (s *Server) handleELBTargetGroups(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "lb_arn query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("elb-target-groups", profile, region, lbArn), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListELBTargetGroups(r.Context(), profile, region, lbArn)
+	s.serveCached(w, r, cacheKey("elb-target-groups", profile, region, lbArn), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListELBTargetGroups(ctx, profile, region, lbArn)
 	})
 }
 
@@ -346,44 +408,47 @@ func (s *Server) handleELBTargetHealth(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "tg_arn query parameter is required")
 		return
 	}
-	s.serveCached(w, r, cacheKey("elb-target-health", profile, region, tgArn), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.DescribeELBTargetHealth(r.Context(), profile, region, tgArn)
+	s.serveCached(w, r, cacheKey("elb-target-health", profile, region, tgArn), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.DescribeELBTargetHealth(ctx, profile, region, tgArn)
 	})
 }
 
 func (s *Server) handleDynamo(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("dynamo", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListDynamoResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "dynamo")
 }
 
 func (s *Server) handleAPIGW(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("apigw", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListAPIGatewayResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "apigw")
 }
 
 func (s *Server) handleNATGW(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("natgw", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListNATGatewayResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "natgw")
+}
+
+func (s *Server) loadNATGateways(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListNATGatewayResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyNATGatewayCostEstimates(resources, s.priceTableForEstimate(profile, region, "natgw"))
+	return resources, nil
 }
 
 func (s *Server) handleSQS(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("sqs", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListSQSResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "sqs")
 }
 
 func (s *Server) handleWAF(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("waf", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListWAFResources(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "waf")
+}
+
+func (s *Server) loadWAF(ctx context.Context, profile, region string) (any, error) {
+	resources, err := awsinternal.ListWAFResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyWAFCostEstimates(resources, s.priceTableForEstimate(profile, region, "waf"))
+	return resources, nil
 }
 
 func writeJSON(w http.ResponseWriter, v any) {
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -13,41 +15,35 @@ import (
 
 func (s *Server) handleBQDatasets(w http.ResponseWriter, r *http.Request) {
 	projectID := r.URL.Query().Get("project_id")
-	client, cleanup, ok := s.bqClientFromQuery(w, r, projectID)
-	if !ok {
+	if !s.bqAvailable(w, projectID) {
 		return
 	}
-	defer cleanup()
-	s.serveCached(w, r, cacheKey("bq-datasets", projectID), cacheTTL, writeInternalFromError, func() (any, error) {
-		return client.ListDatasets(r.Context())
-	})
+	s.serveCached(w, r, cacheKey("bq-datasets", projectID), cacheTTL, writeInternalFromError, s.bqLoader(projectID, func(ctx context.Context, client *bigquery.Client) (any, error) {
+		return client.ListDatasets(ctx)
+	}))
 }
 
 func (s *Server) handleBQTables(w http.ResponseWriter, r *http.Request) {
 	projectID := r.URL.Query().Get("project_id")
 	dataset := r.PathValue("dataset")
-	client, cleanup, ok := s.bqClientFromQuery(w, r, projectID)
-	if !ok {
+	if !s.bqAvailable(w, projectID) {
 		return
 	}
-	defer cleanup()
-	s.serveCached(w, r, cacheKey("bq-tables", projectID, dataset), cacheTTL, writeInternalFromError, func() (any, error) {
-		return client.ListTables(r.Context(), dataset)
-	})
+	s.serveCached(w, r, cacheKey("bq-tables", projectID, dataset), cacheTTL, writeInternalFromError, s.bqLoader(projectID, func(ctx context.Context, client *bigquery.Client) (any, error) {
+		return client.ListTables(ctx, dataset)
+	}))
 }
 
 func (s *Server) handleBQSchema(w http.ResponseWriter, r *http.Request) {
 	projectID := r.URL.Query().Get("project_id")
 	dataset := r.PathValue("dataset")
 	table := r.PathValue("table")
-	client, cleanup, ok := s.bqClientFromQuery(w, r, projectID)
-	if !ok {
+	if !s.bqAvailable(w, projectID) {
 		return
 	}
-	defer cleanup()
-	s.serveCached(w, r, cacheKey("bq-schema", projectID, dataset, table), cacheTTL, writeInternalFromError, func() (any, error) {
-		return client.GetTableSchema(r.Context(), dataset, table)
-	})
+	s.serveCached(w, r, cacheKey("bq-schema", projectID, dataset, table), cacheTTL, writeInternalFromError, s.bqLoader(projectID, func(ctx context.Context, client *bigquery.Client) (any, error) {
+		return client.GetTableSchema(ctx, dataset, table)
+	}))
 }
 
 // handleBQQueryStart はクエリを非同期ジョブとして開始しジョブ ID を返す。
@@ -194,18 +190,43 @@ func writeBQError(w http.ResponseWriter, err error) {
 // or falls back to the server-level client. cleanup はリクエスト単位で生成した
 // クライアントのみを閉じる (サーバ共有クライアントには何もしない)。
 func (s *Server) bqClientFromQuery(w http.ResponseWriter, r *http.Request, projectID string) (*bigquery.Client, func(), bool) {
-	if projectID != "" {
-		bq, err := bigquery.NewClient(r.Context(), projectID)
-		if err != nil {
-			writeInternalError(w, "bigquery client: "+err.Error())
-			return nil, nil, false
-		}
-		return bq, func() { _ = bq.Close() }, true
+	if !s.bqAvailable(w, projectID) {
+		return nil, nil, false
 	}
-	if s.bq != nil {
+	if projectID == "" {
 		return s.bq, func() {}, true
 	}
+	bq, err := bigquery.NewClient(r.Context(), projectID)
+	if err != nil {
+		writeInternalError(w, "bigquery client: "+err.Error())
+		return nil, nil, false
+	}
+	return bq, func() { _ = bq.Close() }, true
+}
+
+// bqAvailable は project_id もサーバ共有クライアントもない場合に 503 を書き出して false を返す。
+func (s *Server) bqAvailable(w http.ResponseWriter, projectID string) bool {
+	if projectID != "" || s.bq != nil {
+		return true
+	}
 	writeError(w, http.StatusServiceUnavailable, "BQ_NOT_CONFIGURED",
 		"BigQuery is not configured; provide ?project_id= or set GOOGLE_CLOUD_PROJECT")
-	return nil, nil, false
+	return false
+}
+
+// bqLoader は serveCached 用の loader を返す。project_id 指定時のクライアントは loader の中で生成・破棄する。
+// stale-while-revalidate のバックグラウンド更新はレスポンス送出後に走るため、ハンドラのスコープで
+// 閉じるクライアントは使えない。
+func (s *Server) bqLoader(projectID string, load func(context.Context, *bigquery.Client) (any, error)) func(context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		if projectID == "" {
+			return load(ctx, s.bq)
+		}
+		bq, err := bigquery.NewClient(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery client: %w", err)
+		}
+		defer func() { _ = bq.Close() }()
+		return load(ctx, bq)
+	}
 }
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sfuruya0612/thief/backend/internal/cache"
+)
+
+// CacheInfoResponse は GET /api/cache のレスポンス。Entries はメモリ上のエントリのみで、
+// 追い出し済み・再起動後に未参照のディスク上のエントリは含まない。
+type CacheInfoResponse struct {
+	Stats   cache.Stats       `json:"stats"`
+	Entries []cache.EntryInfo `json:"entries"`
+}
+
+// CacheInvalidateResponse は DELETE /api/cache のレスポンス。
+type CacheInvalidateResponse struct {
+	Prefix  string `json:"prefix"`
+	Removed int    `json:"removed"`
+}
+
+// handleCacheInfo は resourceCache のエントリ一覧 (キー・サイズ・キャッシュ時刻・期限・STALE か・ヒット数) と
+// ヒット / ミスの集計を返す。値そのものは返さない。
+func (s *Server) handleCacheInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, CacheInfoResponse{
+		Stats:   s.resourceCache.Stats(),
+		Entries: s.resourceCache.Entries(),
+	})
+}
+
+// handleCacheInvalidate は prefix で始まるキーのエントリをメモリとディスクから削除する。
+// キーは cacheKey の形式 (service:profile:region:...) のため、例えば prefix=ec2:prod で
+// prod プロファイルの EC2 一覧を全リージョン分まとめて破棄できる。誤って全件を消さないよう prefix は必須とする。
+func (s *Server) handleCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		writeBadRequest(w, "prefix query parameter is required")
+		return
+	}
+	writeJSON(w, CacheInvalidateResponse{
+		Prefix:  prefix,
+		Removed: s.resourceCache.InvalidatePrefix(prefix),
+	})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleCacheInfoAndInvalidate(t *testing.T) {
+	s := newTestServer(t)
+	s.resourceCache.Set(cacheKey("ec2", "prod", "ap-northeast-1"), []string{"i-1"}, time.Hour)
+	s.resourceCache.Set(cacheKey("ec2", "prod", "us-east-1"), []string{"i-2"}, time.Hour)
+	s.resourceCache.Set(cacheKey("ec2", "dev", "ap-northeast-1"), []string{"i-3"}, time.Hour)
+
+	w := httptest.NewRecorder()
+	s.handleCacheInfo(w, httptest.NewRequest(http.MethodGet, "/api/cache", nil))
+	var info CacheInfoResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("unmarshal info: %v", err)
+	}
+	if info.Stats.Entries != 3 || len(info.Entries) != 3 {
+		t.Fatalf("info = %+v, want 3 entries", info)
+	}
+	if got := info.Entries[0]; got.Key != "ec2:dev:ap-northeast-1" || got.Size != int64(len(`["i-3"]`)) {
+		t.Errorf("Entries[0] = %+v, want ec2:dev:ap-northeast-1 sorted first with its JSON size", got)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		wantCode    int
+		wantRemoved int
+	}{
+		{name: "missing prefix", url: "/api/cache", wantCode: http.StatusBadRequest},
+		{name: "profile prefix", url: "/api/cache?prefix=ec2:prod", wantCode: http.StatusOK, wantRemoved: 2},
+		{name: "no match", url: "/api/cache?prefix=rds:", wantCode: http.StatusOK, wantRemoved: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleCacheInvalidate(w, httptest.NewRequest(http.MethodDelete, tt.url, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var res CacheInvalidateResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if res.Removed != tt.wantRemoved {
+				t.Errorf("Removed = %d, want %d", res.Removed, tt.wantRemoved)
+			}
+		})
+	}
+	if _, ok := s.resourceCache.Get(cacheKey("ec2", "dev", "ap-northeast-1")); !ok {
+		t.Error("expected other profile's entry to remain")
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -22,15 +23,15 @@ func (s *Server) handleCost(w http.ResponseWriter, r *http.Request) {
 		opts.Months = months
 	}
 	key := cacheKey("cost", profile, region, boolStr(opts.IncludeToday), opts.Granularity, opts.GroupByDimension, opts.ServiceFilter, opts.StartDate, opts.EndDate, strconv.Itoa(opts.Months))
-	s.serveCached(w, r, key, cacheTTL, writeInternalFromError, func() (any, error) {
-		return awsinternal.GetCost(r.Context(), profile, region, opts)
+	s.serveCached(w, r, key, cacheTTL, writeInternalFromError, func(ctx context.Context) (any, error) {
+		return awsinternal.GetCost(ctx, profile, region, opts)
 	})
 }
 
 func (s *Server) handleCostForecast(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("cost-forecast", profile, region), cacheTTL, writeInternalFromError, func() (any, error) {
-		return awsinternal.GetForecast(r.Context(), profile, region)
+	s.serveCached(w, r, cacheKey("cost-forecast", profile, region), cacheTTL, writeInternalFromError, func(ctx context.Context) (any, error) {
+		return awsinternal.GetForecast(ctx, profile, region)
 	})
 }
 
@@ -13,10 +13,16 @@ import (
 // handleCWLogGroups は指定 profile/region の CloudWatch Logs ロググループ一覧を返す。
 // 変化が緩やかなためキャッシュを通す。
 func (s *Server) handleCWLogGroups(w http.ResponseWriter, r *http.Request) {
-	profile, region := s.profileAndRegion(r)
-	s.serveCached(w, r, cacheKey("cwlogs-groups", profile, region), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListLogGroups(r.Context(), profile, region)
-	})
+	s.serveRegional(w, r, "cwlogs-groups")
+}
+
+func (s *Server) loadLogGroups(ctx context.Context, profile, region string) (any, error) {
+	groups, err := awsinternal.ListLogGroups(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	awsinternal.ApplyLogGroupCostEstimates(groups, s.priceTableForEstimate(profile, region, "cwlogs"))
+	return groups, nil
 }
 
 // handleCWLogEvents は選択ロググループ群を横断してログイベントを検索し 1 ページ返す。
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -10,8 +11,8 @@ import (
 func (s *Server) handleDynamoSchema(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	table := r.PathValue("table")
-	s.serveCached(w, r, cacheKey("dynamo-schema", profile, region, table), cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.DescribeDynamoTable(r.Context(), profile, region, table)
+	s.serveCached(w, r, cacheKey("dynamo-schema", profile, region, table), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.DescribeDynamoTable(ctx, profile, region, table)
 	})
 }
 
@@ -28,7 +29,7 @@ func (s *Server) handleDynamoItems(w http.ResponseWriter, r *http.Request) {
 	}
 	// PK/SK/属性フィルタ/件数の値そのものをキャッシュキーに含める (Query/Scan 結果は入力ごとに変わる)。
 	key := cacheKey("dynamo-items", profile, region, table, req.PKValue, req.SKValue, req.AttrName, req.AttrValue, r.URL.Query().Get("limit"))
-	s.serveCached(w, r, key, cacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.QueryDynamoItems(r.Context(), profile, region, table, req)
+	s.serveCached(w, r, key, cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.QueryDynamoItems(ctx, profile, region, table, req)
 	})
 }
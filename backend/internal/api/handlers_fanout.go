@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// FanoutResponse は fan-out モード (?profiles= / ?regions=) の一覧レスポンス。Items は取得できた
+// profile/region の組の行で、各行に取得元の profile / アカウント ID / region を付ける。Errors は取得できなかった
+// 組とその理由で、一部の組が失敗しても他の組の結果は Items に含める。
+type FanoutResponse struct {
+	Items  []awsinternal.FanoutItem[json.RawMessage] `json:"items"`
+	Errors []awsinternal.FanoutError                 `json:"errors"`
+}
+
+// isFanoutRequest は一覧リクエストが fan-out モードかどうかを返す。
+func isFanoutRequest(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has("profiles") || q.Has("regions")
+}
+
+// serveRegionalFanout は regionalResources[service] の一覧を複数の profile/region にわたって並列に取得する。
+// profiles (カンマ区切り、all で設定済みの全プロファイル) を省略した場合はパスの {profile} を、regions
+// (カンマ区切り、all で有効化済みの全リージョン) を省略した場合は region パラメータ (既定は cfg.Region) を使う。
+// 各組の取得は単一の一覧と同じキーで resourceCache を通すため、キャッシュは単一の一覧と共有される。
+// 複数のエントリをまとめるため X-Cache-* ヘッダは付けない。?tag= は単一の一覧と同様に各組の行に適用する。
+func (s *Server) serveRegionalFanout(w http.ResponseWriter, r *http.Request, service string) {
+	filters, err := tagFilters(r)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	profile, region := s.profileAndRegion(r)
+	targets, resolveErrs, ok := s.resolveFanoutRequest(w, r, profile, region)
+	if !ok {
+		return
+	}
+
+	refresh := s.refresh(r)
+	load := regionalResources[service]
+	items, listErrs := awsinternal.Fanout(r.Context(), targets, awsinternal.DefaultFanoutConcurrency,
+		func(ctx context.Context, profile, region string) ([]json.RawMessage, error) {
+			entry, _, err := s.resourceCache.Load(ctx, cacheKey(service, profile, region), cacheTTL, refresh, func(ctx context.Context) (any, error) {
+				return load(s, ctx, profile, region)
+			})
+			if err != nil {
+				return nil, err
+			}
+			var rows []json.RawMessage
+			if err := remarshal(entry.Value, &rows); err != nil {
+				return nil, err
+			}
+			if len(filters) > 0 {
+				rows = filterRowsByTags(rows, filters)
+			}
+			return rows, nil
+		})
+
+	errs := append([]awsinternal.FanoutError{}, resolveErrs...)
+	writeJSON(w, FanoutResponse{Items: items, Errors: append(errs, listErrs...)})
+}
+
+// resolveFanoutRequest は ?profiles= (カンマ区切り、all で設定済みの全プロファイル。省略時は profile) と
+// ?regions= (カンマ区切り、all で有効化済みの全リージョン。省略時は region) を fan-out の組に解決する。
+// 設定済みのプロファイルを読めない場合はエラー応答を書いて ok = false を返す。
+func (s *Server) resolveFanoutRequest(w http.ResponseWriter, r *http.Request, profile, region string) (targets []awsinternal.FanoutTarget, errs []awsinternal.FanoutError, ok bool) {
+	q := r.URL.Query()
+	profiles := []string{profile}
+	if v := awsinternal.SplitFanoutList(q.Get("profiles")); len(v) > 0 {
+		profiles = v
+	}
+	regions := []string{region}
+	if v := awsinternal.SplitFanoutList(q.Get("regions")); len(v) > 0 {
+		regions = v
+	}
+	profiles, err := awsinternal.ResolveFanoutProfiles(profiles)
+	if err != nil {
+		writeInternalError(w, err.Error())
+		return nil, nil, false
+	}
+	targets, errs = awsinternal.ResolveFanoutTargets(r.Context(), profiles, regions, s.fanoutResolver())
+	return targets, errs, true
+}
+
+// fanoutResolver は DefaultFanoutResolver のリージョン一覧の取得を、handleRegions と同じキーで resourceCache に通す。
+func (s *Server) fanoutResolver() awsinternal.FanoutResolver {
+	resolver := awsinternal.DefaultFanoutResolver()
+	resolver.Regions = func(ctx context.Context, profile string) ([]string, error) {
+		entry, _, err := s.resourceCache.Load(ctx, cacheKey("regions", profile), regionsCacheTTL, false, func(ctx context.Context) (any, error) {
+			return awsinternal.ListRegions(ctx, profile)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var regions []awsinternal.RegionResource
+		if err := remarshal(entry.Value, &regions); err != nil {
+			return nil, err
+		}
+		codes := make([]string, len(regions))
+		for i, r := range regions {
+			codes[i] = r.Code
+		}
+		return codes, nil
+	}
+	return resolver
+}
+
+// remarshal は resourceCache の値を JSON 経由で out に変換する。値はロード直後なら元の型、ディスクから復元した
+// 場合は json.RawMessage になるため、どちらでも同じように扱えるようにする。
+func remarshal(v any, out any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode cached value: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("decode cached value: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// TestServeRegionalFanout は ?profiles= / ?regions= による fan-out の JSON 契約を検証する。
+// アカウント ID は ~/.aws/config の sso_account_id から解決させるため HOME を差し替える
+// (STS を呼ばないようにするため。このため t.Parallel とは併用できない)。
+func TestServeRegionalFanout(t *testing.T) {
+	home := t.TempDir()
+	config := "[profile prod]\nsso_account_id = 111111111111\n\n[profile dev]\nsso_account_id = 222222222222\n"
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	regionalResources["fanout-test"] = func(_ *Server, _ context.Context, profile, region string) (any, error) {
+		if profile == "dev" && region == "us-east-1" {
+			return nil, errors.New("access denied")
+		}
+		return []string{profile + "/" + region}, nil
+	}
+	t.Cleanup(func() { delete(regionalResources, "fanout-test") })
+
+	do := func(t *testing.T, s *Server, url string) (int, FanoutResponse) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.SetPathValue("profile", "prod")
+		w := httptest.NewRecorder()
+		s.serveRegional(w, r, "fanout-test")
+		var body FanoutResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+		}
+		return w.Code, body
+	}
+	item := func(profile, accountID, region string) awsinternal.FanoutItem[json.RawMessage] {
+		return awsinternal.FanoutItem[json.RawMessage]{
+			FanoutTarget: awsinternal.FanoutTarget{Profile: profile, AccountID: accountID, Region: region},
+			Resource:     json.RawMessage(`"` + profile + "/" + region + `"`),
+		}
+	}
+
+	t.Run("profiles and regions with a partial failure", func(t *testing.T) {
+		s := newTestServer(t)
+		code, body := do(t, s, "/?profiles=prod,dev&regions=ap-northeast-1,us-east-1")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		want := FanoutResponse{
+			Items: []awsinternal.FanoutItem[json.RawMessage]{
+				item("prod", "111111111111", "ap-northeast-1"),
+				item("prod", "111111111111", "us-east-1"),
+				item("dev", "222222222222", "ap-northeast-1"),
+			},
+			Errors: []awsinternal.FanoutError{{Profile: "dev", Region: "us-east-1", Error: "access denied"}},
+		}
+		if diff := cmp.Diff(want, body); diff != "" {
+			t.Errorf("body mismatch (-want +got):\n%s", diff)
+		}
+		if _, ok := s.resourceCache.Get(cacheKey("fanout-test", "dev", "ap-northeast-1")); !ok {
+			t.Error("expected each target to be cached under the single-list key")
+		}
+	})
+
+	t.Run("all regions from the cached region list", func(t *testing.T) {
+		s := newTestServer(t)
+		s.resourceCache.Set(cacheKey("regions", "prod"), []awsinternal.RegionResource{{Code: "eu-west-1"}, {Code: "us-west-2"}}, time.Hour)
+		code, body := do(t, s, "/?regions=all")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		want := FanoutResponse{
+			Items: []awsinternal.FanoutItem[json.RawMessage]{
+				item("prod", "111111111111", "eu-west-1"),
+				item("prod", "111111111111", "us-west-2"),
+			},
+			Errors: []awsinternal.FanoutError{},
+		}
+		if diff := cmp.Diff(want, body); diff != "" {
+			t.Errorf("body mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("all profiles", func(t *testing.T) {
+		code, body := do(t, newTestServer(t), "/?profiles=all&region=ap-northeast-1")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		if len(body.Items) != 2 || body.Items[0].Profile != "prod" || body.Items[1].Profile != "dev" {
+			t.Errorf("items = %+v, want one row per configured profile", body.Items)
+		}
+	})
+}
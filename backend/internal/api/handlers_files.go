@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+// maxRemoteUploadSize は EC2 インスタンス / ECS タスクへアップロードできるファイルサイズの上限。
+// データチャネル上を base64 で 1KiB ずつ送るため、S3 のアップロードより小さく抑える。
+const maxRemoteUploadSize = 32 << 20 // 32MiB
+
+// startCommandFunc は command を実行する SSM セッション (AWS-StartInteractiveCommand / ECS Exec) を開始する。
+type startCommandFunc func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error)
+
+// handleEC2FileDownload は EC2 インスタンス上の path (ファイルまたはディレクトリ) を tar アーカイブとしてダウンロードする。
+func (s *Server) handleEC2FileDownload(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	instance := r.PathValue("instance")
+	s.serveRemoteDownload(w, r, profile, region, func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.StartSSMCommandSession(ctx, profile, region, instance, command)
+	})
+}
+
+// handleEC2FileUpload は multipart/form-data の file パートを EC2 インスタンスの dir に書き込む。
+func (s *Server) handleEC2FileUpload(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	instance := r.PathValue("instance")
+	s.serveRemoteUpload(w, r, profile, region, func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.StartSSMCommandSession(ctx, profile, region, instance, command)
+	})
+}
+
+// handleECSFileDownload は ECS タスクコンテナ内の path を tar アーカイブとしてダウンロードする。
+func (s *Server) handleECSFileDownload(w http.ResponseWriter, r *http.Request) {
+	start, ok := s.ecsCommandStarter(w, r)
+	if !ok {
+		return
+	}
+	profile, region := s.profileAndRegion(r)
+	s.serveRemoteDownload(w, r, profile, region, start)
+}
+
+// handleECSFileUpload は multipart/form-data の file パートを ECS タスクコンテナの dir に書き込む。
+func (s *Server) handleECSFileUpload(w http.ResponseWriter, r *http.Request) {
+	start, ok := s.ecsCommandStarter(w, r)
+	if !ok {
+		return
+	}
+	profile, region := s.profileAndRegion(r)
+	s.serveRemoteUpload(w, r, profile, region, start)
+}
+
+// ecsCommandStarter はパスパラメータと container クエリから ECS Exec の開始関数を組み立てる。
+// container が指定されていない場合は 400 を書き込んで false を返す。
+func (s *Server) ecsCommandStarter(w http.ResponseWriter, r *http.Request) (startCommandFunc, bool) {
+	profile, region := s.profileAndRegion(r)
+	cluster := r.PathValue("cluster")
+	task := r.PathValue("task")
+	container := r.URL.Query().Get("container")
+	if container == "" {
+		writeBadRequest(w, "container query parameter is required")
+		return nil, false
+	}
+	return func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.ExecuteECSCommand(ctx, profile, region, cluster, task, container, command)
+	}, true
+}
+
+// serveRemoteDownload は path クエリのリモートパスを取り出し、チェックサムを検証してから
+// application/x-tar として返す。検証前に送り始めないよう、一時ファイルに受け取ってから書き出す。
+func (s *Server) serveRemoteDownload(w http.ResponseWriter, r *http.Request, profile, region string, start startCommandFunc) {
+	remotePath := r.URL.Query().Get("path")
+	if remotePath == "" {
+		writeBadRequest(w, "path query parameter is required")
+		return
+	}
+	download, err := session.NewDownload(remotePath)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	tmp, err := os.CreateTemp("", "thief-download-*.tar")
+	if err != nil {
+		writeInternalError(w, "create temp file: "+err.Error())
+		return
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	err = runRemoteCopy(r.Context(), profile, region, download.Command(), start, func(ctx context.Context, dc *session.DataChannel) error {
+		_, err := download.Run(ctx, dc, tmp)
+		return err
+	})
+	if err != nil {
+		writeRemoteCopyError(w, err)
+		return
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		writeInternalError(w, "read downloaded archive: "+err.Error())
+		return
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		writeInternalError(w, "read downloaded archive: "+err.Error())
+		return
+	}
+	filename := sanitizeContentDispositionFilename(path.Base(download.Path)) + ".tar"
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+	if _, err := io.Copy(w, tmp); err != nil {
+		// ヘッダは送信済みなのでエラー応答は書けない。ログのみ。
+		slog.Warn("remote download stream copy failed", "path", download.Path, "err", err.Error())
+	}
+}
+
+// serveRemoteUpload は multipart/form-data の file パートを tar アーカイブに包み、dir クエリの
+// リモートディレクトリへ展開する。リモートでチェックサムが一致した場合のみ展開される。
+func (s *Server) serveRemoteUpload(w http.ResponseWriter, r *http.Request, profile, region string, start startCommandFunc) {
+	dir := r.URL.Query().Get("dir")
+	if dir == "" {
+		writeBadRequest(w, "dir query parameter is required")
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeBadRequest(w, "invalid multipart body: "+err.Error())
+		return
+	}
+	var (
+		archive *os.File
+		name    string
+	)
+	for archive == nil {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeBadRequest(w, "read multipart part: "+err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		name = part.FileName()
+		archive, err = archiveUploadPart(part, name)
+		part.Close()
+		if err != nil {
+			writeBadRequest(w, "read file part: "+err.Error())
+			return
+		}
+	}
+	if archive == nil {
+		writeBadRequest(w, `multipart form must contain a "file" part`)
+		return
+	}
+	defer func() {
+		archive.Close()
+		os.Remove(archive.Name())
+	}()
+
+	upload, err := session.NewUpload(dir, archive)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	err = runRemoteCopy(r.Context(), profile, region, upload.Command(), start, upload.Run)
+	if err != nil {
+		writeRemoteCopyError(w, err)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok", "dir": upload.Dir, "name": path.Base(name)})
+}
+
+// archiveUploadPart はアップロードされたファイルを name の 1 エントリだけを含む tar アーカイブの一時ファイルにする。
+// tar ヘッダにサイズが必要なため、いったん一時ファイルに受け取ってからアーカイブする。
+func archiveUploadPart(part io.Reader, name string) (*os.File, error) {
+	if name == "" {
+		return nil, errors.New("file name is required")
+	}
+	content, err := os.CreateTemp("", "thief-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		content.Close()
+		os.Remove(content.Name())
+	}()
+	size, err := io.Copy(content, io.LimitReader(part, maxRemoteUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRemoteUploadSize {
+		return nil, fmt.Errorf("file exceeds the %d byte limit", maxRemoteUploadSize)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	archive, err := os.CreateTemp("", "thief-upload-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	if err := session.WriteArchiveFile(archive, name, content, size); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		return nil, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		return nil, err
+	}
+	return archive, nil
+}
+
+// runRemoteCopy は command を実行するセッションを開始してデータチャネルで transfer を行い、終了後にセッションを Terminate する。
+func runRemoteCopy(ctx context.Context, profile, region, command string, start startCommandFunc, transfer func(context.Context, *session.DataChannel) error) error {
+	result, err := start(ctx, command)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionTerminateTimeout)
+		defer cancel()
+		if err := awsinternal.TerminateSSMSession(ctx, profile, region, result.SessionID); err != nil {
+			slog.Warn("failed to terminate file copy session", "session_id", result.SessionID, "err", err)
+		}
+	}()
+
+	dc, err := session.OpenDataChannel(ctx, result.StreamURL, result.TokenValue, result.SessionID)
+	if err != nil {
+		return fmt.Errorf("open data channel: %w", err)
+	}
+	defer dc.Close()
+	dc.EnableKMSEncryption(result.Target, awsinternal.SessionDataKeyGenerator(profile, region))
+	dc.EnableReconnect(awsinternal.SessionResumer(profile, region, result.SessionID))
+	return transfer(ctx, dc)
+}
+
+// writeRemoteCopyError はファイルコピーのエラーを書き込む。チェックサム不一致は転送経路の問題のため 502 とする。
+func writeRemoteCopyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, session.ErrChecksumMismatch) {
+		writeError(w, http.StatusBadGateway, "CHECKSUM_MISMATCH", err.Error())
+		return
+	}
+	writeAWSError(w, err)
+}
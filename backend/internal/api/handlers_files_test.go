@@ -0,0 +1,60 @@
+package api
+
+import (
+	"archive/tar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteFileHandlersRequireQuery(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{name: "ec2 download without path", method: http.MethodGet, target: "/files", handler: s.handleEC2FileDownload, want: "path"},
+		{name: "ec2 upload without dir", method: http.MethodPost, target: "/files", handler: s.handleEC2FileUpload, want: "dir"},
+		{name: "ecs download without container", method: http.MethodGet, target: "/files?path=/tmp/heap.hprof", handler: s.handleECSFileDownload, want: "container"},
+		{name: "ecs upload without container", method: http.MethodPost, target: "/files?dir=/tmp", handler: s.handleECSFileUpload, want: "container"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status = %d body = %q, want 400 mentioning %q", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveUploadPart(t *testing.T) {
+	archive, err := archiveUploadPart(strings.NewReader("key=value\n"), "app.conf")
+	if err != nil {
+		t.Fatalf("archiveUploadPart() error = %v", err)
+	}
+	t.Cleanup(func() { archive.Close() })
+
+	tr := tar.NewReader(archive)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	body, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("read archive entry: %v", err)
+	}
+	if hdr.Name != "app.conf" || string(body) != "key=value\n" {
+		t.Errorf("entry = %q %q, want app.conf with the uploaded content", hdr.Name, body)
+	}
+
+	if _, err := archiveUploadPart(strings.NewReader(strings.Repeat("x", maxRemoteUploadSize+1)), "big.bin"); err == nil {
+		t.Error("archiveUploadPart() error = nil, want size limit error")
+	}
+}
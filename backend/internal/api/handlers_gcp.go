@@ -1,10 +1,8 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -44,7 +42,7 @@ func (s *Server) handleGCPProjects(w http.ResponseWriter, r *http.Request) {
 	}
 
 	refresh := s.refresh(r)
-	s.serveCached(w, r, cacheKey("gcp-projects"), regionsCacheTTL, writeGCPError, func() (any, error) {
+	s.serveCached(w, r, cacheKey("gcp-projects"), regionsCacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
 		if !refresh {
 			if projects, _, ok, err := gcp.LoadProjectsFromDisk(dir); err != nil {
 				return nil, err
@@ -52,7 +50,7 @@ func (s *Server) handleGCPProjects(w http.ResponseWriter, r *http.Request) {
 				return projects, nil
 			}
 		}
-		return gcp.RefreshProjectsOnDisk(r.Context(), dir)
+		return gcp.RefreshProjectsOnDisk(ctx, dir)
 	})
 }
 
@@ -62,8 +60,8 @@ func (s *Server) handleGCPCloudRun(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-	s.serveCached(w, r, cacheKey("gcp-cloudrun", projectID), cacheTTL, writeGCPError, func() (any, error) {
-		return gcp.ListCloudRun(r.Context(), projectID)
+	s.serveCached(w, r, cacheKey("gcp-cloudrun", projectID), cacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
+		return gcp.ListCloudRun(ctx, projectID)
 	})
 }
 
@@ -73,8 +71,8 @@ func (s *Server) handleGCPGCS(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-	s.serveCached(w, r, cacheKey("gcp-gcs", projectID), cacheTTL, writeGCPError, func() (any, error) {
-		return gcp.ListBuckets(r.Context(), projectID)
+	s.serveCached(w, r, cacheKey("gcp-gcs", projectID), cacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
+		return gcp.ListBuckets(ctx, projectID)
 	})
 }
 
@@ -93,8 +91,8 @@ func (s *Server) handleGCPGCSObjects(w http.ResponseWriter, r *http.Request) {
 	}
 	bucket := r.PathValue("bucket")
 	prefix := r.URL.Query().Get("prefix")
-	s.serveCached(w, r, cacheKey("gcp-gcs-objects", projectID, bucket, prefix), cacheTTL, writeGCPError, func() (any, error) {
-		objects, truncated, err := gcp.ListObjects(r.Context(), projectID, bucket, prefix)
+	s.serveCached(w, r, cacheKey("gcp-gcs-objects", projectID, bucket, prefix), cacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
+		objects, truncated, err := gcp.ListObjects(ctx, projectID, bucket, prefix)
 		if err != nil {
 			return nil, err
 		}
@@ -144,8 +142,9 @@ func (s *Server) handleGCPGCSObjectDownload(w http.ResponseWriter, r *http.Reque
 }
 
 // handleGCPGCSObjectPreview は GCS オブジェクトの中身をプレビュー用 JSON エンベロープで返す。
-// 既知のバイナリ拡張子を除く 5 MB 未満のオブジェクトを対象とし、中身がテキストでなければ
-// buildPreviewResponse が弾く。
+// テキストは既知のバイナリ拡張子を除く 5 MB 未満のオブジェクトを対象とし、中身がテキストでなければ
+// buildPreviewResponse が弾く。Parquet / Avro / ORC と CSV / TSV / NDJSON は先頭の ?rows= 行を表にし、
+// gzip / zstd で圧縮されたものは展開して読む (writeObjectPreview)。
 func (s *Server) handleGCPGCSObjectPreview(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := s.gcpProjectIDFromQuery(w, r)
 	if !ok {
@@ -161,29 +160,24 @@ func (s *Server) handleGCPGCSObjectPreview(w http.ResponseWriter, r *http.Reques
 		writeBadRequest(w, "key is required")
 		return
 	}
-	if !previewExtensionAllowed(objectKey) {
-		writePreviewUnsupportedType(w)
-		return
-	}
-
-	obj, err := gcp.GetObject(r.Context(), projectID, bucket, objectKey)
-	if err != nil {
-		writeGCPError(w, err)
-		return
-	}
-	defer obj.Close()
 
-	if !previewSizeAllowed(obj.Size) {
-		writePreviewTooLarge(w)
-		return
-	}
-
-	resp, err := buildPreviewResponse(obj, obj.ContentType)
-	if err != nil {
-		writePreviewError(w, err)
-		return
-	}
-	writeJSON(w, resp)
+	writeObjectPreview(w, r, objectKey, previewSource{
+		open: func(ctx context.Context) (*previewObject, error) {
+			obj, err := gcp.GetObject(ctx, projectID, bucket, objectKey)
+			if err != nil {
+				return nil, err
+			}
+			return &previewObject{body: obj, contentType: obj.ContentType, size: obj.Size, close: obj.Close}, nil
+		},
+		openAt: func(ctx context.Context) (*previewObject, error) {
+			ra, err := gcp.NewObjectReaderAt(ctx, projectID, bucket, objectKey)
+			if err != nil {
+				return nil, err
+			}
+			return &previewObject{readerAt: ra, contentType: ra.ContentType, size: ra.Size, close: ra.Close}, nil
+		},
+		writeError: writeGCPError,
+	})
 }
 
 // handleGCPIAM は指定プロジェクトの IAM ポリシーをメンバー単位に展開して返す。
@@ -192,8 +186,8 @@ func (s *Server) handleGCPIAM(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
-	s.serveCached(w, r, cacheKey("gcp-iam", projectID), cacheTTL, writeGCPError, func() (any, error) {
-		return gcp.ListIAMBindings(r.Context(), projectID)
+	s.serveCached(w, r, cacheKey("gcp-iam", projectID), cacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
+		return gcp.ListIAMBindings(ctx, projectID)
 	})
 }
 
@@ -203,13 +197,13 @@ func (s *Server) handleGCPServiceAccounts(w http.ResponseWriter, r *http.Request
 	if !ok {
 		return
 	}
-	s.serveCached(w, r, cacheKey("gcp-serviceaccounts", projectID), cacheTTL, writeGCPError, func() (any, error) {
-		return gcp.ListServiceAccounts(r.Context(), projectID)
+	s.serveCached(w, r, cacheKey("gcp-serviceaccounts", projectID), cacheTTL, writeGCPError, func(ctx context.Context) (any, error) {
+		return gcp.ListServiceAccounts(ctx, projectID)
 	})
 }
 
-// handleGCPGCSObjectUpload は multipart/form-data の file パートを読み込んで GCS に書き込む。
-// アップロード上限サイズは S3 と同じ maxS3UploadSize / readS3UploadBody を再利用する。
+// handleGCPGCSObjectUpload は multipart/form-data の file パートを再開可能アップロードでストリーミングに
+// GCS へ書き込む (gcp.UploadObject)。進捗の扱いは S3 と同じ receiveObjectUpload に任せる。
 func (s *Server) handleGCPGCSObjectUpload(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := s.gcpProjectIDFromQuery(w, r)
 	if !ok {
@@ -226,43 +220,9 @@ func (s *Server) handleGCPGCSObjectUpload(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	reader, err := r.MultipartReader()
-	if err != nil {
-		writeBadRequest(w, "invalid multipart body: "+err.Error())
-		return
-	}
-
-	var uploaded bool
-	for {
-		part, err := reader.NextPart()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			writeBadRequest(w, "read multipart part: "+err.Error())
-			return
-		}
-		if part.FormName() != "file" {
-			part.Close()
-			continue
-		}
-		contentType := part.Header.Get("Content-Type")
-		body, err := readS3UploadBody(part)
-		part.Close()
-		if err != nil {
-			writeBadRequest(w, "read file part: "+err.Error())
-			return
-		}
-		if err := gcp.PutObject(r.Context(), projectID, bucket, objectKey, bytes.NewReader(body), contentType); err != nil {
-			writeGCPError(w, err)
-			return
-		}
-		uploaded = true
-		break
-	}
-
-	if !uploaded {
-		writeBadRequest(w, `multipart form must contain a "file" part`)
+	if !s.receiveObjectUpload(w, r, bucket, objectKey, writeGCPError, func(ctx context.Context, body io.Reader, contentType string, progress func(int64)) (int64, error) {
+		return gcp.UploadObject(ctx, projectID, bucket, objectKey, body, contentType, progress)
+	}) {
 		return
 	}
 
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/graph"
+)
+
+// リソースグラフの探索の上限。depth の既定値はリクエスト経路 (CloudFront → ALB → リスナー → ルール →
+// ターゲットグループ → インスタンス → ENI) を WAF からたどっても収まる深さにする。
+const (
+	defaultGraphDepth = 8
+	maxGraphDepth     = 16
+	maxGraphNodes     = 500
+)
+
+// リソースグラフのノードの種類。
+const (
+	graphCloudFront     = "cloudfront"
+	graphWAF            = "waf"
+	graphELB            = "elb"
+	graphELBListener    = "elb-listener"
+	graphELBRule        = "elb-rule"
+	graphELBTargetGroup = "elb-target-group"
+	graphEC2Instance    = "ec2-instance"
+	graphEC2ENI         = "ec2-eni"
+	graphECSCluster     = "ecs-cluster"
+	graphECSService     = "ecs-service"
+	graphECSTask        = "ecs-task"
+	graphECSContainer   = "ecs-container"
+	graphS3Bucket       = "s3-bucket"
+	graphLambda         = "lambda"
+	graphIP             = "ip"
+	graphOrigin         = "origin"
+)
+
+// graphExpander はノードの種類ごとの隣接ノードの解決。各一覧はドリルダウンの API と同じキーで resourceCache を通す。
+type graphExpander func(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error)
+
+var graphExpanders = map[string]graphExpander{
+	graphCloudFront:     expandCloudFront,
+	graphWAF:            expandWAF,
+	graphELB:            expandELB,
+	graphELBListener:    expandELBListener,
+	graphELBRule:        expandELBRule,
+	graphELBTargetGroup: expandELBTargetGroup,
+	graphEC2Instance:    expandEC2Instance,
+	graphECSCluster:     expandECSCluster,
+	graphECSService:     expandECSService,
+	graphECSTask:        expandECSTask,
+}
+
+// handleResourceGraph は ?root=<arn> のリソースから関係をたどり、ノードとエッジのグラフを返す。
+// たどる関係は CloudFront → オリジン (S3 / ALB)、WAF → 関連付けられた ALB / ディストリビューション、
+// ALB → リスナー → ルール → ターゲットグループ → ターゲット (EC2 / IP / Lambda)、EC2 → ENI、
+// ECS クラスター → サービス → タスク → コンテナ。?depth= で深さ (既定 8、最大 16) を指定できる。
+func (s *Server) handleResourceGraph(w http.ResponseWriter, r *http.Request) {
+	profile := r.PathValue("profile")
+	q := r.URL.Query()
+	root, err := graphNodeFromARN(q.Get("root"))
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	depth := defaultGraphDepth
+	if v := q.Get("depth"); v != "" {
+		depth, err = strconv.Atoi(v)
+		if err != nil || depth < 1 || depth > maxGraphDepth {
+			writeBadRequest(w, fmt.Sprintf("depth must be between 1 and %d", maxGraphDepth))
+			return
+		}
+	}
+
+	refresh := s.refresh(r)
+	expanders := make(map[string]graph.Expander, len(graphExpanders))
+	for typ, expand := range graphExpanders {
+		expanders[typ] = func(ctx context.Context, n graph.Node) ([]graph.Neighbor, error) {
+			return expand(s, ctx, profile, refresh, n)
+		}
+	}
+	writeJSON(w, graph.Build(r.Context(), root, expanders, graph.Options{MaxDepth: depth, MaxNodes: maxGraphNodes}))
+}
+
+// graphLoad は key の一覧を resourceCache から (なければ load で取得して) T として返す。
+func graphLoad[T any](s *Server, ctx context.Context, refresh bool, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var out T
+	entry, _, err := s.resourceCache.Load(ctx, key, cacheTTL, refresh, func(ctx context.Context) (any, error) {
+		return load(ctx)
+	})
+	if err != nil {
+		return out, err
+	}
+	err = remarshal(entry.Value, &out)
+	return out, err
+}
+
+// graphNodeFromARN は ARN からグラフのノードを作る。グラフで扱わない種類の ARN はエラーを返す。
+func graphNodeFromARN(s string) (graph.Node, error) {
+	if s == "" {
+		return graph.Node{}, fmt.Errorf("root query parameter is required")
+	}
+	a, err := arn.Parse(s)
+	if err != nil {
+		return graph.Node{}, fmt.Errorf("invalid root ARN %q: %w", s, err)
+	}
+	parts := strings.Split(a.Resource, "/")
+	node := func(typ, name string) (graph.Node, error) {
+		return graph.Node{ID: s, Type: typ, Name: name, Region: a.Region}, nil
+	}
+	switch {
+	case a.Service == "cloudfront" && len(parts) == 2 && parts[0] == "distribution":
+		n, err := node(graphCloudFront, parts[1])
+		n.Region = "us-east-1"
+		return n, err
+	case a.Service == "wafv2" && len(parts) == 4 && parts[1] == "webacl":
+		n, err := node(graphWAF, parts[2])
+		if parts[0] == "global" {
+			n.Region = "us-east-1"
+		}
+		return n, err
+	case a.Service == "elasticloadbalancing" && len(parts) == 4 && parts[0] == "loadbalancer":
+		return node(graphELB, parts[2])
+	case a.Service == "elasticloadbalancing" && len(parts) == 5 && parts[0] == "listener":
+		return node(graphELBListener, parts[2])
+	case a.Service == "elasticloadbalancing" && len(parts) == 6 && parts[0] == "listener-rule":
+		return node(graphELBRule, parts[5])
+	case a.Service == "elasticloadbalancing" && len(parts) == 3 && parts[0] == "targetgroup":
+		return node(graphELBTargetGroup, parts[1])
+	case a.Service == "ec2" && len(parts) == 2 && parts[0] == "instance":
+		return node(graphEC2Instance, parts[1])
+	case a.Service == "ecs" && len(parts) == 2 && parts[0] == "cluster":
+		return node(graphECSCluster, parts[1])
+	case a.Service == "ecs" && len(parts) == 3 && parts[0] == "service":
+		n, err := node(graphECSService, parts[2])
+		n.Attributes = map[string]string{"cluster": parts[1]}
+		return n, err
+	case a.Service == "ecs" && len(parts) == 3 && parts[0] == "task":
+		n, err := node(graphECSTask, parts[2])
+		n.Attributes = map[string]string{"cluster": parts[1]}
+		return n, err
+	case a.Service == "s3" && len(parts) == 1:
+		return node(graphS3Bucket, a.Resource)
+	case a.Service == "lambda" && strings.HasPrefix(a.Resource, "function:"):
+		return node(graphLambda, strings.TrimPrefix(a.Resource, "function:"))
+	}
+	return graph.Node{}, fmt.Errorf("unsupported root ARN %q (cloudfront distribution, wafv2 web ACL, load balancer, listener, listener rule, target group, ec2 instance, ecs cluster, service or task)", s)
+}
+
+// neighborFromARN は ARN の隣接ノードを作る。グラフで扱わない種類の ARN は Type を ARN のサービス名にした末端のノードにする。
+func neighborFromARN(s, relation string) graph.Neighbor {
+	n, err := graphNodeFromARN(s)
+	if err != nil {
+		n = graph.Node{ID: s, Type: s, Name: s}
+		if a, err := arn.Parse(s); err == nil {
+			n.Type, n.Region = a.Service, a.Region
+		}
+	}
+	return graph.Neighbor{Node: n, Relation: relation}
+}
+
+// s3OriginPattern は S3 オリジンのドメイン (bucket.s3.amazonaws.com / bucket.s3.<region>.amazonaws.com /
+// bucket.s3-website-<region>.amazonaws.com など) からバケット名を取り出す。
+var s3OriginPattern = regexp.MustCompile(`^(.+?)\.s3(?:[.-][a-z0-9-]+)*\.amazonaws\.com$`)
+
+// elbOriginPattern は ALB オリジンのドメイン (name-123.<region>.elb.amazonaws.com) からリージョンを取り出す。
+var elbOriginPattern = regexp.MustCompile(`\.([a-z0-9-]+)\.elb\.amazonaws\.com$`)
+
+func expandCloudFront(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	dists, err := graphLoad(s, ctx, refresh, cacheKey("cloudfront", profile, n.Region), func(ctx context.Context) ([]awsinternal.CloudFrontResource, error) {
+		return awsinternal.ListCloudFrontResources(ctx, profile, n.Region)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var origins []string
+	for _, d := range dists {
+		if strings.HasSuffix(n.ID, "/"+d.ID) {
+			origins = d.Origins
+		}
+	}
+
+	var out []graph.Neighbor
+	for _, domain := range origins {
+		domain = strings.ToLower(domain)
+		if m := s3OriginPattern.FindStringSubmatch(domain); m != nil {
+			out = append(out, graph.Neighbor{Node: graph.Node{ID: "arn:aws:s3:::" + m[1], Type: graphS3Bucket, Name: m[1]}, Relation: "origin"})
+			continue
+		}
+		if m := elbOriginPattern.FindStringSubmatch(domain); m != nil {
+			region := m[1]
+			lbs, err := graphLoad(s, ctx, refresh, cacheKey("elb", profile, region), func(ctx context.Context) ([]awsinternal.ELBResource, error) {
+				return awsinternal.ListELBResources(ctx, profile, region)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if i := indexELBByDNS(lbs, domain); i >= 0 {
+				out = append(out, graph.Neighbor{Node: graph.Node{ID: lbs[i].ID, Type: graphELB, Name: lbs[i].Name, Region: region}, Relation: "origin"})
+				continue
+			}
+		}
+		out = append(out, graph.Neighbor{Node: graph.Node{ID: "origin/" + domain, Type: graphOrigin, Name: domain}, Relation: "origin"})
+	}
+	return out, nil
+}
+
+// indexELBByDNS は DNS 名が domain (dualstack. 付きを含む) のロードバランサーの位置を返す。
+func indexELBByDNS(lbs []awsinternal.ELBResource, domain string) int {
+	domain = strings.TrimPrefix(domain, "dualstack.")
+	for i, lb := range lbs {
+		if strings.EqualFold(lb.DNSName, domain) {
+			return i
+		}
+	}
+	return -1
+}
+
+func expandWAF(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	arns, err := graphLoad(s, ctx, refresh, cacheKey("waf-resources", profile, n.Region, n.ID), func(ctx context.Context) ([]string, error) {
+		return awsinternal.ListWAFAssociatedResources(ctx, profile, n.Region, n.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(arns))
+	for i, a := range arns {
+		out[i] = neighborFromARN(a, "protects")
+	}
+	return out, nil
+}
+
+func expandELB(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	listeners, err := graphLoad(s, ctx, refresh, cacheKey("elb-listeners", profile, n.Region, n.ID), func(ctx context.Context) ([]awsinternal.ELBListenerResource, error) {
+		return awsinternal.ListELBListeners(ctx, profile, n.Region, n.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(listeners))
+	for i, l := range listeners {
+		out[i] = graph.Neighbor{Node: graph.Node{
+			ID:     l.ARN,
+			Type:   graphELBListener,
+			Name:   fmt.Sprintf("%s:%d", l.Protocol, l.Port),
+			Region: n.Region,
+		}, Relation: "listener"}
+	}
+	return out, nil
+}
+
+func expandELBListener(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	rules, err := graphLoad(s, ctx, refresh, cacheKey("elb-rules", profile, n.Region, n.ID), func(ctx context.Context) ([]awsinternal.ELBRuleResource, error) {
+		return awsinternal.ListELBRules(ctx, profile, n.Region, n.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(rules))
+	for i, rule := range rules {
+		name := "priority " + rule.Priority
+		if rule.IsDefault {
+			name = "default"
+		}
+		attrs := map[string]string{"action": rule.ActionType}
+		if len(rule.Conditions) > 0 {
+			attrs["conditions"] = strings.Join(rule.Conditions, "; ")
+		}
+		out[i] = graph.Neighbor{Node: graph.Node{ID: rule.ARN, Type: graphELBRule, Name: name, Region: n.Region, Attributes: attrs}, Relation: "rule"}
+	}
+	return out, nil
+}
+
+// expandELBRule はルールの転送先のターゲットグループを返す。ルールの一覧は親のリスナーの展開で取得済みのため、
+// リスナー ARN (ルール ARN の listener-rule を listener にして末尾の ID を除いたもの) のキャッシュから引く。
+func expandELBRule(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	listenerArn := strings.Replace(n.ID[:strings.LastIndex(n.ID, "/")], ":listener-rule/", ":listener/", 1)
+	rules, err := graphLoad(s, ctx, refresh, cacheKey("elb-rules", profile, n.Region, listenerArn), func(ctx context.Context) ([]awsinternal.ELBRuleResource, error) {
+		return awsinternal.ListELBRules(ctx, profile, n.Region, listenerArn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.ARN != n.ID || rule.TargetGroupArn == "" {
+			continue
+		}
+		tg := neighborFromARN(rule.TargetGroupArn, "forward")
+		return []graph.Neighbor{tg}, nil
+	}
+	return nil, nil
+}
+
+func expandELBTargetGroup(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	targets, err := graphLoad(s, ctx, refresh, cacheKey("elb-target-health", profile, n.Region, n.ID), func(ctx context.Context) ([]awsinternal.ELBTargetHealthResource, error) {
+		return awsinternal.DescribeELBTargetHealth(ctx, profile, n.Region, n.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	a, err := arn.Parse(n.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target group ARN %q: %w", n.ID, err)
+	}
+	out := make([]graph.Neighbor, len(targets))
+	for i, t := range targets {
+		attrs := map[string]string{"health": t.State}
+		if t.Port != 0 {
+			attrs["port"] = strconv.Itoa(int(t.Port))
+		}
+		var nb graph.Neighbor
+		switch {
+		case strings.HasPrefix(t.TargetID, "i-"):
+			nb = graph.Neighbor{Node: graph.Node{
+				ID:     fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", a.Partition, a.Region, a.AccountID, t.TargetID),
+				Type:   graphEC2Instance,
+				Name:   t.TargetID,
+				Region: a.Region,
+			}}
+		case strings.HasPrefix(t.TargetID, "arn:"):
+			nb = neighborFromARN(t.TargetID, "")
+		default:
+			nb = graph.Neighbor{Node: graph.Node{ID: "ip/" + t.TargetID, Type: graphIP, Name: t.TargetID, Region: a.Region}}
+		}
+		nb.Relation = "target"
+		nb.Attributes = attrs
+		out[i] = nb
+	}
+	return out, nil
+}
+
+func expandEC2Instance(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	a, err := arn.Parse(n.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance ARN %q: %w", n.ID, err)
+	}
+	enis, err := graphLoad(s, ctx, refresh, cacheKey("ec2-enis", profile, n.Region, n.Name), func(ctx context.Context) ([]awsinternal.EC2NetworkInterfaceResource, error) {
+		return awsinternal.ListEC2NetworkInterfaces(ctx, profile, n.Region, n.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(enis))
+	for i, eni := range enis {
+		out[i] = graph.Neighbor{Node: graph.Node{
+			ID:     fmt.Sprintf("arn:%s:ec2:%s:%s:network-interface/%s", a.Partition, a.Region, a.AccountID, eni.ID),
+			Type:   graphEC2ENI,
+			Name:   eni.ID,
+			Region: n.Region,
+			Attributes: map[string]string{
+				"private_ip":      eni.PrivateIP,
+				"public_ip":       eni.PublicIP,
+				"subnet_id":       eni.SubnetID,
+				"security_groups": strings.Join(eni.SecurityGroups, ","),
+			},
+		}, Relation: "network-interface"}
+	}
+	return out, nil
+}
+
+func expandECSCluster(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	services, err := graphLoad(s, ctx, refresh, cacheKey("ecs-services", profile, n.Region, n.Name), func(ctx context.Context) ([]awsinternal.ECSServiceResource, error) {
+		return awsinternal.ListECSServices(ctx, profile, n.Region, n.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(services))
+	for i, svc := range services {
+		out[i] = graph.Neighbor{Node: graph.Node{
+			ID:     svc.ARN,
+			Type:   graphECSService,
+			Name:   svc.Name,
+			Region: n.Region,
+			Attributes: map[string]string{
+				"cluster": n.Name,
+				"status":  svc.Status,
+				"running": strconv.Itoa(int(svc.RunningCount)),
+				"desired": strconv.Itoa(int(svc.DesiredCount)),
+			},
+		}, Relation: "service"}
+	}
+	return out, nil
+}
+
+func expandECSService(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	cluster := n.Attributes["cluster"]
+	tasks, err := graphLoad(s, ctx, refresh, cacheKey("ecs-tasks", profile, n.Region, cluster, n.Name), func(ctx context.Context) ([]awsinternal.ECSTaskResource, error) {
+		return awsinternal.ListECSTasks(ctx, profile, n.Region, cluster, n.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(tasks))
+	for i, task := range tasks {
+		out[i] = graph.Neighbor{Node: graph.Node{
+			ID:         task.ARN,
+			Type:       graphECSTask,
+			Name:       task.ARN[strings.LastIndex(task.ARN, "/")+1:],
+			Region:     n.Region,
+			Attributes: map[string]string{"cluster": cluster, "status": task.LastStatus},
+		}, Relation: "task"}
+	}
+	return out, nil
+}
+
+func expandECSTask(s *Server, ctx context.Context, profile string, refresh bool, n graph.Node) ([]graph.Neighbor, error) {
+	cluster := n.Attributes["cluster"]
+	containers, err := graphLoad(s, ctx, refresh, cacheKey("ecs-containers", profile, n.Region, cluster, n.Name), func(ctx context.Context) ([]awsinternal.ECSContainerResource, error) {
+		return awsinternal.ListECSContainers(ctx, profile, n.Region, cluster, n.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]graph.Neighbor, len(containers))
+	for i, c := range containers {
+		out[i] = graph.Neighbor{Node: graph.Node{
+			ID:         n.ID + "/" + c.Name,
+			Type:       graphECSContainer,
+			Name:       c.Name,
+			Region:     n.Region,
+			Attributes: map[string]string{"status": c.LastStatus},
+		}, Relation: "container"}
+	}
+	return out, nil
+}
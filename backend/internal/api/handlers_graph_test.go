@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/graph"
+)
+
+func TestGraphNodeFromARN(t *testing.T) {
+	tests := []struct {
+		arn     string
+		want    graph.Node
+		wantErr bool
+	}{
+		{
+			arn:  "arn:aws:cloudfront::111111111111:distribution/E123",
+			want: graph.Node{Type: graphCloudFront, Name: "E123", Region: "us-east-1"},
+		},
+		{
+			arn:  "arn:aws:wafv2:us-east-1:111111111111:global/webacl/edge/abc",
+			want: graph.Node{Type: graphWAF, Name: "edge", Region: "us-east-1"},
+		},
+		{
+			arn:  "arn:aws:wafv2:ap-northeast-1:111111111111:regional/webacl/api/abc",
+			want: graph.Node{Type: graphWAF, Name: "api", Region: "ap-northeast-1"},
+		},
+		{
+			arn:  "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:loadbalancer/app/web/1",
+			want: graph.Node{Type: graphELB, Name: "web", Region: "ap-northeast-1"},
+		},
+		{
+			arn:  "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:targetgroup/web-tg/2",
+			want: graph.Node{Type: graphELBTargetGroup, Name: "web-tg", Region: "ap-northeast-1"},
+		},
+		{
+			arn:  "arn:aws:ecs:ap-northeast-1:111111111111:service/main/api",
+			want: graph.Node{Type: graphECSService, Name: "api", Region: "ap-northeast-1", Attributes: map[string]string{"cluster": "main"}},
+		},
+		{
+			arn:  "arn:aws:ec2:ap-northeast-1:111111111111:instance/i-1",
+			want: graph.Node{Type: graphEC2Instance, Name: "i-1", Region: "ap-northeast-1"},
+		},
+		{arn: "", wantErr: true},
+		{arn: "web", wantErr: true},
+		{arn: "arn:aws:sqs:ap-northeast-1:111111111111:queue", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			got, err := graphNodeFromARN(tt.arn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("graphNodeFromARN(%q) error = %v, wantErr %v", tt.arn, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.want.ID = tt.arn
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("graphNodeFromARN(%q) mismatch (-want +got):\n%s", tt.arn, diff)
+			}
+		})
+	}
+}
+
+// TestHandleResourceGraph は CloudFront ディストリビューションから S3 / ALB オリジン、リスナー、ルール、
+// ターゲットグループ、ターゲットをたどるグラフを検証する。各一覧は resourceCache に事前に入れて AWS を呼ばない。
+func TestHandleResourceGraph(t *testing.T) {
+	const (
+		region = "ap-northeast-1"
+		dist   = "arn:aws:cloudfront::111111111111:distribution/E123"
+		lb     = "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:loadbalancer/app/web/1"
+		lis    = "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:listener/app/web/1/10"
+		rule   = "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:listener-rule/app/web/1/10/100"
+		tg     = "arn:aws:elasticloadbalancing:ap-northeast-1:111111111111:targetgroup/web-tg/2"
+		inst   = "arn:aws:ec2:ap-northeast-1:111111111111:instance/i-1"
+	)
+	s := newTestServer(t)
+	for key, v := range map[string]any{
+		cacheKey("cloudfront", "prod", "us-east-1"): []awsinternal.CloudFrontResource{
+			{ID: "E999", Origins: []string{"other.example.com"}},
+			{ID: "E123", Origins: []string{"assets.s3.ap-northeast-1.amazonaws.com", "dualstack.web-1.ap-northeast-1.elb.amazonaws.com", "api.example.com"}},
+		},
+		cacheKey("elb", "prod", region):               []awsinternal.ELBResource{{ID: lb, Name: "web", DNSName: "web-1.ap-northeast-1.elb.amazonaws.com"}},
+		cacheKey("elb-listeners", "prod", region, lb): []awsinternal.ELBListenerResource{{ARN: lis, Protocol: "HTTPS", Port: 443}},
+		cacheKey("elb-rules", "prod", region, lis):    []awsinternal.ELBRuleResource{{ARN: rule, Priority: "default", IsDefault: true, ActionType: "forward", TargetGroupArn: tg}},
+		cacheKey("elb-target-health", "prod", region, tg): []awsinternal.ELBTargetHealthResource{
+			{TargetID: "i-1", Port: 80, State: "healthy"},
+			{TargetID: "10.0.0.5", Port: 80, State: "unhealthy"},
+		},
+		cacheKey("ec2-enis", "prod", region, "i-1"): []awsinternal.EC2NetworkInterfaceResource{{ID: "eni-1", PrivateIP: "10.0.0.4"}},
+	} {
+		s.resourceCache.Set(key, v, time.Hour)
+	}
+
+	do := func(t *testing.T, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/api/aws/profiles/prod/graph"+query, nil)
+		r.SetPathValue("profile", "prod")
+		w := httptest.NewRecorder()
+		s.handleResourceGraph(w, r)
+		return w
+	}
+
+	t.Run("cloudfront root", func(t *testing.T) {
+		w := do(t, "?root="+dist)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+		}
+		var g graph.Graph
+		if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		wantEdges := []graph.Edge{
+			{From: dist, To: "arn:aws:s3:::assets", Relation: "origin"},
+			{From: dist, To: lb, Relation: "origin"},
+			{From: dist, To: "origin/api.example.com", Relation: "origin"},
+			{From: lb, To: lis, Relation: "listener"},
+			{From: lis, To: rule, Relation: "rule"},
+			{From: rule, To: tg, Relation: "forward"},
+			{From: tg, To: inst, Relation: "target"},
+			{From: tg, To: "ip/10.0.0.5", Relation: "target"},
+			{From: inst, To: "arn:aws:ec2:ap-northeast-1:111111111111:network-interface/eni-1", Relation: "network-interface"},
+		}
+		if diff := cmp.Diff(wantEdges, g.Edges); diff != "" {
+			t.Errorf("edges mismatch (-want +got):\n%s", diff)
+		}
+		if len(g.Errors) != 0 || g.Truncated {
+			t.Errorf("errors = %v, truncated = %v, want none", g.Errors, g.Truncated)
+		}
+		for _, n := range g.Nodes {
+			if n.ID == inst && (n.Type != graphEC2Instance || n.Attributes["health"] != "healthy" || n.Attributes["port"] != "80") {
+				t.Errorf("instance node = %+v, want ec2-instance with health and port", n)
+			}
+		}
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		w := do(t, "?root="+lb+"&depth=1")
+		var g graph.Graph
+		if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		if len(g.Nodes) != 2 || !g.Truncated {
+			t.Errorf("nodes = %v, truncated = %v, want load balancer and listener with truncation", g.Nodes, g.Truncated)
+		}
+	})
+
+	for _, query := range []string{"", "?root=web", "?root=" + lb + "&depth=0", "?root=" + lb + "&depth=x"} {
+		if w := do(t, query); w.Code != http.StatusBadRequest {
+			t.Errorf("GET graph%s status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
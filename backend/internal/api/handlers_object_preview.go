@@ -2,13 +2,17 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/sfuruya0612/thief/backend/internal/preview"
 )
 
 // maxPreviewSize は S3 / GCS オブジェクトプレビューの上限サイズ。TODO の要件
@@ -18,10 +22,19 @@ const maxPreviewSize = 5 << 20 // 5MiB
 // errPreviewTooLarge は読み込んだ body が maxPreviewSize を超えたことを示す。
 var errPreviewTooLarge = fmt.Errorf("object exceeds max preview size of %d bytes", maxPreviewSize)
 
+// defaultPreviewRows / maxPreviewRows は表形式のプレビュー (Parquet / Avro / ORC / CSV / TSV / NDJSON) で
+// 返す行数 (?rows=) の既定値と上限。
+const (
+	defaultPreviewRows = 100
+	maxPreviewRows     = 1000
+)
+
 // previewBinaryExtensions は拡張子だけでバイナリ (プレビュー非対応) と判定できる形式
 // (大文字小文字を区別しない)。ここに無い拡張子は「テキストの可能性あり」として扱い、
 // 最終的な可否は buildPreviewResponse の中身検査 (UTF-8 妥当性 + NUL バイト非混入) が決める。
-// frontend の PREVIEW_BINARY_EXTENSIONS (lib/objectPreview.ts) と同じ集合を保つこと。
+// gzip / zstd (.gz / .zst) と Parquet / Avro / ORC は internal/preview が読めるためここには含めない
+// (frontend は表の表示に対応するまでこれらを含めたままにしている)。それ以外は frontend の
+// PREVIEW_BINARY_EXTENSIONS (lib/objectPreview.ts) と同じ集合を保つこと。
 var previewBinaryExtensions = map[string]bool{
 	// 画像
 	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true,
@@ -34,8 +47,8 @@ var previewBinaryExtensions = map[string]bool{
 	".mp3": true, ".wav": true, ".flac": true, ".aac": true, ".ogg": true,
 	".oga": true, ".m4a": true, ".wma": true, ".opus": true,
 	// アーカイブ / 圧縮
-	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".tbz2": true,
-	".xz": true, ".7z": true, ".rar": true, ".zst": true, ".lz4": true,
+	".zip": true, ".tgz": true, ".bz2": true, ".tbz2": true,
+	".xz": true, ".7z": true, ".rar": true, ".lz4": true,
 	".lzma": true, ".br": true,
 	// 実行ファイル / バイナリ
 	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".bin": true,
@@ -47,28 +60,52 @@ var previewBinaryExtensions = map[string]bool{
 	// フォント
 	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
 	// シリアライズ / データ
-	".parquet": true, ".avro": true, ".orc": true, ".pb": true, ".pyc": true,
-	".pyo": true, ".npy": true, ".npz": true, ".pkl": true, ".h5": true,
-	".hdf5": true, ".feather": true,
+	".pb": true, ".pyc": true, ".pyo": true, ".npy": true, ".npz": true,
+	".pkl": true, ".h5": true, ".hdf5": true, ".feather": true,
 	// ディスクイメージ / DB
 	".iso": true, ".dmg": true, ".img": true,
 	".db": true, ".sqlite": true, ".sqlite3": true, ".mdb": true,
 }
 
 // PreviewResponse は S3 / GCS オブジェクトプレビューの共通レスポンス。
+// テキストは Content に入れ、表形式として読める形式 (Parquet / Avro / ORC と CSV / TSV / NDJSON) は
+// Table にスキーマと先頭の行を入れる。CSV / TSV / NDJSON は Content と Table の両方を返す。
 type PreviewResponse struct {
 	Content     string `json:"content"`
 	ContentType string `json:"content_type"`
-	Size        int64  `json:"size"`
+	// Size はオブジェクトのサイズ。圧縮されたオブジェクトは圧縮後のサイズ。
+	Size int64 `json:"size"`
+	// Encoding は展開した圧縮形式 (gzip / zstd)。
+	Encoding string `json:"encoding,omitempty"`
+	// Truncated は展開後のテキストが maxPreviewSize を超えたため、Content を先頭の行だけにしたことを示す。
+	Truncated bool           `json:"truncated,omitempty"`
+	Table     *preview.Table `json:"table,omitempty"`
 }
 
 // previewExtensionAllowed は key の拡張子 (最終セグメントのみ、大文字小文字を区別しない) が
 // プレビュー対象になりうるかを判定する。既知のバイナリ拡張子ならプレビュー不可 (false)、
 // それ以外 (拡張子なしを含む) はテキストの可能性ありとして true を返す。中身がバイナリな
-// テキスト拡張子は buildPreviewResponse の中身検査が最終的に弾く。"file.txt.gz" のような
-// 多重拡張子は最後の拡張子 (.gz) のみで判定する。
+// テキスト拡張子は buildPreviewResponse の中身検査が最終的に弾く。"file.png.gz" のように
+// gzip / zstd で圧縮されたものは、圧縮の拡張子を除いた内側の拡張子 (.png) で判定する。
 func previewExtensionAllowed(key string) bool {
-	return !previewBinaryExtensions[strings.ToLower(filepath.Ext(key))]
+	ext := strings.ToLower(filepath.Ext(key))
+	if compression, _ := preview.Detect(key); compression != preview.CompressionNone {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(key, filepath.Ext(key))))
+	}
+	return !previewBinaryExtensions[ext]
+}
+
+// previewRows は ?rows= (表形式のプレビューで返す行数) を読む。未指定なら defaultPreviewRows。
+func previewRows(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("rows")
+	if v == "" {
+		return defaultPreviewRows, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxPreviewRows {
+		return 0, fmt.Errorf("rows must be between 1 and %d", maxPreviewRows)
+	}
+	return n, nil
 }
 
 // previewSizeAllowed は size (メタデータ由来のオブジェクトサイズ) が maxPreviewSize 未満かを
@@ -94,7 +131,7 @@ func writePreviewNotText(w http.ResponseWriter) {
 
 // readPreviewBody は r から最大 maxPreviewSize+1 バイトを読み込み、上限超過を検出する。
 // メタデータ (ContentLength 等) が信頼できず実体がそれより大きいケースへの防御であり、
-// 上限を超えた場合は errPreviewTooLarge を返す (readS3UploadBody と同じパターン)。
+// 上限を超えた場合は errPreviewTooLarge を返す。
 func readPreviewBody(r io.Reader) ([]byte, error) {
 	body, err := io.ReadAll(io.LimitReader(r, maxPreviewSize+1))
 	if err != nil {
@@ -130,14 +167,185 @@ func buildPreviewResponse(body io.Reader, contentType string) (*PreviewResponse,
 // NUL バイトを含む) ことを示す。
 var errPreviewNotText = fmt.Errorf("object content appears to be binary")
 
-// writePreviewError は buildPreviewResponse が返したエラーを適切な HTTP レスポンスへ変換する。
+// writePreviewError は buildPreviewResponse / buildObjectPreview が返したエラーを適切な HTTP レスポンスへ変換する。
 func writePreviewError(w http.ResponseWriter, err error) {
 	switch {
 	case errors.Is(err, errPreviewTooLarge):
 		writePreviewTooLarge(w)
 	case errors.Is(err, errPreviewNotText):
 		writePreviewNotText(w)
+	case errors.Is(err, preview.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "PREVIEW_TOO_LARGE", err.Error())
+	case errors.Is(err, preview.ErrUnsupported):
+		writeError(w, http.StatusUnprocessableEntity, "PREVIEW_UNSUPPORTED_FORMAT", err.Error())
+	case errors.Is(err, preview.ErrInvalid):
+		writeError(w, http.StatusUnprocessableEntity, "PREVIEW_INVALID_FORMAT", err.Error())
 	default:
 		writeInternalError(w, err.Error())
 	}
 }
+
+// previewObject はプレビューのために開いたオブジェクト。先頭から読むストリーム (body) か、範囲リクエストで
+// 読む readerAt のどちらかを持つ。size はオブジェクトのサイズで、不明なら -1。
+type previewObject struct {
+	body        io.Reader
+	readerAt    io.ReaderAt
+	contentType string
+	size        int64
+	close       func() error
+}
+
+// previewSource は S3 / GCS のハンドラが writeObjectPreview に渡す、オブジェクトの開き方とエラーの書き方。
+type previewSource struct {
+	// open はオブジェクトを先頭から読むストリームとして開く。
+	open func(ctx context.Context) (*previewObject, error)
+	// openAt はオブジェクトを範囲リクエストで読む io.ReaderAt として開く (Parquet / ORC 用)。
+	openAt func(ctx context.Context) (*previewObject, error)
+	// writeError は open / openAt が返したクラウド API のエラーを書く (writeAWSError / writeGCPError)。
+	writeError func(w http.ResponseWriter, err error)
+}
+
+// writeObjectPreview は S3 / GCS 共通のプレビューの流れ (拡張子ガード → ?rows= の検証 → 形式の判定 →
+// オブジェクトを開く → PreviewResponse の組み立て) を実行する。Parquet / ORC はメタデータと先頭の行だけを
+// 範囲リクエストで読み、それ以外は先頭から読む。
+func writeObjectPreview(w http.ResponseWriter, r *http.Request, key string, src previewSource) {
+	if !previewExtensionAllowed(key) {
+		writePreviewUnsupportedType(w)
+		return
+	}
+	rows, err := previewRows(r)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	compression, format := preview.Detect(key)
+	open := src.open
+	if compression == preview.CompressionNone && (format == preview.FormatParquet || format == preview.FormatORC) {
+		open = src.openAt
+	}
+	obj, err := open(r.Context())
+	if err != nil {
+		src.writeError(w, err)
+		return
+	}
+	defer obj.close()
+
+	resp, err := buildObjectPreview(obj, compression, format, rows)
+	if err != nil {
+		writePreviewError(w, err)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// buildObjectPreview は key から判定した圧縮形式 compression と表形式 format に応じて obj を読み、
+// PreviewResponse を組み立てる。表は先頭の rows 行まで。
+func buildObjectPreview(obj *previewObject, compression, format string, rows int) (*PreviewResponse, error) {
+	resp := &PreviewResponse{ContentType: obj.contentType, Size: obj.size, Encoding: compression}
+	var err error
+	switch format {
+	case preview.FormatParquet, preview.FormatORC:
+		if resp.Table, err = readColumnarPreview(obj, compression, format, rows); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	case preview.FormatAvro:
+		body, err := preview.Decompress(obj.body, compression)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		if resp.Table, err = preview.ReadAvro(body, rows); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if compression == preview.CompressionNone {
+		if obj.size >= 0 && !previewSizeAllowed(obj.size) {
+			return nil, errPreviewTooLarge
+		}
+		text, err := buildPreviewResponse(obj.body, obj.contentType)
+		if err != nil {
+			return nil, err
+		}
+		resp = text
+	} else {
+		data, truncated, err := readDecompressedText(obj.body, compression)
+		if err != nil {
+			return nil, err
+		}
+		resp.Content, resp.Truncated = string(data), truncated
+	}
+	if format == preview.FormatText {
+		return resp, nil
+	}
+
+	var table *preview.Table
+	switch format {
+	case preview.FormatCSV:
+		table, err = preview.ReadDelimited([]byte(resp.Content), ',', rows)
+	case preview.FormatTSV:
+		table, err = preview.ReadDelimited([]byte(resp.Content), '\t', rows)
+	case preview.FormatNDJSON:
+		table, err = preview.ReadNDJSON([]byte(resp.Content), rows)
+	}
+	// 表として読めないテキスト (拡張子と中身が合わない等) は Content だけを返す。
+	if err == nil {
+		table.Truncated = table.Truncated || resp.Truncated
+		resp.Table = table
+	}
+	return resp, nil
+}
+
+// readColumnarPreview は Parquet / ORC の先頭の rows 行を表にする。圧縮されたファイルは範囲で読めないため、
+// 展開した内容を preview.MaxReadBytes までメモリに読んでから読む。
+func readColumnarPreview(obj *previewObject, compression, format string, rows int) (*preview.Table, error) {
+	ra, size := obj.readerAt, obj.size
+	if ra == nil {
+		body, err := preview.Decompress(obj.body, compression)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		data, err := io.ReadAll(io.LimitReader(body, preview.MaxReadBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", format, err)
+		}
+		if len(data) > preview.MaxReadBytes {
+			return nil, fmt.Errorf("%s %w", compression, preview.ErrTooLarge)
+		}
+		ra, size = bytes.NewReader(data), int64(len(data))
+	}
+	if format == preview.FormatORC {
+		return preview.ReadORC(ra, size, rows)
+	}
+	return preview.ReadParquet(ra, size, rows)
+}
+
+// readDecompressedText は圧縮された body を展開し、先頭 maxPreviewSize バイトまでをテキストとして返す。
+// 展開後のサイズはメタデータからはわからないため、上限を超えた場合はエラーにせず、最後の改行までに
+// 切り詰めて truncated を true にする (CSV / NDJSON の行を途中で切らない)。
+func readDecompressedText(body io.Reader, compression string) (data []byte, truncated bool, err error) {
+	r, err := preview.Decompress(body, compression)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+	data, err = io.ReadAll(io.LimitReader(r, maxPreviewSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("decompress %s: %w", compression, err)
+	}
+	if len(data) > maxPreviewSize {
+		i := bytes.LastIndexByte(data[:maxPreviewSize], '\n')
+		if i < 0 {
+			return nil, false, errPreviewTooLarge
+		}
+		data, truncated = data[:i+1], true
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return nil, false, errPreviewNotText
+	}
+	return data, truncated, nil
+}
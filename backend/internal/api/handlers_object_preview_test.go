@@ -2,9 +2,21 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/sfuruya0612/thief/backend/internal/preview"
 )
 
 func TestPreviewExtensionAllowed(t *testing.T) {
@@ -24,7 +36,12 @@ func TestPreviewExtensionAllowed(t *testing.T) {
 		{name: "binary extension png rejected", key: "image.png", want: false},
 		{name: "binary extension png uppercase rejected", key: "IMAGE.PNG", want: false},
 		{name: "binary extension pdf rejected", key: "doc.pdf", want: false},
-		{name: "archive gz rejected via last extension", key: "archive.json.gz", want: false},
+		{name: "gzip judged by inner extension", key: "archive.json.gz", want: true},
+		{name: "zstd judged by inner extension", key: "logs/app.ndjson.zst", want: true},
+		{name: "compressed binary rejected via inner extension", key: "image.png.gz", want: false},
+		{name: "bare gz is allowed", key: "archive.gz", want: true},
+		{name: "parquet is allowed", key: "data/part-0000.parquet", want: true},
+		{name: "orc uppercase is allowed", key: "DATA.ORC", want: true},
 		{name: "text remains allowed via last extension", key: "archive.gz.json", want: true},
 		{name: "trailing dot is allowed", key: "weird.", want: true},
 	}
@@ -170,3 +187,250 @@ func TestBuildPreviewResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestPreviewRows(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{query: "", want: defaultPreviewRows},
+		{query: "rows=1", want: 1},
+		{query: "rows=1000", want: maxPreviewRows},
+		{query: "rows=0", wantErr: true},
+		{query: "rows=1001", wantErr: true},
+		{query: "rows=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/preview?"+tt.query, nil)
+			got, err := previewRows(r)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("previewRows(%q) = %d, %v, want %d (err %v)", tt.query, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuildObjectPreview(t *testing.T) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ndjson := []byte("{\"id\": 1, \"name\": \"alice\"}\n{\"id\": 2, \"name\": \"bob\"}\n")
+	tsv := []byte("id\tname\n1\talice\n")
+	// 展開後に maxPreviewSize を超える CSV。最後の改行で切り詰められる。
+	var large bytes.Buffer
+	large.WriteString("id,value\n")
+	for i := 0; large.Len() <= maxPreviewSize; i++ {
+		large.WriteString("1,aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n")
+	}
+	largeGzip := gzipBytes(t, large.Bytes())
+
+	tests := []struct {
+		name        string
+		body        []byte
+		size        int64
+		compression string
+		format      string
+		rows        int
+		want        *PreviewResponse
+		wantErr     error
+	}{
+		{
+			name:        "gzip ndjson as table",
+			body:        gzipBytes(t, ndjson),
+			compression: preview.CompressionGzip,
+			format:      preview.FormatNDJSON,
+			rows:        1,
+			want: &PreviewResponse{
+				Content:  string(ndjson),
+				Encoding: preview.CompressionGzip,
+				Table: &preview.Table{
+					Format:    preview.FormatNDJSON,
+					Columns:   []preview.Column{{Name: "id", Type: "number"}, {Name: "name", Type: "string"}},
+					Rows:      [][]any{{json.Number("1"), "alice"}},
+					Truncated: true,
+				},
+			},
+		},
+		{
+			name:        "zstd tsv as table",
+			body:        enc.EncodeAll(tsv, nil),
+			compression: preview.CompressionZstd,
+			format:      preview.FormatTSV,
+			rows:        10,
+			want: &PreviewResponse{
+				Content:  string(tsv),
+				Encoding: preview.CompressionZstd,
+				Table: &preview.Table{
+					Format:  preview.FormatTSV,
+					Columns: []preview.Column{{Name: "id", Type: "string"}, {Name: "name", Type: "string"}},
+					Rows:    [][]any{{"1", "alice"}},
+				},
+			},
+		},
+		{
+			name:        "gzip text without table",
+			body:        gzipBytes(t, []byte("hello\n")),
+			compression: preview.CompressionGzip,
+			want:        &PreviewResponse{Content: "hello\n", Encoding: preview.CompressionGzip},
+		},
+		{
+			name:   "ndjson that is not json keeps content only",
+			body:   []byte("not json\n"),
+			format: preview.FormatNDJSON,
+			rows:   10,
+			want:   &PreviewResponse{Content: "not json\n"},
+		},
+		{
+			name:        "gzip of binary",
+			body:        gzipBytes(t, []byte{0x00, 0x01, 0x02}),
+			compression: preview.CompressionGzip,
+			wantErr:     errPreviewNotText,
+		},
+		{
+			name:        "not gzip",
+			body:        []byte("plain"),
+			compression: preview.CompressionGzip,
+			wantErr:     preview.ErrInvalid,
+		},
+		{
+			name:    "uncompressed text over max size by metadata",
+			body:    []byte("small"),
+			size:    maxPreviewSize,
+			wantErr: errPreviewTooLarge,
+		},
+		{
+			name:    "avro that is not avro",
+			body:    []byte("PAR1"),
+			format:  preview.FormatAvro,
+			rows:    10,
+			wantErr: preview.ErrInvalid,
+		},
+		{
+			name:    "parquet that is not parquet",
+			body:    []byte("id,name\n1,alice\n"),
+			format:  preview.FormatParquet,
+			rows:    10,
+			wantErr: preview.ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.size
+			if size == 0 {
+				size = int64(len(tt.body))
+			}
+			obj := &previewObject{body: bytes.NewReader(tt.body), size: size, close: func() error { return nil }}
+			if tt.format == preview.FormatParquet || tt.format == preview.FormatORC {
+				obj.body, obj.readerAt = nil, bytes.NewReader(tt.body)
+			}
+			got, err := buildObjectPreview(obj, tt.compression, tt.format, tt.rows)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			tt.want.Size = size
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("buildObjectPreview mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("large gzip csv truncated at last newline", func(t *testing.T) {
+		obj := &previewObject{body: bytes.NewReader(largeGzip), size: int64(len(largeGzip)), close: func() error { return nil }}
+		got, err := buildObjectPreview(obj, preview.CompressionGzip, preview.FormatCSV, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Truncated || len(got.Content) > maxPreviewSize || !strings.HasSuffix(got.Content, "\n") {
+			t.Errorf("truncated = %v, content of %d bytes, want truncated content ending with a newline", got.Truncated, len(got.Content))
+		}
+		if got.Table == nil || len(got.Table.Rows) != 5 || !got.Table.Truncated {
+			t.Errorf("table = %+v, want 5 truncated rows", got.Table)
+		}
+	})
+}
+
+func TestWritePreviewError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{name: "too large", err: errPreviewTooLarge, wantCode: http.StatusRequestEntityTooLarge, wantBody: "PREVIEW_TOO_LARGE"},
+		{name: "not text", err: errPreviewNotText, wantCode: http.StatusUnprocessableEntity, wantBody: "PREVIEW_NOT_TEXT"},
+		{name: "columnar read over budget", err: fmt.Errorf("parquet column a: %w", preview.ErrTooLarge), wantCode: http.StatusRequestEntityTooLarge, wantBody: "PREVIEW_TOO_LARGE"},
+		{name: "unsupported codec", err: fmt.Errorf("parquet codec LZO: %w", preview.ErrUnsupported), wantCode: http.StatusUnprocessableEntity, wantBody: "PREVIEW_UNSUPPORTED_FORMAT"},
+		{name: "broken file", err: fmt.Errorf("orc: %w: missing magic", preview.ErrInvalid), wantCode: http.StatusUnprocessableEntity, wantBody: "PREVIEW_INVALID_FORMAT"},
+		{name: "other", err: io.ErrClosedPipe, wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writePreviewError(w, tt.err)
+			if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %s, want %d containing %q", w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWriteObjectPreview(t *testing.T) {
+	var opened string
+	src := previewSource{
+		open: func(context.Context) (*previewObject, error) {
+			opened = "open"
+			body := []byte("id,name\n1,alice\n")
+			return &previewObject{body: bytes.NewReader(body), contentType: "text/csv", size: int64(len(body)), close: func() error { return nil }}, nil
+		},
+		openAt: func(context.Context) (*previewObject, error) {
+			opened = "openAt"
+			return nil, errors.New("access denied")
+		},
+		writeError: func(w http.ResponseWriter, err error) {
+			writeError(w, http.StatusForbidden, "ACCESS_DENIED", err.Error())
+		},
+	}
+	tests := []struct {
+		name       string
+		key        string
+		query      string
+		wantOpened string
+		wantCode   int
+	}{
+		{name: "csv is read as a stream", key: "export.csv", wantOpened: "open", wantCode: http.StatusOK},
+		{name: "parquet is read by range", key: "part-0000.parquet", wantOpened: "openAt", wantCode: http.StatusForbidden},
+		{name: "compressed parquet is read as a stream", key: "part-0000.parquet.gz", wantOpened: "open", wantCode: http.StatusUnprocessableEntity},
+		{name: "binary extension", key: "image.png", wantCode: http.StatusBadRequest},
+		{name: "invalid rows", key: "export.csv", query: "&rows=0", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/preview?key="+tt.key+tt.query, nil)
+			writeObjectPreview(w, r, tt.key, src)
+			if opened != tt.wantOpened || w.Code != tt.wantCode {
+				t.Errorf("opened %q with %d %s, want %q with %d", opened, w.Code, w.Body.String(), tt.wantOpened, tt.wantCode)
+			}
+		})
+	}
+}
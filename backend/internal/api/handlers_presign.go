@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/gcp"
+)
+
+// maxPresignExpiresIn は expires_in (秒) の上限。S3 (SigV4) と GCS (V4 署名) の署名付き URL はどちらも 7 日まで。
+const maxPresignExpiresIn = 7 * 24 * 60 * 60
+
+// PresignRequest は handleS3ObjectPresign / handleGCPGCSObjectSign のリクエストボディ。
+// Method は GET / PUT / HEAD / DELETE (省略時 GET)、ExpiresIn は有効期限の秒数 (省略時 15 分)。
+type PresignRequest struct {
+	Key       string `json:"key"`
+	Method    string `json:"method"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// decodePresignRequest はリクエストボディを読み、key と expires_in の範囲を検証する。
+// 不正な場合は 400 を書き込み false を返す。メソッドの検証は各クラウドの署名関数に任せる。
+func decodePresignRequest(w http.ResponseWriter, r *http.Request) (PresignRequest, time.Duration, bool) {
+	var req PresignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request body: "+err.Error())
+		return req, 0, false
+	}
+	if r.PathValue("bucket") == "" || req.Key == "" {
+		writeBadRequest(w, "bucket and key are required")
+		return req, 0, false
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxPresignExpiresIn {
+		writeBadRequest(w, "expires_in must be between 1 and 604800 seconds")
+		return req, 0, false
+	}
+	return req, time.Duration(req.ExpiresIn) * time.Second, true
+}
+
+// handleS3ObjectPresign は S3 オブジェクトの署名付き URL を発行する。URL は毎回異なるためキャッシュしない。
+func (s *Server) handleS3ObjectPresign(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	req, expires, ok := decodePresignRequest(w, r)
+	if !ok {
+		return
+	}
+
+	presigned, err := awsinternal.PresignS3Object(r.Context(), profile, region, r.PathValue("bucket"), req.Key, req.Method, expires)
+	if err != nil {
+		if errors.Is(err, awsinternal.ErrS3InvalidPresign) {
+			writeBadRequest(w, err.Error())
+			return
+		}
+		writeAWSError(w, err)
+		return
+	}
+	writeJSON(w, presigned)
+}
+
+// handleGCPGCSObjectSign は GCS オブジェクトの V4 署名付き URL を発行する。URL は毎回異なるためキャッシュしない。
+func (s *Server) handleGCPGCSObjectSign(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := s.gcpProjectIDFromQuery(w, r)
+	if !ok {
+		return
+	}
+	req, expires, ok := decodePresignRequest(w, r)
+	if !ok {
+		return
+	}
+
+	signed, err := gcp.SignObjectURL(r.Context(), projectID, r.PathValue("bucket"), req.Key, req.Method, expires)
+	if err != nil {
+		if errors.Is(err, gcp.ErrInvalidSignedURL) {
+			writeBadRequest(w, err.Error())
+			return
+		}
+		writeGCPError(w, err)
+		return
+	}
+	writeJSON(w, signed)
+}
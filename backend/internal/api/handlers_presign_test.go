@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandleS3ObjectPresignBadRequest はクライアントを作る前に弾かれるリクエストを検証する (AWS を呼ばない)。
+func TestHandleS3ObjectPresignBadRequest(t *testing.T) {
+	s := newTestServer(t)
+	for _, body := range []string{
+		`not json`,
+		`{}`,
+		`{"key":"a.txt","expires_in":-1}`,
+		`{"key":"a.txt","expires_in":604801}`,
+		`{"key":"a.txt","method":"POST"}`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/aws/profiles/prod/s3/bucket/objects/presign", strings.NewReader(body))
+		r.SetPathValue("profile", "prod")
+		r.SetPathValue("bucket", "bucket")
+		w := httptest.NewRecorder()
+		s.handleS3ObjectPresign(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s status = %d, want %d (body %s)", body, w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	}
+}
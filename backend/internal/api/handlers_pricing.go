@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	"sync"
 	"time"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
@@ -78,9 +80,25 @@ func (s *Server) handlePricing(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var fetchErr error // GetPricing 由来のエラーかどうかをキャッシュ I/O エラーと区別する
-	data, err := pricecache.Fetch(dir, service, region, func() ([]byte, error) {
-		table, gerr := awsinternal.GetPricing(r.Context(), profile, region, service)
+	data, fetchErr, err := fetchPriceTable(r.Context(), dir, profile, service, region, !isLiveOnly)
+	if err != nil {
+		if fetchErr != nil {
+			writePricingError(w, fetchErr)
+			return
+		}
+		slog.Error("persist price cache failed", "service", service, "region", region, "err", err)
+		writeInternalError(w, "failed to persist price cache")
+		return
+	}
+	writeJSONBytes(w, data)
+}
+
+// fetchPriceTable は GetPricing を pricecache.Fetch の singleflight 下で実行し、
+// persist が true なら結果をディスクキャッシュへ保存する。fetchErr は GetPricing
+// 由来のエラーのときだけ非 nil になり、呼び出し側がキャッシュ I/O エラーと区別できる。
+func fetchPriceTable(ctx context.Context, dir, profile, service, region string, persist bool) (data []byte, fetchErr, err error) {
+	data, err = pricecache.Fetch(dir, service, region, func() ([]byte, error) {
+		table, gerr := awsinternal.GetPricing(ctx, profile, region, service)
 		if gerr != nil {
 			fetchErr = gerr
 			return nil, gerr
@@ -90,23 +108,138 @@ func (s *Server) handlePricing(w http.ResponseWriter, r *http.Request) {
 		if merr != nil {
 			return nil, merr
 		}
-		if !isLiveOnly {
+		if persist {
 			if serr := pricecache.Save(dir, service, region, payload, table.FetchedAt); serr != nil {
 				return nil, serr
 			}
 		}
 		return payload, nil
 	})
+	return data, fetchErr, err
+}
+
+// priceTableForEstimate は一覧ハンドラが CostMonthly の推定に使う On-Demand レート表を
+// 返す。handlePricing と同じディスクキャッシュ (pricecache) だけを読み、未取得なら
+// バックグラウンドで取得を始めて nil を返す (Price List のページングを伴う取得で一覧の
+// 応答を待たせないため。取得できたら推定のない一覧のキャッシュを捨て、次の一覧から推定が付く)。推定は付加情報であり、
+// pricing:GetProducts 権限が無いロール等で取得に失敗しても一覧自体は返したいため、
+// エラーはログに残して nil を返す (呼び出し側の CostMonthly は 0 のまま)。
+func (s *Server) priceTableForEstimate(profile, region, service string) *awsinternal.PriceTable {
+	if err := pricecache.ValidateRegion(region); err != nil {
+		return nil
+	}
+	dir := pricingCacheDir(s.cfg.PriceCacheDir)
+	data, _, ok, err := pricecache.Load(dir, service, region)
 	if err != nil {
-		if fetchErr != nil {
-			writePricingError(w, fetchErr)
-			return
-		}
-		slog.Error("persist price cache failed", "service", service, "region", region, "err", err)
-		writeInternalError(w, "failed to persist price cache")
+		slog.Warn("load price cache for cost estimate failed", "service", service, "region", region, "err", err)
+		return nil
+	}
+	if !ok {
+		s.estimatePrices.start(profile, service, region)
+		return nil
+	}
+	var table awsinternal.PriceTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		slog.Warn("decode price table for cost estimate failed", "service", service, "region", region, "err", err)
+		return nil
+	}
+	return &table
+}
+
+// estimateListServices は推定用のレート表 (GetPricing の service) ごとに、そのレート表で CostMonthly を
+// 設定する一覧 (resourceCache のキーの service)。
+var estimateListServices = map[string][]string{
+	"ec2":         {"ec2"},
+	"rds":         {"rds"},
+	"elasticache": {"elasticache"},
+	"kinesis":     {"kinesis"},
+	"waf":         {"waf"},
+	"natgw":       {"natgw"},
+	"ecr":         {"ecr-images"},
+	"cwlogs":      {"cwlogs-groups"},
+}
+
+// invalidateEstimatedLists は profile/region の service のレート表を取得できたときに、そのレート表なしで
+// 取得した一覧のキャッシュ (リポジトリごとの ECR イメージのようにキーが続くものを含む) を捨てる。
+// 捨てないと cacheTTL が切れるまで CostMonthly が 0 の一覧を返し続けるため。
+func (s *Server) invalidateEstimatedLists(profile, service, region string) {
+	for _, list := range estimateListServices[service] {
+		key := cacheKey(list, profile, region)
+		s.resourceCache.Invalidate(key)
+		s.resourceCache.InvalidatePrefix(key + ":")
+	}
+}
+
+// estimatePriceRetryInterval は推定用のレート表の取得に失敗したあと、同じ profile/service/region
+// で再び取得を試みるまでの間隔。権限の無いロール等で一覧を開くたびに取得が走らないようにする。
+const estimatePriceRetryInterval = 10 * time.Minute
+
+// estimatePriceFetchTimeout は推定用のレート表をバックグラウンドで取得するときの上限時間。
+const estimatePriceFetchTimeout = 5 * time.Minute
+
+// estimatePriceFetcher は CostMonthly の推定に使うレート表のバックグラウンド取得を管理する。
+// 同じ profile/service/region の取得は同時に 1 つだけ走らせ、失敗した組は
+// estimatePriceRetryInterval の間取得し直さない。
+type estimatePriceFetcher struct {
+	mu       sync.Mutex
+	inflight map[string]bool
+	failedAt map[string]time.Time
+	wg       sync.WaitGroup
+
+	// fetch はレート表を取得してディスクキャッシュへ保存する。テストではフェイクに差し替える。
+	fetch func(ctx context.Context, profile, service, region string) error
+	// fetched は取得に成功したあとに呼ばれる (nil なら何もしない)。
+	fetched func(profile, service, region string)
+	now     func() time.Time
+}
+
+func newEstimatePriceFetcher(dir string, fetched func(profile, service, region string)) *estimatePriceFetcher {
+	return &estimatePriceFetcher{
+		fetched:  fetched,
+		inflight: map[string]bool{},
+		failedAt: map[string]time.Time{},
+		fetch: func(ctx context.Context, profile, service, region string) error {
+			_, _, err := fetchPriceTable(ctx, dir, profile, service, region, true)
+			return err
+		},
+		now: time.Now,
+	}
+}
+
+// start は profile/service/region のレート表の取得をバックグラウンドで始める。取得中、または
+// 直近に失敗している組では何もしない。取得はリクエストの context から切り離して行う。
+func (f *estimatePriceFetcher) start(profile, service, region string) {
+	key := profile + "\x00" + service + "\x00" + region
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.inflight[key] {
 		return
 	}
-	writeJSONBytes(w, data)
+	if at, ok := f.failedAt[key]; ok && f.now().Sub(at) < estimatePriceRetryInterval {
+		return
+	}
+	f.inflight[key] = true
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), estimatePriceFetchTimeout)
+		defer cancel()
+		err := f.fetch(ctx, profile, service, region)
+
+		f.mu.Lock()
+		delete(f.inflight, key)
+		if err != nil {
+			slog.Warn("fetch pricing for cost estimate failed", "service", service, "region", region, "err", err)
+			f.failedAt[key] = f.now()
+			f.mu.Unlock()
+			return
+		}
+		delete(f.failedAt, key)
+		f.mu.Unlock()
+		if f.fetched != nil {
+			f.fetched(profile, service, region)
+		}
+	}()
 }
 
 func writeJSONBytes(w http.ResponseWriter, data []byte) {
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 	"github.com/sfuruya0612/thief/backend/internal/pricecache"
 )
 
@@ -151,3 +154,97 @@ func TestHandlePricingCacheIOErrorIsRedacted(t *testing.T) {
 		t.Errorf("error message %q leaks the cache directory path %q", got, s.cfg.PriceCacheDir)
 	}
 }
+
+func TestPriceTableForEstimateReadsDiskCache(t *testing.T) {
+	s := newTestServer(t)
+	data := []byte(`{"service":"ec2","region":"ap-northeast-1","fetched_at":"2026-07-18T09:00:00Z","license_unresolved":false,"rates":[{"rate_id":"r1","model":"on_demand","group":"On-Demand","label":"t3.micro","attributes":{"instance_type":"t3.micro","os":"Linux","tenancy":"Shared","license_model":"No License required"},"term":{"lease":null,"offering_class":null,"payment":null},"unit":"Hrs","price_usd":0.0136,"upfront_usd":0,"currency":"USD"}]}`)
+	if err := pricecache.Save(pricingCacheDir(s.cfg.PriceCacheDir), "ec2", "ap-northeast-1", data, time.Now()); err != nil {
+		t.Fatalf("pricecache.Save() err = %v", err)
+	}
+
+	// キャッシュヒットする限り AWS 呼び出しは発生しない (認証情報の無い profile でも成功する)。
+	table := s.priceTableForEstimate("default", "ap-northeast-1", "ec2")
+	if table == nil {
+		t.Fatal("priceTableForEstimate() = nil, want cached table")
+	}
+	if len(table.Rates) != 1 || table.Rates[0].PriceUSD != 0.0136 {
+		t.Errorf("rates = %+v, want the cached t3.micro rate", table.Rates)
+	}
+}
+
+func TestPriceTableForEstimateInvalidRegion(t *testing.T) {
+	s := newTestServer(t)
+	if table := s.priceTableForEstimate("default", "../etc", "ec2"); table != nil {
+		t.Errorf("priceTableForEstimate() = %+v, want nil", table)
+	}
+}
+
+func TestPriceTableForEstimateFetchesInBackground(t *testing.T) {
+	s := newTestServer(t)
+	dir := pricingCacheDir(s.cfg.PriceCacheDir)
+	now := time.Date(2026, 7, 18, 9, 0, 0, 0, time.UTC)
+	s.estimatePrices.now = func() time.Time { return now }
+	calls := 0
+	fail := true
+	s.estimatePrices.fetch = func(_ context.Context, _, service, region string) error {
+		calls++
+		if fail {
+			return errors.New("AccessDeniedException")
+		}
+		return pricecache.Save(dir, service, region, []byte(`{"service":"ec2","region":"ap-northeast-1","rates":[]}`), now)
+	}
+	get := func() *awsinternal.PriceTable {
+		t.Helper()
+		table := s.priceTableForEstimate("default", "ap-northeast-1", "ec2")
+		s.estimatePrices.wg.Wait()
+		return table
+	}
+
+	// キャッシュに無ければ取得を待たずに nil を返す。
+	if table := get(); table != nil || calls != 1 {
+		t.Fatalf("first call = %+v (fetch calls %d), want nil after 1 fetch", table, calls)
+	}
+	// 失敗は estimatePriceRetryInterval の間記録し、取得し直さない。
+	if table := get(); table != nil || calls != 1 {
+		t.Fatalf("call after failure = %+v (fetch calls %d), want nil without fetching", table, calls)
+	}
+	now = now.Add(estimatePriceRetryInterval)
+	fail = false
+	if table := get(); table != nil || calls != 2 {
+		t.Fatalf("call after retry interval = %+v (fetch calls %d), want nil after 2 fetches", table, calls)
+	}
+	// バックグラウンドで保存したレート表を次の呼び出しから返す。
+	if table := get(); table == nil || table.Service != "ec2" || calls != 2 {
+		t.Errorf("call after fetch = %+v (fetch calls %d), want the saved table", table, calls)
+	}
+}
+
+// レート表を取得できたら、そのレート表なしで取得した同じ profile/region の一覧のキャッシュを捨てる。
+func TestPriceTableForEstimateInvalidatesLists(t *testing.T) {
+	s := newTestServer(t)
+	dir := pricingCacheDir(s.cfg.PriceCacheDir)
+	s.estimatePrices.fetch = func(_ context.Context, _, service, region string) error {
+		return pricecache.Save(dir, service, region, []byte(`{"service":"ecr","region":"ap-northeast-1","rates":[]}`), time.Now())
+	}
+	keys := []string{
+		cacheKey("ecr-images", "default", "ap-northeast-1"),
+		cacheKey("ecr-images", "default", "ap-northeast-1", "app"),
+		cacheKey("ecr-images", "other", "ap-northeast-1"),
+		cacheKey("ecr", "default", "ap-northeast-1"),
+	}
+	for _, key := range keys {
+		s.resourceCache.Set(key, []string{}, time.Hour)
+	}
+
+	if table := s.priceTableForEstimate("default", "ap-northeast-1", "ecr"); table != nil {
+		t.Fatalf("priceTableForEstimate() = %+v, want nil before the fetch", table)
+	}
+	s.estimatePrices.wg.Wait()
+
+	for i, key := range keys {
+		_, ok := s.resourceCache.Get(key)
+		if want := i >= 2; ok != want {
+			t.Errorf("cached %s = %v, want %v", key, ok, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/sfuruya0612/thief/backend/internal/recording"
+)
+
+// recordingReplayMaxSpeed は再生倍率の上限。
+const recordingReplayMaxSpeed = 32
+
+// recordingReplayDefaultMaxIdle はイベント間の待ち時間の既定上限。長時間の無操作を詰めて再生する。
+const recordingReplayDefaultMaxIdle = 2 * time.Second
+
+// replayControlMessage は再生 WebSocket の TEXT (JSON) 制御メッセージ。
+// フレーム規約はセッションブリッジ (session.Bridge) と同じで、ブラウザの Terminal コンポーネントで
+// そのまま再生できる (BINARY = 端末出力、TEXT = resize / exit / error)。
+type replayControlMessage struct {
+	Type    string `json:"type"`
+	Cols    uint32 `json:"cols,omitempty"`
+	Rows    uint32 `json:"rows,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// handleRecordingsList は保存済みのセッション記録を開始日時の降順で返す。
+func (s *Server) handleRecordingsList(w http.ResponseWriter, r *http.Request) {
+	items, err := s.recordings.List()
+	if err != nil {
+		writeRecordingError(w, err)
+		return
+	}
+	writeJSON(w, items)
+}
+
+// handleRecordingDownload はセッション記録を asciicast v2 ファイル (<id>.cast) としてダウンロードさせる。
+func (s *Server) handleRecordingDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	f, rec, err := s.recordings.Open(id)
+	if err != nil {
+		writeRecordingError(w, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename=%q`, id+".cast"))
+	http.ServeContent(w, r, id+".cast", rec.UpdatedAt, f)
+}
+
+// handleRecordingReplay はセッション記録を WebSocket で元のタイミングのまま再生する。
+// クエリ speed (再生倍率、既定 1) と max_idle (イベント間の待ち時間の上限秒数、既定 2、0 で上限なし) を受け付ける。
+// 入力イベント ("i") は端末出力側にエコーとして含まれるため送らない。
+func (s *Server) handleRecordingReplay(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseReplayOptions(r)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	f, _, err := s.recordings.Open(r.PathValue("id"))
+	if err != nil {
+		writeRecordingError(w, err)
+		return
+	}
+	defer f.Close()
+	reader, err := recording.NewReader(f)
+	if err != nil {
+		writeRecordingError(w, err)
+		return
+	}
+
+	browser, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: s.cfg.WebOrigins,
+	})
+	if err != nil {
+		slog.Warn("failed to accept browser websocket", "err", err)
+		return
+	}
+	defer browser.CloseNow()
+
+	// ブラウザからのメッセージ (キー入力等) は不要。CloseRead はブラウザの切断で ctx をキャンセルする。
+	ctx := browser.CloseRead(r.Context())
+
+	writeControl := func(msg replayControlMessage) error {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("marshal replay control message: %w", err)
+		}
+		return browser.Write(ctx, websocket.MessageText, payload)
+	}
+	if err := writeControl(replayControlMessage{Type: "resize", Cols: reader.Header.Width, Rows: reader.Header.Height}); err != nil {
+		return
+	}
+
+	err = recording.Replay(ctx, reader, opts, func(ev recording.Event) error {
+		switch ev.Code {
+		case recording.EventOutput:
+			return browser.Write(ctx, websocket.MessageBinary, []byte(ev.Data))
+		case recording.EventResize:
+			var cols, rows uint32
+			if _, err := fmt.Sscanf(ev.Data, "%dx%d", &cols, &rows); err != nil {
+				return nil
+			}
+			return writeControl(replayControlMessage{Type: "resize", Cols: cols, Rows: rows})
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		// ブラウザ側の切断 (再生途中で閉じた等) は正常終了として扱う。
+		return
+	}
+	status, final := websocket.StatusNormalClosure, replayControlMessage{Type: "exit"}
+	if err != nil {
+		slog.Warn("session recording replay failed", "err", err)
+		status, final = websocket.StatusInternalError, replayControlMessage{Type: "error", Message: err.Error()}
+	}
+	if err := writeControl(final); err != nil {
+		slog.Warn("failed to notify browser of replay end", "err", err)
+	}
+	if err := browser.Close(status, ""); err != nil {
+		slog.Warn("failed to close replay websocket", "err", err)
+	}
+}
+
+// parseReplayOptions は再生 API のクエリパラメータを検証して ReplayOptions を返す。
+func parseReplayOptions(r *http.Request) (recording.ReplayOptions, error) {
+	opts := recording.ReplayOptions{Speed: 1, MaxIdle: recordingReplayDefaultMaxIdle}
+	if v := r.URL.Query().Get("speed"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || speed <= 0 || speed > recordingReplayMaxSpeed {
+			return opts, fmt.Errorf("speed must be a number greater than 0 and at most %d", recordingReplayMaxSpeed)
+		}
+		opts.Speed = speed
+	}
+	if v := r.URL.Query().Get("max_idle"); v != "" {
+		sec, err := strconv.ParseFloat(v, 64)
+		if err != nil || sec < 0 {
+			return opts, errors.New("max_idle must be a non-negative number of seconds")
+		}
+		opts.MaxIdle = time.Duration(sec * float64(time.Second))
+	}
+	return opts, nil
+}
+
+// writeRecordingError はセッション記録操作のエラーを HTTP ステータスへマップする。
+func writeRecordingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, recording.ErrInvalidID):
+		writeBadRequest(w, err.Error())
+	case errors.Is(err, recording.ErrNotFound):
+		writeError(w, http.StatusNotFound, "RECORDING_NOT_FOUND", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "RECORDING_ERROR", err.Error())
+	}
+}
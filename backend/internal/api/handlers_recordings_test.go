@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/sfuruya0612/thief/backend/internal/recording"
+)
+
+// recordTestSession は s.recordings に 1 件のセッション記録を作成し、その ID を返す。
+func recordTestSession(t *testing.T, s *Server) string {
+	t.Helper()
+	w, id, err := s.recordings.Create(recording.Metadata{Kind: sessionKindEC2, Profile: "prod", Target: "i-0123", SessionID: "user-0123"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	w.Resize(100, 30)
+	w.Input([]byte("ls\n"))
+	w.Output([]byte("file.txt\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return id
+}
+
+func TestHandleRecordingsListAndDownload(t *testing.T) {
+	s := newTestServer(t)
+	id := recordTestSession(t, s)
+
+	w := httptest.NewRecorder()
+	s.handleRecordingsList(w, httptest.NewRequest(http.MethodGet, "/api/recordings", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var items []recording.Recording
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != id || items[0].Profile != "prod" || items[0].Target != "i-0123" {
+		t.Fatalf("list = %+v, want the recorded session", items)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/recordings/"+id+"/download", nil)
+	r.SetPathValue("id", id)
+	w = httptest.NewRecorder()
+	s.handleRecordingDownload(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("download status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, id+".cast") {
+		t.Errorf("Content-Disposition = %q, want filename %s.cast", got, id)
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, `{"version":2`) || !strings.Contains(body, `"i","ls\n"`) {
+		t.Errorf("download body = %q, want asciicast v2 with input events", body)
+	}
+}
+
+func TestHandleRecordingDownloadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		wantCode int
+	}{
+		{name: "traversal", id: "..", wantCode: http.StatusBadRequest},
+		{name: "missing", id: "20260101T000000Z_missing", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			r := httptest.NewRequest(http.MethodGet, "/api/recordings/x/download", nil)
+			r.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			s.handleRecordingDownload(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (body=%q)", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseReplayOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantSpeed   float64
+		wantMaxIdle time.Duration
+		wantErr     bool
+	}{
+		{name: "defaults", query: "", wantSpeed: 1, wantMaxIdle: 2 * time.Second},
+		{name: "custom", query: "?speed=4&max_idle=0.5", wantSpeed: 4, wantMaxIdle: 500 * time.Millisecond},
+		{name: "no idle limit", query: "?max_idle=0", wantSpeed: 1, wantMaxIdle: 0},
+		{name: "zero speed", query: "?speed=0", wantErr: true},
+		{name: "too fast", query: "?speed=100", wantErr: true},
+		{name: "negative idle", query: "?max_idle=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseReplayOptions(httptest.NewRequest(http.MethodGet, "/api/recordings/x/replay"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReplayOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (opts.Speed != tt.wantSpeed || opts.MaxIdle != tt.wantMaxIdle) {
+				t.Errorf("opts = %+v, want speed %v max idle %v", opts, tt.wantSpeed, tt.wantMaxIdle)
+			}
+		})
+	}
+}
+
+func TestHandleRecordingReplay(t *testing.T) {
+	s := newTestServer(t)
+	id := recordTestSession(t, s)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/recordings/{id}/replay", s.handleRecordingReplay)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/api/recordings/"+id+"/replay?speed=32", nil)
+	if err != nil {
+		t.Fatalf("dial replay: %v", err)
+	}
+	defer conn.CloseNow()
+
+	// 初期サイズ → 記録中のリサイズ → 出力 → exit の順で届き、入力イベントは送られない。
+	var got []string
+	for {
+		typ, data, err := conn.Read(ctx)
+		if err != nil {
+			break
+		}
+		if typ == websocket.MessageBinary {
+			got = append(got, "output:"+string(data))
+			continue
+		}
+		var msg replayControlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("unmarshal control message: %v", err)
+		}
+		switch msg.Type {
+		case "resize":
+			got = append(got, fmt.Sprintf("resize:%dx%d", msg.Cols, msg.Rows))
+		default:
+			got = append(got, msg.Type)
+		}
+	}
+	want := "resize:80x24,resize:100x30,output:file.txt\n,exit"
+	if strings.Join(got, ",") != want {
+		t.Errorf("replayed frames = %q, want %q", strings.Join(got, ","), want)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
@@ -10,7 +11,7 @@ import (
 // キャッシュは長期 (24 時間)、プロファイル単位でキーを分ける。
 func (s *Server) handleRegions(w http.ResponseWriter, r *http.Request) {
 	profile := r.PathValue("profile")
-	s.serveCached(w, r, cacheKey("regions", profile), regionsCacheTTL, writeAWSError, func() (any, error) {
-		return awsinternal.ListRegions(r.Context(), profile)
+	s.serveCached(w, r, cacheKey("regions", profile), regionsCacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		return awsinternal.ListRegions(ctx, profile)
 	})
 }
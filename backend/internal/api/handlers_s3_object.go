@@ -1,26 +1,120 @@
 package api
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 )
 
-// S3ObjectsResponse は handleS3Objects のレスポンスエンベロープ。
-// Truncated は maxS3ListObjects で打ち切られたことを示す。
+// S3ObjectsResponse は handleS3Objects のレスポンスエンベロープ。CommonPrefixes は ?delimiter= 指定時の
+// 下の階層 (フォルダ)、Truncated は続きがあることを示し、NextContinuationToken を ?continuation_token= に
+// 渡すと続きを返す。
 type S3ObjectsResponse struct {
-	Objects   []awsinternal.S3ObjectResource `json:"objects"`
-	Truncated bool                           `json:"truncated"`
+	Objects               []awsinternal.S3ObjectResource `json:"objects"`
+	CommonPrefixes        []string                       `json:"common_prefixes"`
+	NextContinuationToken string                         `json:"next_continuation_token,omitempty"`
+	Truncated             bool                           `json:"truncated"`
 }
 
-// handleS3Objects は指定バケット (と prefix) のオブジェクト一覧を返す。
-// キャッシュキーには prefix も含める (prefix ごとに独立キャッシュ)。
+// s3ListMaxKeys は ?max_keys= の上限。ListObjectsV2 の 1 リクエストの上限と同じにする。
+const s3ListMaxKeys = 1000
+
+// s3ObjectsQuery は handleS3Objects のクエリパラメータ。
+type s3ObjectsQuery struct {
+	opts awsinternal.S3ListOptions
+	sort string
+	desc bool
+}
+
+// parseS3ObjectsQuery は handleS3Objects のクエリパラメータを検証する。
+// prefix / delimiter / continuation_token / max_keys (1〜1000) でページを、contains / min_size / max_size (バイト) /
+// modified_after / modified_before (RFC3339 または YYYY-MM-DD) で絞り込みを、sort (key / size / last_modified) と
+// order (asc / desc) でページ内の並び順を指定する。
+func parseS3ObjectsQuery(r *http.Request) (s3ObjectsQuery, error) {
+	q := r.URL.Query()
+	p := s3ObjectsQuery{
+		opts: awsinternal.S3ListOptions{
+			Prefix:            q.Get("prefix"),
+			Delimiter:         q.Get("delimiter"),
+			ContinuationToken: q.Get("continuation_token"),
+			Filter:            awsinternal.S3ObjectFilter{KeyContains: q.Get("contains")},
+		},
+		sort: q.Get("sort"),
+	}
+	if v := q.Get("max_keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > s3ListMaxKeys {
+			return p, fmt.Errorf("max_keys must be between 1 and %d", s3ListMaxKeys)
+		}
+		p.opts.MaxKeys = n
+	}
+	for name, dst := range map[string]*int64{"min_size": &p.opts.Filter.MinSize, "max_size": &p.opts.Filter.MaxSize} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return p, fmt.Errorf("%s must be a non-negative number of bytes", name)
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]*time.Time{"modified_after": &p.opts.Filter.ModifiedAfter, "modified_before": &p.opts.Filter.ModifiedBefore} {
+		if v := q.Get(name); v != "" {
+			t, err := parseS3QueryTime(v)
+			if err != nil {
+				return p, fmt.Errorf("%s must be RFC3339 or YYYY-MM-DD: %w", name, err)
+			}
+			*dst = t
+		}
+	}
+	if p.sort != "" && !slices.Contains(awsinternal.S3ObjectSortFields, p.sort) {
+		return p, fmt.Errorf("sort must be one of %s", strings.Join(awsinternal.S3ObjectSortFields, ", "))
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		p.desc = true
+	default:
+		return p, errors.New("order must be asc or desc")
+	}
+	return p, nil
+}
+
+// parseS3QueryTime は RFC3339 の日時または YYYY-MM-DD (UTC の 0 時) を解析する。
+func parseS3QueryTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+// key は一覧のキャッシュキー。アップロード時のバケット単位の前方一致の無効化に合うよう、
+// bucket の後に prefix を置く。
+func (p s3ObjectsQuery) key(profile, region, bucket string) string {
+	f := p.opts.Filter
+	timeStr := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return cacheKey("s3-objects", profile, region, bucket, p.opts.Prefix, p.opts.Delimiter, p.opts.ContinuationToken,
+		strconv.Itoa(p.opts.MaxKeys), f.KeyContains, strconv.FormatInt(f.MinSize, 10), strconv.FormatInt(f.MaxSize, 10),
+		timeStr(f.ModifiedAfter), timeStr(f.ModifiedBefore), p.sort, boolStr(p.desc))
+}
+
+// handleS3Objects は指定バケットのオブジェクト一覧を 1 ページ返す。?delimiter=/ でフォルダ単位に、
+// ?continuation_token= で前のページの続きから一覧する。絞り込みは S3 から取得しながらサーバー側で行い、
+// 並べ替えは返すページ内で行う (クエリパラメータは parseS3ObjectsQuery)。
+// キャッシュキーには prefix 以降のクエリパラメータもすべて含める。
 func (s *Server) handleS3Objects(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	bucket := r.PathValue("bucket")
@@ -28,14 +122,28 @@ func (s *Server) handleS3Objects(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "bucket is required")
 		return
 	}
-	prefix := r.URL.Query().Get("prefix")
+	q, err := parseS3ObjectsQuery(r)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
 
-	s.serveCached(w, r, cacheKey("s3-objects", profile, region, bucket, prefix), cacheTTL, writeAWSError, func() (any, error) {
-		objects, truncated, err := awsinternal.ListS3Objects(r.Context(), profile, region, bucket, prefix)
+	s.serveCached(w, r, q.key(profile, region, bucket), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		page, err := awsinternal.ListS3Objects(ctx, profile, region, bucket, q.opts)
 		if err != nil {
 			return nil, err
 		}
-		return S3ObjectsResponse{Objects: objects, Truncated: truncated}, nil
+		if q.sort != "" {
+			if err := awsinternal.SortS3Objects(page.Objects, q.sort, q.desc); err != nil {
+				return nil, err
+			}
+		}
+		return S3ObjectsResponse{
+			Objects:               page.Objects,
+			CommonPrefixes:        page.CommonPrefixes,
+			NextContinuationToken: page.NextContinuationToken,
+			Truncated:             page.Truncated,
+		}, nil
 	})
 }
 
@@ -78,8 +186,9 @@ func (s *Server) handleS3ObjectDownload(w http.ResponseWriter, r *http.Request)
 }
 
 // handleS3ObjectPreview は S3 オブジェクトの中身をプレビュー用 JSON エンベロープで返す。
-// 既知のバイナリ拡張子を除く 5 MB 未満のオブジェクトを対象とし、中身がテキストでなければ
-// buildPreviewResponse が弾く。
+// テキストは既知のバイナリ拡張子を除く 5 MB 未満のオブジェクトを対象とし、中身がテキストでなければ
+// buildPreviewResponse が弾く。Parquet / Avro / ORC と CSV / TSV / NDJSON は先頭の ?rows= 行を表にし、
+// gzip / zstd で圧縮されたものは展開して読む (writeObjectPreview)。
 func (s *Server) handleS3ObjectPreview(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	bucket := r.PathValue("bucket")
@@ -92,58 +201,35 @@ func (s *Server) handleS3ObjectPreview(w http.ResponseWriter, r *http.Request) {
 		writeBadRequest(w, "key is required")
 		return
 	}
-	if !previewExtensionAllowed(objectKey) {
-		writePreviewUnsupportedType(w)
-		return
-	}
 
-	out, err := awsinternal.GetS3Object(r.Context(), profile, region, bucket, objectKey)
-	if err != nil {
-		writeAWSError(w, err)
-		return
-	}
-	defer out.Body.Close()
-
-	if out.ContentLength != nil && !previewSizeAllowed(*out.ContentLength) {
-		writePreviewTooLarge(w)
-		return
-	}
-
-	contentType := ""
-	if out.ContentType != nil {
-		contentType = *out.ContentType
-	}
-	resp, err := buildPreviewResponse(out.Body, contentType)
-	if err != nil {
-		writePreviewError(w, err)
-		return
-	}
-	writeJSON(w, resp)
-}
-
-// maxS3UploadSize は handleS3ObjectUpload が受け付けるアップロードの上限サイズ。
-// S3 PutObject は Content-Length が必須で、multipart.Part は io.Seeker を実装しないため
-// アップロード前にメモリへ読み込んでサイズを確定する。無制限に読み込むとメモリを圧迫するため
-// 上限を設ける。
-const maxS3UploadSize = 100 << 20 // 100MiB
-
-// errS3UploadTooLarge は読み込んだ body が maxS3UploadSize を超えたことを示す。
-var errS3UploadTooLarge = fmt.Errorf("file exceeds max upload size of %d bytes", maxS3UploadSize)
-
-// readS3UploadBody は r から最大 maxS3UploadSize+1 バイトを読み込み、上限超過を検出する。
-// 上限を超えた場合は errS3UploadTooLarge を返す。
-func readS3UploadBody(r io.Reader) ([]byte, error) {
-	body, err := io.ReadAll(io.LimitReader(r, maxS3UploadSize+1))
-	if err != nil {
-		return nil, err
-	}
-	if len(body) > maxS3UploadSize {
-		return nil, errS3UploadTooLarge
-	}
-	return body, nil
+	writeObjectPreview(w, r, objectKey, previewSource{
+		open: func(ctx context.Context) (*previewObject, error) {
+			out, err := awsinternal.GetS3Object(ctx, profile, region, bucket, objectKey)
+			if err != nil {
+				return nil, err
+			}
+			obj := &previewObject{body: out.Body, size: -1, close: out.Body.Close}
+			if out.ContentLength != nil {
+				obj.size = *out.ContentLength
+			}
+			if out.ContentType != nil {
+				obj.contentType = *out.ContentType
+			}
+			return obj, nil
+		},
+		openAt: func(ctx context.Context) (*previewObject, error) {
+			ra, err := awsinternal.NewS3ObjectReaderAt(ctx, profile, region, bucket, objectKey)
+			if err != nil {
+				return nil, err
+			}
+			return &previewObject{readerAt: ra, contentType: ra.ContentType, size: ra.Size, close: func() error { return nil }}, nil
+		},
+		writeError: writeAWSError,
+	})
 }
 
-// handleS3ObjectUpload は multipart/form-data の file パートを読み込んで PutObject する。
+// handleS3ObjectUpload は multipart/form-data の file パートをストリーミングで S3 に書き込む。
+// 1 パートに満たない小さなファイルは PutObject、それ以外はマルチパートアップロードで送る (UploadS3Object)。
 func (s *Server) handleS3ObjectUpload(w http.ResponseWriter, r *http.Request) {
 	profile, region := s.profileAndRegion(r)
 	bucket := r.PathValue("bucket")
@@ -157,50 +243,76 @@ func (s *Server) handleS3ObjectUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.receiveObjectUpload(w, r, bucket, objectKey, writeAWSError, func(ctx context.Context, body io.Reader, contentType string, progress func(int64)) (int64, error) {
+		return awsinternal.UploadS3Object(ctx, profile, region, bucket, objectKey, body, contentType, progress)
+	}) {
+		return
+	}
+
+	// アップロード成功後、対象バケット配下のオブジェクト一覧キャッシュを無効化する。
+	s.invalidateS3Objects(profile, region, bucket)
+	writeJSON(w, map[string]string{"status": "ok", "key": objectKey})
+}
+
+// objectUploadFunc は receiveObjectUpload がファイルパートを渡すアップロード関数 (S3 / GCS)。
+// progress にはクラウド側に送り終えたバイト数を渡す。
+type objectUploadFunc func(ctx context.Context, body io.Reader, contentType string, progress func(int64)) (int64, error)
+
+// receiveObjectUpload は multipart/form-data の最初の file パートを読みながら upload に流す (S3 / GCS 共通)。
+// パートをメモリに読み込まないためサイズの上限はない。?upload_id= があれば進捗を s.uploads に記録し、
+// handleUploadEvents で返す (?size= はクライアントが知っているファイルの全長)。失敗した場合は
+// エラー応答を書き込んで false を返す。アップロード自体のエラーは writeErr で返す。
+func (s *Server) receiveObjectUpload(w http.ResponseWriter, r *http.Request, bucket, key string, writeErr func(http.ResponseWriter, error), upload objectUploadFunc) bool {
+	var progress func(int64)
+	finish := func(error) {}
+	if id := r.URL.Query().Get("upload_id"); id != "" {
+		if !uploadIDPattern.MatchString(id) {
+			writeBadRequest(w, "invalid upload_id")
+			return false
+		}
+		total, _ := strconv.ParseInt(r.URL.Query().Get("size"), 10, 64)
+		p, f, ok := s.uploads.start(id, bucket, key, total)
+		if !ok {
+			writeError(w, http.StatusConflict, "UPLOAD_ID_IN_USE", "upload_id is already used by another upload")
+			return false
+		}
+		progress, finish = p.setBytes, f
+	}
+
 	reader, err := r.MultipartReader()
 	if err != nil {
+		finish(err)
 		writeBadRequest(w, "invalid multipart body: "+err.Error())
-		return
+		return false
 	}
-
-	var uploaded bool
 	for {
 		part, err := reader.NextPart()
 		if errors.Is(err, io.EOF) {
 			break
 		}
 		if err != nil {
+			finish(err)
 			writeBadRequest(w, "read multipart part: "+err.Error())
-			return
+			return false
 		}
 		if part.FormName() != "file" {
 			part.Close()
 			continue
 		}
-		contentType := part.Header.Get("Content-Type")
-		body, err := readS3UploadBody(part)
+		_, err = upload(r.Context(), part, part.Header.Get("Content-Type"), progress)
 		part.Close()
+		finish(err)
 		if err != nil {
-			writeBadRequest(w, "read file part: "+err.Error())
-			return
+			writeErr(w, err)
+			return false
 		}
-		if err := awsinternal.PutS3Object(r.Context(), profile, region, bucket, objectKey, bytes.NewReader(body), int64(len(body)), contentType); err != nil {
-			writeAWSError(w, err)
-			return
-		}
-		uploaded = true
-		break
-	}
-
-	if !uploaded {
-		writeBadRequest(w, `multipart form must contain a "file" part`)
-		return
+		return true
 	}
 
-	// アップロード成功後、対象バケット配下のオブジェクト一覧キャッシュを無効化する。
-	// prefix ごとにキーが分かれるため、バケット単位のキー前方一致で一括無効化する。
-	s.resourceCache.InvalidatePrefix(cacheKey("s3-objects", profile, region, bucket, ""))
-	writeJSON(w, map[string]string{"status": "ok", "key": objectKey})
+	err = errors.New(`multipart form must contain a "file" part`)
+	finish(err)
+	writeBadRequest(w, err.Error())
+	return false
 }
 
 // sanitizeContentDispositionFilename は Content-Disposition の filename に埋め込む前に
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// S3ObjectVersionsResponse は handleS3ObjectVersions のレスポンスエンベロープ。
+// Truncated は maxS3ListObjects で打ち切られたことを示す。
+type S3ObjectVersionsResponse struct {
+	Versions  []awsinternal.S3ObjectVersionResource `json:"versions"`
+	Truncated bool                                  `json:"truncated"`
+}
+
+// S3RestoreRequest は handleS3ObjectRestore のリクエストボディ。
+type S3RestoreRequest struct {
+	Key       string `json:"key"`
+	VersionID string `json:"version_id"`
+}
+
+// S3DeleteRequest は handleS3ObjectDelete のリクエストボディ。Objects と Prefix (配下のすべてのオブジェクト) の
+// 少なくとも一方を指定する。ConfirmToken が空なら削除せず対象とトークンを返す。
+type S3DeleteRequest struct {
+	Objects      []awsinternal.S3ObjectIdentifier `json:"objects"`
+	Prefix       string                           `json:"prefix"`
+	ConfirmToken string                           `json:"confirm_token"`
+}
+
+// S3DeleteResponse は handleS3ObjectDelete のレスポンス。Deleted が false のときは確認のための応答で、
+// ConfirmToken を付けて同じリクエストを送ると Objects を削除する。
+type S3DeleteResponse struct {
+	Objects      []awsinternal.S3ObjectIdentifier `json:"objects"`
+	ConfirmToken string                           `json:"confirm_token"`
+	Deleted      bool                             `json:"deleted"`
+}
+
+// S3CopyRequest は handleS3ObjectCopy のリクエストボディ。Source は "/" で終わるとプレフィックス、
+// DestBucket は省略時に同じバケット、Move はコピー後にコピー元を削除する。
+type S3CopyRequest struct {
+	Source      string `json:"source"`
+	DestBucket  string `json:"dest_bucket"`
+	Destination string `json:"destination"`
+	Move        bool   `json:"move"`
+}
+
+// S3CopyResponse は handleS3ObjectCopy のレスポンス。
+type S3CopyResponse struct {
+	Copied []awsinternal.S3CopyResult `json:"copied"`
+}
+
+// handleS3ObjectVersions は ?prefix= 配下のオブジェクトのバージョンと削除マーカーを返す。
+func (s *Server) handleS3ObjectVersions(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	bucket := r.PathValue("bucket")
+	if bucket == "" {
+		writeBadRequest(w, "bucket is required")
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+
+	s.serveCached(w, r, cacheKey("s3-versions", profile, region, bucket, prefix), cacheTTL, writeAWSError, func(ctx context.Context) (any, error) {
+		versions, truncated, err := awsinternal.ListS3ObjectVersions(ctx, profile, region, bucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return S3ObjectVersionsResponse{Versions: versions, Truncated: truncated}, nil
+	})
+}
+
+// handleS3ObjectRestore は以前のバージョンを同じキーにコピーして最新に戻す。
+func (s *Server) handleS3ObjectRestore(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	bucket := r.PathValue("bucket")
+	var req S3RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request body: "+err.Error())
+		return
+	}
+	if bucket == "" || req.Key == "" || req.VersionID == "" {
+		writeBadRequest(w, "bucket, key and version_id are required")
+		return
+	}
+
+	versionID, err := awsinternal.RestoreS3ObjectVersion(r.Context(), profile, region, bucket, req.Key, req.VersionID)
+	if err != nil {
+		writeAWSError(w, err)
+		return
+	}
+	s.invalidateS3Objects(profile, region, bucket)
+	writeJSON(w, map[string]string{"status": "ok", "key": req.Key, "version_id": versionID})
+}
+
+// handleS3ObjectDelete はオブジェクトを削除する。削除は 2 段階で、confirm_token なしのリクエストには
+// 対象 (prefix を展開したもの) と確認トークンを返し、同じリクエストにそのトークンを付けると削除する。
+// トークンの発行後に対象が変わった (prefix 配下にオブジェクトが増えた など) 場合は 409 を返す。
+func (s *Server) handleS3ObjectDelete(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	bucket := r.PathValue("bucket")
+	var req S3DeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request body: "+err.Error())
+		return
+	}
+	if bucket == "" || (len(req.Objects) == 0 && req.Prefix == "") {
+		writeBadRequest(w, "bucket and objects or prefix are required")
+		return
+	}
+	for _, o := range req.Objects {
+		if o.Key == "" {
+			writeBadRequest(w, "object key must not be empty")
+			return
+		}
+	}
+
+	objects, err := awsinternal.ExpandS3Objects(r.Context(), profile, region, bucket, req.Objects, req.Prefix)
+	if err != nil {
+		writeS3BatchError(w, err)
+		return
+	}
+	token := awsinternal.S3DeleteConfirmToken(bucket, objects)
+	if req.ConfirmToken == "" {
+		writeJSON(w, S3DeleteResponse{Objects: objects, ConfirmToken: token})
+		return
+	}
+	if req.ConfirmToken != token {
+		writeError(w, http.StatusConflict, "CONFIRM_TOKEN_MISMATCH", "objects to delete have changed since the confirmation token was issued; request a new token")
+		return
+	}
+	if err := awsinternal.DeleteS3Objects(r.Context(), profile, region, bucket, objects); err != nil {
+		writeAWSError(w, err)
+		return
+	}
+	s.invalidateS3Objects(profile, region, bucket)
+	writeJSON(w, S3DeleteResponse{Objects: objects, ConfirmToken: token, Deleted: true})
+}
+
+// handleS3ObjectCopy はオブジェクト (またはプレフィックス配下) を同じ / 別のバケットにコピー (移動) する。
+func (s *Server) handleS3ObjectCopy(w http.ResponseWriter, r *http.Request) {
+	profile, region := s.profileAndRegion(r)
+	bucket := r.PathValue("bucket")
+	var req S3CopyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request body: "+err.Error())
+		return
+	}
+	if bucket == "" || req.Source == "" {
+		writeBadRequest(w, "bucket and source are required")
+		return
+	}
+	dstBucket := req.DestBucket
+	if dstBucket == "" {
+		dstBucket = bucket
+	}
+
+	copied, err := awsinternal.CopyS3Objects(r.Context(), profile, region, bucket, req.Source, dstBucket, req.Destination, req.Move)
+	// 途中で失敗してもコピー済みのオブジェクトがあるため、キャッシュは常に無効化する。
+	s.invalidateS3Objects(profile, region, dstBucket)
+	if req.Move {
+		s.invalidateS3Objects(profile, region, bucket)
+	}
+	if err != nil {
+		writeS3BatchError(w, err)
+		return
+	}
+	writeJSON(w, S3CopyResponse{Copied: copied})
+}
+
+// invalidateS3Objects はバケットのオブジェクト一覧とバージョン一覧のキャッシュを無効化する。
+// prefix やページごとにキーが分かれるため、バケット単位のキー前方一致で一括無効化する。
+func (s *Server) invalidateS3Objects(profile, region, bucket string) {
+	s.resourceCache.InvalidatePrefix(cacheKey("s3-objects", profile, region, bucket, ""))
+	s.resourceCache.InvalidatePrefix(cacheKey("s3-versions", profile, region, bucket, ""))
+}
+
+// writeS3BatchError は削除・コピーの対象の誤り (件数超過、空のプレフィックス、同じオブジェクトへのコピー) を 400、
+// それ以外を writeAWSError で返す。
+func writeS3BatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, awsinternal.ErrS3TooManyObjects) || errors.Is(err, awsinternal.ErrS3NoObjects) || errors.Is(err, awsinternal.ErrS3SameObject) {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	writeAWSError(w, err)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandleS3ObjectDeleteConfirm は削除の 2 段階の確認を検証する。objects だけを指定した場合は
+// prefix の展開で S3 を呼ばないため、確認とトークン不一致までは AWS なしで確かめられる。
+func TestHandleS3ObjectDeleteConfirm(t *testing.T) {
+	s := newTestServer(t)
+	do := func(t *testing.T, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/api/aws/profiles/prod/s3/bucket/objects/delete", strings.NewReader(body))
+		r.SetPathValue("profile", "prod")
+		r.SetPathValue("bucket", "bucket")
+		w := httptest.NewRecorder()
+		s.handleS3ObjectDelete(w, r)
+		return w
+	}
+
+	w := do(t, `{"objects":[{"key":"b.txt"},{"key":"a.txt","version_id":"v1"},{"key":"b.txt"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+	}
+	var preview S3DeleteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("unmarshal body: %v", err)
+	}
+	if preview.Deleted || preview.ConfirmToken == "" || len(preview.Objects) != 2 || preview.Objects[0].Key != "a.txt" {
+		t.Errorf("preview = %+v, want 2 sorted objects with a token and no deletion", preview)
+	}
+
+	if w := do(t, `{"objects":[{"key":"a.txt"}],"confirm_token":"`+preview.ConfirmToken+`"}`); w.Code != http.StatusConflict {
+		t.Errorf("mismatched token status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	for _, body := range []string{`{}`, `{"objects":[{"key":""}]}`, `not json`} {
+		if w := do(t, body); w.Code != http.StatusBadRequest {
+			t.Errorf("body %s status = %d, want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
-	"bytes"
-	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 )
 
 func TestSanitizeContentDispositionFilename(t *testing.T) {
@@ -59,43 +62,73 @@ func TestSanitizeContentDispositionFilename(t *testing.T) {
 	}
 }
 
-func TestReadS3UploadBody(t *testing.T) {
+func TestParseS3ObjectsQuery(t *testing.T) {
 	tests := []struct {
-		name    string
-		in      []byte
-		want    []byte
-		wantErr error
+		query   string
+		want    s3ObjectsQuery
+		wantErr bool
 	}{
+		{query: "", want: s3ObjectsQuery{}},
 		{
-			name: "empty body",
-			in:   []byte{},
-			want: []byte{},
-		},
-		{
-			name: "small body",
-			in:   []byte("hello"),
-			want: []byte("hello"),
+			query: "?prefix=logs/&delimiter=/&continuation_token=abc&max_keys=200",
+			want: s3ObjectsQuery{opts: awsinternal.S3ListOptions{
+				Prefix: "logs/", Delimiter: "/", ContinuationToken: "abc", MaxKeys: 200,
+			}},
 		},
 		{
-			name: "exactly at limit",
-			in:   bytes.Repeat([]byte("a"), maxS3UploadSize),
-			want: bytes.Repeat([]byte("a"), maxS3UploadSize),
-		},
-		{
-			name:    "exceeds limit",
-			in:      bytes.Repeat([]byte("a"), maxS3UploadSize+1),
-			wantErr: errS3UploadTooLarge,
+			query: "?contains=.gz&min_size=1024&max_size=2048&modified_after=2026-01-01&modified_before=2026-02-01T09:00:00%2B09:00&sort=size&order=desc",
+			want: s3ObjectsQuery{
+				opts: awsinternal.S3ListOptions{Filter: awsinternal.S3ObjectFilter{
+					KeyContains:    ".gz",
+					MinSize:        1024,
+					MaxSize:        2048,
+					ModifiedAfter:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+					ModifiedBefore: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+				}},
+				sort: "size",
+				desc: true,
+			},
 		},
+		{query: "?max_keys=0", wantErr: true},
+		{query: "?max_keys=1001", wantErr: true},
+		{query: "?min_size=-1", wantErr: true},
+		{query: "?modified_after=yesterday", wantErr: true},
+		{query: "?sort=etag", wantErr: true},
+		{query: "?order=up", wantErr: true},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := readS3UploadBody(strings.NewReader(string(tt.in)))
-			if !errors.Is(err, tt.wantErr) {
-				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseS3ObjectsQuery(httptest.NewRequest(http.MethodGet, "/objects"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
 			}
-			if tt.wantErr == nil && !bytes.Equal(got, tt.want) {
-				t.Errorf("got %d bytes, want %d bytes", len(got), len(tt.want))
+			if !got.opts.Filter.ModifiedBefore.Equal(tt.want.opts.Filter.ModifiedBefore) {
+				t.Errorf("ModifiedBefore = %v, want %v", got.opts.Filter.ModifiedBefore, tt.want.opts.Filter.ModifiedBefore)
+			}
+			got.opts.Filter.ModifiedBefore = tt.want.opts.Filter.ModifiedBefore
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
 			}
 		})
 	}
 }
+
+// TestS3ObjectsQueryKey はアップロード時のバケット単位の無効化 (cacheKey("s3-objects", profile, region, bucket, "") の
+// 前方一致) が、ページや絞り込みを含むすべての一覧のキャッシュキーに及ぶことを確認する。
+func TestS3ObjectsQueryKey(t *testing.T) {
+	q, err := parseS3ObjectsQuery(httptest.NewRequest(http.MethodGet, "/objects?prefix=logs/&delimiter=/&continuation_token=abc&contains=x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := q.key("prod", "ap-northeast-1", "bucket")
+	if prefix := cacheKey("s3-objects", "prod", "ap-northeast-1", "bucket", ""); !strings.HasPrefix(key, prefix) {
+		t.Errorf("key %q does not start with %q", key, prefix)
+	}
+	other, _ := parseS3ObjectsQuery(httptest.NewRequest(http.MethodGet, "/objects?prefix=logs/&delimiter=/&continuation_token=def&contains=x", nil))
+	if other.key("prod", "ap-northeast-1", "bucket") == key {
+		t.Error("keys for different continuation tokens are equal")
+	}
+}
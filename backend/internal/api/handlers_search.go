@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/search"
+)
+
+// defaultSearchLimit / maxSearchLimit は GET /api/search の limit の既定値と上限。
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// SearchResponse は GET /api/search のレスポンス。Indexed は検索対象になったリソースの総数で、
+// 0 件のときに「一致しなかった」のか「まだ一覧を取得していない」のかを区別するために返す。
+type SearchResponse struct {
+	Query   string          `json:"query"`
+	Indexed int             `json:"indexed"`
+	Results []search.Result `json:"results"`
+}
+
+// handleSearch は resourceCache に載っているリソース一覧 (regionalResources のエントリ) を横断して、
+// 名前 / ID / ARN / タグが q に一致するリソースを返す。AWS へは問い合わせないため、検索対象は
+// 画面・CLI・プリウォームで取得済みの profile/region/service に限られる。
+// service / profile / region (いずれもカンマ区切り) で取得元を絞り込める。
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	text := strings.TrimSpace(q.Get("q"))
+	if text == "" {
+		writeBadRequest(w, "q query parameter is required")
+		return
+	}
+	limit := defaultSearchLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeBadRequest(w, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	s.syncSearchIndex()
+	writeJSON(w, SearchResponse{
+		Query:   text,
+		Indexed: s.searchIndex.Len(),
+		Results: s.searchIndex.Search(search.Query{
+			Text:     text,
+			Services: awsinternal.SplitFanoutList(q.Get("service")),
+			Profiles: awsinternal.SplitFanoutList(q.Get("profile")),
+			Regions:  awsinternal.SplitFanoutList(q.Get("region")),
+			Limit:    limit,
+		}),
+	})
+}
+
+// syncSearchIndex は searchIndex を resourceCache のメモリ上のエントリに合わせる。再取得されたエントリ
+// (CachedAt が変わったもの) だけを取り込み直し、追い出し・無効化・期限切れで消えたエントリは索引からも取り除く。
+// ディスクに永続化したエントリは参照されるまでメモリに戻らないため、最初の同期で一覧のエントリをすべて戻す
+// (再起動前に取得した一覧も検索できるようにする)。
+func (s *Server) syncSearchIndex() {
+	s.searchRestore.Do(func() {
+		s.resourceCache.RestoreWhere(func(key string) bool {
+			_, ok := searchOrigin(key)
+			return ok
+		})
+	})
+	entries := s.resourceCache.Snapshot()
+	s.searchIndex.Retain(func(key string) bool {
+		_, ok := entries[key]
+		return ok
+	})
+	for key, e := range entries {
+		origin, ok := searchOrigin(key)
+		if !ok {
+			continue
+		}
+		s.searchIndex.Put(key, e.CachedAt, origin, e.Value)
+	}
+}
+
+// auditOnlyResources は監査でのみ使う regionalResources の一覧。ecr / elb の一覧と同じリソースを重ねて返さないよう、
+// 検索の索引には取り込まない。
+var auditOnlyResources = map[string]bool{"ecr-images": true, "elb-security": true}
+
+// searchOrigin はキャッシュキーが regionalResources の一覧 (cacheKey(service, profile, region)) であれば
+// その取得元を返す。パラメータ一覧や S3 オブジェクトなど、リソース一覧以外のエントリは索引に取り込まない。
+func searchOrigin(key string) (search.Origin, bool) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 3 || auditOnlyResources[parts[0]] {
+		return search.Origin{}, false
+	}
+	if _, ok := regionalResources[parts[0]]; !ok {
+		return search.Origin{}, false
+	}
+	return search.Origin{Service: parts[0], Profile: parts[1], Region: parts[2]}, true
+}
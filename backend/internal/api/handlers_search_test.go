@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/cache"
+)
+
+func TestHandleSearch(t *testing.T) {
+	s := newTestServer(t)
+	s.resourceCache.Set(cacheKey("ecs", "prod", "ap-northeast-1"), []awsinternal.ECSResource{
+		{ID: "arn:aws:ecs:ap-northeast-1:111111111111:cluster/payment-api", Name: "payment-api"},
+	}, time.Hour)
+	s.resourceCache.Set(cacheKey("lambda", "dev", "us-east-1"), json.RawMessage(`[{"id":"notify-payment-api","name":"notify-payment-api"}]`), time.Hour)
+	// リソース一覧以外のエントリは取り込まない。
+	s.resourceCache.Set(cacheKey("rds-parameters", "prod", "ap-northeast-1", "payment-api"), []awsinternal.RDSParameterInfo{{Name: "payment-api"}}, time.Hour)
+	// 監査でのみ使う一覧も ecr / elb と重複するため取り込まない。
+	s.resourceCache.Set(cacheKey("elb-security", "prod", "ap-northeast-1"), []awsinternal.ELBSecurityResource{
+		{ELBResource: awsinternal.ELBResource{ID: "payment-api-alb", Name: "payment-api"}},
+	}, time.Hour)
+
+	do := func(url string) (*httptest.ResponseRecorder, SearchResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.handleSearch(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var body SearchResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+		}
+		return w, body
+	}
+
+	w, body := do("/api/search?q=payment-api")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if body.Indexed != 2 || len(body.Results) != 2 {
+		t.Fatalf("body = %+v, want 2 indexed resources and 2 results", body)
+	}
+	if got := body.Results[0]; got.Service != "ecs" || got.Profile != "prod" || got.Region != "ap-northeast-1" || got.Name != "payment-api" {
+		t.Errorf("Results[0] = %+v, want the exact ecs match first", got)
+	}
+	if got := body.Results[1]; got.Service != "lambda" || got.Name != "notify-payment-api" {
+		t.Errorf("Results[1] = %+v, want the lambda substring match", got)
+	}
+
+	if _, body := do("/api/search?q=payment-api&service=lambda"); len(body.Results) != 1 || body.Results[0].Service != "lambda" {
+		t.Errorf("service filter results = %+v, want only lambda", body.Results)
+	}
+
+	// 無効化されたエントリは索引からも消える。
+	s.resourceCache.Invalidate(cacheKey("ecs", "prod", "ap-northeast-1"))
+	if _, body := do("/api/search?q=payment-api"); body.Indexed != 1 || len(body.Results) != 1 {
+		t.Errorf("after invalidation body = %+v, want only the lambda entry", body)
+	}
+
+	for _, url := range []string{"/api/search", "/api/search?q=x&limit=0", "/api/search?q=x&limit=abc"} {
+		if w, _ := do(url); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// 再起動前にディスクへ永続化した一覧は、参照されていなくても最初の検索から索引に入る。
+func TestHandleSearchRestoresDiskCache(t *testing.T) {
+	store, err := cache.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func() *cache.Cache[any] {
+		c := cache.NewWithOptions(time.Hour, cache.Options[any]{Store: store, Decode: decodeCachedJSON})
+		t.Cleanup(c.Close)
+		return c
+	}
+	before := open()
+	before.Set(cacheKey("lambda", "dev", "us-east-1"), []awsinternal.LambdaResource{{ID: "notify-payment-api", Name: "notify-payment-api"}}, time.Hour)
+	before.Set(cacheKey("rds-parameters", "prod", "ap-northeast-1", "payment-api"), []awsinternal.RDSParameterInfo{{Name: "payment-api"}}, time.Hour)
+
+	s := newTestServer(t)
+	s.resourceCache = open()
+	w := httptest.NewRecorder()
+	s.handleSearch(w, httptest.NewRequest(http.MethodGet, "/api/search?q=payment-api", nil))
+	var body SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal body: %v", err)
+	}
+	if body.Indexed != 1 || len(body.Results) != 1 || body.Results[0].Service != "lambda" || body.Results[0].Profile != "dev" {
+		t.Errorf("body = %+v, want the lambda list restored from disk", body)
+	}
+	if _, ok := s.resourceCache.Snapshot()[cacheKey("rds-parameters", "prod", "ap-northeast-1", "payment-api")]; ok {
+		t.Error("expected entries other than resource lists to stay on disk")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/coder/websocket"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/recording"
 	"github.com/sfuruya0612/thief/backend/internal/session"
 )
 
@@ -16,6 +17,12 @@ import (
 // 旧 CLI (cmd/ecs.go の ecsExecCmd) のデフォルト値に揃える。
 const ecsExecDefaultCommand = "/bin/sh"
 
+// セッション記録 (recording.Metadata.Kind) に残すセッション種別。
+const (
+	sessionKindEC2 = "ec2-session"
+	sessionKindECS = "ecs-exec"
+)
+
 // sessionTerminateTimeout はセッション確立に失敗した際の後始末 (TerminateSSMSession 呼び出し) に使うタイムアウト。
 const sessionTerminateTimeout = 5 * time.Second
 
@@ -31,7 +38,7 @@ func (s *Server) handleEC2Session(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.runSessionBridge(w, r, result, func(ctx context.Context) error {
+	s.runSessionBridge(w, r, sessionKindEC2, profile, region, result, func(ctx context.Context) error {
 		return awsinternal.TerminateSSMSession(ctx, profile, region, result.SessionID)
 	})
 }
@@ -59,7 +66,7 @@ func (s *Server) handleECSExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.runSessionBridge(w, r, result, func(ctx context.Context) error {
+	s.runSessionBridge(w, r, sessionKindECS, profile, region, result, func(ctx context.Context) error {
 		return awsinternal.TerminateSSMSession(ctx, profile, region, result.SessionID)
 	})
 }
@@ -68,7 +75,11 @@ func (s *Server) handleECSExec(w http.ResponseWriter, r *http.Request) {
 // StartSSMSession/ExecuteECSCommand が成功した直後に呼ぶこと。
 // データチャネル接続やアップグレードに失敗した場合は、AWS 側のセッションが残らないよう terminate を呼んでから
 // 通常の HTTP エラーを返す (アップグレード前なので通常のレスポンスがまだ書ける)。
-func (s *Server) runSessionBridge(w http.ResponseWriter, r *http.Request, result *awsinternal.StartSessionResult, terminate session.TerminateFunc) {
+// Session Manager の設定で KMS 暗号化が有効な場合に備え、profile / region の認証情報でデータキーを生成できるようにする。
+// セッション記録が有効な場合、記録ファイルを作成できなければ監査証跡のないセッションを開かないよう失敗させる。
+// データチャネルが切断された場合は ResumeSession で再接続し、ブラウザが切断された場合は
+// cfg.SessionReattachGrace の間 /api/sessions/{id}/attach からの再接続を待つ。
+func (s *Server) runSessionBridge(w http.ResponseWriter, r *http.Request, kind, profile, region string, result *awsinternal.StartSessionResult, terminate session.TerminateFunc) {
 	ctx := r.Context()
 
 	dc, err := session.OpenDataChannel(ctx, result.StreamURL, result.TokenValue, result.SessionID)
@@ -77,6 +88,33 @@ func (s *Server) runSessionBridge(w http.ResponseWriter, r *http.Request, result
 		terminateBestEffort(terminate)
 		return
 	}
+	dc.EnableKMSEncryption(result.Target, awsinternal.SessionDataKeyGenerator(profile, region))
+	dc.EnableReconnect(awsinternal.SessionResumer(profile, region, result.SessionID))
+
+	var recorder *recording.Writer
+	if s.cfg.SessionRecording {
+		var id string
+		recorder, id, err = s.recordings.Create(recording.Metadata{
+			Kind:      kind,
+			Profile:   profile,
+			Region:    region,
+			Target:    result.Target,
+			SessionID: result.SessionID,
+		})
+		if err != nil {
+			writeInternalError(w, "start session recording: "+err.Error())
+			if closeErr := dc.Close(); closeErr != nil {
+				slog.Warn("failed to close data channel after recording failure", "err", closeErr)
+			}
+			terminateBestEffort(terminate)
+			return
+		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				slog.Warn("failed to finish session recording", "id", id, "err", err)
+			}
+		}()
+	}
 
 	// OriginPatterns は cfg.WebOrigins (デフォルト localhost:8088/127.0.0.1:8088、環境変数
 	// THIEF_WEB_ORIGINS で上書き可能) に従う。DNS rebinding 対策のためここにのみ渡し、
@@ -93,7 +131,23 @@ func (s *Server) runSessionBridge(w http.ResponseWriter, r *http.Request, result
 		return
 	}
 
-	bridge := &session.Bridge{DataChannel: dc, Browser: browser, Terminate: terminate}
+	bridge := &session.Bridge{
+		DataChannel:   dc,
+		Browser:       browser,
+		Terminate:     terminate,
+		IdleTimeout:   s.cfg.SessionIdleTimeout,
+		ReattachGrace: s.cfg.SessionReattachGrace,
+	}
+	if recorder != nil {
+		bridge.Recorder = recorder
+	}
+	remove := s.sessions.Add(result.SessionID, session.SessionMetadata{
+		Kind:    kind,
+		Profile: profile,
+		Region:  region,
+		Target:  result.Target,
+	}, bridge)
+	defer remove()
 	if err := bridge.Run(ctx); err != nil {
 		slog.Error("session bridge ended with error", "err", err)
 	}
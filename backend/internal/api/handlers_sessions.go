@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/coder/websocket"
+
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+// sessionTerminatedReason は API から強制終了したときにブラウザへ通知する終了理由。
+const sessionTerminatedReason = "session terminated from thief"
+
+// handleSessionsList は稼働中のブラウザターミナルセッション (EC2 Session / ECS Exec) を開始日時の降順で返す。
+func (s *Server) handleSessionsList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.sessions.List())
+}
+
+// handleSessionDelete は稼働中のセッションを強制終了する。ブラウザへ exit を通知して接続を閉じ、
+// ブリッジの後始末で SSM セッションも TerminateSession される。
+func (s *Server) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.sessions.Stop(id, sessionTerminatedReason) {
+		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "session not found: "+id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSessionAttach はブラウザの WebSocket を稼働中のセッションへ接続し直す。ネットワーク断やタブのリロードで
+// 切断されたブラウザが、cfg.SessionReattachGrace の猶予期間内に同じシェルへ戻るために使う。切断中の出力は
+// 接続直後にまとめて届く。別のブラウザが接続中の場合はそちらを切り離して引き継ぐ。
+func (s *Server) handleSessionAttach(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	bridge, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "session not found: "+id)
+		return
+	}
+
+	browser, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: s.cfg.WebOrigins,
+	})
+	if err != nil {
+		slog.Warn("failed to accept browser websocket", "err", err)
+		return
+	}
+	if err := bridge.Attach(r.Context(), browser); err != nil && !errors.Is(err, session.ErrBridgeClosed) {
+		slog.Warn("session attach ended with error", "id", id, "err", err)
+	}
+}
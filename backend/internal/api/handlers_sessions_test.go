@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+func TestHandleSessionsListAndDelete(t *testing.T) {
+	s := newTestServer(t)
+	// Run 前のブリッジでも一覧・停止要求の受け付けはできる (Run 開始時に即座に終了する)。
+	remove := s.sessions.Add("user-0123", session.SessionMetadata{Kind: sessionKindEC2, Profile: "prod", Target: "i-0123"}, &session.Bridge{})
+	defer remove()
+
+	w := httptest.NewRecorder()
+	s.handleSessionsList(w, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var items []session.SessionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "user-0123" || items[0].Kind != sessionKindEC2 || items[0].Target != "i-0123" {
+		t.Fatalf("list = %+v, want the registered session", items)
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		wantCode int
+	}{
+		{name: "registered", id: "user-0123", wantCode: http.StatusNoContent},
+		{name: "missing", id: "user-missing", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/api/sessions/"+tt.id, nil)
+			r.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			s.handleSessionDelete(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (body=%q)", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSessionAttachNotFound(t *testing.T) {
+	s := newTestServer(t)
+	r := httptest.NewRequest(http.MethodGet, "/api/sessions/user-missing/attach", nil)
+	r.SetPathValue("id", "user-missing")
+	w := httptest.NewRecorder()
+	s.handleSessionAttach(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d (body=%q)", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
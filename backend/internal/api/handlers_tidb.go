@@ -1,18 +1,19 @@
 package api
 
 import (
+	"context"
 	"net/http"
 )
 
 func (s *Server) handleTiDBProjects(w http.ResponseWriter, r *http.Request) {
-	s.serveCached(w, r, cacheKey("tidb-projects"), cacheTTL, writeInternalFromError, func() (any, error) {
+	s.serveCached(w, r, cacheKey("tidb-projects"), cacheTTL, writeInternalFromError, func(context.Context) (any, error) {
 		return s.tidb.ListProjects()
 	})
 }
 
 func (s *Server) handleTiDBClusters(w http.ResponseWriter, r *http.Request) {
 	projectID := r.PathValue("project_id")
-	s.serveCached(w, r, cacheKey("tidb-clusters", projectID), cacheTTL, writeInternalFromError, func() (any, error) {
+	s.serveCached(w, r, cacheKey("tidb-clusters", projectID), cacheTTL, writeInternalFromError, func(context.Context) (any, error) {
 		return s.tidb.ListClusters(projectID)
 	})
 }
@@ -28,7 +29,7 @@ func (s *Server) handleTiDBCost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	s.serveCached(w, r, cacheKey("tidb-cost", start, end), cacheTTL, writeInternalFromError, func() (any, error) {
+	s.serveCached(w, r, cacheKey("tidb-cost", start, end), cacheTTL, writeInternalFromError, func(context.Context) (any, error) {
 		return s.tidb.GetCostRange(start, end)
 	})
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+)
+
+// wasteCSVHeader は GET /api/waste?format=csv のヘッダ行。audit.WasteFinding.ToRow の列順に合わせる。
+var wasteCSVHeader = []string{"service", "account_id", "profile", "region", "rule", "name", "id", "reason", "monthly_saving_usd"}
+
+// WasteResponse は GET /api/waste のレスポンス。Findings は節約額の大きい順、TotalMonthlySavingUSD は
+// その合計 (USD)、Errors は一覧を取得できなかったサービスと profile/region の組。
+type WasteResponse struct {
+	Findings              []audit.WasteFinding `json:"findings"`
+	TotalMonthlySavingUSD float64              `json:"total_monthly_saving_usd"`
+	Errors                []audit.Error        `json:"errors"`
+}
+
+// handleWaste は使われていない可能性が高いリソース (停止中の EC2、稼働中のインスタンスがない VPC の NAT ゲートウェイ、
+// 空の SQS キュー、pull されていない ECR イメージ、保持期間のないロググループ) を月額の節約見込みとともに返す。
+// 対象は ?profiles= / ?regions= (省略時は既定のプロファイル・リージョン) の組で、?format=csv では CSV ファイルとして返す。
+func (s *Server) handleWaste(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeBadRequest(w, "format must be json or csv")
+		return
+	}
+	targets, resolveErrs, ok := s.resolveFanoutRequest(w, r, s.cfg.Profile, s.cfg.Region)
+	if !ok {
+		return
+	}
+
+	findings, errs := audit.CollectWaste(r.Context(), targets, s.cachedList(s.refresh(r)), time.Now())
+	errs = appendResolveErrors(errs, resolveErrs)
+
+	if format == "csv" {
+		writeAuditCSV(w, "waste.csv", wasteCSVHeader, findings, errs)
+		return
+	}
+	if errs == nil {
+		errs = []audit.Error{}
+	}
+	writeJSON(w, WasteResponse{
+		Findings:              findings,
+		TotalMonthlySavingUSD: audit.TotalMonthlySaving(findings),
+		Errors:                errs,
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHandleWaste は無駄なリソースの JSON / CSV 応答を検証する。アカウント ID は ~/.aws/config の sso_account_id から
+// 解決させるため HOME を差し替え、regionalResources の監査対象の一覧を差し替える (このため t.Parallel とは併用できない)。
+func TestHandleWaste(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte("[profile prod]\nsso_account_id = 111111111111\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	type row struct {
+		ID         string  `json:"id"`
+		Name       string  `json:"name"`
+		State      string  `json:"state"`
+		VpcID      string  `json:"vpc_id"`
+		RetainDays int     `json:"retention_days"`
+		Cost       float64 `json:"cost_monthly"`
+	}
+	for service, load := range map[string]regionalLoader{
+		"ec2": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []row{{ID: "i-1", Name: "web", State: "running", VpcID: "vpc-a"}, {ID: "i-2", Name: "old", State: "stopped"}}, nil
+		},
+		"natgw": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []row{{ID: "nat-a", State: "available", VpcID: "vpc-a"}, {ID: "nat-b", Name: "idle", State: "available", VpcID: "vpc-b", Cost: 32.85}}, nil
+		},
+		"sqs": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return nil, errors.New("access denied")
+		},
+		"ecr-images": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []row{}, nil
+		},
+		"cwlogs-groups": func(_ *Server, _ context.Context, _, _ string) (any, error) {
+			return []row{{ID: "ignored", Name: "/kept", RetainDays: 7}}, nil
+		},
+	} {
+		orig := regionalResources[service]
+		regionalResources[service] = load
+		t.Cleanup(func() { regionalResources[service] = orig })
+	}
+
+	do := func(t *testing.T, url string) *httptest.ResponseRecorder {
+		t.Helper()
+		s := newTestServer(t)
+		w := httptest.NewRecorder()
+		s.handleWaste(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+	const query = "?profiles=prod&regions=ap-northeast-1"
+
+	t.Run("json", func(t *testing.T) {
+		w := do(t, "/api/waste"+query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+		}
+		var body WasteResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		var got []string
+		for _, f := range body.Findings {
+			got = append(got, f.Rule+"/"+f.ID)
+		}
+		if want := []string{"natgw-no-instances/nat-b", "ec2-stopped/i-2"}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("findings = %v, want %v", got, want)
+		}
+		if body.TotalMonthlySavingUSD != 32.85 {
+			t.Errorf("total = %v, want 32.85", body.TotalMonthlySavingUSD)
+		}
+		if len(body.Errors) != 1 || body.Errors[0].Service != "sqs" {
+			t.Errorf("errors = %+v, want the sqs failure", body.Errors)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := do(t, "/api/waste"+query+"&format=csv")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 4 || lines[0] != strings.Join(wasteCSVHeader, ",") || !strings.HasSuffix(lines[1], ",32.85") {
+			t.Fatalf("body = %q, want the header, 2 findings and the sqs failure", w.Body.String())
+		}
+		if want := "error,sqs,prod,ap-northeast-1,access denied,,,,"; lines[3] != want {
+			t.Errorf("last line = %q, want %q", lines[3], want)
+		}
+		if got := w.Header().Get("X-Audit-Errors"); got != "1" {
+			t.Errorf("X-Audit-Errors = %q, want 1 for the failed sqs list", got)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if w := do(t, "/api/waste"+query+"&format=xml"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -11,7 +11,7 @@ type ErrorResponse struct {
 
 // CacheHeaders holds the values written to X-Cache-* response headers.
 type CacheHeaders struct {
-	Status    string // HIT or MISS
+	Status    string // HIT, MISS or STALE
 	CachedAt  time.Time
 	ExpiresAt time.Time
 	TTL       int
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// prewarmTarget は Config.Prewarm の 1 要素 ("profile/region/service") を表す。
+type prewarmTarget struct {
+	profile string
+	region  string
+	service string
+}
+
+func (t prewarmTarget) key() string {
+	return cacheKey(t.service, t.profile, t.region)
+}
+
+// parsePrewarmTargets は Config.Prewarm を解釈する。設定ミスに起動時に気付けるよう、形式が不正な要素や
+// regionalResources にないサービスはエラーとする。
+func parsePrewarmTargets(specs []string) ([]prewarmTarget, error) {
+	targets := make([]prewarmTarget, 0, len(specs))
+	for _, spec := range specs {
+		parts := strings.Split(spec, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid prewarm target %q: want profile/region/service", spec)
+		}
+		t := prewarmTarget{profile: parts[0], region: parts[1], service: parts[2]}
+		if _, ok := regionalResources[t.service]; !ok {
+			return nil, fmt.Errorf("invalid prewarm target %q: unknown service %q", spec, t.service)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// runPrewarm は起動直後と interval ごとに targets を取得して resourceCache に書き込む。
+// ctx (サーバのコンテキスト) が終了すると停止する。
+func (s *Server) runPrewarm(ctx context.Context, targets []prewarmTarget, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.prewarm(ctx, targets, interval)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// prewarm は未取得のエントリと、次の確認 (interval 後) までに期限切れになるエントリを取得し直す。
+// AWS API へのリクエストが一度に集中しないよう、対象は順番に取得する。
+func (s *Server) prewarm(ctx context.Context, targets []prewarmTarget, interval time.Duration) {
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		key := t.key()
+		if e, ok := s.resourceCache.Get(key); ok && time.Until(e.Expiry) > interval {
+			continue
+		}
+		load := regionalResources[t.service]
+		_, err := s.resourceCache.Refresh(ctx, key, cacheTTL, func(ctx context.Context) (any, error) {
+			return load(s, ctx, t.profile, t.region)
+		})
+		if err != nil {
+			slog.Warn("prewarm failed", "key", key, "err", err)
+		}
+	}
+}
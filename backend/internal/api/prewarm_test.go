@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParsePrewarmTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []prewarmTarget
+		wantErr bool
+	}{
+		{name: "empty", specs: nil, want: []prewarmTarget{}},
+		{
+			name:  "valid",
+			specs: []string{"prod/ap-northeast-1/ecs", "dev/us-east-1/ec2"},
+			want: []prewarmTarget{
+				{profile: "prod", region: "ap-northeast-1", service: "ecs"},
+				{profile: "dev", region: "us-east-1", service: "ec2"},
+			},
+		},
+		{name: "missing part", specs: []string{"prod/ecs"}, wantErr: true},
+		{name: "empty region", specs: []string{"prod//ecs"}, wantErr: true},
+		{name: "unknown service", specs: []string{"prod/ap-northeast-1/ecs-tasks"}, wantErr: true},
+		{name: "empty profile", specs: []string{"/ap-northeast-1/ecs"}, wantErr: true},
+		{
+			name:  "profile with a space",
+			specs: []string{"CT Audit/ap-northeast-1/ecs"},
+			want:  []prewarmTarget{{profile: "CT Audit", region: "ap-northeast-1", service: "ecs"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePrewarmTargets(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("target[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPrewarmRefreshesMissingAndExpiringEntries(t *testing.T) {
+	var calls atomic.Int32
+	regionalResources["prewarm-test"] = func(_ *Server, _ context.Context, profile, region string) (any, error) {
+		calls.Add(1)
+		return []string{profile, region}, nil
+	}
+	t.Cleanup(func() { delete(regionalResources, "prewarm-test") })
+
+	s := newTestServer(t)
+	targets := []prewarmTarget{
+		{profile: "missing", region: "ap-northeast-1", service: "prewarm-test"},
+		{profile: "expiring", region: "ap-northeast-1", service: "prewarm-test"},
+		{profile: "fresh", region: "ap-northeast-1", service: "prewarm-test"},
+	}
+	s.resourceCache.Set(targets[1].key(), "old", 5*time.Minute)
+	s.resourceCache.Set(targets[2].key(), "old", time.Hour)
+
+	s.prewarm(context.Background(), targets, 30*time.Minute)
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("loader calls = %d, want 2 (missing and expiring only)", got)
+	}
+	for _, tg := range targets[:2] {
+		e, ok := s.resourceCache.Get(tg.key())
+		if !ok {
+			t.Fatalf("expected %q to be cached", tg.key())
+		}
+		if time.Until(e.Expiry) <= 30*time.Minute {
+			t.Errorf("%q expires in %v, want a fresh cacheTTL", tg.key(), time.Until(e.Expiry))
+		}
+	}
+	if e, _ := s.resourceCache.Get(targets[2].key()); e.Value != "old" {
+		t.Errorf("fresh entry was replaced: %v", e.Value)
+	}
+}
@@ -23,6 +23,10 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ecs/{cluster}/tasks/{task}/containers", s.handleECSContainers)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ec2/{instance}/session", s.handleEC2Session)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ecs/{cluster}/tasks/{task}/exec", s.handleECSExec)
+	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ec2/{instance}/files", s.handleEC2FileDownload)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/ec2/{instance}/files", s.handleEC2FileUpload)
+	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ecs/{cluster}/tasks/{task}/files", s.handleECSFileDownload)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/ecs/{cluster}/tasks/{task}/files", s.handleECSFileUpload)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ecr", s.handleECR)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/ecr/{repo}/images", s.handleECRImages)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/s3", s.handleS3)
@@ -30,6 +34,11 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/s3/{bucket}/objects/download", s.handleS3ObjectDownload)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/s3/{bucket}/objects/preview", s.handleS3ObjectPreview)
 	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/s3/{bucket}/objects/upload", s.handleS3ObjectUpload)
+	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/s3/{bucket}/objects/versions", s.handleS3ObjectVersions)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/s3/{bucket}/objects/restore", s.handleS3ObjectRestore)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/s3/{bucket}/objects/delete", s.handleS3ObjectDelete)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/s3/{bucket}/objects/copy", s.handleS3ObjectCopy)
+	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/s3/{bucket}/objects/presign", s.handleS3ObjectPresign)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/iam", s.handleIAM)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/sso", s.handleSSO)
 	s.mux.HandleFunc("POST /api/aws/profiles/{profile}/sso/login", s.handleSSOLogin)
@@ -59,6 +68,7 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/natgw", s.handleNATGW)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/sqs", s.handleSQS)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/waf", s.handleWAF)
+	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/graph", s.handleResourceGraph)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/cost", s.handleCost)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/cost/forecast", s.handleCostForecast)
 	s.mux.HandleFunc("GET /api/aws/profiles/{profile}/pricing", s.handlePricing)
@@ -98,6 +108,7 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /api/gcp/gcs/{bucket}/objects/download", s.handleGCPGCSObjectDownload)
 	s.mux.HandleFunc("GET /api/gcp/gcs/{bucket}/objects/preview", s.handleGCPGCSObjectPreview)
 	s.mux.HandleFunc("POST /api/gcp/gcs/{bucket}/objects/upload", s.handleGCPGCSObjectUpload)
+	s.mux.HandleFunc("POST /api/gcp/gcs/{bucket}/objects/sign", s.handleGCPGCSObjectSign)
 	s.mux.HandleFunc("GET /api/gcp/iam", s.handleGCPIAM)
 	s.mux.HandleFunc("GET /api/gcp/serviceaccounts", s.handleGCPServiceAccounts)
 	s.mux.HandleFunc("GET /api/gcp/logging/entries", s.handleGCPLoggingEntries)
@@ -112,6 +123,35 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /api/tidb/projects/{project_id}/clusters", s.handleTiDBClusters)
 	s.mux.HandleFunc("GET /api/tidb/cost", s.handleTiDBCost)
 
+	// リソースキャッシュ (resourceCache) の確認と無効化
+	s.mux.HandleFunc("GET /api/cache", s.handleCacheInfo)
+	s.mux.HandleFunc("DELETE /api/cache", s.handleCacheInvalidate)
+
+	// 取得済みリソースのサービス・プロファイル・リージョン横断検索
+	s.mux.HandleFunc("GET /api/search", s.handleSearch)
+
+	// タグポリシー監査 (必須タグ・許可値に違反したリソースの一覧)
+	s.mux.HandleFunc("GET /api/audit/tags", s.handleTagAudit)
+
+	// セキュリティチェック (公開・未暗号化のバケット、MFA のない IAM ユーザー、HTTP のみの ALB など)
+	s.mux.HandleFunc("GET /api/audit/security", s.handleSecurityAudit)
+
+	// 使われていない可能性が高いリソースと月額の節約見込み
+	s.mux.HandleFunc("GET /api/waste", s.handleWaste)
+
+	// S3 / GCS への ?upload_id= 付きアップロードの進捗 (Server-Sent Events)
+	s.mux.HandleFunc("GET /api/uploads/{id}/events", s.handleUploadEvents)
+
+	// 稼働中のブラウザターミナルセッション (EC2 Session / ECS Exec)
+	s.mux.HandleFunc("GET /api/sessions", s.handleSessionsList)
+	s.mux.HandleFunc("GET /api/sessions/{id}/attach", s.handleSessionAttach)
+	s.mux.HandleFunc("DELETE /api/sessions/{id}", s.handleSessionDelete)
+
+	// セッション記録 (EC2 Session / ECS Exec の asciicast v2 ファイル)
+	s.mux.HandleFunc("GET /api/recordings", s.handleRecordingsList)
+	s.mux.HandleFunc("GET /api/recordings/{id}/download", s.handleRecordingDownload)
+	s.mux.HandleFunc("GET /api/recordings/{id}/replay", s.handleRecordingReplay)
+
 	// クエリスニペット (サービス別ディレクトリへのローカルファイル保存)
 	s.mux.HandleFunc("GET /api/snippets/{service}", s.handleSnippetsList)
 	s.mux.HandleFunc("POST /api/snippets/{service}", s.handleSnippetSave)
@@ -2,43 +2,82 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
 
 	bqclient "github.com/sfuruya0612/thief/backend/internal/bigquery"
 	"github.com/sfuruya0612/thief/backend/internal/cache"
 	"github.com/sfuruya0612/thief/backend/internal/config"
 	ddclient "github.com/sfuruya0612/thief/backend/internal/datadog"
+	"github.com/sfuruya0612/thief/backend/internal/recording"
+	"github.com/sfuruya0612/thief/backend/internal/search"
+	"github.com/sfuruya0612/thief/backend/internal/session"
 	"github.com/sfuruya0612/thief/backend/internal/snippet"
 	tidbclient "github.com/sfuruya0612/thief/backend/internal/tidb"
 )
 
 const cacheTTL = time.Hour
 
+// resourceCacheJanitorInterval は resourceCache の期限切れエントリ (永続化している場合はファイルも) を掃除する間隔。
+const resourceCacheJanitorInterval = 5 * time.Minute
+
 // regionsCacheTTL はリージョン一覧の長期キャッシュ TTL。
 // 有効化済みリージョンは頻繁に変わらないため 24 時間保持する。
 const regionsCacheTTL = 24 * time.Hour
 
 // Server holds all shared state for the HTTP API server.
 type Server struct {
-	cfg           *config.Config
-	bq            *bqclient.Client
-	ddV2          *ddclient.UsageMeteringV2API
-	ddCtx         context.Context
-	tidb          *tidbclient.Client
-	snippets      *snippet.Store
-	resourceCache *cache.Cache[any]
+	cfg            *config.Config
+	bq             *bqclient.Client
+	ddV2           *ddclient.UsageMeteringV2API
+	ddCtx          context.Context
+	tidb           *tidbclient.Client
+	snippets       *snippet.Store
+	recordings     *recording.Store
+	sessions       *session.Registry
+	uploads        *uploadTracker
+	estimatePrices *estimatePriceFetcher
+	resourceCache  *cache.Cache[any]
+	searchIndex    *search.Index
+	// searchRestore は resourceCache のディスク上の一覧を最初の検索でメモリに戻す (syncSearchIndex)。
+	searchRestore sync.Once
 	mux           *http.ServeMux
 }
 
 // NewServer initialises the API server. The BigQuery client is optional:
 // if projectID is empty or ADC fails, BigQuery endpoints return 503.
 func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
-	s := &Server{
-		cfg:           cfg,
-		resourceCache: cache.New[any](5 * time.Minute),
+	s := &Server{cfg: cfg}
+
+	// リソースキャッシュ。ResourceCacheDir が設定されていればディスクにも書き込み、再起動後もそのまま返す。
+	// CacheStaleTTL が設定されていれば、期限切れのエントリを STALE として返しつつバックグラウンドで再取得する。
+	// メモリ上のエントリは ResourceCacheMaxMB を超えると最も長く参照されていないものから追い出す。
+	prewarm, err := parsePrewarmTargets(cfg.Prewarm)
+	if err != nil {
+		return nil, err
+	}
+	opts := cache.Options[any]{
+		StaleWhileRevalidate: cfg.CacheStaleTTL,
+		MaxBytes:             int64(cfg.ResourceCacheMaxMB) << 20,
+	}
+	if cfg.ResourceCacheDir != "" {
+		store, err := cache.NewDiskStore(cfg.ResourceCacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("open resource cache dir: %w", err)
+		}
+		opts.Store = store
+		opts.Decode = decodeCachedJSON
+	}
+	s.resourceCache = cache.NewWithOptions(resourceCacheJanitorInterval, opts)
+	// リソース横断検索の索引。resourceCache のエントリから検索時に組み立てる (handleSearch)。
+	s.searchIndex = search.New()
+	if len(prewarm) > 0 {
+		go s.runPrewarm(ctx, prewarm, cfg.PrewarmInterval)
 	}
 
 	// BigQuery: try to initialise but don't fail server startup.
@@ -61,6 +100,24 @@ func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
 	// クエリスニペット (ローカルファイル保存)
 	s.snippets = snippet.NewStore(cfg.SnippetsDir)
 
+	// セッション記録 (ローカルファイル保存)。記録の有無に関わらず、保存済み記録の一覧・再生は常に提供する。
+	recordingsDir := cfg.RecordingsDir
+	if recordingsDir == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			return nil, fmt.Errorf("resolve recordings dir: %w", err)
+		}
+		recordingsDir = filepath.Join(dir, "recordings")
+	}
+	s.recordings = recording.NewStore(recordingsDir)
+
+	// 稼働中のブラウザターミナルセッション (一覧・強制終了 API 用)
+	s.sessions = session.NewRegistry()
+
+	// ?upload_id= 付きの S3 / GCS アップロードの進捗 (handleUploadEvents 用)
+	s.uploads = newUploadTracker()
+	s.estimatePrices = newEstimatePriceFetcher(pricingCacheDir(cfg.PriceCacheDir), s.invalidateEstimatedLists)
+
 	s.mux = http.NewServeMux()
 	s.registerRoutes()
 	return s, nil
@@ -102,6 +159,12 @@ func (s *Server) HTTPServer(ctx context.Context) *http.Server {
 	}
 }
 
+// decodeCachedJSON はディスクから復元したキャッシュ値を JSON のまま返す。resourceCache の値は
+// serveCached で JSON として書き出すだけなので、元の型に戻さなくても応答は変わらない。
+func decodeCachedJSON(b []byte) (any, error) {
+	return json.RawMessage(b), nil
+}
+
 // cacheKey builds a namespaced cache key to avoid collisions between services/profiles.
 func cacheKey(parts ...string) string {
 	key := ""
@@ -116,24 +179,37 @@ func cacheKey(parts ...string) string {
 
 // serveCached は resourceCache.Load の結果をキャッシュヘッダ付き JSON で書き出す。
 // キャッシュ応答を返すハンドラ共通のボイラープレート (Load → エラー → ヘッダ → JSON) を集約する。
+// load にはリクエストのコンテキストが渡されるが、STALE 応答後のバックグラウンド更新ではレスポンス送出後も
+// キャンセルされないコンテキストが渡される。load は r.Context() ではなく引数の ctx を使うこと。
 // エラー応答は onErr に委ねる。AWS リソース系は writeAWSError (SSO 期限切れで 401)、
 // それ以外 (cost / gcp / datadog / tidb / bq) は writeInternalError を渡し、
 // 既存のエラーレスポンス形状を変えないこと。
+// ?tag= が指定された場合は一覧の行をタグで絞り込む (キャッシュには絞り込む前の一覧を保持する)。
 func (s *Server) serveCached(
 	w http.ResponseWriter,
 	r *http.Request,
 	key string,
 	ttl time.Duration,
 	onErr func(http.ResponseWriter, error),
-	load func() (any, error),
+	load func(ctx context.Context) (any, error),
 ) {
-	entry, hit, err := s.resourceCache.Load(key, ttl, s.refresh(r), load)
+	filters, err := tagFilters(r)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+	entry, hit, err := s.resourceCache.Load(r.Context(), key, ttl, s.refresh(r), load)
 	if err != nil {
 		onErr(w, err)
 		return
 	}
+	value, err := filterByTags(entry.Value, filters)
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
 	writeCacheHeaders(w, cacheHeadersFrom(hit, entry))
-	writeJSON(w, entry.Value)
+	writeJSON(w, value)
 }
 
 func writeCacheHeaders(w http.ResponseWriter, headers CacheHeaders) {
@@ -1,15 +1,20 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sfuruya0612/thief/backend/internal/cache"
 	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/recording"
+	"github.com/sfuruya0612/thief/backend/internal/search"
+	"github.com/sfuruya0612/thief/backend/internal/session"
 	"github.com/sfuruya0612/thief/backend/internal/snippet"
 )
 
@@ -19,17 +24,23 @@ func newTestServer(t *testing.T) *Server {
 	t.Cleanup(c.Close)
 	cfg := config.Defaults()
 	cfg.PriceCacheDir = t.TempDir()
-	return &Server{
+	s := &Server{
 		cfg:           cfg,
 		snippets:      snippet.NewStore(t.TempDir()),
+		recordings:    recording.NewStore(t.TempDir()),
+		sessions:      session.NewRegistry(),
+		uploads:       newUploadTracker(),
 		resourceCache: c,
+		searchIndex:   search.New(),
 	}
+	s.estimatePrices = newEstimatePriceFetcher(pricingCacheDir(cfg.PriceCacheDir), s.invalidateEstimatedLists)
+	return s
 }
 
 func TestServeCachedMissThenHit(t *testing.T) {
 	s := newTestServer(t)
 	calls := 0
-	load := func() (any, error) {
+	load := func(context.Context) (any, error) {
 		calls++
 		return []string{"a", "b"}, nil
 	}
@@ -75,6 +86,51 @@ func TestServeCachedMissThenHit(t *testing.T) {
 	}
 }
 
+func TestServeCachedPersistentAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	newServer := func() *Server {
+		store, err := cache.NewDiskStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newTestServer(t)
+		s.resourceCache = cache.NewPersistent(time.Minute, store, decodeCachedJSON)
+		t.Cleanup(s.resourceCache.Close)
+		return s
+	}
+	do := func(s *Server, load func(context.Context) (any, error)) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/test", nil)
+		w := httptest.NewRecorder()
+		s.serveCached(w, r, cacheKey("ec2", "prod", "ap-northeast-1"), time.Minute, writeInternalFromError, load)
+		return w
+	}
+
+	first := do(newServer(), func(context.Context) (any, error) {
+		return []map[string]string{{"id": "i-1", "name": "web"}}, nil
+	})
+	if got := first.Header().Get("X-Cache-Status"); got != "MISS" {
+		t.Fatalf("X-Cache-Status = %q, want MISS", got)
+	}
+
+	// 再起動後のサーバはローダーを呼ばずにディスクの値を返し、キャッシュ時刻も引き継ぐ。
+	second := do(newServer(), func(context.Context) (any, error) {
+		t.Fatal("loader must not be called after restart")
+		return nil, nil
+	})
+	if got := second.Header().Get("X-Cache-Status"); got != "HIT" {
+		t.Errorf("X-Cache-Status = %q, want HIT", got)
+	}
+	for _, h := range []string{"X-Cached-At", "X-Cache-Expires-At"} {
+		if got, want := second.Header().Get(h), first.Header().Get(h); got != want {
+			t.Errorf("%s = %q, want %q", h, got, want)
+		}
+	}
+	if got, want := second.Body.String(), first.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
 func TestServeCachedLoaderError(t *testing.T) {
 	s := newTestServer(t)
 	loadErr := errors.New("boom")
@@ -85,7 +141,7 @@ func TestServeCachedLoaderError(t *testing.T) {
 	s.serveCached(w, r, "err-key", time.Minute, func(w http.ResponseWriter, err error) {
 		gotErr = err
 		writeInternalError(w, err.Error())
-	}, func() (any, error) {
+	}, func(context.Context) (any, error) {
 		return nil, loadErr
 	})
 
@@ -99,3 +155,94 @@ func TestServeCachedLoaderError(t *testing.T) {
 		t.Errorf("X-Cache-Status = %q, want empty on error", got)
 	}
 }
+
+// TestServeCachedTagFilter は ?tag= による一覧の絞り込みを検証する。キャッシュには絞り込む前の一覧が入り、
+// ディスクから復元した JSON の値 (tags がリスト形式のものを含む) も同じように絞り込めること。
+func TestServeCachedTagFilter(t *testing.T) {
+	type row struct {
+		ID   string            `json:"id"`
+		Tags map[string]string `json:"tags"`
+	}
+	typed := []row{
+		{ID: "a", Tags: map[string]string{"team": "payments", "env": "prod"}},
+		{ID: "b", Tags: map[string]string{"team": "payments", "env": "dev"}},
+		{ID: "c", Tags: map[string]string{"team": "search"}},
+		{ID: "d"},
+	}
+	restored := json.RawMessage(`[{"id":"a","tags":[{"key":"team","value":"payments"}]},{"id":"b","tags":[]}]`)
+
+	tests := []struct {
+		name     string
+		value    any
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "key:value", value: typed, query: "tag=team:payments", wantCode: http.StatusOK, wantIDs: []string{"a", "b"}},
+		{name: "different keys are ANDed", value: typed, query: "tag=team:payments&tag=env:prod", wantCode: http.StatusOK, wantIDs: []string{"a"}},
+		{name: "same key is ORed", value: typed, query: "tag=team:payments&tag=team:search", wantCode: http.StatusOK, wantIDs: []string{"a", "b", "c"}},
+		{name: "key only", value: typed, query: "tag=env", wantCode: http.StatusOK, wantIDs: []string{"a", "b"}},
+		{name: "restored json", value: restored, query: "tag=team=payments", wantCode: http.StatusOK, wantIDs: []string{"a"}},
+		{name: "no match", value: typed, query: "tag=team:none", wantCode: http.StatusOK, wantIDs: []string{}},
+		{name: "empty key", value: typed, query: "tag=:x", wantCode: http.StatusBadRequest},
+		{name: "not a list", value: map[string]string{"id": "x"}, query: "tag=team", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			r := httptest.NewRequest(http.MethodGet, "/test?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			s.serveCached(w, r, "tag-key", time.Minute, writeInternalFromError, func(context.Context) (any, error) {
+				return tt.value, nil
+			})
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var body []struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+			ids := []string{}
+			for _, b := range body {
+				ids = append(ids, b.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if entry, ok := s.resourceCache.Get("tag-key"); !ok || entry.Value == nil {
+				t.Error("expected the unfiltered list to be cached")
+			}
+		})
+	}
+}
+
+func TestServeCachedStale(t *testing.T) {
+	s := newTestServer(t)
+	s.resourceCache = cache.NewWithOptions(time.Minute, cache.Options[any]{StaleWhileRevalidate: time.Hour})
+	t.Cleanup(s.resourceCache.Close)
+	s.resourceCache.Set("stale-key", []string{"old"}, -time.Second)
+
+	refreshed := make(chan struct{})
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	s.serveCached(w, r, "stale-key", time.Minute, writeInternalFromError, func(context.Context) (any, error) {
+		defer close(refreshed)
+		return []string{"new"}, nil
+	})
+
+	if got := w.Header().Get("X-Cache-Status"); got != "STALE" {
+		t.Errorf("X-Cache-Status = %q, want STALE", got)
+	}
+	if got := w.Header().Get("X-Cache-TTL-Seconds"); got != "0" {
+		t.Errorf("X-Cache-TTL-Seconds = %q, want 0 for a stale entry", got)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `["old"]` {
+		t.Errorf("body = %s, want the stale value", got)
+	}
+	<-refreshed
+}
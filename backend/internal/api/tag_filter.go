@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// errTagFilterUnsupported は一覧 (JSON 配列) でない応答に ?tag= が指定された場合のエラー。
+var errTagFilterUnsupported = errors.New("tag filter is not supported for this endpoint")
+
+// tagFilters は ?tag= (繰り返し指定可、"key:value" / "key=value" / "key") を解釈する。
+func tagFilters(r *http.Request) ([]awsinternal.TagFilter, error) {
+	specs := r.URL.Query()["tag"]
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	return awsinternal.ParseTagFilters(specs)
+}
+
+// filterByTags は resourceCache の値 (リソースの一覧) のうち filters に一致する行だけを返す。
+// 値はロード直後なら元の型、ディスクから復元した場合は json.RawMessage のため、どちらも JSON の tags
+// フィールドで判定する。filters が空なら値をそのまま返す。
+func filterByTags(v any, filters []awsinternal.TagFilter) (any, error) {
+	if len(filters) == 0 {
+		return v, nil
+	}
+	var rows []json.RawMessage
+	if err := remarshal(v, &rows); err != nil {
+		return nil, errTagFilterUnsupported
+	}
+	return filterRowsByTags(rows, filters), nil
+}
+
+// filterRowsByTags は rows のうち tags が filters に一致する行を返す。
+func filterRowsByTags(rows []json.RawMessage, filters []awsinternal.TagFilter) []json.RawMessage {
+	out := make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		if awsinternal.MatchTags(awsinternal.TagsFromJSON(row), filters) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
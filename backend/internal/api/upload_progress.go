@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// uploadProgressRetention は完了・失敗したアップロードの進捗を残しておく時間。アップロードの完了後に
+// 進捗イベントへ接続したクライアントにも最終状態を返せるようにする。
+const uploadProgressRetention = time.Minute
+
+// uploadEventInterval は進捗イベントの最小送信間隔。パートごとの更新が続いてもイベントを溢れさせない。
+const uploadEventInterval = 250 * time.Millisecond
+
+// uploadIDPattern は ?upload_id= に使える文字。クライアントが生成する (crypto.randomUUID() など) 前提。
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// アップロードの状態。pending は進捗イベントへの接続が先で、アップロードがまだ始まっていないことを示す。
+const (
+	uploadPending   = "pending"
+	uploadUploading = "uploading"
+	uploadCompleted = "completed"
+	uploadFailed    = "failed"
+)
+
+// UploadProgress は handleUploadEvents が送る進捗イベントの内容。BytesUploaded はクラウド側に送り終えた
+// バイト数 (S3 はパート、GCS はチャンク単位で増える)。TotalBytes はクライアントが ?size= で渡した全長で、
+// 不明な場合は 0。
+type UploadProgress struct {
+	ID            string `json:"id"`
+	Bucket        string `json:"bucket"`
+	Key           string `json:"key"`
+	Status        string `json:"status"`
+	BytesUploaded int64  `json:"bytes_uploaded"`
+	TotalBytes    int64  `json:"total_bytes"`
+	Error         string `json:"error,omitempty"`
+}
+
+// uploadProgress は 1 アップロードの進捗。更新のたびに changed を閉じて作り直し、待っている購読者を起こす。
+type uploadProgress struct {
+	mu          sync.Mutex
+	state       UploadProgress
+	subscribers int
+	changed     chan struct{}
+}
+
+func (p *uploadProgress) update(fn func(*UploadProgress)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.state)
+	p.notifyLocked()
+}
+
+// notifyLocked は待っている購読者を起こす。p.mu を保持して呼ぶこと。
+func (p *uploadProgress) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// setBytes は BytesUploaded を更新する。アップロード関数の progress コールバックとして渡す。
+func (p *uploadProgress) setBytes(n int64) {
+	p.update(func(s *UploadProgress) { s.BytesUploaded = n })
+}
+
+// snapshot は現在の状態と、次に更新されたときに閉じるチャネルを返す。
+func (p *uploadProgress) snapshot() (UploadProgress, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.changed
+}
+
+// uploadTracker は進行中のアップロードの進捗を upload_id で管理する。アップロードのリクエストと
+// 進捗イベントの接続はどちらが先に届いてもよく、先に届いた側がエントリを作る。
+type uploadTracker struct {
+	mu      sync.Mutex
+	entries map[string]*uploadProgress
+}
+
+func newUploadTracker() *uploadTracker {
+	return &uploadTracker{entries: map[string]*uploadProgress{}}
+}
+
+// entry は id のエントリを返し、無ければ pending で作る。t.mu を保持して呼ぶこと。
+func (t *uploadTracker) entry(id string) *uploadProgress {
+	p, ok := t.entries[id]
+	if !ok {
+		p = &uploadProgress{state: UploadProgress{ID: id, Status: uploadPending}, changed: make(chan struct{})}
+		t.entries[id] = p
+	}
+	return p
+}
+
+// start は id のアップロードを開始し、終了時に結果を記録する finish を返す。同じ id のアップロードが
+// すでに始まっている (または終わって保持期間内の) 場合は false を返す。
+func (t *uploadTracker) start(id, bucket, key string, total int64) (p *uploadProgress, finish func(error), ok bool) {
+	// 購読者の release による pending エントリの削除と競合しないよう、t.mu を保持したまま状態を変える。
+	t.mu.Lock()
+	p = t.entry(id)
+	p.mu.Lock()
+	started := p.state.Status != uploadPending
+	if !started {
+		p.state.Bucket, p.state.Key, p.state.TotalBytes, p.state.Status = bucket, key, total, uploadUploading
+		p.notifyLocked()
+	}
+	p.mu.Unlock()
+	t.mu.Unlock()
+	if started {
+		return nil, nil, false
+	}
+
+	finish = func(err error) {
+		p.update(func(s *UploadProgress) {
+			s.Status = uploadCompleted
+			if err != nil {
+				s.Status, s.Error = uploadFailed, err.Error()
+			}
+		})
+		time.AfterFunc(uploadProgressRetention, func() { t.remove(id, p) })
+	}
+	return p, finish, true
+}
+
+// subscribe は進捗イベントの購読を登録する。release はアップロードが始まらないまま購読者が
+// いなくなった pending のエントリを消す。
+func (t *uploadTracker) subscribe(id string) (p *uploadProgress, release func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p = t.entry(id)
+	p.mu.Lock()
+	p.subscribers++
+	p.mu.Unlock()
+
+	return p, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.subscribers--
+		if p.subscribers == 0 && p.state.Status == uploadPending && t.entries[id] == p {
+			delete(t.entries, id)
+		}
+	}
+}
+
+// remove は id のエントリが p のままなら削除する (同じ id で作り直されたエントリは消さない)。
+func (t *uploadTracker) remove(id string, p *uploadProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.entries[id] == p {
+		delete(t.entries, id)
+	}
+}
+
+// handleUploadEvents は ?upload_id= 付きのアップロード (S3 / GCS 共通) の進捗を Server-Sent Events で返す。
+// 状態が変わるたびに UploadProgress を progress イベントとして送り、completed / failed を送ったら閉じる。
+// アップロードのリクエストより先に接続してよく、その間は pending を送って開始を待つ。
+func (s *Server) handleUploadEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !uploadIDPattern.MatchString(id) {
+		writeBadRequest(w, "invalid upload id")
+		return
+	}
+	p, release := s.uploads.subscribe(id)
+	defer release()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for {
+		state, changed := p.snapshot()
+		payload, err := json.Marshal(state)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: progress\ndata: %s\n\n", payload); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if state.Status == uploadCompleted || state.Status == uploadFailed {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(uploadEventInterval):
+		}
+	}
+}
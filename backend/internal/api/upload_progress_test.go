@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// newUploadRequest は note フィールドと file パートを持つ multipart/form-data のアップロードリクエストを作る。
+func newUploadRequest(t *testing.T, query, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("note", "ignored"); err != nil {
+		t.Fatal(err)
+	}
+	if content != "" {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="file"; filename="dump.sql"`},
+			"Content-Type":        {"application/sql"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(part, content)
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload?key=dump.sql"+query, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestReceiveObjectUpload(t *testing.T) {
+	s := newTestServer(t)
+	var got, gotType string
+	upload := func(_ context.Context, body io.Reader, contentType string, progress func(int64)) (int64, error) {
+		b, err := io.ReadAll(body)
+		got, gotType = string(b), contentType
+		if progress != nil {
+			progress(int64(len(b)))
+		}
+		return int64(len(b)), err
+	}
+
+	w := httptest.NewRecorder()
+	if !s.receiveObjectUpload(w, newUploadRequest(t, "&upload_id=u-1&size=11", "hello world"), "bucket", "dump.sql", writeAWSError, upload) {
+		t.Fatalf("upload failed: %d %s", w.Code, w.Body.String())
+	}
+	if got != "hello world" || gotType != "application/sql" {
+		t.Errorf("uploaded %q (%s), want the file part", got, gotType)
+	}
+	state, _ := s.uploads.entries["u-1"].snapshot()
+	if want := (UploadProgress{ID: "u-1", Bucket: "bucket", Key: "dump.sql", Status: uploadCompleted, BytesUploaded: 11, TotalBytes: 11}); state != want {
+		t.Errorf("progress = %+v, want %+v", state, want)
+	}
+
+	w = httptest.NewRecorder()
+	if s.receiveObjectUpload(w, newUploadRequest(t, "&upload_id=u-1", "again"), "bucket", "dump.sql", writeAWSError, upload) || w.Code != http.StatusConflict {
+		t.Errorf("reused upload_id status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = httptest.NewRecorder()
+	if s.receiveObjectUpload(w, newUploadRequest(t, "&upload_id=u-2", ""), "bucket", "dump.sql", writeAWSError, upload) || w.Code != http.StatusBadRequest {
+		t.Errorf("missing file part status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if state, _ := s.uploads.entries["u-2"].snapshot(); state.Status != uploadFailed || state.Error == "" {
+		t.Errorf("progress = %+v, want failed with an error", state)
+	}
+
+	w = httptest.NewRecorder()
+	if s.receiveObjectUpload(w, newUploadRequest(t, "&upload_id=a/b", "x"), "bucket", "dump.sql", writeAWSError, upload) || w.Code != http.StatusBadRequest {
+		t.Errorf("invalid upload_id status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+// TestHandleUploadEvents はアップロードより先に接続した購読者が pending から completed までを受け取ることを検証する。
+func TestHandleUploadEvents(t *testing.T) {
+	s := newTestServer(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/uploads/{id}/events", s.handleUploadEvents)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/uploads/u-1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	events := bufio.NewScanner(resp.Body)
+	next := func() (UploadProgress, bool) {
+		t.Helper()
+		var p UploadProgress
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				if err := json.Unmarshal([]byte(data), &p); err != nil {
+					t.Fatalf("unmarshal event %q: %v", data, err)
+				}
+				return p, true
+			}
+		}
+		return p, false
+	}
+
+	if p, _ := next(); p.Status != uploadPending {
+		t.Fatalf("first event = %+v, want pending", p)
+	}
+	p, finish, ok := s.uploads.start("u-1", "bucket", "dump.sql", 100)
+	if !ok {
+		t.Fatal("start returned false for a pending upload")
+	}
+	p.setBytes(40)
+	p.setBytes(100)
+	finish(nil)
+
+	var last UploadProgress
+	for {
+		p, ok := next()
+		if !ok {
+			break
+		}
+		last = p
+	}
+	if last.Status != uploadCompleted || last.BytesUploaded != 100 || last.TotalBytes != 100 {
+		t.Errorf("last event = %+v, want completed with 100 bytes", last)
+	}
+}
+
+func TestUploadTrackerRelease(t *testing.T) {
+	tr := newUploadTracker()
+	_, release := tr.subscribe("u-1")
+	_, release2 := tr.subscribe("u-1")
+	release()
+	if _, ok := tr.entries["u-1"]; !ok {
+		t.Fatal("pending entry removed while a subscriber remains")
+	}
+	release2()
+	if _, ok := tr.entries["u-1"]; ok {
+		t.Error("pending entry kept after the last subscriber left")
+	}
+
+	_, release = tr.subscribe("u-2")
+	if _, _, ok := tr.start("u-2", "bucket", "key", 0); !ok {
+		t.Fatal("start returned false for a pending upload")
+	}
+	release()
+	if _, ok := tr.entries["u-2"]; !ok {
+		t.Error("started upload removed when its subscriber left")
+	}
+}
@@ -0,0 +1,370 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// ErrInvalidSecurityCheck は存在しないチェック ID や重要度が指定されたことを表す。
+var ErrInvalidSecurityCheck = errors.New("invalid security check")
+
+// Severity はセキュリティチェックの重要度。
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+// severities は重要度を高い順に並べたもの。
+var severities = []Severity{SeverityHigh, SeverityMedium, SeverityLow}
+
+// ParseSeverity は重要度の名前 (high / medium / low、大文字小文字を区別しない) を Severity に変換する。
+func ParseSeverity(v string) (Severity, error) {
+	s := Severity(strings.ToLower(strings.TrimSpace(v)))
+	if !slices.Contains(severities, s) {
+		return "", fmt.Errorf("%w: unknown severity %q (available: high, medium, low)", ErrInvalidSecurityCheck, v)
+	}
+	return s, nil
+}
+
+// AtLeast は s が minSeverity 以上の重要度なら true を返す。
+func (s Severity) AtLeast(minSeverity Severity) bool {
+	return slices.Index(severities, s) <= slices.Index(severities, minSeverity)
+}
+
+// SecurityInventory は 1 つの profile/region のセキュリティチェックに使う一覧。S3 と IAM は global なサービスのため、
+// プロファイルの最初の profile/region の組にだけ入る。
+type SecurityInventory struct {
+	S3  []awsinternal.S3Resource
+	IAM []awsinternal.IAMResource
+	ELB []awsinternal.ELBSecurityResource
+}
+
+// SecurityResource はチェックに該当したリソース。Region が空の場合は一覧を取得した組のリージョンを使う。
+type SecurityResource struct {
+	ID     string
+	Name   string
+	Region string
+	Detail string
+}
+
+// SecurityCheck は 1 つのセキュリティチェック。Service は Run が参照する一覧 (SecurityInventory のフィールドに
+// 対応する API サーバのキャッシュキー) で、チェックを選ぶとその一覧だけを取得する。SecurityChecks に追加すれば
+// CLI (thief audit security) と API (GET /api/audit/security) の両方で使える。
+type SecurityCheck struct {
+	ID          string                                                        `json:"id"`
+	Service     string                                                        `json:"service"`
+	Severity    Severity                                                      `json:"severity"`
+	Title       string                                                        `json:"title"`
+	Remediation string                                                        `json:"remediation"`
+	Run         func(inv SecurityInventory, now time.Time) []SecurityResource `json:"-"`
+}
+
+// SecurityFinding はチェックに該当したリソースと、重要度・対処方法。
+type SecurityFinding struct {
+	awsinternal.FanoutTarget
+	Check       string   `json:"check"`
+	Severity    Severity `json:"severity"`
+	Service     string   `json:"service"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Detail      string   `json:"detail"`
+	Remediation string   `json:"remediation"`
+}
+
+// ToRow converts SecurityFinding to a string slice suitable for table formatting.
+func (f SecurityFinding) ToRow() []string {
+	return []string{string(f.Severity), f.Check, f.AccountID, f.Profile, f.Region, f.Name, f.ID, f.Detail, f.Remediation}
+}
+
+// securityGlobalServices はリージョンに依存せず、プロファイルごとに 1 回だけ取得する一覧。
+var securityGlobalServices = []string{"s3", "iam"}
+
+// iamUnusedDays はこの日数より長く使われていない IAM ユーザーを未使用とみなす。
+const iamUnusedDays = 90
+
+// SecurityChecks は組み込みのセキュリティチェック。
+var SecurityChecks = []SecurityCheck{
+	{
+		ID:          "s3-public-bucket",
+		Service:     "s3",
+		Severity:    SeverityHigh,
+		Title:       "S3 bucket may be public",
+		Remediation: "Enable all four Block Public Access settings on the bucket unless it must be public",
+		Run: func(inv SecurityInventory, _ time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, b := range inv.S3 {
+				if b.Public {
+					out = append(out, SecurityResource{ID: b.ID, Name: b.Name, Region: b.Region, Detail: "Block Public Access is not fully enabled"})
+				}
+			}
+			return out
+		},
+	},
+	{
+		ID:          "s3-unencrypted-bucket",
+		Service:     "s3",
+		Severity:    SeverityMedium,
+		Title:       "S3 bucket has no default encryption",
+		Remediation: "Configure default encryption (SSE-S3 or SSE-KMS) on the bucket",
+		Run: func(inv SecurityInventory, _ time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, b := range inv.S3 {
+				if b.Encryption == "" || b.Encryption == "none" {
+					out = append(out, SecurityResource{ID: b.ID, Name: b.Name, Region: b.Region, Detail: "no default encryption configuration"})
+				}
+			}
+			return out
+		},
+	},
+	{
+		ID:          "iam-user-no-mfa",
+		Service:     "iam",
+		Severity:    SeverityHigh,
+		Title:       "IAM user has no MFA device",
+		Remediation: "Assign an MFA device to the user, or remove its console password if it only needs access keys",
+		Run: func(inv SecurityInventory, _ time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, u := range inv.IAM {
+				if u.Kind == "user" && !u.MFAEnabled {
+					out = append(out, SecurityResource{ID: u.ARN, Name: u.Name, Region: "global", Detail: "no MFA device"})
+				}
+			}
+			return out
+		},
+	},
+	{
+		ID:          "iam-user-unused",
+		Service:     "iam",
+		Severity:    SeverityMedium,
+		Title:       fmt.Sprintf("IAM user unused for %d days", iamUnusedDays),
+		Remediation: "Delete the user, or deactivate its console password and access keys",
+		Run: func(inv SecurityInventory, now time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, u := range inv.IAM {
+				if u.Kind != "user" {
+					continue
+				}
+				// 利用履歴がないユーザーは作成日時から数える (作成直後のユーザーを未使用としない)。
+				// どちらの日時も読めないユーザーは判定できないため対象外。
+				if last, err := time.Parse(time.RFC3339, u.LastActivity); err == nil {
+					if now.Sub(last) > iamUnusedDays*24*time.Hour {
+						out = append(out, SecurityResource{ID: u.ARN, Name: u.Name, Region: "global", Detail: "last used at " + u.LastActivity})
+					}
+					continue
+				}
+				if created, err := time.Parse(time.RFC3339, u.CreateDate); err == nil && now.Sub(created) > iamUnusedDays*24*time.Hour {
+					out = append(out, SecurityResource{ID: u.ARN, Name: u.Name, Region: "global", Detail: "no recorded password or access key use since creation at " + u.CreateDate})
+				}
+			}
+			return out
+		},
+	},
+	{
+		ID:          "elb-http-listener",
+		Service:     "elb-security",
+		Severity:    SeverityMedium,
+		Title:       "ALB serves plain HTTP",
+		Remediation: "Redirect the HTTP listener to HTTPS, or replace it with an HTTPS listener",
+		Run: func(inv SecurityInventory, _ time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, lb := range inv.ELB {
+				if lb.Type != "application" {
+					continue
+				}
+				for _, l := range lb.Listeners {
+					if l.Protocol == "HTTP" && l.DefaultActionType != "redirect" {
+						out = append(out, SecurityResource{ID: lb.ID, Name: lb.Name, Detail: fmt.Sprintf("HTTP listener on port %d does not redirect to HTTPS", l.Port)})
+					}
+				}
+			}
+			return out
+		},
+	},
+	{
+		ID:          "elb-no-waf",
+		Service:     "elb-security",
+		Severity:    SeverityLow,
+		Title:       "Internet-facing ALB has no WAF web ACL",
+		Remediation: "Associate a WAF web ACL (e.g. with the AWS managed core rule set) with the load balancer",
+		Run: func(inv SecurityInventory, _ time.Time) []SecurityResource {
+			var out []SecurityResource
+			for _, lb := range inv.ELB {
+				if lb.Type == "application" && lb.Scheme == "internet-facing" && lb.WebACLArn == "" {
+					out = append(out, SecurityResource{ID: lb.ID, Name: lb.Name, Detail: "no web ACL associated"})
+				}
+			}
+			return out
+		},
+	},
+}
+
+// SelectSecurityChecks は checks から ID が ids に含まれ (ids が空ならすべて)、重要度が minSeverity 以上のチェックを返す。
+func SelectSecurityChecks(checks []SecurityCheck, ids []string, minSeverity Severity) ([]SecurityCheck, error) {
+	for _, id := range ids {
+		if !slices.ContainsFunc(checks, func(c SecurityCheck) bool { return c.ID == id }) {
+			names := make([]string, len(checks))
+			for i, c := range checks {
+				names[i] = c.ID
+			}
+			return nil, fmt.Errorf("%w: unknown check %q (available: %s)", ErrInvalidSecurityCheck, id, strings.Join(names, ", "))
+		}
+	}
+	var selected []SecurityCheck
+	for _, c := range checks {
+		if (len(ids) == 0 || slices.Contains(ids, c.ID)) && c.Severity.AtLeast(minSeverity) {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+// RunSecurityChecks は inv に checks を適用する。
+func RunSecurityChecks(target awsinternal.FanoutTarget, inv SecurityInventory, checks []SecurityCheck, now time.Time) []SecurityFinding {
+	var findings []SecurityFinding
+	for _, c := range checks {
+		for _, r := range c.Run(inv, now) {
+			t := target
+			if r.Region != "" {
+				t.Region = r.Region
+			}
+			findings = append(findings, SecurityFinding{
+				FanoutTarget: t,
+				Check:        c.ID,
+				Severity:     c.Severity,
+				Service:      c.Service,
+				ID:           r.ID,
+				Name:         r.Name,
+				Detail:       r.Detail,
+				Remediation:  c.Remediation,
+			})
+		}
+	}
+	return findings
+}
+
+// SortSecurityFindings は findings を重要度の高い順 (同じ重要度はチェック・アカウント・リージョン・名前の順) に並べる。
+func SortSecurityFindings(findings []SecurityFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return slices.Index(severities, a.Severity) < slices.Index(severities, b.Severity)
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Name < b.Name
+	})
+}
+
+// CountBySeverity は findings の重要度ごとの件数を返す (該当のない重要度も 0 として含める)。
+func CountBySeverity(findings []SecurityFinding) map[Severity]int {
+	counts := map[Severity]int{}
+	for _, s := range severities {
+		counts[s] = 0
+	}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// SecurityServices は checks が参照する一覧を返す。
+func SecurityServices(checks []SecurityCheck) []string {
+	var services []string
+	for _, c := range checks {
+		if !slices.Contains(services, c.Service) {
+			services = append(services, c.Service)
+		}
+	}
+	return services
+}
+
+// SecurityListCount は CollectSecurity が targets について checks のために取得する一覧の数を返す。
+func SecurityListCount(targets []awsinternal.FanoutTarget, checks []SecurityCheck) int {
+	regional, global, globalTargets := securityPlan(targets, checks)
+	return len(targets)*len(regional) + len(globalTargets)*len(global)
+}
+
+// securityPlan は checks が参照する一覧を regional と global に分け、global な一覧を取得する組
+// (プロファイルごとの最初の組) を返す。
+func securityPlan(targets []awsinternal.FanoutTarget, checks []SecurityCheck) (regional, global []string, globalTargets []awsinternal.FanoutTarget) {
+	for _, service := range SecurityServices(checks) {
+		if slices.Contains(securityGlobalServices, service) {
+			global = append(global, service)
+		} else {
+			regional = append(regional, service)
+		}
+	}
+	for _, t := range targets {
+		if !slices.ContainsFunc(globalTargets, func(g awsinternal.FanoutTarget) bool { return g.Profile == t.Profile }) {
+			globalTargets = append(globalTargets, t)
+		}
+	}
+	return regional, global, globalTargets
+}
+
+// CollectSecurity は checks が参照する一覧を targets について並列に取得し、チェックを適用して重要度の高い順に返す。
+// global な一覧 (S3 / IAM) はプロファイルの最初の組でだけ取得する。一覧を取得できなかった組は Error として返し、
+// その組は取得できた一覧だけでチェックする。
+func CollectSecurity(ctx context.Context, targets []awsinternal.FanoutTarget, checks []SecurityCheck, list ListFunc, now time.Time) ([]SecurityFinding, []Error) {
+	regional, global, globalTargets := securityPlan(targets, checks)
+	regionalLists, errs := collect(ctx, targets, regional, list, decodeSecurityList)
+	globalLists, globalErrs := collect(ctx, globalTargets, global, list, decodeSecurityList)
+	errs = append(errs, globalErrs...)
+
+	findings := []SecurityFinding{}
+	for i, t := range targets {
+		var inv SecurityInventory
+		for j := range regional {
+			setSecurityList(&inv, regionalLists[i*len(regional)+j])
+		}
+		if k := slices.Index(globalTargets, t); k >= 0 {
+			for j := range global {
+				setSecurityList(&inv, globalLists[k*len(global)+j])
+			}
+		}
+		findings = append(findings, RunSecurityChecks(t, inv, checks, now)...)
+	}
+	SortSecurityFindings(findings)
+	return findings, errs
+}
+
+// decodeSecurityList は service の一覧を JSON 経由で SecurityInventory のフィールドの型に変換する。
+func decodeSecurityList(_ awsinternal.FanoutTarget, service string, v any) (any, error) {
+	switch service {
+	case "s3":
+		return decodeList[awsinternal.S3Resource](service, v)
+	case "iam":
+		return decodeList[awsinternal.IAMResource](service, v)
+	case "elb-security":
+		return decodeList[awsinternal.ELBSecurityResource](service, v)
+	}
+	return nil, fmt.Errorf("unknown security service %q", service)
+}
+
+func setSecurityList(inv *SecurityInventory, v any) {
+	switch v := v.(type) {
+	case []awsinternal.S3Resource:
+		inv.S3 = v
+	case []awsinternal.IAMResource:
+		inv.IAM = v
+	case []awsinternal.ELBSecurityResource:
+		inv.ELB = v
+	}
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+func TestSelectSecurityChecks(t *testing.T) {
+	ids := func(checks []SecurityCheck) []string {
+		var out []string
+		for _, c := range checks {
+			out = append(out, c.ID)
+		}
+		return out
+	}
+
+	got, err := SelectSecurityChecks(SecurityChecks, nil, SeverityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"s3-public-bucket", "iam-user-no-mfa"}, ids(got)); diff != "" {
+		t.Errorf("high checks mismatch (-want +got):\n%s", diff)
+	}
+	got, err = SelectSecurityChecks(SecurityChecks, []string{"elb-no-waf", "iam-user-unused"}, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"iam-user-unused", "elb-no-waf"}, ids(got)); diff != "" {
+		t.Errorf("selected checks mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := SelectSecurityChecks(SecurityChecks, []string{"ec2-open-ssh"}, SeverityLow); !errors.Is(err, ErrInvalidSecurityCheck) {
+		t.Errorf("err = %v, want ErrInvalidSecurityCheck", err)
+	}
+	if _, err := ParseSeverity("critical"); !errors.Is(err, ErrInvalidSecurityCheck) {
+		t.Errorf("ParseSeverity err = %v, want ErrInvalidSecurityCheck", err)
+	}
+}
+
+func TestRunSecurityChecks(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	target := awsinternal.FanoutTarget{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"}
+	alb := func(name, scheme, waf string, listeners ...awsinternal.ELBListenerResource) awsinternal.ELBSecurityResource {
+		return awsinternal.ELBSecurityResource{
+			ELBResource: awsinternal.ELBResource{ID: "arn:" + name, Name: name, Type: "application", Scheme: scheme},
+			Listeners:   listeners,
+			WebACLArn:   waf,
+		}
+	}
+	inv := SecurityInventory{
+		S3: []awsinternal.S3Resource{
+			{ID: "logs", Name: "logs", Region: "us-east-1", Public: true, Encryption: "AES256"},
+			{ID: "data", Name: "data", Region: "ap-northeast-1", Encryption: "none"},
+		},
+		IAM: []awsinternal.IAMResource{
+			{Name: "alice", ARN: "arn:user/alice", Kind: "user", MFAEnabled: true, LastActivity: "2026-05-01T00:00:00Z"},
+			{Name: "ci", ARN: "arn:user/ci", Kind: "user", MFAEnabled: true, LastActivity: "2026-01-01T00:00:00Z"},
+			{Name: "bob", ARN: "arn:user/bob", Kind: "user", CreateDate: "2025-01-01T00:00:00Z"},
+			{Name: "new", ARN: "arn:user/new", Kind: "user", MFAEnabled: true, CreateDate: "2026-05-20T00:00:00Z"},
+			{Name: "deploy", ARN: "arn:role/deploy", Kind: "role"},
+		},
+		ELB: []awsinternal.ELBSecurityResource{
+			alb("web", "internet-facing", "", awsinternal.ELBListenerResource{Protocol: "HTTP", Port: 80, DefaultActionType: "forward"}),
+			alb("api", "internet-facing", "arn:acl", awsinternal.ELBListenerResource{Protocol: "HTTP", Port: 80, DefaultActionType: "redirect"}),
+			alb("internal", "internal", ""),
+		},
+	}
+
+	got := RunSecurityChecks(target, inv, SecurityChecks, now)
+	SortSecurityFindings(got)
+	at := func(region string) awsinternal.FanoutTarget {
+		t := target
+		t.Region = region
+		return t
+	}
+	want := []SecurityFinding{
+		{FanoutTarget: at("global"), Check: "iam-user-no-mfa", Severity: SeverityHigh, Service: "iam", ID: "arn:user/bob", Name: "bob", Detail: "no MFA device", Remediation: SecurityChecks[2].Remediation},
+		{FanoutTarget: at("us-east-1"), Check: "s3-public-bucket", Severity: SeverityHigh, Service: "s3", ID: "logs", Name: "logs", Detail: "Block Public Access is not fully enabled", Remediation: SecurityChecks[0].Remediation},
+		{FanoutTarget: target, Check: "elb-http-listener", Severity: SeverityMedium, Service: "elb-security", ID: "arn:web", Name: "web", Detail: "HTTP listener on port 80 does not redirect to HTTPS", Remediation: SecurityChecks[4].Remediation},
+		{FanoutTarget: at("global"), Check: "iam-user-unused", Severity: SeverityMedium, Service: "iam", ID: "arn:user/bob", Name: "bob", Detail: "no recorded password or access key use since creation at 2025-01-01T00:00:00Z", Remediation: SecurityChecks[3].Remediation},
+		{FanoutTarget: at("global"), Check: "iam-user-unused", Severity: SeverityMedium, Service: "iam", ID: "arn:user/ci", Name: "ci", Detail: "last used at 2026-01-01T00:00:00Z", Remediation: SecurityChecks[3].Remediation},
+		{FanoutTarget: target, Check: "s3-unencrypted-bucket", Severity: SeverityMedium, Service: "s3", ID: "data", Name: "data", Detail: "no default encryption configuration", Remediation: SecurityChecks[1].Remediation},
+		{FanoutTarget: target, Check: "elb-no-waf", Severity: SeverityLow, Service: "elb-security", ID: "arn:web", Name: "web", Detail: "no web ACL associated", Remediation: SecurityChecks[5].Remediation},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want +got):\n%s", diff)
+	}
+	if counts := CountBySeverity(got); counts[SeverityHigh] != 2 || counts[SeverityMedium] != 4 || counts[SeverityLow] != 1 {
+		t.Errorf("CountBySeverity = %v, want high 2, medium 4, low 1", counts)
+	}
+}
+
+// TestCollectSecurity は global な一覧 (IAM) がプロファイルごとに 1 回だけ取得されることを検証する。
+func TestCollectSecurity(t *testing.T) {
+	targets := []awsinternal.FanoutTarget{
+		{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"},
+		{Profile: "prod", AccountID: "111111111111", Region: "us-east-1"},
+		{Profile: "dev", AccountID: "222222222222", Region: "ap-northeast-1"},
+	}
+	checks, err := SelectSecurityChecks(SecurityChecks, []string{"iam-user-no-mfa", "elb-no-waf"}, SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var calls []string
+	list := func(_ context.Context, service, profile, region string) (any, error) {
+		if service == "iam" {
+			mu.Lock()
+			calls = append(calls, profile+"/"+region)
+			mu.Unlock()
+			return []awsinternal.IAMResource{{Name: profile + "-user", Kind: "user"}}, nil
+		}
+		if profile == "dev" {
+			return nil, errors.New("access denied")
+		}
+		return []awsinternal.ELBSecurityResource{{ELBResource: awsinternal.ELBResource{Name: "alb-" + region, Type: "application", Scheme: "internet-facing"}}}, nil
+	}
+
+	findings, errs := CollectSecurity(context.Background(), targets, checks, list, time.Now())
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Check+"/"+f.Profile+"/"+f.Name)
+	}
+	want := []string{"iam-user-no-mfa/prod/prod-user", "iam-user-no-mfa/dev/dev-user", "elb-no-waf/prod/alb-ap-northeast-1", "elb-no-waf/prod/alb-us-east-1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want +got):\n%s", diff)
+	}
+	if len(calls) != 2 {
+		t.Errorf("iam listed %d times (%v), want once per profile", len(calls), calls)
+	}
+	if n := SecurityListCount(targets, checks); n != 5 {
+		t.Errorf("SecurityListCount = %d, want 5 (3 elb-security + 2 iam)", n)
+	}
+	wantErrs := []Error{{Service: "elb-security", Profile: "dev", Region: "ap-northeast-1", Error: "access denied"}}
+	if diff := cmp.Diff(wantErrs, errs); diff != "" {
+		t.Errorf("errors mismatch (-want +got):\n%s", diff)
+	}
+}
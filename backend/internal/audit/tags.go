@@ -0,0 +1,243 @@
+// Package audit は取得済みのリソース一覧に対するポリシー監査を提供する。
+// 一覧の取得 (Collect) は CLI と API サーバで共通にし、取得した一覧をポリシーと照合した結果を
+// サービス・アカウントごとにまとめて返す。
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrInvalidTagPolicy はタグポリシーの指定が不正な場合のエラー。
+var ErrInvalidTagPolicy = errors.New("invalid tag policy")
+
+// TagServices はタグ監査の対象サービス。API サーバのキャッシュキーと同じ名前にする
+// (API では各サービスの一覧をキャッシュ経由で取得するため)。
+var TagServices = []string{"ec2", "rds", "lambda", "sqs", "kinesis", "dynamo", "waf", "elasticache"}
+
+// TagPolicy はタグ監査の条件。
+type TagPolicy struct {
+	// Required はすべてのリソースに必須のタグのキー。値が空のタグも付いていないものとして扱う。
+	Required []string `json:"required"`
+	// Allowed はキーごとの許可値。タグが付いていて値が許可値に含まれない場合に違反とする
+	// (タグが付いていないことは Required で判定する)。
+	Allowed map[string][]string `json:"allowed,omitempty"`
+}
+
+// ParseTagPolicy は必須キーの一覧と "env=prod|stg|dev" 形式の許可値の指定からポリシーを作る。
+// 許可値の指定は --tag と同様に "env:prod|stg|dev" とも書け、"=" を含む指定は最初の "=" で分ける。
+func ParseTagPolicy(required, allowed []string) (TagPolicy, error) {
+	p := TagPolicy{}
+	for _, key := range required {
+		if key = strings.TrimSpace(key); key != "" && !slices.Contains(p.Required, key) {
+			p.Required = append(p.Required, key)
+		}
+	}
+	for _, spec := range allowed {
+		sep := ":"
+		if strings.Contains(spec, "=") {
+			sep = "="
+		}
+		key, values, _ := strings.Cut(spec, sep)
+		key = strings.TrimSpace(key)
+		var vals []string
+		for _, v := range strings.Split(values, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				vals = append(vals, v)
+			}
+		}
+		if key == "" || len(vals) == 0 {
+			return TagPolicy{}, fmt.Errorf("%w: %q (want key=value1|value2)", ErrInvalidTagPolicy, spec)
+		}
+		if p.Allowed == nil {
+			p.Allowed = map[string][]string{}
+		}
+		p.Allowed[key] = append(p.Allowed[key], vals...)
+	}
+	if len(p.Required) == 0 && len(p.Allowed) == 0 {
+		return TagPolicy{}, fmt.Errorf("%w: specify required keys or allowed values", ErrInvalidTagPolicy)
+	}
+	return p, nil
+}
+
+// Resource は監査対象の 1 リソースと取得元。
+type Resource struct {
+	awsinternal.FanoutTarget
+	Service string            `json:"service"`
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+}
+
+// TagViolation はタグポリシーに違反したリソース。Missing は付いていない必須キー、Disallowed は
+// 許可値に含まれない値が付いたキーとその値。
+type TagViolation struct {
+	Resource
+	Missing    []string          `json:"missing"`
+	Disallowed map[string]string `json:"disallowed"`
+}
+
+// ToRow converts TagViolation to a string slice suitable for table formatting.
+func (v TagViolation) ToRow() []string {
+	disallowed := make([]string, 0, len(v.Disallowed))
+	for _, k := range slices.Sorted(maps.Keys(v.Disallowed)) {
+		disallowed = append(disallowed, k+"="+v.Disallowed[k])
+	}
+	return []string{
+		v.Service, v.AccountID, v.Profile, v.Region, v.Name, v.ID,
+		strings.Join(v.Missing, ","), strings.Join(disallowed, ","),
+	}
+}
+
+// AuditTags は resources のうち policy に違反したものを、サービス・アカウント・リージョン・名前の順に返す。
+func AuditTags(resources []Resource, policy TagPolicy) []TagViolation {
+	violations := []TagViolation{}
+	for _, r := range resources {
+		v := TagViolation{Resource: r, Missing: []string{}, Disallowed: map[string]string{}}
+		for _, key := range policy.Required {
+			if strings.TrimSpace(r.Tags[key]) == "" {
+				v.Missing = append(v.Missing, key)
+			}
+		}
+		for key, allowed := range policy.Allowed {
+			if value, ok := r.Tags[key]; ok && strings.TrimSpace(value) != "" && !slices.Contains(allowed, value) {
+				v.Disallowed[key] = value
+			}
+		}
+		if len(v.Missing) > 0 || len(v.Disallowed) > 0 {
+			violations = append(violations, v)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Name < b.Name
+	})
+	return violations
+}
+
+// TagViolationGroup はサービスとアカウントごとの違反。
+type TagViolationGroup struct {
+	Service    string         `json:"service"`
+	AccountID  string         `json:"account_id"`
+	Count      int            `json:"count"`
+	Violations []TagViolation `json:"violations"`
+}
+
+// GroupTagViolations は AuditTags の結果をサービスとアカウントごとにまとめる。violations の順序を保つ。
+func GroupTagViolations(violations []TagViolation) []TagViolationGroup {
+	groups := []TagViolationGroup{}
+	for _, v := range violations {
+		if n := len(groups); n > 0 && groups[n-1].Service == v.Service && groups[n-1].AccountID == v.AccountID {
+			groups[n-1].Violations = append(groups[n-1].Violations, v)
+			groups[n-1].Count++
+			continue
+		}
+		groups = append(groups, TagViolationGroup{Service: v.Service, AccountID: v.AccountID, Count: 1, Violations: []TagViolation{v}})
+	}
+	return groups
+}
+
+// ListFunc は profile/region のサービスの一覧 (リソースのスライス、またはその JSON 表現) を返す。
+type ListFunc func(ctx context.Context, service, profile, region string) (any, error)
+
+// Error は一覧を取得できなかったサービスと profile/region の組とその理由。
+type Error struct {
+	Service string `json:"service"`
+	Profile string `json:"profile"`
+	Region  string `json:"region"`
+	Error   string `json:"error"`
+}
+
+// Collect は targets と services のすべての組の一覧を最大 awsinternal.DefaultFanoutConcurrency 並列で取得し、
+// Resource に変換して返す。取得できなかった組は Error として返し、他の組の結果は返す。
+// 結果の順序は targets、services の順。
+func Collect(ctx context.Context, targets []awsinternal.FanoutTarget, services []string, list ListFunc) ([]Resource, []Error) {
+	lists, errs := collect(ctx, targets, services, list, resourcesFrom)
+	resources := []Resource{}
+	for _, l := range lists {
+		resources = append(resources, l...)
+	}
+	return resources, errs
+}
+
+// collect は targets と services のすべての組の一覧を並列に取得し、convert で変換する。結果は
+// targets[i] と services[j] の組が i*len(services)+j 番目になるように並べ、取得または変換に失敗した組はゼロ値にする。
+func collect[T any](
+	ctx context.Context,
+	targets []awsinternal.FanoutTarget,
+	services []string,
+	list ListFunc,
+	convert func(target awsinternal.FanoutTarget, service string, v any) (T, error),
+) ([]T, []Error) {
+	values := make([]T, len(targets)*len(services))
+	failures := make([]*Error, len(values))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(awsinternal.DefaultFanoutConcurrency)
+	for i, t := range targets {
+		for j, service := range services {
+			idx := i*len(services) + j
+			g.Go(func() error {
+				v, err := list(gctx, service, t.Profile, t.Region)
+				if err == nil {
+					values[idx], err = convert(t, service, v)
+				}
+				if err != nil {
+					failures[idx] = &Error{Service: service, Profile: t.Profile, Region: t.Region, Error: err.Error()}
+				}
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+
+	var errs []Error
+	for _, f := range failures {
+		if f != nil {
+			errs = append(errs, *f)
+		}
+	}
+	return values, errs
+}
+
+// resourcesFrom は一覧を JSON 経由で Resource に変換する。一覧はロード直後の型付きのスライスと
+// ディスクから復元した json.RawMessage のどちらもあり得るため、id / name / tags フィールドで読む。
+func resourcesFrom(target awsinternal.FanoutTarget, service string, list any) ([]Resource, error) {
+	b, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s resources: %w", service, err)
+	}
+	var rows []struct {
+		ID   string            `json:"id"`
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return nil, fmt.Errorf("decode %s resources: %w", service, err)
+	}
+	resources := make([]Resource, len(rows))
+	for i, row := range rows {
+		resources[i] = Resource{FanoutTarget: target, Service: service, ID: row.ID, Name: row.Name, Tags: row.Tags}
+	}
+	return resources, nil
+}
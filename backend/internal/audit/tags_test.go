@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+func TestParseTagPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		allowed  []string
+		want     TagPolicy
+		wantErr  bool
+	}{
+		{
+			name:     "required keys are trimmed and deduplicated",
+			required: []string{"owner", " env", "owner", ""},
+			want:     TagPolicy{Required: []string{"owner", "env"}},
+		},
+		{
+			name:    "allowed values with = and :",
+			allowed: []string{"env=prod|stg", "tier:web", "aws:cloudformation:stack-name=core"},
+			want: TagPolicy{Allowed: map[string][]string{
+				"env":                           {"prod", "stg"},
+				"tier":                          {"web"},
+				"aws:cloudformation:stack-name": {"core"},
+			}},
+		},
+		{name: "allowed without values", allowed: []string{"env"}, wantErr: true},
+		{name: "allowed without key", allowed: []string{"=prod"}, wantErr: true},
+		{name: "empty policy", required: []string{" "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTagPolicy(tt.required, tt.allowed)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTagPolicy) {
+					t.Fatalf("err = %v, want ErrInvalidTagPolicy", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("policy mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuditTags(t *testing.T) {
+	prod := awsinternal.FanoutTarget{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"}
+	dev := awsinternal.FanoutTarget{Profile: "dev", AccountID: "222222222222", Region: "ap-northeast-1"}
+	resources := []Resource{
+		{FanoutTarget: prod, Service: "sqs", Name: "ok", Tags: map[string]string{"owner": "a", "env": "prod"}},
+		{FanoutTarget: dev, Service: "ec2", Name: "web", Tags: map[string]string{"owner": "a", "env": "qa"}},
+		{FanoutTarget: prod, Service: "ec2", Name: "db", Tags: map[string]string{"owner": " "}},
+		{FanoutTarget: prod, Service: "ec2", Name: "api"},
+	}
+	policy := TagPolicy{Required: []string{"owner", "env"}, Allowed: map[string][]string{"env": {"prod", "stg"}}}
+
+	got := AuditTags(resources, policy)
+	want := []TagViolation{
+		{Resource: resources[3], Missing: []string{"owner", "env"}, Disallowed: map[string]string{}},
+		{Resource: resources[2], Missing: []string{"owner", "env"}, Disallowed: map[string]string{}},
+		{Resource: resources[1], Missing: []string{}, Disallowed: map[string]string{"env": "qa"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("violations mismatch (-want +got):\n%s", diff)
+	}
+	if row := got[2].ToRow(); row[6] != "" || row[7] != "env=qa" {
+		t.Errorf("ToRow = %q, want empty missing and env=qa", row)
+	}
+
+	groups := GroupTagViolations(got)
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v, want 2 groups", groups)
+	}
+	if g := groups[0]; g.Service != "ec2" || g.AccountID != "111111111111" || g.Count != 2 {
+		t.Errorf("groups[0] = %s/%s count %d, want ec2/111111111111 count 2", g.Service, g.AccountID, g.Count)
+	}
+	if g := groups[1]; g.Service != "ec2" || g.AccountID != "222222222222" || g.Count != 1 {
+		t.Errorf("groups[1] = %s/%s count %d, want ec2/222222222222 count 1", g.Service, g.AccountID, g.Count)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	type row struct {
+		ID   string            `json:"id"`
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+	targets := []awsinternal.FanoutTarget{
+		{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"},
+		{Profile: "dev", AccountID: "222222222222", Region: "us-east-1"},
+	}
+	list := func(_ context.Context, service, profile, region string) (any, error) {
+		switch {
+		case service == "waf" && profile == "dev":
+			return nil, errors.New("access denied")
+		case service == "waf":
+			// ディスクから復元したキャッシュの値
+			return json.RawMessage(`[{"id":"w-1","name":"acl","tags":{"owner":"sec"}}]`), nil
+		default:
+			return []row{{ID: "i-" + profile, Name: "web-" + region}}, nil
+		}
+	}
+
+	resources, errs := Collect(context.Background(), targets, []string{"ec2", "waf"}, list)
+	want := []Resource{
+		{FanoutTarget: targets[0], Service: "ec2", ID: "i-prod", Name: "web-ap-northeast-1"},
+		{FanoutTarget: targets[0], Service: "waf", ID: "w-1", Name: "acl", Tags: map[string]string{"owner": "sec"}},
+		{FanoutTarget: targets[1], Service: "ec2", ID: "i-dev", Name: "web-us-east-1"},
+	}
+	if diff := cmp.Diff(want, resources); diff != "" {
+		t.Errorf("resources mismatch (-want +got):\n%s", diff)
+	}
+	wantErrs := []Error{{Service: "waf", Profile: "dev", Region: "us-east-1", Error: "access denied"}}
+	if diff := cmp.Diff(wantErrs, errs); diff != "" {
+		t.Errorf("errors mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+// WasteServices は無駄なリソースの検出に使う一覧。API サーバのキャッシュキーと同じ名前にする。
+var WasteServices = []string{"ec2", "natgw", "sqs", "ecr-images", "cwlogs-groups"}
+
+// bytesPerGB は保管量の表示に使う 1 GB のバイト数。
+const bytesPerGB = 1e9
+
+// unpulledImageMinAge は pull されていない ECR イメージを無駄とみなすまでの push からの経過時間。
+const unpulledImageMinAge = 30 * 24 * time.Hour
+
+// WasteInventory は 1 つの profile/region の無駄なリソースの検出に使う一覧。
+type WasteInventory struct {
+	EC2         []awsinternal.EC2Resource
+	NATGateways []awsinternal.NATGatewayResource
+	SQS         []awsinternal.SQSResource
+	ECRImages   []awsinternal.ECRImageResource
+	LogGroups   []awsinternal.LogGroupInfo
+
+	// EC2Listed は EC2 の一覧を取得できたか。取得できなかった profile/region では、稼働中の
+	// インスタンスがないと誤って判定しないよう NAT ゲートウェイの検出を行わない。
+	EC2Listed bool
+}
+
+// WasteFinding は使われていない可能性が高いリソースと、削除・設定変更による月額の節約見込み (USD)。
+// 節約額は一覧の CostMonthly (リージョンのレート表から推定した月額) で、見積もれない場合は 0。
+type WasteFinding struct {
+	awsinternal.FanoutTarget
+	Service          string  `json:"service"`
+	Rule             string  `json:"rule"`
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Reason           string  `json:"reason"`
+	MonthlySavingUSD float64 `json:"monthly_saving_usd"`
+}
+
+// ToRow converts WasteFinding to a string slice suitable for table formatting.
+func (f WasteFinding) ToRow() []string {
+	return []string{
+		f.Service, f.AccountID, f.Profile, f.Region, f.Rule, f.Name, f.ID, f.Reason,
+		strconv.FormatFloat(f.MonthlySavingUSD, 'f', 2, 64),
+	}
+}
+
+// DetectWaste は inv から使われていない可能性が高いリソースを検出する。
+//   - 停止中の EC2 インスタンス
+//   - 同じ VPC に稼働中の EC2 インスタンスがない NAT ゲートウェイ (Lambda・Fargate・RDS など EC2 以外の
+//     利用は確認しないため「使われていない可能性がある」とする。EC2 の一覧がなければ検出しない)
+//   - メッセージのない SQS キュー
+//   - push から 30 日以上 pull されていない ECR イメージ
+//   - 保持期間が設定されていない (無期限の) ロググループ
+//
+// 節約額は NAT ゲートウェイの時間料金、ECR イメージと無期限のロググループの保管料金で、いずれも一覧に
+// 設定された CostMonthly を使う。停止中の EC2 インスタンスの EBS ボリューム・Elastic IP は一覧に
+// 含まれないため、空の SQS キューは固定料金がない (リクエスト数だけで課金される) ため 0 とする。
+func DetectWaste(target awsinternal.FanoutTarget, inv WasteInventory, now time.Time) []WasteFinding {
+	var findings []WasteFinding
+	add := func(service, rule, id, name, reason string, saving float64) {
+		findings = append(findings, WasteFinding{
+			FanoutTarget:     target,
+			Service:          service,
+			Rule:             rule,
+			ID:               id,
+			Name:             name,
+			Reason:           reason,
+			MonthlySavingUSD: roundCents(saving),
+		})
+	}
+
+	runningVPCs := map[string]bool{}
+	for _, r := range inv.EC2 {
+		switch r.State {
+		case "running", "pending":
+			runningVPCs[r.VpcID] = true
+		case "stopped":
+			add("ec2", "ec2-stopped", r.ID, r.Name, "stopped; EBS volumes and Elastic IPs are still billed", 0)
+		}
+	}
+	for _, r := range inv.NATGateways {
+		if !inv.EC2Listed || r.State != "available" || runningVPCs[r.VpcID] {
+			continue
+		}
+		add("natgw", "natgw-no-instances", r.ID, r.Name, fmt.Sprintf("possibly idle: no running EC2 instances in %s (Lambda, Fargate and RDS are not checked)", r.VpcID), r.CostMonthly)
+	}
+	for _, r := range inv.SQS {
+		if r.AvailableMessages == 0 && r.InFlight == 0 {
+			add("sqs", "sqs-empty", r.ID, r.Name, "no visible or in-flight messages", 0)
+		}
+	}
+	for _, img := range inv.ECRImages {
+		pushed, err := time.Parse(time.RFC3339, img.PushedAt)
+		if img.LastPulledAt != "" || err != nil || now.Sub(pushed) < unpulledImageMinAge {
+			continue
+		}
+		tag := img.ImageTag
+		if tag == "" {
+			tag = "<untagged>"
+		}
+		add("ecr", "ecr-never-pulled", img.ImageDigest, img.RepositoryName+":"+tag,
+			fmt.Sprintf("never pulled since pushed at %s", img.PushedAt), img.CostMonthly)
+	}
+	for _, g := range inv.LogGroups {
+		if g.RetentionDays == 0 {
+			add("logs", "logs-no-retention", g.ARN, g.Name,
+				fmt.Sprintf("never expires (%.2f GB stored)", float64(g.StoredBytes)/bytesPerGB), g.CostMonthly)
+		}
+	}
+	return findings
+}
+
+// SortWasteFindings は findings を節約額の大きい順 (同額はサービス・アカウント・リージョン・名前の順) に並べる。
+func SortWasteFindings(findings []WasteFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.MonthlySavingUSD != b.MonthlySavingUSD {
+			return a.MonthlySavingUSD > b.MonthlySavingUSD
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Name < b.Name
+	})
+}
+
+// TotalMonthlySaving は findings の節約見込みの合計を返す。
+func TotalMonthlySaving(findings []WasteFinding) float64 {
+	total := 0.0
+	for _, f := range findings {
+		total += f.MonthlySavingUSD
+	}
+	return roundCents(total)
+}
+
+// CollectWaste は targets の WasteServices の一覧を並列に取得して DetectWaste を適用し、節約額の大きい順に返す。
+// 一覧を取得できなかった組は Error として返し、その profile/region は取得できた一覧だけで検出する。
+func CollectWaste(ctx context.Context, targets []awsinternal.FanoutTarget, list ListFunc, now time.Time) ([]WasteFinding, []Error) {
+	lists, errs := collect(ctx, targets, WasteServices, list, decodeWasteList)
+	findings := []WasteFinding{}
+	for i, t := range targets {
+		var inv WasteInventory
+		for j := range WasteServices {
+			switch v := lists[i*len(WasteServices)+j].(type) {
+			case []awsinternal.EC2Resource:
+				inv.EC2 = v
+				inv.EC2Listed = true
+			case []awsinternal.NATGatewayResource:
+				inv.NATGateways = v
+			case []awsinternal.SQSResource:
+				inv.SQS = v
+			case []awsinternal.ECRImageResource:
+				inv.ECRImages = v
+			case []awsinternal.LogGroupInfo:
+				inv.LogGroups = v
+			}
+		}
+		findings = append(findings, DetectWaste(t, inv, now)...)
+	}
+	SortWasteFindings(findings)
+	return findings, errs
+}
+
+// decodeWasteList は service の一覧を JSON 経由で WasteInventory のフィールドの型に変換する。一覧はロード直後の
+// 型付きのスライスとディスクから復元した json.RawMessage のどちらもあり得るため、いずれも同じように扱う。
+func decodeWasteList(_ awsinternal.FanoutTarget, service string, v any) (any, error) {
+	switch service {
+	case "ec2":
+		return decodeList[awsinternal.EC2Resource](service, v)
+	case "natgw":
+		return decodeList[awsinternal.NATGatewayResource](service, v)
+	case "sqs":
+		return decodeList[awsinternal.SQSResource](service, v)
+	case "ecr-images":
+		return decodeList[awsinternal.ECRImageResource](service, v)
+	case "cwlogs-groups":
+		return decodeList[awsinternal.LogGroupInfo](service, v)
+	}
+	return nil, fmt.Errorf("unknown waste service %q", service)
+}
+
+func decodeList[T any](service string, v any) ([]T, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s resources: %w", service, err)
+	}
+	var out []T
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("decode %s resources: %w", service, err)
+	}
+	return out, nil
+}
+
+// roundCents は金額をセント単位に丸める。
+func roundCents(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
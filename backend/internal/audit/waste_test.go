@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+func TestDetectWaste(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	target := awsinternal.FanoutTarget{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"}
+	inv := WasteInventory{
+		EC2: []awsinternal.EC2Resource{
+			{ID: "i-1", Name: "web", State: "running", VpcID: "vpc-a"},
+			{ID: "i-2", Name: "old", State: "stopped", VpcID: "vpc-b"},
+		},
+		NATGateways: []awsinternal.NATGatewayResource{
+			{ID: "nat-a", Name: "used", State: "available", VpcID: "vpc-a"},
+			{ID: "nat-b", Name: "idle", State: "available", VpcID: "vpc-b", CostMonthly: 45.26},
+			{ID: "nat-c", Name: "deleted", State: "deleted", VpcID: "vpc-c"},
+		},
+		EC2Listed: true,
+		SQS: []awsinternal.SQSResource{
+			{ID: "q-1", Name: "busy", AvailableMessages: 3},
+			{ID: "q-2", Name: "empty"},
+		},
+		ECRImages: []awsinternal.ECRImageResource{
+			{RepositoryName: "app", ImageTag: "v1", ImageDigest: "sha256:1", PushedAt: "2026-01-01T00:00:00Z", ImageSizeBytes: 2e9, CostMonthly: 0.2},
+			{RepositoryName: "app", ImageTag: "v2", ImageDigest: "sha256:2", PushedAt: "2026-01-01T00:00:00Z", LastPulledAt: "2026-05-01T00:00:00Z"},
+			{RepositoryName: "app", ImageDigest: "sha256:3", PushedAt: "2026-05-20T00:00:00Z"},
+			{RepositoryName: "app", ImageDigest: "sha256:4", PushedAt: "2026-02-01T00:00:00Z", ImageSizeBytes: 5e8, CostMonthly: 0.05},
+		},
+		LogGroups: []awsinternal.LogGroupInfo{
+			{Name: "/app", ARN: "arn:logs:/app", StoredBytes: 100e9, CostMonthly: 3.3},
+			{Name: "/kept", ARN: "arn:logs:/kept", StoredBytes: 100e9, RetentionDays: 30},
+		},
+	}
+
+	got := DetectWaste(target, inv, now)
+	SortWasteFindings(got)
+	want := []WasteFinding{
+		{FanoutTarget: target, Service: "natgw", Rule: "natgw-no-instances", ID: "nat-b", Name: "idle", Reason: "possibly idle: no running EC2 instances in vpc-b (Lambda, Fargate and RDS are not checked)", MonthlySavingUSD: 45.26},
+		{FanoutTarget: target, Service: "logs", Rule: "logs-no-retention", ID: "arn:logs:/app", Name: "/app", Reason: "never expires (100.00 GB stored)", MonthlySavingUSD: 3.3},
+		{FanoutTarget: target, Service: "ecr", Rule: "ecr-never-pulled", ID: "sha256:1", Name: "app:v1", Reason: "never pulled since pushed at 2026-01-01T00:00:00Z", MonthlySavingUSD: 0.2},
+		{FanoutTarget: target, Service: "ecr", Rule: "ecr-never-pulled", ID: "sha256:4", Name: "app:<untagged>", Reason: "never pulled since pushed at 2026-02-01T00:00:00Z", MonthlySavingUSD: 0.05},
+		{FanoutTarget: target, Service: "ec2", Rule: "ec2-stopped", ID: "i-2", Name: "old", Reason: "stopped; EBS volumes and Elastic IPs are still billed"},
+		{FanoutTarget: target, Service: "sqs", Rule: "sqs-empty", ID: "q-2", Name: "empty", Reason: "no visible or in-flight messages"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want +got):\n%s", diff)
+	}
+	if total := TotalMonthlySaving(got); total != 48.81 {
+		t.Errorf("TotalMonthlySaving = %v, want 48.81", total)
+	}
+	if row := got[0].ToRow(); row[8] != "45.26" {
+		t.Errorf("ToRow saving = %q, want 45.26", row[8])
+	}
+}
+
+func TestCollectWaste(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	targets := []awsinternal.FanoutTarget{
+		{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"},
+		{Profile: "dev", AccountID: "222222222222", Region: "us-east-1"},
+	}
+	list := func(_ context.Context, service, profile, _ string) (any, error) {
+		switch {
+		case service == "sqs" && profile == "dev":
+			return nil, errors.New("access denied")
+		case service == "sqs":
+			// ディスクから復元したキャッシュの値
+			return json.RawMessage(`[{"id":"q-1","name":"empty","available_messages":0}]`), nil
+		case service == "ec2" && profile == "dev":
+			return nil, errors.New("throttled")
+		case service == "ec2":
+			return []awsinternal.EC2Resource{{ID: "i-" + profile, Name: profile, State: "stopped", VpcID: "vpc-1"}}, nil
+		case service == "natgw":
+			// EC2 の一覧を取得できなかった dev では、稼働中のインスタンスの有無が分からないため検出しない
+			return []awsinternal.NATGatewayResource{{ID: "nat-" + profile, State: "available", VpcID: "vpc-1", CostMonthly: 32.85}}, nil
+		default:
+			return []struct{}{}, nil
+		}
+	}
+
+	findings, errs := CollectWaste(context.Background(), targets, list, now)
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Profile+"/"+f.Rule+"/"+f.ID)
+	}
+	want := []string{"prod/natgw-no-instances/nat-prod", "prod/ec2-stopped/i-prod", "prod/sqs-empty/q-1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want +got):\n%s", diff)
+	}
+	wantErrs := []Error{
+		{Service: "ec2", Profile: "dev", Region: "us-east-1", Error: "throttled"},
+		{Service: "sqs", Profile: "dev", Region: "us-east-1", Error: "access denied"},
+	}
+	if diff := cmp.Diff(wantErrs, errs); diff != "" {
+		t.Errorf("errors mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -298,12 +298,12 @@ func cfnResourceFromSDK(r cfntypes.StackResourceSummary) CFNStackResourceSummary
 
 // CfnStackSummary はレガシー CLI 互換の CloudFormation スタック一覧表示用フィールドを保持する。
 type CfnStackSummary struct {
-	StackName   string
-	Status      string
-	DriftStatus string
-	CreatedTime string
-	UpdatedTime string
-	Description string
+	StackName   string `json:"stack_name"`
+	Status      string `json:"status"`
+	DriftStatus string `json:"drift_status"`
+	CreatedTime string `json:"created_time"`
+	UpdatedTime string `json:"updated_time"`
+	Description string `json:"description"`
 }
 
 // ToRow converts CfnStackSummary to a string slice suitable for table formatting.
@@ -313,9 +313,9 @@ func (s CfnStackSummary) ToRow() []string {
 
 // CfnParameter holds a CloudFormation stack parameter key/value pair.
 type CfnParameter struct {
-	Key           string
-	Value         string
-	ResolvedValue string
+	Key           string `json:"key"`
+	Value         string `json:"value"`
+	ResolvedValue string `json:"resolved_value"`
 }
 
 // ToRow converts CfnParameter to a string slice suitable for table formatting.
@@ -325,10 +325,10 @@ func (p CfnParameter) ToRow() []string {
 
 // CfnOutput holds a CloudFormation stack output entry.
 type CfnOutput struct {
-	Key         string
-	Value       string
-	ExportName  string
-	Description string
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	ExportName  string `json:"export_name"`
+	Description string `json:"description"`
 }
 
 // ToRow converts CfnOutput to a string slice suitable for table formatting.
@@ -338,8 +338,8 @@ func (o CfnOutput) ToRow() []string {
 
 // CfnTag holds a CloudFormation stack tag key/value pair.
 type CfnTag struct {
-	Key   string
-	Value string
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 // ToRow converts CfnTag to a string slice suitable for table formatting.
@@ -349,23 +349,23 @@ func (t CfnTag) ToRow() []string {
 
 // CfnStackDetail はスタックの詳細 (パラメータ・出力・タグを含む) を保持する。
 type CfnStackDetail struct {
-	StackName   string
-	Status      string
-	DriftStatus string
-	CreatedTime string
-	UpdatedTime string
-	Description string
-	Parameters  []CfnParameter
-	Outputs     []CfnOutput
-	Tags        []CfnTag
+	StackName   string         `json:"stack_name"`
+	Status      string         `json:"status"`
+	DriftStatus string         `json:"drift_status"`
+	CreatedTime string         `json:"created_time"`
+	UpdatedTime string         `json:"updated_time"`
+	Description string         `json:"description"`
+	Parameters  []CfnParameter `json:"parameters"`
+	Outputs     []CfnOutput    `json:"outputs"`
+	Tags        []CfnTag       `json:"tags"`
 }
 
 // CfnChangeDetail は Change Set 内の 1 変更の表示用フィールドを保持する。
 type CfnChangeDetail struct {
-	Action       string
-	LogicalID    string
-	ResourceType string
-	Replacement  string
+	Action       string `json:"action"`
+	LogicalID    string `json:"logical_id"`
+	ResourceType string `json:"resource_type"`
+	Replacement  string `json:"replacement"`
 }
 
 // ToRow converts CfnChangeDetail to a string slice suitable for table formatting.
@@ -74,6 +74,37 @@ func CreateCloudFrontInvalidation(ctx context.Context, profile, distributionID s
 	return nil
 }
 
+// listCloudFrontDistributionArnsByWebACL は WAF (CLOUDFRONT スコープ) の Web ACL を使う全ディストリビューションの ARN を返す。
+func listCloudFrontDistributionArnsByWebACL(ctx context.Context, profile, webACLArn string) ([]string, error) {
+	client, err := newCloudFrontClient(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var arns []string
+	var marker *string
+	for {
+		out, err := client.ListDistributionsByWebACLId(ctx, &cloudfront.ListDistributionsByWebACLIdInput{
+			WebACLId: aws.String(webACLArn),
+			Marker:   marker,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list cloudfront distributions by web acl: %w", err)
+		}
+		if out.DistributionList == nil {
+			break
+		}
+		for _, d := range out.DistributionList.Items {
+			arns = append(arns, ptrStr(d.ARN))
+		}
+		if !ptrBool(out.DistributionList.IsTruncated) {
+			break
+		}
+		marker = out.DistributionList.NextMarker
+	}
+	return arns, nil
+}
+
 func cloudfrontFromSummary(d cftypes.DistributionSummary) CloudFrontResource {
 	var origins []string
 	if d.Origins != nil {
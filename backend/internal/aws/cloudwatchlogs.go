@@ -22,6 +22,9 @@ type LogGroupInfo struct {
 	StoredBytes   int64  `json:"stored_bytes"`
 	RetentionDays int32  `json:"retention_days"`
 	CreationTime  string `json:"creation_time"`
+	// CostMonthly は StoredBytes から推定した保管料金の月額 (USD)。一覧 API が
+	// ApplyLogGroupCostEstimates で設定する。単価を解決できない場合は 0。
+	CostMonthly float64 `json:"cost_monthly"`
 }
 
 // ToRow は CLI のテーブル表示用に 1 行分の文字列スライスを返す。
@@ -21,15 +21,16 @@ const dynamoItemQueryMaxLimit = 100
 
 // DynamoResource represents a DynamoDB table.
 type DynamoResource struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	State       string            `json:"state"`
-	Mode        string            `json:"mode"`
-	ItemCount   int64             `json:"item_count"`
-	SizeBytes   int64             `json:"size_bytes"`
-	GSICount    int               `json:"gsi_count"`
-	Tags        map[string]string `json:"tags"`
-	CostMonthly float64           `json:"cost_monthly"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	State     string            `json:"state"`
+	Mode      string            `json:"mode"`
+	ItemCount int64             `json:"item_count"`
+	SizeBytes int64             `json:"size_bytes"`
+	GSICount  int               `json:"gsi_count"`
+	Tags      map[string]string `json:"tags"`
+	// CostMonthly は常に nil (JSON では null)。キャパシティの設定と保存量のレート表がないため見積もらない。
+	CostMonthly *float64 `json:"cost_monthly"`
 }
 
 func (r DynamoResource) ResourceID() string    { return r.ID }
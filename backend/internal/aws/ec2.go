@@ -21,9 +21,12 @@ type EC2Resource struct {
 	PrivateIP    string            `json:"private_ip"`
 	PublicIP     string            `json:"public_ip"`
 	VpcID        string            `json:"vpc_id"`
+	Platform     string            `json:"platform"`
 	Tags         map[string]string `json:"tags"`
-	CostMonthly  float64           `json:"cost_monthly"`
-	LaunchTime   time.Time         `json:"launch_time"`
+	// CostMonthly は On-Demand 単価から推定した月額 (USD)。一覧 API が
+	// ApplyEC2CostEstimates で設定する。単価を解決できない場合は 0。
+	CostMonthly float64   `json:"cost_monthly"`
+	LaunchTime  time.Time `json:"launch_time"`
 }
 
 func (r EC2Resource) ResourceID() string    { return r.ID }
@@ -72,6 +75,7 @@ func ec2FromInstance(inst ec2types.Instance) EC2Resource {
 		PrivateIP:    ptrStr(inst.PrivateIpAddress),
 		PublicIP:     ptrStr(inst.PublicIpAddress),
 		VpcID:        ptrStr(inst.VpcId),
+		Platform:     ptrStr(inst.PlatformDetails),
 		Tags:         tags,
 		LaunchTime:   launch,
 	}
@@ -88,16 +92,17 @@ type EC2ListOptions struct {
 
 // EC2InstanceInfo はレガシー CLI 互換の EC2 表示用フィールドを保持する。
 type EC2InstanceInfo struct {
-	Name         string
-	InstanceID   string
-	InstanceType string
-	Lifecycle    string
-	PrivateIP    string
-	PublicIP     string
-	State        string
-	KeyName      string
-	AZ           string
-	LaunchTime   string
+	Name         string            `json:"name"`
+	InstanceID   string            `json:"instance_id"`
+	InstanceType string            `json:"instance_type"`
+	Lifecycle    string            `json:"lifecycle"`
+	PrivateIP    string            `json:"private_ip"`
+	PublicIP     string            `json:"public_ip"`
+	State        string            `json:"state"`
+	KeyName      string            `json:"key_name"`
+	AZ           string            `json:"az"`
+	LaunchTime   string            `json:"launch_time"`
+	Tags         map[string]string `json:"tags"`
 }
 
 // ToRow converts EC2InstanceInfo to a string slice suitable for table formatting.
@@ -108,6 +113,9 @@ func (i EC2InstanceInfo) ToRow() []string {
 	}
 }
 
+// ResourceTags returns the instance tags.
+func (i EC2InstanceInfo) ResourceTags() map[string]string { return i.Tags }
+
 // ListEC2Instances はレガシー CLI 互換のフィールドで EC2 インスタンス一覧を返す。
 // ListEC2Resources と異なり terminated を除外せず、running / instance-id フィルタに対応する。
 func ListEC2Instances(ctx context.Context, profile, region string, opts EC2ListOptions) ([]EC2InstanceInfo, error) {
@@ -182,8 +190,9 @@ func ec2InstanceInfoFromSDK(inst ec2types.Instance) EC2InstanceInfo {
 		launchTime = inst.LaunchTime.String()
 	}
 
+	tags := tagsToMap(inst.Tags)
 	return EC2InstanceInfo{
-		Name:         tagsToMap(inst.Tags)["Name"],
+		Name:         tags["Name"],
 		InstanceID:   ptrStr(inst.InstanceId),
 		InstanceType: string(inst.InstanceType),
 		Lifecycle:    lifecycle,
@@ -193,6 +202,7 @@ func ec2InstanceInfoFromSDK(inst ec2types.Instance) EC2InstanceInfo {
 		KeyName:      keyName,
 		AZ:           az,
 		LaunchTime:   launchTime,
+		Tags:         tags,
 	}
 }
 
@@ -236,6 +246,60 @@ func ptrBool(b *bool) bool {
 	return *b
 }
 
+// EC2NetworkInterfaceResource はインスタンスにアタッチされた ENI。
+type EC2NetworkInterfaceResource struct {
+	ID             string   `json:"id"`
+	Description    string   `json:"description"`
+	PrivateIP      string   `json:"private_ip"`
+	PublicIP       string   `json:"public_ip"`
+	SubnetID       string   `json:"subnet_id"`
+	VpcID          string   `json:"vpc_id"`
+	SecurityGroups []string `json:"security_groups"`
+}
+
+// ListEC2NetworkInterfaces はインスタンスにアタッチされた全 ENI を返す。
+func ListEC2NetworkInterfaces(ctx context.Context, profile, region, instanceID string) ([]EC2NetworkInterfaceResource, error) {
+	client, err := newEC2Client(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []EC2NetworkInterfaceResource
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(client, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("attachment.instance-id"), Values: []string{instanceID}}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe network interfaces for instance %s: %w", instanceID, err)
+		}
+		for _, ni := range page.NetworkInterfaces {
+			resources = append(resources, ec2NetworkInterfaceFromSDK(ni))
+		}
+	}
+	return resources, nil
+}
+
+func ec2NetworkInterfaceFromSDK(ni ec2types.NetworkInterface) EC2NetworkInterfaceResource {
+	publicIP := ""
+	if ni.Association != nil {
+		publicIP = ptrStr(ni.Association.PublicIp)
+	}
+	var groups []string
+	for _, g := range ni.Groups {
+		groups = append(groups, ptrStr(g.GroupId))
+	}
+	return EC2NetworkInterfaceResource{
+		ID:             ptrStr(ni.NetworkInterfaceId),
+		Description:    ptrStr(ni.Description),
+		PrivateIP:      ptrStr(ni.PrivateIpAddress),
+		PublicIP:       publicIP,
+		SubnetID:       ptrStr(ni.SubnetId),
+		VpcID:          ptrStr(ni.VpcId),
+		SecurityGroups: groups,
+	}
+}
+
 // newEC2Client は EC2 API クライアントを生成する。
 func newEC2Client(ctx context.Context, profile, region string) (*ec2.Client, error) {
 	return NewClient(ctx, profile, region, func(cfg aws.Config) *ec2.Client {
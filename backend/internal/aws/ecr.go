@@ -36,6 +36,9 @@ type ECRImageResource struct {
 	PushedAt       string `json:"pushed_at"`
 	LastPulledAt   string `json:"last_pulled_at"`
 	ImageSizeBytes int64  `json:"image_size_bytes"`
+	// CostMonthly はイメージサイズから推定したストレージ料金の月額 (USD)。一覧 API が
+	// ApplyECRImageCostEstimates で設定する。単価を解決できない場合は 0。
+	CostMonthly float64 `json:"cost_monthly"`
 }
 
 // ListECRResources returns all ECR repositories for the given profile/region.
@@ -65,7 +68,37 @@ func ListECRImages(ctx context.Context, profile, region, repoName string) ([]ECR
 	if err != nil {
 		return nil, err
 	}
+	return describeECRImages(ctx, client, repoName)
+}
+
+// ListAllECRImages は profile/region の全リポジトリのイメージを返す。リポジトリごとに DescribeImages を呼ぶため、
+// リポジトリ数に比例して時間がかかる (無駄の検出など、全イメージを横断する用途向け)。
+func ListAllECRImages(ctx context.Context, profile, region string) ([]ECRImageResource, error) {
+	client, err := newECRClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ECRImageResource
+	paginator := ecr.NewDescribeRepositoriesPaginator(client, &ecr.DescribeRepositoriesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe ecr repositories: %w", err)
+		}
+		for _, repo := range page.Repositories {
+			repoImages, err := describeECRImages(ctx, client, ptrStr(repo.RepositoryName))
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, repoImages...)
+		}
+	}
+	return images, nil
+}
 
+// describeECRImages は repoName の全イメージを DescribeImages のページをたどって取得する。
+func describeECRImages(ctx context.Context, client *ecr.Client, repoName string) ([]ECRImageResource, error) {
 	var images []ECRImageResource
 	paginator := ecr.NewDescribeImagesPaginator(client, &ecr.DescribeImagesInput{
 		RepositoryName: aws.String(repoName),
@@ -133,9 +166,9 @@ const (
 
 // ECRRepoInfo はレガシー CLI 互換の ECR リポジトリ表示用フィールドを保持する。
 type ECRRepoInfo struct {
-	RepositoryName string
-	RepositoryUri  string
-	CreatedAt      string
+	RepositoryName string `json:"repository_name"`
+	RepositoryUri  string `json:"repository_uri"`
+	CreatedAt      string `json:"created_at"`
 }
 
 // ToRow converts ECRRepoInfo to a string slice suitable for table formatting.
@@ -145,12 +178,12 @@ func (r ECRRepoInfo) ToRow() []string {
 
 // ECRImageInfo はレガシー CLI 互換の ECR イメージ表示用フィールドを保持する。
 type ECRImageInfo struct {
-	RepositoryName string
-	ImageTag       string
-	ImageDigest    string
-	PushedAt       string
-	LastPulledAt   string
-	ImageSizeBytes string
+	RepositoryName string `json:"repository_name"`
+	ImageTag       string `json:"image_tag"`
+	ImageDigest    string `json:"image_digest"`
+	PushedAt       string `json:"pushed_at"`
+	LastPulledAt   string `json:"last_pulled_at"`
+	ImageSizeBytes string `json:"image_size_bytes"`
 }
 
 // ToRow converts ECRImageInfo to a string slice suitable for table formatting.
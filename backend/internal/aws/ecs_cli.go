@@ -21,12 +21,13 @@ const (
 
 // ECSClusterInfo はレガシー CLI 互換の ECS クラスタ表示用フィールドを保持する。
 type ECSClusterInfo struct {
-	Name                         string
-	Status                       string
-	ActiveServicesCount          int32
-	RunningTasksCount            int32
-	PendingTasksCount            int32
-	RegisteredContainerInstances int32
+	Name                         string            `json:"name"`
+	Status                       string            `json:"status"`
+	ActiveServicesCount          int32             `json:"active_services_count"`
+	RunningTasksCount            int32             `json:"running_tasks_count"`
+	PendingTasksCount            int32             `json:"pending_tasks_count"`
+	RegisteredContainerInstances int32             `json:"registered_container_instances"`
+	Tags                         map[string]string `json:"tags"`
 }
 
 // ToRow converts ECSClusterInfo to a string slice suitable for table formatting.
@@ -41,15 +42,18 @@ func (c ECSClusterInfo) ToRow() []string {
 	}
 }
 
+// ResourceTags returns the cluster tags.
+func (c ECSClusterInfo) ResourceTags() map[string]string { return c.Tags }
+
 // ECSServiceInfo はレガシー CLI 互換の ECS サービス表示用フィールドを保持する。
 type ECSServiceInfo struct {
-	ClusterName    string
-	ServiceName    string
-	TaskDefinition string
-	Status         string
-	DesiredCount   int32
-	RunningCount   int32
-	PendingCount   int32
+	ClusterName    string `json:"cluster_name"`
+	ServiceName    string `json:"service_name"`
+	TaskDefinition string `json:"task_definition"`
+	Status         string `json:"status"`
+	DesiredCount   int32  `json:"desired_count"`
+	RunningCount   int32  `json:"running_count"`
+	PendingCount   int32  `json:"pending_count"`
 }
 
 // ToRow converts ECSServiceInfo to a string slice suitable for table formatting.
@@ -67,16 +71,16 @@ func (s ECSServiceInfo) ToRow() []string {
 
 // ECSTaskInfo はレガシー CLI 互換の ECS タスク表示用フィールド (コンテナ単位) を保持する。
 type ECSTaskInfo struct {
-	TaskDefinition  string
-	TaskID          string
-	ContainerName   string
-	LastStatus      string
-	DesiredStatus   string
-	HealthStatus    string
-	LaunchType      string
-	PlatformFamily  string
-	PlatformVersion string
-	StartedAt       string
+	TaskDefinition  string `json:"task_definition"`
+	TaskID          string `json:"task_id"`
+	ContainerName   string `json:"container_name"`
+	LastStatus      string `json:"last_status"`
+	DesiredStatus   string `json:"desired_status"`
+	HealthStatus    string `json:"health_status"`
+	LaunchType      string `json:"launch_type"`
+	PlatformFamily  string `json:"platform_family"`
+	PlatformVersion string `json:"platform_version"`
+	StartedAt       string `json:"started_at"`
 }
 
 // ToRow converts ECSTaskInfo to a string slice suitable for table formatting.
@@ -133,6 +137,7 @@ func GetECSClusterInfos(ctx context.Context, profile, region string, arns []stri
 		end := min(i+ecsDescribeClustersBatchSize, len(arns))
 		out, err := client.DescribeClusters(ctx, &ecs.DescribeClustersInput{
 			Clusters: arns[i:end],
+			Include:  []ecstypes.ClusterField{ecstypes.ClusterFieldTags},
 		})
 		if err != nil {
 			return nil, fmt.Errorf("describe ecs clusters: %w", err)
@@ -145,6 +150,7 @@ func GetECSClusterInfos(ctx context.Context, profile, region string, arns []stri
 				RunningTasksCount:            c.RunningTasksCount,
 				PendingTasksCount:            c.PendingTasksCount,
 				RegisteredContainerInstances: c.RegisteredContainerInstancesCount,
+				Tags:                         ecsTagsToMap(c.Tags),
 			})
 		}
 	}
@@ -274,7 +280,7 @@ func ecsTaskInfosFromSDK(t ecstypes.Task) []ECSTaskInfo {
 }
 
 // ECSExecSession は ECS Exec で開始した SSM セッション情報と、
-// session-manager-plugin のターゲット文字列生成に必要な ARN 群を保持する。
+// SSM のターゲット文字列生成に必要な ARN 群を保持する。
 type ECSExecSession struct {
 	SessionID    string
 	StreamURL    string
@@ -284,7 +290,7 @@ type ECSExecSession struct {
 	ContainerArn string
 }
 
-// Target は session-manager-plugin に渡す ECS ターゲット文字列
+// Target は SSM セッションの ECS ターゲット文字列
 // (ecs:<cluster>_<task-id>_<container-runtime-id>) を返す。
 func (s ECSExecSession) Target() string {
 	return fmt.Sprintf("ecs:%s_%s_%s",
@@ -295,7 +301,7 @@ func (s ECSExecSession) Target() string {
 }
 
 // ExecuteECSCommandSession は ECS Exec を開始し、セッション情報と関連 ARN を返す。
-// WebSocket ブリッジ用の ExecuteECSCommand と異なり、CLI (session-manager-plugin) 向け。
+// WebSocket ブリッジ用の ExecuteECSCommand と異なり、CLI 向けに関連 ARN も返す。
 func ExecuteECSCommandSession(ctx context.Context, profile, region, cluster, task, container, command string) (*ECSExecSession, error) {
 	client, err := newECSClient(ctx, profile, region)
 	if err != nil {
@@ -258,5 +258,10 @@ func ExecuteECSCommand(ctx context.Context, profile, region, cluster, task, cont
 		SessionID:  ptrStr(out.Session.SessionId),
 		StreamURL:  ptrStr(out.Session.StreamUrl),
 		TokenValue: ptrStr(out.Session.TokenValue),
+		Target: ECSExecSession{
+			ClusterArn:   ptrStr(out.ClusterArn),
+			TaskArn:      ptrStr(out.TaskArn),
+			ContainerArn: ptrStr(out.ContainerArn),
+		}.Target(),
 	}, nil
 }
@@ -11,18 +11,19 @@ import (
 
 // ElastiCacheResource represents a single ElastiCache cluster.
 type ElastiCacheResource struct {
-	ID                 string  `json:"id"`
-	Name               string  `json:"name"`
-	State              string  `json:"state"`
-	Engine             string  `json:"engine"`
-	EngineVersion      string  `json:"engine_version"`
-	NodeType           string  `json:"node_type"`
-	NumNodes           int32   `json:"num_nodes"`
-	Endpoint           string  `json:"endpoint"`
-	Port               int32   `json:"port"`
-	ParameterGroup     string  `json:"parameter_group"`
-	ReplicationGroupID string  `json:"replication_group_id"`
-	CostMonthly        float64 `json:"cost_monthly"`
+	ID                 string            `json:"id"`
+	Name               string            `json:"name"`
+	State              string            `json:"state"`
+	Engine             string            `json:"engine"`
+	EngineVersion      string            `json:"engine_version"`
+	NodeType           string            `json:"node_type"`
+	NumNodes           int32             `json:"num_nodes"`
+	Endpoint           string            `json:"endpoint"`
+	Port               int32             `json:"port"`
+	ParameterGroup     string            `json:"parameter_group"`
+	ReplicationGroupID string            `json:"replication_group_id"`
+	Tags               map[string]string `json:"tags"`
+	CostMonthly        float64           `json:"cost_monthly"`
 }
 
 // ElastiCacheParameter represents a single parameter in a cache parameter group.
@@ -58,7 +59,15 @@ func ListElastiCacheResources(ctx context.Context, profile, region string) ([]El
 			return nil, fmt.Errorf("describe elasticache clusters: %w", err)
 		}
 		for _, c := range page.CacheClusters {
-			resources = append(resources, elastiCacheFromCluster(c))
+			r := elastiCacheFromCluster(c)
+			// タグ取得は失敗してもクラスタ情報は返す
+			if c.ARN != nil {
+				tagsOut, tagErr := client.ListTagsForResource(ctx, &elasticache.ListTagsForResourceInput{ResourceName: c.ARN})
+				if tagErr == nil {
+					r.Tags = tagsToMapFunc(tagsOut.TagList, func(t ectypes.Tag) (*string, *string) { return t.Key, t.Value })
+				}
+			}
+			resources = append(resources, r)
 		}
 	}
 	return resources, nil
@@ -130,12 +139,12 @@ func ListElastiCacheParameters(ctx context.Context, profile, region, group strin
 
 // ElastiCacheClusterInfo はレガシー CLI 互換の ElastiCache 表示用フィールドを保持する。
 type ElastiCacheClusterInfo struct {
-	ReplicationGroupID string
-	CacheClusterID     string
-	CacheNodeType      string
-	Engine             string
-	EngineVersion      string
-	Status             string
+	ReplicationGroupID string `json:"replication_group_id"`
+	CacheClusterID     string `json:"cache_cluster_id"`
+	CacheNodeType      string `json:"cache_node_type"`
+	Engine             string `json:"engine"`
+	EngineVersion      string `json:"engine_version"`
+	Status             string `json:"status"`
 }
 
 // ToRow converts ElastiCacheClusterInfo to a string slice suitable for table formatting.
@@ -176,12 +185,12 @@ func ListElastiCacheClusterInfos(ctx context.Context, profile, region string) ([
 
 // ElastiCacheParameterInfo はレガシー CLI 互換の ElastiCache パラメータ表示用フィールドを保持する。
 type ElastiCacheParameterInfo struct {
-	Name         string
-	Value        string
-	ChangeType   string
-	DataType     string
-	IsModifiable string
-	Source       string
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	ChangeType   string `json:"change_type"`
+	DataType     string `json:"data_type"`
+	IsModifiable string `json:"is_modifiable"`
+	Source       string `json:"source"`
 }
 
 // ToRow converts ElastiCacheParameterInfo to a string slice suitable for table formatting.
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/wafv2"
 )
 
 // ELBResource represents an Elastic Load Balancer (ALB, NLB, or CLB).
@@ -89,6 +90,11 @@ func ListELBListeners(ctx context.Context, profile, region, lbArn string) ([]ELB
 		return nil, err
 	}
 
+	return describeELBListeners(ctx, client, lbArn)
+}
+
+// describeELBListeners は lbArn の全リスナーを DescribeListeners のページをたどって取得する。
+func describeELBListeners(ctx context.Context, client *elbv2.Client, lbArn string) ([]ELBListenerResource, error) {
 	var resources []ELBListenerResource
 	paginator := elbv2.NewDescribeListenersPaginator(client, &elbv2.DescribeListenersInput{
 		LoadBalancerArn: aws.String(lbArn),
@@ -122,6 +128,51 @@ func elbListenerFromSDK(l elbv2types.Listener) ELBListenerResource {
 	}
 }
 
+// ELBSecurityResource はセキュリティチェック用に ELBResource へリスナーと関連付けられた WAF Web ACL を加えたもの。
+// WebACLArn は ALB (application) でのみ解決し、関連付けがなければ空。
+type ELBSecurityResource struct {
+	ELBResource
+	Listeners []ELBListenerResource `json:"listeners"`
+	WebACLArn string                `json:"web_acl_arn"`
+}
+
+// ListELBSecurityResources は profile/region の全ロードバランサーをリスナーと WAF の関連付けとともに返す。
+// ロードバランサーごとに DescribeListeners (ALB は GetWebACLForResource も) を呼ぶため、ListELBResources より遅い。
+func ListELBSecurityResources(ctx context.Context, profile, region string) ([]ELBSecurityResource, error) {
+	lbs, err := ListELBResources(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newELBClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	wafClient, err := newWAFClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]ELBSecurityResource, 0, len(lbs))
+	for _, lb := range lbs {
+		listeners, err := describeELBListeners(ctx, client, lb.ID)
+		if err != nil {
+			return nil, err
+		}
+		r := ELBSecurityResource{ELBResource: lb, Listeners: listeners}
+		if lb.Type == string(elbv2types.LoadBalancerTypeEnumApplication) {
+			out, err := wafClient.GetWebACLForResource(ctx, &wafv2.GetWebACLForResourceInput{ResourceArn: aws.String(lb.ID)})
+			if err != nil {
+				return nil, fmt.Errorf("get web acl for load balancer %s: %w", lb.Name, err)
+			}
+			if out.WebACL != nil {
+				r.WebACLArn = ptrStr(out.WebACL.ARN)
+			}
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
 // ELBRuleResource represents a single rule on a listener.
 type ELBRuleResource struct {
 	ARN string `json:"arn"`
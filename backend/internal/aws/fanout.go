@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// FanoutAll は profiles / regions に指定すると、設定済みの全プロファイル / 有効化済みの全リージョンに展開される値。
+const FanoutAll = "all"
+
+// DefaultFanoutConcurrency は Fanout で同時に問い合わせる profile/region の組の既定の上限。
+// 40 アカウント × 全リージョンのような指定でも API のレート制限やローカルの接続数を使い切らない程度に抑える。
+const DefaultFanoutConcurrency = 8
+
+// FanoutTarget は fan-out で問い合わせる profile と region の組。AccountID は解決できた場合のみ設定される。
+type FanoutTarget struct {
+	Profile   string `json:"profile"`
+	AccountID string `json:"account_id"`
+	Region    string `json:"region"`
+}
+
+// FanoutItem は fan-out の結果の 1 行。取得元の profile / アカウント / region を付けて Resource を包む。
+type FanoutItem[T any] struct {
+	FanoutTarget
+	Resource T `json:"resource"`
+}
+
+// FanoutError は取得に失敗した profile/region の組とその理由。リージョン一覧の取得に失敗した場合の Region は空。
+type FanoutError struct {
+	Profile string `json:"profile"`
+	Region  string `json:"region"`
+	Error   string `json:"error"`
+}
+
+// FanoutResolver は ResolveFanoutTargets がプロファイルごとに参照する情報の取得方法。
+type FanoutResolver struct {
+	// AccountID はプロファイルのアカウント ID を返す。解決できない場合は空文字列を返す。
+	AccountID func(ctx context.Context, profile string) string
+	// Regions はプロファイルで有効化済みのリージョンコードを返す。
+	Regions func(ctx context.Context, profile string) ([]string, error)
+}
+
+// DefaultFanoutResolver は ~/.aws/config の静的な情報 (SSO プロファイルの sso_account_id) でアカウント ID を解決し、
+// 書かれていないプロファイルは STS GetCallerIdentity で補う。リージョンは ListRegions で取得する。
+func DefaultFanoutResolver() FanoutResolver {
+	accounts := map[string]string{}
+	if profiles, err := ListProfiles(); err == nil {
+		for _, p := range profiles {
+			accounts[p.Name] = p.AccountID
+		}
+	}
+	return FanoutResolver{
+		AccountID: func(ctx context.Context, profile string) string {
+			if id := accounts[profile]; id != "" {
+				return id
+			}
+			identity, err := GetCallerIdentity(ctx, profile)
+			if err != nil {
+				return ""
+			}
+			return identity.AccountID
+		},
+		Regions: func(ctx context.Context, profile string) ([]string, error) {
+			regions, err := ListRegions(ctx, profile)
+			if err != nil {
+				return nil, err
+			}
+			codes := make([]string, len(regions))
+			for i, r := range regions {
+				codes[i] = r.Code
+			}
+			return codes, nil
+		},
+	}
+}
+
+// SplitFanoutList は "a,b" 形式の指定を分割する。前後の空白を除き、空の要素と重複は捨てる。
+func SplitFanoutList(v string) []string {
+	var items []string
+	seen := map[string]bool{}
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// ResolveFanoutProfiles はプロファイル名の指定を展開する。FanoutAll が含まれる場合は設定済みの全プロファイルを返し、
+// それ以外は names をそのまま返す。プロファイル名は AWS SDK の認証とキャッシュキーにのみ使うため、他のハンドラと同じく
+// ValidateProfileName は適用しない ("CT Audit" のようなスペースを含む実在のプロファイル名を拒否してしまうため)。
+func ResolveFanoutProfiles(names []string) ([]string, error) {
+	for _, name := range names {
+		if name != FanoutAll {
+			continue
+		}
+		profiles, err := ListProfiles()
+		if err != nil {
+			return nil, fmt.Errorf("list profiles: %w", err)
+		}
+		all := make([]string, len(profiles))
+		for i, p := range profiles {
+			all[i] = p.Name
+		}
+		return all, nil
+	}
+	return names, nil
+}
+
+// ResolveFanoutTargets は profiles × regions の組を返す。regions が FanoutAll を含む場合は、プロファイルごとに
+// 有効化済みの全リージョンへ展開する (オプトインリージョンはアカウントごとに異なるため)。リージョン一覧を取得できなかった
+// プロファイルは組を作らず、FanoutError として返す。
+func ResolveFanoutTargets(ctx context.Context, profiles, regions []string, resolver FanoutResolver) ([]FanoutTarget, []FanoutError) {
+	allRegions := false
+	for _, r := range regions {
+		if r == FanoutAll {
+			allRegions = true
+		}
+	}
+
+	// 各 goroutine は自分の index にのみ書き込むため、結果スライスへの書き込みはロック不要。
+	targets := make([][]FanoutTarget, len(profiles))
+	errs := make([]*FanoutError, len(profiles))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(DefaultFanoutConcurrency)
+	for i, profile := range profiles {
+		g.Go(func() error {
+			profileRegions := regions
+			if allRegions {
+				var err error
+				profileRegions, err = resolver.Regions(gctx, profile)
+				if err != nil {
+					errs[i] = &FanoutError{Profile: profile, Error: fmt.Sprintf("list regions: %v", err)}
+					return nil
+				}
+			}
+			accountID := resolver.AccountID(gctx, profile)
+			for _, region := range profileRegions {
+				targets[i] = append(targets[i], FanoutTarget{Profile: profile, AccountID: accountID, Region: region})
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var flat []FanoutTarget
+	var fanoutErrs []FanoutError
+	for i := range profiles {
+		flat = append(flat, targets[i]...)
+		if errs[i] != nil {
+			fanoutErrs = append(fanoutErrs, *errs[i])
+		}
+	}
+	return flat, fanoutErrs
+}
+
+// Fanout は targets ごとに list を最大 concurrency 並列で呼び出し、各行に取得元を付けて targets の順に連結して返す。
+// 1 つの組の失敗で他の組を中断せず、失敗した組は FanoutError として返す (部分的な結果を返せるようにするため)。
+func Fanout[T any](
+	ctx context.Context,
+	targets []FanoutTarget,
+	concurrency int,
+	list func(ctx context.Context, profile, region string) ([]T, error),
+) ([]FanoutItem[T], []FanoutError) {
+	if concurrency <= 0 {
+		concurrency = DefaultFanoutConcurrency
+	}
+	results := make([][]T, len(targets))
+	errs := make([]error, len(targets))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, t := range targets {
+		g.Go(func() error {
+			results[i], errs[i] = list(gctx, t.Profile, t.Region)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	items := []FanoutItem[T]{}
+	var fanoutErrs []FanoutError
+	for i, t := range targets {
+		if errs[i] != nil {
+			fanoutErrs = append(fanoutErrs, FanoutError{Profile: t.Profile, Region: t.Region, Error: errs[i].Error()})
+			continue
+		}
+		for _, r := range results[i] {
+			items = append(items, FanoutItem[T]{FanoutTarget: t, Resource: r})
+		}
+	}
+	return items, fanoutErrs
+}
@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitFanoutList(t *testing.T) {
+	got := SplitFanoutList(" prod, dev,,prod ,stg")
+	if want := []string{"prod", "dev", "stg"}; !slices.Equal(got, want) {
+		t.Errorf("SplitFanoutList = %q, want %q", got, want)
+	}
+	if got := SplitFanoutList(""); got != nil {
+		t.Errorf("SplitFanoutList(\"\") = %q, want nil", got)
+	}
+}
+
+func TestResolveFanoutProfiles(t *testing.T) {
+	got, err := ResolveFanoutProfiles([]string{"prod", "dev"})
+	if err != nil || !slices.Equal(got, []string{"prod", "dev"}) {
+		t.Errorf("ResolveFanoutProfiles = (%q, %v), want the given profiles", got, err)
+	}
+	// スペースを含むプロファイル名もそのまま受け付ける
+	got, err = ResolveFanoutProfiles([]string{"CT Audit"})
+	if err != nil || !slices.Equal(got, []string{"CT Audit"}) {
+		t.Errorf("ResolveFanoutProfiles = (%q, %v), want the profile with a space", got, err)
+	}
+}
+
+func TestResolveFanoutTargets(t *testing.T) {
+	resolver := FanoutResolver{
+		AccountID: func(_ context.Context, profile string) string {
+			if profile == "prod" {
+				return "111111111111"
+			}
+			return ""
+		},
+		Regions: func(_ context.Context, profile string) ([]string, error) {
+			if profile == "broken" {
+				return nil, errors.New("access denied")
+			}
+			return []string{"ap-northeast-1", "us-east-1"}, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		profiles []string
+		regions  []string
+		want     []FanoutTarget
+		wantErrs []FanoutError
+	}{
+		{
+			name:     "explicit regions",
+			profiles: []string{"prod", "dev"},
+			regions:  []string{"eu-west-1"},
+			want: []FanoutTarget{
+				{Profile: "prod", AccountID: "111111111111", Region: "eu-west-1"},
+				{Profile: "dev", Region: "eu-west-1"},
+			},
+		},
+		{
+			name:     "all regions per profile",
+			profiles: []string{"prod", "broken"},
+			regions:  []string{FanoutAll},
+			want: []FanoutTarget{
+				{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"},
+				{Profile: "prod", AccountID: "111111111111", Region: "us-east-1"},
+			},
+			wantErrs: []FanoutError{{Profile: "broken", Error: "list regions: access denied"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := ResolveFanoutTargets(context.Background(), tt.profiles, tt.regions, resolver)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("targets = %+v, want %+v", got, tt.want)
+			}
+			if !slices.Equal(errs, tt.wantErrs) {
+				t.Errorf("errors = %+v, want %+v", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestFanoutPartialResults(t *testing.T) {
+	targets := []FanoutTarget{
+		{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"},
+		{Profile: "prod", AccountID: "111111111111", Region: "us-east-1"},
+		{Profile: "dev", Region: "ap-northeast-1"},
+	}
+	var inFlight, maxInFlight atomic.Int32
+	items, errs := Fanout(context.Background(), targets, 2, func(_ context.Context, profile, region string) ([]string, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if profile == "dev" {
+			return nil, errors.New("sso token expired")
+		}
+		return []string{region + "-a", region + "-b"}, nil
+	})
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("max concurrent calls = %d, want <= 2", got)
+	}
+	want := []FanoutItem[string]{
+		{FanoutTarget: targets[0], Resource: "ap-northeast-1-a"},
+		{FanoutTarget: targets[0], Resource: "ap-northeast-1-b"},
+		{FanoutTarget: targets[1], Resource: "us-east-1-a"},
+		{FanoutTarget: targets[1], Resource: "us-east-1-b"},
+	}
+	if !slices.Equal(items, want) {
+		t.Errorf("items = %+v, want %+v", items, want)
+	}
+	wantErrs := []FanoutError{{Profile: "dev", Region: "ap-northeast-1", Error: "sso token expired"}}
+	if !slices.Equal(errs, wantErrs) {
+		t.Errorf("errors = %+v, want %+v", errs, wantErrs)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"golang.org/x/sync/errgroup"
 )
 
 // IAMResource represents an IAM user.
@@ -19,7 +20,8 @@ type IAMResource struct {
 	ARN          string   `json:"arn"`
 	Kind         string   `json:"kind"` // user
 	MFAEnabled   bool     `json:"mfa_enabled"`
-	LastActivity string   `json:"last_activity"`
+	LastActivity string   `json:"last_activity"` // ユーザーはパスワードとアクセスキーの利用のうち新しい方
+	CreateDate   string   `json:"create_date"`
 	Groups       []string `json:"groups"`
 	Policies     []string `json:"policies"`
 }
@@ -29,6 +31,10 @@ func (r IAMResource) ResourceName() string  { return r.Name }
 func (r IAMResource) ResourceState() string { return "active" }
 func (r IAMResource) ServiceName() string   { return "iam" }
 
+// iamUserConcurrency はユーザーごとの属性解決を同時実行する上限数。IAM の API はリクエストレートの
+// 上限が低いため、S3 のバケットごとの解決 (s3BucketConcurrency) より小さくする。
+const iamUserConcurrency = 8
+
 // ListIAMResources returns all IAM users and roles for the given profile.
 // IAM is a global service; region is ignored.
 func ListIAMResources(ctx context.Context, profile, _ string) ([]IAMResource, error) {
@@ -37,20 +43,34 @@ func ListIAMResources(ctx context.Context, profile, _ string) ([]IAMResource, er
 		return nil, err
 	}
 
-	var resources []IAMResource
+	var users []iamtypes.User
 	userPaginator := iam.NewListUsersPaginator(client, &iam.ListUsersInput{})
 	for userPaginator.HasMorePages() {
 		page, err := userPaginator.NextPage(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("list iam users: %w", err)
 		}
-		for _, u := range page.Users {
-			r, err := iamFromUser(ctx, client, u)
+		users = append(users, page.Users...)
+	}
+
+	// ユーザーごとの属性解決 (MFA / グループ / ポリシー / アクセスキーの最終利用) はユーザーあたり
+	// 4 本以上の直列 API 呼び出しを伴うため、ユーザー間で並列実行する。各 goroutine は自分の
+	// index にのみ書き込む (ListS3Resources と同じ)。
+	resources := make([]IAMResource, len(users))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(iamUserConcurrency)
+	for i, u := range users {
+		g.Go(func() error {
+			r, err := iamFromUser(gctx, client, u)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			resources = append(resources, r)
-		}
+			resources[i] = r
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	rolePaginator := iam.NewListRolesPaginator(client, &iam.ListRolesInput{})
@@ -98,18 +118,49 @@ func iamFromUser(ctx context.Context, client *iam.Client, u iamtypes.User) (IAMR
 		}
 	}
 
-	var passwordLastUsed *time.Time
-	if u.PasswordLastUsed != nil {
-		passwordLastUsed = u.PasswordLastUsed
+	// 最終利用はパスワードとアクセスキーの利用のうち新しい方 (アクセスキーだけを使うユーザーもいるため)
+	lastUsed := latestTime(u.PasswordLastUsed, latestIAMAccessKeyUse(ctx, client, name))
+
+	return newIAMUserResource(ptrStr(u.UserId), name, ptrStr(u.Arn), mfaEnabled, lastUsed, u.CreateDate, groups, policies), nil
+}
+
+// latestIAMAccessKeyUse はユーザーのアクセスキーの最終利用日時のうち最も新しいものを返す。
+// 利用履歴がない、または取得に失敗した場合は nil。
+func latestIAMAccessKeyUse(ctx context.Context, client *iam.Client, userName string) *time.Time {
+	keysOut, err := client.ListAccessKeys(ctx, &iam.ListAccessKeysInput{UserName: aws.String(userName)})
+	if err != nil {
+		return nil
 	}
+	var latest *time.Time
+	for _, k := range keysOut.AccessKeyMetadata {
+		out, err := client.GetAccessKeyLastUsed(ctx, &iam.GetAccessKeyLastUsedInput{AccessKeyId: k.AccessKeyId})
+		if err != nil || out.AccessKeyLastUsed == nil {
+			continue
+		}
+		latest = latestTime(latest, out.AccessKeyLastUsed.LastUsedDate)
+	}
+	return latest
+}
 
-	return newIAMUserResource(ptrStr(u.UserId), name, ptrStr(u.Arn), mfaEnabled, passwordLastUsed, groups, policies), nil
+// latestTime は nil でない日時のうち最も新しいものを返す。すべて nil なら nil。
+func latestTime(times ...*time.Time) *time.Time {
+	var latest *time.Time
+	for _, t := range times {
+		if t != nil && (latest == nil || t.After(*latest)) {
+			latest = t
+		}
+	}
+	return latest
 }
 
-func newIAMUserResource(id, name, arn string, mfaEnabled bool, passwordLastUsed *time.Time, groups, policies []string) IAMResource {
+func newIAMUserResource(id, name, arn string, mfaEnabled bool, lastUsed, createDate *time.Time, groups, policies []string) IAMResource {
 	lastActivity := ""
-	if passwordLastUsed != nil {
-		lastActivity = passwordLastUsed.Format(time.RFC3339)
+	if lastUsed != nil {
+		lastActivity = lastUsed.Format(time.RFC3339)
+	}
+	created := ""
+	if createDate != nil {
+		created = createDate.Format(time.RFC3339)
 	}
 	return IAMResource{
 		ID:           id,
@@ -118,6 +169,7 @@ func newIAMUserResource(id, name, arn string, mfaEnabled bool, passwordLastUsed
 		Kind:         "user",
 		MFAEnabled:   mfaEnabled,
 		LastActivity: lastActivity,
+		CreateDate:   created,
 		Groups:       groups,
 		Policies:     policies,
 	}
@@ -159,11 +211,11 @@ func newIAMRoleResource(id, name, arn string, lastUsed *time.Time, policies []st
 
 // IAMUserInfo はレガシー CLI 互換の IAM ユーザー表示用フィールドを保持する。
 type IAMUserInfo struct {
-	UserName   string
-	UserID     string
-	Groups     string // カンマ区切りのグループ名
-	Policies   string // カンマ区切りのアタッチ済みマネージドポリシー名
-	CreateDate string
+	UserName   string `json:"user_name"`
+	UserID     string `json:"user_id"`
+	Groups     string `json:"groups"`   // カンマ区切りのグループ名
+	Policies   string `json:"policies"` // カンマ区切りのアタッチ済みマネージドポリシー名
+	CreateDate string `json:"create_date"`
 }
 
 // ToRow converts IAMUserInfo to a string slice suitable for table formatting.
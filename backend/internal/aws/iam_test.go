@@ -8,6 +8,7 @@ import (
 
 func TestNewIAMUserResource(t *testing.T) {
 	lastUsed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
 
 	tests := []struct {
 		name             string
@@ -16,6 +17,7 @@ func TestNewIAMUserResource(t *testing.T) {
 		arn              string
 		mfaEnabled       bool
 		passwordLastUsed *time.Time
+		createDate       *time.Time
 		groups           []string
 		policies         []string
 		want             IAMResource
@@ -27,6 +29,7 @@ func TestNewIAMUserResource(t *testing.T) {
 			arn:              "arn:aws:iam::123456789012:user/alice",
 			mfaEnabled:       true,
 			passwordLastUsed: &lastUsed,
+			createDate:       &created,
 			groups:           []string{"admins"},
 			policies:         []string{"AdministratorAccess"},
 			want: IAMResource{
@@ -36,6 +39,7 @@ func TestNewIAMUserResource(t *testing.T) {
 				Kind:         "user",
 				MFAEnabled:   true,
 				LastActivity: lastUsed.Format(time.RFC3339),
+				CreateDate:   created.Format(time.RFC3339),
 				Groups:       []string{"admins"},
 				Policies:     []string{"AdministratorAccess"},
 			},
@@ -63,7 +67,7 @@ func TestNewIAMUserResource(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := newIAMUserResource(tt.id, tt.userName, tt.arn, tt.mfaEnabled, tt.passwordLastUsed, tt.groups, tt.policies)
+			got := newIAMUserResource(tt.id, tt.userName, tt.arn, tt.mfaEnabled, tt.passwordLastUsed, tt.createDate, tt.groups, tt.policies)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("got %#v want %#v", got, tt.want)
 			}
@@ -125,3 +129,14 @@ func TestNewIAMRoleResource(t *testing.T) {
 		})
 	}
 }
+
+func TestLatestTime(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if got := latestTime(nil, &older, &newer, nil); got == nil || !got.Equal(newer) {
+		t.Errorf("latestTime = %v, want %v", got, newer)
+	}
+	if got := latestTime(nil, nil); got != nil {
+		t.Errorf("latestTime(nil, nil) = %v, want nil", got)
+	}
+}
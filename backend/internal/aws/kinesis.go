@@ -15,6 +15,7 @@ type KinesisResource struct {
 	Name           string            `json:"name"`
 	State          string            `json:"state"`
 	ShardCount     int32             `json:"shard_count"`
+	StreamMode     string            `json:"stream_mode"`
 	RetentionHours int32             `json:"retention_hours"`
 	EncryptionType string            `json:"encryption_type"`
 	Tags           map[string]string `json:"tags"`
@@ -51,7 +52,16 @@ func ListKinesisResources(ctx context.Context, profile, region string) ([]Kinesi
 		if err != nil {
 			return nil, fmt.Errorf("describe kinesis stream %s: %w", name, err)
 		}
-		resources = append(resources, kinesisFromSummary(out.StreamDescriptionSummary))
+		r := kinesisFromSummary(out.StreamDescriptionSummary)
+		// タグ取得は失敗してもストリーム情報は返す (1 ストリームのタグは最大 50 個のため 1 回で取得できる)
+		tagsOut, tagErr := client.ListTagsForStream(ctx, &kinesis.ListTagsForStreamInput{
+			StreamName: aws.String(name),
+			Limit:      aws.Int32(50),
+		})
+		if tagErr == nil {
+			r.Tags = tagsToMapFunc(tagsOut.Tags, func(t kinesistypes.Tag) (*string, *string) { return t.Key, t.Value })
+		}
+		resources = append(resources, r)
 	}
 	return resources, nil
 }
@@ -66,10 +76,19 @@ func kinesisFromSummary(s *kinesistypes.StreamDescriptionSummary) KinesisResourc
 		State:          DisplayState(string(s.StreamStatus)),
 		ShardCount:     ptrInt32(s.OpenShardCount),
 		RetentionHours: ptrInt32(s.RetentionPeriodHours),
+		StreamMode:     streamMode(s.StreamModeDetails),
 		EncryptionType: string(s.EncryptionType),
 	}
 }
 
+// streamMode はストリームの容量モード (PROVISIONED / ON_DEMAND) を返す。
+func streamMode(d *kinesistypes.StreamModeDetails) string {
+	if d == nil {
+		return ""
+	}
+	return string(d.StreamMode)
+}
+
 // newKinesisClient は Kinesis API クライアントを生成する。
 func newKinesisClient(ctx context.Context, profile, region string) (*kinesis.Client, error) {
 	return NewClient(ctx, profile, region, func(cfg aws.Config) *kinesis.Client {
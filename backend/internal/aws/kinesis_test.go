@@ -28,6 +28,7 @@ func TestKinesisFromSummary(t *testing.T) {
 				OpenShardCount:       aws.Int32(4),
 				RetentionPeriodHours: aws.Int32(24),
 				EncryptionType:       kinesistypes.EncryptionTypeKms,
+				StreamModeDetails:    &kinesistypes.StreamModeDetails{StreamMode: kinesistypes.StreamModeProvisioned},
 			},
 			want: KinesisResource{
 				ID:             "arn:aws:kinesis:ap-northeast-1:123:stream/foo",
@@ -35,6 +36,7 @@ func TestKinesisFromSummary(t *testing.T) {
 				State:          "active",
 				ShardCount:     4,
 				RetentionHours: 24,
+				StreamMode:     "PROVISIONED",
 				EncryptionType: "KMS",
 			},
 		},
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// kmsDataKeyAPI は KMS SDK クライアントのうちセッションの暗号化が利用する操作。
+// テストでは手書きフェイクを差し込む。
+type kmsDataKeyAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+}
+
+// GenerateSessionDataKey は Session Manager の KMS 暗号化 (KMSEncryption ハンドシェイク) 用に、
+// keyID の KMS キーで numberOfBytes バイトのデータキーを生成し、暗号化済みデータキーと平文のデータキーを返す。
+func GenerateSessionDataKey(ctx context.Context, profile, region, keyID string, encryptionContext map[string]string, numberOfBytes int32) (ciphertext, plaintext []byte, err error) {
+	client, err := newKMSClient(ctx, profile, region)
+	if err != nil {
+		return nil, nil, err
+	}
+	return generateDataKey(ctx, client, keyID, encryptionContext, numberOfBytes)
+}
+
+func generateDataKey(ctx context.Context, client kmsDataKeyAPI, keyID string, encryptionContext map[string]string, numberOfBytes int32) ([]byte, []byte, error) {
+	out, err := client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(keyID),
+		NumberOfBytes:     aws.Int32(numberOfBytes),
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate data key with kms key %s: %w", keyID, err)
+	}
+	return out.CiphertextBlob, out.Plaintext, nil
+}
+
+// SessionDataKeyGenerator は profile / region を束縛した GenerateSessionDataKey を返す。
+// session.DataKeyGenerator として DataChannel.EnableKMSEncryption に渡す。
+func SessionDataKeyGenerator(profile, region string) func(ctx context.Context, keyID string, encryptionContext map[string]string, numberOfBytes int32) ([]byte, []byte, error) {
+	return func(ctx context.Context, keyID string, encryptionContext map[string]string, numberOfBytes int32) ([]byte, []byte, error) {
+		return GenerateSessionDataKey(ctx, profile, region, keyID, encryptionContext, numberOfBytes)
+	}
+}
+
+// newKMSClient は KMS API クライアントを生成する。
+func newKMSClient(ctx context.Context, profile, region string) (*kms.Client, error) {
+	return NewClient(ctx, profile, region, func(cfg aws.Config) *kms.Client {
+		return kms.NewFromConfig(cfg)
+	})
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	smithy "github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeKMS は kmsDataKeyAPI の手書きフェイク。受け取った入力を保持する。
+type fakeKMS struct {
+	input *kms.GenerateDataKeyInput
+	err   error
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, p *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	f.input = p
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &kms.GenerateDataKeyOutput{CiphertextBlob: []byte("blob"), Plaintext: []byte("key")}, nil
+}
+
+func TestGenerateDataKey(t *testing.T) {
+	fake := &fakeKMS{}
+	ec := map[string]string{"aws:ssm:SessionId": "s-1"}
+	ciphertext, plaintext, err := generateDataKey(context.Background(), fake, "alias/session", ec, 64)
+	if err != nil {
+		t.Fatalf("generateDataKey() error = %v", err)
+	}
+	if string(ciphertext) != "blob" || string(plaintext) != "key" {
+		t.Errorf("output = %q / %q, want blob / key", ciphertext, plaintext)
+	}
+	if aws.ToString(fake.input.KeyId) != "alias/session" || aws.ToInt32(fake.input.NumberOfBytes) != 64 {
+		t.Errorf("input = %s / %d, want alias/session / 64", aws.ToString(fake.input.KeyId), aws.ToInt32(fake.input.NumberOfBytes))
+	}
+	if diff := cmp.Diff(ec, fake.input.EncryptionContext); diff != "" {
+		t.Errorf("encryption context mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateDataKeyKeepsAPIError(t *testing.T) {
+	fake := &fakeKMS{err: &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized"}}
+	_, _, err := generateDataKey(context.Background(), fake, "alias/session", nil, 64)
+	if err == nil {
+		t.Fatal("generateDataKey() error = nil, want access denied")
+	}
+	if !IsAccessDenied(err) {
+		t.Errorf("IsAccessDenied(%v) = false, want true", err)
+	}
+}
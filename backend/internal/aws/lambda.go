@@ -11,16 +11,17 @@ import (
 
 // LambdaResource represents a single Lambda function.
 type LambdaResource struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	State       string            `json:"state"`
-	Runtime     string            `json:"runtime"`
-	MemoryMB    int32             `json:"memory_mb"`
-	TimeoutSec  int32             `json:"timeout_sec"`
-	Handler     string            `json:"handler"`
-	Role        string            `json:"role"`
-	Tags        map[string]string `json:"tags"`
-	CostMonthly float64           `json:"cost_monthly"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	State      string            `json:"state"`
+	Runtime    string            `json:"runtime"`
+	MemoryMB   int32             `json:"memory_mb"`
+	TimeoutSec int32             `json:"timeout_sec"`
+	Handler    string            `json:"handler"`
+	Role       string            `json:"role"`
+	Tags       map[string]string `json:"tags"`
+	// CostMonthly は常に nil (JSON では null)。Lambda は呼び出し回数と実行時間で課金され、一覧からは見積もれない。
+	CostMonthly *float64 `json:"cost_monthly"`
 }
 
 func (r LambdaResource) ResourceID() string    { return r.ID }
@@ -43,7 +44,12 @@ func ListLambdaResources(ctx context.Context, profile, region string) ([]LambdaR
 			return nil, fmt.Errorf("list lambda functions: %w", err)
 		}
 		for _, fn := range page.Functions {
-			resources = append(resources, lambdaFromFunction(fn))
+			r := lambdaFromFunction(fn)
+			// タグ取得は失敗しても関数情報は返す
+			if tagsOut, tagErr := client.ListTags(ctx, &lambda.ListTagsInput{Resource: fn.FunctionArn}); tagErr == nil {
+				r.Tags = tagsOut.Tags
+			}
+			resources = append(resources, r)
 		}
 	}
 	return resources, nil
@@ -140,6 +140,35 @@ var resourceServiceSpecs = map[string]resourceServiceSpec{
 		productFamily:  "Compute",
 		riSupported:    false,
 	},
+	// kinesis/waf/natgw/ecr/cwlogs are usage-billed services with no
+	// instance types; their rates are normalized by
+	// usageOnDemandRatesFromDocument and exist to feed the list handlers'
+	// CostMonthly estimates (pricing_estimate.go) and the waste report.
+	"kinesis": {
+		awsServiceCode: "AmazonKinesis",
+		productFamily:  "Kinesis Streams",
+		riSupported:    false,
+	},
+	"waf": {
+		awsServiceCode: "awswaf",
+		productFamily:  "Web Application Firewall",
+		riSupported:    false,
+	},
+	"natgw": {
+		awsServiceCode: "AmazonEC2",
+		productFamily:  "NAT Gateway",
+		riSupported:    false,
+	},
+	"ecr": {
+		awsServiceCode: "AmazonECR",
+		productFamily:  "EC2 Container Registry",
+		riSupported:    false,
+	},
+	"cwlogs": {
+		awsServiceCode: "AmazonCloudWatch",
+		productFamily:  "Storage Snapshot",
+		riSupported:    false,
+	},
 }
 
 // savingsPlanServiceSpec maps a thief Savings Plans service slug
@@ -456,8 +485,11 @@ func priceRatesFromDocument(service string, spec resourceServiceSpec, doc priceL
 	if doc.Product.ProductFamily != spec.productFamily {
 		return nil
 	}
-	if service == "ecs" {
+	switch service {
+	case "ecs":
 		return ecsOnDemandRatesFromDocument(doc)
+	case "kinesis", "waf", "natgw", "ecr", "cwlogs":
+		return usageOnDemandRatesFromDocument(service, doc)
 	}
 	return instanceOnDemandRatesFromDocument(service, spec, doc, opLicense)
 }
@@ -737,6 +769,74 @@ func ecsOnDemandRatesFromDocument(doc priceListDocument) []PriceRate {
 	return rates
 }
 
+// usageKind classifies the usagetype of a usage-billed service's Price List
+// row into the normalized "usage" attribute the cost estimates join on.
+// usagetype carries a region prefix ("APN1-Storage-ShardHour"; none in
+// us-east-1), so only the suffix is matched. Request, payload, data
+// processing (NAT Gateway/CloudWatch Logs ingestion) and extended retention
+// meters depend on traffic the resource lists don't carry and are excluded
+// via ok=false, as are Kinesis on-demand stream-hours (on-demand streams are
+// billed by throughput, not by shard).
+func usageKind(service, usageType string) (usage, label string, ok bool) {
+	switch service {
+	case "kinesis":
+		if hasUsageSuffix(usageType, "Storage-ShardHour") {
+			return "shard_hour", "Shard Hour", true
+		}
+	case "waf":
+		switch {
+		case hasUsageSuffix(usageType, "WebACL"):
+			return "web_acl", "Web ACL", true
+		case hasUsageSuffix(usageType, "Rule"):
+			return "rule", "Rule", true
+		}
+	case "natgw":
+		if hasUsageSuffix(usageType, "NatGateway-Hours") {
+			return "hour", "NAT Gateway Hour", true
+		}
+	case "ecr", "cwlogs":
+		if hasUsageSuffix(usageType, "TimedStorage-ByteHrs") {
+			return "storage_gb_month", "Storage (GB-Mo)", true
+		}
+	}
+	return "", "", false
+}
+
+// hasUsageSuffix reports whether usageType is name, optionally preceded by a
+// "<region prefix>-".
+func hasUsageSuffix(usageType, name string) bool {
+	return usageType == name || strings.HasSuffix(usageType, "-"+name)
+}
+
+func usageOnDemandRatesFromDocument(service string, doc priceListDocument) []PriceRate {
+	usage, label, ok := usageKind(service, doc.Product.Attributes["usagetype"])
+	if !ok {
+		return nil
+	}
+	var rates []PriceRate
+	for _, term := range doc.Terms.OnDemand {
+		for _, dim := range term.PriceDimensions {
+			price, ok := parseUSD(dim.PricePerUnit.USD)
+			if !ok {
+				continue
+			}
+			rates = append(rates, PriceRate{
+				RateID:     dim.RateCode,
+				Model:      "on_demand",
+				Group:      "On-Demand",
+				Label:      label,
+				Attributes: map[string]string{"usage": usage},
+				Term:       PriceTerm{},
+				Unit:       dim.Unit,
+				PriceUSD:   price,
+				UpfrontUSD: 0,
+				Currency:   "USD",
+			})
+		}
+	}
+	return rates
+}
+
 // ---- Savings Plans (savingsplans:DescribeSavingsPlansOfferingRates) ----
 
 // resourceKindFromServiceCode maps a Savings Plans offering rate's
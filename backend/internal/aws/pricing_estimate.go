@@ -0,0 +1,299 @@
+package aws
+
+import "strings"
+
+// hoursPerMonth は時間単価を月額へ換算する係数。AWS の料金計算ツールと同じ 730 時間
+// (365 日 × 24 時間 ÷ 12 か月) を使う。
+const hoursPerMonth = 730
+
+// bytesPerGB は GB-Mo 単価のストレージ料金の見積もりに使う 1 GB のバイト数。
+const bytesPerGB = 1e9
+
+// onDemandIndex は PriceTable の on_demand 行を、突合に使う属性の組から時間単価を
+// 引けるようにした索引。リソース数 × レート数の総当たりを避けるため、テーブルごとに
+// 1 回だけ構築する。
+type onDemandIndex struct {
+	keys  []string
+	price map[string]float64
+}
+
+// newOnDemandIndex は table の on_demand 行 (単位 Hrs) を keys の属性値で索引化する。
+// 同じ属性の組に複数行が該当する場合 (索引キーに含めない属性だけが異なる行) は最安値を
+// 採用する。table が nil のときは空の索引を返し、呼び出し側は一律「単価なし」として扱える。
+func newOnDemandIndex(table *PriceTable, keys ...string) onDemandIndex {
+	idx := onDemandIndex{keys: keys, price: map[string]float64{}}
+	if table == nil {
+		return idx
+	}
+	for _, r := range table.Rates {
+		if r.Model != "on_demand" || r.Unit != "Hrs" {
+			continue
+		}
+		k := idx.key(r.Attributes)
+		if cur, ok := idx.price[k]; !ok || r.PriceUSD < cur {
+			idx.price[k] = r.PriceUSD
+		}
+	}
+	return idx
+}
+
+func (idx onDemandIndex) key(attrs map[string]string) string {
+	parts := make([]string, len(idx.keys))
+	for i, k := range idx.keys {
+		parts[i] = attrs[k]
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// hourly は attrs に一致する on_demand 行の時間単価を返す。
+func (idx onDemandIndex) hourly(attrs map[string]string) (float64, bool) {
+	p, ok := idx.price[idx.key(attrs)]
+	return p, ok
+}
+
+// monthlyCost は時間単価 × 台数を月額へ換算し、セント単位に丸める。
+func monthlyCost(hourly float64, count int32) float64 {
+	return roundCents(hourly * float64(count) * hoursPerMonth)
+}
+
+// roundCents は金額をセント単位に丸める。
+func roundCents(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
+
+// usagePrice は table の on_demand 行のうち usage 属性 (usageKind) が usage に一致する行の単価を
+// 返す。段階料金で複数行が該当する場合は最初の段階 (最も高い単価) を採用し、無料枠 (単価 0)
+// の行は使わない。
+func usagePrice(table *PriceTable, usage string) (float64, bool) {
+	if table == nil {
+		return 0, false
+	}
+	price := 0.0
+	for _, r := range table.Rates {
+		if r.Model == "on_demand" && r.Attributes["usage"] == usage && r.PriceUSD > price {
+			price = r.PriceUSD
+		}
+	}
+	return price, price > 0
+}
+
+// ApplyEC2CostEstimates は EC2 の On-Demand レート表 (GetPricing の "ec2") と突合し、
+// 各インスタンスの CostMonthly に推定月額 (On-Demand・共有テナンシー) を設定する。
+// 停止中のインスタンスはインスタンス料金が発生しないため 0 のままとする。
+// OS を Price List の operatingSystem に対応付けられないインスタンス (SQL Server
+// 同梱 AMI 等。レート表側が preInstalledSw=NA に絞っているため) も 0 のままとする。
+func ApplyEC2CostEstimates(resources []EC2Resource, table *PriceTable) {
+	idx := newOnDemandIndex(table, "instance_type", "os", "tenancy", "license_model")
+	for i := range resources {
+		r := &resources[i]
+		if r.State != "running" && r.State != "pending" {
+			continue
+		}
+		os, ok := ec2PricingOS(r.Platform)
+		if !ok {
+			continue
+		}
+		license := "No License required"
+		if os == "Windows" {
+			license = "License included"
+		}
+		hourly, ok := idx.hourly(map[string]string{
+			"instance_type": r.InstanceType,
+			"os":            os,
+			"tenancy":       "Shared",
+			"license_model": license,
+		})
+		if !ok {
+			continue
+		}
+		r.CostMonthly = monthlyCost(hourly, 1)
+	}
+}
+
+// ec2PricingOS は DescribeInstances の PlatformDetails を Price List の
+// operatingSystem 属性値へ変換する。
+func ec2PricingOS(platformDetails string) (string, bool) {
+	switch platformDetails {
+	case "", "Linux/UNIX":
+		return "Linux", true
+	case "Windows":
+		return "Windows", true
+	case "Red Hat Enterprise Linux":
+		return "RHEL", true
+	case "Red Hat Enterprise Linux with HA":
+		return "Red Hat Enterprise Linux with HA", true
+	case "SUSE Linux":
+		return "SUSE", true
+	case "Ubuntu Pro":
+		return "Ubuntu Pro", true
+	default:
+		return "", false
+	}
+}
+
+// ApplyRDSCostEstimates は RDS の On-Demand レート表 (GetPricing の "rds") と突合し、
+// 各 DB インスタンスの CostMonthly に推定月額 (インスタンス料金のみ、ストレージ・
+// I/O・バックアップは含まない) を設定する。停止中のインスタンスは 0 のままとする。
+// Oracle / SQL Server / Db2 はエディションとライセンスモデルをレート表の属性だけで
+// 一意に決められないため推定しない。
+func ApplyRDSCostEstimates(resources []RDSResource, table *PriceTable) {
+	idx := newOnDemandIndex(table, "instance_type", "engine", "deployment_option", "storage_type")
+	for i := range resources {
+		r := &resources[i]
+		if r.State == "stopped" || r.State == "stopping" {
+			continue
+		}
+		engine, ok := rdsPricingEngine(r.Engine)
+		if !ok {
+			continue
+		}
+		// Aurora はクラスタ内の各インスタンスが Single-AZ 単価で課金される
+		// (Multi-AZ 構成はレプリカインスタンスの台数として表れる)。
+		deployment := "Single-AZ"
+		if r.MultiAZ && !strings.HasPrefix(r.Engine, "aurora") {
+			deployment = "Multi-AZ"
+		}
+		hourly, ok := idx.hourly(map[string]string{
+			"instance_type":     r.Class,
+			"engine":            engine,
+			"deployment_option": deployment,
+			"storage_type":      "standard",
+		})
+		if !ok {
+			continue
+		}
+		r.CostMonthly = monthlyCost(hourly, 1)
+	}
+}
+
+// rdsPricingEngine は DescribeDBInstances の Engine を Price List の
+// databaseEngine 属性値へ変換する。
+func rdsPricingEngine(engine string) (string, bool) {
+	switch engine {
+	case "mysql":
+		return "MySQL", true
+	case "postgres":
+		return "PostgreSQL", true
+	case "mariadb":
+		return "MariaDB", true
+	case "aurora", "aurora-mysql":
+		return "Aurora MySQL", true
+	case "aurora-postgresql":
+		return "Aurora PostgreSQL", true
+	default:
+		return "", false
+	}
+}
+
+// ApplyElastiCacheCostEstimates は ElastiCache の On-Demand レート表
+// (GetPricing の "elasticache") と突合し、各クラスタの CostMonthly に
+// ノード単価 × ノード数の推定月額を設定する。同期耐久性オプション課金
+// (SyncDurability) は含めない。
+func ApplyElastiCacheCostEstimates(resources []ElastiCacheResource, table *PriceTable) {
+	idx := newOnDemandIndex(table, "instance_type", "engine", "sync_durability")
+	for i := range resources {
+		r := &resources[i]
+		engine, ok := elastiCachePricingEngine(r.Engine)
+		if !ok {
+			continue
+		}
+		hourly, ok := idx.hourly(map[string]string{
+			"instance_type":   r.NodeType,
+			"engine":          engine,
+			"sync_durability": "Standard",
+		})
+		if !ok {
+			continue
+		}
+		r.CostMonthly = monthlyCost(hourly, r.NumNodes)
+	}
+}
+
+// elastiCachePricingEngine は DescribeCacheClusters の Engine を Price List の
+// cacheEngine 属性値へ変換する。
+func elastiCachePricingEngine(engine string) (string, bool) {
+	switch engine {
+	case "redis":
+		return "Redis", true
+	case "valkey":
+		return "Valkey", true
+	case "memcached":
+		return "Memcached", true
+	default:
+		return "", false
+	}
+}
+
+// ApplyKinesisCostEstimates は Kinesis のレート表 (GetPricing の "kinesis") と突合し、
+// プロビジョンドモードの各ストリームの CostMonthly にシャード時間の料金 × オープンシャード数の
+// 推定月額を設定する。PUT ペイロードユニット・保持期間の延長・拡張ファンアウトは含めない。
+// オンデマンドモードのストリームはスループットで課金されるため 0 のままとする。
+func ApplyKinesisCostEstimates(resources []KinesisResource, table *PriceTable) {
+	hourly, ok := usagePrice(table, "shard_hour")
+	if !ok {
+		return
+	}
+	for i := range resources {
+		r := &resources[i]
+		if r.StreamMode == "ON_DEMAND" {
+			continue
+		}
+		r.CostMonthly = monthlyCost(hourly, r.ShardCount)
+	}
+}
+
+// ApplyWAFCostEstimates は WAF のレート表 (GetPricing の "waf") と突合し、各 Web ACL の
+// CostMonthly に Web ACL とルールの月額料金 (ルールの月額 × RuleCount) を設定する。リクエスト数に
+// 応じた料金は含めない。CLOUDFRONT スコープの Web ACL も同じリージョンの単価で見積もる。
+func ApplyWAFCostEstimates(resources []WAFResource, table *PriceTable) {
+	acl, ok := usagePrice(table, "web_acl")
+	if !ok {
+		return
+	}
+	rule, _ := usagePrice(table, "rule")
+	for i := range resources {
+		r := &resources[i]
+		r.CostMonthly = roundCents(acl + rule*float64(r.RuleCount))
+	}
+}
+
+// ApplyNATGatewayCostEstimates は NAT ゲートウェイのレート表 (GetPricing の "natgw") と突合し、
+// 利用可能な各 NAT ゲートウェイの CostMonthly に時間料金の推定月額を設定する。データ処理料金は含めない。
+func ApplyNATGatewayCostEstimates(resources []NATGatewayResource, table *PriceTable) {
+	hourly, ok := usagePrice(table, "hour")
+	if !ok {
+		return
+	}
+	for i := range resources {
+		r := &resources[i]
+		if r.State != "available" && r.State != "pending" {
+			continue
+		}
+		r.CostMonthly = monthlyCost(hourly, 1)
+	}
+}
+
+// ApplyECRImageCostEstimates は ECR のレート表 (GetPricing の "ecr") と突合し、各イメージの
+// CostMonthly にイメージサイズのストレージ料金の推定月額を設定する。リポジトリ内でレイヤーを共有する
+// イメージはそれぞれのサイズで数えるため、リポジトリの実際の請求額より大きくなることがある。
+func ApplyECRImageCostEstimates(images []ECRImageResource, table *PriceTable) {
+	perGB, ok := usagePrice(table, "storage_gb_month")
+	if !ok {
+		return
+	}
+	for i := range images {
+		images[i].CostMonthly = roundCents(float64(images[i].ImageSizeBytes) / bytesPerGB * perGB)
+	}
+}
+
+// ApplyLogGroupCostEstimates は CloudWatch のレート表 (GetPricing の "cwlogs") と突合し、各ロググループの
+// CostMonthly に保存済みのログの保管料金の推定月額を設定する。取り込み料金は含めない。
+func ApplyLogGroupCostEstimates(groups []LogGroupInfo, table *PriceTable) {
+	perGB, ok := usagePrice(table, "storage_gb_month")
+	if !ok {
+		return
+	}
+	for i := range groups {
+		groups[i].CostMonthly = roundCents(float64(groups[i].StoredBytes) / bytesPerGB * perGB)
+	}
+}
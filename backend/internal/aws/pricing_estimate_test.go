@@ -0,0 +1,166 @@
+package aws
+
+import "testing"
+
+func onDemandRate(price float64, attrs map[string]string) PriceRate {
+	return PriceRate{Model: "on_demand", Unit: "Hrs", PriceUSD: price, Attributes: attrs}
+}
+
+func TestApplyEC2CostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{
+		onDemandRate(0.0136, map[string]string{"instance_type": "t3.micro", "os": "Linux", "tenancy": "Shared", "license_model": "No License required"}),
+		onDemandRate(0.0228, map[string]string{"instance_type": "t3.micro", "os": "Windows", "tenancy": "Shared", "license_model": "License included"}),
+		onDemandRate(0.0100, map[string]string{"instance_type": "t3.micro", "os": "Windows", "tenancy": "Shared", "license_model": "Bring your own license"}),
+		{Model: "reserved", Unit: "Hrs", PriceUSD: 0.001, Attributes: map[string]string{"instance_type": "t3.micro", "os": "Linux", "tenancy": "Shared", "license_model": "No License required"}},
+	}}
+	resources := []EC2Resource{
+		{ID: "linux", InstanceType: "t3.micro", State: "running", Platform: "Linux/UNIX"},
+		{ID: "windows", InstanceType: "t3.micro", State: "running", Platform: "Windows"},
+		{ID: "stopped", InstanceType: "t3.micro", State: "stopped", Platform: "Linux/UNIX"},
+		{ID: "unknown-type", InstanceType: "x9.huge", State: "running", Platform: "Linux/UNIX"},
+		{ID: "sql-server", InstanceType: "t3.micro", State: "running", Platform: "Windows with SQL Server Standard"},
+	}
+
+	ApplyEC2CostEstimates(resources, table)
+
+	want := map[string]float64{
+		"linux":        9.93,
+		"windows":      16.64,
+		"stopped":      0,
+		"unknown-type": 0,
+		"sql-server":   0,
+	}
+	for _, r := range resources {
+		if r.CostMonthly != want[r.ID] {
+			t.Errorf("%s: CostMonthly = %v, want %v", r.ID, r.CostMonthly, want[r.ID])
+		}
+	}
+}
+
+func TestApplyRDSCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{
+		onDemandRate(0.1, map[string]string{"instance_type": "db.r6g.large", "engine": "PostgreSQL", "deployment_option": "Single-AZ", "storage_type": "standard"}),
+		onDemandRate(0.2, map[string]string{"instance_type": "db.r6g.large", "engine": "PostgreSQL", "deployment_option": "Multi-AZ", "storage_type": "standard"}),
+		onDemandRate(0.3, map[string]string{"instance_type": "db.r6g.large", "engine": "Aurora PostgreSQL", "deployment_option": "Single-AZ", "storage_type": "standard"}),
+		onDemandRate(0.4, map[string]string{"instance_type": "db.r6g.large", "engine": "Aurora PostgreSQL", "deployment_option": "Single-AZ", "storage_type": "io_optimized"}),
+	}}
+	resources := []RDSResource{
+		{ID: "single", Class: "db.r6g.large", Engine: "postgres", State: "available"},
+		{ID: "multi", Class: "db.r6g.large", Engine: "postgres", State: "available", MultiAZ: true},
+		{ID: "aurora", Class: "db.r6g.large", Engine: "aurora-postgresql", State: "available", MultiAZ: true},
+		{ID: "stopped", Class: "db.r6g.large", Engine: "postgres", State: "stopped"},
+		{ID: "oracle", Class: "db.r6g.large", Engine: "oracle-ee", State: "available"},
+	}
+
+	ApplyRDSCostEstimates(resources, table)
+
+	want := map[string]float64{
+		"single":  73,
+		"multi":   146,
+		"aurora":  219,
+		"stopped": 0,
+		"oracle":  0,
+	}
+	for _, r := range resources {
+		if r.CostMonthly != want[r.ID] {
+			t.Errorf("%s: CostMonthly = %v, want %v", r.ID, r.CostMonthly, want[r.ID])
+		}
+	}
+}
+
+func TestApplyElastiCacheCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{
+		onDemandRate(0.068, map[string]string{"instance_type": "cache.t4g.medium", "engine": "Redis", "sync_durability": "Standard"}),
+		onDemandRate(0.5, map[string]string{"instance_type": "cache.t4g.medium", "engine": "Redis", "sync_durability": "SyncDurability"}),
+	}}
+	resources := []ElastiCacheResource{
+		{ID: "redis", NodeType: "cache.t4g.medium", Engine: "redis", NumNodes: 2},
+		{ID: "memcached", NodeType: "cache.t4g.medium", Engine: "memcached", NumNodes: 1},
+	}
+
+	ApplyElastiCacheCostEstimates(resources, table)
+
+	if got := resources[0].CostMonthly; got != 99.28 {
+		t.Errorf("redis: CostMonthly = %v, want 99.28", got)
+	}
+	if got := resources[1].CostMonthly; got != 0 {
+		t.Errorf("memcached: CostMonthly = %v, want 0", got)
+	}
+}
+
+func usageRate(price float64, usage string) PriceRate {
+	return PriceRate{Model: "on_demand", PriceUSD: price, Attributes: map[string]string{"usage": usage}}
+}
+
+func TestApplyKinesisCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{usageRate(0.0195, "shard_hour")}}
+	resources := []KinesisResource{
+		{ID: "provisioned", ShardCount: 4, StreamMode: "PROVISIONED"},
+		{ID: "on-demand", ShardCount: 4, StreamMode: "ON_DEMAND"},
+	}
+
+	ApplyKinesisCostEstimates(resources, table)
+
+	if got := resources[0].CostMonthly; got != 56.94 {
+		t.Errorf("provisioned: CostMonthly = %v, want 56.94", got)
+	}
+	if got := resources[1].CostMonthly; got != 0 {
+		t.Errorf("on-demand: CostMonthly = %v, want 0", got)
+	}
+}
+
+func TestApplyWAFCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{
+		usageRate(5, "web_acl"),
+		usageRate(1, "rule"),
+		usageRate(0, "rule"), // 無料枠の行は使わない
+	}}
+	resources := []WAFResource{{ID: "acl", RuleCount: 3}, {ID: "empty"}}
+
+	ApplyWAFCostEstimates(resources, table)
+
+	if got := resources[0].CostMonthly; got != 8 {
+		t.Errorf("acl: CostMonthly = %v, want 8", got)
+	}
+	if got := resources[1].CostMonthly; got != 5 {
+		t.Errorf("empty: CostMonthly = %v, want 5", got)
+	}
+}
+
+func TestApplyNATGatewayCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{usageRate(0.062, "hour")}}
+	resources := []NATGatewayResource{{ID: "available", State: "available"}, {ID: "deleted", State: "deleted"}}
+
+	ApplyNATGatewayCostEstimates(resources, table)
+
+	if got := resources[0].CostMonthly; got != 45.26 {
+		t.Errorf("available: CostMonthly = %v, want 45.26", got)
+	}
+	if got := resources[1].CostMonthly; got != 0 {
+		t.Errorf("deleted: CostMonthly = %v, want 0", got)
+	}
+}
+
+func TestApplyStorageCostEstimates(t *testing.T) {
+	table := &PriceTable{Rates: []PriceRate{usageRate(0.1, "storage_gb_month")}}
+	images := []ECRImageResource{{ImageDigest: "sha256:1", ImageSizeBytes: 2e9}}
+	ApplyECRImageCostEstimates(images, table)
+	if got := images[0].CostMonthly; got != 0.2 {
+		t.Errorf("ECR image: CostMonthly = %v, want 0.2", got)
+	}
+
+	table = &PriceTable{Rates: []PriceRate{usageRate(0.033, "storage_gb_month")}}
+	groups := []LogGroupInfo{{Name: "/app", StoredBytes: 100e9}}
+	ApplyLogGroupCostEstimates(groups, table)
+	if got := groups[0].CostMonthly; got != 3.3 {
+		t.Errorf("log group: CostMonthly = %v, want 3.3", got)
+	}
+}
+
+func TestApplyCostEstimatesNilTable(t *testing.T) {
+	resources := []EC2Resource{{ID: "i-1", InstanceType: "t3.micro", State: "running"}}
+	ApplyEC2CostEstimates(resources, nil)
+	if resources[0].CostMonthly != 0 {
+		t.Errorf("CostMonthly = %v, want 0", resources[0].CostMonthly)
+	}
+}
@@ -220,6 +220,69 @@ func TestParsePriceDocument(t *testing.T) {
 	})
 }
 
+const kinesisShardHourDoc = `{
+  "product": {"sku": "SKU10", "productFamily": "Kinesis Streams", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-Storage-ShardHour", "group": "Provisioned shard hour"
+  }},
+  "terms": {"OnDemand": {"SKU10.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU10.OTC1.RC1": {"rateCode": "SKU10.OTC1.RC1", "unit": "ShardHour", "pricePerUnit": {"USD": "0.0195000000"}}
+  }}}}
+}`
+
+const kinesisExtendedRetentionDoc = `{
+  "product": {"sku": "SKU11", "productFamily": "Kinesis Streams", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-Extended-ShardHour"
+  }},
+  "terms": {"OnDemand": {"SKU11.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU11.OTC1.RC1": {"rateCode": "SKU11.OTC1.RC1", "unit": "ShardHour", "pricePerUnit": {"USD": "0.0240000000"}}
+  }}}}
+}`
+
+const wafWebACLDoc = `{
+  "product": {"sku": "SKU12", "productFamily": "Web Application Firewall", "attributes": {
+    "regionCode": "us-east-1", "usagetype": "WebACL", "group": "Web ACL"
+  }},
+  "terms": {"OnDemand": {"SKU12.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU12.OTC1.RC1": {"rateCode": "SKU12.OTC1.RC1", "unit": "WebACL", "pricePerUnit": {"USD": "5.0000000000"}}
+  }}}}
+}`
+
+const wafRequestDoc = `{
+  "product": {"sku": "SKU13", "productFamily": "Web Application Firewall", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-Request"
+  }},
+  "terms": {"OnDemand": {"SKU13.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU13.OTC1.RC1": {"rateCode": "SKU13.OTC1.RC1", "unit": "Request", "pricePerUnit": {"USD": "0.0000006000"}}
+  }}}}
+}`
+
+const natGatewayHoursDoc = `{
+  "product": {"sku": "SKU14", "productFamily": "NAT Gateway", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-NatGateway-Hours", "operation": "NatGateway"
+  }},
+  "terms": {"OnDemand": {"SKU14.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU14.OTC1.RC1": {"rateCode": "SKU14.OTC1.RC1", "unit": "Hrs", "pricePerUnit": {"USD": "0.0620000000"}}
+  }}}}
+}`
+
+const natGatewayBytesDoc = `{
+  "product": {"sku": "SKU15", "productFamily": "NAT Gateway", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-NatGateway-Bytes", "operation": "NatGateway"
+  }},
+  "terms": {"OnDemand": {"SKU15.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU15.OTC1.RC1": {"rateCode": "SKU15.OTC1.RC1", "unit": "GB", "pricePerUnit": {"USD": "0.0620000000"}}
+  }}}}
+}`
+
+const cwlogsStorageDoc = `{
+  "product": {"sku": "SKU16", "productFamily": "Storage Snapshot", "attributes": {
+    "regionCode": "ap-northeast-1", "usagetype": "APN1-TimedStorage-ByteHrs"
+  }},
+  "terms": {"OnDemand": {"SKU16.OTC1": {"offerTermCode": "OTC1", "termAttributes": {}, "priceDimensions": {
+    "SKU16.OTC1.RC1": {"rateCode": "SKU16.OTC1.RC1", "unit": "GB-Mo", "pricePerUnit": {"USD": "0.0330000000"}}
+  }}}}
+}`
+
 func TestPriceRatesFromDocument(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -398,6 +461,76 @@ func TestPriceRatesFromDocument(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "kinesis shard hour",
+			service: "kinesis",
+			raw:     kinesisShardHourDoc,
+			want: []PriceRate{
+				{
+					RateID: "SKU10.OTC1.RC1", Model: "on_demand", Group: "On-Demand",
+					Label:      "Shard Hour",
+					Attributes: map[string]string{"usage": "shard_hour"},
+					Unit:       "ShardHour", PriceUSD: 0.0195, Currency: "USD",
+				},
+			},
+		},
+		{
+			name:    "kinesis extended retention excluded",
+			service: "kinesis",
+			raw:     kinesisExtendedRetentionDoc,
+			want:    nil,
+		},
+		{
+			name:    "waf web acl without region prefix (us-east-1)",
+			service: "waf",
+			raw:     wafWebACLDoc,
+			want: []PriceRate{
+				{
+					RateID: "SKU12.OTC1.RC1", Model: "on_demand", Group: "On-Demand",
+					Label:      "Web ACL",
+					Attributes: map[string]string{"usage": "web_acl"},
+					Unit:       "WebACL", PriceUSD: 5, Currency: "USD",
+				},
+			},
+		},
+		{
+			name:    "waf requests excluded",
+			service: "waf",
+			raw:     wafRequestDoc,
+			want:    nil,
+		},
+		{
+			name:    "nat gateway hours",
+			service: "natgw",
+			raw:     natGatewayHoursDoc,
+			want: []PriceRate{
+				{
+					RateID: "SKU14.OTC1.RC1", Model: "on_demand", Group: "On-Demand",
+					Label:      "NAT Gateway Hour",
+					Attributes: map[string]string{"usage": "hour"},
+					Unit:       "Hrs", PriceUSD: 0.062, Currency: "USD",
+				},
+			},
+		},
+		{
+			name:    "nat gateway data processing excluded",
+			service: "natgw",
+			raw:     natGatewayBytesDoc,
+			want:    nil,
+		},
+		{
+			name:    "cloudwatch logs storage",
+			service: "cwlogs",
+			raw:     cwlogsStorageDoc,
+			want: []PriceRate{
+				{
+					RateID: "SKU16.OTC1.RC1", Model: "on_demand", Group: "On-Demand",
+					Label:      "Storage (GB-Mo)",
+					Attributes: map[string]string{"usage": "storage_gb_month"},
+					Unit:       "GB-Mo", PriceUSD: 0.033, Currency: "USD",
+				},
+			},
+		},
 		{
 			name:    "ecs fargate ephemeral storage excluded",
 			service: "ecs",
@@ -1801,6 +1934,11 @@ func TestValidatePricingService(t *testing.T) {
 		{service: "rds", wantErr: false},
 		{service: "elasticache", wantErr: false},
 		{service: "ecs", wantErr: false},
+		{service: "kinesis", wantErr: false},
+		{service: "waf", wantErr: false},
+		{service: "natgw", wantErr: false},
+		{service: "ecr", wantErr: false},
+		{service: "cwlogs", wantErr: false},
 		{service: "compute-sp", wantErr: false},
 		{service: "ec2-instance-sp", wantErr: false},
 		{service: "database-sp", wantErr: false},
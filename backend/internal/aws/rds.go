@@ -190,13 +190,14 @@ func rdsTagsToMap(tags []rdstypes.Tag) map[string]string {
 
 // RDSInstanceInfo はレガシー CLI 互換の RDS インスタンス表示用フィールドを保持する。
 type RDSInstanceInfo struct {
-	Name            string
-	DBInstanceClass string
-	Engine          string
-	EngineVersion   string
-	Storage         string
-	StorageType     string
-	Status          string
+	Name            string            `json:"name"`
+	DBInstanceClass string            `json:"db_instance_class"`
+	Engine          string            `json:"engine"`
+	EngineVersion   string            `json:"engine_version"`
+	Storage         string            `json:"storage"`
+	StorageType     string            `json:"storage_type"`
+	Status          string            `json:"status"`
+	Tags            map[string]string `json:"tags"`
 }
 
 // ToRow converts RDSInstanceInfo to a string slice suitable for table formatting.
@@ -207,13 +208,17 @@ func (i RDSInstanceInfo) ToRow() []string {
 	}
 }
 
+// ResourceTags returns the DB instance tags.
+func (i RDSInstanceInfo) ResourceTags() map[string]string { return i.Tags }
+
 // RDSClusterInfo はレガシー CLI 互換の RDS クラスタ表示用フィールドを保持する。
 type RDSClusterInfo struct {
-	Name          string
-	Engine        string
-	EngineVersion string
-	EngineMode    string
-	Status        string
+	Name          string            `json:"name"`
+	Engine        string            `json:"engine"`
+	EngineVersion string            `json:"engine_version"`
+	EngineMode    string            `json:"engine_mode"`
+	Status        string            `json:"status"`
+	Tags          map[string]string `json:"tags"`
 }
 
 // ToRow converts RDSClusterInfo to a string slice suitable for table formatting.
@@ -221,6 +226,9 @@ func (c RDSClusterInfo) ToRow() []string {
 	return []string{c.Name, c.Engine, c.EngineVersion, c.EngineMode, c.Status}
 }
 
+// ResourceTags returns the DB cluster tags.
+func (c RDSClusterInfo) ResourceTags() map[string]string { return c.Tags }
+
 // ListRDSInstanceInfos は RDS DB インスタンス一覧をレガシー CLI 互換フィールドで返す。
 func ListRDSInstanceInfos(ctx context.Context, profile, region string) ([]RDSInstanceInfo, error) {
 	client, err := newRDSClient(ctx, profile, region)
@@ -244,6 +252,7 @@ func ListRDSInstanceInfos(ctx context.Context, profile, region string) ([]RDSIns
 				Storage:         fmt.Sprintf("%dGB", ptrInt32(db.AllocatedStorage)),
 				StorageType:     ptrStr(db.StorageType),
 				Status:          ptrStr(db.DBInstanceStatus),
+				Tags:            rdsTagsToMap(db.TagList),
 			})
 		}
 	}
@@ -271,6 +280,7 @@ func ListRDSClusterInfos(ctx context.Context, profile, region string) ([]RDSClus
 				EngineVersion: ptrStr(c.EngineVersion),
 				EngineMode:    ptrStr(c.EngineMode),
 				Status:        ptrStr(c.Status),
+				Tags:          rdsTagsToMap(c.TagList),
 			})
 		}
 	}
@@ -279,12 +289,12 @@ func ListRDSClusterInfos(ctx context.Context, profile, region string) ([]RDSClus
 
 // RDSParameterInfo はレガシー CLI 互換の RDS パラメータ表示用フィールドを保持する。
 type RDSParameterInfo struct {
-	Name         string
-	Value        string
-	ApplyType    string
-	DataType     string
-	IsModifiable string
-	Source       string
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	ApplyType    string `json:"apply_type"`
+	DataType     string `json:"data_type"`
+	IsModifiable string `json:"is_modifiable"`
+	Source       string `json:"source"`
 }
 
 // ToRow converts RDSParameterInfo to a string slice suitable for table formatting.
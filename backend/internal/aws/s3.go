@@ -15,14 +15,16 @@ import (
 
 // S3Resource represents a single S3 bucket.
 type S3Resource struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	State       string  `json:"state"`
-	Region      string  `json:"region"`
-	CreatedAt   string  `json:"created_at"`
-	Public      bool    `json:"public"`
-	Encryption  string  `json:"encryption"`
-	CostMonthly float64 `json:"cost_monthly"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Region     string `json:"region"`
+	CreatedAt  string `json:"created_at"`
+	Public     bool   `json:"public"`
+	Encryption string `json:"encryption"`
+	// CostMonthly は常に nil (JSON では null)。S3 はストレージクラスごとの保存量とリクエスト数で課金され、
+	// ListBuckets の一覧からは見積もれない。
+	CostMonthly *float64 `json:"cost_monthly"`
 }
 
 func (r S3Resource) ResourceID() string    { return r.ID }
@@ -130,8 +132,8 @@ func resolveS3Public(ctx context.Context, client *s3.Client, bucket string) bool
 
 // S3BucketInfo はレガシー CLI 互換の S3 バケット表示用フィールドを保持する。
 type S3BucketInfo struct {
-	Name         string
-	CreationDate string
+	Name         string `json:"name"`
+	CreationDate string `json:"creation_date"`
 }
 
 // ToRow converts S3BucketInfo to a string slice suitable for table formatting.
@@ -1,9 +1,11 @@
 package aws
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"io"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -25,56 +27,173 @@ func (r S3ObjectResource) ResourceName() string  { return r.Key }
 func (r S3ObjectResource) ResourceState() string { return "" }
 func (r S3ObjectResource) ServiceName() string   { return "s3-objects" }
 
-// maxS3ListObjects は ListS3Objects が返すオブジェクト件数の上限。オブジェクトが膨大な
-// バケットで取得に時間がかかりレスポンスが肥大化するのを避けるため、蓄積件数がこれに
-// 達した時点でページネーションを打ち切る。ページサイズ (ListObjectsV2 の既定 1000 件/ページ)
-// に依存せず、オブジェクト単位でカウントする。
+// maxS3ListObjects は ListS3Objects が 1 回に返すオブジェクトとプレフィックスの件数の上限 (既定値)。
+// オブジェクトが膨大なバケットで取得に時間がかかりレスポンスが肥大化するのを避けるため、
+// 蓄積件数がこれに達した時点で列挙を止め、続きは NextContinuationToken で取得させる。
 const maxS3ListObjects = 1000
 
-// ListS3Objects は指定バケット (と prefix) のオブジェクト一覧を返す。
+// maxS3ListScanKeys は絞り込み (S3ObjectFilter) の指定時に 1 回の ListS3Objects で走査するキー数の上限。
+// 条件に一致するキーが少ないバケットで ListObjectsV2 を際限なく呼び続けないよう、達した時点で
+// 一致件数が MaxKeys 未満でも打ち切って NextContinuationToken を返す。
+const maxS3ListScanKeys = 20000
+
+// S3ObjectFilter は ListS3Objects のキーの絞り込み条件。ゼロ値の項目は条件にしない。
+// KeyContains はオブジェクトとプレフィックスの両方、サイズと更新日時はオブジェクトだけに適用する。
+type S3ObjectFilter struct {
+	KeyContains    string
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+}
+
+// IsZero は絞り込み条件が指定されていないかを返す。
+func (f S3ObjectFilter) IsZero() bool {
+	return f == S3ObjectFilter{}
+}
+
+func (f S3ObjectFilter) matchPrefix(prefix string) bool {
+	return strings.Contains(prefix, f.KeyContains)
+}
+
+func (f S3ObjectFilter) matchObject(o s3types.Object) bool {
+	if !strings.Contains(ptrStr(o.Key), f.KeyContains) {
+		return false
+	}
+	size := aws.ToInt64(o.Size)
+	if f.MinSize > 0 && size < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && size > f.MaxSize {
+		return false
+	}
+	modified := aws.ToTime(o.LastModified)
+	if !f.ModifiedAfter.IsZero() && modified.Before(f.ModifiedAfter) {
+		return false
+	}
+	if !f.ModifiedBefore.IsZero() && !modified.Before(f.ModifiedBefore) {
+		return false
+	}
+	return true
+}
+
+// S3ListOptions は ListS3Objects のオプション。Delimiter を "/" にするとフォルダ単位の一覧になり、
+// Prefix 直下のキーを Objects、その下の階層を CommonPrefixes に返す。ContinuationToken は前回の
+// S3ObjectPage.NextContinuationToken (空なら先頭から)。MaxKeys が 0 以下なら maxS3ListObjects を使う。
+type S3ListOptions struct {
+	Prefix            string
+	Delimiter         string
+	ContinuationToken string
+	MaxKeys           int
+	Filter            S3ObjectFilter
+}
+
+// S3ObjectPage は ListS3Objects の 1 ページ。Truncated は続きがあることを示し、
+// そのときの NextContinuationToken を次の ListS3Objects に渡すと続きから列挙する。
+type S3ObjectPage struct {
+	Objects               []S3ObjectResource `json:"objects"`
+	CommonPrefixes        []string           `json:"common_prefixes"`
+	NextContinuationToken string             `json:"next_continuation_token,omitempty"`
+	Truncated             bool               `json:"truncated"`
+}
+
+// ListS3Objects は指定バケットのオブジェクト一覧を 1 ページ返す。
 // バケットのリージョンを GetBucketLocation で解決してから ListObjectsV2 を呼ぶ。
-// 蓄積件数が maxS3ListObjects に達した時点で打ち切り、truncated に true を返す。
-func ListS3Objects(ctx context.Context, profile, region, bucket, prefix string) (resources []S3ObjectResource, truncated bool, err error) {
+// オブジェクトとプレフィックスの合計が MaxKeys に達するか、絞り込みの指定時に走査したキーが
+// maxS3ListScanKeys に達した時点で止め、続きの ContinuationToken を返す。
+func ListS3Objects(ctx context.Context, profile, region, bucket string, opts S3ListOptions) (S3ObjectPage, error) {
 	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
 	if err != nil {
-		return nil, false, err
+		return S3ObjectPage{}, err
 	}
+	return listS3ObjectPage(ctx, client, bucket, opts)
+}
 
+// s3ListObjectsAPI は S3 SDK クライアントのうち listS3ObjectPage が利用する操作。
+// テストでは手書きフェイクを差し込む。
+type s3ListObjectsAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// listS3ObjectPage は ListObjectsV2 をページごとに呼んで 1 ページ分を集める。各リクエストの MaxKeys を
+// 残り件数にするため、ページの途中で打ち切ることがなく、S3 の NextContinuationToken をそのまま返せる。
+func listS3ObjectPage(ctx context.Context, client s3ListObjectsAPI, bucket string, opts S3ListOptions) (S3ObjectPage, error) {
+	limit := opts.MaxKeys
+	if limit <= 0 {
+		limit = maxS3ListObjects
+	}
 	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket)}
-	if prefix != "" {
-		input.Prefix = aws.String(prefix)
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(opts.ContinuationToken)
 	}
 
-	paginator := s3.NewListObjectsV2Paginator(client, input)
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
+	page := S3ObjectPage{Objects: []S3ObjectResource{}, CommonPrefixes: []string{}}
+	scanned := 0
+	for {
+		input.MaxKeys = aws.Int32(int32(limit - len(page.Objects) - len(page.CommonPrefixes)))
+		out, err := client.ListObjectsV2(ctx, input)
 		if err != nil {
-			return nil, false, fmt.Errorf("list s3 objects in %s: %w", bucket, err)
+			return S3ObjectPage{}, fmt.Errorf("list s3 objects in %s: %w", bucket, err)
+		}
+		for _, p := range out.CommonPrefixes {
+			if prefix := ptrStr(p.Prefix); opts.Filter.matchPrefix(prefix) {
+				page.CommonPrefixes = append(page.CommonPrefixes, prefix)
+			}
+		}
+		for _, o := range out.Contents {
+			if opts.Filter.matchObject(o) {
+				page.Objects = append(page.Objects, s3ObjectFromSDK(o))
+			}
+		}
+		scanned += len(out.Contents) + len(out.CommonPrefixes)
+
+		if !aws.ToBool(out.IsTruncated) || ptrStr(out.NextContinuationToken) == "" {
+			return page, nil
 		}
-		var pageTruncated bool
-		resources, pageTruncated = appendS3ObjectsUpToLimit(resources, page.Contents, maxS3ListObjects)
-		if pageTruncated {
-			return resources, true, nil
+		input.ContinuationToken = out.NextContinuationToken
+		if len(page.Objects)+len(page.CommonPrefixes) >= limit || scanned >= maxS3ListScanKeys {
+			page.NextContinuationToken = ptrStr(out.NextContinuationToken)
+			page.Truncated = true
+			return page, nil
 		}
 	}
-	return resources, false, nil
 }
 
-// appendS3ObjectsUpToLimit は objs を resources に変換しながら追加し、蓄積件数が max に
-// 達した時点で追加を止める。ページの途中でも打ち切るため、ListObjectsV2 の既定ページサイズに
-// 依存せずオブジェクト単位で上限を判定できる。
-func appendS3ObjectsUpToLimit(
-	resources []S3ObjectResource,
-	objs []s3types.Object,
-	max int,
-) (updated []S3ObjectResource, truncated bool) {
-	for _, obj := range objs {
-		if len(resources) >= max {
-			return resources, true
-		}
-		resources = append(resources, s3ObjectFromSDK(obj))
+// S3ObjectSortFields は SortS3Objects で指定できる並び順のキー。
+var S3ObjectSortFields = []string{"key", "size", "last_modified"}
+
+// SortS3Objects は objects を field (S3ObjectSortFields のいずれか) の昇順 (desc なら降順) に並べ替える。
+// 同じ値のオブジェクトはキー順にする。ListS3Objects の 1 ページ内の並べ替えで、バケット全体の順序ではない。
+func SortS3Objects(objects []S3ObjectResource, field string, desc bool) error {
+	var cmpField func(a, b S3ObjectResource) int
+	switch field {
+	case "key":
+		cmpField = func(a, b S3ObjectResource) int { return strings.Compare(a.Key, b.Key) }
+	case "size":
+		cmpField = func(a, b S3ObjectResource) int { return cmp.Compare(a.Size, b.Size) }
+	case "last_modified":
+		// LastModified は UTC の RFC3339 のため文字列順が時刻順になる。
+		cmpField = func(a, b S3ObjectResource) int { return strings.Compare(a.LastModified, b.LastModified) }
+	default:
+		return fmt.Errorf("unknown sort field %q (valid: %s)", field, strings.Join(S3ObjectSortFields, ", "))
 	}
-	return resources, false
+	slices.SortStableFunc(objects, func(a, b S3ObjectResource) int {
+		c := cmpField(a, b)
+		if desc {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(a.Key, b.Key)
+		}
+		return c
+	})
+	return nil
 }
 
 // GetS3Object は指定オブジェクトを取得する。Body はストリーミングで返す。
@@ -94,29 +213,6 @@ func GetS3Object(ctx context.Context, profile, region, bucket, key string) (*s3.
 	return out, nil
 }
 
-// PutS3Object は body を PutObject の Body にそのまま渡してオブジェクトを書き込む。
-// S3 の PutObject は Content-Length が必須のため、呼び出し側は body の
-// 全長が確定した contentLength を渡すこと。
-func PutS3Object(ctx context.Context, profile, region, bucket, key string, body io.Reader, contentLength int64, contentType string) error {
-	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
-	if err != nil {
-		return err
-	}
-	input := &s3.PutObjectInput{
-		Bucket:        aws.String(bucket),
-		Key:           aws.String(key),
-		Body:          body,
-		ContentLength: aws.Int64(contentLength),
-	}
-	if contentType != "" {
-		input.ContentType = aws.String(contentType)
-	}
-	if _, err := client.PutObject(ctx, input); err != nil {
-		return fmt.Errorf("put s3 object %s/%s: %w", bucket, key, err)
-	}
-	return nil
-}
-
 // newS3ClientForBucket はバケットの実リージョンを解決してその上に S3 クライアントを作る。
 // S3 は署名 (SigV4) のためリージョン一致が必要で、us-east-1 固定では別リージョンのバケットに
 // 対する GetObject が 301 でリダイレクトする。
@@ -0,0 +1,477 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxS3BatchObjects は 1 回の削除・コピー・移動で扱うオブジェクト数の上限。DeleteObjects の
+// 1 リクエストの上限と同じにし、prefix 配下がこれを超える場合は対象を絞るよう求める。
+const maxS3BatchObjects = 1000
+
+// maxS3CopyObjectSize は CopyObject 1 回でコピーできるオブジェクトの大きさの上限。これを超えるオブジェクトは
+// UploadPartCopy のマルチパートアップロードでコピーする。
+const maxS3CopyObjectSize = 5 << 30 // 5GiB
+
+// s3CopyPartSize は UploadPartCopy 1 回でコピーするパートの大きさ。パート数が maxS3UploadParts を超える
+// 大きさ (約 4.9TiB 以上) のオブジェクトではパートを大きくする。
+const s3CopyPartSize = 512 << 20 // 512MiB
+
+// ErrS3TooManyObjects は prefix 配下のオブジェクトが maxS3BatchObjects を超えるときに返す。
+var ErrS3TooManyObjects = fmt.Errorf("more than %d objects; narrow the prefix", maxS3BatchObjects)
+
+// ErrS3NoObjects はコピー元の prefix 配下にオブジェクトがないときに返す。
+var ErrS3NoObjects = errors.New("no objects under the prefix")
+
+// ErrS3SameObject はコピー元とコピー先が同じオブジェクトのときに返す。
+var ErrS3SameObject = errors.New("source and destination are the same object")
+
+// s3ObjectOpsAPI は S3 SDK クライアントのうちオブジェクトの変更操作が利用する操作の集合。
+// テストでは手書きフェイクを差し込む。
+type s3ObjectOpsAPI interface {
+	s3ListObjectsAPI
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// S3ObjectVersionResource は S3 オブジェクトの 1 バージョンまたは削除マーカーを表す。
+type S3ObjectVersionResource struct {
+	Key            string `json:"key"`
+	VersionID      string `json:"version_id"`
+	IsLatest       bool   `json:"is_latest"`
+	IsDeleteMarker bool   `json:"is_delete_marker"`
+	Size           int64  `json:"size"`
+	LastModified   string `json:"last_modified"`
+	StorageClass   string `json:"storage_class"`
+	ETag           string `json:"etag"`
+}
+
+// ToRow converts S3ObjectVersionResource to a string slice suitable for table formatting.
+func (r S3ObjectVersionResource) ToRow() []string {
+	kind := "version"
+	if r.IsDeleteMarker {
+		kind = "delete-marker"
+	}
+	latest := ""
+	if r.IsLatest {
+		latest = "latest"
+	}
+	return []string{r.Key, r.VersionID, kind, latest, fmt.Sprintf("%d", r.Size), r.LastModified, r.StorageClass}
+}
+
+// S3ObjectIdentifier は削除対象のオブジェクト。VersionID を指定するとそのバージョンを完全に削除し、
+// 空ならバージョニングが有効なバケットでは削除マーカーを置く。
+type S3ObjectIdentifier struct {
+	Key       string `json:"key"`
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// S3CopyResult はコピー (移動) した 1 オブジェクトのコピー元とコピー先 (いずれも bucket/key)。
+type S3CopyResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// ToRow converts S3CopyResult to a string slice suitable for table formatting.
+func (r S3CopyResult) ToRow() []string {
+	return []string{r.Source, r.Destination}
+}
+
+// ListS3ObjectVersions は prefix 配下のオブジェクトのバージョンと削除マーカーを、キー順・新しい順で返す。
+// 蓄積件数が maxS3ListObjects に達した時点で打ち切り、truncated に true を返す。
+func ListS3ObjectVersions(ctx context.Context, profile, region, bucket, prefix string) (versions []S3ObjectVersionResource, truncated bool, err error) {
+	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+	if err != nil {
+		return nil, false, err
+	}
+	return listS3ObjectVersions(ctx, client, bucket, prefix, maxS3ListObjects)
+}
+
+func listS3ObjectVersions(ctx context.Context, client s3ObjectOpsAPI, bucket, prefix string, limit int) ([]S3ObjectVersionResource, bool, error) {
+	input := &s3.ListObjectVersionsInput{Bucket: aws.String(bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	versions := []S3ObjectVersionResource{}
+	truncated := false
+	for {
+		input.MaxKeys = aws.Int32(int32(limit - len(versions)))
+		out, err := client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return nil, false, fmt.Errorf("list s3 object versions in %s: %w", bucket, err)
+		}
+		for _, v := range out.Versions {
+			versions = append(versions, S3ObjectVersionResource{
+				Key:          ptrStr(v.Key),
+				VersionID:    ptrStr(v.VersionId),
+				IsLatest:     aws.ToBool(v.IsLatest),
+				Size:         aws.ToInt64(v.Size),
+				LastModified: formatS3Time(v.LastModified),
+				StorageClass: string(v.StorageClass),
+				ETag:         ptrStr(v.ETag),
+			})
+		}
+		for _, m := range out.DeleteMarkers {
+			versions = append(versions, S3ObjectVersionResource{
+				Key:            ptrStr(m.Key),
+				VersionID:      ptrStr(m.VersionId),
+				IsLatest:       aws.ToBool(m.IsLatest),
+				IsDeleteMarker: true,
+				LastModified:   formatS3Time(m.LastModified),
+			})
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		if len(versions) >= limit {
+			truncated = true
+			break
+		}
+		input.KeyMarker, input.VersionIdMarker = out.NextKeyMarker, out.NextVersionIdMarker
+	}
+	// Versions と DeleteMarkers は別の配列で返るため、キー順・新しい順に並べ直す。
+	slices.SortStableFunc(versions, func(a, b S3ObjectVersionResource) int {
+		return cmp.Or(strings.Compare(a.Key, b.Key), strings.Compare(b.LastModified, a.LastModified))
+	})
+	return versions, truncated, nil
+}
+
+// RestoreS3ObjectVersion は key の versionID のバージョンを同じキーにコピーし、最新のバージョンとして戻す。
+// 削除マーカーが最新のオブジェクトも復元できる。作成されたバージョンの ID を返す。
+func RestoreS3ObjectVersion(ctx context.Context, profile, region, bucket, key, versionID string) (string, error) {
+	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+	if err != nil {
+		return "", err
+	}
+	return restoreS3ObjectVersion(ctx, client, bucket, key, versionID)
+}
+
+func restoreS3ObjectVersion(ctx context.Context, client s3ObjectOpsAPI, bucket, key, versionID string) (string, error) {
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("restore s3 object %s/%s version %s: %w", bucket, key, versionID, err)
+	}
+	newVersion, err := copyS3Object(ctx, client, client, bucket, key, versionID, aws.ToInt64(head.ContentLength), bucket, key)
+	if err != nil {
+		return "", fmt.Errorf("restore s3 object %s/%s version %s: %w", bucket, key, versionID, err)
+	}
+	return newVersion, nil
+}
+
+// ExpandS3Objects は objects と prefix 配下のオブジェクトを合わせ、キー順に重複なく返す。
+// prefix 配下が maxS3BatchObjects を超える場合は ErrS3TooManyObjects を返す。
+func ExpandS3Objects(ctx context.Context, profile, region, bucket string, objects []S3ObjectIdentifier, prefix string) ([]S3ObjectIdentifier, error) {
+	objects = slices.Clone(objects)
+	if prefix != "" {
+		client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+		if err != nil {
+			return nil, err
+		}
+		listed, err := listS3BatchObjects(ctx, client, bucket, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range listed {
+			objects = append(objects, S3ObjectIdentifier{Key: o.Key})
+		}
+	}
+	slices.SortFunc(objects, func(a, b S3ObjectIdentifier) int {
+		return cmp.Or(strings.Compare(a.Key, b.Key), strings.Compare(a.VersionID, b.VersionID))
+	})
+	return slices.Compact(objects), nil
+}
+
+// S3DeleteConfirmToken は bucket の objects の削除を確認するトークンを返す。対象の集合 (順序は問わない) から
+// 決まるため、削除の前に対象を一覧して得たトークンを渡させることで、確認した対象と異なるものを消さないようにする。
+func S3DeleteConfirmToken(bucket string, objects []S3ObjectIdentifier) string {
+	lines := make([]string, len(objects))
+	for i, o := range objects {
+		lines[i] = o.Key + "\x00" + o.VersionID
+	}
+	slices.Sort(lines)
+	h := sha256.New()
+	h.Write([]byte(bucket + "\n"))
+	for _, l := range lines {
+		h.Write([]byte(l + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// DeleteS3Objects は bucket の objects を DeleteObjects で削除する。一部のオブジェクトの削除に失敗した場合は
+// 失敗した件数と最初の失敗を含むエラーを返す。
+func DeleteS3Objects(ctx context.Context, profile, region, bucket string, objects []S3ObjectIdentifier) error {
+	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+	if err != nil {
+		return err
+	}
+	return deleteS3Objects(ctx, client, bucket, objects)
+}
+
+func deleteS3Objects(ctx context.Context, client s3ObjectOpsAPI, bucket string, objects []S3ObjectIdentifier) error {
+	var failed []s3types.Error
+	for chunk := range slices.Chunk(objects, maxS3BatchObjects) {
+		ids := make([]s3types.ObjectIdentifier, len(chunk))
+		for i, o := range chunk {
+			ids[i] = s3types.ObjectIdentifier{Key: aws.String(o.Key)}
+			if o.VersionID != "" {
+				ids[i].VersionId = aws.String(o.VersionID)
+			}
+		}
+		out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("delete s3 objects in %s: %w", bucket, err)
+		}
+		failed = append(failed, out.Errors...)
+	}
+	if len(failed) > 0 {
+		first := failed[0]
+		return fmt.Errorf("delete s3 objects in %s: %d of %d failed (%s: %s %s)",
+			bucket, len(failed), len(objects), ptrStr(first.Key), ptrStr(first.Code), ptrStr(first.Message))
+	}
+	return nil
+}
+
+// CopyS3Objects は srcBucket の src を dstBucket の dst にコピーし、move なら全件のコピー後にコピー元を削除する。
+// src が "/" で終わる場合は prefix とみなし、配下のオブジェクトを相対パスを保って dst (prefix) の下にコピーする。
+// src がキーで dst が空または "/" で終わる場合は dst の下に同じファイル名でコピーする。
+// maxS3CopyObjectSize を超えるオブジェクトはマルチパートアップロードでコピーする。
+// コピーに失敗した場合はそれまでにコピーした組とエラーを返し、コピー元は削除しない。
+func CopyS3Objects(ctx context.Context, profile, region, srcBucket, src, dstBucket, dst string, move bool) ([]S3CopyResult, error) {
+	srcClient, err := newS3ClientForBucket(ctx, profile, region, srcBucket)
+	if err != nil {
+		return nil, err
+	}
+	// CopyObject はコピー先のバケットのリージョンに送る。
+	dstClient := srcClient
+	if dstBucket != srcBucket {
+		dstClient, err = newS3ClientForBucket(ctx, profile, region, dstBucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return copyS3Objects(ctx, srcClient, dstClient, srcBucket, src, dstBucket, dst, move)
+}
+
+func copyS3Objects(ctx context.Context, srcClient, dstClient s3ObjectOpsAPI, srcBucket, src, dstBucket, dst string, move bool) ([]S3CopyResult, error) {
+	// コピーの方法 (CopyObject かマルチパートか) を決めるため、コピーを始める前にコピー元の大きさを調べる。
+	sizes := map[string]int64{}
+	keys := []string{src}
+	if strings.HasSuffix(src, "/") {
+		listed, err := listS3BatchObjects(ctx, srcClient, srcBucket, src)
+		if err != nil {
+			return nil, err
+		}
+		keys = make([]string, len(listed))
+		for i, o := range listed {
+			keys[i], sizes[o.Key] = o.Key, o.Size
+		}
+	}
+	pairs, err := planS3Copy(keys, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	if srcBucket == dstBucket && slices.ContainsFunc(pairs, func(p [2]string) bool { return p[0] == p[1] }) {
+		return nil, ErrS3SameObject
+	}
+	if !strings.HasSuffix(src, "/") {
+		head, err := srcClient.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(srcBucket), Key: aws.String(src)})
+		if err != nil {
+			return nil, fmt.Errorf("head s3 object %s/%s: %w", srcBucket, src, err)
+		}
+		sizes[src] = aws.ToInt64(head.ContentLength)
+	}
+
+	results := make([]S3CopyResult, 0, len(pairs))
+	for _, p := range pairs {
+		if _, err := copyS3Object(ctx, srcClient, dstClient, srcBucket, p[0], "", sizes[p[0]], dstBucket, p[1]); err != nil {
+			return results, fmt.Errorf("copy s3 object %s/%s to %s/%s: %w", srcBucket, p[0], dstBucket, p[1], err)
+		}
+		results = append(results, S3CopyResult{Source: srcBucket + "/" + p[0], Destination: dstBucket + "/" + p[1]})
+	}
+	if move {
+		sources := make([]S3ObjectIdentifier, len(pairs))
+		for i, p := range pairs {
+			sources[i] = S3ObjectIdentifier{Key: p[0]}
+		}
+		if err := deleteS3Objects(ctx, srcClient, srcBucket, sources); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// copyS3Object は srcBucket の srcKey (versionID が空なら最新のバージョン) を dstBucket の dstKey にコピーし、
+// 作成されたバージョンの ID を返す。size が maxS3CopyObjectSize 以下なら CopyObject 1 回で、超える場合は
+// UploadPartCopy のマルチパートアップロードでコピーする。HeadObject はコピー元のバケットのリージョンに、
+// コピーの操作はコピー先のバケットのリージョンに送る。途中で失敗した場合はマルチパートアップロードを中止する。
+func copyS3Object(ctx context.Context, srcClient, dstClient s3ObjectOpsAPI, srcBucket, srcKey, versionID string, size int64, dstBucket, dstKey string) (string, error) {
+	source := aws.String(s3CopySource(srcBucket, srcKey, versionID))
+	if size <= maxS3CopyObjectSize {
+		out, err := dstClient.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(dstBucket),
+			Key:        aws.String(dstKey),
+			CopySource: source,
+		})
+		if err != nil {
+			return "", err
+		}
+		return ptrStr(out.VersionId), nil
+	}
+
+	// CopyObject はメタデータを引き継ぐが、マルチパートアップロードは引き継がないためコピー元から写す。
+	headInput := &s3.HeadObjectInput{Bucket: aws.String(srcBucket), Key: aws.String(srcKey)}
+	if versionID != "" {
+		headInput.VersionId = aws.String(versionID)
+	}
+	head, err := srcClient.HeadObject(ctx, headInput)
+	if err != nil {
+		return "", fmt.Errorf("head source object: %w", err)
+	}
+	out, err := dstClient.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(dstBucket),
+		Key:                aws.String(dstKey),
+		ContentType:        head.ContentType,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		CacheControl:       head.CacheControl,
+		Metadata:           head.Metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+	uploadID := out.UploadId
+
+	partSize := max(s3CopyPartSize, (size+maxS3UploadParts-1)/maxS3UploadParts)
+	var parts []s3types.CompletedPart
+	err = func() error {
+		for start, partNumber := int64(0), int32(1); start < size; start, partNumber = start+partSize, partNumber+1 {
+			part, err := dstClient.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+				Bucket:          aws.String(dstBucket),
+				Key:             aws.String(dstKey),
+				UploadId:        uploadID,
+				PartNumber:      aws.Int32(partNumber),
+				CopySource:      source,
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, min(start+partSize, size)-1)),
+			})
+			if err != nil {
+				return fmt.Errorf("copy part %d: %w", partNumber, err)
+			}
+			completed := s3types.CompletedPart{PartNumber: aws.Int32(partNumber)}
+			if part.CopyPartResult != nil {
+				completed.ETag = part.CopyPartResult.ETag
+			}
+			parts = append(parts, completed)
+		}
+		return nil
+	}()
+	if err == nil {
+		done, cerr := dstClient.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(dstBucket),
+			Key:             aws.String(dstKey),
+			UploadId:        uploadID,
+			MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+		})
+		if cerr == nil {
+			return ptrStr(done.VersionId), nil
+		}
+		err = fmt.Errorf("complete multipart upload: %w", cerr)
+	}
+
+	// 呼び出し元の切断で ctx がキャンセルされていても中止は送る (未完了のパートは保存料金がかかり続けるため)。
+	if _, aerr := dstClient.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(dstBucket),
+		Key:      aws.String(dstKey),
+		UploadId: uploadID,
+	}); aerr != nil {
+		err = errors.Join(err, fmt.Errorf("abort multipart upload: %w", aerr))
+	}
+	return "", err
+}
+
+// planS3Copy は src の配下のキー keys ごとのコピー先のキーを決め、[コピー元, コピー先] の組で返す。
+func planS3Copy(keys []string, src, dst string) ([][2]string, error) {
+	if src == "" {
+		return nil, errors.New("source key or prefix is required")
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: %w", src, ErrS3NoObjects)
+	}
+	dstIsPrefix := dst == "" || strings.HasSuffix(dst, "/")
+	pairs := make([][2]string, len(keys))
+	for i, k := range keys {
+		switch {
+		case strings.HasSuffix(src, "/"):
+			prefix := dst
+			if !dstIsPrefix {
+				prefix += "/"
+			}
+			pairs[i] = [2]string{k, prefix + strings.TrimPrefix(k, src)}
+		case dstIsPrefix:
+			pairs[i] = [2]string{k, dst + path.Base(k)}
+		default:
+			pairs[i] = [2]string{k, dst}
+		}
+	}
+	return pairs, nil
+}
+
+// listS3BatchObjects は prefix 配下のオブジェクトを返す。maxS3BatchObjects を超える場合は ErrS3TooManyObjects を返す。
+func listS3BatchObjects(ctx context.Context, client s3ListObjectsAPI, bucket, prefix string) ([]S3ObjectResource, error) {
+	page, err := listS3ObjectPage(ctx, client, bucket, S3ListOptions{Prefix: prefix, MaxKeys: maxS3BatchObjects})
+	if err != nil {
+		return nil, err
+	}
+	if page.Truncated {
+		return nil, fmt.Errorf("s3://%s/%s: %w", bucket, prefix, ErrS3TooManyObjects)
+	}
+	return page.Objects, nil
+}
+
+// s3CopySource は CopyObject の CopySource (URL エンコードした bucket/key と versionId) を返す。
+// キーの "/" はエンコードしない。
+func s3CopySource(bucket, key, versionID string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	src := bucket + "/" + strings.Join(segments, "/")
+	if versionID != "" {
+		src += "?versionId=" + url.QueryEscape(versionID)
+	}
+	return src
+}
+
+// formatS3Time は S3 の日時を UTC の RFC3339 にする (nil は空文字列)。
+func formatS3Time(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,352 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// fakeS3Ops は s3ObjectOpsAPI の手書きフェイク。ListObjectsV2 は fakeS3Bucket に任せ、HeadObject は
+// fakeS3Bucket のオブジェクトの大きさを返す。CopyObject / マルチパートアップロード / DeleteObjects は
+// 呼び出しを記録する。copyErr はコピー先のキーごとに CopyObject の、partErr はパート番号ごとに
+// UploadPartCopy のエラーを返させる。
+type fakeS3Ops struct {
+	fakeS3Bucket
+	listObjectVersions func(*s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error)
+	copyErr            map[string]error
+	partErr            map[int32]error
+	heads              []string
+	copies             []string
+	parts              []string
+	completed          []string
+	aborted            []string
+	deletes            [][]string
+	deleteErrors       []s3types.Error
+}
+
+func (f *fakeS3Ops) HeadObject(_ context.Context, p *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.heads = append(f.heads, aws.ToString(p.Key)+"@"+aws.ToString(p.VersionId))
+	for _, o := range f.objects {
+		if aws.ToString(o.Key) == aws.ToString(p.Key) {
+			return &s3.HeadObjectOutput{ContentLength: o.Size, ContentType: aws.String("text/plain")}, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeS3Ops) ListObjectVersions(_ context.Context, p *s3.ListObjectVersionsInput, _ ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return f.listObjectVersions(p)
+}
+
+func (f *fakeS3Ops) CopyObject(_ context.Context, p *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := f.copyErr[aws.ToString(p.Key)]; err != nil {
+		return nil, err
+	}
+	f.copies = append(f.copies, aws.ToString(p.CopySource)+" -> "+aws.ToString(p.Bucket)+"/"+aws.ToString(p.Key))
+	return &s3.CopyObjectOutput{VersionId: aws.String("copied")}, nil
+}
+
+func (f *fakeS3Ops) CreateMultipartUpload(_ context.Context, p *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if aws.ToString(p.ContentType) != "text/plain" {
+		return nil, fmt.Errorf("content type = %q, want the source's text/plain", aws.ToString(p.ContentType))
+	}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-" + aws.ToString(p.Key))}, nil
+}
+
+func (f *fakeS3Ops) UploadPartCopy(_ context.Context, p *s3.UploadPartCopyInput, _ ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	if err := f.partErr[aws.ToInt32(p.PartNumber)]; err != nil {
+		return nil, err
+	}
+	f.parts = append(f.parts, fmt.Sprintf("%s %d %s", aws.ToString(p.UploadId), aws.ToInt32(p.PartNumber), aws.ToString(p.CopySourceRange)))
+	return &s3.UploadPartCopyOutput{CopyPartResult: &s3types.CopyPartResult{ETag: aws.String(fmt.Sprintf("etag-%d", aws.ToInt32(p.PartNumber)))}}, nil
+}
+
+func (f *fakeS3Ops) CompleteMultipartUpload(_ context.Context, p *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.completed = append(f.completed, fmt.Sprintf("%s %d parts", aws.ToString(p.UploadId), len(p.MultipartUpload.Parts)))
+	return &s3.CompleteMultipartUploadOutput{VersionId: aws.String("assembled")}, nil
+}
+
+func (f *fakeS3Ops) AbortMultipartUpload(_ context.Context, p *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.aborted = append(f.aborted, aws.ToString(p.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3Ops) DeleteObjects(_ context.Context, p *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	var keys []string
+	for _, o := range p.Delete.Objects {
+		keys = append(keys, aws.ToString(o.Key)+"@"+aws.ToString(o.VersionId))
+	}
+	f.deletes = append(f.deletes, keys)
+	return &s3.DeleteObjectsOutput{Errors: f.deleteErrors}, nil
+}
+
+func TestListS3ObjectVersions(t *testing.T) {
+	at := func(h int) *time.Time {
+		t := time.Date(2026, 3, 1, h, 0, 0, 0, time.UTC)
+		return &t
+	}
+	var markers []*string
+	f := &fakeS3Ops{listObjectVersions: func(p *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+		markers = append(markers, p.KeyMarker)
+		if p.KeyMarker == nil {
+			return &s3.ListObjectVersionsOutput{
+				Versions: []s3types.ObjectVersion{
+					{Key: aws.String("a.txt"), VersionId: aws.String("a2"), IsLatest: aws.Bool(true), Size: aws.Int64(2), LastModified: at(2)},
+					{Key: aws.String("a.txt"), VersionId: aws.String("a1"), Size: aws.Int64(1), LastModified: at(1)},
+					{Key: aws.String("b.txt"), VersionId: aws.String("b1"), Size: aws.Int64(5), LastModified: at(1)},
+				},
+				DeleteMarkers: []s3types.DeleteMarkerEntry{
+					{Key: aws.String("b.txt"), VersionId: aws.String("b2"), IsLatest: aws.Bool(true), LastModified: at(3)},
+				},
+				IsTruncated:   aws.Bool(true),
+				NextKeyMarker: aws.String("b.txt"),
+			}, nil
+		}
+		return &s3.ListObjectVersionsOutput{
+			Versions: []s3types.ObjectVersion{{Key: aws.String("c.txt"), VersionId: aws.String("c1"), IsLatest: aws.Bool(true), LastModified: at(1)}},
+		}, nil
+	}}
+
+	got, truncated, err := listS3ObjectVersions(context.Background(), f, "bucket", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, v := range got {
+		ids = append(ids, v.VersionID)
+	}
+	if want := []string{"a2", "a1", "b2", "b1", "c1"}; !slices.Equal(ids, want) || truncated {
+		t.Errorf("versions = %v, truncated = %v, want %v without truncation", ids, truncated, want)
+	}
+	if !got[2].IsDeleteMarker || !got[2].IsLatest || got[2].ToRow()[2] != "delete-marker" {
+		t.Errorf("versions[2] = %+v, want latest delete marker", got[2])
+	}
+	if len(markers) != 2 || aws.ToString(markers[1]) != "b.txt" {
+		t.Errorf("key markers = %v, want second request to continue from b.txt", markers)
+	}
+
+	markers = nil
+	got, truncated, err = listS3ObjectVersions(context.Background(), f, "bucket", "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || !truncated || len(markers) != 1 {
+		t.Errorf("got %d versions, truncated = %v after %d requests, want 4, true, 1", len(got), truncated, len(markers))
+	}
+}
+
+func TestS3DeleteConfirmToken(t *testing.T) {
+	a := S3ObjectIdentifier{Key: "logs/a"}
+	b := S3ObjectIdentifier{Key: "logs/b"}
+	token := S3DeleteConfirmToken("bucket", []S3ObjectIdentifier{a, b})
+	if len(token) != 16 {
+		t.Errorf("token = %q, want 16 hex characters", token)
+	}
+	if got := S3DeleteConfirmToken("bucket", []S3ObjectIdentifier{b, a}); got != token {
+		t.Errorf("token depends on order: %q != %q", got, token)
+	}
+	for _, other := range []string{
+		S3DeleteConfirmToken("other", []S3ObjectIdentifier{a, b}),
+		S3DeleteConfirmToken("bucket", []S3ObjectIdentifier{a}),
+		S3DeleteConfirmToken("bucket", []S3ObjectIdentifier{a, {Key: "logs/b", VersionID: "v1"}}),
+	} {
+		if other == token {
+			t.Errorf("token for a different target set = %q, want different from %q", other, token)
+		}
+	}
+}
+
+func TestDeleteS3Objects(t *testing.T) {
+	objects := make([]S3ObjectIdentifier, maxS3BatchObjects+2)
+	for i := range objects {
+		objects[i] = S3ObjectIdentifier{Key: fmt.Sprintf("k%04d", i)}
+	}
+	objects[0].VersionID = "v1"
+
+	f := &fakeS3Ops{}
+	if err := deleteS3Objects(context.Background(), f, "bucket", objects); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.deletes) != 2 || len(f.deletes[0]) != maxS3BatchObjects || len(f.deletes[1]) != 2 {
+		t.Fatalf("DeleteObjects batches = %d, want %d and 2 objects", len(f.deletes), maxS3BatchObjects)
+	}
+	if f.deletes[0][0] != "k0000@v1" || f.deletes[0][1] != "k0001@" {
+		t.Errorf("first objects = %v, want version ID only for k0000", f.deletes[0][:2])
+	}
+
+	f = &fakeS3Ops{deleteErrors: []s3types.Error{{Key: aws.String("k0001"), Code: aws.String("AccessDenied")}}}
+	err := deleteS3Objects(context.Background(), f, "bucket", objects[:2])
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 failed") || !strings.Contains(err.Error(), "k0001: AccessDenied") {
+		t.Errorf("err = %v, want partial failure with the first failed key", err)
+	}
+}
+
+func TestPlanS3Copy(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		src     string
+		dst     string
+		want    [][2]string
+		wantErr bool
+	}{
+		{name: "key to key", keys: []string{"a/x.txt"}, src: "a/x.txt", dst: "b/y.txt", want: [][2]string{{"a/x.txt", "b/y.txt"}}},
+		{name: "key into prefix", keys: []string{"a/x.txt"}, src: "a/x.txt", dst: "b/", want: [][2]string{{"a/x.txt", "b/x.txt"}}},
+		{name: "key into bucket root", keys: []string{"a/x.txt"}, src: "a/x.txt", dst: "", want: [][2]string{{"a/x.txt", "x.txt"}}},
+		{
+			name: "prefix to prefix",
+			keys: []string{"a/x.txt", "a/sub/y.txt"},
+			src:  "a/",
+			dst:  "archive/a",
+			want: [][2]string{{"a/x.txt", "archive/a/x.txt"}, {"a/sub/y.txt", "archive/a/sub/y.txt"}},
+		},
+		{name: "empty prefix", keys: nil, src: "a/", dst: "b/", wantErr: true},
+		{name: "no source", keys: []string{""}, src: "", dst: "b/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planS3Copy(tt.keys, tt.src, tt.dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyS3Objects(t *testing.T) {
+	newFake := func() *fakeS3Ops {
+		return &fakeS3Ops{fakeS3Bucket: fakeS3Bucket{objects: []s3types.Object{
+			{Key: aws.String("in/a b.txt"), Size: aws.Int64(10)},
+			{Key: aws.String("in/sub/c.txt"), Size: aws.Int64(20)},
+			{Key: aws.String("other.txt"), Size: aws.Int64(30)},
+		}}}
+	}
+	const large = maxS3CopyObjectSize + 1
+
+	t.Run("move prefix", func(t *testing.T) {
+		src, dst := newFake(), &fakeS3Ops{}
+		got, err := copyS3Objects(context.Background(), src, dst, "src", "in/", "dst", "out/", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []S3CopyResult{{Source: "src/in/a b.txt", Destination: "dst/out/a b.txt"}, {Source: "src/in/sub/c.txt", Destination: "dst/out/sub/c.txt"}}
+		if !slices.Equal(got, want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+		if wantCopies := []string{"src/in/a%20b.txt -> dst/out/a b.txt", "src/in/sub/c.txt -> dst/out/sub/c.txt"}; !slices.Equal(dst.copies, wantCopies) {
+			t.Errorf("CopyObject calls = %v, want %v", dst.copies, wantCopies)
+		}
+		if len(src.deletes) != 1 || !slices.Equal(src.deletes[0], []string{"in/a b.txt@", "in/sub/c.txt@"}) {
+			t.Errorf("DeleteObjects calls = %v, want sources deleted after copy", src.deletes)
+		}
+	})
+
+	t.Run("copy failure keeps sources", func(t *testing.T) {
+		f := newFake()
+		f.copyErr = map[string]error{"out/sub/c.txt": errors.New("access denied")}
+		got, err := copyS3Objects(context.Background(), f, f, "b", "in/", "b", "out/", true)
+		if err == nil || len(got) != 1 || len(f.deletes) != 0 {
+			t.Errorf("results = %v, err = %v, deletes = %v, want 1 result, error and no deletes", got, err, f.deletes)
+		}
+	})
+
+	t.Run("same object", func(t *testing.T) {
+		f := newFake()
+		if _, err := copyS3Objects(context.Background(), f, f, "b", "other.txt", "b", "", false); !errors.Is(err, ErrS3SameObject) {
+			t.Errorf("err = %v, want ErrS3SameObject", err)
+		}
+	})
+
+	t.Run("object over 5GiB uses multipart copy", func(t *testing.T) {
+		src, dst := newFake(), &fakeS3Ops{}
+		src.objects[2].Size = aws.Int64(large)
+		got, err := copyS3Objects(context.Background(), src, dst, "src", "other.txt", "dst", "big.txt", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []S3CopyResult{{Source: "src/other.txt", Destination: "dst/big.txt"}}; !slices.Equal(got, want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+		if len(dst.copies) != 0 {
+			t.Errorf("CopyObject calls = %v, want none for an object over 5GiB", dst.copies)
+		}
+		// 5GiB + 1 バイトは 512MiB のパート 10 個と 1 バイトのパート 1 個。
+		if len(dst.parts) != 11 || dst.parts[0] != "upload-big.txt 1 bytes=0-536870911" || dst.parts[10] != "upload-big.txt 11 bytes=5368709120-5368709120" {
+			t.Errorf("UploadPartCopy calls = %v, want 11 parts covering the object", dst.parts)
+		}
+		if !slices.Equal(dst.completed, []string{"upload-big.txt 11 parts"}) || len(dst.aborted) != 0 {
+			t.Errorf("completed = %v, aborted = %v, want the upload completed", dst.completed, dst.aborted)
+		}
+	})
+
+	t.Run("move failing midway keeps sources", func(t *testing.T) {
+		src, dst := newFake(), &fakeS3Ops{}
+		src.objects = append(src.objects[:1], append([]s3types.Object{{Key: aws.String("in/big.bin"), Size: aws.Int64(large)}}, src.objects[1:]...)...)
+		dst.partErr = map[int32]error{3: errors.New("slow down")}
+		got, err := copyS3Objects(context.Background(), src, dst, "src", "in/", "dst", "out/", true)
+		if err == nil || !strings.Contains(err.Error(), "in/big.bin") {
+			t.Fatalf("err = %v, want the failed copy of in/big.bin", err)
+		}
+		if want := []S3CopyResult{{Source: "src/in/a b.txt", Destination: "dst/out/a b.txt"}}; !slices.Equal(got, want) {
+			t.Errorf("results = %v, want only the object copied before the failure", got)
+		}
+		if len(dst.parts) != 2 || !slices.Equal(dst.aborted, []string{"upload-out/big.bin"}) || len(dst.completed) != 0 {
+			t.Errorf("parts = %v, aborted = %v, completed = %v, want the upload aborted after 2 parts", dst.parts, dst.aborted, dst.completed)
+		}
+		if len(dst.copies) != 1 || len(src.deletes) != 0 {
+			t.Errorf("CopyObject calls = %v, deletes = %v, want the later objects neither copied nor sources deleted", dst.copies, src.deletes)
+		}
+	})
+}
+
+func TestRestoreS3ObjectVersion(t *testing.T) {
+	f := &fakeS3Ops{fakeS3Bucket: fakeS3Bucket{objects: []s3types.Object{
+		{Key: aws.String("small.txt"), Size: aws.Int64(5)},
+		{Key: aws.String("big.bin"), Size: aws.Int64(maxS3CopyObjectSize + 1)},
+	}}}
+
+	got, err := restoreS3ObjectVersion(context.Background(), f, "b", "small.txt", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "copied" || !slices.Equal(f.copies, []string{"b/small.txt?versionId=v1 -> b/small.txt"}) {
+		t.Errorf("version = %q, CopyObject calls = %v, want a CopyObject of v1", got, f.copies)
+	}
+
+	got, err = restoreS3ObjectVersion(context.Background(), f, "b", "big.bin", "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "assembled" || len(f.parts) != 11 || !strings.HasPrefix(f.parts[0], "upload-big.bin 1 ") {
+		t.Errorf("version = %q, UploadPartCopy calls = %v, want a multipart copy", got, f.parts)
+	}
+	if !slices.Contains(f.heads, "big.bin@v2") {
+		t.Errorf("HeadObject calls = %v, want the restored version", f.heads)
+	}
+}
+
+func TestS3CopySource(t *testing.T) {
+	tests := []struct {
+		key, versionID, want string
+	}{
+		{key: "dir/file.txt", want: "bucket/dir/file.txt"},
+		{key: "dir/a b+c?.txt", want: "bucket/dir/a%20b+c%3F.txt"},
+		{key: "x", versionID: "v/1+2", want: "bucket/x?versionId=v%2F1%2B2"},
+	}
+	for _, tt := range tests {
+		if got := s3CopySource("bucket", tt.key, tt.versionID); got != tt.want {
+			t.Errorf("s3CopySource(%q, %q) = %q, want %q", tt.key, tt.versionID, got, tt.want)
+		}
+	}
+}
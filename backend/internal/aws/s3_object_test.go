@@ -1,22 +1,19 @@
 package aws
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-func makeS3Objects(n int) []s3types.Object {
-	objs := make([]s3types.Object, n)
-	for i := range objs {
-		objs[i] = s3types.Object{Key: aws.String(fmt.Sprintf("obj-%d", i))}
-	}
-	return objs
-}
-
 func TestS3ObjectFromSDK(t *testing.T) {
 	fixed := time.Date(2026, 7, 8, 12, 34, 56, 0, time.UTC)
 	tests := []struct {
@@ -73,38 +70,191 @@ func TestS3ObjectFromSDK(t *testing.T) {
 	}
 }
 
-func TestAppendS3ObjectsUpToLimit(t *testing.T) {
+// fakeS3Bucket は s3ListObjectsAPI の手書きフェイク。keys (キー順) を ListObjectsV2 と同じ規則
+// (Prefix / Delimiter / MaxKeys / ContinuationToken) で返し、ContinuationToken は次のキーの位置とする。
+type fakeS3Bucket struct {
+	objects []s3types.Object
+	maxKeys []int32
+}
+
+func (f *fakeS3Bucket) ListObjectsV2(_ context.Context, p *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.maxKeys = append(f.maxKeys, aws.ToInt32(p.MaxKeys))
+	i := 0
+	if p.ContinuationToken != nil {
+		i, _ = strconv.Atoi(*p.ContinuationToken)
+	}
+	prefix, delimiter := aws.ToString(p.Prefix), aws.ToString(p.Delimiter)
+	out := &s3.ListObjectsV2Output{}
+	for n := int32(0); i < len(f.objects); {
+		key := aws.ToString(f.objects[i].Key)
+		if !strings.HasPrefix(key, prefix) {
+			i++
+			continue
+		}
+		if n == aws.ToInt32(p.MaxKeys) {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(strconv.Itoa(i))
+			break
+		}
+		n++
+		rest := strings.TrimPrefix(key, prefix)
+		if j := strings.Index(rest, delimiter); delimiter != "" && j >= 0 {
+			common := prefix + rest[:j+len(delimiter)]
+			out.CommonPrefixes = append(out.CommonPrefixes, s3types.CommonPrefix{Prefix: aws.String(common)})
+			for i < len(f.objects) && strings.HasPrefix(aws.ToString(f.objects[i].Key), common) {
+				i++
+			}
+			continue
+		}
+		out.Contents = append(out.Contents, f.objects[i])
+		i++
+	}
+	return out, nil
+}
+
+func s3Keys(page S3ObjectPage) []string {
+	keys := slices.Clone(page.CommonPrefixes)
+	for _, o := range page.Objects {
+		keys = append(keys, o.Key)
+	}
+	return keys
+}
+
+func TestListS3ObjectPage(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	bucket := &fakeS3Bucket{objects: []s3types.Object{
+		{Key: aws.String("logs/2026/01/a.gz"), Size: aws.Int64(10), LastModified: day(1)},
+		{Key: aws.String("logs/2026/01/b.gz"), Size: aws.Int64(500), LastModified: day(2)},
+		{Key: aws.String("logs/2026/02/a.gz"), Size: aws.Int64(20), LastModified: day(3)},
+		{Key: aws.String("logs/2026/02/b.gz"), Size: aws.Int64(900), LastModified: day(4)},
+		{Key: aws.String("logs/readme.txt"), Size: aws.Int64(1), LastModified: day(5)},
+	}}
+
 	tests := []struct {
 		name          string
-		existing      int
-		objs          int
-		max           int
-		wantLen       int
+		opts          S3ListOptions
+		want          []string
+		wantNext      string
 		wantTruncated bool
+		wantMaxKeys   []int32
 	}{
-		{name: "under limit", existing: 0, objs: 10, max: 1000, wantLen: 10, wantTruncated: false},
-		{name: "exactly at limit", existing: 0, objs: 1000, max: 1000, wantLen: 1000, wantTruncated: false},
-		{name: "exceeds limit", existing: 0, objs: 1001, max: 1000, wantLen: 1000, wantTruncated: true},
-		{name: "already at limit before page", existing: 1000, objs: 5, max: 1000, wantLen: 1000, wantTruncated: true},
 		{
-			name:          "limit reached mid page",
-			existing:      998,
-			objs:          10,
-			max:           1000,
-			wantLen:       1000,
+			name:          "first page",
+			opts:          S3ListOptions{Prefix: "logs/", MaxKeys: 2},
+			want:          []string{"logs/2026/01/a.gz", "logs/2026/01/b.gz"},
+			wantNext:      "2",
+			wantTruncated: true,
+			wantMaxKeys:   []int32{2},
+		},
+		{
+			name:        "last page",
+			opts:        S3ListOptions{Prefix: "logs/", MaxKeys: 2, ContinuationToken: "4"},
+			want:        []string{"logs/readme.txt"},
+			wantMaxKeys: []int32{2},
+		},
+		{
+			name:        "folders",
+			opts:        S3ListOptions{Prefix: "logs/", Delimiter: "/"},
+			want:        []string{"logs/2026/", "logs/readme.txt"},
+			wantMaxKeys: []int32{1000},
+		},
+		{
+			name:        "sub folders",
+			opts:        S3ListOptions{Prefix: "logs/2026/", Delimiter: "/"},
+			want:        []string{"logs/2026/01/", "logs/2026/02/"},
+			wantMaxKeys: []int32{1000},
+		},
+		{
+			// 一致しないキーを飛ばしながら、残り件数を MaxKeys にして次のページを取る。
+			name:          "filter across pages",
+			opts:          S3ListOptions{MaxKeys: 2, Filter: S3ObjectFilter{KeyContains: ".gz", MinSize: 15}},
+			want:          []string{"logs/2026/01/b.gz", "logs/2026/02/a.gz"},
+			wantNext:      "3",
 			wantTruncated: true,
+			wantMaxKeys:   []int32{2, 1},
+		},
+		{
+			name:        "modified range",
+			opts:        S3ListOptions{Filter: S3ObjectFilter{ModifiedAfter: *day(2), ModifiedBefore: *day(4)}},
+			want:        []string{"logs/2026/01/b.gz", "logs/2026/02/a.gz"},
+			wantMaxKeys: []int32{1000},
+		},
+		{
+			name:        "filter prefixes by key",
+			opts:        S3ListOptions{Prefix: "logs/2026/", Delimiter: "/", Filter: S3ObjectFilter{KeyContains: "/02/"}},
+			want:        []string{"logs/2026/02/"},
+			wantMaxKeys: []int32{1000},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resources := make([]S3ObjectResource, tt.existing)
-			got, truncated := appendS3ObjectsUpToLimit(resources, makeS3Objects(tt.objs), tt.max)
-			if len(got) != tt.wantLen {
-				t.Errorf("len(got) = %d, want %d", len(got), tt.wantLen)
+			bucket.maxKeys = nil
+			page, err := listS3ObjectPage(context.Background(), bucket, "bucket", tt.opts)
+			if err != nil {
+				t.Fatal(err)
 			}
-			if truncated != tt.wantTruncated {
-				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			if got := s3Keys(page); !slices.Equal(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+			if page.NextContinuationToken != tt.wantNext || page.Truncated != tt.wantTruncated {
+				t.Errorf("next = %q, truncated = %v, want %q, %v", page.NextContinuationToken, page.Truncated, tt.wantNext, tt.wantTruncated)
+			}
+			if !slices.Equal(bucket.maxKeys, tt.wantMaxKeys) {
+				t.Errorf("MaxKeys per request = %v, want %v", bucket.maxKeys, tt.wantMaxKeys)
 			}
 		})
 	}
 }
+
+func TestListS3ObjectPageScanLimit(t *testing.T) {
+	bucket := &fakeS3Bucket{}
+	for i := range maxS3ListScanKeys + 500 {
+		bucket.objects = append(bucket.objects, s3types.Object{Key: aws.String(fmt.Sprintf("obj-%06d", i))})
+	}
+	page, err := listS3ObjectPage(context.Background(), bucket, "bucket", S3ListOptions{Filter: S3ObjectFilter{KeyContains: "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Objects) != 0 || !page.Truncated || page.NextContinuationToken != strconv.Itoa(maxS3ListScanKeys) {
+		t.Errorf("page = %d objects, truncated %v, next %q, want scan to stop at %d keys", len(page.Objects), page.Truncated, page.NextContinuationToken, maxS3ListScanKeys)
+	}
+}
+
+func TestSortS3Objects(t *testing.T) {
+	objects := func() []S3ObjectResource {
+		return []S3ObjectResource{
+			{Key: "b", Size: 10, LastModified: "2026-01-02T00:00:00Z"},
+			{Key: "a", Size: 30, LastModified: "2026-01-03T00:00:00Z"},
+			{Key: "c", Size: 10, LastModified: "2026-01-01T00:00:00Z"},
+		}
+	}
+	tests := []struct {
+		field string
+		desc  bool
+		want  []string
+	}{
+		{field: "key", want: []string{"a", "b", "c"}},
+		{field: "size", want: []string{"b", "c", "a"}},
+		{field: "size", desc: true, want: []string{"a", "b", "c"}},
+		{field: "last_modified", desc: true, want: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		got := objects()
+		if err := SortS3Objects(got, tt.field, tt.desc); err != nil {
+			t.Fatalf("SortS3Objects(%q) error = %v", tt.field, err)
+		}
+		var keys []string
+		for _, o := range got {
+			keys = append(keys, o.Key)
+		}
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("SortS3Objects(%q, desc=%v) = %v, want %v", tt.field, tt.desc, keys, tt.want)
+		}
+	}
+	if err := SortS3Objects(objects(), "etag", false); err == nil {
+		t.Error("SortS3Objects(etag) error = nil, want error")
+	}
+}
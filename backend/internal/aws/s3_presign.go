@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultS3PresignExpiry は有効期限を指定しなかったときの署名付き URL の有効期限 (SDK の既定と同じ)。
+const DefaultS3PresignExpiry = 15 * time.Minute
+
+// maxS3PresignExpiry は署名付き URL の有効期限の上限。SigV4 のクエリ署名は 7 日を超えられない。
+const maxS3PresignExpiry = 7 * 24 * time.Hour
+
+// ErrS3InvalidPresign は署名付き URL のメソッドまたは有効期限が不正なときに返す。
+var ErrS3InvalidPresign = errors.New("invalid presign request")
+
+// S3PresignedURL は署名付き URL と、その URL で使う HTTP メソッド、有効期限の時刻を表す。
+type S3PresignedURL struct {
+	URL       string `json:"url"`
+	Method    string `json:"method"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// PresignS3Object は bucket/key に対する署名付き URL を発行する。method は GET / PUT / HEAD / DELETE
+// (大文字小文字は問わない、空なら GET)、expires は 0 なら DefaultS3PresignExpiry。
+// 署名はローカルで行うため、URL の発行自体は権限がなくても成功し、アクセス時に拒否される。
+func PresignS3Object(ctx context.Context, profile, region, bucket, key, method string, expires time.Duration) (S3PresignedURL, error) {
+	method, expires, err := normalizeS3Presign(key, method, expires)
+	if err != nil {
+		return S3PresignedURL{}, err
+	}
+	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+	if err != nil {
+		return S3PresignedURL{}, err
+	}
+	return presignS3Object(ctx, s3.NewPresignClient(client), bucket, key, method, expires)
+}
+
+// normalizeS3Presign はメソッドを大文字に揃え、既定値を補ってキーと有効期限を検証する。
+func normalizeS3Presign(key, method string, expires time.Duration) (string, time.Duration, error) {
+	if key == "" {
+		return "", 0, fmt.Errorf("%w: key is required", ErrS3InvalidPresign)
+	}
+	method = strings.ToUpper(method)
+	switch method {
+	case "":
+		method = "GET"
+	case "GET", "PUT", "HEAD", "DELETE":
+	default:
+		return "", 0, fmt.Errorf("%w: unsupported method %q (use GET, PUT, HEAD or DELETE)", ErrS3InvalidPresign, method)
+	}
+	switch {
+	case expires == 0:
+		expires = DefaultS3PresignExpiry
+	case expires < time.Second || expires > maxS3PresignExpiry:
+		return "", 0, fmt.Errorf("%w: expiry must be between 1s and %s", ErrS3InvalidPresign, maxS3PresignExpiry)
+	}
+	return method, expires, nil
+}
+
+func presignS3Object(ctx context.Context, client *s3.PresignClient, bucket, key, method string, expires time.Duration) (S3PresignedURL, error) {
+	withExpires := s3.WithPresignExpires(expires)
+	var (
+		req *v4.PresignedHTTPRequest
+		err error
+	)
+	switch method {
+	case "GET":
+		req, err = client.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}, withExpires)
+	case "PUT":
+		req, err = client.PresignPutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}, withExpires)
+	case "HEAD":
+		req, err = client.PresignHeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}, withExpires)
+	case "DELETE":
+		req, err = client.PresignDeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}, withExpires)
+	}
+	if err != nil {
+		return S3PresignedURL{}, fmt.Errorf("presign %s s3 object %s/%s: %w", method, bucket, key, err)
+	}
+	expiresAt := time.Now().Add(expires)
+	return S3PresignedURL{URL: req.URL, Method: req.Method, ExpiresAt: formatS3Time(&expiresAt)}, nil
+}
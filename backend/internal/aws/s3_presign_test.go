@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestNormalizeS3Presign(t *testing.T) {
+	tests := []struct {
+		key, method string
+		expires     time.Duration
+		wantMethod  string
+		wantExpires time.Duration
+		wantErr     bool
+	}{
+		{key: "a.txt", method: "", expires: 0, wantMethod: "GET", wantExpires: DefaultS3PresignExpiry},
+		{key: "a.txt", method: "put", expires: time.Hour, wantMethod: "PUT", wantExpires: time.Hour},
+		{key: "a.txt", method: "DELETE", expires: maxS3PresignExpiry, wantMethod: "DELETE", wantExpires: maxS3PresignExpiry},
+		{key: "a.txt", method: "POST", wantErr: true},
+		{key: "a.txt", method: "GET", expires: maxS3PresignExpiry + time.Second, wantErr: true},
+		{key: "a.txt", method: "GET", expires: -time.Minute, wantErr: true},
+		{key: "", method: "GET", expires: time.Hour, wantErr: true},
+	}
+	for _, tt := range tests {
+		method, expires, err := normalizeS3Presign(tt.key, tt.method, tt.expires)
+		if tt.wantErr {
+			if !errors.Is(err, ErrS3InvalidPresign) {
+				t.Errorf("normalizeS3Presign(%q, %q, %s) err = %v, want ErrS3InvalidPresign", tt.key, tt.method, tt.expires, err)
+			}
+			continue
+		}
+		if err != nil || method != tt.wantMethod || expires != tt.wantExpires {
+			t.Errorf("normalizeS3Presign(%q, %q, %s) = %q, %s, %v, want %q, %s", tt.key, tt.method, tt.expires, method, expires, err, tt.wantMethod, tt.wantExpires)
+		}
+	}
+}
+
+// TestPresignS3Object は固定のクレデンシャルで実際の PresignClient を使う。署名はローカルで行われ AWS を呼ばない。
+func TestPresignS3Object(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region: "ap-northeast-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	presign := s3.NewPresignClient(client)
+
+	got, err := presignS3Object(context.Background(), presign, "bucket", "dir/a b.txt", "PUT", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(got.URL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", got.URL, err)
+	}
+	if got.Method != "PUT" || u.Host != "bucket.s3.ap-northeast-1.amazonaws.com" || u.Path != "/dir/a b.txt" {
+		t.Errorf("presigned = %+v, want PUT on the virtual-hosted bucket URL", got)
+	}
+	q := u.Query()
+	if q.Get("X-Amz-Expires") != "3600" || !strings.HasPrefix(q.Get("X-Amz-Credential"), "AKIDEXAMPLE/") || q.Get("X-Amz-Signature") == "" {
+		t.Errorf("query = %v, want a SigV4 signature valid for 3600 seconds", q)
+	}
+	if _, err := time.Parse(time.RFC3339, got.ExpiresAt); err != nil {
+		t.Errorf("ExpiresAt = %q, want RFC3339: %v", got.ExpiresAt, err)
+	}
+}
@@ -0,0 +1,74 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3Range は s3RangeAPI の手書きフェイク。受け取った Range と If-Match を順に保持する。
+type fakeS3Range struct {
+	data    []byte
+	ranges  []string
+	ifMatch []string
+}
+
+func (f *fakeS3Range) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(f.data))),
+		ContentType:   aws.String("application/octet-stream"),
+		ETag:          aws.String(`"etag-1"`),
+	}, nil
+}
+
+func (f *fakeS3Range) GetObject(_ context.Context, p *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.ranges = append(f.ranges, aws.ToString(p.Range))
+	f.ifMatch = append(f.ifMatch, aws.ToString(p.IfMatch))
+	var start, end int
+	if _, err := fmt.Sscanf(aws.ToString(p.Range), "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+	if end >= len(f.data) {
+		return nil, errors.New("range not satisfiable")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(f.data[start : end+1]))}, nil
+}
+
+func TestS3ObjectReaderAt(t *testing.T) {
+	fake := &fakeS3Range{data: []byte("0123456789")}
+	r, err := newS3ObjectReaderAt(context.Background(), fake, "bucket", "data.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size != 10 || r.ContentType != "application/octet-stream" {
+		t.Errorf("size = %d, content type = %q", r.Size, r.ContentType)
+	}
+
+	p := make([]byte, 4)
+	if n, err := r.ReadAt(p, 2); n != 4 || err != nil || string(p) != "2345" {
+		t.Errorf("ReadAt(4, 2) = %d, %v, %q", n, err, p)
+	}
+	// 末尾を越える読み込みは末尾までを読んで io.EOF を返す。
+	if n, err := r.ReadAt(p, 8); n != 2 || err != io.EOF || string(p[:n]) != "89" {
+		t.Errorf("ReadAt(4, 8) = %d, %v, %q", n, err, p[:n])
+	}
+	if n, err := r.ReadAt(p, 10); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt(4, 10) = %d, %v", n, err)
+	}
+
+	wantRanges := []string{"bytes=2-5", "bytes=8-9"}
+	if fmt.Sprint(fake.ranges) != fmt.Sprint(wantRanges) {
+		t.Errorf("ranges = %v, want %v", fake.ranges, wantRanges)
+	}
+	for _, m := range fake.ifMatch {
+		if m != `"etag-1"` {
+			t.Errorf("If-Match = %q, want the etag from HeadObject", m)
+		}
+	}
+}
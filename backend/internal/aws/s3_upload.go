@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3UploadPartSize は UploadS3Object が 1 パートとして送るサイズ。マルチパートアップロードは最後以外の
+// パートが 5MiB 以上、パート数が maxS3UploadParts までのため、16MiB で約 156GiB まで送れる。
+// メモリに載せるのは常に 1 パート分だけ。
+const s3UploadPartSize = 16 << 20 // 16MiB
+
+// maxS3UploadParts はマルチパートアップロードのパート数の上限。
+const maxS3UploadParts = 10000
+
+// s3UploadAPI は S3 SDK クライアントのうちアップロードが利用する操作の集合。
+// テストでは手書きフェイクを差し込む。
+type s3UploadAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// UploadS3Object は body を先頭から s3UploadPartSize ずつ読んでストリーミングで書き込み、書き込んだバイト数を返す。
+// 全長が事前にわからなくてよく、1 パートに満たないオブジェクトは PutObject 1 回、それ以外はマルチパート
+// アップロードで送る。progress が nil でなければ、パートを送り終えるたびにそれまでに送ったバイト数を渡す。
+// 途中で失敗した場合はマルチパートアップロードを中止し、送信済みのパートを残さない。
+func UploadS3Object(ctx context.Context, profile, region, bucket, key string, body io.Reader, contentType string, progress func(int64)) (int64, error) {
+	client, err := newS3ClientForBucket(ctx, profile, region, bucket)
+	if err != nil {
+		return 0, err
+	}
+	return uploadS3Object(ctx, client, bucket, key, body, contentType, s3UploadPartSize, progress)
+}
+
+func uploadS3Object(ctx context.Context, client s3UploadAPI, bucket, key string, body io.Reader, contentType string, partSize int, progress func(int64)) (int64, error) {
+	if progress == nil {
+		progress = func(int64) {}
+	}
+	buf := make([]byte, partSize)
+	n, last, err := readUploadPart(body, buf)
+	if err != nil {
+		return 0, fmt.Errorf("read upload body: %w", err)
+	}
+	if last {
+		input := &s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		}
+		if contentType != "" {
+			input.ContentType = aws.String(contentType)
+		}
+		if _, err := client.PutObject(ctx, input); err != nil {
+			return 0, fmt.Errorf("put s3 object %s/%s: %w", bucket, key, err)
+		}
+		progress(int64(n))
+		return int64(n), nil
+	}
+
+	// パートのチェックサムを CompleteMultipartUpload に渡せるよう、SDK の既定と同じ CRC32 を明示する。
+	create := &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		ChecksumAlgorithm: s3types.ChecksumAlgorithmCrc32,
+	}
+	if contentType != "" {
+		create.ContentType = aws.String(contentType)
+	}
+	out, err := client.CreateMultipartUpload(ctx, create)
+	if err != nil {
+		return 0, fmt.Errorf("create multipart upload %s/%s: %w", bucket, key, err)
+	}
+	uploadID := out.UploadId
+
+	var (
+		parts   []s3types.CompletedPart
+		written int64
+	)
+	err = func() error {
+		for partNumber := int32(1); ; partNumber++ {
+			if partNumber > maxS3UploadParts {
+				return fmt.Errorf("object exceeds %d parts of %d bytes", maxS3UploadParts, partSize)
+			}
+			part, err := client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:            aws.String(bucket),
+				Key:               aws.String(key),
+				UploadId:          uploadID,
+				PartNumber:        aws.Int32(partNumber),
+				Body:              bytes.NewReader(buf[:n]),
+				ContentLength:     aws.Int64(int64(n)),
+				ChecksumAlgorithm: s3types.ChecksumAlgorithmCrc32,
+			})
+			if err != nil {
+				return fmt.Errorf("upload part %d: %w", partNumber, err)
+			}
+			parts = append(parts, s3types.CompletedPart{
+				ETag:          part.ETag,
+				ChecksumCRC32: part.ChecksumCRC32,
+				PartNumber:    aws.Int32(partNumber),
+			})
+			written += int64(n)
+			progress(written)
+
+			if last {
+				return nil
+			}
+			n, last, err = readUploadPart(body, buf)
+			if err != nil {
+				return fmt.Errorf("read upload body: %w", err)
+			}
+			// body がパートの境目ちょうどで終わった場合は空のパートを送らない。
+			if n == 0 && last {
+				return nil
+			}
+		}
+	}()
+	if err == nil {
+		_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(key),
+			UploadId:        uploadID,
+			MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+		})
+		if err == nil {
+			return written, nil
+		}
+		err = fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	// 呼び出し元の切断で ctx がキャンセルされていても中止は送る (未完了のパートは保存料金がかかり続けるため)。
+	if _, aerr := client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	}); aerr != nil {
+		err = errors.Join(err, fmt.Errorf("abort multipart upload: %w", aerr))
+	}
+	return written, fmt.Errorf("upload s3 object %s/%s: %w", bucket, key, err)
+}
+
+// readUploadPart は buf が埋まるか body 自身が io.EOF を返すまで読み、読んだバイト数と body の終端に
+// 達したか (last) を返す。io.ReadFull は使わない: multipart の Part はクライアントの切断で
+// io.ErrUnexpectedEOF を返すため、io.ReadFull では「短い最後のパート」と途中で切れた body を区別できない。
+func readUploadPart(body io.Reader, buf []byte) (n int, last bool, err error) {
+	for n < len(buf) {
+		m, err := body.Read(buf[n:])
+		n += m
+		if err == io.EOF {
+			return n, true, nil
+		}
+		if err != nil {
+			return n, false, err
+		}
+	}
+	return n, false, nil
+}
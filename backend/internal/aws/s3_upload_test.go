@@ -0,0 +1,134 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3Upload は s3UploadAPI の手書きフェイク。受け取ったパートを順に保持する。
+type fakeS3Upload struct {
+	put       []byte
+	parts     [][]byte
+	completed []int32
+	aborted   bool
+	failPart  int32
+}
+
+func (f *fakeS3Upload) PutObject(_ context.Context, p *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	b, err := io.ReadAll(p.Body)
+	f.put = b
+	return &s3.PutObjectOutput{}, err
+}
+
+func (f *fakeS3Upload) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (f *fakeS3Upload) UploadPart(_ context.Context, p *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if aws.ToInt32(p.PartNumber) == f.failPart {
+		return nil, errors.New("slow down")
+	}
+	b, err := io.ReadAll(p.Body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) != aws.ToInt64(p.ContentLength) {
+		return nil, errors.New("content length mismatch")
+	}
+	f.parts = append(f.parts, b)
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (f *fakeS3Upload) CompleteMultipartUpload(_ context.Context, p *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	for _, part := range p.MultipartUpload.Parts {
+		f.completed = append(f.completed, aws.ToInt32(part.PartNumber))
+	}
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3Upload) AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.aborted = true
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestUploadS3Object(t *testing.T) {
+	tests := []struct {
+		name          string
+		size          int
+		wantPut       bool
+		wantParts     []int
+		wantProgress  []int64
+		wantCompleted []int32
+	}{
+		{name: "empty", size: 0, wantPut: true, wantProgress: []int64{0}},
+		{name: "smaller than a part", size: 9, wantPut: true, wantProgress: []int64{9}},
+		{name: "exactly one part", size: 10, wantParts: []int{10}, wantProgress: []int64{10}, wantCompleted: []int32{1}},
+		{name: "last part shorter", size: 25, wantParts: []int{10, 10, 5}, wantProgress: []int64{10, 20, 25}, wantCompleted: []int32{1, 2, 3}},
+		{name: "multiple of part size", size: 20, wantParts: []int{10, 10}, wantProgress: []int64{10, 20}, wantCompleted: []int32{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("x"), tt.size)
+			f := &fakeS3Upload{}
+			var progress []int64
+			n, err := uploadS3Object(context.Background(), f, "bucket", "dump.sql", bytes.NewReader(body), "", 10, func(b int64) {
+				progress = append(progress, b)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(tt.size) {
+				t.Errorf("written = %d, want %d", n, tt.size)
+			}
+			if tt.wantPut != (f.put != nil) || (tt.wantPut && len(f.put) != tt.size) {
+				t.Errorf("PutObject body = %d bytes, want PutObject %v", len(f.put), tt.wantPut)
+			}
+			var parts []int
+			for _, p := range f.parts {
+				parts = append(parts, len(p))
+			}
+			if !slices.Equal(parts, tt.wantParts) || !slices.Equal(f.completed, tt.wantCompleted) {
+				t.Errorf("parts = %v, completed = %v, want %v, %v", parts, f.completed, tt.wantParts, tt.wantCompleted)
+			}
+			if !slices.Equal(progress, tt.wantProgress) {
+				t.Errorf("progress = %v, want %v", progress, tt.wantProgress)
+			}
+		})
+	}
+
+	// クライアントの切断で multipart の Part が返す io.ErrUnexpectedEOF は body の終端ではない。
+	// 途中までの内容をオブジェクトとして保存せず、エラーにする。
+	t.Run("body cut mid-stream", func(t *testing.T) {
+		for _, size := range []int{5, 25} {
+			f := &fakeS3Upload{}
+			body := io.MultiReader(strings.NewReader(strings.Repeat("x", size)), iotest.ErrReader(io.ErrUnexpectedEOF))
+			_, err := uploadS3Object(context.Background(), f, "bucket", "dump.sql", body, "", 10, nil)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("size %d: err = %v, want io.ErrUnexpectedEOF", size, err)
+			}
+			if f.put != nil || f.completed != nil {
+				t.Errorf("size %d: put = %d bytes, completed = %v, want neither PutObject nor CompleteMultipartUpload", size, len(f.put), f.completed)
+			}
+			if size > 10 && !f.aborted {
+				t.Errorf("size %d: multipart upload not aborted", size)
+			}
+		}
+	})
+
+	t.Run("failed part aborts", func(t *testing.T) {
+		f := &fakeS3Upload{failPart: 2}
+		n, err := uploadS3Object(context.Background(), f, "bucket", "dump.sql", strings.NewReader(strings.Repeat("x", 25)), "", 10, nil)
+		if err == nil || !strings.Contains(err.Error(), "upload part 2") || !f.aborted || f.completed != nil || n != 10 {
+			t.Errorf("written = %d, err = %v, aborted = %v, completed = %v, want part 2 failure with abort", n, err, f.aborted, f.completed)
+		}
+	})
+}
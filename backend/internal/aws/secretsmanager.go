@@ -97,9 +97,10 @@ func PutSecretValue(ctx context.Context, profile, region, name, value string) er
 // SecretInfo は CLI 一覧表示用のシークレットメタデータを保持する。
 // SecretResource と異なり値を含まない (ListSecrets のメタデータのみ)。
 type SecretInfo struct {
-	Name        string
-	Description string
-	LastChanged string
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	LastChanged string            `json:"last_changed"`
+	Tags        map[string]string `json:"tags"`
 }
 
 // ToRow converts SecretInfo to a string slice for table output.
@@ -107,6 +108,9 @@ func (s SecretInfo) ToRow() []string {
 	return []string{s.Name, s.Description, s.LastChanged}
 }
 
+// ResourceTags returns the secret tags.
+func (s SecretInfo) ResourceTags() map[string]string { return s.Tags }
+
 // ListSecretInfos は Secrets Manager のシークレットメタデータ一覧を返す。
 // 値は取得しない (CLI 一覧で平文値を端末やシェル履歴に残さないため。値の取得は Web UI と
 // API の ListSecretResources 経由に限定する)。
@@ -139,6 +143,7 @@ func secretInfoFromEntry(s smtypes.SecretListEntry) SecretInfo {
 		Name:        ptrStr(s.Name),
 		Description: ptrStr(s.Description),
 		LastChanged: lastChanged,
+		Tags:        tagsToMapFunc(s.Tags, func(t smtypes.Tag) (*string, *string) { return t.Key, t.Value }),
 	}
 }
 
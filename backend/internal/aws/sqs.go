@@ -21,7 +21,8 @@ type SQSResource struct {
 	InFlight          int               `json:"in_flight"`
 	RetentionDays     int               `json:"retention_days"`
 	Tags              map[string]string `json:"tags"`
-	CostMonthly       float64           `json:"cost_monthly"`
+	// CostMonthly は常に nil (JSON では null)。SQS はリクエスト数だけで課金され、固定の月額がないため見積もらない。
+	CostMonthly *float64 `json:"cost_monthly"`
 }
 
 func (r SQSResource) ResourceID() string    { return r.ID }
@@ -72,11 +72,11 @@ func GetSSMParameter(ctx context.Context, profile, region, name string, decrypt
 // SSMParameterInfo はレガシー CLI 互換の SSM パラメータ一覧表示用フィールドを保持する。
 // SSMParameterResource と異なり値を含まない (DescribeParameters のメタデータのみ)。
 type SSMParameterInfo struct {
-	Name             string
-	Type             string
-	LastModifiedDate string
-	Version          int64
-	DataType         string
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	LastModifiedDate string `json:"last_modified_date"`
+	Version          int64  `json:"version"`
+	DataType         string `json:"data_type"`
 }
 
 // ToRow converts SSMParameterInfo to a string slice for table output.
@@ -92,11 +92,11 @@ func (p SSMParameterInfo) ToRow() []string {
 
 // SSMParameterValue は GetParameter が返す単一パラメータの値と属性を保持する。
 type SSMParameterValue struct {
-	Name    string
-	Type    string
-	Value   string
-	Version int64
-	ARN     string
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Version int64  `json:"version"`
+	ARN     string `json:"arn"`
 }
 
 // ToRow converts SSMParameterValue to a string slice for table output.
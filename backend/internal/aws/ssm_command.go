@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// ssmCommandPollInterval は SendCommand の完了を確認する間隔。
+	ssmCommandPollInterval = 2 * time.Second
+	// ssmCommandInvocationConcurrency は GetCommandInvocation を並列に呼び出す数の上限。
+	ssmCommandInvocationConcurrency = 8
+)
+
+// SSMCommandInvocation は SendCommand を実行した 1 インスタンス分の結果。
+type SSMCommandInvocation struct {
+	InstanceID string `json:"instance_id"`
+	// Status は Success / Failed / TimedOut / Cancelled などの実行ステータス。
+	Status string `json:"status"`
+	// ExitCode はコマンドの終了コード。実行されなかった場合は -1。
+	ExitCode int32 `json:"exit_code"`
+	// Stdout / Stderr は SSM が保持する出力 (それぞれ先頭 24,000 文字まで)。
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
+// parseSSMTargets は "<key>=<value>[,<value>...]" 形式の指定を SendCommand の Targets に変換する。
+func parseSSMTargets(specs []string) ([]ssmtypes.Target, error) {
+	targets := make([]ssmtypes.Target, 0, len(specs))
+	for _, spec := range specs {
+		key, values, ok := strings.Cut(spec, "=")
+		if !ok || key == "" || values == "" {
+			return nil, fmt.Errorf("invalid target %q: expected <key>=<value>[,<value>...]", spec)
+		}
+		targets = append(targets, ssmtypes.Target{
+			Key:    aws.String(key),
+			Values: strings.Split(values, ","),
+		})
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+	return targets, nil
+}
+
+// RunSSMCommand は targets に一致するインスタンスで command を AWS-RunShellScript として実行し、
+// 全インスタンスの実行が終わるまで待って結果をインスタンス ID 順に返す。targets は "<key>=<value>[,<value>...]"
+// 形式で、key には tag:<タグ名> / InstanceIds / resource-groups:Name などを指定する (例: tag:Role=web)。
+func RunSSMCommand(ctx context.Context, profile, region string, targets []string, command string) ([]SSMCommandInvocation, error) {
+	ssmTargets, err := parseSSMTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newSSMClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.SendCommand(ctx, &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		Targets:      ssmTargets,
+		Parameters:   map[string][]string{"commands": {command}},
+		Comment:      aws.String("thief ec2 run"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("send command: %w", err)
+	}
+	if out.Command == nil || out.Command.CommandId == nil {
+		return nil, errors.New("send command: no command returned")
+	}
+	commandID := *out.Command.CommandId
+
+	if err := waitSSMCommand(ctx, client, commandID); err != nil {
+		return nil, err
+	}
+
+	var instanceIDs []string
+	paginator := ssm.NewListCommandInvocationsPaginator(client, &ssm.ListCommandInvocationsInput{
+		CommandId: aws.String(commandID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list command invocations for %s: %w", commandID, err)
+		}
+		for _, inv := range page.CommandInvocations {
+			instanceIDs = append(instanceIDs, ptrStr(inv.InstanceId))
+		}
+	}
+	sort.Strings(instanceIDs)
+
+	invocations := make([]SSMCommandInvocation, len(instanceIDs))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(ssmCommandInvocationConcurrency)
+	for i, id := range instanceIDs {
+		g.Go(func() error {
+			inv, err := client.GetCommandInvocation(gctx, &ssm.GetCommandInvocationInput{
+				CommandId:  aws.String(commandID),
+				InstanceId: aws.String(id),
+			})
+			if err != nil {
+				return fmt.Errorf("get command invocation for %s: %w", id, err)
+			}
+			invocations[i] = ssmCommandInvocationFromSDK(id, inv)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return invocations, nil
+}
+
+// waitSSMCommand はコマンドが全インスタンスで終了する (Pending / InProgress / Cancelling 以外になる) まで待つ。
+func waitSSMCommand(ctx context.Context, client *ssm.Client, commandID string) error {
+	ticker := time.NewTicker(ssmCommandPollInterval)
+	defer ticker.Stop()
+	for {
+		out, err := client.ListCommands(ctx, &ssm.ListCommandsInput{CommandId: aws.String(commandID)})
+		if err != nil {
+			return fmt.Errorf("list commands for %s: %w", commandID, err)
+		}
+		if len(out.Commands) > 0 {
+			cmd := out.Commands[0]
+			if !ssmCommandInProgress(cmd.Status) {
+				if cmd.TargetCount == 0 {
+					return errors.New("no instances matched the targets")
+				}
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for command %s: %w", commandID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ssmCommandInProgress はコマンドのステータスがまだ終了していないかを返す。
+func ssmCommandInProgress(status ssmtypes.CommandStatus) bool {
+	switch status {
+	case ssmtypes.CommandStatusPending, ssmtypes.CommandStatusInProgress, ssmtypes.CommandStatusCancelling:
+		return true
+	}
+	return false
+}
+
+func ssmCommandInvocationFromSDK(instanceID string, out *ssm.GetCommandInvocationOutput) SSMCommandInvocation {
+	return SSMCommandInvocation{
+		InstanceID: instanceID,
+		Status:     string(out.Status),
+		ExitCode:   out.ResponseCode,
+		Stdout:     ptrStr(out.StandardOutputContent),
+		Stderr:     ptrStr(out.StandardErrorContent),
+	}
+}
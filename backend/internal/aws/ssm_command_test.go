@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseSSMTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []ssmtypes.Target
+		wantErr bool
+	}{
+		{
+			name:  "tag and instance ids",
+			specs: []string{"tag:Role=web", "InstanceIds=i-0123,i-0456"},
+			want: []ssmtypes.Target{
+				{Key: aws.String("tag:Role"), Values: []string{"web"}},
+				{Key: aws.String("InstanceIds"), Values: []string{"i-0123", "i-0456"}},
+			},
+		},
+		{name: "missing value", specs: []string{"tag:Role="}, wantErr: true},
+		{name: "missing separator", specs: []string{"tag:Role"}, wantErr: true},
+		{name: "no targets", specs: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSSMTargets(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSSMTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreUnexported(ssmtypes.Target{})); diff != "" {
+				t.Errorf("parseSSMTargets() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSSMCommandInvocationFromSDK(t *testing.T) {
+	got := ssmCommandInvocationFromSDK("i-0123", &ssm.GetCommandInvocationOutput{
+		Status:                ssmtypes.CommandInvocationStatusFailed,
+		ResponseCode:          2,
+		StandardOutputContent: aws.String("partial\n"),
+		StandardErrorContent:  aws.String("No such file\n"),
+	})
+	want := SSMCommandInvocation{InstanceID: "i-0123", Status: "Failed", ExitCode: 2, Stdout: "partial\n", Stderr: "No such file\n"}
+	if got != want {
+		t.Errorf("ssmCommandInvocationFromSDK() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSSMCommandInProgress(t *testing.T) {
+	for status, want := range map[ssmtypes.CommandStatus]bool{
+		ssmtypes.CommandStatusPending:    true,
+		ssmtypes.CommandStatusInProgress: true,
+		ssmtypes.CommandStatusSuccess:    false,
+		ssmtypes.CommandStatusTimedOut:   false,
+	} {
+		if got := ssmCommandInProgress(status); got != want {
+			t.Errorf("ssmCommandInProgress(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
@@ -14,19 +15,56 @@ type StartSessionResult struct {
 	SessionID  string
 	StreamURL  string
 	TokenValue string
+	// Target はセッションの接続先 (SSM のターゲット文字列)。KMS 暗号化の暗号化コンテキストに使う。
+	Target string
 }
 
 // StartSSMSession starts an SSM Session Manager session against the given managed node target
 // (typically an EC2 instance ID) and returns the data channel connection info.
 func StartSSMSession(ctx context.Context, profile, region, target string) (*StartSessionResult, error) {
+	return startSSMSession(ctx, profile, region, &ssm.StartSessionInput{
+		Target: aws.String(target),
+	})
+}
+
+// StartSSMPortForwardingSession は target (踏み台となるマネージドノード) 経由で remoteHost:remotePort へ
+// 転送するポートフォワーディングセッションを開始する。remoteHost が空の場合は target 自身の
+// remotePort へ転送する (AWS-StartPortForwardingSession)。localPort は agent 側のログ表示用に渡す。
+func StartSSMPortForwardingSession(ctx context.Context, profile, region, target, remoteHost string, remotePort, localPort int) (*StartSessionResult, error) {
+	document := "AWS-StartPortForwardingSession"
+	params := map[string][]string{
+		"portNumber":      {strconv.Itoa(remotePort)},
+		"localPortNumber": {strconv.Itoa(localPort)},
+	}
+	if remoteHost != "" {
+		document = "AWS-StartPortForwardingSessionToRemoteHost"
+		params["host"] = []string{remoteHost}
+	}
+	return startSSMSession(ctx, profile, region, &ssm.StartSessionInput{
+		Target:       aws.String(target),
+		DocumentName: aws.String(document),
+		Parameters:   params,
+	})
+}
+
+// StartSSMCommandSession は target 上で command を実行するセッションを開始する (AWS-StartInteractiveCommand)。
+// command は agent 上で PTY 付きの sh -c として実行され、終了するとセッションも閉じられる。
+func StartSSMCommandSession(ctx context.Context, profile, region, target, command string) (*StartSessionResult, error) {
+	return startSSMSession(ctx, profile, region, &ssm.StartSessionInput{
+		Target:       aws.String(target),
+		DocumentName: aws.String("AWS-StartInteractiveCommand"),
+		Parameters:   map[string][]string{"command": {command}},
+	})
+}
+
+func startSSMSession(ctx context.Context, profile, region string, input *ssm.StartSessionInput) (*StartSessionResult, error) {
 	client, err := newSSMClient(ctx, profile, region)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := client.StartSession(ctx, &ssm.StartSessionInput{
-		Target: aws.String(target),
-	})
+	target := ptrStr(input.Target)
+	out, err := client.StartSession(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("start ssm session for target %s: %w", target, err)
 	}
@@ -35,9 +73,43 @@ func StartSSMSession(ctx context.Context, profile, region, target string) (*Star
 		SessionID:  ptrStr(out.SessionId),
 		StreamURL:  ptrStr(out.StreamUrl),
 		TokenValue: ptrStr(out.TokenValue),
+		Target:     target,
 	}, nil
 }
 
+// ResumeSSMSession は切断されたセッションのデータチャネルへ再接続するための StreamUrl とトークンを取得する。
+// ECS Exec のセッションも SSM のセッション ID で再開できる。
+func ResumeSSMSession(ctx context.Context, profile, region, sessionID string) (*StartSessionResult, error) {
+	client, err := newSSMClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.ResumeSession(ctx, &ssm.ResumeSessionInput{
+		SessionId: aws.String(sessionID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resume ssm session %s: %w", sessionID, err)
+	}
+	return &StartSessionResult{
+		SessionID:  ptrStr(out.SessionId),
+		StreamURL:  ptrStr(out.StreamUrl),
+		TokenValue: ptrStr(out.TokenValue),
+	}, nil
+}
+
+// SessionResumer は profile / region / sessionID を束縛した ResumeSSMSession を返す。
+// session.ResumeFunc として DataChannel.EnableReconnect に渡す。
+func SessionResumer(profile, region, sessionID string) func(ctx context.Context) (string, string, error) {
+	return func(ctx context.Context) (string, string, error) {
+		result, err := ResumeSSMSession(ctx, profile, region, sessionID)
+		if err != nil {
+			return "", "", err
+		}
+		return result.StreamURL, result.TokenValue, nil
+	}
+}
+
 // TerminateSSMSession terminates the given SSM Session Manager session.
 // Callers should invoke this with a short-lived context (e.g. detached from the
 // original request context) since it runs as bridge cleanup.
@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTagFilter はタグフィルタの指定にキーがない場合のエラー。
+var ErrInvalidTagFilter = errors.New("invalid tag filter")
+
+// Tagged はタグを持つリソース。CLI の一覧型が実装し、--tag / --tag-columns で参照される。
+type Tagged interface {
+	ResourceTags() map[string]string
+}
+
+// TagFilter は値の一致 ("key:value") またはキーの存在 ("key") のタグ条件。
+type TagFilter struct {
+	Key   string
+	Value string
+	// AnyValue が true のときは Key のタグが存在すれば値を問わず一致する。
+	AnyValue bool
+}
+
+// ParseTagFilters は "team:payments" / "env" 形式の指定を解釈する。キー自体に ":" を含むタグ
+// (aws:cloudformation:stack-name など) は "key=value" 形式で指定でき、"=" を含む指定は最初の "=" で分ける。
+func ParseTagFilters(specs []string) ([]TagFilter, error) {
+	filters := make([]TagFilter, 0, len(specs))
+	for _, spec := range specs {
+		sep := ":"
+		if strings.Contains(spec, "=") {
+			sep = "="
+		}
+		key, value, hasValue := strings.Cut(spec, sep)
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("%w: %q (want key:value, key=value or key)", ErrInvalidTagFilter, spec)
+		}
+		filters = append(filters, TagFilter{Key: key, Value: value, AnyValue: !hasValue})
+	}
+	return filters, nil
+}
+
+// MatchTags は tags が filters をすべて満たすかを返す。同じキーの条件が複数ある場合はいずれかの値に
+// 一致すればよい (例: team:a と team:b は team が a または b)。異なるキーの条件はすべて満たす必要がある。
+func MatchTags(tags map[string]string, filters []TagFilter) bool {
+	byKey := map[string][]TagFilter{}
+	for _, f := range filters {
+		byKey[f.Key] = append(byKey[f.Key], f)
+	}
+	for key, fs := range byKey {
+		v, ok := tags[key]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, f := range fs {
+			if f.AnyValue || f.Value == v {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// TagsFromJSON はリソースの JSON 表現の tags フィールドを読み取る。{"key": "value"} と
+// [{"key": ..., "value": ...}] (CloudFormation など) のどちらの形式にも対応し、tags がなければ nil を返す。
+func TagsFromJSON(raw json.RawMessage) map[string]string {
+	var fields struct {
+		Tags json.RawMessage `json:"tags"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil || len(fields.Tags) == 0 {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(fields.Tags, &m); err == nil {
+		return m
+	}
+	var list []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(fields.Tags, &list); err != nil {
+		return nil
+	}
+	m = make(map[string]string, len(list))
+	for _, t := range list {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+// ResourceTags implementations for Tagged compatibility.
+
+func (r EC2Resource) ResourceTags() map[string]string         { return r.Tags }
+func (r RDSResource) ResourceTags() map[string]string         { return r.Tags }
+func (r LambdaResource) ResourceTags() map[string]string      { return r.Tags }
+func (r ECSResource) ResourceTags() map[string]string         { return r.Tags }
+func (r CFNStackResource) ResourceTags() map[string]string    { return r.Tags }
+func (r KinesisResource) ResourceTags() map[string]string     { return r.Tags }
+func (r DynamoResource) ResourceTags() map[string]string      { return r.Tags }
+func (r ElastiCacheResource) ResourceTags() map[string]string { return r.Tags }
+func (r APIGatewayResource) ResourceTags() map[string]string  { return r.Tags }
+func (r NATGatewayResource) ResourceTags() map[string]string  { return r.Tags }
+func (r SQSResource) ResourceTags() map[string]string         { return r.Tags }
+func (r WAFResource) ResourceTags() map[string]string         { return r.Tags }
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTagFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []TagFilter
+		wantErr error
+	}{
+		{
+			name:  "key:value and key",
+			specs: []string{"team:payments", "env"},
+			want:  []TagFilter{{Key: "team", Value: "payments"}, {Key: "env", AnyValue: true}},
+		},
+		{
+			name:  "key=value allows colons in the key",
+			specs: []string{"aws:cloudformation:stack-name=app"},
+			want:  []TagFilter{{Key: "aws:cloudformation:stack-name", Value: "app"}},
+		},
+		{
+			name:  "empty value",
+			specs: []string{"owner:"},
+			want:  []TagFilter{{Key: "owner"}},
+		},
+		{name: "missing key", specs: []string{":payments"}, wantErr: ErrInvalidTagFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTagFilters(tt.specs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("filters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	tags := map[string]string{"team": "payments", "env": "prod"}
+	tests := []struct {
+		name  string
+		specs []string
+		want  bool
+	}{
+		{name: "no filters", want: true},
+		{name: "all keys match", specs: []string{"team:payments", "env:prod"}, want: true},
+		{name: "one key differs", specs: []string{"team:payments", "env:dev"}, want: false},
+		{name: "same key is OR", specs: []string{"env:dev", "env:prod"}, want: true},
+		{name: "key exists", specs: []string{"team"}, want: true},
+		{name: "key missing", specs: []string{"owner"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := ParseTagFilters(tt.specs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := MatchTags(tags, filters); got != tt.want {
+				t.Errorf("MatchTags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagsFromJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]string
+	}{
+		{name: "map", raw: `{"id":"i-1","tags":{"team":"payments"}}`, want: map[string]string{"team": "payments"}},
+		{name: "key/value list", raw: `{"tags":[{"key":"team","value":"payments"}]}`, want: map[string]string{"team": "payments"}},
+		{name: "no tags", raw: `{"id":"i-1"}`},
+		{name: "not an object", raw: `"x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, TagsFromJSON(json.RawMessage(tt.raw))); diff != "" {
+				t.Errorf("tags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/wafv2"
@@ -110,6 +111,26 @@ func listWAFACLs(ctx context.Context, client *wafv2.Client, scope waftypes.Scope
 	return resources, nil
 }
 
+// ListWAFAssociatedResources は Web ACL に関連付けられたリソースの ARN を返す。REGIONAL スコープでは ALB、
+// CLOUDFRONT スコープ (ARN が :global/webacl/ を含む) ではディストリビューションを返す。
+func ListWAFAssociatedResources(ctx context.Context, profile, region, webACLArn string) ([]string, error) {
+	if strings.Contains(webACLArn, ":global/webacl/") {
+		return listCloudFrontDistributionArnsByWebACL(ctx, profile, webACLArn)
+	}
+	client, err := newWAFClient(ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.ListResourcesForWebACL(ctx, &wafv2.ListResourcesForWebACLInput{
+		WebACLArn:    aws.String(webACLArn),
+		ResourceType: waftypes.ResourceTypeApplicationLoadBalancer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list resources for web acl %s: %w", webACLArn, err)
+	}
+	return out.ResourceArns, nil
+}
+
 func newWAFResource(id, name string, scope waftypes.Scope, ruleCount, associatedCount int, tags map[string]string) WAFResource {
 	return WAFResource{
 		ID:              id,
@@ -1,34 +1,130 @@
 package cache
 
 import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/singleflight"
 )
 
+// revalidateTimeout bounds a background refresh started for a stale entry.
+// It runs detached from the request that triggered it, so it needs its own
+// deadline.
+const revalidateTimeout = 5 * time.Minute
+
 // Entry holds a cached value with timing metadata.
 type Entry[V any] struct {
 	Value    V
 	CachedAt time.Time
 	Expiry   time.Time
+	// Stale reports that Load served the entry after its expiry while a
+	// refresh runs in the background (see Options.StaleWhileRevalidate).
+	Stale bool
+}
+
+// Options configures optional Cache behaviour. The zero value gives a plain
+// in-memory TTL cache.
+type Options[V any] struct {
+	// Store, when set, persists entries: every Set is also written to disk as
+	// JSON, and a memory miss falls back to the stored entry (decoded with
+	// Decode), keeping its original CachedAt and Expiry. Entries written by a
+	// previous process are therefore served until they expire. Invalidation
+	// and the janitor apply to the stored entries as well.
+	Store  *DiskStore
+	Decode func([]byte) (V, error)
+
+	// StaleWhileRevalidate is how long after its expiry an entry may still be
+	// returned by Load (marked Stale) while a single background refresh
+	// replaces it. Entries are kept until the end of this window. Zero makes
+	// Load block on expired entries.
+	StaleWhileRevalidate time.Duration
+
+	// MaxBytes bounds the memory held by entries, measured as the size of
+	// their JSON encoding. When a Set exceeds it, the least recently used
+	// entries are evicted from memory (a persisted copy stays on disk and is
+	// restored on the next access). Zero means unbounded.
+	MaxBytes int64
+}
+
+// Stats is a point-in-time summary of a Cache.
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	StaleHits uint64 `json:"stale_hits"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// EntryInfo describes one entry held in memory, without its value.
+type EntryInfo struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	CachedAt time.Time `json:"cached_at"`
+	Expiry   time.Time `json:"expiry"`
+	Stale    bool      `json:"stale"`
+	Hits     uint64    `json:"hits"`
+}
+
+// item is the in-memory record of an entry. It is the Value of an element
+// of Cache.lru.
+type item[V any] struct {
+	key   string
+	entry Entry[V]
+	size  int64
+	hits  uint64
 }
 
 // Cache is a generic TTL cache with singleflight dogpile prevention.
 type Cache[V any] struct {
-	mu    sync.RWMutex
-	items map[string]Entry[V]
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List // front is the most recently used item
+	bytes int64
 	group singleflight.Group
 	stop  chan struct{}
+
+	disk     *DiskStore
+	decode   func([]byte) (V, error)
+	stale    time.Duration
+	maxBytes int64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	staleHits atomic.Uint64
+	evictions atomic.Uint64
 }
 
 // New creates a Cache and starts a janitor goroutine that removes expired
 // entries at the given interval. Call Close to stop it.
 func New[V any](janitorInterval time.Duration) *Cache[V] {
+	return NewWithOptions(janitorInterval, Options[V]{})
+}
+
+// NewPersistent creates a Cache backed by store (see Options.Store). A nil
+// store behaves like New.
+func NewPersistent[V any](janitorInterval time.Duration, store *DiskStore, decode func([]byte) (V, error)) *Cache[V] {
+	return NewWithOptions(janitorInterval, Options[V]{Store: store, Decode: decode})
+}
+
+// NewWithOptions creates a Cache configured by opts and starts its janitor.
+func NewWithOptions[V any](janitorInterval time.Duration, opts Options[V]) *Cache[V] {
 	c := &Cache[V]{
-		items: make(map[string]Entry[V]),
-		stop:  make(chan struct{}),
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		stop:     make(chan struct{}),
+		disk:     opts.Store,
+		decode:   opts.Decode,
+		stale:    opts.StaleWhileRevalidate,
+		maxBytes: opts.MaxBytes,
 	}
 	go c.janitor(janitorInterval)
 	return c
@@ -43,82 +139,331 @@ func (c *Cache[V]) Close() {
 func (c *Cache[V]) Set(key string, v V, ttl time.Duration) Entry[V] {
 	now := time.Now()
 	e := Entry[V]{Value: v, CachedAt: now, Expiry: now.Add(ttl)}
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Warn("failed to encode cache entry", "key", key, "err", err)
+	}
 	c.mu.Lock()
-	c.items[key] = e
+	c.put(key, e, int64(len(b)))
 	c.mu.Unlock()
+	if c.disk != nil && err == nil {
+		c.persist(key, e, b)
+	}
 	return e
 }
 
+// put stores e as the most recently used item and evicts older items while
+// the cache is over its byte budget. The caller must hold c.mu.
+func (c *Cache[V]) put(key string, e Entry[V], size int64) {
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	c.items[key] = c.lru.PushFront(&item[V]{key: key, entry: e, size: size})
+	c.bytes += size
+	for c.maxBytes > 0 && c.bytes > c.maxBytes && c.lru.Len() > 1 {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// removeElement drops el from memory. The caller must hold c.mu.
+func (c *Cache[V]) removeElement(el *list.Element) {
+	it := c.lru.Remove(el).(*item[V])
+	delete(c.items, it.key)
+	c.bytes -= it.size
+}
+
+// persist writes e, whose value encodes to b, to the disk store. Failures
+// only cost a cold start after the next restart, so they are logged rather
+// than returned.
+func (c *Cache[V]) persist(key string, e Entry[V], b []byte) {
+	err := c.disk.save(diskEntry{Key: key, Value: b, CachedAt: e.CachedAt, Expiry: e.Expiry})
+	if err != nil {
+		slog.Warn("failed to persist cache entry", "key", key, "err", err)
+	}
+}
+
 // Get returns the Entry for key and whether it was found and not expired.
 func (c *Cache[V]) Get(key string) (Entry[V], bool) {
-	c.mu.RLock()
-	e, ok := c.items[key]
-	c.mu.RUnlock()
+	e, ok := c.lookup(key, false)
 	if !ok || time.Now().After(e.Expiry) {
 		return Entry[V]{}, false
 	}
 	return e, true
 }
 
+// lookup returns the entry for key, including an expired one that is still
+// within the stale window, restoring it from disk on a memory miss. A found
+// entry becomes the most recently used; count also records it as a hit on
+// the entry.
+func (c *Cache[V]) lookup(key string, count bool) (Entry[V], bool) {
+	c.mu.Lock()
+	_, ok := c.items[key]
+	c.mu.Unlock()
+	if !ok && c.disk != nil {
+		c.restore(key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return Entry[V]{}, false
+	}
+	it := el.Value.(*item[V])
+	if c.dead(it.entry.Expiry, time.Now()) {
+		return Entry[V]{}, false
+	}
+	c.lru.MoveToFront(el)
+	if count {
+		it.hits++
+	}
+	return it.entry, true
+}
+
+// dead reports whether an entry expiring at expiry can no longer be served,
+// not even as stale.
+func (c *Cache[V]) dead(expiry, now time.Time) bool {
+	return now.After(expiry.Add(c.stale))
+}
+
+// restore loads key from the disk store into memory. Entries past the stale
+// window are deleted instead of restored.
+func (c *Cache[V]) restore(key string) {
+	de, ok := c.disk.load(key)
+	if !ok {
+		return
+	}
+	c.restoreEntry(de)
+}
+
+// restoreEntry puts de, read from the disk store, into memory unless it is
+// past the stale window or cannot be decoded, in which case its file is
+// deleted.
+func (c *Cache[V]) restoreEntry(de diskEntry) {
+	if c.dead(de.Expiry, time.Now()) {
+		c.disk.remove(de.Key)
+		return
+	}
+	v, err := c.decode(de.Value)
+	if err != nil {
+		slog.Warn("failed to decode persisted cache entry", "key", de.Key, "err", err)
+		c.disk.remove(de.Key)
+		return
+	}
+	c.mu.Lock()
+	// Prefer a value that a concurrent Set stored in the meantime.
+	if _, ok := c.items[de.Key]; !ok {
+		c.put(de.Key, Entry[V]{Value: v, CachedAt: de.CachedAt, Expiry: de.Expiry}, int64(len(de.Value)))
+	}
+	c.mu.Unlock()
+}
+
+// RestoreWhere loads every entry in the disk store whose key satisfies match
+// into memory, as Get would on a memory miss. Entries are otherwise only
+// restored when their key is looked up, so readers of Snapshot (e.g. the
+// search index) would not see what a previous process persisted. It does
+// nothing for a cache without a disk store.
+func (c *Cache[V]) RestoreWhere(match func(key string) bool) {
+	if c.disk == nil {
+		return
+	}
+	for _, de := range c.disk.entries() {
+		c.mu.Lock()
+		_, ok := c.items[de.Key]
+		c.mu.Unlock()
+		if !ok && match(de.Key) {
+			c.restoreEntry(de)
+		}
+	}
+}
+
 // Invalidate removes the entry for key.
 func (c *Cache[V]) Invalidate(key string) {
 	c.mu.Lock()
-	delete(c.items, key)
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
 	c.mu.Unlock()
+	if c.disk != nil {
+		c.disk.remove(key)
+	}
 }
 
-// InvalidatePrefix removes every entry whose key starts with prefix.
+// InvalidatePrefix removes every entry whose key starts with prefix and
+// returns the number of distinct keys removed from memory and disk.
 // Used when a write affects an unknown set of cached keys that share a
 // common namespace segment (e.g. all "s3-objects:profile:region:bucket:*"
 // entries regardless of the trailing prefix query parameter).
-func (c *Cache[V]) InvalidatePrefix(prefix string) {
+func (c *Cache[V]) InvalidatePrefix(prefix string) int {
+	removed := make(map[string]struct{})
 	c.mu.Lock()
-	for k := range c.items {
+	for k, el := range c.items {
 		if strings.HasPrefix(k, prefix) {
-			delete(c.items, k)
+			c.removeElement(el)
+			removed[k] = struct{}{}
+		}
+	}
+	c.mu.Unlock()
+	if c.disk != nil {
+		for _, k := range c.disk.removeWhere(func(e diskEntry) bool { return strings.HasPrefix(e.Key, prefix) }) {
+			removed[k] = struct{}{}
+		}
+	}
+	return len(removed)
+}
+
+// Stats returns the current size of the cache and the counters accumulated
+// by Load since the cache was created.
+func (c *Cache[V]) Stats() Stats {
+	c.mu.Lock()
+	entries, bytes := len(c.items), c.bytes
+	c.mu.Unlock()
+	return Stats{
+		Entries:   entries,
+		Bytes:     bytes,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		StaleHits: c.staleHits.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+// Entries describes the entries held in memory, sorted by key. Entries that
+// are only on disk (evicted, or written before a restart and not accessed
+// since) are not included.
+func (c *Cache[V]) Entries() []EntryInfo {
+	now := time.Now()
+	c.mu.Lock()
+	infos := make([]EntryInfo, 0, len(c.items))
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		it := el.Value.(*item[V])
+		if c.dead(it.entry.Expiry, now) {
+			continue
 		}
+		infos = append(infos, EntryInfo{
+			Key:      it.key,
+			Size:     it.size,
+			CachedAt: it.entry.CachedAt,
+			Expiry:   it.entry.Expiry,
+			Stale:    now.After(it.entry.Expiry),
+			Hits:     it.hits,
+		})
 	}
 	c.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
+}
+
+// Snapshot returns the entries held in memory, including stale ones, keyed
+// by key. Unlike Get it neither restores entries from disk nor changes their
+// recency or hit counts, so readers that derive data from the whole cache
+// (e.g. the search index) do not disturb eviction.
+func (c *Cache[V]) Snapshot() map[string]Entry[V] {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make(map[string]Entry[V], len(c.items))
+	for k, el := range c.items {
+		it := el.Value.(*item[V])
+		if c.dead(it.entry.Expiry, now) {
+			continue
+		}
+		e := it.entry
+		e.Stale = now.After(e.Expiry)
+		entries[k] = e
+	}
+	return entries
 }
 
 // Load is the primary entry point for all cached resource fetches.
 // If refresh=true, the existing entry is invalidated before loading.
-// Uses singleflight to prevent concurrent duplicate requests to loader.
+// Uses singleflight to prevent concurrent duplicate requests to loader,
+// which receives ctx (or, for a background refresh, a context detached
+// from it). An expired entry within the stale window is returned at once
+// with Stale set, and a background refresh replaces it.
 // Returns the entry, whether it was a cache hit, and any error.
 func (c *Cache[V]) Load(
+	ctx context.Context,
 	key string,
 	ttl time.Duration,
 	refresh bool,
-	loader func() (V, error),
+	loader func(context.Context) (V, error),
 ) (Entry[V], bool, error) {
 	if refresh {
 		c.Invalidate(key)
 	}
 
-	if e, ok := c.Get(key); ok {
+	if e, ok := c.lookup(key, true); ok {
+		if !time.Now().After(e.Expiry) {
+			c.hits.Add(1)
+			return e, true, nil
+		}
+		c.staleHits.Add(1)
+		c.revalidate(ctx, key, ttl, loader)
+		e.Stale = true
 		return e, true, nil
 	}
 
-	type result struct {
-		entry Entry[V]
+	c.misses.Add(1)
+	res := <-c.fetch(ctx, key, ttl, loader)
+	if res.Err != nil {
+		var zero Entry[V]
+		return zero, false, res.Err
 	}
+	return res.Val.(Entry[V]), false, nil
+}
 
-	ch := c.group.DoChan(key, func() (any, error) {
-		v, err := loader()
+// Refresh loads key unconditionally and stores the result, sharing the call
+// with any concurrent Load of the same key. Used to warm the cache ahead of
+// requests.
+func (c *Cache[V]) Refresh(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(context.Context) (V, error),
+) (Entry[V], error) {
+	res := <-c.fetch(ctx, key, ttl, loader)
+	if res.Err != nil {
+		return Entry[V]{}, res.Err
+	}
+	return res.Val.(Entry[V]), nil
+}
+
+// fetch runs loader for key through singleflight and stores the result.
+func (c *Cache[V]) fetch(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(context.Context) (V, error),
+) <-chan singleflight.Result {
+	return c.group.DoChan(key, func() (any, error) {
+		v, err := loader(ctx)
 		if err != nil {
 			return nil, err
 		}
-		e := c.Set(key, v, ttl)
-		return result{entry: e}, nil
+		return c.Set(key, v, ttl), nil
 	})
+}
 
-	res := <-ch
-	if res.Err != nil {
-		var zero Entry[V]
-		return zero, false, res.Err
-	}
-	r := res.Val.(result)
-	return r.entry, false, nil
+// revalidate refreshes key in the background. The result channel is
+// buffered by singleflight, so nobody has to wait for it.
+func (c *Cache[V]) revalidate(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(context.Context) (V, error),
+) {
+	c.fetch(context.WithoutCancel(ctx), key, ttl, func(ctx context.Context) (V, error) {
+		ctx, cancel := context.WithTimeout(ctx, revalidateTimeout)
+		defer cancel()
+		v, err := loader(ctx)
+		if err != nil {
+			slog.Warn("background cache refresh failed", "key", key, "err", err)
+		}
+		return v, err
+	})
 }
 
 func (c *Cache[V]) janitor(interval time.Duration) {
@@ -134,13 +479,19 @@ func (c *Cache[V]) janitor(interval time.Duration) {
 	}
 }
 
+// deleteExpired removes entries that can no longer be served, keeping
+// expired ones for the stale window.
 func (c *Cache[V]) deleteExpired() {
 	now := time.Now()
 	c.mu.Lock()
-	for k, e := range c.items {
-		if now.After(e.Expiry) {
-			delete(c.items, k)
+	for _, el := range c.items {
+		if c.dead(el.Value.(*item[V]).entry.Expiry, now) {
+			c.removeElement(el)
 		}
 	}
 	c.mu.Unlock()
+	if c.disk != nil {
+		c.disk.removeWhere(func(e diskEntry) bool { return c.dead(e.Expiry, now) })
+		c.disk.removeStaleTemp(time.Hour)
+	}
 }
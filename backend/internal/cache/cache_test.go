@@ -1,16 +1,18 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 )
 
 func TestCacheInvalidatePrefix(t *testing.T) {
 	tests := []struct {
-		name       string
-		seed       map[string]string
-		prefix     string
-		wantRemain []string
+		name        string
+		seed        map[string]string
+		prefix      string
+		wantRemain  []string
+		wantRemoved int
 	}{
 		{
 			name: "removes matching prefix, keeps others",
@@ -19,8 +21,9 @@ func TestCacheInvalidatePrefix(t *testing.T) {
 				"s3-objects:p:r:bucket:logs": "b",
 				"s3-objects:p:r:other:":      "c",
 			},
-			prefix:     "s3-objects:p:r:bucket:",
-			wantRemain: []string{"s3-objects:p:r:other:"},
+			prefix:      "s3-objects:p:r:bucket:",
+			wantRemain:  []string{"s3-objects:p:r:other:"},
+			wantRemoved: 2,
 		},
 		{
 			name: "no match leaves everything",
@@ -46,7 +49,9 @@ func TestCacheInvalidatePrefix(t *testing.T) {
 				c.Set(k, v, time.Minute)
 			}
 
-			c.InvalidatePrefix(tt.prefix)
+			if got := c.InvalidatePrefix(tt.prefix); got != tt.wantRemoved {
+				t.Errorf("InvalidatePrefix = %d, want %d", got, tt.wantRemoved)
+			}
 
 			for _, k := range tt.wantRemain {
 				if _, ok := c.Get(k); !ok {
@@ -71,3 +76,180 @@ func TestCacheInvalidatePrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheLoadStaleWhileRevalidate(t *testing.T) {
+	c := NewWithOptions(time.Hour, Options[string]{StaleWhileRevalidate: time.Hour})
+	t.Cleanup(c.Close)
+	c.Set("k", "old", -time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	refreshed := make(chan error, 1)
+	entry, hit, err := c.Load(ctx, "k", time.Hour, false, func(ctx context.Context) (string, error) {
+		<-release
+		refreshed <- ctx.Err()
+		return "new", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hit || !entry.Stale || entry.Value != "old" {
+		t.Fatalf("Load = (%q, hit=%v, stale=%v), want stale hit with old value", entry.Value, hit, entry.Stale)
+	}
+
+	// The refresh outlives the request that triggered it, and concurrent Loads
+	// keep getting the stale value instead of starting another refresh.
+	cancel()
+	entry, _, _ = c.Load(context.Background(), "k", time.Hour, false, func(context.Context) (string, error) {
+		t.Error("only one refresh must run at a time")
+		return "", nil
+	})
+	if !entry.Stale {
+		t.Error("expected second Load during refresh to be stale as well")
+	}
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Fatalf("background refresh context: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if e, ok := c.Get("k"); ok && e.Value == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not replace the entry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCacheLoadBlocksPastStaleWindow(t *testing.T) {
+	tests := []struct {
+		name  string
+		stale time.Duration
+	}{
+		{name: "stale-while-revalidate disabled", stale: 0},
+		{name: "expired beyond the stale window", stale: time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWithOptions(time.Hour, Options[string]{StaleWhileRevalidate: tt.stale})
+			t.Cleanup(c.Close)
+			c.Set("k", "old", -time.Minute)
+
+			entry, hit, err := c.Load(context.Background(), "k", time.Hour, false, func(context.Context) (string, error) {
+				return "new", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hit || entry.Stale || entry.Value != "new" {
+				t.Errorf("Load = (%q, hit=%v, stale=%v), want blocking miss with new value", entry.Value, hit, entry.Stale)
+			}
+		})
+	}
+}
+
+func TestCacheJanitorKeepsStaleEntries(t *testing.T) {
+	c := NewWithOptions(time.Hour, Options[string]{StaleWhileRevalidate: time.Hour})
+	t.Cleanup(c.Close)
+	c.Set("stale", "a", -time.Minute)
+	c.Set("dead", "b", -2*time.Hour)
+
+	c.deleteExpired()
+
+	if _, ok := c.lookup("stale", false); !ok {
+		t.Error("expected entry within the stale window to be kept")
+	}
+	if _, ok := c.lookup("dead", false); ok {
+		t.Error("expected entry past the stale window to be removed")
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	c := New[string](time.Hour)
+	t.Cleanup(c.Close)
+	c.Set("k", "old", time.Hour)
+
+	e, err := c.Refresh(context.Background(), "k", time.Hour, func(context.Context) (string, error) {
+		return "new", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("k"); e.Value != "new" || got.Value != "new" {
+		t.Errorf("Refresh = %q, Get = %q, want new", e.Value, got.Value)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	// Each value encodes to 5 bytes ("aaa" with quotes), so three fit in 15.
+	c := NewWithOptions(time.Hour, Options[string]{MaxBytes: 15})
+	t.Cleanup(c.Close)
+	c.Set("a", "aaa", time.Hour)
+	c.Set("b", "bbb", time.Hour)
+	c.Set("c", "ccc", time.Hour)
+	c.Get("a") // "b" becomes the least recently used entry
+
+	c.Set("d", "ddd", time.Hour)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("expected %q to remain", k)
+		}
+	}
+	if st := c.Stats(); st.Entries != 3 || st.Bytes != 15 || st.Evictions != 1 {
+		t.Errorf("Stats = %+v, want 3 entries, 15 bytes, 1 eviction", st)
+	}
+}
+
+func TestCacheStatsAndEntries(t *testing.T) {
+	c := NewWithOptions(time.Hour, Options[string]{StaleWhileRevalidate: time.Hour})
+	t.Cleanup(c.Close)
+	load := func(context.Context) (string, error) { return "value", nil }
+	ctx := context.Background()
+
+	c.Load(ctx, "fresh", time.Hour, false, load) // miss
+	c.Load(ctx, "fresh", time.Hour, false, load) // hit
+	c.Load(ctx, "fresh", time.Hour, false, load) // hit
+	c.Set("stale", "old", -time.Minute)
+	c.Load(ctx, "stale", time.Hour, false, func(context.Context) (string, error) {
+		return "", context.Canceled // keep the entry stale
+	})
+
+	st := c.Stats()
+	if st.Hits != 2 || st.Misses != 1 || st.StaleHits != 1 {
+		t.Errorf("Stats = %+v, want 2 hits, 1 miss, 1 stale hit", st)
+	}
+
+	got := c.Entries()
+	if len(got) != 2 {
+		t.Fatalf("Entries = %+v, want 2 entries", got)
+	}
+	if got[0].Key != "fresh" || got[0].Hits != 2 || got[0].Stale || got[0].Size != int64(len(`"value"`)) {
+		t.Errorf("Entries[0] = %+v, want fresh entry with 2 hits", got[0])
+	}
+	if got[1].Key != "stale" || !got[1].Stale || got[1].Hits != 1 {
+		t.Errorf("Entries[1] = %+v, want stale entry with 1 hit", got[1])
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	c := NewWithOptions(time.Hour, Options[string]{StaleWhileRevalidate: time.Hour})
+	t.Cleanup(c.Close)
+	c.Set("fresh", "a", time.Hour)
+	c.Set("stale", "b", -time.Minute)
+	c.Set("dead", "c", -2*time.Hour)
+
+	got := c.Snapshot()
+	if len(got) != 2 || got["fresh"].Value != "a" || got["fresh"].Stale || !got["stale"].Stale {
+		t.Errorf("Snapshot = %+v, want fresh and stale entries only", got)
+	}
+	if e := c.Entries(); e[0].Hits != 0 || e[1].Hits != 0 {
+		t.Errorf("Entries = %+v, want Snapshot not to count hits", e)
+	}
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// diskFileExt is the extension of entry files. Temporary files written by
+// save use a different suffix so that a crash mid-write never leaves a
+// truncated file that looks like an entry.
+const diskFileExt = ".json"
+
+// DiskStore persists cache entries as one JSON file per key so that a Cache
+// can be warm again after a process restart. File names are the SHA-256 of
+// the key (keys contain profile names and ARNs, which are not safe file
+// names); the key itself is recorded inside the file so that prefix
+// invalidation and pruning can work from a directory listing alone.
+type DiskStore struct {
+	dir string
+}
+
+// diskEntry is the on-disk representation of an Entry.
+type diskEntry struct {
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	CachedAt time.Time       `json:"cached_at"`
+	Expiry   time.Time       `json:"expiry"`
+}
+
+// NewDiskStore returns a DiskStore rooted at dir, creating it if necessary.
+// Cached API responses may contain account details, so the directory and
+// files are only readable by the current user.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+func (d *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+diskFileExt)
+}
+
+// load returns the stored entry for key. Missing, unreadable and corrupt
+// files are all reported as not found.
+func (d *DiskStore) load(key string) (diskEntry, bool) {
+	b, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return diskEntry{}, false
+	}
+	var e diskEntry
+	if err := json.Unmarshal(b, &e); err != nil || e.Key != key {
+		return diskEntry{}, false
+	}
+	return e, true
+}
+
+// save writes e atomically (temporary file + rename) so that concurrent
+// readers never observe a partially written entry.
+func (d *DiskStore) save(e diskEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode cache entry: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create cache file: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path(e.Key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("rename cache file: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the stored entry for key, if any.
+func (d *DiskStore) remove(key string) {
+	os.Remove(d.path(key))
+}
+
+// entries returns every stored entry. Unreadable and corrupt files are
+// skipped.
+func (d *DiskStore) entries() []diskEntry {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil
+	}
+	var entries []diskEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), diskFileExt) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(d.dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		var e diskEntry
+		if err := json.Unmarshal(b, &e); err == nil && e.Key != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// removeWhere deletes every stored entry for which match returns true and
+// returns their keys. Files that can no longer be decoded are deleted as
+// well, since they would never be served.
+func (d *DiskStore) removeWhere(match func(diskEntry) bool) []string {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil
+	}
+	var removed []string
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), diskFileExt) {
+			continue
+		}
+		p := filepath.Join(d.dir, f.Name())
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var e diskEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			os.Remove(p)
+		} else if match(e) {
+			os.Remove(p)
+			removed = append(removed, e.Key)
+		}
+	}
+	return removed
+}
+
+// removeStaleTemp deletes temporary files left behind by a save that was
+// interrupted (e.g. by a crash) more than olderThan ago.
+func (d *DiskStore) removeStaleTemp(olderThan time.Duration) {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-olderThan)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		if info, err := f.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(d.dir, f.Name()))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeString(b []byte) (string, error) {
+	var s string
+	err := json.Unmarshal(b, &s)
+	return s, err
+}
+
+func newPersistentString(t *testing.T, dir string) *Cache[string] {
+	t.Helper()
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewPersistent(time.Hour, store, decodeString)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestPersistentCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	first := newPersistentString(t, dir)
+	stored := first.Set("ec2:prod:ap-northeast-1", "instances", time.Hour)
+	first.Set("ec2:prod:us-east-1", "expired", -time.Second)
+
+	second := newPersistentString(t, dir)
+	e, ok := second.Get("ec2:prod:ap-northeast-1")
+	if !ok {
+		t.Fatal("expected entry written by the previous cache to be restored")
+	}
+	if e.Value != "instances" {
+		t.Errorf("Value = %q, want %q", e.Value, "instances")
+	}
+	if !e.CachedAt.Equal(stored.CachedAt) || !e.Expiry.Equal(stored.Expiry) {
+		t.Errorf("timing = (%v, %v), want (%v, %v)", e.CachedAt, e.Expiry, stored.CachedAt, stored.Expiry)
+	}
+
+	entry, hit, err := second.Load(context.Background(), "ec2:prod:ap-northeast-1", time.Hour, false, func(context.Context) (string, error) {
+		t.Fatal("loader must not be called for a restored entry")
+		return "", nil
+	})
+	if err != nil || !hit || entry.Value != "instances" {
+		t.Errorf("Load = (%q, %v, %v), want restored hit", entry.Value, hit, err)
+	}
+
+	if _, ok := second.Get("ec2:prod:us-east-1"); ok {
+		t.Error("expected expired entry not to be restored")
+	}
+	if n := countEntryFiles(t, dir); n != 1 {
+		t.Errorf("entry files = %d, want 1 (expired file removed on read)", n)
+	}
+}
+
+func TestPersistentCacheInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	c := newPersistentString(t, dir)
+	c.Set("s3-objects:p:r:bucket:", "a", time.Hour)
+	c.Set("s3-objects:p:r:bucket:logs", "b", time.Hour)
+	c.Set("s3-objects:p:r:other:", "c", time.Hour)
+	c.Set("ecs:p:r", "d", time.Hour)
+
+	if n := c.InvalidatePrefix("s3-objects:p:r:bucket:"); n != 2 {
+		t.Errorf("InvalidatePrefix = %d, want 2", n)
+	}
+	c.Invalidate("ecs:p:r")
+
+	restarted := newPersistentString(t, dir)
+	for _, k := range []string{"s3-objects:p:r:bucket:", "s3-objects:p:r:bucket:logs", "ecs:p:r"} {
+		if _, ok := restarted.Get(k); ok {
+			t.Errorf("expected %q to be removed from disk", k)
+		}
+	}
+	if _, ok := restarted.Get("s3-objects:p:r:other:"); !ok {
+		t.Error("expected unrelated entry to remain on disk")
+	}
+}
+
+func TestPersistentCacheJanitorPrunesFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := newPersistentString(t, dir)
+	c.Set("fresh", "a", time.Hour)
+	c.Set("expired", "b", -time.Second)
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "entry-1.tmp")
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	c.deleteExpired()
+
+	if n := countEntryFiles(t, dir); n != 1 {
+		t.Errorf("entry files = %d, want 1", n)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale temp file to be removed, stat err = %v", err)
+	}
+}
+
+func TestPersistentCacheRestoresEvictedEntries(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewWithOptions(time.Hour, Options[string]{Store: store, Decode: decodeString, MaxBytes: 5})
+	t.Cleanup(c.Close)
+	c.Set("a", "aaa", time.Hour)
+	c.Set("b", "bbb", time.Hour)
+
+	if st := c.Stats(); st.Entries != 1 || st.Evictions != 1 {
+		t.Fatalf("Stats = %+v, want 1 entry after evicting one", st)
+	}
+	e, ok := c.Get("a")
+	if !ok || e.Value != "aaa" {
+		t.Errorf("Get(a) = (%q, %v), want evicted entry restored from disk", e.Value, ok)
+	}
+}
+
+func TestPersistentCacheRestoreWhere(t *testing.T) {
+	dir := t.TempDir()
+	first := newPersistentString(t, dir)
+	first.Set("ec2:prod:ap-northeast-1", "instances", time.Hour)
+	first.Set("rds-parameters:prod:ap-northeast-1:default", "parameters", time.Hour)
+	first.Set("ec2:prod:us-east-1", "expired", -time.Second)
+
+	second := newPersistentString(t, dir)
+	if n := len(second.Snapshot()); n != 0 {
+		t.Fatalf("Snapshot before RestoreWhere = %d entries, want 0", n)
+	}
+	second.RestoreWhere(func(key string) bool { return strings.HasPrefix(key, "ec2:") })
+	got := second.Snapshot()
+	if len(got) != 1 || got["ec2:prod:ap-northeast-1"].Value != "instances" {
+		t.Errorf("Snapshot after RestoreWhere = %+v, want only the unexpired ec2 entry", got)
+	}
+	if n := countEntryFiles(t, dir); n != 2 {
+		t.Errorf("entry files = %d, want 2 (expired file removed on restore)", n)
+	}
+}
+
+func countEntryFiles(t *testing.T, dir string) int {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+diskFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(matches)
+}
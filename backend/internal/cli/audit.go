@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var tagAuditColumns = []util.Column{
+	{Header: "Service"},
+	{Header: "AccountID"},
+	{Header: "Profile"},
+	{Header: "Region"},
+	{Header: "Name"},
+	{Header: "ID"},
+	{Header: "Missing"},
+	{Header: "Disallowed"},
+}
+
+var securityFindingColumns = []util.Column{
+	{Header: "Severity"},
+	{Header: "Check"},
+	{Header: "AccountID"},
+	{Header: "Profile"},
+	{Header: "Region"},
+	{Header: "Name"},
+	{Header: "ID"},
+	{Header: "Detail"},
+	{Header: "Remediation"},
+}
+
+var securityCheckColumns = []util.Column{
+	{Header: "ID"},
+	{Header: "Severity"},
+	{Header: "Service"},
+	{Header: "Title"},
+}
+
+// securitySources は audit.SecurityChecks が参照する一覧の取得関数。elb-security はリスナーと WAF の関連付けを含む
+// ロードバランサーの一覧で、searchSources にはない。
+var securitySources = map[string]func(ctx context.Context, profile, region string) (any, error){
+	"s3":           searchList(awsinternal.ListS3Resources),
+	"iam":          searchList(awsinternal.ListIAMResources),
+	"elb-security": searchList(awsinternal.ListELBSecurityResources),
+}
+
+// tagAuditSources は audit.TagServices の一覧関数 (searchSources のうち同じサービスのリージョン別の一覧)。
+var tagAuditSources = func() map[string]func(ctx context.Context, profile, region string) (any, error) {
+	sources := make(map[string]func(ctx context.Context, profile, region string) (any, error), len(audit.TagServices))
+	for _, src := range searchSources {
+		if !src.global && slices.Contains(audit.TagServices, src.service) {
+			sources[src.service] = src.list
+		}
+	}
+	return sources
+}()
+
+// securityCheckRow は thief audit security --list-checks の 1 行。
+type securityCheckRow audit.SecurityCheck
+
+// ToRow converts securityCheckRow to a string slice suitable for table formatting.
+func (c securityCheckRow) ToRow() []string {
+	return []string{c.ID, string(c.Severity), c.Service, c.Title}
+}
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit resources against policies",
+		Long:  `Provides commands to check resources across profiles and regions against policies.`,
+	}
+
+	tagsCmd := &cobra.Command{
+		Use:   "tags",
+		Short: "List resources missing required tags or carrying disallowed tag values",
+		Long: `Lists the resources of ` + strings.Join(audit.TagServices, ", ") + ` and prints the ones
+that lack a required tag key (--require) or carry a value not allowed for a key (--allow), ordered
+by service and account. Use --profiles and --all-regions to audit several accounts and regions, -o csv
+to export the report, and --group-by Service,AccountID to count violations.`,
+		Example: `  thief audit tags --require owner,env,cost-center
+  thief audit tags --require owner --allow 'env=prod|stg|dev' --profiles all --all-regions -o csv > tags.csv`,
+		RunE: runAuditTags,
+	}
+	tagsCmd.Flags().StringP("require", "", "", "Required tag keys (comma-separated)")
+	tagsCmd.Flags().StringArrayP("allow", "", nil, "Allowed values of a tag key as key=value1|value2 (repeatable)")
+	tagsCmd.Flags().StringP("services", "", "", "Services to audit (comma-separated: "+strings.Join(audit.TagServices, ", ")+"; default all)")
+	addFanoutFlags(tagsCmd)
+
+	securityCmd := &cobra.Command{
+		Use:   "security",
+		Short: "Check resources for common security misconfigurations",
+		Long: `Runs security checks (public or unencrypted S3 buckets, IAM users without MFA or unused
+for 90 days, ALBs serving plain HTTP or without a WAF web ACL) and prints the matching resources
+with a severity and a remediation hint, most severe first. Use --checks and --severity to select
+checks, --list-checks to show them, and --profiles and --all-regions to check several accounts
+and regions. S3 and IAM are listed once per profile.`,
+		Example: `  thief audit security
+  thief audit security --severity high --profiles all --all-regions -o csv > security.csv
+  thief audit security --checks elb-http-listener,elb-no-waf`,
+		RunE: runAuditSecurity,
+	}
+	securityCmd.Flags().StringP("checks", "", "", "Check IDs to run (comma-separated; default all)")
+	securityCmd.Flags().StringP("severity", "", "low", "Minimum severity to report (high, medium, low)")
+	securityCmd.Flags().BoolP("list-checks", "", false, "List the available checks and exit")
+	addFanoutFlags(securityCmd)
+
+	auditCmd.AddCommand(tagsCmd, securityCmd)
+	return auditCmd
+}
+
+// runAuditTags は profile/region と対象サービスの組ごとに一覧を取得し、タグポリシーに違反したリソースを出力する。
+// 一部の組が失敗しても残りの結果は出力し、失敗した組は標準エラー出力に書いたうえでエラーを返す (runFanoutList と同じ)。
+func runAuditTags(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	allowed, _ := cmd.Flags().GetStringArray("allow")
+	policy, err := audit.ParseTagPolicy(awsinternal.SplitFanoutList(cmd.Flag("require").Value.String()), allowed)
+	if err != nil {
+		return err
+	}
+	services, err := selectTagAuditServices(cmd.Flag("services").Value.String())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	targets, resolveErrs, err := resolveFanoutTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	resources, errs := audit.Collect(ctx, targets, services, auditSourceList(tagAuditSources))
+	failure := reportAuditErrors(cmd, resolveErrs, errs, len(targets)*len(services))
+
+	violations := audit.AuditTags(resources, policy)
+	if len(violations) == 0 && !util.IsStructuredFormat(cfg.Output) {
+		// 一部の取得に失敗した場合は違反がないとは言えないため、準拠のメッセージは出さない。
+		if failure == nil {
+			cmd.Printf("All %d resource(s) comply with the tag policy\n", len(resources))
+		}
+	} else if err := printItems(cfg, tagAuditColumns, violations); err != nil {
+		return err
+	}
+	return failure
+}
+
+// runAuditSecurity は profile/region ごとに選んだチェックが参照する一覧を取得し、該当したリソースを重要度の高い順に出力する。
+// 一部の組が失敗しても残りの結果は出力し、失敗した組は標準エラー出力に書いたうえでエラーを返す (runFanoutList と同じ)。
+func runAuditSecurity(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	minSeverity, err := audit.ParseSeverity(cmd.Flag("severity").Value.String())
+	if err != nil {
+		return err
+	}
+	checks, err := audit.SelectSecurityChecks(audit.SecurityChecks, awsinternal.SplitFanoutList(cmd.Flag("checks").Value.String()), minSeverity)
+	if err != nil {
+		return err
+	}
+	if listChecks, _ := cmd.Flags().GetBool("list-checks"); listChecks {
+		rows := make([]securityCheckRow, len(checks))
+		for i, c := range checks {
+			rows[i] = securityCheckRow(c)
+		}
+		return printItems(cfg, securityCheckColumns, rows)
+	}
+
+	ctx := context.Background()
+	targets, resolveErrs, err := resolveFanoutTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	findings, errs := audit.CollectSecurity(ctx, targets, checks, auditSourceList(securitySources), time.Now())
+	failure := reportAuditErrors(cmd, resolveErrs, errs, audit.SecurityListCount(targets, checks))
+
+	if len(findings) == 0 && !util.IsStructuredFormat(cfg.Output) {
+		// 一部の取得に失敗した場合は該当なしとは言えないため、該当なしのメッセージは出さない。
+		if failure == nil {
+			cmd.Printf("No findings from %d check(s)\n", len(checks))
+		}
+	} else if err := printItems(cfg, securityFindingColumns, findings); err != nil {
+		return err
+	}
+	return failure
+}
+
+// auditSourceList は service ごとの一覧関数 sources を audit.ListFunc にする。sources にないサービスは
+// その組の取得の失敗として扱う。
+func auditSourceList(sources map[string]func(ctx context.Context, profile, region string) (any, error)) audit.ListFunc {
+	return func(ctx context.Context, service, profile, region string) (any, error) {
+		list, ok := sources[service]
+		if !ok {
+			return nil, fmt.Errorf("no list for service %q", service)
+		}
+		return list(ctx, profile, region)
+	}
+}
+
+// reportAuditErrors はアカウント ID を解決できなかった profile/region (resolveErrs) と一覧を取得できなかった組 (errs) を
+// 標準エラー出力に書き、失敗があれば runFanoutList と同じ形式のエラーを返す。total は resolveErrs を除く組の数。
+// 呼び出し側は残りの結果を出力してからこのエラーを返す。
+func reportAuditErrors(cmd *cobra.Command, resolveErrs []awsinternal.FanoutError, errs []audit.Error, total int) error {
+	for _, e := range resolveErrs {
+		cmd.PrintErrf("%s: %s\n", fanoutErrorTarget(e), e.Error)
+	}
+	for _, e := range errs {
+		cmd.PrintErrf("%s %s/%s: %s\n", e.Service, e.Profile, e.Region, e.Error)
+	}
+	if failed := len(resolveErrs) + len(errs); failed > 0 {
+		return fmt.Errorf("failed on %d of %d service target(s)", failed, len(resolveErrs)+total)
+	}
+	return nil
+}
+
+// selectTagAuditServices は --services の指定 (カンマ区切り、空なら全サービス) を audit.TagServices から選ぶ。
+func selectTagAuditServices(v string) ([]string, error) {
+	names := awsinternal.SplitFanoutList(v)
+	if len(names) == 0 {
+		return audit.TagServices, nil
+	}
+	for _, name := range names {
+		if !slices.Contains(audit.TagServices, name) {
+			return nil, fmt.Errorf("unknown service %q (available: %s)", name, strings.Join(audit.TagServices, ", "))
+		}
+	}
+	return names, nil
+}
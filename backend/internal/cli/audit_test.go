@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/spf13/cobra"
+)
+
+func TestSelectTagAuditServices(t *testing.T) {
+	got, err := selectTagAuditServices("")
+	if err != nil || !slices.Equal(got, audit.TagServices) {
+		t.Errorf("selectTagAuditServices(\"\") = %v, %v, want all services", got, err)
+	}
+	got, err = selectTagAuditServices("ec2, waf")
+	if err != nil || !slices.Equal(got, []string{"ec2", "waf"}) {
+		t.Errorf("selectTagAuditServices = %v, %v, want [ec2 waf]", got, err)
+	}
+	if _, err := selectTagAuditServices("s3"); err == nil {
+		t.Error("expected an error for a service without a tag audit")
+	}
+}
+
+// runAuditTags は tagAuditSources の一覧関数を使うため、監査対象のサービスはすべて searchSources のリージョン別の一覧にあること。
+func TestTagAuditServicesHaveSources(t *testing.T) {
+	for _, service := range audit.TagServices {
+		if tagAuditSources[service] == nil {
+			t.Errorf("service %q has no regional searchSource", service)
+		}
+	}
+}
+
+// runAuditSecurity は securitySources の一覧関数を使うため、チェックが参照する一覧はすべて securitySources にあること。
+func TestSecurityChecksHaveSources(t *testing.T) {
+	for _, service := range audit.SecurityServices(audit.SecurityChecks) {
+		if securitySources[service] == nil {
+			t.Errorf("service %q has no securitySource", service)
+		}
+	}
+}
+
+func TestAuditSourceList(t *testing.T) {
+	list := auditSourceList(map[string]func(ctx context.Context, profile, region string) (any, error){
+		"ec2": func(_ context.Context, profile, region string) (any, error) { return profile + "/" + region, nil },
+	})
+	if got, err := list(context.Background(), "ec2", "prod", "us-east-1"); err != nil || got != "prod/us-east-1" {
+		t.Errorf("list(ec2) = %v, %v, want prod/us-east-1", got, err)
+	}
+	if _, err := list(context.Background(), "waf", "prod", "us-east-1"); err == nil {
+		t.Error("expected an error for a service without a list")
+	}
+}
+
+func TestReportAuditErrors(t *testing.T) {
+	var stderr bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetErr(&stderr)
+	if err := reportAuditErrors(cmd, nil, nil, 4); err != nil || stderr.Len() != 0 {
+		t.Errorf("reportAuditErrors without failures = %v (stderr %q), want nil", err, stderr.String())
+	}
+
+	resolveErrs := []awsinternal.FanoutError{{Profile: "dev", Error: "no account id"}}
+	errs := []audit.Error{{Service: "waf", Profile: "prod", Region: "us-east-1", Error: "access denied"}}
+	err := reportAuditErrors(cmd, resolveErrs, errs, 4)
+	if err == nil || err.Error() != "failed on 2 of 5 service target(s)" {
+		t.Errorf("error = %v, want failed on 2 of 5 service target(s)", err)
+	}
+	if want := "dev: no account id\nwaf prod/us-east-1: access denied\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
@@ -121,17 +121,14 @@ func listBqDatasets(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if len(datasets) == 0 {
+	if len(datasets) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("No datasets found")
 		return nil
 	}
 
-	rows := make([][]string, len(datasets))
-	for i, d := range datasets {
-		rows[i] = []string{d.DatasetID, d.Location, d.CreationTime, d.LastModifiedTime, d.Description}
-	}
-
-	return printRowsOrGroupBy(cfg, bqDatasetColumns, rows)
+	return printItemsFunc(cfg, bqDatasetColumns, datasets, func(d bigquery.DatasetInfo) []string {
+		return []string{d.DatasetID, d.Location, d.CreationTime, d.LastModifiedTime, d.Description}
+	})
 }
 
 func listBqTables(cmd *cobra.Command, args []string) error {
@@ -153,14 +150,13 @@ func listBqTables(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if len(tables) == 0 {
+	if len(tables) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Printf("No tables found in dataset %s\n", datasetID)
 		return nil
 	}
 
-	rows := make([][]string, len(tables))
-	for i, t := range tables {
-		rows[i] = []string{
+	return printItemsFunc(cfg, bqTableColumns, tables, func(t bigquery.TableInfo) []string {
+		return []string{
 			t.TableID,
 			t.Type,
 			t.CreationTime,
@@ -168,9 +164,7 @@ func listBqTables(cmd *cobra.Command, args []string) error {
 			fmt.Sprintf("%d", t.NumRows),
 			fmt.Sprintf("%d", t.NumBytes),
 		}
-	}
-
-	return printRowsOrGroupBy(cfg, bqTableColumns, rows)
+	})
 }
 
 func showBqTableInfo(cmd *cobra.Command, args []string) error {
@@ -196,17 +190,14 @@ func showBqTableInfo(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if len(fields) == 0 {
+	if len(fields) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("No schema fields found")
 		return nil
 	}
 
-	rows := make([][]string, len(fields))
-	for i, f := range fields {
-		rows[i] = []string{f.Name, f.Type, f.Mode, f.Description}
-	}
-
-	return printRowsOrGroupBy(cfg, bqFieldColumns, rows)
+	return printItemsFunc(cfg, bqFieldColumns, fields, func(f bigquery.FieldInfo) []string {
+		return []string{f.Name, f.Type, f.Mode, f.Description}
+	})
 }
 
 // splitBqTableRef は "dataset.table" 形式の引数を分割する。
@@ -236,7 +227,7 @@ func executeBqQuery(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("execute query: %w", err)
 	}
 
-	if len(result.Rows) == 0 {
+	if len(result.Rows) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("Query returned no results")
 		return nil
 	}
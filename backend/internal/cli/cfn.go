@@ -74,6 +74,8 @@ func newCFNCmd() *cobra.Command {
 		RunE:  describeCfnChangeset,
 	}
 
+	addFanoutFlags(lsCmd)
+
 	cfnCmd.AddCommand(lsCmd, describeCmd, changesetCmd)
 	return cfnCmd
 }
@@ -102,6 +104,10 @@ func describeCfnStack(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("describe stack: %w", err)
 	}
 
+	if util.IsStructuredFormat(cfg.Output) {
+		return printStructured(cfg, detail)
+	}
+
 	// スタックの基本情報を key-value 形式で出力する。
 	infoRows := [][]string{
 		{"StackName", detail.StackName},
@@ -156,10 +162,10 @@ func describeCfnChangeset(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("describe change set: %w", err)
 	}
 
-	if len(changes) == 0 {
+	if len(changes) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("No changes found in change set")
 		return nil
 	}
 
-	return printRowsOrGroupBy(cfg, cfnChangeColumns, toRows(changes))
+	return printItems(cfg, cfnChangeColumns, changes)
 }
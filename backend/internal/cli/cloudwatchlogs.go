@@ -40,6 +40,8 @@ func newLogsCmd() *cobra.Command {
 		},
 	}
 
+	addFanoutFlags(lsCmd)
+
 	logsCmd.AddCommand(lsCmd)
 	return logsCmd
 }
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// copySpec は cp コマンドの <src> <dst> を解析した結果。リモート側は <target>:<path> で指定する。
+type copySpec struct {
+	// target はリモート側のターゲット (ECS タスク / EC2 インスタンス ID)。
+	target     string
+	remotePath string
+	localPath  string
+	// upload はローカルからリモートへのコピーかどうか。
+	upload bool
+}
+
+// parseCopyArgs は src / dst のどちらか一方だけが <target>:<path> 形式であることを確認して copySpec を返す。
+func parseCopyArgs(src, dst string) (copySpec, error) {
+	srcTarget, srcPath, srcRemote := splitRemoteArg(src)
+	dstTarget, dstPath, dstRemote := splitRemoteArg(dst)
+
+	var spec copySpec
+	switch {
+	case srcRemote && dstRemote:
+		return copySpec{}, errors.New("copying between two remote paths is not supported")
+	case srcRemote:
+		spec = copySpec{target: srcTarget, remotePath: srcPath, localPath: dst}
+	case dstRemote:
+		spec = copySpec{target: dstTarget, remotePath: dstPath, localPath: src, upload: true}
+	default:
+		return copySpec{}, errors.New("either source or destination must be a remote path (<target>:<path>)")
+	}
+	if spec.remotePath == "" {
+		return copySpec{}, errors.New("remote path must not be empty")
+	}
+	if spec.localPath == "" {
+		return copySpec{}, errors.New("local path must not be empty")
+	}
+	return spec, nil
+}
+
+// splitRemoteArg は <target>:<path> 形式の引数を分割する。絶対パス (Windows のドライブレター付きを含む) や
+// ":" より前にパス区切りを含む引数はローカルパスとみなす。
+func splitRemoteArg(arg string) (target, remotePath string, ok bool) {
+	if filepath.IsAbs(arg) {
+		return "", "", false
+	}
+	i := strings.Index(arg, ":")
+	if i < 0 || strings.ContainsAny(arg[:i], `/\`) {
+		return "", "", false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+// startCommandFunc は command を実行する SSM セッション (AWS-StartInteractiveCommand / ECS Exec) を開始する。
+type startCommandFunc func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error)
+
+// runFileCopy は spec に従ってダウンロード (リモートのパスを tar で取り出してローカルのディレクトリに展開) または
+// アップロード (ローカルのパスを tar に固めてリモートのディレクトリに展開) を行う。
+func runFileCopy(ctx context.Context, cmd *cobra.Command, cfg *config.Config, spec copySpec, start startCommandFunc) error {
+	tmp, err := os.CreateTemp("", "thief-cp-*.tar")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	if spec.upload {
+		if err := session.WriteArchive(tmp, spec.localPath); err != nil {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("rewind archive: %w", err)
+		}
+		upload, err := session.NewUpload(spec.remotePath, tmp)
+		if err != nil {
+			return err
+		}
+		if err := runCommandSession(ctx, cmd, cfg, upload.Command(), start, upload.Run); err != nil {
+			return fmt.Errorf("upload %s: %w", spec.localPath, err)
+		}
+		cmd.Printf("Copied %s to %s:%s\n", spec.localPath, spec.target, upload.Dir)
+		return nil
+	}
+
+	download, err := session.NewDownload(spec.remotePath)
+	if err != nil {
+		return err
+	}
+	var size int64
+	err = runCommandSession(ctx, cmd, cfg, download.Command(), start, func(ctx context.Context, dc *session.DataChannel) error {
+		n, err := download.Run(ctx, dc, tmp)
+		size = n
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("download %s: %w", spec.remotePath, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind archive: %w", err)
+	}
+	if err := os.MkdirAll(spec.localPath, 0o755); err != nil {
+		return fmt.Errorf("create local directory: %w", err)
+	}
+	if err := session.ExtractArchive(tmp, spec.localPath); err != nil {
+		return err
+	}
+	cmd.Printf("Copied %s:%s to %s (%d bytes archived)\n", spec.target, download.Path, spec.localPath, size)
+	return nil
+}
+
+// runCommandSession は command を実行するセッションを開始してデータチャネルで transfer を行い、終了後にセッションを Terminate する。
+func runCommandSession(ctx context.Context, cmd *cobra.Command, cfg *config.Config, command string, start startCommandFunc, transfer func(context.Context, *session.DataChannel) error) error {
+	result, err := start(ctx, command)
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer func() {
+		if err := awsinternal.TerminateSSMSession(context.Background(), cfg.Profile, cfg.Region, result.SessionID); err != nil {
+			cmd.PrintErrf("terminate session: %v\n", err)
+		}
+	}()
+
+	dc, err := session.OpenDataChannel(ctx, result.StreamURL, result.TokenValue, result.SessionID)
+	if err != nil {
+		return fmt.Errorf("open data channel: %w", err)
+	}
+	defer dc.Close()
+	dc.EnableKMSEncryption(result.Target, awsinternal.SessionDataKeyGenerator(cfg.Profile, cfg.Region))
+	dc.EnableReconnect(awsinternal.SessionResumer(cfg.Profile, cfg.Region, result.SessionID))
+	return transfer(ctx, dc)
+}
@@ -0,0 +1,52 @@
+package cli
+
+import "testing"
+
+func TestParseCopyArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dst     string
+		want    copySpec
+		wantErr bool
+	}{
+		{
+			name: "download",
+			src:  "0123abcd:/tmp/heap.hprof",
+			dst:  "./dumps",
+			want: copySpec{target: "0123abcd", remotePath: "/tmp/heap.hprof", localPath: "./dumps"},
+		},
+		{
+			name: "upload",
+			src:  "app.conf",
+			dst:  "i-0123:/etc/app",
+			want: copySpec{target: "i-0123", remotePath: "/etc/app", localPath: "app.conf", upload: true},
+		},
+		{
+			name: "remote without target",
+			src:  ":/var/log/app.log",
+			dst:  ".",
+			want: copySpec{remotePath: "/var/log/app.log", localPath: "."},
+		},
+		{
+			name: "local path containing colon",
+			src:  "./a:b",
+			dst:  "i-0123:/tmp",
+			want: copySpec{target: "i-0123", remotePath: "/tmp", localPath: "./a:b", upload: true},
+		},
+		{name: "both remote", src: "i-0123:/a", dst: "i-0456:/b", wantErr: true},
+		{name: "both local", src: "./a", dst: "/tmp/b", wantErr: true},
+		{name: "empty remote path", src: "i-0123:", dst: ".", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCopyArgs(tt.src, tt.dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCopyArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseCopyArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
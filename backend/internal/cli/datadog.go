@@ -92,9 +92,8 @@ func showDatadogCost(cmd *cobra.Command, fetch datadogCostFetcher) error {
 		return err
 	}
 
-	rows := make([][]string, len(items))
-	for i, item := range items {
-		rows[i] = []string{
+	return printItemsFunc(cfg, datadogCostColumns, items, func(item datadog.CostInfo) []string {
+		return []string{
 			item.Month,
 			item.AccountName,
 			item.OrgName,
@@ -102,9 +101,7 @@ func showDatadogCost(cmd *cobra.Command, fetch datadogCostFetcher) error {
 			item.ChargeType,
 			strconv.FormatFloat(item.Cost, 'f', -1, 64),
 		}
-	}
-
-	return printRowsOrGroupBy(cfg, datadogCostColumns, rows)
+	})
 }
 
 // isValidDatadogView checks if the provided view string is valid.
@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/session"
 	"github.com/sfuruya0612/thief/backend/internal/util"
 	"github.com/spf13/cobra"
 )
@@ -54,7 +61,8 @@ func newEC2Cmd() *cobra.Command {
 		RunE:  displayEC2Instances,
 	}
 	lsCmd.Flags().BoolP("running", "", false, "Show only running instances")
-	lsCmd.Flags().BoolP("global", "", false, "Show instances in all regions")
+	lsCmd.Flags().BoolP("global", "", false, "Show instances in all regions (alias for --all-regions)")
+	addFanoutFlags(lsCmd)
 
 	sessionCmd := &cobra.Command{
 		Use:     "session",
@@ -66,63 +74,140 @@ If no instance ID is provided, it will prompt for selection from available insta
 	}
 	sessionCmd.Flags().StringP("instance-id", "i", "", "Instance ID")
 
-	ec2Cmd.AddCommand(lsCmd, sessionCmd)
+	portForwardCmd := &cobra.Command{
+		Use:     "port-forward",
+		Aliases: []string{"pf"},
+		Short:   "Forward a local port through an EC2 instance",
+		Long: `Starts an SSM port forwarding session through a specified EC2 instance and forwards
+local TCP connections to the remote host and port. session-manager-plugin is not required.
+If --remote-host is omitted, connections are forwarded to the port on the instance itself.
+If no instance ID is provided, it will prompt for selection from available instances.`,
+		RunE: startEC2PortForward,
+	}
+	portForwardCmd.Flags().StringP("instance-id", "i", "", "Instance ID")
+	portForwardCmd.Flags().IntP("local", "", 0, "Local port to listen on (0 picks a free port)")
+	portForwardCmd.Flags().StringP("remote-host", "", "", "Remote host reachable from the instance (default: the instance itself)")
+	portForwardCmd.Flags().IntP("remote-port", "", 0, "Remote port")
+
+	cpCmd := &cobra.Command{
+		Use:   "cp <src> <dst>",
+		Short: "Copy files between an EC2 instance and the local machine",
+		Long: `Copies a file or directory between an EC2 instance and the local machine over an SSM session
+(AWS-StartInteractiveCommand). Specify the remote side as <instance-id>:<path>; if the instance ID
+is omitted (:<path>), it will prompt for selection from available instances. Downloads are extracted
+into the local directory and uploads are extracted into the remote directory. The content is
+transferred as a base64-encoded tar archive and its SHA-256 checksum is verified on arrival.`,
+		Example: `  thief ec2 cp i-0123456789abcdef0:/var/log/app.log ./logs
+  thief ec2 cp ./app.conf i-0123456789abcdef0:/tmp`,
+		Args: cobra.ExactArgs(2),
+		RunE: copyEC2Files,
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run a command on EC2 instances",
+		Long: `Runs a shell command on the EC2 instances matching --targets with SSM Run Command
+(AWS-RunShellScript), waits for all invocations to finish, and prints the status, exit code,
+stdout and stderr of each instance as a table (or as JSON / YAML / NDJSON with -o).
+Specify targets as <key>=<value>[,<value>...], e.g. tag:Role=web or InstanceIds=i-0123,i-4567.
+SSM keeps up to 24,000 characters of each output. The command exits with an error if it fails
+on any instance.`,
+		Example: `  thief ec2 run --command 'uptime' --targets tag:Role=web
+  thief ec2 run --command 'systemctl is-active nginx' --targets InstanceIds=i-0123456789abcdef0 -o json`,
+		RunE: runEC2Command,
+	}
+	runCmd.Flags().StringP("command", "", "", "Shell command to run")
+	runCmd.Flags().StringArrayP("targets", "", nil, "Targets as <key>=<value>[,<value>...] (repeatable)")
+
+	ec2Cmd.AddCommand(lsCmd, sessionCmd, portForwardCmd, cpCmd, runCmd)
 	return ec2Cmd
 }
 
 // displayEC2Instances retrieves and displays EC2 instances.
 func displayEC2Instances(cmd *cobra.Command, args []string) error {
+	running, _ := cmd.Flags().GetBool("running")
+	opts := awsinternal.EC2ListOptions{Running: running}
+	return runList(cmd, ListConfig[awsinternal.EC2InstanceInfo]{
+		Columns:  ec2Columns,
+		EmptyMsg: "No EC2 instances found",
+		Fetch: func(ctx context.Context, cfg *config.Config) ([]awsinternal.EC2InstanceInfo, error) {
+			list, err := awsinternal.ListEC2Instances(ctx, cfg.Profile, cfg.Region, opts)
+			if err != nil {
+				return nil, fmt.Errorf("list EC2 instances: %w", err)
+			}
+			return list, nil
+		},
+	})
+}
+
+// startEC2Session starts an SSM session to an EC2 instance and attaches it to the local terminal.
+// データチャネルは session パッケージで直接扱うため、session-manager-plugin は不要。
+func startEC2Session(cmd *cobra.Command, args []string) error {
 	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
-	running, _ := cmd.Flags().GetBool("running")
-	global, _ := cmd.Flags().GetBool("global")
-
-	ctx := context.Background()
-	opts := awsinternal.EC2ListOptions{Running: running}
+	// raw モードでは Ctrl+C はリモートへ送られるため、ここで受けるのは非端末入力時の割り込みと SIGTERM のみ。
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	var list []awsinternal.EC2InstanceInfo
-	if global {
-		regions, err := awsinternal.ListRegions(ctx, cfg.Profile)
+	instanceID := cmd.Flag("instance-id").Value.String()
+	if instanceID == "" {
+		instanceID, err = selectEC2Instance(ctx, cfg)
 		if err != nil {
-			return fmt.Errorf("describe regions: %w", err)
+			return err
 		}
+	}
 
-		for _, r := range regions {
-			instances, err := awsinternal.ListEC2Instances(ctx, cfg.Profile, r.Code, opts)
-			if err != nil {
-				cmd.PrintErrf("list EC2 instances in region %s: %v\n", r.Code, err)
-				continue
-			}
-			list = append(list, instances...)
-		}
-	} else {
-		list, err = awsinternal.ListEC2Instances(ctx, cfg.Profile, cfg.Region, opts)
-		if err != nil {
-			return fmt.Errorf("list EC2 instances: %w", err)
-		}
+	result, err := awsinternal.StartSSMSession(ctx, cfg.Profile, cfg.Region, instanceID)
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	terminate := func(ctx context.Context) error {
+		return awsinternal.TerminateSSMSession(ctx, cfg.Profile, cfg.Region, result.SessionID)
 	}
 
-	if len(list) == 0 {
-		cmd.Println("No EC2 instances found")
-		return nil
+	dc, err := session.OpenDataChannel(ctx, result.StreamURL, result.TokenValue, result.SessionID)
+	if err != nil {
+		if termErr := terminate(context.Background()); termErr != nil {
+			cmd.PrintErrf("terminate session: %v\n", termErr)
+		}
+		return fmt.Errorf("open data channel: %w", err)
 	}
+	dc.EnableKMSEncryption(result.Target, awsinternal.SessionDataKeyGenerator(cfg.Profile, cfg.Region))
+	dc.EnableReconnect(awsinternal.SessionResumer(cfg.Profile, cfg.Region, result.SessionID))
 
-	return printRowsOrGroupBy(cfg, ec2Columns, toRows(list))
+	cmd.Printf("Starting session with SessionId: %s\n", result.SessionID)
+	if err := runConsoleSession(ctx, dc, terminate); err != nil {
+		return err
+	}
+	cmd.Printf("\nExiting session with sessionId: %s.\n", result.SessionID)
+	return nil
 }
 
-// startEC2Session starts an SSM session to an EC2 instance via session-manager-plugin.
-func startEC2Session(cmd *cobra.Command, args []string) error {
+// startEC2PortForward はローカルポートで待ち受け、SSM ポートフォワーディングセッションのデータチャネルへ
+// 接続を多重化して中継する。Ctrl+C で終了し、セッションを Terminate する。
+func startEC2PortForward(cmd *cobra.Command, args []string) error {
 	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
-	ctx := context.Background()
-	instanceID := cmd.Flag("instance-id").Value.String()
+	localPort, _ := cmd.Flags().GetInt("local")
+	remotePort, _ := cmd.Flags().GetInt("remote-port")
+	remoteHost := cmd.Flag("remote-host").Value.String()
+	if remotePort <= 0 || remotePort > 65535 {
+		return errors.New("--remote-port must be between 1 and 65535")
+	}
+	if localPort < 0 || localPort > 65535 {
+		return errors.New("--local must be between 0 and 65535")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	instanceID := cmd.Flag("instance-id").Value.String()
 	if instanceID == "" {
 		instanceID, err = selectEC2Instance(ctx, cfg)
 		if err != nil {
@@ -130,44 +215,95 @@ func startEC2Session(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	session, err := awsinternal.StartSSMSession(ctx, cfg.Profile, cfg.Region, instanceID)
+	// 待ち受けを先に確立し、ポート競合時は SSM セッションを開始せずに失敗させる。
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
 	if err != nil {
-		return fmt.Errorf("start session: %w", err)
+		return fmt.Errorf("listen on local port %d: %w", localPort, err)
 	}
+	defer listener.Close()
+	localPort = listener.Addr().(*net.TCPAddr).Port
 
-	sessJSON, err := sessionManagerSessionJSON(session.SessionID, session.StreamURL, session.TokenValue)
+	result, err := awsinternal.StartSSMPortForwardingSession(ctx, cfg.Profile, cfg.Region, instanceID, remoteHost, remotePort, localPort)
 	if err != nil {
-		return fmt.Errorf("marshal session: %w", err)
+		return fmt.Errorf("start port forwarding session: %w", err)
 	}
 
-	paramsJSON, err := util.Parser(struct {
-		Target string
-	}{Target: instanceID})
+	dc, err := session.OpenPortDataChannel(ctx, result.StreamURL, result.TokenValue, result.SessionID)
 	if err != nil {
-		return fmt.Errorf("marshal start session input: %w", err)
+		if termErr := awsinternal.TerminateSSMSession(context.Background(), cfg.Profile, cfg.Region, result.SessionID); termErr != nil {
+			cmd.PrintErrf("terminate session: %v\n", termErr)
+		}
+		return fmt.Errorf("open data channel: %w", err)
+	}
+	dc.EnableKMSEncryption(result.Target, awsinternal.SessionDataKeyGenerator(cfg.Profile, cfg.Region))
+	dc.EnableReconnect(awsinternal.SessionResumer(cfg.Profile, cfg.Region, result.SessionID))
+
+	remote := remoteHost
+	if remote == "" {
+		remote = instanceID
+	}
+	cmd.Printf("Forwarding 127.0.0.1:%d -> %s:%d via %s (session %s)\n", localPort, remote, remotePort, instanceID, result.SessionID)
+	cmd.Println("Press Ctrl+C to stop.")
+
+	forwarder := &session.PortForwarder{
+		DataChannel: dc,
+		Listener:    listener,
+		Terminate: func(ctx context.Context) error {
+			return awsinternal.TerminateSSMSession(ctx, cfg.Profile, cfg.Region, result.SessionID)
+		},
 	}
+	return forwarder.Run(ctx)
+}
 
-	plug, err := lookupSessionManagerPlugin()
+// copyEC2Files は AWS-StartInteractiveCommand で tar/base64 転送用のコマンドを実行し、インスタンスとローカルの間で
+// ファイルをコピーする。
+func copyEC2Files(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
-	ssmEndpoint := fmt.Sprintf("https://ssm.%s.amazonaws.com", cfg.Region)
-	execErr := util.ExecCommand(plug, string(sessJSON), cfg.Region, "StartSession", cfg.Profile, string(paramsJSON), ssmEndpoint)
+	spec, err := parseCopyArgs(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if execErr != nil {
-		cmd.PrintErrf("execute command: %v\n", execErr)
-		if termErr := awsinternal.TerminateSSMSession(ctx, cfg.Profile, cfg.Region, session.SessionID); termErr != nil {
-			return fmt.Errorf("terminate session after exec error: %w (original exec error: %v)", termErr, execErr)
+	if spec.target == "" {
+		spec.target, err = selectEC2Instance(ctx, cfg)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf("execute command: %w", execErr)
 	}
 
-	if err := awsinternal.TerminateSSMSession(ctx, cfg.Profile, cfg.Region, session.SessionID); err != nil {
-		return fmt.Errorf("terminate session: %w", err)
+	return runFileCopy(ctx, cmd, cfg, spec, func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.StartSSMCommandSession(ctx, cfg.Profile, cfg.Region, spec.target, command)
+	})
+}
+
+// runEC2Command は SSM Run Command で --targets に一致するインスタンスにコマンドを実行し、結果を出力する。
+func runEC2Command(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	command, _ := cmd.Flags().GetString("command")
+	targets, _ := cmd.Flags().GetStringArray("targets")
+	if strings.TrimSpace(command) == "" || len(targets) == 0 {
+		return errors.New("--command and --targets flags are required")
 	}
 
-	return nil
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	invocations, err := awsinternal.RunSSMCommand(ctx, cfg.Profile, cfg.Region, targets, command)
+	if err != nil {
+		return fmt.Errorf("run command: %w", err)
+	}
+	return printCommandResults(cfg, ssmCommandResults(invocations))
 }
 
 // selectEC2Instance は SSM 接続可能なインスタンスを対話式に選択させ、インスタンス ID を返す。
@@ -71,6 +71,8 @@ func newECRCmd() *cobra.Command {
 	imagesCmd.Flags().StringP("repo", "", "", "Repository name")
 	imagesCmd.Flags().BoolP("all", "", false, "Fetch all images across all pages")
 
+	addFanoutFlags(lsCmd)
+
 	ecrCmd.AddCommand(lsCmd, imagesCmd)
 	return ecrCmd
 }
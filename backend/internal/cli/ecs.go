@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/session"
 	"github.com/sfuruya0612/thief/backend/internal/util"
 	"github.com/spf13/cobra"
 )
@@ -88,9 +92,13 @@ func newECSCmd() *cobra.Command {
 	tasksCmd.Flags().BoolP("running", "", false, "Show only running tasks")
 
 	execCmd := &cobra.Command{
-		Use:     "exec",
-		Short:   "Execute a command in a container",
-		Long:    "Executes a command in a container running in an ECS task using AWS SSM Session Manager.",
+		Use:   "exec",
+		Short: "Execute a command in a container",
+		Long: `Executes a command in a container running in an ECS task using AWS SSM Session Manager. session-manager-plugin is not required.
+With --no-tty, the command runs non-interactively and its stdout, stderr and exit code are printed
+as a table (or as JSON / YAML / NDJSON with -o). The command exits with an error if the remote command fails.`,
+		Example: `  thief ecs exec --cluster my-cluster --task 0123456789abcdef --container app
+  thief ecs exec --cluster my-cluster --task 0123456789abcdef --container app --command 'df -h' --no-tty -o json`,
 		Aliases: []string{"e"},
 		RunE:    ecsExecuteCommand,
 	}
@@ -98,8 +106,27 @@ func newECSCmd() *cobra.Command {
 	execCmd.Flags().StringP("task", "", "", "Task name")
 	execCmd.Flags().StringP("container", "", "", "Container name")
 	execCmd.Flags().StringP("command", "", "/bin/sh", "Command")
+	execCmd.Flags().BoolP("no-tty", "", false, "Run the command non-interactively and print its output and exit code")
+
+	cpCmd := &cobra.Command{
+		Use:   "cp <src> <dst>",
+		Short: "Copy files between a container and the local machine",
+		Long: `Copies a file or directory between a container running in an ECS task and the local machine
+over ECS Exec. Specify the remote side as <task>:<path>. Downloads are extracted into the local
+directory and uploads are extracted into the remote directory. The content is transferred as a
+base64-encoded tar archive and its SHA-256 checksum is verified on arrival.
+The container must provide sh, tar, base64 and sha256sum (or shasum / openssl).`,
+		Example: `  thief ecs cp --cluster my-cluster --container app 0123456789abcdef:/tmp/heap.hprof ./dumps
+  thief ecs cp --cluster my-cluster --container app ./app.conf 0123456789abcdef:/etc/app`,
+		Args: cobra.ExactArgs(2),
+		RunE: ecsCopyFiles,
+	}
+	cpCmd.Flags().StringP("cluster", "", "", "Cluster name")
+	cpCmd.Flags().StringP("container", "", "", "Container name")
+
+	addFanoutFlags(clustersCmd)
 
-	ecsCmd.AddCommand(clustersCmd, servicesCmd, tasksCmd, execCmd)
+	ecsCmd.AddCommand(clustersCmd, servicesCmd, tasksCmd, execCmd, cpCmd)
 	return ecsCmd
 }
 
@@ -132,21 +159,21 @@ func displayECSServices(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("list ECS clusters: %w", err)
 	}
 
-	var allRows [][]string
+	var allServices []awsinternal.ECSServiceInfo
 	for _, c := range clusterArns {
 		services, err := awsinternal.GetECSServiceInfos(ctx, cfg.Profile, cfg.Region, c)
 		if err != nil {
 			return fmt.Errorf("describe ECS services for cluster %s: %w", c, err)
 		}
-		allRows = append(allRows, toRows(services)...)
+		allServices = append(allServices, services...)
 	}
 
-	if len(allRows) == 0 {
+	if len(allServices) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("No ECS services found")
 		return nil
 	}
 
-	return printRowsOrGroupBy(cfg, ecsServiceColumns, allRows)
+	return printItems(cfg, ecsServiceColumns, allServices)
 }
 
 func displayECSTasks(cmd *cobra.Command, args []string) error {
@@ -183,12 +210,12 @@ func displayECSTasks(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("list ECS tasks: %w", err)
 	}
 
-	if len(tasks) == 0 {
+	if len(tasks) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println("No ECS tasks found")
 		return nil
 	}
 
-	return printRowsOrGroupBy(cfg, ecsTaskColumns, toRows(tasks))
+	return printItems(cfg, ecsTaskColumns, tasks)
 }
 
 func ecsExecuteCommand(cmd *cobra.Command, args []string) error {
@@ -206,34 +233,92 @@ func ecsExecuteCommand(cmd *cobra.Command, args []string) error {
 		return errors.New("--cluster, --task, and --container flags are required")
 	}
 
-	ctx := context.Background()
-	session, err := awsinternal.ExecuteECSCommandSession(ctx, cfg.Profile, cfg.Region, cluster, task, container, command)
+	if noTTY, _ := cmd.Flags().GetBool("no-tty"); noTTY {
+		if !cmd.Flags().Changed("command") {
+			return errors.New("--command flag is required with --no-tty")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return ecsRunCommand(ctx, cmd, cfg, cluster, task, container, command)
+	}
+
+	// raw モードでは Ctrl+C はリモートへ送られるため、ここで受けるのは非端末入力時の割り込みと SIGTERM のみ。
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exec, err := awsinternal.ExecuteECSCommandSession(ctx, cfg.Profile, cfg.Region, cluster, task, container, command)
 	if err != nil {
 		return fmt.Errorf("execute command: %w", err)
 	}
+	terminate := func(ctx context.Context) error {
+		return awsinternal.TerminateSSMSession(ctx, cfg.Profile, cfg.Region, exec.SessionID)
+	}
 
-	sessJSON, err := sessionManagerSessionJSON(session.SessionID, session.StreamURL, session.TokenValue)
+	dc, err := session.OpenDataChannel(ctx, exec.StreamURL, exec.TokenValue, exec.SessionID)
 	if err != nil {
-		return fmt.Errorf("marshal session: %w", err)
+		if termErr := terminate(context.Background()); termErr != nil {
+			cmd.PrintErrf("terminate session: %v\n", termErr)
+		}
+		return fmt.Errorf("open data channel: %w", err)
+	}
+	dc.EnableKMSEncryption(exec.Target(), awsinternal.SessionDataKeyGenerator(cfg.Profile, cfg.Region))
+	dc.EnableReconnect(awsinternal.SessionResumer(cfg.Profile, cfg.Region, exec.SessionID))
+
+	cmd.Printf("Starting session with SessionId: %s\n", exec.SessionID)
+	if err := runConsoleSession(ctx, dc, terminate); err != nil {
+		return err
 	}
+	cmd.Printf("\nExiting session with sessionId: %s.\n", exec.SessionID)
+	return nil
+}
 
-	targetJSON, err := util.Parser(struct {
-		Target string `json:"Target"`
-	}{Target: session.Target()})
+// ecsRunCommand は ECS Exec でコマンドを非対話で実行し、標準出力・標準エラー出力・終了コードを出力する。
+// ECS Exec は PTY 付きでしか実行できないため、標準エラー出力は標準出力にまとめて返される。
+func ecsRunCommand(ctx context.Context, cmd *cobra.Command, cfg *config.Config, cluster, task, container, command string) error {
+	c, err := session.NewCommand(command)
 	if err != nil {
-		return fmt.Errorf("marshal target: %w", err)
+		return err
 	}
+	var result *session.CommandResult
+	err = runCommandSession(ctx, cmd, cfg, c.Script(), func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.ExecuteECSCommand(ctx, cfg.Profile, cfg.Region, cluster, task, container, command)
+	}, func(ctx context.Context, dc *session.DataChannel) error {
+		r, err := c.Run(ctx, dc)
+		result = r
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("execute command: %w", err)
+	}
+	return printCommandResults(cfg, []commandResult{sessionCommandResult(task, result)})
+}
 
-	plug, err := lookupSessionManagerPlugin()
+// ecsCopyFiles は ECS Exec で tar/base64 転送用のコマンドを実行し、コンテナとローカルの間でファイルをコピーする。
+func ecsCopyFiles(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
-	if err = util.ExecCommand(plug, string(sessJSON), cfg.Region, "StartSession", cfg.Profile, string(targetJSON), fmt.Sprintf("https://ecs.%s.amazonaws.com", cfg.Region)); err != nil {
-		return fmt.Errorf("execute session-manager-plugin command: %w", err)
+	cluster := cmd.Flag("cluster").Value.String()
+	container := cmd.Flag("container").Value.String()
+	if cluster == "" || container == "" {
+		return errors.New("--cluster and --container flags are required")
+	}
+	spec, err := parseCopyArgs(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if spec.target == "" {
+		return errors.New("task must be specified as <task>:<path>")
 	}
 
-	return nil
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return runFileCopy(ctx, cmd, cfg, spec, func(ctx context.Context, command string) (*awsinternal.StartSessionResult, error) {
+		return awsinternal.ExecuteECSCommand(ctx, cfg.Profile, cfg.Region, cluster, spec.target, container, command)
+	})
 }
 
 // ecsSelectItems は ARN 一覧を "/" 区切りの num 番目の要素を名前とする選択アイテムに変換する。
@@ -68,6 +68,8 @@ func newElastiCacheCmd() *cobra.Command {
 	}
 	parametersCmd.Flags().StringP("group", "", "", "Cache parameter group name")
 
+	addFanoutFlags(lsCmd)
+
 	elasticacheCmd.AddCommand(lsCmd, parametersCmd)
 	return elasticacheCmd
 }
@@ -10,7 +10,7 @@ import (
 )
 
 func newELBCmd() *cobra.Command {
-	return &cobra.Command{
+	return addFanoutFlags(&cobra.Command{
 		Use:   "elb",
 		Short: "List Elastic Load Balancers (ALB/NLB/CLB)",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -22,5 +22,5 @@ func newELBCmd() *cobra.Command {
 				},
 			})
 		},
-	}
+	})
 }
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+	"github.com/spf13/cobra"
+)
+
+// fanoutColumns は fan-out 時に各行の先頭に付ける取得元の列。
+var fanoutColumns = []util.Column{
+	{Header: "Profile"},
+	{Header: "AccountID"},
+	{Header: "Region"},
+}
+
+// addFanoutFlags は profile と region で決まるリソースの一覧コマンドに fan-out のフラグを追加する。
+func addFanoutFlags(cmd *cobra.Command) *cobra.Command {
+	cmd.Flags().StringP("profiles", "", "", "List across multiple AWS profiles (comma-separated, or \"all\" for every configured profile)")
+	cmd.Flags().BoolP("all-regions", "", false, "List across all enabled regions")
+	return cmd
+}
+
+// runFanoutList は runList の fan-out モード。cfg.Profiles (省略時は cfg.Profile) と cfg.Region
+// (cfg.AllRegions のときはプロファイルごとに有効化済みの全リージョン) の組ごとに lc.Fetch を並列に呼び出し、
+// 取得元の profile / アカウント ID / region を付けて出力する。一部の組が失敗しても残りの結果は出力し、
+// 失敗した組は標準エラー出力に書いたうえでエラーを返す (終了コードで失敗に気付けるようにするため)。
+// tags は runList と同様に各組の一覧に適用する。
+func runFanoutList[T util.Row](cmd *cobra.Command, cfg *config.Config, lc ListConfig[T], tags tagOptions) error {
+	ctx := context.Background()
+	targets, resolveErrs, err := resolveFanoutTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	items, listErrs := awsinternal.Fanout(ctx, targets, awsinternal.DefaultFanoutConcurrency,
+		func(ctx context.Context, profile, region string) ([]T, error) {
+			c := *cfg
+			c.Profile, c.Region = profile, region
+			items, err := lc.Fetch(ctx, &c)
+			return slices.DeleteFunc(items, func(item T) bool { return !tags.match(item) }), err
+		})
+	errs := append(resolveErrs, listErrs...)
+	for _, e := range errs {
+		cmd.PrintErrf("%s: %s\n", fanoutErrorTarget(e), e.Error)
+	}
+
+	if len(items) == 0 && !util.IsStructuredFormat(cfg.Output) {
+		if len(errs) == 0 {
+			cmd.Println(lc.EmptyMsg)
+		}
+	} else if err := printFanoutItems(cfg, lc.Columns, items, tags); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed on %d of %d target(s)", len(errs), len(targets)+len(resolveErrs))
+	}
+	return nil
+}
+
+// resolveFanoutTargets は cfg.Profiles (省略時は cfg.Profile) と cfg.Region (cfg.AllRegions のときは
+// プロファイルごとに有効化済みの全リージョン) から fan-out の組を解決する。
+func resolveFanoutTargets(ctx context.Context, cfg *config.Config) ([]awsinternal.FanoutTarget, []awsinternal.FanoutError, error) {
+	profiles := []string{cfg.Profile}
+	if len(cfg.Profiles) > 0 {
+		var err error
+		profiles, err = awsinternal.ResolveFanoutProfiles(cfg.Profiles)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	regions := []string{cfg.Region}
+	if cfg.AllRegions {
+		regions = []string{awsinternal.FanoutAll}
+	}
+	targets, errs := awsinternal.ResolveFanoutTargets(ctx, profiles, regions, awsinternal.DefaultFanoutResolver())
+	return targets, errs, nil
+}
+
+// printFanoutItems は fan-out の結果を出力する。表形式では fanoutColumns を先頭に付けた行を、
+// json / yaml / ndjson では profile / account_id / region と resource を持つオブジェクトを出力する。
+// --tag-columns のタグの列は末尾に付ける。
+func printFanoutItems[T util.Row](cfg *config.Config, columns []util.Column, items []awsinternal.FanoutItem[T], tags tagOptions) error {
+	return printItemsFunc(cfg, slices.Concat(fanoutColumns, columns, tags.headers()), items, func(item awsinternal.FanoutItem[T]) []string {
+		return slices.Concat([]string{item.Profile, item.AccountID, item.Region}, item.Resource.ToRow(), tags.values(item.Resource))
+	})
+}
+
+// fanoutErrorTarget は失敗した組を "profile/region" 形式で返す。リージョン一覧の取得に失敗した場合は profile のみ。
+func fanoutErrorTarget(e awsinternal.FanoutError) string {
+	if e.Region == "" {
+		return e.Profile
+	}
+	return e.Profile + "/" + e.Region
+}
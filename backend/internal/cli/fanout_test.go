@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"testing"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+)
+
+type fanoutTestRow struct {
+	Name string `json:"name"`
+}
+
+func (r fanoutTestRow) ToRow() []string { return []string{r.Name} }
+
+func TestPrintFanoutItems(t *testing.T) {
+	items := []awsinternal.FanoutItem[fanoutTestRow]{
+		{FanoutTarget: awsinternal.FanoutTarget{Profile: "prod", AccountID: "111111111111", Region: "ap-northeast-1"}, Resource: fanoutTestRow{Name: "a"}},
+		{FanoutTarget: awsinternal.FanoutTarget{Profile: "dev", Region: "us-east-1"}, Resource: fanoutTestRow{Name: "b"}},
+	}
+	columns := []util.Column{{Header: "Name"}}
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{
+			name: "csv prefixes the target columns",
+			cfg:  config.Config{Output: "csv"},
+			want: "Profile,AccountID,Region,Name\nprod,111111111111,ap-northeast-1,a\ndev,,us-east-1,b\n",
+		},
+		{
+			name: "ndjson wraps each resource with its target",
+			cfg:  config.Config{Output: "ndjson"},
+			want: `{"profile":"prod","account_id":"111111111111","region":"ap-northeast-1","resource":{"name":"a"}}` + "\n" +
+				`{"profile":"dev","account_id":"","region":"us-east-1","resource":{"name":"b"}}` + "\n",
+		},
+		{
+			name: "group-by can use the target columns",
+			cfg:  config.Config{Output: "ndjson", GroupBy: "Profile"},
+			want: `{"Profile":"dev","Count":"1"}` + "\n" + `{"Profile":"prod","Count":"1"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() error {
+				return printFanoutItems(&tt.cfg, columns, items, tagOptions{})
+			})
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanoutErrorTarget(t *testing.T) {
+	if got := fanoutErrorTarget(awsinternal.FanoutError{Profile: "prod", Region: "us-east-1"}); got != "prod/us-east-1" {
+		t.Errorf("fanoutErrorTarget = %q, want prod/us-east-1", got)
+	}
+	if got := fanoutErrorTarget(awsinternal.FanoutError{Profile: "prod"}); got != "prod" {
+		t.Errorf("fanoutErrorTarget = %q, want prod", got)
+	}
+}
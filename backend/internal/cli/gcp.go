@@ -81,6 +81,22 @@ func newGCPCmd() *cobra.Command {
 	}
 	objectsCmd.Flags().String("prefix", "", "Object name prefix filter")
 	gcsCmd.AddCommand(objectsCmd)
+	signCmd := &cobra.Command{
+		Use:   "sign <bucket> <object>",
+		Short: "Generate a V4 signed URL for an object",
+		Long: "Prints a V4 signed URL that grants the chosen method on the object until it expires (at most 7 days). " +
+			"Signing requires service account credentials or permission to sign as the ADC service account. " +
+			"The URL is printed to stdout and its expiry time to stderr.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, _ := cmd.Flags().GetString("method")
+			expires, _ := cmd.Flags().GetDuration("expires")
+			return gcpRunSign(cmd, args[0], args[1], method, expires)
+		},
+	}
+	signCmd.Flags().String("method", "GET", "HTTP method the URL allows (GET, PUT, HEAD or DELETE)")
+	signCmd.Flags().Duration("expires", gcp.DefaultSignedURLExpiry, "How long the URL is valid (e.g. 15m, 1h, 168h for 7d)")
+	gcsCmd.AddCommand(signCmd)
 
 	// iam サブコマンド
 	iamCmd := &cobra.Command{
@@ -198,10 +214,6 @@ func gcpRunProjectsRefresh(cmd *cobra.Command) error {
 }
 
 func printGCPProjects(cfg *config.Config, projects []gcp.ProjectInfo) error {
-	rows := make([][]string, len(projects))
-	for i, p := range projects {
-		rows[i] = []string{p.ProjectID, p.Name, fmt.Sprintf("%d", p.ProjectNumber), p.State, p.CreateTime}
-	}
 	cols := []util.Column{
 		{Header: "ProjectID"},
 		{Header: "Name"},
@@ -209,7 +221,9 @@ func printGCPProjects(cfg *config.Config, projects []gcp.ProjectInfo) error {
 		{Header: "State"},
 		{Header: "CreateTime"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, projects, func(p gcp.ProjectInfo) []string {
+		return []string{p.ProjectID, p.Name, fmt.Sprintf("%d", p.ProjectNumber), p.State, p.CreateTime}
+	})
 }
 
 func gcpRunCloudRun(cmd *cobra.Command) error {
@@ -225,10 +239,6 @@ func gcpRunCloudRun(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(items))
-	for i, r := range items {
-		rows[i] = []string{r.Name, r.Kind, r.Region, r.URI, r.CreateTime, r.UpdateTime}
-	}
 	cols := []util.Column{
 		{Header: "Name"},
 		{Header: "Kind"},
@@ -237,7 +247,9 @@ func gcpRunCloudRun(cmd *cobra.Command) error {
 		{Header: "CreateTime"},
 		{Header: "UpdateTime"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, items, func(r gcp.RunResourceInfo) []string {
+		return []string{r.Name, r.Kind, r.Region, r.URI, r.CreateTime, r.UpdateTime}
+	})
 }
 
 func gcpRunBuckets(cmd *cobra.Command) error {
@@ -253,10 +265,6 @@ func gcpRunBuckets(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(buckets))
-	for i, b := range buckets {
-		rows[i] = []string{b.Name, b.Location, b.StorageClass, b.CreateTime, b.UpdateTime}
-	}
 	cols := []util.Column{
 		{Header: "Name"},
 		{Header: "Location"},
@@ -264,7 +272,9 @@ func gcpRunBuckets(cmd *cobra.Command) error {
 		{Header: "CreateTime"},
 		{Header: "UpdateTime"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, buckets, func(b gcp.BucketInfo) []string {
+		return []string{b.Name, b.Location, b.StorageClass, b.CreateTime, b.UpdateTime}
+	})
 }
 
 func gcpRunIAMBindings(cmd *cobra.Command) error {
@@ -280,17 +290,15 @@ func gcpRunIAMBindings(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(bindings))
-	for i, b := range bindings {
-		rows[i] = []string{b.Member, b.Role, b.ProjectID, b.ConditionTitle}
-	}
 	cols := []util.Column{
 		{Header: "Member"},
 		{Header: "Role"},
 		{Header: "ProjectID"},
 		{Header: "ConditionTitle"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, bindings, func(b gcp.IAMBindingInfo) []string {
+		return []string{b.Member, b.Role, b.ProjectID, b.ConditionTitle}
+	})
 }
 
 func gcpRunServiceAccounts(cmd *cobra.Command) error {
@@ -306,17 +314,15 @@ func gcpRunServiceAccounts(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(accounts))
-	for i, a := range accounts {
-		rows[i] = []string{a.Email, a.DisplayName, a.Description, fmt.Sprintf("%t", a.Disabled)}
-	}
 	cols := []util.Column{
 		{Header: "Email"},
 		{Header: "DisplayName"},
 		{Header: "Description"},
 		{Header: "Disabled"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, accounts, func(a gcp.ServiceAccountInfo) []string {
+		return []string{a.Email, a.DisplayName, a.Description, fmt.Sprintf("%t", a.Disabled)}
+	})
 }
 
 func gcpRunLoggingList(cmd *cobra.Command, filter string, since time.Duration, limit int) error {
@@ -333,10 +339,6 @@ func gcpRunLoggingList(cmd *cobra.Command, filter string, since time.Duration, l
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(page.Entries))
-	for i, e := range page.Entries {
-		rows[i] = []string{e.Timestamp, e.Severity, e.LogName, e.ResourceType, e.Payload}
-	}
 	cols := []util.Column{
 		{Header: "Timestamp"},
 		{Header: "Severity"},
@@ -344,7 +346,27 @@ func gcpRunLoggingList(cmd *cobra.Command, filter string, since time.Duration, l
 		{Header: "ResourceType"},
 		{Header: "Payload"},
 	}
-	return printRowsOrGroupBy(cfg, cols, rows)
+	return printItemsFunc(cfg, cols, page.Entries, func(e gcp.LogEntryInfo) []string {
+		return []string{e.Timestamp, e.Severity, e.LogName, e.ResourceType, e.Payload}
+	})
+}
+
+func gcpRunSign(cmd *cobra.Command, bucket, key, method string, expires time.Duration) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	projectID, err := gcpRequireProjectID(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	signed, err := gcp.SignObjectURL(context.Background(), projectID, bucket, key, method, expires)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), signed.URL)
+	cmd.PrintErrf("%s URL expires at %s\n", signed.Method, signed.ExpiresAt)
+	return nil
 }
 
 func gcpRunObjects(cmd *cobra.Command, bucket, prefix string) error {
@@ -360,10 +382,6 @@ func gcpRunObjects(cmd *cobra.Command, bucket, prefix string) error {
 	if err != nil {
 		return err
 	}
-	rows := make([][]string, len(objects))
-	for i, o := range objects {
-		rows[i] = []string{o.Name, o.Bucket, fmt.Sprintf("%d", o.Size), o.ContentType, o.StorageClass, o.Updated}
-	}
 	cols := []util.Column{
 		{Header: "Name"},
 		{Header: "Bucket"},
@@ -372,7 +390,9 @@ func gcpRunObjects(cmd *cobra.Command, bucket, prefix string) error {
 		{Header: "StorageClass"},
 		{Header: "Updated"},
 	}
-	if err := printRowsOrGroupBy(cfg, cols, rows); err != nil {
+	if err := printItemsFunc(cfg, cols, objects, func(o gcp.ObjectInfo) []string {
+		return []string{o.Name, o.Bucket, fmt.Sprintf("%d", o.Size), o.ContentType, o.StorageClass, o.Updated}
+	}); err != nil {
 		return err
 	}
 	if truncated {
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"slices"
 	"strings"
 
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 	"github.com/sfuruya0612/thief/backend/internal/config"
 	"github.com/sfuruya0612/thief/backend/internal/util"
 	"github.com/spf13/cobra"
@@ -29,6 +32,15 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 	override("output", func(v string) { cfg.Output = v })
 	override("no-header", func(v string) { cfg.NoHeader = v == "true" })
 	override("group-by", func(v string) { cfg.GroupBy = v })
+	// --tag は繰り返し指定できるため、Value.String() (CSV 形式) ではなく配列として読む。
+	if f := cmd.Flag("tag"); f != nil && f.Changed {
+		cfg.Tags, _ = cmd.Flags().GetStringArray("tag")
+	}
+	override("tag-columns", func(v string) { cfg.TagColumns = awsinternal.SplitFanoutList(v) })
+	// fan-out (addFanoutFlags を持つ一覧コマンドのフラグ)。ec2 ls の --global は --all-regions の別名。
+	override("profiles", func(v string) { cfg.Profiles = awsinternal.SplitFanoutList(v) })
+	override("all-regions", func(v string) { cfg.AllRegions = v == "true" })
+	override("global", func(v string) { cfg.AllRegions = cfg.AllRegions || v == "true" })
 	// Datadog (datadog コマンドの永続フラグ)
 	override("site", func(v string) { cfg.Datadog.Site = v })
 	override("api-key", func(v string) { cfg.SetDatadogAPIKey(v) })
@@ -91,33 +103,77 @@ type ListConfig[T util.Row] struct {
 
 // runList handles the common pattern of fetching a typed list, checking for empty
 // results, and formatting output as a table.
+// --profiles / --all-regions が指定された場合は複数の profile/region にわたって取得する (runFanoutList)。
+// --tag / --tag-columns が指定された場合はタグで絞り込み、タグの値を列として追加する。
 func runList[T util.Row](cmd *cobra.Command, lc ListConfig[T]) error {
 	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
+	tags, err := newTagOptions[T](cfg)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Profiles) > 0 || cfg.AllRegions {
+		return runFanoutList(cmd, cfg, lc, tags)
+	}
 
 	items, err := lc.Fetch(context.Background(), cfg)
 	if err != nil {
 		return err
 	}
+	items = slices.DeleteFunc(items, func(item T) bool { return !tags.match(item) })
 
-	if len(items) == 0 {
+	// 構造化出力ではパイプ先で扱えるよう、空の場合もメッセージではなく空のリストを出力する。
+	if len(items) == 0 && !util.IsStructuredFormat(cfg.Output) {
 		cmd.Println(lc.EmptyMsg)
 		return nil
 	}
 
-	return printRowsOrGroupBy(cfg, lc.Columns, toRows(items))
+	return printItemsFunc(cfg, slices.Concat(lc.Columns, tags.headers()), items, func(item T) []string {
+		return append(item.ToRow(), tags.values(item)...)
+	})
+}
+
+// printItems prints items as a table, or as the full structs when cfg.Output is json/yaml/ndjson.
+func printItems[T util.Row](cfg *config.Config, columns []util.Column, items []T) error {
+	return printItemsFunc(cfg, columns, items, func(item T) []string { return item.ToRow() })
+}
+
+// printItemsFunc は util.Row を実装しない型 (gcp / tidb などのパッケージの型) 向けの printItems。
+// 表形式では row で変換した行を出力し、json / yaml / ndjson では items を JSON タグのフィールド名で
+// そのまま出力する。--group-by 指定時は集計結果を出力する。
+func printItemsFunc[T any](cfg *config.Config, columns []util.Column, items []T, row func(T) []string) error {
+	if util.IsStructuredFormat(cfg.Output) && cfg.GroupBy == "" {
+		return printStructured(cfg, items)
+	}
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = row(item)
+	}
+	return printRowsOrGroupBy(cfg, columns, rows)
+}
+
+// printStructured は v を cfg.Output (json / yaml / ndjson) の形式で標準出力に書き出す。
+func printStructured(cfg *config.Config, v any) error {
+	if err := util.WriteStructured(os.Stdout, cfg.Output, v); err != nil {
+		return fmt.Errorf("write %s output: %w", cfg.Output, err)
+	}
+	return nil
 }
 
 // printRowsOrGroupBy prints rows as a normal table, or groups by cfg.GroupBy columns if set.
 // runList を使えないコマンド ([][]string を直接組み立てる場合) からも呼ばれる。
+// json / yaml / ndjson では行を列名をキーとするオブジェクトとして出力する (構造体を持つ場合は printItems を使う)。
 func printRowsOrGroupBy(cfg *config.Config, columns []util.Column, rows [][]string) error {
 	if cfg.GroupBy != "" {
 		groupCols, grouped, err := util.GroupByColumns(columns, rows, cfg.GroupBy)
 		if err != nil {
 			return err
 		}
+		if util.IsStructuredFormat(cfg.Output) {
+			return printStructured(cfg, util.Records(groupCols, grouped))
+		}
 		f := util.NewTableFormatter(groupCols, cfg.Output)
 		if !cfg.NoHeader {
 			f.PrintHeader()
@@ -126,6 +182,9 @@ func printRowsOrGroupBy(cfg *config.Config, columns []util.Column, rows [][]stri
 		return nil
 	}
 
+	if util.IsStructuredFormat(cfg.Output) {
+		return printStructured(cfg, util.Records(columns, rows))
+	}
 	f := util.NewTableFormatter(columns, cfg.Output)
 	if !cfg.NoHeader {
 		f.PrintHeader()
@@ -1,6 +1,13 @@
 package cli
 
-import "testing"
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+)
 
 func TestStripOneTrailingNewline(t *testing.T) {
 	tests := []struct {
@@ -25,3 +32,65 @@ func TestStripOneTrailingNewline(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintItemsFuncStructured(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+		Zone string `json:"zone"`
+	}
+	items := []item{{Name: "a", Zone: "1a"}, {Name: "b", Zone: "1a"}}
+	columns := []util.Column{{Header: "Name"}, {Header: "Zone"}}
+	row := func(i item) []string { return []string{i.Name, i.Zone} }
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{
+			name: "ndjson emits structs",
+			cfg:  config.Config{Output: "ndjson"},
+			want: `{"name":"a","zone":"1a"}` + "\n" + `{"name":"b","zone":"1a"}` + "\n",
+		},
+		{
+			name: "group-by emits grouped columns",
+			cfg:  config.Config{Output: "ndjson", GroupBy: "Zone"},
+			want: `{"Zone":"1a","Count":"2"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() error {
+				return printItemsFunc(&tt.cfg, columns, items, row)
+			})
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureStdout は fn の実行中に標準出力へ書き込まれた内容を返す。
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	fnErr := fn()
+	_ = w.Close()
+	out := <-done
+	if fnErr != nil {
+		t.Fatal(fnErr)
+	}
+	return out
+}
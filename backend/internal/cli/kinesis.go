@@ -10,7 +10,7 @@ import (
 )
 
 func newKinesisCmd() *cobra.Command {
-	return &cobra.Command{
+	return addFanoutFlags(&cobra.Command{
 		Use:   "kinesis",
 		Short: "List Kinesis Data Streams",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -22,5 +22,5 @@ func newKinesisCmd() *cobra.Command {
 				},
 			})
 		},
-	}
+	})
 }
@@ -10,7 +10,7 @@ import (
 )
 
 func newLambdaCmd() *cobra.Command {
-	return &cobra.Command{
+	return addFanoutFlags(&cobra.Command{
 		Use:   "lambda",
 		Short: "List Lambda functions",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -22,5 +22,5 @@ func newLambdaCmd() *cobra.Command {
 				},
 			})
 		},
-	}
+	})
 }
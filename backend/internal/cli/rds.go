@@ -113,6 +113,9 @@ func newRDSCmd() *cobra.Command {
 	}
 	clusterParametersCmd.Flags().StringP("cluster", "", "", "DB cluster identifier")
 
+	addFanoutFlags(lsCmd)
+	addFanoutFlags(clusterCmd)
+
 	rdsCmd.AddCommand(lsCmd, clusterCmd, parametersCmd, clusterParametersCmd)
 	return rdsCmd
 }
@@ -18,9 +18,11 @@ including AWS, BigQuery, Datadog, and TiDB.`,
 	// Persistent flags available to all subcommands.
 	root.PersistentFlags().StringP("profile", "p", "", "AWS profile (default uses environment or config file)")
 	root.PersistentFlags().StringP("region", "r", "", "AWS region (default ap-northeast-1)")
-	root.PersistentFlags().StringP("output", "o", "", "Output format (tab/csv)")
+	root.PersistentFlags().StringP("output", "o", "", "Output format (tab/csv/json/yaml/ndjson)")
 	root.PersistentFlags().BoolP("no-header", "", false, "Hide the header in output")
 	root.PersistentFlags().StringP("group-by", "g", "", "Group output by column name(s) and show count (comma-separated for multiple)")
+	root.PersistentFlags().StringArrayP("tag", "", nil, "Filter resource lists by tag (key:value, key=value or key; repeatable, same key is ORed)")
+	root.PersistentFlags().StringP("tag-columns", "", "", "Add tag values as columns named tag:<key> (comma-separated keys)")
 
 	root.AddCommand(
 		// レガシー CLI と共通のコマンド群
@@ -45,6 +47,9 @@ including AWS, BigQuery, Datadog, and TiDB.`,
 		newCloudFrontCmd(),
 		newELBCmd(),
 		newLogsCmd(),
+		newSearchCmd(),
+		newAuditCmd(),
+		newWasteCmd(),
 		newGCPCmd(),
 		newServerCmd(),
 	)
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/session"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+)
+
+var commandResultColumns = []util.Column{
+	{Header: "Target"},
+	{Header: "Status"},
+	{Header: "ExitCode"},
+	{Header: "Stdout"},
+	{Header: "Stderr"},
+}
+
+// commandResult は ecs exec --no-tty / ec2 run で実行したコマンドの 1 ターゲット分の結果。
+type commandResult struct {
+	Target   string `json:"target"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// ToRow は表の 1 行に収めるため、出力の改行とタブをエスケープして返す。
+func (r commandResult) ToRow() []string {
+	return []string{r.Target, r.Status, strconv.Itoa(r.ExitCode), flattenOutput(r.Stdout), flattenOutput(r.Stderr)}
+}
+
+var outputEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`)
+
+// flattenOutput は末尾の改行を取り除き、残りの改行とタブを \n / \t と表記した 1 行の文字列にする。
+func flattenOutput(s string) string {
+	return outputEscaper.Replace(strings.TrimRight(s, "\r\n"))
+}
+
+// sessionCommandResult は SSM セッションで実行したコマンドの結果を commandResult に変換する。
+// 終了コードを取得できなかった場合のステータスは Unknown とする。
+func sessionCommandResult(target string, result *session.CommandResult) commandResult {
+	status := "Success"
+	switch {
+	case result.ExitCode == session.UnknownExitCode:
+		status = "Unknown"
+	case result.ExitCode != 0:
+		status = "Failed"
+	}
+	return commandResult{
+		Target:   target,
+		Status:   status,
+		ExitCode: result.ExitCode,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+	}
+}
+
+// ssmCommandResults は SendCommand の結果を commandResult に変換する。
+func ssmCommandResults(invocations []awsinternal.SSMCommandInvocation) []commandResult {
+	results := make([]commandResult, len(invocations))
+	for i, inv := range invocations {
+		results[i] = commandResult{
+			Target:   inv.InstanceID,
+			Status:   inv.Status,
+			ExitCode: int(inv.ExitCode),
+			Stdout:   inv.Stdout,
+			Stderr:   inv.Stderr,
+		}
+	}
+	return results
+}
+
+// printCommandResults は結果を cfg.Output の形式で標準出力に書き出す。
+// スクリプトから失敗を検出できるよう、終了コードが 0 以外のターゲットがあれば出力後にエラーを返す。
+func printCommandResults(cfg *config.Config, results []commandResult) error {
+	if err := printItems(cfg, commandResultColumns, results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d target(s)", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+func TestFlattenOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "single line", in: "ok\n", want: "ok"},
+		{name: "multiple lines", in: "a\nb\n", want: `a\nb`},
+		{name: "tabs and crlf", in: "a\tb\r\nc\r\n", want: `a\tb\r\nc`},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flattenOutput(tt.in); got != tt.want {
+				t.Errorf("flattenOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionCommandResultStatus(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		want     string
+	}{
+		{exitCode: 0, want: "Success"},
+		{exitCode: 2, want: "Failed"},
+		{exitCode: session.UnknownExitCode, want: "Unknown"},
+	}
+	for _, tt := range tests {
+		got := sessionCommandResult("task", &session.CommandResult{ExitCode: tt.exitCode})
+		if got.Status != tt.want {
+			t.Errorf("exit code %d: status = %q, want %q", tt.exitCode, got.Status, tt.want)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
 	"github.com/sfuruya0612/thief/backend/internal/config"
@@ -15,6 +17,70 @@ var s3Columns = []util.Column{
 	{Header: "CreationDate"},
 }
 
+var s3VersionColumns = []util.Column{
+	{Header: "Key"},
+	{Header: "VersionID"},
+	{Header: "Type"},
+	{Header: "Latest"},
+	{Header: "Size"},
+	{Header: "LastModified"},
+	{Header: "StorageClass"},
+}
+
+var s3CopyColumns = []util.Column{
+	{Header: "Source"},
+	{Header: "Destination"},
+}
+
+// parseS3URI は s3://bucket/key 形式の引数をバケットとキー (プレフィックス) に分ける。
+func parseS3URI(arg string) (bucket, key string, err error) {
+	rest, ok := strings.CutPrefix(arg, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("%q is not an s3://bucket/key URI", arg)
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("%q has no bucket", arg)
+	}
+	return bucket, key, nil
+}
+
+// s3RemoveTargets は rm の引数から削除対象のバケット、オブジェクト、プレフィックスを決める。
+// recursive では 1 つの URI をプレフィックスとして扱い、それ以外はすべて同じバケットのキーとして扱う。
+func s3RemoveTargets(args []string, recursive bool, versionID string) (bucket string, objects []awsinternal.S3ObjectIdentifier, prefix string, err error) {
+	switch {
+	case recursive && len(args) != 1:
+		return "", nil, "", errors.New("--recursive takes exactly one s3://bucket/prefix")
+	case recursive && versionID != "":
+		return "", nil, "", errors.New("--version-id cannot be used with --recursive")
+	case versionID != "" && len(args) != 1:
+		return "", nil, "", errors.New("--version-id takes exactly one s3://bucket/key")
+	}
+	for _, arg := range args {
+		b, key, err := parseS3URI(arg)
+		if err != nil {
+			return "", nil, "", err
+		}
+		if bucket != "" && b != bucket {
+			return "", nil, "", fmt.Errorf("all objects must be in the same bucket (%s and %s)", bucket, b)
+		}
+		bucket = b
+		switch {
+		case recursive:
+			prefix = key
+		case key == "" || strings.HasSuffix(key, "/"):
+			return "", nil, "", fmt.Errorf("%s is not an object key (use --recursive for a prefix)", arg)
+		default:
+			objects = append(objects, awsinternal.S3ObjectIdentifier{Key: key, VersionID: versionID})
+		}
+	}
+	if recursive && prefix == "" {
+		// バケット全体の削除は誤操作の影響が大きいため受け付けない。
+		return "", nil, "", errors.New("--recursive requires a non-empty prefix")
+	}
+	return bucket, objects, prefix, nil
+}
+
 func newS3Cmd() *cobra.Command {
 	s3Cmd := &cobra.Command{
 		Use:   "s3",
@@ -41,6 +107,179 @@ func newS3Cmd() *cobra.Command {
 		},
 	}
 
-	s3Cmd.AddCommand(lsCmd)
+	versionsCmd := &cobra.Command{
+		Use:   "versions s3://bucket[/prefix]",
+		Short: "List S3 object versions and delete markers",
+		Long:  "Lists the versions and delete markers of the objects under the prefix, newest first for each key.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bucket, prefix, err := parseS3URI(args[0])
+			if err != nil {
+				return err
+			}
+			return runList(cmd, ListConfig[awsinternal.S3ObjectVersionResource]{
+				Columns:  s3VersionColumns,
+				EmptyMsg: "No object versions found",
+				Fetch: func(ctx context.Context, cfg *config.Config) ([]awsinternal.S3ObjectVersionResource, error) {
+					versions, truncated, err := awsinternal.ListS3ObjectVersions(ctx, cfg.Profile, cfg.Region, bucket, prefix)
+					if truncated {
+						cmd.PrintErrln("Showing the first versions only; narrow the prefix to see more")
+					}
+					return versions, err
+				},
+			})
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore s3://bucket/key --version-id <id>",
+		Short: "Restore a previous version of an S3 object",
+		Long: "Copies the given version over the same key so that it becomes the latest version. " +
+			"This also restores an object whose latest version is a delete marker.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			bucket, key, err := parseS3URI(args[0])
+			if err != nil {
+				return err
+			}
+			versionID, _ := cmd.Flags().GetString("version-id")
+			if key == "" || versionID == "" {
+				return errors.New("an object key and --version-id are required")
+			}
+			newVersion, err := awsinternal.RestoreS3ObjectVersion(context.Background(), cfg.Profile, cfg.Region, bucket, key, versionID)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Restored s3://%s/%s from version %s (new version %s)\n", bucket, key, versionID, newVersion)
+			return nil
+		},
+	}
+	restoreCmd.Flags().String("version-id", "", "Version ID to restore")
+
+	rmCmd := &cobra.Command{
+		Use:   "rm s3://bucket/key... [--recursive] [--confirm <token>]",
+		Short: "Delete S3 objects",
+		Long: "Deletes objects in two steps: without --confirm the objects to delete and a confirmation token are printed; " +
+			"run the same command again with --confirm <token> to delete them. " +
+			"--recursive deletes every object under the prefix and --version-id permanently deletes a single version.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			versionID, _ := cmd.Flags().GetString("version-id")
+			confirm, _ := cmd.Flags().GetString("confirm")
+			bucket, objects, prefix, err := s3RemoveTargets(args, recursive, versionID)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			objects, err = awsinternal.ExpandS3Objects(ctx, cfg.Profile, cfg.Region, bucket, objects, prefix)
+			if err != nil {
+				return err
+			}
+			if len(objects) == 0 {
+				cmd.Println("No objects to delete")
+				return nil
+			}
+			token := awsinternal.S3DeleteConfirmToken(bucket, objects)
+			if confirm == "" {
+				for _, o := range objects {
+					line := "s3://" + bucket + "/" + o.Key
+					if o.VersionID != "" {
+						line += " (version " + o.VersionID + ")"
+					}
+					cmd.Println(line)
+				}
+				cmd.Printf("Re-run with --confirm %s to delete %d objects\n", token, len(objects))
+				return nil
+			}
+			if confirm != token {
+				return errors.New("objects to delete have changed since the confirmation token was issued; re-run without --confirm")
+			}
+			if err := awsinternal.DeleteS3Objects(ctx, cfg.Profile, cfg.Region, bucket, objects); err != nil {
+				return err
+			}
+			cmd.Printf("Deleted %d objects from s3://%s\n", len(objects), bucket)
+			return nil
+		},
+	}
+	rmCmd.Flags().Bool("recursive", false, "Delete every object under the prefix")
+	rmCmd.Flags().String("version-id", "", "Permanently delete this version of the object")
+	rmCmd.Flags().String("confirm", "", "Confirmation token printed by a previous run without --confirm")
+
+	presignCmd := &cobra.Command{
+		Use:   "presign s3://bucket/key [--method GET|PUT|HEAD|DELETE] [--expires 15m]",
+		Short: "Generate a presigned URL for an S3 object",
+		Long: "Prints a presigned URL that grants the chosen method on the object until it expires (at most 7 days). " +
+			"The URL is printed to stdout and its expiry time to stderr.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			bucket, key, err := parseS3URI(args[0])
+			if err != nil {
+				return err
+			}
+			method, _ := cmd.Flags().GetString("method")
+			expires, _ := cmd.Flags().GetDuration("expires")
+			presigned, err := awsinternal.PresignS3Object(context.Background(), cfg.Profile, cfg.Region, bucket, key, method, expires)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), presigned.URL)
+			cmd.PrintErrf("%s URL expires at %s\n", presigned.Method, presigned.ExpiresAt)
+			return nil
+		},
+	}
+	presignCmd.Flags().String("method", "GET", "HTTP method the URL allows (GET, PUT, HEAD or DELETE)")
+	presignCmd.Flags().Duration("expires", awsinternal.DefaultS3PresignExpiry, "How long the URL is valid (e.g. 15m, 1h, 168h for 7d)")
+
+	s3Cmd.AddCommand(lsCmd, versionsCmd, restoreCmd, rmCmd, newS3CopyCmd(false), newS3CopyCmd(true), presignCmd)
 	return s3Cmd
 }
+
+// newS3CopyCmd は s3 cp (move なら s3 mv) コマンドを作る。
+func newS3CopyCmd(move bool) *cobra.Command {
+	use, short, verb := "cp", "Copy S3 objects", "Copies"
+	if move {
+		use, short, verb = "mv", "Move S3 objects", "Moves"
+	}
+	return &cobra.Command{
+		Use:   use + " s3://bucket/src s3://bucket/dst",
+		Short: short,
+		Long: verb + " an object, or every object under a prefix ending with \"/\", to another key or prefix in the same or another bucket. " +
+			"A destination ending with \"/\" keeps the file name (or the relative paths under the source prefix).",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			srcBucket, src, err := parseS3URI(args[0])
+			if err != nil {
+				return err
+			}
+			dstBucket, dst, err := parseS3URI(args[1])
+			if err != nil {
+				return err
+			}
+			copied, err := awsinternal.CopyS3Objects(context.Background(), cfg.Profile, cfg.Region, srcBucket, src, dstBucket, dst, move)
+			if len(copied) > 0 {
+				if perr := printItems(cfg, s3CopyColumns, copied); perr != nil {
+					return perr
+				}
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+)
+
+func TestParseS3URI(t *testing.T) {
+	tests := []struct {
+		arg        string
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{arg: "s3://bucket/path/to/file.txt", wantBucket: "bucket", wantKey: "path/to/file.txt"},
+		{arg: "s3://bucket/logs/", wantBucket: "bucket", wantKey: "logs/"},
+		{arg: "s3://bucket", wantBucket: "bucket"},
+		{arg: "bucket/key", wantErr: true},
+		{arg: "s3:///key", wantErr: true},
+	}
+	for _, tt := range tests {
+		bucket, key, err := parseS3URI(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseS3URI(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if bucket != tt.wantBucket || key != tt.wantKey {
+			t.Errorf("parseS3URI(%q) = %q, %q, want %q, %q", tt.arg, bucket, key, tt.wantBucket, tt.wantKey)
+		}
+	}
+}
+
+func TestS3RemoveTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		recursive   bool
+		versionID   string
+		wantObjects []awsinternal.S3ObjectIdentifier
+		wantPrefix  string
+		wantErr     bool
+	}{
+		{
+			name:        "keys",
+			args:        []string{"s3://b/a.txt", "s3://b/dir/c.txt"},
+			wantObjects: []awsinternal.S3ObjectIdentifier{{Key: "a.txt"}, {Key: "dir/c.txt"}},
+		},
+		{
+			name:        "version",
+			args:        []string{"s3://b/a.txt"},
+			versionID:   "v1",
+			wantObjects: []awsinternal.S3ObjectIdentifier{{Key: "a.txt", VersionID: "v1"}},
+		},
+		{name: "recursive", args: []string{"s3://b/logs/"}, recursive: true, wantPrefix: "logs/"},
+		{name: "recursive bucket root", args: []string{"s3://b"}, recursive: true, wantErr: true},
+		{name: "recursive with version", args: []string{"s3://b/logs/"}, recursive: true, versionID: "v1", wantErr: true},
+		{name: "prefix without recursive", args: []string{"s3://b/logs/"}, wantErr: true},
+		{name: "different buckets", args: []string{"s3://b/a", "s3://c/a"}, wantErr: true},
+		{name: "version for many keys", args: []string{"s3://b/a", "s3://b/c"}, versionID: "v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, objects, prefix, err := s3RemoveTargets(tt.args, tt.recursive, tt.versionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if bucket != "b" || prefix != tt.wantPrefix || !slices.Equal(objects, tt.wantObjects) {
+				t.Errorf("got %q, %v, %q, want b, %v, %q", bucket, objects, prefix, tt.wantObjects, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/search"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var searchColumns = []util.Column{
+	{Header: "Service"},
+	{Header: "Profile"},
+	{Header: "Region"},
+	{Header: "Name"},
+	{Header: "ID"},
+	{Header: "State"},
+}
+
+// searchSource は thief search が一覧を取得するサービス。service は API サーバのキャッシュキーと同じ名前にする
+// (GET /api/search の結果と service の値を揃えるため)。global なサービスはリージョンに依存しないため、
+// プロファイルごとに 1 回だけ取得する。
+type searchSource struct {
+	service string
+	global  bool
+	list    func(ctx context.Context, profile, region string) (any, error)
+}
+
+// searchList は awsinternal の一覧関数を searchSource.list に変換する。
+func searchList[T any](list func(context.Context, string, string) (T, error)) func(context.Context, string, string) (any, error) {
+	return func(ctx context.Context, profile, region string) (any, error) {
+		return list(ctx, profile, region)
+	}
+}
+
+var searchSources = []searchSource{
+	{service: "ec2", list: searchList(awsinternal.ListEC2Resources)},
+	{service: "rds", list: searchList(awsinternal.ListRDSResources)},
+	{service: "elasticache", list: searchList(awsinternal.ListElastiCacheResources)},
+	{service: "lambda", list: searchList(awsinternal.ListLambdaResources)},
+	{service: "ecs", list: searchList(awsinternal.ListECSResources)},
+	{service: "ecr", list: searchList(awsinternal.ListECRResources)},
+	{service: "dynamo", list: searchList(awsinternal.ListDynamoResources)},
+	{service: "sqs", list: searchList(awsinternal.ListSQSResources)},
+	{service: "kinesis", list: searchList(awsinternal.ListKinesisResources)},
+	{service: "elb", list: searchList(awsinternal.ListELBResources)},
+	{service: "apigw", list: searchList(awsinternal.ListAPIGatewayResources)},
+	{service: "natgw", list: searchList(awsinternal.ListNATGatewayResources)},
+	{service: "cfn", list: searchList(awsinternal.ListCFNStacks)},
+	{service: "waf", list: searchList(awsinternal.ListWAFResources)},
+	{service: "secretsmanager-list", list: searchList(awsinternal.ListSecretResources)},
+	{service: "ssm-list", list: searchList(awsinternal.ListSSMParameters)},
+	{service: "cwlogs-groups", list: searchList(awsinternal.ListLogGroups)},
+	{service: "s3", global: true, list: searchList(awsinternal.ListS3Resources)},
+	{service: "iam", global: true, list: searchList(awsinternal.ListIAMResources)},
+	{service: "cloudfront", global: true, list: searchList(awsinternal.ListCloudFrontResources)},
+}
+
+func newSearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search resources across services, profiles and regions",
+		Long: `Lists resources of the selected services and prints the ones whose name, ID, ARN or tags
+match every word of the query (case-insensitive), best matches first. A word of the form key=value
+matches a tag exactly. Use --profiles and --all-regions to search across profiles and regions.
+Services that cannot be listed (e.g. missing permissions) are reported on stderr and skipped.`,
+		Example: `  thief search payment-api
+  thief search team=payments --services ecs,lambda --profiles all --all-regions`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runSearch,
+	}
+	names := make([]string, len(searchSources))
+	for i, src := range searchSources {
+		names[i] = src.service
+	}
+	cmd.Flags().StringP("services", "", "", "Services to search (comma-separated: "+strings.Join(names, ", ")+"; default all)")
+	cmd.Flags().IntP("limit", "", 50, "Maximum number of results (0 for unlimited)")
+	return addFanoutFlags(cmd)
+}
+
+// runSearch は選択したサービスの一覧を profile/region の組ごとに並列に取得して search.Index に取り込み、
+// クエリに一致したリソースを出力する。取得に失敗した組は標準エラー出力に書いて読み飛ばし、
+// すべての組が失敗した場合のみエラーを返す。
+func runSearch(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	sources, err := selectSearchSources(cmd.Flag("services").Value.String())
+	if err != nil {
+		return err
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	ctx := context.Background()
+	targets, resolveErrs, err := resolveFanoutTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	for _, e := range resolveErrs {
+		cmd.PrintErrf("%s: %s\n", fanoutErrorTarget(e), e.Error)
+	}
+
+	index := search.New()
+	var mu sync.Mutex
+	var failed, total int
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(awsinternal.DefaultFanoutConcurrency)
+	listed := map[string]bool{}
+	for _, t := range targets {
+		for _, src := range sources {
+			// global なサービスはプロファイルの最初の組 (リージョン) でだけ取得する。
+			if src.global {
+				if listed[src.service+":"+t.Profile] {
+					continue
+				}
+				listed[src.service+":"+t.Profile] = true
+			}
+			total++
+			g.Go(func() error {
+				v, err := src.list(gctx, t.Profile, t.Region)
+				if err != nil {
+					mu.Lock()
+					failed++
+					cmd.PrintErrf("%s %s/%s: %v\n", src.service, t.Profile, t.Region, err)
+					mu.Unlock()
+					return nil
+				}
+				origin := search.Origin{Service: src.service, Profile: t.Profile, Region: t.Region}
+				index.Put(strings.Join([]string{src.service, t.Profile, t.Region}, ":"), time.Time{}, origin, v)
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+	if total > 0 && failed == total {
+		return fmt.Errorf("failed to list all %d service target(s)", total)
+	}
+
+	results := index.Search(search.Query{Text: strings.Join(args, " "), Limit: limit})
+	if len(results) == 0 && !util.IsStructuredFormat(cfg.Output) {
+		cmd.Println("No matching resources found")
+		return nil
+	}
+	return printItemsFunc(cfg, searchColumns, results, func(r search.Result) []string {
+		return []string{r.Service, r.Profile, r.Region, r.Name, r.ID, r.State}
+	})
+}
+
+// selectSearchSources は --services の指定 (カンマ区切り、空なら全サービス) を searchSources に解決する。
+func selectSearchSources(v string) ([]searchSource, error) {
+	names := awsinternal.SplitFanoutList(v)
+	if len(names) == 0 {
+		return searchSources, nil
+	}
+	var sources []searchSource
+	for _, name := range names {
+		i := slices.IndexFunc(searchSources, func(src searchSource) bool { return src.service == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown service %q", name)
+		}
+		sources = append(sources, searchSources[i])
+	}
+	return sources, nil
+}
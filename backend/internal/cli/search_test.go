@@ -0,0 +1,22 @@
+package cli
+
+import "testing"
+
+func TestSelectSearchSources(t *testing.T) {
+	all, err := selectSearchSources("")
+	if err != nil || len(all) != len(searchSources) {
+		t.Fatalf("selectSearchSources(\"\") = (%d sources, %v), want all %d", len(all), err, len(searchSources))
+	}
+
+	got, err := selectSearchSources("lambda, s3,lambda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].service != "lambda" || got[1].service != "s3" || !got[1].global {
+		t.Errorf("selectSearchSources = %+v, want lambda and global s3", got)
+	}
+
+	if _, err := selectSearchSources("ec2,nope"); err == nil {
+		t.Error("expected an error for an unknown service")
+	}
+}
@@ -63,6 +63,8 @@ func newSecretsManagerCmd() *cobra.Command {
 	}
 	putCmd.Flags().StringP("value", "", "", "New secret value (if omitted, read from stdin)")
 
+	addFanoutFlags(lsCmd)
+
 	secretsCmd.AddCommand(lsCmd, putCmd)
 	return secretsCmd
 }
@@ -71,6 +71,9 @@ func newSSMCmd() *cobra.Command {
 				return err
 			}
 
+			if util.IsStructuredFormat(cfg.Output) {
+				return printStructured(cfg, param)
+			}
 			return printRowsOrGroupBy(cfg, ssmParamGetColumns, [][]string{param.ToRow()})
 		},
 	}
@@ -101,6 +104,8 @@ func newSSMCmd() *cobra.Command {
 	}
 	putCmd.Flags().StringP("value", "", "", "New parameter value (if omitted, read from stdin)")
 
+	addFanoutFlags(lsCmd)
+
 	paramCmd.AddCommand(lsCmd, getCmd, putCmd)
 	ssmCmd.AddCommand(paramCmd)
 	return ssmCmd
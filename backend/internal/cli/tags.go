@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+)
+
+// tagColumnPrefix は --tag-columns で追加する列のヘッダの接頭辞。--group-by tag:team のように集計にも使える。
+const tagColumnPrefix = "tag:"
+
+// tagOptions は一覧コマンドの --tag (絞り込み) と --tag-columns (列の追加) の指定。
+type tagOptions struct {
+	filters []awsinternal.TagFilter
+	columns []string
+}
+
+// newTagOptions は cfg.Tags / cfg.TagColumns を解釈する。一覧の型 T がタグを持たない (awsinternal.Tagged を
+// 実装しない) コマンドでこれらが指定された場合は、黙って無視せずエラーにする。
+func newTagOptions[T any](cfg *config.Config) (tagOptions, error) {
+	if len(cfg.Tags) == 0 && len(cfg.TagColumns) == 0 {
+		return tagOptions{}, nil
+	}
+	var zero T
+	if _, ok := any(zero).(awsinternal.Tagged); !ok {
+		return tagOptions{}, fmt.Errorf("--tag and --tag-columns are not supported by this command")
+	}
+	filters, err := awsinternal.ParseTagFilters(cfg.Tags)
+	if err != nil {
+		return tagOptions{}, err
+	}
+	return tagOptions{filters: filters, columns: cfg.TagColumns}, nil
+}
+
+// match は item のタグが --tag の条件をすべて満たすかを返す。
+func (o tagOptions) match(item any) bool {
+	if len(o.filters) == 0 {
+		return true
+	}
+	t, ok := item.(awsinternal.Tagged)
+	return ok && awsinternal.MatchTags(t.ResourceTags(), o.filters)
+}
+
+// headers は --tag-columns で追加する列を返す。
+func (o tagOptions) headers() []util.Column {
+	cols := make([]util.Column, len(o.columns))
+	for i, key := range o.columns {
+		cols[i] = util.Column{Header: tagColumnPrefix + key}
+	}
+	return cols
+}
+
+// values は item の --tag-columns のタグの値を返す。タグがない場合は空文字列。
+func (o tagOptions) values(item any) []string {
+	vals := make([]string, len(o.columns))
+	t, ok := item.(awsinternal.Tagged)
+	if !ok {
+		return vals
+	}
+	tags := t.ResourceTags()
+	for i, key := range o.columns {
+		vals[i] = tags[key]
+	}
+	return vals
+}
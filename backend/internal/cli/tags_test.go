@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/config"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+)
+
+type taggedTestRow struct {
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags"`
+}
+
+func (r taggedTestRow) ToRow() []string                 { return []string{r.Name} }
+func (r taggedTestRow) ResourceTags() map[string]string { return r.Tags }
+
+func TestNewTagOptions(t *testing.T) {
+	if _, err := newTagOptions[fanoutTestRow](&config.Config{Tags: []string{"team:payments"}}); err == nil {
+		t.Error("expected an error for a type without tags")
+	}
+	if _, err := newTagOptions[fanoutTestRow](&config.Config{}); err != nil {
+		t.Errorf("unexpected error without tag flags: %v", err)
+	}
+	if _, err := newTagOptions[taggedTestRow](&config.Config{Tags: []string{":x"}}); !errors.Is(err, awsinternal.ErrInvalidTagFilter) {
+		t.Errorf("err = %v, want ErrInvalidTagFilter", err)
+	}
+
+	o, err := newTagOptions[taggedTestRow](&config.Config{Tags: []string{"team:payments", "env"}, TagColumns: []string{"team", "owner"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []taggedTestRow{
+		{Name: "a", Tags: map[string]string{"team": "payments", "env": "prod", "owner": "alice"}},
+		{Name: "b", Tags: map[string]string{"team": "payments"}},
+		{Name: "c", Tags: map[string]string{"team": "search", "env": "prod"}},
+	}
+	got := slices.DeleteFunc(slices.Clone(items), func(item taggedTestRow) bool { return !o.match(item) })
+	if len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("filtered = %+v, want only a", got)
+	}
+	if h := o.headers(); len(h) != 2 || h[0].Header != "tag:team" || h[1].Header != "tag:owner" {
+		t.Errorf("headers = %+v, want tag:team, tag:owner", h)
+	}
+	if v := o.values(items[1]); !slices.Equal(v, []string{"payments", ""}) {
+		t.Errorf("values = %q, want [payments \"\"]", v)
+	}
+}
+
+func TestPrintFanoutItemsTagColumns(t *testing.T) {
+	items := []awsinternal.FanoutItem[taggedTestRow]{
+		{FanoutTarget: awsinternal.FanoutTarget{Profile: "prod"}, Resource: taggedTestRow{Name: "a", Tags: map[string]string{"team": "payments"}}},
+		{FanoutTarget: awsinternal.FanoutTarget{Profile: "dev"}, Resource: taggedTestRow{Name: "b", Tags: map[string]string{"team": "payments"}}},
+		{FanoutTarget: awsinternal.FanoutTarget{Profile: "dev"}, Resource: taggedTestRow{Name: "c"}},
+	}
+	tags := tagOptions{columns: []string{"team"}}
+	columns := []util.Column{{Header: "Name"}}
+
+	got := captureStdout(t, func() error {
+		return printFanoutItems(&config.Config{Output: "csv"}, columns, items, tags)
+	})
+	want := "Profile,AccountID,Region,Name,tag:team\nprod,,,a,payments\ndev,,,b,payments\ndev,,,c,\n"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	got = captureStdout(t, func() error {
+		return printFanoutItems(&config.Config{Output: "ndjson", GroupBy: "tag:team"}, columns, items, tags)
+	})
+	want = `{"tag:team":"","Count":"1"}` + "\n" + `{"tag:team":"payments","Count":"2"}` + "\n"
+	if got != want {
+		t.Errorf("group-by output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+// runConsoleSession はデータチャネルをローカル端末 (標準入出力) に接続して対話セッションを実行する。
+// 標準入力が端末の場合は raw モードに切り替え (Ctrl+C 等もリモートへそのまま送る)、終了時に元に戻す。
+func runConsoleSession(ctx context.Context, dc *session.DataChannel, terminate session.TerminateFunc) error {
+	stdinFd := os.Stdin.Fd()
+	if term.IsTerminal(stdinFd) {
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("set terminal raw mode: %w", err)
+		}
+		defer func() {
+			_ = term.Restore(stdinFd, state)
+		}()
+	}
+
+	resize := make(chan session.TerminalSize, 1)
+	stopWatch := watchTerminalSize(os.Stdout.Fd(), resize)
+	defer stopWatch()
+
+	console := &session.Console{
+		DataChannel: dc,
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Resize:      resize,
+		Terminate:   terminate,
+	}
+	return console.Run(ctx)
+}
+
+// terminalSize は fd が端末であればそのサイズを返す。
+func terminalSize(fd uintptr) (session.TerminalSize, bool) {
+	if !term.IsTerminal(fd) {
+		return session.TerminalSize{}, false
+	}
+	w, h, err := term.GetSize(fd)
+	if err != nil || w <= 0 || h <= 0 {
+		return session.TerminalSize{}, false
+	}
+	return session.TerminalSize{Cols: uint32(w), Rows: uint32(h)}, true
+}
+
+// sendLatestSize は未送信の古いサイズを捨てて最新のサイズだけを resize に積む。
+// 受信側 (Console) がハンドシェイク待ちの間に複数回リサイズされても、最後のサイズが必ず届くようにする。
+func sendLatestSize(resize chan session.TerminalSize, size session.TerminalSize) {
+	for {
+		select {
+		case resize <- size:
+			return
+		default:
+		}
+		select {
+		case <-resize:
+		default:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+// watchTerminalSize は初期サイズを resize に積み、以降は SIGWINCH を受けるたびに最新サイズを積む。
+// 戻り値の関数で監視を停止する。fd が端末でない場合は何も送らない。
+func watchTerminalSize(fd uintptr, resize chan session.TerminalSize) func() {
+	if size, ok := terminalSize(fd); ok {
+		sendLatestSize(resize, size)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				if size, ok := terminalSize(fd); ok {
+					sendLatestSize(resize, size)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package cli
+
+import (
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/session"
+)
+
+// terminalSizePollInterval は Windows で端末サイズの変化を確認する間隔 (SIGWINCH が無いためポーリングする)。
+const terminalSizePollInterval = 500 * time.Millisecond
+
+// watchTerminalSize は初期サイズを resize に積み、以降はサイズが変わるたびに最新サイズを積む。
+// 戻り値の関数で監視を停止する。fd が端末でない場合は何も送らない。
+func watchTerminalSize(fd uintptr, resize chan session.TerminalSize) func() {
+	last, ok := terminalSize(fd)
+	if ok {
+		sendLatestSize(resize, last)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(terminalSizePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if size, ok := terminalSize(fd); ok && size != last {
+					last = size
+					sendLatestSize(resize, size)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -108,19 +108,16 @@ func listTidbProjects(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var items [][]string
-	for _, p := range projects {
-		items = append(items, []string{
+	return printItemsFunc(cfg, tidbProjectColumns, projects, func(p tidb.Project) []string {
+		return []string{
 			p.ID,
 			p.OrgID,
 			p.Name,
 			fmt.Sprintf("%d", p.ClusterCount),
 			fmt.Sprintf("%d", p.UserCount),
 			tidbTimeString(p.CreatedAt),
-		})
-	}
-
-	return printRowsOrGroupBy(cfg, tidbProjectColumns, items)
+		}
+	})
 }
 
 func listTidbClusters(cmd *cobra.Command, args []string) error {
@@ -149,26 +146,26 @@ func listTidbClusters(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	var items [][]string
+	var clusters []tidb.Cluster
 	for _, projectID := range projectIDs {
-		clusters, err := client.ListClusters(projectID)
+		cs, err := client.ListClusters(projectID)
 		if err != nil {
 			return fmt.Errorf("failed to get clusters for project %s: %w", projectID, err)
 		}
-		for _, c := range clusters {
-			items = append(items, []string{
-				c.ID,
-				c.Name,
-				c.Status,
-				c.Region,
-				c.CloudProvider,
-				c.ClusterType,
-				tidbTimeString(c.CreatedAt),
-			})
+		clusters = append(clusters, cs...)
+	}
+
+	return printItemsFunc(cfg, tidbClusterColumns, clusters, func(c tidb.Cluster) []string {
+		return []string{
+			c.ID,
+			c.Name,
+			c.Status,
+			c.Region,
+			c.CloudProvider,
+			c.ClusterType,
+			tidbTimeString(c.CreatedAt),
 		}
-	}
-
-	return printRowsOrGroupBy(cfg, tidbClusterColumns, items)
+	})
 }
 
 func showTidbCost(cmd *cobra.Command, args []string) error {
@@ -191,9 +188,8 @@ func showTidbCost(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var items [][]string
-	for _, c := range costs {
-		items = append(items, []string{
+	return printItemsFunc(cfg, tidbCostColumns, costs, func(c tidb.Cost) []string {
+		return []string{
 			c.BilledDate,
 			c.ProjectName,
 			c.ClusterName,
@@ -202,8 +198,6 @@ func showTidbCost(cmd *cobra.Command, args []string) error {
 			c.DiscountsRaw,
 			c.RunningTotalRaw,
 			c.TotalCostRaw,
-		})
-	}
-
-	return printRowsOrGroupBy(cfg, tidbCostColumns, items)
+		}
+	})
 }
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+	awsinternal "github.com/sfuruya0612/thief/backend/internal/aws"
+	"github.com/sfuruya0612/thief/backend/internal/util"
+	"github.com/spf13/cobra"
+)
+
+var wasteColumns = []util.Column{
+	{Header: "Service"},
+	{Header: "AccountID"},
+	{Header: "Profile"},
+	{Header: "Region"},
+	{Header: "Rule"},
+	{Header: "Name"},
+	{Header: "ID"},
+	{Header: "Reason"},
+	{Header: "MonthlySavingUSD"},
+}
+
+// wasteSources は audit.WasteServices の一覧関数。ecr-images は全リポジトリのイメージで、searchSources にはない。
+// 節約額に使う一覧は、API サーバの一覧と同じくリージョンのレート表から CostMonthly を設定する。
+var wasteSources = map[string]func(ctx context.Context, profile, region string) (any, error){
+	"ec2":           searchList(awsinternal.ListEC2Resources),
+	"natgw":         wasteList(awsinternal.ListNATGatewayResources, "natgw", awsinternal.ApplyNATGatewayCostEstimates),
+	"sqs":           searchList(awsinternal.ListSQSResources),
+	"ecr-images":    wasteList(awsinternal.ListAllECRImages, "ecr", awsinternal.ApplyECRImageCostEstimates),
+	"cwlogs-groups": wasteList(awsinternal.ListLogGroups, "cwlogs", awsinternal.ApplyLogGroupCostEstimates),
+}
+
+// wasteList は list の一覧に、region の pricing のレート表 (pricing の service) で apply が推定月額を設定する
+// 一覧関数を返す。
+func wasteList[T any](list func(context.Context, string, string) ([]T, error), pricing string, apply func([]T, *awsinternal.PriceTable)) func(context.Context, string, string) (any, error) {
+	return func(ctx context.Context, profile, region string) (any, error) {
+		resources, err := list(ctx, profile, region)
+		if err != nil {
+			return nil, err
+		}
+		apply(resources, wastePriceTable(ctx, profile, region, pricing))
+		return resources, nil
+	}
+}
+
+// wastePriceTables は region/service ごとのレート表の取得。レート表はアカウントによらないため、同じ region の
+// profile 間で 1 回の取得を共有する。
+var wastePriceTables sync.Map
+
+// wastePriceTable は region の service のレート表を返す。取得できない (pricing:GetProducts の権限がない等)
+// 場合は nil を返し、その一覧の節約額は 0 になる。
+func wastePriceTable(ctx context.Context, profile, region, service string) *awsinternal.PriceTable {
+	get, _ := wastePriceTables.LoadOrStore(region+"/"+service, sync.OnceValue(func() *awsinternal.PriceTable {
+		table, err := awsinternal.GetPricing(ctx, profile, region, service)
+		if err != nil {
+			return nil
+		}
+		return table
+	}))
+	return get.(func() *awsinternal.PriceTable)()
+}
+
+func newWasteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "waste",
+		Short: "List likely idle resources with an estimated monthly saving",
+		Long: `Lists stopped EC2 instances, NAT gateways in VPCs without running instances, SQS queues
+without messages, ECR images never pulled for 30 days after push and log groups without retention,
+ordered by the estimated monthly saving (USD). NAT gateway hours, ECR storage and CloudWatch Logs
+storage savings use each region's on-demand prices from the AWS Price List (pricing:GetProducts is
+required; otherwise they are 0). Stopped instances and empty queues report 0 because their EBS volumes
+and request charges are not listed. Use --profiles and --all-regions to check several accounts and
+regions.`,
+		Example: `  thief waste
+  thief waste --profiles all --all-regions -o csv > waste.csv`,
+		RunE: runWaste,
+	}
+	addFanoutFlags(cmd)
+	return cmd
+}
+
+// runWaste は profile/region ごとに一覧を取得し、使われていない可能性が高いリソースと節約見込みの合計を出力する。
+// 一部の組が失敗しても残りの結果は出力し、失敗した組は標準エラー出力に書いたうえでエラーを返す (runFanoutList と同じ)。
+func runWaste(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	targets, resolveErrs, err := resolveFanoutTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	findings, errs := audit.CollectWaste(ctx, targets, auditSourceList(wasteSources), time.Now())
+	failure := reportAuditErrors(cmd, resolveErrs, errs, len(targets)*len(audit.WasteServices))
+
+	if util.IsStructuredFormat(cfg.Output) {
+		if err := printItems(cfg, wasteColumns, findings); err != nil {
+			return err
+		}
+		return failure
+	}
+	if len(findings) == 0 {
+		// 一部の取得に失敗した場合は無駄なリソースがないとは言えないため、該当なしのメッセージは出さない。
+		if failure == nil {
+			cmd.Println("No idle resources found")
+		}
+		return failure
+	}
+	if err := printItems(cfg, wasteColumns, findings); err != nil {
+		return err
+	}
+	cmd.Printf("Estimated monthly saving: $%.2f (%d resource(s))\n", audit.TotalMonthlySaving(findings), len(findings))
+	return failure
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/sfuruya0612/thief/backend/internal/audit"
+)
+
+// runWaste は wasteSources の一覧関数を使うため、検出に使うサービスはすべて wasteSources にあること。
+func TestWasteServicesHaveSources(t *testing.T) {
+	for _, service := range audit.WasteServices {
+		if wasteSources[service] == nil {
+			t.Errorf("service %q has no wasteSource", service)
+		}
+	}
+	if len(wasteSources) != len(audit.WasteServices) {
+		t.Errorf("wasteSources has %d services, want %d", len(wasteSources), len(audit.WasteServices))
+	}
+}
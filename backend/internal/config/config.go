@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -24,6 +25,16 @@ type Config struct {
 	NoHeader bool   `yaml:"no-header"`
 	GroupBy  string `yaml:"-"`
 
+	// Profiles / AllRegions は一覧コマンドの fan-out 指定 (--profiles / --all-regions)。
+	// 複数のプロファイル / 全リージョンにわたって一覧を取得する CLI 専用の指定で、設定ファイルからは読まない。
+	Profiles   []string `yaml:"-"`
+	AllRegions bool     `yaml:"-"`
+
+	// Tags / TagColumns は一覧コマンドのタグ指定 (--tag / --tag-columns)。Tags はタグによる絞り込み
+	// ("key:value" / "key=value" / "key")、TagColumns は列として追加するタグのキー。
+	Tags       []string `yaml:"-"`
+	TagColumns []string `yaml:"-"`
+
 	// ListenAddr は API サーバの listen アドレス。`thief server` サブコマンド専用で
 	// 他のサブコマンドからは参照されない。
 	ListenAddr string `yaml:"listen-addr"`
@@ -40,6 +51,28 @@ type Config struct {
 	// (internal/pricecache 参照)。
 	PriceCacheDir string `yaml:"price-cache-dir"`
 
+	// ResourceCacheDir はリソース一覧のキャッシュ (API サーバの resourceCache) を永続化する
+	// ディレクトリ。空の場合はメモリのみで保持し、サーバを再起動するとキャッシュは失われる。API サーバ専用。
+	ResourceCacheDir string `yaml:"resource-cache-dir"`
+
+	// ResourceCacheMaxMB は resourceCache がメモリに保持するエントリの上限 (MB、JSON にしたときのサイズで計算)。
+	// 超えた場合は最も長く参照されていないエントリから追い出す (永続化していればディスクには残る)。
+	// 0 の場合は上限なし。API サーバ専用。
+	ResourceCacheMaxMB int `yaml:"resource-cache-max-mb"`
+
+	// CacheStaleTTL は resourceCache のエントリを期限切れ後も STALE として返し続ける時間。この間のリクエストは
+	// 古い値を即座に受け取り、再取得はバックグラウンドで 1 回だけ行う。0 の場合は無効 (期限切れ後の
+	// 最初のリクエストが再取得を待つ)。API サーバ専用で、既定は 0 (opt-in)。
+	CacheStaleTTL time.Duration `yaml:"-"`
+
+	// Prewarm はサーバが定期的に取得して resourceCache を温めておくリソースの一覧。
+	// 各要素は "profile/region/service" (例: "prod/ap-northeast-1/ecs") 形式。API サーバ専用。
+	Prewarm []string `yaml:"prewarm"`
+
+	// PrewarmInterval は Prewarm の対象を確認する間隔。期限切れまでの残りがこの間隔以下のエントリを
+	// 再取得するため、resourceCache の TTL (1 時間) より短くしておけば期限切れになる前に置き換わる。API サーバ専用。
+	PrewarmInterval time.Duration `yaml:"-"`
+
 	// S3PathStyle は S3 クライアントを path-style アクセス (http://host:port/bucket/key) で
 	// 構成するかどうかを示す。floci 等の S3 互換エミュレータ向けの opt-in で、既定は false
 	// (virtual-hosted style)。実際の S3 クライアント生成は internal/aws パッケージが
@@ -47,6 +80,22 @@ type Config struct {
 	// このフィールド自体は設定値の保持・可視化のために存在する。
 	S3PathStyle bool `yaml:"-"`
 
+	// SessionRecording はブラウザターミナル (EC2 Session / ECS Exec) のセッションを
+	// asciicast v2 形式で記録するかどうか。API サーバ専用で、既定は false (opt-in)。
+	SessionRecording bool `yaml:"session-recording"`
+
+	// RecordingsDir はセッション記録の保存先ディレクトリ。空の場合は Dir() 配下の recordings を使う
+	// (config.yaml と同じ場所に置き、/tmp のように再起動で消えないようにする)。API サーバ専用。
+	RecordingsDir string `yaml:"recordings-dir"`
+
+	// SessionIdleTimeout はブラウザターミナルで入力が途絶えてからセッションを自動終了するまでの時間。
+	// 0 の場合は無効。既定は Session Manager の既定アイドルタイムアウトと同じ 20 分。API サーバ専用。
+	SessionIdleTimeout time.Duration `yaml:"-"`
+
+	// SessionReattachGrace はブラウザターミナルの WebSocket が切断されてから、同じセッションへの再接続
+	// (/api/sessions/{id}/attach) を待つ時間。0 の場合は切断と同時にセッションを終了する。API サーバ専用。
+	SessionReattachGrace time.Duration `yaml:"-"`
+
 	BigQuery BigQueryConfig
 	Datadog  DatadogConfig `yaml:"datadog"`
 	TiDB     TiDBConfig    `yaml:"tidb"`
@@ -83,14 +132,23 @@ func (r redacted) value() string        { return string(r) }
 
 // fileConfig mirrors top-level fields for YAML unmarshalling.
 type fileConfig struct {
-	Profile       string `yaml:"profile"`
-	Region        string `yaml:"region"`
-	Output        string `yaml:"output"`
-	NoHeader      bool   `yaml:"no-header"`
-	ListenAddr    string `yaml:"listen-addr"`
-	SnippetsDir   string `yaml:"snippets-dir"`
-	PriceCacheDir string `yaml:"price-cache-dir"`
-	BigQuery      struct {
+	Profile              string   `yaml:"profile"`
+	Region               string   `yaml:"region"`
+	Output               string   `yaml:"output"`
+	NoHeader             bool     `yaml:"no-header"`
+	ListenAddr           string   `yaml:"listen-addr"`
+	SnippetsDir          string   `yaml:"snippets-dir"`
+	PriceCacheDir        string   `yaml:"price-cache-dir"`
+	ResourceCacheDir     string   `yaml:"resource-cache-dir"`
+	ResourceCacheMaxMB   *int     `yaml:"resource-cache-max-mb"`
+	CacheStaleTTL        string   `yaml:"cache-stale-ttl"`
+	Prewarm              []string `yaml:"prewarm"`
+	PrewarmInterval      string   `yaml:"prewarm-interval"`
+	SessionRecording     bool     `yaml:"session-recording"`
+	RecordingsDir        string   `yaml:"recordings-dir"`
+	SessionIdleTimeout   string   `yaml:"session-idle-timeout"`
+	SessionReattachGrace string   `yaml:"session-reattach-grace"`
+	BigQuery             struct {
 		ProjectID string `yaml:"project-id"`
 	} `yaml:"bigquery"`
 	Datadog struct {
@@ -109,6 +167,18 @@ type fileConfig struct {
 // WebSocket 許可オリジンのデフォルト値。
 var defaultWebOrigins = []string{"localhost:8088", "127.0.0.1:8088"}
 
+// defaultSessionIdleTimeout は Session Manager の既定アイドルタイムアウト (20 分) に揃える。
+const defaultSessionIdleTimeout = 20 * time.Minute
+
+// defaultSessionReattachGrace は VPN の瞬断やタブのリロードから復帰できる程度の猶予とする。
+const defaultSessionReattachGrace = 2 * time.Minute
+
+// defaultPrewarmInterval は resourceCache の TTL (1 時間) の半分とし、温めたエントリが期限切れになる前に再取得する。
+const defaultPrewarmInterval = 30 * time.Minute
+
+// defaultResourceCacheMaxMB は多数の profile/region を巡回しても API サーバのメモリが膨らみ続けない程度の上限とする。
+const defaultResourceCacheMaxMB = 256
+
 // Defaults returns a Config with built-in default values.
 func Defaults() *Config {
 	return &Config{
@@ -118,6 +188,11 @@ func Defaults() *Config {
 		WebOrigins:    defaultWebOrigins,
 		SnippetsDir:   "/tmp/thief",
 		PriceCacheDir: "/tmp/thief/price",
+		// SessionIdleTimeout / SessionReattachGrace は 0 で無効。
+		SessionIdleTimeout:   defaultSessionIdleTimeout,
+		SessionReattachGrace: defaultSessionReattachGrace,
+		PrewarmInterval:      defaultPrewarmInterval,
+		ResourceCacheMaxMB:   defaultResourceCacheMaxMB,
 		Datadog: DatadogConfig{
 			Site: "datadoghq.com",
 			View: "summary",
@@ -160,6 +235,33 @@ func applyFile(cfg *Config, fc fileConfig) {
 	if fc.PriceCacheDir != "" {
 		cfg.PriceCacheDir = fc.PriceCacheDir
 	}
+	if fc.ResourceCacheDir != "" {
+		cfg.ResourceCacheDir = fc.ResourceCacheDir
+	}
+	if fc.ResourceCacheMaxMB != nil && *fc.ResourceCacheMaxMB >= 0 {
+		cfg.ResourceCacheMaxMB = *fc.ResourceCacheMaxMB
+	}
+	if d, ok := parseDuration(fc.CacheStaleTTL); ok {
+		cfg.CacheStaleTTL = d
+	}
+	if len(fc.Prewarm) > 0 {
+		cfg.Prewarm = fc.Prewarm
+	}
+	if d, ok := parseDuration(fc.PrewarmInterval); ok && d > 0 {
+		cfg.PrewarmInterval = d
+	}
+	if fc.SessionRecording {
+		cfg.SessionRecording = true
+	}
+	if fc.RecordingsDir != "" {
+		cfg.RecordingsDir = fc.RecordingsDir
+	}
+	if d, ok := parseDuration(fc.SessionIdleTimeout); ok {
+		cfg.SessionIdleTimeout = d
+	}
+	if d, ok := parseDuration(fc.SessionReattachGrace); ok {
+		cfg.SessionReattachGrace = d
+	}
 	if fc.BigQuery.ProjectID != "" {
 		cfg.BigQuery.ProjectID = fc.BigQuery.ProjectID
 	}
@@ -204,11 +306,42 @@ func applyEnv(cfg *Config) {
 	if v := os.Getenv("THIEF_PRICE_CACHE_DIR"); v != "" {
 		cfg.PriceCacheDir = v
 	}
+	if v := os.Getenv("THIEF_RESOURCE_CACHE_DIR"); v != "" {
+		cfg.ResourceCacheDir = v
+	}
+	if v := os.Getenv("THIEF_RESOURCE_CACHE_MAX_MB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.ResourceCacheMaxMB = n
+		}
+	}
+	if d, ok := parseDuration(os.Getenv("THIEF_CACHE_STALE_TTL")); ok {
+		cfg.CacheStaleTTL = d
+	}
+	if v := os.Getenv("THIEF_PREWARM"); v != "" {
+		cfg.Prewarm = splitList(v)
+	}
+	if d, ok := parseDuration(os.Getenv("THIEF_PREWARM_INTERVAL")); ok && d > 0 {
+		cfg.PrewarmInterval = d
+	}
 	if v := os.Getenv("THIEF_S3_PATH_STYLE"); v != "" {
 		if b, err := strconv.ParseBool(v); err == nil {
 			cfg.S3PathStyle = b
 		}
 	}
+	if v := os.Getenv("THIEF_SESSION_RECORDING"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.SessionRecording = b
+		}
+	}
+	if v := os.Getenv("THIEF_RECORDINGS_DIR"); v != "" {
+		cfg.RecordingsDir = v
+	}
+	if d, ok := parseDuration(os.Getenv("THIEF_SESSION_IDLE_TIMEOUT")); ok {
+		cfg.SessionIdleTimeout = d
+	}
+	if d, ok := parseDuration(os.Getenv("THIEF_SESSION_REATTACH_GRACE")); ok {
+		cfg.SessionReattachGrace = d
+	}
 	if v := os.Getenv("THIEF_WEB_ORIGINS"); v != "" {
 		origins := strings.Split(v, ",")
 		for i, o := range origins {
@@ -278,6 +411,30 @@ func Dir() (string, error) {
 	return filepath.Join(home, ".config", "thief"), nil
 }
 
+// parseDuration は "30m" 形式の期間を解釈する。空文字列・不正な値・負の値は ok=false (既定値のまま) とする。
+// "0" は明示的な無効化として受け付ける。
+func parseDuration(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// splitList はカンマ区切りの値を分割し、前後の空白を除いて空の要素を捨てる。
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func firstEnv(keys ...string) string {
 	for _, k := range keys {
 		if v := os.Getenv(k); v != "" {
@@ -1,6 +1,10 @@
 package config
 
-import "testing"
+import (
+	"slices"
+	"testing"
+	"time"
+)
 
 func TestDefaultsPriceCacheDir(t *testing.T) {
 	got := Defaults().PriceCacheDir
@@ -38,3 +42,163 @@ func TestApplyEnvPriceCacheDir(t *testing.T) {
 		t.Errorf("PriceCacheDir = %q, want %q", cfg.PriceCacheDir, "/custom/price/dir")
 	}
 }
+
+func TestResourceCacheDir(t *testing.T) {
+	if got := Defaults().ResourceCacheDir; got != "" {
+		t.Errorf("Defaults().ResourceCacheDir = %q, want empty (memory only)", got)
+	}
+
+	cfg := Defaults()
+	applyFile(cfg, fileConfig{ResourceCacheDir: "/var/lib/thief/cache"})
+	if cfg.ResourceCacheDir != "/var/lib/thief/cache" {
+		t.Errorf("ResourceCacheDir from file = %q, want %q", cfg.ResourceCacheDir, "/var/lib/thief/cache")
+	}
+
+	t.Setenv("THIEF_RESOURCE_CACHE_DIR", "/custom/cache")
+	applyEnv(cfg)
+	if cfg.ResourceCacheDir != "/custom/cache" {
+		t.Errorf("ResourceCacheDir from env = %q, want %q", cfg.ResourceCacheDir, "/custom/cache")
+	}
+}
+
+func TestApplyEnvSessionRecording(t *testing.T) {
+	t.Setenv("THIEF_SESSION_RECORDING", "true")
+	t.Setenv("THIEF_RECORDINGS_DIR", "/var/lib/thief/recordings")
+	cfg := Defaults()
+	applyEnv(cfg)
+	if !cfg.SessionRecording {
+		t.Error("SessionRecording = false, want true")
+	}
+	if cfg.RecordingsDir != "/var/lib/thief/recordings" {
+		t.Errorf("RecordingsDir = %q, want %q", cfg.RecordingsDir, "/var/lib/thief/recordings")
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  string
+		want time.Duration
+	}{
+		{name: "default", want: 20 * time.Minute},
+		{name: "file", file: "30m", want: 30 * time.Minute},
+		{name: "env overrides file", file: "30m", env: "1h", want: time.Hour},
+		{name: "zero disables", env: "0", want: 0},
+		{name: "invalid keeps default", file: "soon", env: "-5m", want: 20 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THIEF_SESSION_IDLE_TIMEOUT", tt.env)
+			cfg := Defaults()
+			applyFile(cfg, fileConfig{SessionIdleTimeout: tt.file})
+			applyEnv(cfg)
+			if cfg.SessionIdleTimeout != tt.want {
+				t.Errorf("SessionIdleTimeout = %v, want %v", cfg.SessionIdleTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionReattachGrace(t *testing.T) {
+	t.Setenv("THIEF_SESSION_REATTACH_GRACE", "0")
+	cfg := Defaults()
+	if cfg.SessionReattachGrace != 2*time.Minute {
+		t.Errorf("default SessionReattachGrace = %v, want 2m", cfg.SessionReattachGrace)
+	}
+	applyFile(cfg, fileConfig{SessionReattachGrace: "30s"})
+	if cfg.SessionReattachGrace != 30*time.Second {
+		t.Errorf("SessionReattachGrace from file = %v, want 30s", cfg.SessionReattachGrace)
+	}
+	applyEnv(cfg)
+	if cfg.SessionReattachGrace != 0 {
+		t.Errorf("SessionReattachGrace from env = %v, want 0 (disabled)", cfg.SessionReattachGrace)
+	}
+}
+
+func TestCacheStaleTTL(t *testing.T) {
+	t.Setenv("THIEF_CACHE_STALE_TTL", "")
+	cfg := Defaults()
+	if cfg.CacheStaleTTL != 0 {
+		t.Errorf("default CacheStaleTTL = %v, want 0 (disabled)", cfg.CacheStaleTTL)
+	}
+	applyFile(cfg, fileConfig{CacheStaleTTL: "6h"})
+	if cfg.CacheStaleTTL != 6*time.Hour {
+		t.Errorf("CacheStaleTTL from file = %v, want 6h", cfg.CacheStaleTTL)
+	}
+	t.Setenv("THIEF_CACHE_STALE_TTL", "24h")
+	applyEnv(cfg)
+	if cfg.CacheStaleTTL != 24*time.Hour {
+		t.Errorf("CacheStaleTTL from env = %v, want 24h", cfg.CacheStaleTTL)
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	tests := []struct {
+		name         string
+		fc           fileConfig
+		env          string
+		envInterval  string
+		want         []string
+		wantInterval time.Duration
+	}{
+		{name: "default", wantInterval: 30 * time.Minute},
+		{
+			name:         "file",
+			fc:           fileConfig{Prewarm: []string{"prod/ap-northeast-1/ecs"}, PrewarmInterval: "10m"},
+			want:         []string{"prod/ap-northeast-1/ecs"},
+			wantInterval: 10 * time.Minute,
+		},
+		{
+			name:         "env overrides file",
+			fc:           fileConfig{Prewarm: []string{"prod/ap-northeast-1/ecs"}},
+			env:          " prod/ap-northeast-1/s3, ,dev/us-east-1/ec2 ",
+			envInterval:  "15m",
+			want:         []string{"prod/ap-northeast-1/s3", "dev/us-east-1/ec2"},
+			wantInterval: 15 * time.Minute,
+		},
+		{name: "zero interval keeps default", envInterval: "0", wantInterval: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THIEF_PREWARM", tt.env)
+			t.Setenv("THIEF_PREWARM_INTERVAL", tt.envInterval)
+			cfg := Defaults()
+			applyFile(cfg, tt.fc)
+			applyEnv(cfg)
+			if !slices.Equal(cfg.Prewarm, tt.want) {
+				t.Errorf("Prewarm = %q, want %q", cfg.Prewarm, tt.want)
+			}
+			if cfg.PrewarmInterval != tt.wantInterval {
+				t.Errorf("PrewarmInterval = %v, want %v", cfg.PrewarmInterval, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestResourceCacheMaxMB(t *testing.T) {
+	zero, negative := 0, -1
+	tests := []struct {
+		name string
+		file *int
+		env  string
+		want int
+	}{
+		{name: "default", want: 256},
+		{name: "file zero disables the bound", file: &zero, want: 0},
+		{name: "negative keeps default", file: &negative, want: 256},
+		{name: "env overrides file", file: &zero, env: "64", want: 64},
+		{name: "invalid env ignored", env: "lots", want: 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THIEF_RESOURCE_CACHE_MAX_MB", tt.env)
+			cfg := Defaults()
+			applyFile(cfg, fileConfig{ResourceCacheMaxMB: tt.file})
+			applyEnv(cfg)
+			if cfg.ResourceCacheMaxMB != tt.want {
+				t.Errorf("ResourceCacheMaxMB = %d, want %d", cfg.ResourceCacheMaxMB, tt.want)
+			}
+		})
+	}
+}
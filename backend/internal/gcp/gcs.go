@@ -2,8 +2,12 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
@@ -128,26 +132,194 @@ func GetObject(ctx context.Context, projectID, bucket, key string) (*ObjectReade
 	}, nil
 }
 
-// PutObject は body を Cloud Storage オブジェクトとして書き込む。
-func PutObject(ctx context.Context, projectID, bucket, key string, body io.Reader, contentType string) error {
+// ObjectReaderAt は GCS オブジェクトを範囲リクエストで読む io.ReaderAt。Parquet / ORC のように末尾の
+// メタデータと一部の範囲だけを読めばよい形式を、オブジェクト全体をダウンロードせずにプレビューするために使う。
+// 開いた時点の世代 (generation) を固定するため、途中でオブジェクトが上書きされても新旧の内容が混ざらない。
+// 呼び出し側は読み終えたら Close すること。
+type ObjectReaderAt struct {
+	ContentType string
+	Size        int64
+
+	// ctx は ReadAt の範囲リクエストに使う。io.ReaderAt は context を受け取れないため、開いたときの
+	// context (リクエストの context) を保持する。
+	ctx    context.Context
+	client *storage.Client
+	object *storage.ObjectHandle
+}
+
+// NewObjectReaderAt はオブジェクトの属性 (サイズと世代) を取得し、範囲で読む ObjectReaderAt を返す。
+func NewObjectReaderAt(ctx context.Context, projectID, bucket, key string) (*ObjectReaderAt, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create storage client: %w", err)
+	}
+
+	object := client.Bucket(bucket).UserProject(projectID).Object(key)
+	attrs, err := object.Attrs(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("get object attrs %s/%s: %w", bucket, key, err)
+	}
+
+	return &ObjectReaderAt{
+		ContentType: attrs.ContentType,
+		Size:        attrs.Size,
+		ctx:         ctx,
+		client:      client,
+		object:      object.Generation(attrs.Generation),
+	}, nil
+}
+
+// ReadAt は off から len(p) バイトを 1 回の範囲リクエストで読む。オブジェクトの末尾を越える分は読まずに
+// io.EOF を返す。
+func (r *ObjectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("gcs object range: negative offset")
+	}
+	if off >= r.Size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	length := min(int64(len(p)), r.Size-off)
+	reader, err := r.object.NewRangeReader(r.ctx, off, length)
+	if err != nil {
+		return 0, fmt.Errorf("get object %s/%s range %d+%d: %w", r.object.BucketName(), r.object.ObjectName(), off, length, err)
+	}
+	defer reader.Close()
+	n, err := io.ReadFull(reader, p[:length])
+	if err != nil {
+		return n, fmt.Errorf("read object %s/%s range %d+%d: %w", r.object.BucketName(), r.object.ObjectName(), off, length, err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close は範囲読み込みに使った Client を解放する。
+func (r *ObjectReaderAt) Close() error {
+	return r.client.Close()
+}
+
+// gcsUploadChunkSize は UploadObject の再開可能アップロード (resumable upload) で 1 回に送るチャンクのサイズ。
+// Writer はチャンク単位でバッファして送り、失敗したチャンクだけを再送する。メモリに載せるのは 1 チャンク分だけ。
+const gcsUploadChunkSize = 16 << 20 // 16MiB
+
+// UploadObject は body を再開可能アップロードでストリーミングに書き込み、書き込んだバイト数を返す。
+// progress が nil でなければ、チャンクを送り終えるたびにそれまでに送ったバイト数を渡す。
+// body の読み込みに失敗した場合はアップロードを中止し、途中までの内容をオブジェクトとして残さない。
+func UploadObject(ctx context.Context, projectID, bucket, key string, body io.Reader, contentType string, progress func(int64)) (int64, error) {
 	client, err := storage.NewClient(ctx)
 	if err != nil {
-		return fmt.Errorf("create storage client: %w", err)
+		return 0, fmt.Errorf("create storage client: %w", err)
 	}
 	defer client.Close()
 
+	// Writer は Close 前に ctx をキャンセルするとアップロードを中止する (Close すると途中までの内容で確定してしまう)。
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	writer := client.Bucket(bucket).UserProject(projectID).Object(key).NewWriter(ctx)
+	writer.ChunkSize = gcsUploadChunkSize
 	if contentType != "" {
 		writer.ContentType = contentType
 	}
-	if _, err := io.Copy(writer, body); err != nil {
+	if progress != nil {
+		writer.ProgressFunc = progress
+	}
+	n, err := io.Copy(writer, body)
+	if err != nil {
+		cancel()
 		writer.Close()
-		return fmt.Errorf("put object %s/%s: %w", bucket, key, err)
+		return n, fmt.Errorf("upload object %s/%s: %w", bucket, key, err)
 	}
 	if err := writer.Close(); err != nil {
-		return fmt.Errorf("put object %s/%s: %w", bucket, key, err)
+		return n, fmt.Errorf("upload object %s/%s: %w", bucket, key, err)
+	}
+	if progress != nil {
+		progress(n)
+	}
+	return n, nil
+}
+
+// DefaultSignedURLExpiry は有効期限を指定しなかったときの署名付き URL の有効期限。
+// S3 の DefaultS3PresignExpiry と揃える。
+const DefaultSignedURLExpiry = 15 * time.Minute
+
+// maxSignedURLExpiry は V4 署名付き URL の有効期限の上限 (7 日)。
+const maxSignedURLExpiry = 7 * 24 * time.Hour
+
+// ErrInvalidSignedURL は署名付き URL のメソッド・キー・有効期限が不正なときに返す。
+var ErrInvalidSignedURL = errors.New("invalid signed URL request")
+
+// SignedURL は署名付き URL と、その URL で使う HTTP メソッド、有効期限の時刻を表す。
+type SignedURL struct {
+	URL       string `json:"url"`
+	Method    string `json:"method"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// signObjectFunc は BucketHandle.SignedURL のシグネチャ。テストでは署名を差し替える。
+type signObjectFunc func(key string, opts *storage.SignedURLOptions) (string, error)
+
+// SignObjectURL は bucket/key に対する V4 署名付き URL を発行する。method は GET / PUT / HEAD / DELETE
+// (大文字小文字は問わない、空なら GET)、expires は 0 なら DefaultSignedURLExpiry。
+// 署名にはサービスアカウントの鍵、または ADC のサービスアカウントに対する IAM signBlob 権限
+// (roles/iam.serviceAccountTokenCreator) が必要で、gcloud のユーザー認証情報では署名できない。
+func SignObjectURL(ctx context.Context, projectID, bucket, key, method string, expires time.Duration) (SignedURL, error) {
+	method, expires, err := normalizeSignedURL(key, method, expires)
+	if err != nil {
+		return SignedURL{}, err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return SignedURL{}, fmt.Errorf("create storage client: %w", err)
+	}
+	defer client.Close()
+
+	return signObjectURL(client.Bucket(bucket).SignedURL, projectID, bucket, key, method, expires, time.Now())
+}
+
+// normalizeSignedURL はメソッドを大文字に揃え、既定値を補ってキーと有効期限を検証する。
+func normalizeSignedURL(key, method string, expires time.Duration) (string, time.Duration, error) {
+	if key == "" {
+		return "", 0, fmt.Errorf("%w: key is required", ErrInvalidSignedURL)
+	}
+	method = strings.ToUpper(method)
+	switch method {
+	case "":
+		method = "GET"
+	case "GET", "PUT", "HEAD", "DELETE":
+	default:
+		return "", 0, fmt.Errorf("%w: unsupported method %q (use GET, PUT, HEAD or DELETE)", ErrInvalidSignedURL, method)
+	}
+	switch {
+	case expires == 0:
+		expires = DefaultSignedURLExpiry
+	case expires < time.Second || expires > maxSignedURLExpiry:
+		return "", 0, fmt.Errorf("%w: expiry must be between 1s and %s", ErrInvalidSignedURL, maxSignedURLExpiry)
+	}
+	return method, expires, nil
+}
+
+func signObjectURL(sign signObjectFunc, projectID, bucket, key, method string, expires time.Duration, now time.Time) (SignedURL, error) {
+	expiresAt := now.Add(expires)
+	opts := &storage.SignedURLOptions{
+		Method:  method,
+		Expires: expiresAt,
+		Scheme:  storage.SigningSchemeV4,
+	}
+	// 他の GCS 操作の UserProject(projectID) と同じく、リクエスター支払いのバケットでも使えるよう
+	// 課金先のプロジェクトを URL に含める。BucketHandle.SignedURL は UserProject を引き継がない。
+	if projectID != "" {
+		opts.QueryParameters = url.Values{"userProject": {projectID}}
+	}
+	signed, err := sign(key, opts)
+	if err != nil {
+		return SignedURL{}, fmt.Errorf("sign %s url for %s/%s: %w", method, bucket, key, err)
 	}
-	return nil
+	return SignedURL{URL: signed, Method: method, ExpiresAt: formatTimestamp(expiresAt, true)}, nil
 }
 
 func bucketFromAttrs(attrs *storage.BucketAttrs) BucketInfo {
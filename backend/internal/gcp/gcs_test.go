@@ -1,6 +1,7 @@
 package gcp
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -95,3 +96,63 @@ func TestObjectFromAttrs(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeSignedURL(t *testing.T) {
+	tests := []struct {
+		key, method string
+		expires     time.Duration
+		wantMethod  string
+		wantExpires time.Duration
+		wantErr     bool
+	}{
+		{key: "a.txt", wantMethod: "GET", wantExpires: DefaultSignedURLExpiry},
+		{key: "a.txt", method: "put", expires: time.Hour, wantMethod: "PUT", wantExpires: time.Hour},
+		{key: "a.txt", method: "HEAD", expires: maxSignedURLExpiry, wantMethod: "HEAD", wantExpires: maxSignedURLExpiry},
+		{key: "a.txt", method: "POST", expires: time.Hour, wantErr: true},
+		{key: "a.txt", method: "GET", expires: maxSignedURLExpiry + time.Second, wantErr: true},
+		{key: "a.txt", method: "GET", expires: -time.Hour, wantErr: true},
+		{key: "", method: "GET", expires: time.Hour, wantErr: true},
+	}
+	for _, tt := range tests {
+		method, expires, err := normalizeSignedURL(tt.key, tt.method, tt.expires)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSignedURL) {
+				t.Errorf("normalizeSignedURL(%q, %q, %s) err = %v, want ErrInvalidSignedURL", tt.key, tt.method, tt.expires, err)
+			}
+			continue
+		}
+		if err != nil || method != tt.wantMethod || expires != tt.wantExpires {
+			t.Errorf("normalizeSignedURL(%q, %q, %s) = %q, %s, %v, want %q, %s", tt.key, tt.method, tt.expires, method, expires, err, tt.wantMethod, tt.wantExpires)
+		}
+	}
+}
+
+func TestSignObjectURL(t *testing.T) {
+	now := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	var gotKey string
+	var gotOpts *storage.SignedURLOptions
+	sign := func(key string, opts *storage.SignedURLOptions) (string, error) {
+		gotKey, gotOpts = key, opts
+		return "https://storage.googleapis.com/b/" + key + "?X-Goog-Signature=sig", nil
+	}
+
+	got, err := signObjectURL(sign, "my-project", "b", "dir/file.txt", "PUT", time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SignedURL{URL: "https://storage.googleapis.com/b/dir/file.txt?X-Goog-Signature=sig", Method: "PUT", ExpiresAt: "2026-04-01T01:00:00Z"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if gotKey != "dir/file.txt" || gotOpts.Method != "PUT" || gotOpts.Scheme != storage.SigningSchemeV4 ||
+		!gotOpts.Expires.Equal(now.Add(time.Hour)) || gotOpts.QueryParameters.Get("userProject") != "my-project" {
+		t.Errorf("sign called with key %q, opts %+v", gotKey, gotOpts)
+	}
+
+	failing := func(string, *storage.SignedURLOptions) (string, error) {
+		return "", errors.New("unable to detect default GoogleAccessID")
+	}
+	if _, err := signObjectURL(failing, "my-project", "b", "a.txt", "GET", time.Hour, now); err == nil {
+		t.Error("want error when signing fails")
+	}
+}
@@ -0,0 +1,122 @@
+// Package graph はリソース間の関係 (ロードバランサー → リスナー → ターゲットグループ → インスタンスなど) を
+// ルートから幅優先でたどり、ノードとエッジのグラフを組み立てる。ノードの種類ごとの隣接ノードの解決は
+// Expander として呼び出し側が与え、このパッケージは AWS に依存しない。
+package graph
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// expandConcurrency は同じ深さのノードの隣接ノードを同時に解決する上限数。
+const expandConcurrency = 8
+
+// Node はグラフの 1 リソース。ID はグラフ内で一意な識別子 (AWS リソースでは ARN)。
+type Node struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Region     string            `json:"region,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Edge は From から To への関係。Relation は関係の種類 (listener / forward / target など)。
+type Edge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// Neighbor は Expander が返す隣接ノードと、元のノードからの関係。
+type Neighbor struct {
+	Node
+	Relation string
+}
+
+// NodeError は隣接ノードを解決できなかったノード。
+type NodeError struct {
+	Node  string `json:"node"`
+	Error string `json:"error"`
+}
+
+// Graph は Build の結果。Truncated は深さまたはノード数の上限で探索を打ち切ったことを表す。
+type Graph struct {
+	Root      string      `json:"root"`
+	Nodes     []Node      `json:"nodes"`
+	Edges     []Edge      `json:"edges"`
+	Errors    []NodeError `json:"errors"`
+	Truncated bool        `json:"truncated"`
+}
+
+// Expander はノードの隣接ノードを返す。
+type Expander func(ctx context.Context, n Node) ([]Neighbor, error)
+
+// Options は探索の上限。MaxDepth はルートからのエッジ数、MaxNodes はルートを含むノード数。
+type Options struct {
+	MaxDepth int
+	MaxNodes int
+}
+
+// Build は root から幅優先で隣接ノードをたどってグラフを組み立てる。ノードは Type に対応する expanders の
+// Expander で展開し、Expander のない種類は末端として扱う。同じ ID のノードは 1 度だけ展開するため循環しても
+// 停止する。Expander の失敗は Errors に記録し、他のノードの探索は続ける。ノードとエッジの順序は探索順で決まる。
+func Build(ctx context.Context, root Node, expanders map[string]Expander, opts Options) Graph {
+	g := Graph{Root: root.ID, Nodes: []Node{root}, Edges: []Edge{}, Errors: []NodeError{}}
+	seen := map[string]bool{root.ID: true}
+	seenEdges := map[Edge]bool{}
+
+	level := []Node{root}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth >= opts.MaxDepth {
+			for _, n := range level {
+				if expanders[n.Type] != nil {
+					g.Truncated = true
+				}
+			}
+			break
+		}
+
+		neighbors := make([][]Neighbor, len(level))
+		errs := make([]error, len(level))
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.SetLimit(expandConcurrency)
+		for i, n := range level {
+			expand := expanders[n.Type]
+			if expand == nil {
+				continue
+			}
+			eg.Go(func() error {
+				neighbors[i], errs[i] = expand(ectx, n)
+				return nil
+			})
+		}
+		_ = eg.Wait()
+
+		var next []Node
+		for i, n := range level {
+			if errs[i] != nil {
+				g.Errors = append(g.Errors, NodeError{Node: n.ID, Error: errs[i].Error()})
+				continue
+			}
+			for _, nb := range neighbors[i] {
+				if !seen[nb.ID] {
+					if len(g.Nodes) >= opts.MaxNodes {
+						g.Truncated = true
+						continue
+					}
+					seen[nb.ID] = true
+					g.Nodes = append(g.Nodes, nb.Node)
+					next = append(next, nb.Node)
+				}
+				e := Edge{From: n.ID, To: nb.ID, Relation: nb.Relation}
+				if !seenEdges[e] {
+					seenEdges[e] = true
+					g.Edges = append(g.Edges, e)
+				}
+			}
+		}
+		level = next
+	}
+	return g
+}
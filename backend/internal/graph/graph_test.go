@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testExpanders は lb → listener → tg → (i-1, i-2) と、tg から lb へ戻る循環を持つグラフを返す。
+func testExpanders() map[string]Expander {
+	return map[string]Expander{
+		"lb": func(_ context.Context, n Node) ([]Neighbor, error) {
+			return []Neighbor{{Node: Node{ID: "listener", Type: "listener"}, Relation: "listener"}}, nil
+		},
+		"listener": func(_ context.Context, n Node) ([]Neighbor, error) {
+			return []Neighbor{
+				{Node: Node{ID: "tg", Type: "tg"}, Relation: "forward"},
+				{Node: Node{ID: "tg", Type: "tg"}, Relation: "forward"},
+			}, nil
+		},
+		"tg": func(_ context.Context, n Node) ([]Neighbor, error) {
+			return []Neighbor{
+				{Node: Node{ID: "i-1", Type: "instance"}, Relation: "target"},
+				{Node: Node{ID: "i-2", Type: "instance"}, Relation: "target"},
+				{Node: Node{ID: "lb", Type: "lb"}, Relation: "load-balancer"},
+			}, nil
+		},
+		"instance": func(_ context.Context, n Node) ([]Neighbor, error) {
+			if n.ID == "i-2" {
+				return nil, errors.New("access denied")
+			}
+			return []Neighbor{{Node: Node{ID: "eni-1", Type: "eni"}, Relation: "network-interface"}}, nil
+		},
+	}
+}
+
+func nodeIDs(g Graph) []string {
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestBuild(t *testing.T) {
+	g := Build(context.Background(), Node{ID: "lb", Type: "lb"}, testExpanders(), Options{MaxDepth: 10, MaxNodes: 100})
+
+	if diff := cmp.Diff([]string{"lb", "listener", "tg", "i-1", "i-2", "eni-1"}, nodeIDs(g)); diff != "" {
+		t.Errorf("nodes mismatch (-want +got):\n%s", diff)
+	}
+	wantEdges := []Edge{
+		{From: "lb", To: "listener", Relation: "listener"},
+		{From: "listener", To: "tg", Relation: "forward"},
+		{From: "tg", To: "i-1", Relation: "target"},
+		{From: "tg", To: "i-2", Relation: "target"},
+		{From: "tg", To: "lb", Relation: "load-balancer"},
+		{From: "i-1", To: "eni-1", Relation: "network-interface"},
+	}
+	if diff := cmp.Diff(wantEdges, g.Edges); diff != "" {
+		t.Errorf("edges mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]NodeError{{Node: "i-2", Error: "access denied"}}, g.Errors); diff != "" {
+		t.Errorf("errors mismatch (-want +got):\n%s", diff)
+	}
+	if g.Truncated {
+		t.Error("Truncated = true, want false")
+	}
+}
+
+func TestBuildLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{name: "depth", opts: Options{MaxDepth: 2, MaxNodes: 100}, want: []string{"lb", "listener", "tg"}},
+		{name: "nodes", opts: Options{MaxDepth: 10, MaxNodes: 4}, want: []string{"lb", "listener", "tg", "i-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := Build(context.Background(), Node{ID: "lb", Type: "lb"}, testExpanders(), tt.opts)
+			if diff := cmp.Diff(tt.want, nodeIDs(g)); diff != "" {
+				t.Errorf("nodes mismatch (-want +got):\n%s", diff)
+			}
+			if !g.Truncated {
+				t.Error("Truncated = false, want true")
+			}
+		})
+	}
+
+	// 末端のノード (Expander のない種類) だけが残った場合は打ち切りではない。
+	g := Build(context.Background(), Node{ID: "i-1", Type: "instance"}, testExpanders(), Options{MaxDepth: 1, MaxNodes: 100})
+	if g.Truncated || len(g.Nodes) != 2 {
+		t.Errorf("graph = %+v, want i-1 and eni-1 without truncation", g)
+	}
+}
@@ -0,0 +1,601 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// avroMagic は Avro オブジェクトコンテナファイル (OCF) の先頭 4 バイト。
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+// avroSyncSize はブロックの区切りに置かれる同期マーカーの長さ。
+const avroSyncSize = 16
+
+// maxAvroItems は配列 / map の 1 ブロックに含められる要素数の上限。null の要素は 0 バイトで表せるため、
+// 残りのバイト数だけでは要素数を抑えられない。
+const maxAvroItems = 1 << 20
+
+// maxAvroDepth は値の入れ子の上限。自身を参照するレコードは 0 バイトで入れ子にできるため、
+// ブロックのバイト数だけでは再帰の深さを抑えられない。
+const maxAvroDepth = 64
+
+// ReadAvro は Avro OCF を先頭から読み、最初の limit レコードを表にする。トップレベルがレコードなら
+// フィールドを列に、それ以外のスキーマなら value 列 1 つにする。ネストしたレコード / 配列 / map は
+// map[string]any / []any のまま値にする。コーデックは null / deflate / snappy / zstandard に対応する。
+func ReadAvro(r io.Reader, limit int) (*Table, error) {
+	br := bufio.NewReader(&budgetReader{r: r, remaining: MaxReadBytes})
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return nil, fmt.Errorf("avro: %w: missing magic", ErrInvalid)
+	}
+	meta, err := readAvroMetadata(br)
+	if err != nil {
+		return nil, err
+	}
+	sync := make([]byte, avroSyncSize)
+	if _, err := io.ReadFull(br, sync); err != nil {
+		return nil, avroReadError(err)
+	}
+
+	var schemaJSON any
+	if err := json.Unmarshal(meta["avro.schema"], &schemaJSON); err != nil {
+		return nil, fmt.Errorf("avro schema: %w: %w", ErrInvalid, err)
+	}
+	schema, err := newAvroSchemaParser().parse(schemaJSON, "")
+	if err != nil {
+		return nil, err
+	}
+	decompress, err := avroCodec(string(meta["avro.codec"]))
+	if err != nil {
+		return nil, err
+	}
+
+	table := newTable(FormatAvro, limit)
+	if schema.typ == "record" {
+		for _, f := range schema.fields {
+			table.Columns = append(table.Columns, Column{Name: f.name, Type: f.schema.typeName()})
+		}
+	} else {
+		table.Columns = append(table.Columns, Column{Name: "value", Type: schema.typeName()})
+	}
+
+	for {
+		count, err := readAvroLong(br)
+		if errors.Is(err, io.EOF) {
+			return table, nil
+		}
+		if err != nil {
+			return nil, avroReadError(err)
+		}
+		size, err := readAvroLong(br)
+		if err != nil {
+			return nil, avroReadError(err)
+		}
+		if count < 0 || size < 0 || size > MaxReadBytes {
+			return nil, fmt.Errorf("avro block of %d records in %d bytes: %w", count, size, ErrInvalid)
+		}
+		if len(table.Rows) == limit {
+			table.Truncated = count > 0
+			if table.Truncated {
+				return table, nil
+			}
+		}
+		block := make([]byte, size)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, avroReadError(err)
+		}
+		if block, err = decompress(block); err != nil {
+			return nil, err
+		}
+		d := &avroDecoder{buf: block}
+		for i := int64(0); i < count; i++ {
+			if len(table.Rows) == limit {
+				table.Truncated = true
+				return table, nil
+			}
+			v, err := schema.decode(d)
+			if err != nil {
+				return nil, err
+			}
+			if schema.typ == "record" {
+				rec := v.(map[string]any)
+				row := make([]any, len(schema.fields))
+				for j, f := range schema.fields {
+					row[j] = rec[f.name]
+				}
+				table.Rows = append(table.Rows, row)
+			} else {
+				table.Rows = append(table.Rows, []any{v})
+			}
+		}
+		marker := make([]byte, avroSyncSize)
+		if _, err := io.ReadFull(br, marker); err != nil {
+			return nil, avroReadError(err)
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, fmt.Errorf("avro: %w: sync marker mismatch", ErrInvalid)
+		}
+	}
+}
+
+// avroReadError はファイルの途中で読めなくなった原因を区別する。MaxReadBytes に達した場合は ErrTooLarge のまま返す。
+func avroReadError(err error) error {
+	if errors.Is(err, ErrTooLarge) {
+		return err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("avro: %w: unexpected end of file", ErrInvalid)
+	}
+	return fmt.Errorf("read avro: %w", err)
+}
+
+// readAvroMetadata はヘッダーのメタデータ (map<bytes>) を読む。
+func readAvroMetadata(br *bufio.Reader) (map[string][]byte, error) {
+	meta := map[string][]byte{}
+	for {
+		count, err := readAvroLong(br)
+		if err != nil {
+			return nil, avroReadError(err)
+		}
+		if count == 0 {
+			return meta, nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := readAvroLong(br); err != nil { // ブロックのバイト数
+				return nil, avroReadError(err)
+			}
+		}
+		for ; count > 0; count-- {
+			key, err := readAvroBytes(br)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readAvroBytes(br)
+			if err != nil {
+				return nil, err
+			}
+			meta[string(key)] = value
+		}
+	}
+}
+
+func readAvroLong(br io.ByteReader) (int64, error) {
+	v, err := binary.ReadVarint(br)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, ErrTooLarge) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("avro: %w: %w", ErrInvalid, err)
+	}
+	return v, err
+}
+
+func readAvroBytes(br *bufio.Reader) ([]byte, error) {
+	n, err := readAvroLong(br)
+	if err != nil {
+		return nil, avroReadError(err)
+	}
+	if n < 0 || n > MaxReadBytes {
+		return nil, fmt.Errorf("avro: %w: bytes of length %d", ErrInvalid, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, avroReadError(err)
+	}
+	return b, nil
+}
+
+// avroCodec は avro.codec に対応するブロックの展開関数を返す。
+func avroCodec(codec string) (func([]byte) ([]byte, error), error) {
+	switch codec {
+	case "", "null":
+		return func(b []byte) ([]byte, error) { return b, nil }, nil
+	case "deflate":
+		return decodeDeflate, nil
+	case "zstandard":
+		return decodeZstd, nil
+	case "snappy":
+		// Snappy のブロックの後ろに、展開後のデータの CRC32 (ビッグエンディアン) が付く。
+		return func(b []byte) ([]byte, error) {
+			if len(b) < 4 {
+				return nil, fmt.Errorf("avro snappy block: %w: too short", ErrInvalid)
+			}
+			out, err := decodeSnappy(b[:len(b)-4])
+			if err != nil {
+				return nil, err
+			}
+			if crc32.ChecksumIEEE(out) != binary.BigEndian.Uint32(b[len(b)-4:]) {
+				return nil, fmt.Errorf("avro snappy block: %w: checksum mismatch", ErrInvalid)
+			}
+			return out, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("avro codec %q: %w", codec, ErrUnsupported)
+}
+
+// avroSchema は解析済みの Avro スキーマ。typ はプリミティブ型名か record / enum / array / map / fixed / union。
+type avroSchema struct {
+	typ       string
+	name      string
+	logical   string
+	precision int
+	scale     int
+	size      int
+	fields    []avroField
+	symbols   []string
+	items     *avroSchema
+	values    *avroSchema
+	branches  []*avroSchema
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// avroSchemaParser は名前付き型 (record / enum / fixed) を完全名と短い名前で引けるように覚えながらスキーマを解析する。
+type avroSchemaParser struct {
+	named map[string]*avroSchema
+}
+
+func newAvroSchemaParser() *avroSchemaParser {
+	return &avroSchemaParser{named: map[string]*avroSchema{}}
+}
+
+func (p *avroSchemaParser) parse(v any, namespace string) (*avroSchema, error) {
+	switch s := v.(type) {
+	case string:
+		return p.parseName(s, namespace)
+	case []any:
+		union := &avroSchema{typ: "union"}
+		for _, b := range s {
+			branch, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, branch)
+		}
+		return union, nil
+	case map[string]any:
+		return p.parseObject(s, namespace)
+	}
+	return nil, fmt.Errorf("avro schema: %w: unexpected %T", ErrInvalid, v)
+}
+
+func (p *avroSchemaParser) parseName(name, namespace string) (*avroSchema, error) {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &avroSchema{typ: name}, nil
+	}
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	if s, ok := p.named[namespace+"."+name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("avro schema: %w: unknown type %q", ErrInvalid, name)
+}
+
+func (p *avroSchemaParser) parseObject(obj map[string]any, namespace string) (*avroSchema, error) {
+	typ, _ := obj["type"].(string)
+	if typ == "" {
+		// {"type": {...}} や {"type": [...]} はネストした型定義。
+		return p.parse(obj["type"], namespace)
+	}
+	logical, _ := obj["logicalType"].(string)
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		s := &avroSchema{typ: typ, logical: logical}
+		if typ == "error" {
+			s.typ = "record"
+		}
+		name, _ := obj["name"].(string)
+		if ns, ok := obj["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+		s.name = name
+		fullName := name
+		if namespace != "" {
+			fullName = namespace + "." + name
+		}
+		// 再帰的なレコード (連結リストなど) がフィールドから自身を参照できるよう、中身より先に登録する。
+		p.named[fullName] = s
+		p.named[name] = s
+		switch typ {
+		case "enum":
+			for _, sym := range asSlice(obj["symbols"]) {
+				symbol, _ := sym.(string)
+				s.symbols = append(s.symbols, symbol)
+			}
+		case "fixed":
+			s.size = asInt(obj["size"])
+			s.precision, s.scale = asInt(obj["precision"]), asInt(obj["scale"])
+			if logical == "decimal" {
+				if err := checkDecimalScale(int64(s.scale)); err != nil {
+					return nil, fmt.Errorf("avro schema %q: %w", name, err)
+				}
+			}
+		default:
+			for _, f := range asSlice(obj["fields"]) {
+				fobj, _ := f.(map[string]any)
+				fname, _ := fobj["name"].(string)
+				fs, err := p.parse(fobj["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("avro field %q: %w", fname, err)
+				}
+				s.fields = append(s.fields, avroField{name: fname, schema: fs})
+			}
+		}
+		return s, nil
+	case "array":
+		items, err := p.parse(obj["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{typ: typ, items: items}, nil
+	case "map":
+		values, err := p.parse(obj["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{typ: typ, values: values}, nil
+	}
+	s, err := p.parseName(typ, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if logical == "" {
+		return s, nil
+	}
+	annotated := *s
+	annotated.logical = logical
+	annotated.precision, annotated.scale = asInt(obj["precision"]), asInt(obj["scale"])
+	if logical == "decimal" {
+		if err := checkDecimalScale(int64(annotated.scale)); err != nil {
+			return nil, fmt.Errorf("avro schema: %w", err)
+		}
+	}
+	return &annotated, nil
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func asInt(v any) int {
+	f, _ := v.(float64)
+	return int(f)
+}
+
+// typeName は列の型名。論理型があればそれを、["null", T] の union は T を返す。
+func (s *avroSchema) typeName() string {
+	switch {
+	case s.logical == "decimal":
+		return fmt.Sprintf("decimal(%d,%d)", s.precision, s.scale)
+	case s.logical != "":
+		return s.logical
+	case s.typ == "union":
+		var names []string
+		for _, b := range s.branches {
+			if b.typ != "null" {
+				names = append(names, b.typeName())
+			}
+		}
+		if len(names) == 0 {
+			return "null"
+		}
+		return strings.Join(names, "|")
+	case s.typ == "record" || s.typ == "enum" || s.typ == "fixed":
+		if s.name != "" {
+			return s.typ + "<" + s.name + ">"
+		}
+	}
+	return s.typ
+}
+
+// avroDecoder は展開済みのブロックからバイナリエンコーディングの値を読む。depth はいま読んでいる値の入れ子の深さ、
+// items はブロックで読んだ配列 / map の要素数の合計。
+type avroDecoder struct {
+	buf   []byte
+	pos   int
+	depth int
+	items int64
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("avro: %w: bad varint", ErrInvalid)
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *avroDecoder) next(n int64) ([]byte, error) {
+	if n < 0 || n > int64(len(d.buf)-d.pos) {
+		return nil, fmt.Errorf("avro: %w: value of %d bytes overruns block", ErrInvalid, n)
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// blockCount は配列 / map のブロックの要素数を読む。負の要素数の後ろにはブロックのバイト数が続く。
+// 0 バイトの要素を並べたブロックを繰り返してもメモリを使い切らないよう、ブロック全体の要素数も
+// maxAvroItems とブロックのバイト数の和までに抑える。
+func (d *avroDecoder) blockCount() (int64, error) {
+	n, err := d.long()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		n = -n
+		if _, err := d.long(); err != nil {
+			return 0, err
+		}
+	}
+	if n < 0 || n > maxAvroItems {
+		return 0, fmt.Errorf("avro: %w: block of %d items", ErrInvalid, n)
+	}
+	if d.items += n; d.items > maxAvroItems+int64(len(d.buf)) {
+		return 0, fmt.Errorf("avro: %w: %d items in a block of %d bytes", ErrInvalid, d.items, len(d.buf))
+	}
+	return n, nil
+}
+
+func (s *avroSchema) decode(d *avroDecoder) (any, error) {
+	if d.depth++; d.depth > maxAvroDepth {
+		return nil, fmt.Errorf("avro: %w: values nested deeper than %d", ErrInvalid, maxAvroDepth)
+	}
+	defer func() { d.depth-- }()
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return avroLogicalInt(s.logical, v), nil
+	case "float":
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return jsonFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))), nil
+	case "double":
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return jsonFloat(math.Float64frombits(binary.LittleEndian.Uint64(b))), nil
+	case "bytes", "string", "fixed":
+		n := int64(s.size)
+		if s.typ != "fixed" {
+			var err error
+			if n, err = d.long(); err != nil {
+				return nil, err
+			}
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case s.logical == "decimal":
+			return formatDecimal(twosComplement(b), s.scale), nil
+		case s.typ == "string":
+			return string(b), nil
+		}
+		return jsonBytes(b), nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("avro: %w: enum index %d", ErrInvalid, i)
+		}
+		return s.symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.branches)) {
+			return nil, fmt.Errorf("avro: %w: union index %d", ErrInvalid, i)
+		}
+		return s.branches[i].decode(d)
+	case "record":
+		rec := make(map[string]any, len(s.fields))
+		for _, f := range s.fields {
+			v, err := f.schema.decode(d)
+			if err != nil {
+				return nil, err
+			}
+			rec[f.name] = v
+		}
+		return rec, nil
+	case "array":
+		items := []any{}
+		for {
+			n, err := d.blockCount()
+			if err != nil || n == 0 {
+				return items, err
+			}
+			for ; n > 0; n-- {
+				v, err := s.items.decode(d)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+		}
+	case "map":
+		m := map[string]any{}
+		for {
+			n, err := d.blockCount()
+			if err != nil || n == 0 {
+				return m, err
+			}
+			for ; n > 0; n-- {
+				kn, err := d.long()
+				if err != nil {
+					return nil, err
+				}
+				k, err := d.next(kn)
+				if err != nil {
+					return nil, err
+				}
+				v, err := s.values.decode(d)
+				if err != nil {
+					return nil, err
+				}
+				m[string(k)] = v
+			}
+		}
+	}
+	return nil, fmt.Errorf("avro type %q: %w", s.typ, ErrUnsupported)
+}
+
+// avroLogicalInt は int / long の論理型 (date / time / timestamp) を文字列にする。
+func avroLogicalInt(logical string, v int64) any {
+	switch logical {
+	case "date":
+		return formatDate(v)
+	case "time-millis":
+		return formatTimeOfDay(time.Duration(v) * time.Millisecond)
+	case "time-micros":
+		return formatTimeOfDay(time.Duration(v) * time.Microsecond)
+	case "timestamp-millis":
+		return formatTimestamp(time.UnixMilli(v), true)
+	case "timestamp-micros":
+		return formatTimestamp(time.UnixMicro(v), true)
+	case "timestamp-nanos":
+		return formatTimestamp(time.Unix(0, v), true)
+	case "local-timestamp-millis":
+		return formatTimestamp(time.UnixMilli(v), false)
+	case "local-timestamp-micros":
+		return formatTimestamp(time.UnixMicro(v), false)
+	case "local-timestamp-nanos":
+		return formatTimestamp(time.Unix(0, v), false)
+	}
+	return v
+}
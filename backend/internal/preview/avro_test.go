@@ -0,0 +1,170 @@
+package preview
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/s2"
+)
+
+const testAvroSchema = `{
+  "type": "record", "name": "Event", "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "score", "type": ["null", "double"]},
+    {"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 9, "scale": 2}},
+    {"name": "next", "type": ["null", "com.example.Event"]}
+  ]
+}`
+
+func avroString(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
+
+// avroEvent は testAvroSchema の 1 レコードをバイナリエンコーディングで書く。next は null にする。
+func avroEvent(b []byte, id int64, name string, score *float64, created int64, kind int64, tags []string, amount []byte) []byte {
+	b = binary.AppendVarint(b, id)
+	b = avroString(b, name)
+	if score == nil {
+		b = binary.AppendVarint(b, 0)
+	} else {
+		b = binary.AppendVarint(b, 1)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(*score))
+	}
+	b = binary.AppendVarint(b, created)
+	b = binary.AppendVarint(b, kind)
+	if len(tags) > 0 {
+		b = binary.AppendVarint(b, int64(len(tags)))
+		for _, tag := range tags {
+			b = avroString(b, tag)
+		}
+	}
+	b = binary.AppendVarint(b, 0)
+	b = append(binary.AppendVarint(b, int64(len(amount))), amount...)
+	return b
+}
+
+// buildAvro は records 件ずつのブロックを持つ OCF を組み立てる。
+func buildAvro(t testing.TB, codec string, blocks ...[]byte) []byte {
+	t.Helper()
+	sync := bytes.Repeat([]byte{0xab}, avroSyncSize)
+	b := append([]byte{}, avroMagic...)
+	b = binary.AppendVarint(b, 2)
+	b = avroString(b, "avro.schema")
+	b = avroString(b, testAvroSchema)
+	b = avroString(b, "avro.codec")
+	b = avroString(b, codec)
+	b = binary.AppendVarint(b, 0)
+	b = append(b, sync...)
+	for i := 0; i < len(blocks); i += 2 {
+		count, data := int64(blocks[i][0]), blocks[i+1]
+		switch codec {
+		case "deflate":
+			var buf bytes.Buffer
+			fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+			fw.Write(data)
+			fw.Close()
+			data = buf.Bytes()
+		case "snappy":
+			data = binary.BigEndian.AppendUint32(s2.EncodeSnappy(nil, data), crc32.ChecksumIEEE(data))
+		}
+		b = binary.AppendVarint(b, count)
+		b = binary.AppendVarint(b, int64(len(data)))
+		b = append(b, data...)
+		b = append(b, sync...)
+	}
+	return b
+}
+
+func TestReadAvro(t *testing.T) {
+	score := 1.5
+	first := avroEvent(nil, 1, "alice", nil, 1700000000000, 0, []string{"x", "y"}, []byte{0x30, 0x39})
+	first = binary.AppendVarint(first, 0)
+	second := avroEvent(nil, -2, "bob", &score, 0, 1, nil, []byte{0xff})
+	second = binary.AppendVarint(second, 1)
+	second = avroEvent(second, 9, "nested", nil, 0, 0, nil, []byte{0})
+	second = binary.AppendVarint(second, 0)
+	third := avroEvent(nil, 3, "carol", nil, 0, 0, nil, nil)
+	third = binary.AppendVarint(third, 0)
+
+	wantColumns := []Column{
+		{Name: "id", Type: "long"},
+		{Name: "name", Type: "string"},
+		{Name: "score", Type: "double"},
+		{Name: "created", Type: "timestamp-millis"},
+		{Name: "kind", Type: "enum<Kind>"},
+		{Name: "tags", Type: "array"},
+		{Name: "amount", Type: "decimal(9,2)"},
+		{Name: "next", Type: "record<Event>"},
+	}
+	wantRows := [][]any{
+		{int64(1), "alice", nil, "2023-11-14T22:13:20Z", "A", []any{"x", "y"}, "123.45", nil},
+		{int64(-2), "bob", 1.5, "1970-01-01T00:00:00Z", "B", []any{}, "-0.01", map[string]any{
+			"id": int64(9), "name": "nested", "score": nil, "created": "1970-01-01T00:00:00Z",
+			"kind": "A", "tags": []any{}, "amount": "0.00", "next": nil,
+		}},
+	}
+
+	for _, codec := range []string{"null", "deflate", "snappy"} {
+		t.Run(codec, func(t *testing.T) {
+			file := buildAvro(t, codec, []byte{2}, append(first, second...), []byte{1}, third)
+			got, err := ReadAvro(bytes.NewReader(file), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Table{Format: FormatAvro, Columns: wantColumns, Rows: wantRows, Truncated: true}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ReadAvro mismatch (-want +got):\n%s", diff)
+			}
+
+			all, err := ReadAvro(bytes.NewReader(file), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all.Rows) != 3 || all.Truncated {
+				t.Errorf("got %d rows, truncated = %v, want all 3 rows", len(all.Rows), all.Truncated)
+			}
+		})
+	}
+
+	t.Run("unsupported codec", func(t *testing.T) {
+		if _, err := ReadAvro(bytes.NewReader(buildAvro(t, "bzip2")), 10); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("err = %v, want ErrUnsupported", err)
+		}
+	})
+	t.Run("not avro", func(t *testing.T) {
+		if _, err := ReadAvro(bytes.NewReader([]byte("PAR1")), 10); !errors.Is(err, ErrInvalid) {
+			t.Errorf("err = %v, want ErrInvalid", err)
+		}
+	})
+	t.Run("truncated block", func(t *testing.T) {
+		file := buildAvro(t, "null", []byte{1}, first)
+		if _, err := ReadAvro(bytes.NewReader(file[:len(file)-20]), 10); !errors.Is(err, ErrInvalid) {
+			t.Errorf("err = %v, want ErrInvalid", err)
+		}
+	})
+}
+
+func FuzzReadAvro(f *testing.F) {
+	score := 1.5
+	records := avroEvent(nil, 1, "alice", &score, 1700000000000, 1, []string{"x"}, []byte{0x30, 0x39})
+	records = binary.AppendVarint(records, 0)
+	for _, codec := range []string{"null", "deflate", "snappy"} {
+		f.Add(buildAvro(f, codec, []byte{1}, records))
+	}
+	f.Add([]byte("PAR1"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		table, err := ReadAvro(bytes.NewReader(data), 3)
+		checkFuzzResult(t, table, err, 3)
+	})
+}
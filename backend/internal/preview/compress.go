@@ -0,0 +1,91 @@
+package preview
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDecodedBlock は Parquet のページ / ORC のチャンク / Avro のブロック 1 つを展開した後のサイズの上限。
+// 圧縮率の極端に高いデータ (いわゆる zip bomb) でメモリを使い切らないようにする。
+const maxDecodedBlock = 128 << 20 // 128MiB
+
+// zstdBlocks はメモリ上のブロックを展開する共有のデコーダー。DecodeAll は並行に呼んでよい。
+var zstdBlocks, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecodedBlock))
+
+// Decompress は compression に応じて r を展開しながら読む Reader を返す。CompressionNone なら r をそのまま返す。
+func Decompress(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip stream: %w: %w", ErrInvalid, err)
+		}
+		return zr, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecodedBlock))
+		if err != nil {
+			return nil, fmt.Errorf("open zstd stream: %w: %w", ErrInvalid, err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("compression %q: %w", compression, ErrUnsupported)
+}
+
+// decodeSnappy は Snappy のブロック形式 (フレーミングなし) を展開する。S2 のデコーダーは Snappy と互換。
+func decodeSnappy(src []byte) ([]byte, error) {
+	n, err := s2.DecodedLen(src)
+	if err != nil {
+		return nil, fmt.Errorf("snappy: %w: %w", ErrInvalid, err)
+	}
+	if n > maxDecodedBlock {
+		return nil, fmt.Errorf("snappy block of %d bytes: %w", n, ErrTooLarge)
+	}
+	out, err := s2.Decode(nil, src)
+	if err != nil {
+		return nil, fmt.Errorf("snappy: %w: %w", ErrInvalid, err)
+	}
+	return out, nil
+}
+
+// decodeZstd は zstd のフレームを展開する。
+func decodeZstd(src []byte) ([]byte, error) {
+	out, err := zstdBlocks.DecodeAll(src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("zstd: %w: %w", ErrInvalid, err)
+	}
+	return out, nil
+}
+
+// decodeDeflate は zlib / gzip のヘッダーを持たない生の deflate (RFC 1951) を展開する。
+func decodeDeflate(src []byte) ([]byte, error) {
+	return readDecoded("deflate", flate.NewReader(bytes.NewReader(src)))
+}
+
+// decodeGzip は gzip 形式のブロックを展開する。
+func decodeGzip(src []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w: %w", ErrInvalid, err)
+	}
+	return readDecoded("gzip", zr)
+}
+
+func readDecoded(codec string, r io.ReadCloser) ([]byte, error) {
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedBlock+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", codec, ErrInvalid, err)
+	}
+	if len(out) > maxDecodedBlock {
+		return nil, fmt.Errorf("%s block over %d bytes: %w", codec, maxDecodedBlock, ErrTooLarge)
+	}
+	return out, nil
+}
@@ -0,0 +1,656 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// orcMagic は ORC ファイルの先頭と PostScript に置かれるマジック。
+const orcMagic = "ORC"
+
+// orcTailSize は PostScript と Footer を探すために最初に末尾から読むバイト数。
+const orcTailSize = 64 << 10 // 64KiB
+
+// orcStreamBuffer はストリームを読むときのバッファの最大サイズ (範囲リクエスト 1 回で読む量)。
+const orcStreamBuffer = 256 << 10 // 256KiB
+
+// maxORCDictionary は辞書エンコーディングの列の辞書の要素数の上限。辞書は LENGTH ストリームの RLE から
+// 数バイトで任意の数の要素を表せるため、ColumnEncoding の dictionarySize を信じて確保しないようにする。
+const maxORCDictionary = 1 << 22
+
+// orcEpoch は ORC のタイムスタンプの基準 (2015-01-01 00:00:00) の Unix 秒。
+const orcEpoch = 1420070400
+
+// CompressionKind の名前。プレビューが展開できるのは NONE / ZLIB / SNAPPY / ZSTD。
+var orcCodecs = []string{"NONE", "ZLIB", "SNAPPY", "LZO", "LZ4", "ZSTD"}
+
+const (
+	orcNone   = 0
+	orcZlib   = 1
+	orcSnappy = 2
+	orcZstd   = 5
+)
+
+// Type.Kind。
+const (
+	orcBoolean = iota
+	orcByte
+	orcShort
+	orcInt
+	orcLong
+	orcFloat
+	orcDouble
+	orcString
+	orcBinary
+	orcTimestamp
+	orcList
+	orcMap
+	orcStruct
+	orcUnion
+	orcDecimal
+	orcDate
+	orcVarchar
+	orcChar
+	orcTimestampInstant
+)
+
+// orcKindNames は Type.Kind の型名 (Hive の表記)。
+var orcKindNames = []string{
+	"boolean", "tinyint", "smallint", "int", "bigint", "float", "double", "string", "binary", "timestamp",
+	"array", "map", "struct", "uniontype", "decimal", "date", "varchar", "char", "timestamp with local time zone",
+}
+
+// Stream.Kind。
+const (
+	orcPresent        = 0
+	orcData           = 1
+	orcLength         = 2
+	orcDictionaryData = 3
+	orcSecondary      = 5
+)
+
+// ColumnEncoding.Kind。
+const (
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// orcColumn は表の 1 列になるトップレベルのプリミティブ型の列。
+type orcColumn struct {
+	// id は Footer.types の位置 (= ストリームとエンコーディングの列番号)。
+	id   uint64
+	name string
+	kind uint64
+}
+
+// orcStreamKey はストライプ内のストリームを列番号と種類で引くためのキー。
+type orcStreamKey struct {
+	column, kind uint64
+}
+
+// orcStripe は 1 ストライプのストリームの位置とエンコーディング。
+type orcStripe struct {
+	ra          io.ReaderAt
+	compression uint64
+	streams     map[orcStreamKey][2]int64
+	encodings   []pbFields
+}
+
+// ReadORC は ORC ファイルの末尾の PostScript と Footer からスキーマを読み、先頭のストライプから最初の limit 行を
+// 表にする。r は ORC ファイル全体 (size バイト) で、必要な範囲 (メタデータと各列のストリームの先頭) だけを読む。
+// トップレベルのプリミティブ型の列を表にし、array / map / struct / uniontype の列は SkippedColumns に入れる。
+func ReadORC(r io.ReaderAt, size int64, limit int) (*Table, error) {
+	ra := newBudgetReaderAt(r)
+	if size <= int64(len(orcMagic)) {
+		return nil, fmt.Errorf("orc: %w: file of %d bytes", ErrInvalid, size)
+	}
+	tail := make([]byte, min(size, orcTailSize))
+	if _, err := ra.ReadAt(tail, size-int64(len(tail))); err != nil && !errors.Is(err, io.EOF) {
+		return nil, orcReadError(err)
+	}
+	psLen := int64(tail[len(tail)-1])
+	if psLen+1 > int64(len(tail)) {
+		return nil, fmt.Errorf("orc: %w: postscript of %d bytes", ErrInvalid, psLen)
+	}
+	ps, err := parseProtobuf(tail[int64(len(tail))-1-psLen : len(tail)-1])
+	if err != nil {
+		return nil, fmt.Errorf("orc postscript: %w", err)
+	}
+	if magic := ps.bytes(8000); len(magic) == 0 || string(magic[0]) != orcMagic {
+		return nil, fmt.Errorf("orc: %w: missing magic", ErrInvalid)
+	}
+	compression := ps.uint(2)
+	if compression != orcNone && compression != orcZlib && compression != orcSnappy && compression != orcZstd {
+		name := strconv.FormatUint(compression, 10)
+		if compression < uint64(len(orcCodecs)) {
+			name = orcCodecs[compression]
+		}
+		return nil, fmt.Errorf("orc compression %s: %w", name, ErrUnsupported)
+	}
+
+	footerLen := int64(ps.uint(1))
+	footerEnd := size - 1 - psLen
+	if footerLen < 0 || footerLen > footerEnd {
+		return nil, fmt.Errorf("orc: %w: footer of %d bytes", ErrInvalid, footerLen)
+	}
+	var footerRaw []byte
+	if tailStart := size - int64(len(tail)); footerEnd-footerLen >= tailStart {
+		footerRaw = tail[footerEnd-footerLen-tailStart : footerEnd-tailStart]
+	} else {
+		footerRaw = make([]byte, footerLen)
+		if _, err := ra.ReadAt(footerRaw, footerEnd-footerLen); err != nil {
+			return nil, orcReadError(err)
+		}
+	}
+	footerData, err := orcDecompressAll(footerRaw, compression)
+	if err != nil {
+		return nil, fmt.Errorf("orc footer: %w", err)
+	}
+	footer, err := parseProtobuf(footerData)
+	if err != nil {
+		return nil, fmt.Errorf("orc footer: %w", err)
+	}
+	types, err := footer.messages(4)
+	if err != nil {
+		return nil, err
+	}
+	stripes, err := footer.messages(3)
+	if err != nil {
+		return nil, err
+	}
+
+	table := newTable(FormatORC, limit)
+	columns, err := orcColumns(types, table)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]any, len(columns))
+	rows := 0
+	for _, info := range stripes {
+		if rows >= limit {
+			break
+		}
+		need := int(min(uint64(limit-rows), info.uint(5)))
+		if need == 0 {
+			continue
+		}
+		stripe, err := openORCStripe(ra, info, compression)
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range columns {
+			v, err := stripe.readColumn(c, need)
+			if err != nil {
+				return nil, fmt.Errorf("orc column %s: %w", c.name, err)
+			}
+			values[i] = append(values[i], v...)
+		}
+		rows += need
+	}
+	for r := range rows {
+		row := make([]any, len(columns))
+		for i := range columns {
+			row[i] = values[i][r]
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	table.Truncated = footer.uint(6) > uint64(rows)
+	return table, nil
+}
+
+func orcReadError(err error) error {
+	if errors.Is(err, ErrTooLarge) || errors.Is(err, ErrInvalid) || errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("orc: %w: unexpected end of stream", ErrInvalid)
+	}
+	return fmt.Errorf("read orc: %w", err)
+}
+
+// orcColumns はルートの struct の子を表の列にし、table の Columns / SkippedColumns を埋める。
+func orcColumns(types []pbFields, table *Table) ([]*orcColumn, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("orc: %w: empty schema", ErrInvalid)
+	}
+	var columns []*orcColumn
+	add := func(id uint64, name string) {
+		t := types[id]
+		kind := t.uint(1)
+		switch kind {
+		case orcList, orcMap, orcStruct, orcUnion:
+			table.SkippedColumns = append(table.SkippedColumns, name)
+			return
+		}
+		typeName := "unknown"
+		if kind < uint64(len(orcKindNames)) {
+			typeName = orcKindNames[kind]
+		}
+		switch kind {
+		case orcDecimal:
+			typeName = fmt.Sprintf("decimal(%d,%d)", t.uint(5), t.uint(6))
+		case orcVarchar, orcChar:
+			typeName = fmt.Sprintf("%s(%d)", typeName, t.uint(4))
+		}
+		columns = append(columns, &orcColumn{id: id, name: name, kind: kind})
+		table.Columns = append(table.Columns, Column{Name: name, Type: typeName})
+	}
+
+	root := types[0]
+	if root.uint(1) != orcStruct {
+		add(0, "value")
+		return columns, nil
+	}
+	subtypes, err := root.uints(2)
+	if err != nil {
+		return nil, err
+	}
+	names := root.bytes(3)
+	for i, id := range subtypes {
+		if id >= uint64(len(types)) {
+			return nil, fmt.Errorf("orc: %w: subtype %d of %d types", ErrInvalid, id, len(types))
+		}
+		name := "_col" + strconv.Itoa(i)
+		if i < len(names) {
+			name = string(names[i])
+		}
+		add(id, name)
+	}
+	return columns, nil
+}
+
+// openORCStripe はストライプのフッターを読み、ストリームの位置を求める。ストリームはフッターに並んだ順に
+// ストライプの先頭から連続して置かれている。
+func openORCStripe(ra io.ReaderAt, info pbFields, compression uint64) (*orcStripe, error) {
+	offset, indexLen, dataLen, footerLen := int64(info.uint(1)), int64(info.uint(2)), int64(info.uint(3)), int64(info.uint(4))
+	if offset < 0 || indexLen < 0 || dataLen < 0 || footerLen < 0 || footerLen > MaxReadBytes {
+		return nil, fmt.Errorf("orc: %w: stripe footer of %d bytes", ErrInvalid, footerLen)
+	}
+	raw := make([]byte, footerLen)
+	if _, err := ra.ReadAt(raw, offset+indexLen+dataLen); err != nil {
+		return nil, orcReadError(err)
+	}
+	data, err := orcDecompressAll(raw, compression)
+	if err != nil {
+		return nil, fmt.Errorf("orc stripe footer: %w", err)
+	}
+	footer, err := parseProtobuf(data)
+	if err != nil {
+		return nil, fmt.Errorf("orc stripe footer: %w", err)
+	}
+	streams, err := footer.messages(1)
+	if err != nil {
+		return nil, err
+	}
+	encodings, err := footer.messages(2)
+	if err != nil {
+		return nil, err
+	}
+	stripe := &orcStripe{ra: ra, compression: compression, streams: map[orcStreamKey][2]int64{}, encodings: encodings}
+	pos := offset
+	for _, s := range streams {
+		length := int64(s.uint(3))
+		if length < 0 {
+			return nil, fmt.Errorf("orc: %w: stream of %d bytes", ErrInvalid, length)
+		}
+		stripe.streams[orcStreamKey{column: s.uint(2), kind: s.uint(1)}] = [2]int64{pos, length}
+		pos += length
+	}
+	return stripe, nil
+}
+
+// stream は列 column の種類 kind のストリームを展開しながら読む Reader を返す。ストリームが無ければ nil。
+func (s *orcStripe) stream(column, kind uint64) *bufio.Reader {
+	loc, ok := s.streams[orcStreamKey{column: column, kind: kind}]
+	if !ok {
+		return nil
+	}
+	var r io.Reader = bufio.NewReaderSize(io.NewSectionReader(s.ra, loc[0], loc[1]), int(max(16, min(loc[1], orcStreamBuffer))))
+	if s.compression != orcNone {
+		r = &orcChunkReader{r: r, compression: s.compression}
+	}
+	return bufio.NewReader(r)
+}
+
+// readColumn は列 c の先頭から n 行分の値を読む。
+func (s *orcStripe) readColumn(c *orcColumn, n int) ([]any, error) {
+	if c.id >= uint64(len(s.encodings)) {
+		return nil, fmt.Errorf("%w: no encoding for column %d", ErrInvalid, c.id)
+	}
+	encoding := s.encodings[c.id].uint(1)
+	v2 := encoding == orcDirectV2 || encoding == orcDictionaryV2
+	stream := func(kind uint64) *bufio.Reader {
+		if r := s.stream(c.id, kind); r != nil {
+			return r
+		}
+		return bufio.NewReader(bytes.NewReader(nil))
+	}
+	ints := func(kind uint64, count int, signed bool) ([]int64, error) {
+		if v2 {
+			return readORCIntRLEv2(stream(kind), count, signed)
+		}
+		return readORCIntRLEv1(stream(kind), count, signed)
+	}
+
+	present := make([]bool, n)
+	count := n
+	if r := s.stream(c.id, orcPresent); r != nil {
+		bits, err := readORCBooleans(r, n)
+		if err != nil {
+			return nil, err
+		}
+		present, count = bits, 0
+		for _, p := range bits {
+			if p {
+				count++
+			}
+		}
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+
+	raw := make([]any, 0, count)
+	switch c.kind {
+	case orcBoolean:
+		bits, err := readORCBooleans(stream(orcData), count)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bits {
+			raw = append(raw, b)
+		}
+	case orcByte:
+		b, err := readORCByteRLE(stream(orcData), count)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range b {
+			raw = append(raw, int64(int8(v)))
+		}
+	case orcShort, orcInt, orcLong, orcDate:
+		v, err := ints(orcData, count, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range v {
+			if c.kind == orcDate {
+				raw = append(raw, formatDate(x))
+			} else {
+				raw = append(raw, x)
+			}
+		}
+	case orcFloat, orcDouble:
+		width := 8
+		if c.kind == orcFloat {
+			width = 4
+		}
+		b, err := readORCBytes(stream(orcData), int64(count*width))
+		if err != nil {
+			return nil, err
+		}
+		for i := range count {
+			if width == 4 {
+				raw = append(raw, jsonFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:])))))
+			} else {
+				raw = append(raw, jsonFloat(math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))))
+			}
+		}
+	case orcString, orcVarchar, orcChar, orcBinary:
+		var values [][]byte
+		if encoding == orcDictionary || encoding == orcDictionaryV2 {
+			size := s.encodings[c.id].uint(2)
+			if size > maxORCDictionary {
+				return nil, fmt.Errorf("%w: dictionary of %d entries", ErrInvalid, size)
+			}
+			dict, err := readORCByteArrays(ints, stream(orcDictionaryData), int(size))
+			if err != nil {
+				return nil, err
+			}
+			indices, err := ints(orcData, count, false)
+			if err != nil {
+				return nil, err
+			}
+			for _, i := range indices {
+				if i < 0 || i >= int64(len(dict)) {
+					return nil, fmt.Errorf("%w: dictionary index %d of %d", ErrInvalid, i, len(dict))
+				}
+				values = append(values, dict[i])
+			}
+		} else {
+			var err error
+			if values, err = readORCByteArrays(ints, stream(orcData), count); err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range values {
+			if c.kind == orcBinary {
+				raw = append(raw, jsonBytes(v))
+			} else {
+				raw = append(raw, string(v))
+			}
+		}
+	case orcDecimal:
+		data := stream(orcData)
+		unscaled := make([]*big.Int, count)
+		for i := range unscaled {
+			v, err := readORCBigVarint(data)
+			if err != nil {
+				return nil, err
+			}
+			unscaled[i] = v
+		}
+		scales, err := ints(orcSecondary, count, true)
+		if err != nil {
+			return nil, err
+		}
+		if len(scales) != count {
+			return nil, fmt.Errorf("%w: %d decimal scales for %d values", ErrInvalid, len(scales), count)
+		}
+		for i, v := range unscaled {
+			if err := checkDecimalScale(scales[i]); err != nil {
+				return nil, err
+			}
+			raw = append(raw, formatDecimal(v, int(scales[i])))
+		}
+	case orcTimestamp, orcTimestampInstant:
+		secs, err := ints(orcData, count, true)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := ints(orcSecondary, count, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(secs) != count || len(nanos) != count {
+			return nil, fmt.Errorf("%w: %d seconds and %d nanoseconds for %d timestamps", ErrInvalid, len(secs), len(nanos), count)
+		}
+		for i := range secs {
+			raw = append(raw, orcTimestampValue(secs[i], nanos[i], c.kind == orcTimestampInstant))
+		}
+	default:
+		return nil, fmt.Errorf("type %d: %w", c.kind, ErrUnsupported)
+	}
+
+	if len(raw) != count {
+		return nil, fmt.Errorf("%w: %d values for %d present rows", ErrInvalid, len(raw), count)
+	}
+	out := make([]any, n)
+	j := 0
+	for i, p := range present {
+		if p {
+			out[i] = raw[j]
+			j++
+		}
+	}
+	return out, nil
+}
+
+// orcTimestampValue はタイムスタンプの秒 (2015-01-01 からの秒) とナノ秒を文字列にする。ナノ秒は末尾の 0 の
+// 桁数を下位 3 ビットに入れた形で書かれている。TIMESTAMP はライターのタイムゾーンの壁時計の時刻のため、
+// オフセットを付けずに返す。
+func orcTimestampValue(secs, encodedNanos int64, instant bool) string {
+	nanos := encodedNanos >> 3
+	if zeros := encodedNanos & 7; zeros != 0 {
+		for range zeros + 1 {
+			nanos *= 10
+		}
+	}
+	unix := secs + orcEpoch
+	// 1970 年より前の値は秒が切り捨てではなく 0 方向に丸めて書かれている (ORC の C++ / Java 実装と同じ補正)。
+	if unix < 0 && nanos > 999999 {
+		unix--
+	}
+	return formatTimestamp(time.Unix(unix, nanos), instant)
+}
+
+// readORCByteArrays は LENGTH ストリームの長さで data を count 個のバイト列に分ける。
+func readORCByteArrays(ints func(uint64, int, bool) ([]int64, error), data *bufio.Reader, count int) ([][]byte, error) {
+	lengths, err := ints(orcLength, count, false)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, l := range lengths {
+		if l < 0 {
+			return nil, fmt.Errorf("%w: negative length", ErrInvalid)
+		}
+		if l > MaxReadBytes-total {
+			return nil, fmt.Errorf("byte arrays in a stream: %w", ErrTooLarge)
+		}
+		total += l
+	}
+	b, err := readORCBytes(data, total)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(lengths))
+	for i, l := range lengths {
+		out[i], b = b[:l], b[l:]
+	}
+	return out, nil
+}
+
+func readORCBytes(r io.Reader, n int64) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: stream of %d bytes", ErrInvalid, n)
+	}
+	if n > MaxReadBytes {
+		return nil, fmt.Errorf("%d bytes in a stream: %w", n, ErrTooLarge)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, orcReadError(err)
+	}
+	return b, nil
+}
+
+// orcChunkReader は圧縮されたストリームを展開しながら読む。ストリームは 3 バイトのヘッダー
+// (長さ << 1 | 非圧縮なら 1) と本体のチャンクの並び。
+type orcChunkReader struct {
+	r           io.Reader
+	compression uint64
+	buf         []byte
+}
+
+func (c *orcChunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		var h [3]byte
+		if _, err := io.ReadFull(c.r, h[:]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("%w: truncated chunk header", ErrInvalid)
+			}
+			return 0, err
+		}
+		header := int(h[0]) | int(h[1])<<8 | int(h[2])<<16
+		chunk := make([]byte, header>>1)
+		if _, err := io.ReadFull(c.r, chunk); err != nil {
+			return 0, orcReadError(err)
+		}
+		if header&1 == 1 {
+			c.buf = chunk
+			continue
+		}
+		var err error
+		switch c.compression {
+		case orcZlib:
+			c.buf, err = decodeDeflate(chunk)
+		case orcSnappy:
+			c.buf, err = decodeSnappy(chunk)
+		case orcZstd:
+			c.buf, err = decodeZstd(chunk)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// orcDecompressAll はメモリ上の圧縮されたメタデータ (Footer / StripeFooter) を展開する。
+func orcDecompressAll(data []byte, compression uint64) ([]byte, error) {
+	if compression == orcNone {
+		return data, nil
+	}
+	out, err := io.ReadAll(io.LimitReader(&orcChunkReader{r: bytes.NewReader(data), compression: compression}, maxDecodedBlock+1))
+	if err != nil {
+		return nil, orcReadError(err)
+	}
+	if len(out) > maxDecodedBlock {
+		return nil, fmt.Errorf("metadata over %d bytes: %w", maxDecodedBlock, ErrTooLarge)
+	}
+	return out, nil
+}
+
+// readORCBigVarint は decimal の値 (上限のない base 128 の zigzag varint) を読む。
+func readORCBigVarint(r io.ByteReader) (*big.Int, error) {
+	v := new(big.Int)
+	for shift := uint(0); ; shift += 7 {
+		if shift > 7*20 { // decimal(38) は 128 ビットに収まる
+			return nil, fmt.Errorf("%w: decimal varint too long", ErrInvalid)
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, orcReadError(err)
+		}
+		v.Or(v, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), shift))
+		if b < 0x80 {
+			break
+		}
+	}
+	negative := v.Bit(0) == 1
+	v.Rsh(v, 1)
+	if negative {
+		v.Add(v, big.NewInt(1)).Neg(v)
+	}
+	return v, nil
+}
+
+// readORCVarint は base 128 varint を読み、signed なら zigzag を戻す。
+func readORCVarint(r io.ByteReader, signed bool) (int64, error) {
+	u, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, orcReadError(err)
+	}
+	return unzigzag(u, signed), nil
+}
+
+func unzigzag(u uint64, signed bool) int64 {
+	if signed {
+		return int64(u>>1) ^ -int64(u&1)
+	}
+	return int64(u)
+}
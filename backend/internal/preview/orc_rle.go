@@ -0,0 +1,261 @@
+package preview
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// readORCByteRLE は Byte RLE (0..127 は続く 1 バイトの 3 回以上の繰り返し、128..255 は 256-c 個のリテラル) を
+// n バイト読む。
+func readORCByteRLE(r *bufio.Reader, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, orcReadError(err)
+		}
+		if c < 0x80 {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, orcReadError(err)
+			}
+			for i := 0; i < int(c)+3 && len(out) < n; i++ {
+				out = append(out, v)
+			}
+			continue
+		}
+		literals, err := readORCBytes(r, int64(256-int(c)))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, literals[:min(len(literals), n-len(out))]...)
+	}
+	return out, nil
+}
+
+// readORCBooleans は Byte RLE で書かれたビット列 (上位ビットから) を n 個読む。PRESENT ストリームと boolean 列で使う。
+func readORCBooleans(r *bufio.Reader, n int) ([]bool, error) {
+	b, err := readORCByteRLE(r, (n+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = b[i/8]>>(7-i%8)&1 == 1
+	}
+	return out, nil
+}
+
+// readORCIntRLEv1 は DIRECT / DICTIONARY エンコーディングの整数 (RLE v1) を n 個読む。
+func readORCIntRLEv1(r *bufio.Reader, n int, signed bool) ([]int64, error) {
+	out := make([]int64, 0, n)
+	for len(out) < n {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, orcReadError(err)
+		}
+		if c < 0x80 {
+			delta, err := r.ReadByte()
+			if err != nil {
+				return nil, orcReadError(err)
+			}
+			base, err := readORCVarint(r, signed)
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < int(c)+3 && len(out) < n; i++ {
+				out = append(out, base+int64(i)*int64(int8(delta)))
+			}
+			continue
+		}
+		for i := 0; i < 256-int(c); i++ {
+			v, err := readORCVarint(r, signed)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) < n {
+				out = append(out, v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// readORCIntRLEv2 は DIRECT_V2 / DICTIONARY_V2 エンコーディングの整数 (RLE v2) を n 個読む。先頭バイトの
+// 上位 2 ビットが SHORT_REPEAT / DIRECT / PATCHED_BASE / DELTA のどれかを表す。
+func readORCIntRLEv2(r *bufio.Reader, n int, signed bool) ([]int64, error) {
+	out := make([]int64, 0, n)
+	for len(out) < n {
+		header, err := readORCBytes(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		first := header[0]
+		var run []int64
+		switch first >> 6 {
+		case 0: // SHORT_REPEAT: 幅 1..8 バイトの値を 3..10 回繰り返す
+			v, err := readORCBigEndian(r, int(first>>3&7)+1)
+			if err != nil {
+				return nil, err
+			}
+			for range int(first&7) + 3 {
+				run = append(run, unzigzag(v, signed))
+			}
+		case 1: // DIRECT: 固定ビット幅で詰めた値の並び
+			second, err := readORCBytes(r, 1)
+			if err != nil {
+				return nil, err
+			}
+			values, err := readORCPacked(r, int(first&1)<<8|int(second[0])+1, orcWidth(int(first>>1&0x1f)))
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range values {
+				run = append(run, unzigzag(v, signed))
+			}
+		case 2: // PATCHED_BASE: 基準値からの差と、幅に収まらない上位ビットのパッチ
+			h, err := readORCBytes(r, 3)
+			if err != nil {
+				return nil, err
+			}
+			width := orcWidth(int(first >> 1 & 0x1f))
+			length := int(first&1)<<8 | int(h[0]) + 1
+			baseWidth := int(h[1]>>5&7) + 1
+			patchWidth := orcWidth(int(h[1] & 0x1f))
+			gapWidth := int(h[2]>>5&7) + 1
+			patches := int(h[2] & 0x1f)
+
+			u, err := readORCBigEndian(r, baseWidth)
+			if err != nil {
+				return nil, err
+			}
+			// 基準値は最上位ビットを符号とする符号と絶対値の表現。
+			sign := uint64(1) << (baseWidth*8 - 1)
+			base := int64(u &^ sign)
+			if u&sign != 0 {
+				base = -base
+			}
+			values, err := readORCPacked(r, length, width)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := readORCPacked(r, patches, orcClosestWidth(gapWidth+patchWidth))
+			if err != nil {
+				return nil, err
+			}
+			idx := 0
+			for _, e := range entries {
+				// 間隔が 255 を超えるパッチは、値 0 のパッチ (間隔 255) を挟んで表す。値 0 の OR は何も変えない。
+				idx += int(e >> patchWidth)
+				if idx >= 0 && idx < len(values) && patchWidth < 64 {
+					values[idx] |= (e & (1<<patchWidth - 1)) << width
+				}
+			}
+			for _, v := range values {
+				run = append(run, base+int64(v))
+			}
+		case 3: // DELTA: 先頭の値と差分 (幅 0 なら一定の差分)
+			second, err := readORCBytes(r, 1)
+			if err != nil {
+				return nil, err
+			}
+			width := 0
+			if w := int(first >> 1 & 0x1f); w != 0 {
+				width = orcWidth(w)
+			}
+			length := int(first&1)<<8 | int(second[0]) + 1
+			base, err := readORCVarint(r, signed)
+			if err != nil {
+				return nil, err
+			}
+			deltaBase, err := readORCVarint(r, true)
+			if err != nil {
+				return nil, err
+			}
+			run = append(run, base)
+			if length > 1 {
+				prev := base + deltaBase
+				run = append(run, prev)
+				if width == 0 {
+					for range length - 2 {
+						prev += deltaBase
+						run = append(run, prev)
+					}
+				} else {
+					deltas, err := readORCPacked(r, length-2, width)
+					if err != nil {
+						return nil, err
+					}
+					for _, d := range deltas {
+						if deltaBase < 0 {
+							prev -= int64(d)
+						} else {
+							prev += int64(d)
+						}
+						run = append(run, prev)
+					}
+				}
+			}
+		}
+		out = append(out, run[:min(len(run), n-len(out))]...)
+	}
+	return out, nil
+}
+
+// readORCBigEndian は width バイトのビッグエンディアンの符号なし整数を読む。
+func readORCBigEndian(r io.Reader, width int) (uint64, error) {
+	b, err := readORCBytes(r, int64(width))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v, nil
+}
+
+// readORCPacked は上位ビットから詰めた width ビットの値を count 個読む。値の並びの末尾はバイト境界までの詰め物。
+func readORCPacked(r io.Reader, count, width int) ([]uint64, error) {
+	if width < 1 || width > 64 {
+		return nil, fmt.Errorf("%w: bit width %d", ErrInvalid, width)
+	}
+	b, err := readORCBytes(r, (int64(count)*int64(width)+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint64, count)
+	bit := 0
+	for i := range out {
+		var v uint64
+		for need := width; need > 0; {
+			off := bit % 8
+			take := min(8-off, need)
+			chunk := uint64(b[bit/8]) >> (8 - off - take) & (1<<take - 1)
+			v = v<<take | chunk
+			bit += take
+			need -= take
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// orcWidth は 5 ビットで符号化されたビット幅を戻す (0..23 は 1..24、以降は 26 / 28 / 30 / 32 / 40 / 48 / 56 / 64)。
+func orcWidth(encoded int) int {
+	if encoded < 24 {
+		return encoded + 1
+	}
+	return []int{26, 28, 30, 32, 40, 48, 56, 64}[encoded-24]
+}
+
+// orcClosestWidth は n ビット以上で表現できる最小の符号化可能なビット幅を返す (パッチの幅に使う)。
+func orcClosestWidth(n int) int {
+	for _, w := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 26, 28, 30, 32, 40, 48, 56, 64} {
+		if w >= n {
+			return w
+		}
+	}
+	return 64
+}
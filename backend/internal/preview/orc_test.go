@@ -0,0 +1,238 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func pbVarint(b []byte, num protowire.Number, v uint64) []byte {
+	return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), v)
+}
+
+func pbBytes(b []byte, num protowire.Number, v []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), v)
+}
+
+// orcZlibChunk は data を ZLIB (raw deflate) のチャンク 1 つにする。original なら非圧縮のチャンクにする。
+func orcZlibChunk(t testing.TB, data []byte, original bool) []byte {
+	t.Helper()
+	body := data
+	if !original {
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+		fw.Close()
+		body = buf.Bytes()
+	}
+	header := len(body) << 1
+	if original {
+		header |= 1
+	}
+	return append([]byte{byte(header), byte(header >> 8), byte(header >> 16)}, body...)
+}
+
+// buildORC は 4 行 1 ストライプの ZLIB 圧縮の ORC ファイルを組み立てる。列は id (bigint, RLE v2 DELTA)、
+// name (string, 辞書 + PRESENT)、score (double, 非圧縮のチャンク)、tags (array<string>、プレビュー対象外)。
+func buildORC(t testing.TB) []byte {
+	t.Helper()
+	var scores []byte
+	for _, v := range []float64{1.5, -2.25, 0, 1e10} {
+		scores = binary.LittleEndian.AppendUint64(scores, math.Float64bits(v))
+	}
+	streams := []struct {
+		column, kind uint64
+		data         []byte
+		original     bool
+	}{
+		{1, orcData, []byte{0xc0, 0x03, 0x02, 0x02}, false}, // DELTA: 1 から 1 ずつ 4 個
+		{2, orcPresent, []byte{0xff, 0xb0}, false},          // リテラル 1 バイト: 1,0,1,1
+		{2, orcData, []byte{0x40, 0x02, 0x40}, false},       // DIRECT 幅 1: 0,1,0
+		{2, orcLength, []byte{0x44, 0x01, 0xac}, false},     // DIRECT 幅 3: 5,3
+		{2, orcDictionaryData, []byte("alicebob"), false},   // 辞書
+		{3, orcData, scores, true},                          // 非圧縮のチャンク
+	}
+
+	file := []byte(orcMagic)
+	var data, stripeFooter []byte
+	for _, s := range streams {
+		chunk := orcZlibChunk(t, s.data, s.original)
+		data = append(data, chunk...)
+		var stream []byte
+		stream = pbVarint(stream, 1, s.kind)
+		stream = pbVarint(stream, 2, s.column)
+		stream = pbVarint(stream, 3, uint64(len(chunk)))
+		stripeFooter = pbBytes(stripeFooter, 1, stream)
+	}
+	for _, e := range [][2]uint64{{orcDirect, 0}, {orcDirectV2, 0}, {orcDictionaryV2, 2}, {orcDirect, 0}, {orcDirectV2, 0}, {orcDirectV2, 0}} {
+		stripeFooter = pbBytes(stripeFooter, 2, pbVarint(pbVarint(nil, 1, e[0]), 2, e[1]))
+	}
+	stripeFooter = orcZlibChunk(t, stripeFooter, false)
+	file = append(file, data...)
+	file = append(file, stripeFooter...)
+
+	var stripe []byte
+	stripe = pbVarint(stripe, 1, uint64(len(orcMagic)))
+	stripe = pbVarint(stripe, 2, 0)
+	stripe = pbVarint(stripe, 3, uint64(len(data)))
+	stripe = pbVarint(stripe, 4, uint64(len(stripeFooter)))
+	stripe = pbVarint(stripe, 5, 4)
+
+	var root []byte
+	root = pbVarint(root, 1, orcStruct)
+	root = pbBytes(root, 2, []byte{1, 2, 3, 4})
+	for _, name := range []string{"id", "name", "score", "tags"} {
+		root = pbBytes(root, 3, []byte(name))
+	}
+	var footer []byte
+	footer = pbVarint(footer, 1, uint64(len(orcMagic)))
+	footer = pbVarint(footer, 2, uint64(len(data)+len(stripeFooter)))
+	footer = pbBytes(footer, 3, stripe)
+	footer = pbBytes(footer, 4, root)
+	footer = pbBytes(footer, 4, pbVarint(nil, 1, orcLong))
+	footer = pbBytes(footer, 4, pbVarint(nil, 1, orcString))
+	footer = pbBytes(footer, 4, pbVarint(nil, 1, orcDouble))
+	footer = pbBytes(footer, 4, pbBytes(pbVarint(nil, 1, orcList), 2, []byte{5}))
+	footer = pbBytes(footer, 4, pbVarint(nil, 1, orcString))
+	footer = pbVarint(footer, 6, 4)
+	footer = orcZlibChunk(t, footer, false)
+	file = append(file, footer...)
+
+	var ps []byte
+	ps = pbVarint(ps, 1, uint64(len(footer)))
+	ps = pbVarint(ps, 2, orcZlib)
+	ps = pbVarint(ps, 3, 256<<10)
+	ps = pbBytes(ps, 8000, []byte(orcMagic))
+	file = append(file, ps...)
+	return append(file, byte(len(ps)))
+}
+
+func TestReadORC(t *testing.T) {
+	file := buildORC(t)
+	columns := []Column{
+		{Name: "id", Type: "bigint"},
+		{Name: "name", Type: "string"},
+		{Name: "score", Type: "double"},
+	}
+	rows := [][]any{
+		{int64(1), "alice", 1.5},
+		{int64(2), nil, -2.25},
+		{int64(3), "bob", 0.0},
+		{int64(4), "alice", 1e10},
+	}
+	for _, limit := range []int{1, 3, 4, 100} {
+		got, err := ReadORC(bytes.NewReader(file), int64(len(file)), limit)
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		n := min(limit, len(rows))
+		want := &Table{Format: FormatORC, Columns: columns, Rows: rows[:n], Truncated: n < len(rows), SkippedColumns: []string{"tags"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("limit %d: ReadORC mismatch (-want +got):\n%s", limit, diff)
+		}
+	}
+
+	t.Run("not orc", func(t *testing.T) {
+		data := []byte("PAR1 not an orc file")
+		if _, err := ReadORC(bytes.NewReader(data), int64(len(data)), 10); !errors.Is(err, ErrInvalid) {
+			t.Errorf("err = %v, want ErrInvalid", err)
+		}
+	})
+	t.Run("unsupported compression", func(t *testing.T) {
+		ps := pbBytes(pbVarint(pbVarint(nil, 1, 0), 2, 4), 8000, []byte(orcMagic))
+		data := append(append([]byte(orcMagic), ps...), byte(len(ps)))
+		if _, err := ReadORC(bytes.NewReader(data), int64(len(data)), 10); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("err = %v, want ErrUnsupported", err)
+		}
+	})
+}
+
+func TestReadORCIntRLEv2(t *testing.T) {
+	// ORC の仕様書のエンコーディング例。
+	tests := []struct {
+		name string
+		data []byte
+		want []int64
+	}{
+		{name: "short repeat", data: []byte{0x0a, 0x27, 0x10}, want: []int64{10000, 10000, 10000, 10000, 10000}},
+		{name: "direct", data: []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, want: []int64{23713, 43806, 57005, 48879}},
+		{
+			name: "patched base",
+			data: []byte{
+				0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46, 0x50, 0x5a,
+				0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8,
+			},
+			want: []int64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190},
+		},
+		{name: "delta", data: []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, want: []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readORCIntRLEv2(bufio.NewReader(bytes.NewReader(tt.data)), len(tt.want), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readORCIntRLEv2(bufio.NewReader(bytes.NewReader([]byte{0x5e, 0x03, 0x5c})), 4, false); !errors.Is(err, ErrInvalid) {
+		t.Errorf("truncated run err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestReadORCByteRLE(t *testing.T) {
+	got, err := readORCByteRLE(bufio.NewReader(bytes.NewReader([]byte{0x61, 0x00, 0xfe, 0x44, 0x45})), 102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(make([]byte, 100), 0x44, 0x45)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestORCTimestampValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		secs    int64
+		nanos   int64
+		instant bool
+		want    string
+	}{
+		{name: "epoch", want: "2015-01-01T00:00:00"},
+		{name: "instant", instant: true, want: "2015-01-01T00:00:00Z"},
+		{name: "micros with trailing zeros", secs: 86400, nanos: 1<<3 | 2, want: "2015-01-02T00:00:00.000001"},
+		{name: "before 1970", secs: -1 - orcEpoch, nanos: 5<<3 | 7, want: "1969-12-31T23:59:58.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orcTimestampValue(tt.secs, tt.nanos, tt.instant); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadORC(f *testing.F) {
+	file := buildORC(f)
+	f.Add(file)
+	f.Add([]byte("PAR1 not an orc file"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		table, err := ReadORC(bytes.NewReader(data), int64(len(data)), 3)
+		checkFuzzResult(t, table, err, 3)
+	})
+}
@@ -0,0 +1,934 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parquetMagic は Parquet ファイルの先頭と末尾に置かれる 4 バイト。
+const parquetMagic = "PAR1"
+
+// parquetTailSize はフッター (FileMetaData) を探すために最初に末尾から読むバイト数。ほとんどのファイルは
+// これ 1 回でメタデータまで読め、S3 / GCS への範囲リクエストが 1 回で済む。
+const parquetTailSize = 64 << 10 // 64KiB
+
+// parquetChunkBuffer は列チャンクを読むときのバッファの最大サイズ (範囲リクエスト 1 回で読む量)。
+const parquetChunkBuffer = 1 << 20 // 1MiB
+
+// Parquet の物理型 (Type)。
+const (
+	parquetBoolean = iota
+	parquetInt32
+	parquetInt64
+	parquetInt96
+	parquetFloat
+	parquetDouble
+	parquetByteArray
+	parquetFixedLenByteArray
+)
+
+// FieldRepetitionType。
+const (
+	parquetOptional = 1
+	parquetRepeated = 2
+)
+
+// PageType。
+const (
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+)
+
+// Encoding。
+const (
+	parquetPlain                = 0
+	parquetPlainDictionary      = 2
+	parquetRLE                  = 3
+	parquetDeltaBinaryPacked    = 5
+	parquetDeltaLengthByteArray = 6
+	parquetDeltaByteArray       = 7
+	parquetRLEDictionary        = 8
+	parquetByteStreamSplit      = 9
+)
+
+// CompressionCodec の名前。プレビューが展開できるのは UNCOMPRESSED / SNAPPY / GZIP / ZSTD。
+var parquetCodecs = []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "BROTLI", "LZ4", "ZSTD", "LZ4_RAW"}
+
+// parquetLeaf は表の 1 列になるスキーマの葉 (プリミティブ型の列)。
+type parquetLeaf struct {
+	// index はスキーマの葉の通し番号で、行グループ内の ColumnChunk の位置と一致する。
+	index      int
+	name       string
+	physical   int64
+	typeLength int
+	// maxDef は最大定義レベル。値が null でないのは定義レベルがこれと等しいときだけ。
+	maxDef   int
+	typeName string
+	convert  func(any) any
+}
+
+// ReadParquet は Parquet ファイルのフッターからスキーマを読み、先頭の行グループから最初の limit 行を表にする。
+// r は Parquet ファイル全体 (size バイト) で、必要な範囲 (フッターと各列チャンクの先頭のページ) だけを読む。
+// 繰り返しを含む列 (list / map) はプレビューせず SkippedColumns に入れ、入れ子の struct は "a.b" の列に展開する。
+func ReadParquet(r io.ReaderAt, size int64, limit int) (*Table, error) {
+	ra := newBudgetReaderAt(r)
+	if size < int64(2*len(parquetMagic)+4) {
+		return nil, fmt.Errorf("parquet: %w: file of %d bytes", ErrInvalid, size)
+	}
+	tail := make([]byte, min(size, parquetTailSize))
+	if _, err := ra.ReadAt(tail, size-int64(len(tail))); err != nil && !errors.Is(err, io.EOF) {
+		return nil, parquetReadError(err)
+	}
+	if string(tail[len(tail)-4:]) != parquetMagic {
+		return nil, fmt.Errorf("parquet: %w: missing magic", ErrInvalid)
+	}
+	metaLen := int64(binary.LittleEndian.Uint32(tail[len(tail)-8:]))
+	if metaLen > size-int64(2*len(parquetMagic)+4) {
+		return nil, fmt.Errorf("parquet: %w: footer of %d bytes", ErrInvalid, metaLen)
+	}
+	var meta []byte
+	if end := int64(len(tail)) - 8; metaLen <= end {
+		meta = tail[end-metaLen : end]
+	} else {
+		meta = make([]byte, metaLen)
+		if _, err := ra.ReadAt(meta, size-8-metaLen); err != nil {
+			return nil, parquetReadError(err)
+		}
+	}
+	fileMeta, err := readThriftStruct(bytes.NewReader(meta))
+	if err != nil {
+		return nil, fmt.Errorf("parquet footer: %w", err)
+	}
+
+	leaves, skipped, err := parquetLeaves(fileMeta.list(2))
+	if err != nil {
+		return nil, err
+	}
+	table := newTable(FormatParquet, limit)
+	table.SkippedColumns = skipped
+	for _, leaf := range leaves {
+		table.Columns = append(table.Columns, Column{Name: leaf.name, Type: leaf.typeName})
+	}
+
+	columns := make([][]any, len(leaves))
+	rows := 0
+	for _, v := range fileMeta.list(4) {
+		if rows >= limit {
+			break
+		}
+		rowGroup, _ := v.(thriftFields)
+		chunks := rowGroup.list(1)
+		need := int(min(int64(limit-rows), rowGroup.int(3)))
+		if need <= 0 {
+			continue
+		}
+		for i, leaf := range leaves {
+			if leaf.index >= len(chunks) {
+				return nil, fmt.Errorf("parquet: %w: row group has %d columns", ErrInvalid, len(chunks))
+			}
+			chunk, _ := chunks[leaf.index].(thriftFields)
+			values, err := readParquetColumn(ra, chunk, leaf, need)
+			if err != nil {
+				return nil, fmt.Errorf("parquet column %s: %w", leaf.name, err)
+			}
+			columns[i] = append(columns[i], values...)
+		}
+		rows += need
+	}
+	for r := range rows {
+		row := make([]any, len(leaves))
+		for i := range leaves {
+			row[i] = columns[i][r]
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	table.Truncated = fileMeta.int(3) > int64(rows)
+	return table, nil
+}
+
+func parquetReadError(err error) error {
+	if errors.Is(err, ErrTooLarge) {
+		return err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("parquet: %w: unexpected end of file", ErrInvalid)
+	}
+	return fmt.Errorf("read parquet: %w", err)
+}
+
+// maxParquetSchemaDepth はスキーマのグループの入れ子の上限。SchemaElement のリストは平らなため、
+// thrift の入れ子の上限では壊れた num_children による再帰の深さを抑えられない。
+const maxParquetSchemaDepth = 64
+
+// parquetLeaves は深さ優先に平らにした SchemaElement のリストから葉の列を組み立てる。繰り返しを含む葉は
+// トップレベルのフィールド名を skipped に入れる。
+func parquetLeaves(elements []any) (leaves []*parquetLeaf, skipped []string, err error) {
+	if len(elements) == 0 {
+		return nil, nil, fmt.Errorf("parquet: %w: empty schema", ErrInvalid)
+	}
+	pos, index := 1, 0
+	var walk func(children int64, path []string, def int, repeated bool) error
+	walk = func(children int64, path []string, def int, repeated bool) error {
+		if len(path) > maxParquetSchemaDepth {
+			return fmt.Errorf("parquet: %w: schema nested deeper than %d", ErrInvalid, maxParquetSchemaDepth)
+		}
+		for range children {
+			if pos >= len(elements) {
+				return fmt.Errorf("parquet: %w: schema is shorter than num_children", ErrInvalid)
+			}
+			el, _ := elements[pos].(thriftFields)
+			pos++
+			childPath := append(path[:len(path):len(path)], el.string(4))
+			childDef, childRepeated := def, repeated
+			switch el.int(3) {
+			case parquetOptional:
+				childDef++
+			case parquetRepeated:
+				childDef++
+				childRepeated = true
+			}
+			if !el.has(1) { // 物理型を持たない要素はグループ
+				if err := walk(el.int(5), childPath, childDef, childRepeated); err != nil {
+					return err
+				}
+				continue
+			}
+			if childRepeated {
+				if top := childPath[0]; len(skipped) == 0 || skipped[len(skipped)-1] != top {
+					skipped = append(skipped, top)
+				}
+			} else {
+				typeName, convert, err := parquetType(el)
+				if err != nil {
+					return fmt.Errorf("parquet column %s: %w", strings.Join(childPath, "."), err)
+				}
+				leaves = append(leaves, &parquetLeaf{
+					index:      index,
+					name:       strings.Join(childPath, "."),
+					physical:   el.int(1),
+					typeLength: int(el.int(2)),
+					maxDef:     childDef,
+					typeName:   typeName,
+					convert:    convert,
+				})
+			}
+			index++
+		}
+		return nil
+	}
+	root, _ := elements[0].(thriftFields)
+	if err := walk(root.int(5), nil, 0, false); err != nil {
+		return nil, nil, err
+	}
+	return leaves, skipped, nil
+}
+
+// readParquetColumn は列チャンクの先頭から need 行分の値を読む。
+func readParquetColumn(ra io.ReaderAt, chunk thriftFields, leaf *parquetLeaf, need int) ([]any, error) {
+	if chunk.string(1) != "" {
+		return nil, fmt.Errorf("column chunk in external file %q: %w", chunk.string(1), ErrUnsupported)
+	}
+	meta := chunk.fields(3)
+	codec := meta.int(4)
+	start := meta.int(9)
+	// dictionary_page_offset を 0 のまま書くライターがあるため、データページより前を指すときだけ使う。
+	if dict := meta.int(11); dict > 0 && dict < start {
+		start = dict
+	}
+	length := meta.int(7)
+	if start < 0 || length <= 0 {
+		return nil, fmt.Errorf("%w: column chunk at %d of %d bytes", ErrInvalid, start, length)
+	}
+	br := bufio.NewReaderSize(io.NewSectionReader(ra, start, length), int(min(length, parquetChunkBuffer)))
+
+	var dict []any
+	values := make([]any, 0, need)
+	for len(values) < need {
+		header, err := readThriftStruct(br)
+		if err != nil {
+			return nil, fmt.Errorf("page header: %w", err)
+		}
+		compressedSize, uncompressedSize := header.int(3), header.int(2)
+		if compressedSize < 0 || compressedSize > MaxReadBytes || uncompressedSize < 0 || uncompressedSize > maxDecodedBlock {
+			return nil, fmt.Errorf("%w: page of %d bytes", ErrInvalid, compressedSize)
+		}
+		page := make([]byte, compressedSize)
+		if _, err := io.ReadFull(br, page); err != nil {
+			return nil, parquetReadError(err)
+		}
+
+		switch header.int(1) {
+		case parquetDictionaryPage:
+			data, err := decompressParquet(codec, page, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			dh := header.fields(7)
+			count, err := parquetValueCount(dh.int(1))
+			if err != nil {
+				return nil, err
+			}
+			if dict, err = decodeParquetPlain(data, leaf, count); err != nil {
+				return nil, fmt.Errorf("dictionary page: %w", err)
+			}
+		case parquetDataPage:
+			data, err := decompressParquet(codec, page, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			dh := header.fields(5)
+			n, err := parquetValueCount(dh.int(1))
+			if err != nil {
+				return nil, err
+			}
+			var defs []int
+			if leaf.maxDef > 0 {
+				if dh.int(3) != parquetRLE {
+					return nil, fmt.Errorf("definition level encoding %d: %w", dh.int(3), ErrUnsupported)
+				}
+				if len(data) < 4 || int(binary.LittleEndian.Uint32(data)) > len(data)-4 {
+					return nil, fmt.Errorf("%w: definition levels overrun page", ErrInvalid)
+				}
+				levels := int(binary.LittleEndian.Uint32(data))
+				if defs, err = decodeRLEHybrid(data[4:4+levels], bitWidth(leaf.maxDef), n); err != nil {
+					return nil, err
+				}
+				data = data[4+levels:]
+			}
+			page, err := decodeParquetPage(data, dh.int(2), defs, n, leaf, dict)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, page...)
+		case parquetDataPageV2:
+			// V2 は繰り返し / 定義レベルを圧縮せずに先頭に置き、値の部分だけを圧縮する。
+			dh := header.fields(8)
+			n, err := parquetValueCount(dh.int(1))
+			if err != nil {
+				return nil, err
+			}
+			defLen, repLen := dh.int(5), dh.int(6)
+			if defLen < 0 || repLen < 0 || defLen+repLen > int64(len(page)) {
+				return nil, fmt.Errorf("%w: levels overrun page", ErrInvalid)
+			}
+			var defs []int
+			if leaf.maxDef > 0 {
+				if defs, err = decodeRLEHybrid(page[repLen:repLen+defLen], bitWidth(leaf.maxDef), n); err != nil {
+					return nil, err
+				}
+			}
+			data := page[repLen+defLen:]
+			if compressed, ok := dh.bool(7); !ok || compressed {
+				if data, err = decompressParquet(codec, data, uncompressedSize-repLen-defLen); err != nil {
+					return nil, err
+				}
+			}
+			page, err := decodeParquetPage(data, dh.int(4), defs, n, leaf, dict)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, page...)
+		}
+	}
+	return values[:need], nil
+}
+
+func decompressParquet(codec int64, data []byte, uncompressedSize int64) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+	switch codec {
+	case 0:
+		out = data
+	case 1:
+		out, err = decodeSnappy(data)
+	case 2:
+		out, err = decodeGzip(data)
+	case 6:
+		out, err = decodeZstd(data)
+	default:
+		name := strconv.FormatInt(codec, 10)
+		if codec > 0 && codec < int64(len(parquetCodecs)) {
+			name = parquetCodecs[codec]
+		}
+		return nil, fmt.Errorf("compression codec %s: %w", name, ErrUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) != uncompressedSize {
+		return nil, fmt.Errorf("%w: page decompressed to %d bytes, want %d", ErrInvalid, len(out), uncompressedSize)
+	}
+	return out, nil
+}
+
+// maxParquetPageValues は 1 ページの値の数の上限。RLE の連は数バイトで任意の数の値を表せるため、
+// ヘッダーの num_values を信じて値のスライスを確保するとメモリを使い切ることがある。一般的なライターの
+// ページは数万行程度。
+const maxParquetPageValues = 1 << 22
+
+// parquetValueCount はページヘッダーの num_values を検査して返す。
+func parquetValueCount(v int64) (int, error) {
+	if v < 0 {
+		return 0, fmt.Errorf("%w: page of %d values", ErrInvalid, v)
+	}
+	if v > maxParquetPageValues {
+		return 0, fmt.Errorf("page of %d values: %w", v, ErrUnsupported)
+	}
+	return int(v), nil
+}
+
+// decodeParquetPage はデータページの値の部分を読み、定義レベル defs に従って null を挟んだ n 件の値にする。
+func decodeParquetPage(data []byte, encoding int64, defs []int, n int, leaf *parquetLeaf, dict []any) ([]any, error) {
+	present := n
+	if defs != nil {
+		if len(defs) != n {
+			return nil, fmt.Errorf("%w: %d definition levels for %d values", ErrInvalid, len(defs), n)
+		}
+		present = 0
+		for _, d := range defs {
+			if d == leaf.maxDef {
+				present++
+			}
+		}
+	}
+
+	var (
+		raw []any
+		err error
+	)
+	switch encoding {
+	case parquetPlain:
+		raw, err = decodeParquetPlain(data, leaf, present)
+	case parquetPlainDictionary, parquetRLEDictionary:
+		if dict == nil {
+			return nil, fmt.Errorf("%w: dictionary-encoded page without a dictionary", ErrInvalid)
+		}
+		if len(data) == 0 {
+			if present > 0 {
+				return nil, fmt.Errorf("%w: empty dictionary indices", ErrInvalid)
+			}
+			break
+		}
+		indices, err := decodeRLEHybrid(data[1:], int(data[0]), present)
+		if err != nil {
+			return nil, err
+		}
+		raw = make([]any, len(indices))
+		for i, idx := range indices {
+			if idx < 0 || idx >= len(dict) {
+				return nil, fmt.Errorf("%w: dictionary index %d of %d", ErrInvalid, idx, len(dict))
+			}
+			raw[i] = dict[idx]
+		}
+	case parquetRLE:
+		if leaf.physical != parquetBoolean {
+			return nil, fmt.Errorf("RLE encoding for type %d: %w", leaf.physical, ErrUnsupported)
+		}
+		if len(data) < 4 || int(binary.LittleEndian.Uint32(data)) > len(data)-4 {
+			return nil, fmt.Errorf("%w: RLE values overrun page", ErrInvalid)
+		}
+		bits, err := decodeRLEHybrid(data[4:4+binary.LittleEndian.Uint32(data)], 1, present)
+		if err != nil {
+			return nil, err
+		}
+		raw = make([]any, len(bits))
+		for i, b := range bits {
+			raw[i] = b == 1
+		}
+	case parquetDeltaBinaryPacked:
+		ints, _, err := decodeDeltaBinaryPacked(data, present)
+		if err != nil {
+			return nil, err
+		}
+		raw = make([]any, len(ints))
+		for i, v := range ints {
+			if leaf.physical == parquetInt32 {
+				raw[i] = int32(v)
+			} else {
+				raw[i] = v
+			}
+		}
+	case parquetDeltaLengthByteArray:
+		raw, err = decodeDeltaLengthByteArray(data, present)
+	case parquetDeltaByteArray:
+		raw, err = decodeDeltaByteArray(data, present)
+	case parquetByteStreamSplit:
+		raw, err = decodeByteStreamSplit(data, leaf, present)
+	default:
+		return nil, fmt.Errorf("encoding %d: %w", encoding, ErrUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < present {
+		return nil, fmt.Errorf("%w: page has %d values, want %d", ErrInvalid, len(raw), present)
+	}
+
+	out := make([]any, n)
+	j := 0
+	for i := range out {
+		if defs == nil || defs[i] == leaf.maxDef {
+			out[i] = leaf.convert(raw[j])
+			j++
+		}
+	}
+	return out, nil
+}
+
+// decodeParquetPlain は PLAIN エンコーディングの値を count 件読む。
+func decodeParquetPlain(data []byte, leaf *parquetLeaf, count int) ([]any, error) {
+	width := map[int64]int{parquetInt32: 4, parquetInt64: 8, parquetInt96: 12, parquetFloat: 4, parquetDouble: 8, parquetFixedLenByteArray: leaf.typeLength}[leaf.physical]
+	switch leaf.physical {
+	case parquetBoolean:
+		if len(data)*8 < count {
+			return nil, fmt.Errorf("%w: %d booleans in %d bytes", ErrInvalid, count, len(data))
+		}
+		out := make([]any, count)
+		for i := range out {
+			out[i] = data[i/8]>>(i%8)&1 == 1
+		}
+		return out, nil
+	case parquetByteArray:
+		out := make([]any, 0, count)
+		for range count {
+			if len(data) < 4 || int(binary.LittleEndian.Uint32(data)) > len(data)-4 {
+				return nil, fmt.Errorf("%w: byte array overruns page", ErrInvalid)
+			}
+			n := int(binary.LittleEndian.Uint32(data))
+			out = append(out, data[4:4+n])
+			data = data[4+n:]
+		}
+		return out, nil
+	}
+	if width <= 0 || len(data)/width < count {
+		return nil, fmt.Errorf("%w: %d values of %d bytes in %d bytes", ErrInvalid, count, width, len(data))
+	}
+	out := make([]any, count)
+	for i := range out {
+		b := data[i*width : (i+1)*width]
+		switch leaf.physical {
+		case parquetInt32:
+			out[i] = int32(binary.LittleEndian.Uint32(b))
+		case parquetInt64:
+			out[i] = int64(binary.LittleEndian.Uint64(b))
+		case parquetFloat:
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case parquetDouble:
+			out[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		default:
+			out[i] = b
+		}
+	}
+	return out, nil
+}
+
+// decodeByteStreamSplit は値のバイトごとに分けて並べた BYTE_STREAM_SPLIT を PLAIN の並びに戻して読む。
+func decodeByteStreamSplit(data []byte, leaf *parquetLeaf, count int) ([]any, error) {
+	width := map[int64]int{parquetInt32: 4, parquetInt64: 8, parquetFloat: 4, parquetDouble: 8, parquetFixedLenByteArray: leaf.typeLength}[leaf.physical]
+	if width <= 0 || len(data)/width < count {
+		return nil, fmt.Errorf("%w: byte stream split of %d values in %d bytes", ErrInvalid, count, len(data))
+	}
+	stride := len(data) / width
+	plain := make([]byte, count*width)
+	for i := range count {
+		for k := range width {
+			plain[i*width+k] = data[k*stride+i]
+		}
+	}
+	return decodeParquetPlain(plain, leaf, count)
+}
+
+// bitWidth は 0..max の値を表すのに必要なビット数。
+func bitWidth(max int) int {
+	n := 0
+	for ; max > 0; max >>= 1 {
+		n++
+	}
+	return n
+}
+
+// unpackBits は LSB から詰めたビット列の bit ビット目から width ビットを読む。
+func unpackBits(buf []byte, bit, width int) uint64 {
+	var v uint64
+	for i := 0; i < width; {
+		off := (bit + i) % 8
+		take := min(8-off, width-i)
+		v |= (uint64(buf[(bit+i)/8]>>off) & (1<<take - 1)) << i
+		i += take
+	}
+	return v
+}
+
+// decodeRLEHybrid は RLE / ビットパッキングのハイブリッド (定義レベルと辞書インデックス) を count 件読む。
+func decodeRLEHybrid(data []byte, width, count int) ([]int, error) {
+	if width > 32 {
+		return nil, fmt.Errorf("%w: RLE bit width %d", ErrInvalid, width)
+	}
+	out := make([]int, 0, count)
+	byteWidth := (width + 7) / 8
+	for len(out) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: RLE data ends after %d of %d values", ErrInvalid, len(out), count)
+		}
+		data = data[n:]
+		if header&1 == 1 {
+			// ビットパッキングの連: 8 値ずつのグループが header>>1 個。最後の連は末尾が切り詰められていることがある。
+			groups := int(min(header>>1, uint64(len(data))))
+			size := min(groups*width, len(data))
+			for i := 0; (i+1)*width <= size*8 && i < groups*8 && len(out) < count; i++ {
+				out = append(out, int(unpackBits(data, i*width, width)))
+			}
+			data = data[size:]
+			continue
+		}
+		if len(data) < byteWidth {
+			return nil, fmt.Errorf("%w: RLE run overruns data", ErrInvalid)
+		}
+		var v int
+		for i := range byteWidth {
+			v |= int(data[i]) << (8 * i)
+		}
+		data = data[byteWidth:]
+		for run := header >> 1; run > 0 && len(out) < count; run-- {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// maxDeltaBlockSize は DELTA_BINARY_PACKED のブロックの値の数の上限。一般的なライターは 128 を使う。
+const maxDeltaBlockSize = 1 << 16
+
+// decodeDeltaBinaryPacked は DELTA_BINARY_PACKED の値を count 件まで読み、読んだバイト数も返す。
+func decodeDeltaBinaryPacked(data []byte, count int) ([]int64, int, error) {
+	pos := 0
+	uvarint := func() uint64 {
+		if pos < 0 {
+			return 0
+		}
+		v, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			pos = -1
+			return 0
+		}
+		pos += n
+		return v
+	}
+	blockSize, miniBlocks, total := uvarint(), uvarint(), uvarint()
+	if pos < 0 {
+		return nil, 0, fmt.Errorf("%w: delta header", ErrInvalid)
+	}
+	first, n := binary.Varint(data[pos:])
+	if n <= 0 || blockSize > maxDeltaBlockSize || miniBlocks == 0 || miniBlocks > blockSize || blockSize%miniBlocks != 0 || (blockSize/miniBlocks)%8 != 0 || total > uint64(count) {
+		return nil, 0, fmt.Errorf("%w: delta header", ErrInvalid)
+	}
+	pos += n
+	perMini := int(blockSize / miniBlocks)
+
+	out := make([]int64, 0, total)
+	if total > 0 {
+		out = append(out, first)
+	}
+	prev := first
+	for uint64(len(out)) < total {
+		minDelta, n := binary.Varint(data[pos:])
+		if n <= 0 || pos+n+int(miniBlocks) > len(data) {
+			return nil, 0, fmt.Errorf("%w: delta block overruns data", ErrInvalid)
+		}
+		pos += n
+		widths := data[pos : pos+int(miniBlocks)]
+		pos += int(miniBlocks)
+		for _, w := range widths {
+			if uint64(len(out)) >= total {
+				break
+			}
+			if w > 64 {
+				return nil, 0, fmt.Errorf("%w: delta bit width %d", ErrInvalid, w)
+			}
+			size := perMini * int(w) / 8
+			if pos+size > len(data) {
+				return nil, 0, fmt.Errorf("%w: delta miniblock overruns data", ErrInvalid)
+			}
+			for i := 0; i < perMini && uint64(len(out)) < total; i++ {
+				prev += minDelta + int64(unpackBits(data[pos:], i*int(w), int(w)))
+				out = append(out, prev)
+			}
+			pos += size
+		}
+	}
+	return out, pos, nil
+}
+
+// decodeDeltaLengthByteArray は DELTA_LENGTH_BYTE_ARRAY (長さの差分と連結した本体) を count 件読む。
+func decodeDeltaLengthByteArray(data []byte, count int) ([]any, error) {
+	lengths, pos, err := decodeDeltaBinaryPacked(data, count)
+	if err != nil {
+		return nil, err
+	}
+	data = data[pos:]
+	out := make([]any, len(lengths))
+	for i, n := range lengths {
+		if n < 0 || n > int64(len(data)) {
+			return nil, fmt.Errorf("%w: byte array overruns page", ErrInvalid)
+		}
+		out[i] = data[:n]
+		data = data[n:]
+	}
+	return out, nil
+}
+
+// decodeDeltaByteArray は DELTA_BYTE_ARRAY (直前の値と共通する接頭辞の長さと残り) を count 件読む。
+func decodeDeltaByteArray(data []byte, count int) ([]any, error) {
+	prefixes, pos, err := decodeDeltaBinaryPacked(data, count)
+	if err != nil {
+		return nil, err
+	}
+	suffixes, err := decodeDeltaLengthByteArray(data[pos:], count)
+	if err != nil {
+		return nil, err
+	}
+	if len(suffixes) != len(prefixes) {
+		return nil, fmt.Errorf("%w: %d prefixes for %d suffixes", ErrInvalid, len(prefixes), len(suffixes))
+	}
+	var prev []byte
+	out := make([]any, len(prefixes))
+	for i, p := range prefixes {
+		if p < 0 || p > int64(len(prev)) {
+			return nil, fmt.Errorf("%w: prefix length %d", ErrInvalid, p)
+		}
+		v := append(prev[:p:p], suffixes[i].([]byte)...)
+		out[i], prev = v, v
+	}
+	return out, nil
+}
+
+// parquetType は SchemaElement の論理型 (logicalType、なければ converted_type) から列の型名と、
+// 物理型の値を JSON 向けの値にする変換を決める。decimal の scale が壊れていれば ErrInvalid を返す。
+func parquetType(el thriftFields) (string, func(any) any, error) {
+	logical := el.fields(10)
+	converted := int64(-1)
+	if el.has(6) {
+		converted = el.int(6)
+	}
+	switch {
+	case logical.has(1) || converted == 0:
+		return "string", parquetString, nil
+	case logical.has(4) || converted == 4:
+		return "enum", parquetString, nil
+	case logical.has(12) || converted == 19:
+		return "json", parquetString, nil
+	case logical.has(5) || converted == 5:
+		scale, precision := el.int(7), el.int(8)
+		if d := logical.fields(5); d != nil {
+			scale, precision = d.int(1), d.int(2)
+		}
+		if err := checkDecimalScale(scale); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("decimal(%d,%d)", precision, scale), func(v any) any {
+			if b, ok := v.([]byte); ok {
+				return formatDecimal(twosComplement(b), int(scale))
+			}
+			if i, ok := asInt64(v); ok {
+				return formatDecimal(big.NewInt(i), int(scale))
+			}
+			return v
+		}, nil
+	case logical.has(6) || converted == 6:
+		return "date", func(v any) any {
+			if i, ok := asInt64(v); ok {
+				return formatDate(i)
+			}
+			return v
+		}, nil
+	case logical.has(7) || converted == 7 || converted == 8:
+		unit := time.Millisecond
+		if converted == 8 {
+			unit = time.Microsecond
+		}
+		if t := logical.fields(7); t != nil {
+			unit = parquetTimeUnit(t.fields(2))
+		}
+		return "time", func(v any) any {
+			if i, ok := asInt64(v); ok {
+				return formatTimeOfDay(time.Duration(i) * unit)
+			}
+			return v
+		}, nil
+	case logical.has(8) || converted == 9 || converted == 10:
+		unit, adjusted := time.Millisecond, true
+		if converted == 10 {
+			unit = time.Microsecond
+		}
+		if t := logical.fields(8); t != nil {
+			unit = parquetTimeUnit(t.fields(2))
+			adjusted, _ = t.bool(1)
+		}
+		return "timestamp", func(v any) any {
+			i, ok := asInt64(v)
+			if !ok {
+				return v
+			}
+			var t time.Time
+			switch unit {
+			case time.Millisecond:
+				t = time.UnixMilli(i)
+			case time.Microsecond:
+				t = time.UnixMicro(i)
+			default:
+				t = time.Unix(0, i)
+			}
+			return formatTimestamp(t, adjusted)
+		}, nil
+	case logical.has(10) || (converted >= 11 && converted <= 18):
+		var bits int
+		var signed bool
+		if converted >= 11 && converted <= 18 {
+			bits, signed = 8<<((converted-11)%4), converted >= 15
+		}
+		if i := logical.fields(10); i != nil {
+			bits = int(i.int(1))
+			signed, _ = i.bool(2)
+		}
+		name := "int" + strconv.Itoa(bits)
+		if !signed {
+			name = "u" + name
+		}
+		return name, func(v any) any {
+			switch x := v.(type) {
+			case int32:
+				if !signed {
+					return uint64(uint32(x))
+				}
+				return int64(x)
+			case int64:
+				if !signed {
+					return uint64(x)
+				}
+			}
+			return v
+		}, nil
+	case logical.has(14):
+		return "uuid", func(v any) any {
+			if b, ok := v.([]byte); ok && len(b) == 16 {
+				return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+			}
+			return v
+		}, nil
+	case logical.has(15):
+		return "float16", func(v any) any {
+			if b, ok := v.([]byte); ok && len(b) == 2 {
+				return jsonFloat(float16(binary.LittleEndian.Uint16(b)))
+			}
+			return v
+		}, nil
+	}
+
+	switch el.int(1) {
+	case parquetBoolean:
+		return "boolean", func(v any) any { return v }, nil
+	case parquetInt32:
+		return "int32", func(v any) any {
+			if i, ok := asInt64(v); ok {
+				return i
+			}
+			return v
+		}, nil
+	case parquetInt64:
+		return "int64", func(v any) any { return v }, nil
+	case parquetInt96:
+		// INT96 は Hive / Spark が書く旧形式のタイムスタンプ (その日のナノ秒 8 バイト + ユリウス日 4 バイト)。
+		return "timestamp", func(v any) any {
+			b, ok := v.([]byte)
+			if !ok || len(b) != 12 {
+				return v
+			}
+			days := int64(binary.LittleEndian.Uint32(b[8:])) - 2440588
+			return formatTimestamp(time.Unix(days*86400, int64(binary.LittleEndian.Uint64(b[:8]))), true)
+		}, nil
+	case parquetFloat:
+		return "float", func(v any) any {
+			if f, ok := v.(float32); ok {
+				return jsonFloat(float64(f))
+			}
+			return v
+		}, nil
+	case parquetDouble:
+		return "double", func(v any) any {
+			if f, ok := v.(float64); ok {
+				return jsonFloat(f)
+			}
+			return v
+		}, nil
+	case parquetFixedLenByteArray:
+		return fmt.Sprintf("fixed_len_byte_array(%d)", el.int(2)), parquetBytes, nil
+	}
+	return "binary", parquetBytes, nil
+}
+
+func parquetString(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func parquetBytes(v any) any {
+	if b, ok := v.([]byte); ok {
+		return jsonBytes(b)
+	}
+	return v
+}
+
+// parquetTimeUnit は TimeUnit (MILLIS = 1 / MICROS = 2 / NANOS = 3 の union) を時間の単位にする。
+func parquetTimeUnit(unit thriftFields) time.Duration {
+	switch {
+	case unit.has(2):
+		return time.Microsecond
+	case unit.has(3):
+		return time.Nanosecond
+	}
+	return time.Millisecond
+}
+
+func asInt64(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	}
+	return 0, false
+}
+
+// float16 は IEEE 754 半精度の値を float64 にする。
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, frac := int(h>>10)&0x1f, float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
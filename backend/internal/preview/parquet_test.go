@@ -0,0 +1,321 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// tf は Thrift compact protocol で書く構造体の 1 フィールド。v は int64 / string / bool / []tf (構造体) / tlist。
+type tf struct {
+	id  int16
+	typ byte
+	v   any
+}
+
+type tlist struct {
+	elem  byte
+	items []any
+}
+
+func ti32(id int16, v int64) tf       { return tf{id, thriftI32, v} }
+func ti64(id int16, v int64) tf       { return tf{id, thriftI64, v} }
+func tstr(id int16, v string) tf      { return tf{id, thriftBinary, v} }
+func tbool(id int16, v bool) tf       { return tf{id, thriftTrue, v} }
+func tstruct(id int16, v ...tf) tf    { return tf{id, thriftStruct, v} }
+func tstructs(id int16, v ...[]tf) tf { return tf{id, thriftList, tlist{thriftStruct, anySlice(v)}} }
+
+func anySlice[T any](v []T) []any {
+	out := make([]any, len(v))
+	for i := range v {
+		out[i] = v[i]
+	}
+	return out
+}
+
+func thriftEncode(b []byte, fields []tf) []byte {
+	var last int16
+	for _, f := range fields {
+		typ := f.typ
+		if typ == thriftTrue && !f.v.(bool) {
+			typ = thriftFalse
+		}
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b = append(b, byte(delta)<<4|typ)
+		} else {
+			b = binary.AppendVarint(append(b, typ), int64(f.id))
+		}
+		last = f.id
+		b = thriftEncodeValue(b, f.typ, f.v)
+	}
+	return append(b, thriftStop)
+}
+
+func thriftEncodeValue(b []byte, typ byte, v any) []byte {
+	switch typ {
+	case thriftI32, thriftI64:
+		return binary.AppendVarint(b, v.(int64))
+	case thriftBinary:
+		return append(binary.AppendUvarint(b, uint64(len(v.(string)))), v.(string)...)
+	case thriftStruct:
+		return thriftEncode(b, v.([]tf))
+	case thriftList:
+		l := v.(tlist)
+		if len(l.items) < 15 {
+			b = append(b, byte(len(l.items))<<4|l.elem)
+		} else {
+			b = binary.AppendUvarint(append(b, 0xf0|l.elem), uint64(len(l.items)))
+		}
+		for _, item := range l.items {
+			b = thriftEncodeValue(b, l.elem, item)
+		}
+	}
+	return b
+}
+
+// packLSB は values を width ビットずつ LSB から詰める。
+func packLSB(values []uint64, width int) []byte {
+	out := make([]byte, (len(values)*width+7)/8)
+	for i, v := range values {
+		for j := range width {
+			if v>>j&1 == 1 {
+				bit := i*width + j
+				out[bit/8] |= 1 << (bit % 8)
+			}
+		}
+	}
+	return out
+}
+
+// parquetBuilder はページを順に書き、列チャンクのメタデータを組み立てる。
+type parquetBuilder struct {
+	buf    []byte
+	chunks [][]tf
+}
+
+func (p *parquetBuilder) page(header []tf, body []byte) int64 {
+	offset := int64(len(p.buf))
+	p.buf = thriftEncode(p.buf, header)
+	p.buf = append(p.buf, body...)
+	return offset
+}
+
+func (p *parquetBuilder) chunk(typ int64, path string, codec int64, numValues int64, dictOffset, dataOffset int64) {
+	end := int64(len(p.buf))
+	start := dataOffset
+	if dictOffset > 0 {
+		start = dictOffset
+	}
+	meta := []tf{
+		ti32(1, typ),
+		{2, thriftList, tlist{thriftI32, []any{int64(0)}}},
+		{3, thriftList, tlist{thriftBinary, []any{path}}},
+		ti32(4, codec),
+		ti64(5, numValues),
+		ti64(6, end-start),
+		ti64(7, end-start),
+		ti64(9, dataOffset),
+	}
+	if dictOffset > 0 {
+		meta = append(meta, ti64(11, dictOffset))
+	}
+	p.chunks = append(p.chunks, []tf{ti64(2, start), tstruct(3, meta...)})
+}
+
+func dataPageV1(n, encoding int64, body []byte) []tf {
+	return []tf{
+		ti32(1, parquetDataPage), ti32(2, int64(len(body))), ti32(3, int64(len(body))),
+		tstruct(5, ti32(1, n), ti32(2, encoding), ti32(3, parquetRLE), ti32(4, parquetRLE)),
+	}
+}
+
+// buildParquet は 5 行 1 行グループの Parquet ファイルを組み立てる。列は id (INT64, 2 ページ)、
+// name (STRING, 辞書 + SNAPPY)、ts (TIMESTAMP, データページ V2 + DELTA_BINARY_PACKED + ZSTD)、
+// price (DECIMAL)、tags (LIST、プレビュー対象外)、loc.lat (入れ子の struct の DOUBLE)。
+func buildParquet(t testing.TB) []byte {
+	t.Helper()
+	p := &parquetBuilder{buf: []byte(parquetMagic)}
+
+	// id: 1, 2, 3 と 4, 5 の 2 ページ。
+	var ids [2][]byte
+	for i := int64(1); i <= 5; i++ {
+		ids[(i-1)/3] = binary.LittleEndian.AppendUint64(ids[(i-1)/3], uint64(i))
+	}
+	idOffset := p.page(dataPageV1(3, parquetPlain, ids[0]), ids[0])
+	p.page(dataPageV1(2, parquetPlain, ids[1]), ids[1])
+	p.chunk(parquetInt64, "id", 0, 5, 0, idOffset)
+
+	// name: 辞書 [alice, bob]、定義レベル [1, 0, 1, 1, 0]、インデックス [0, 1, 0]。
+	dict := []byte{}
+	for _, s := range []string{"alice", "bob"} {
+		dict = append(binary.LittleEndian.AppendUint32(dict, uint32(len(s))), s...)
+	}
+	compressedDict := s2.EncodeSnappy(nil, dict)
+	dictOffset := p.page([]tf{
+		ti32(1, parquetDictionaryPage), ti32(2, int64(len(dict))), ti32(3, int64(len(compressedDict))),
+		tstruct(7, ti32(1, 2), ti32(2, parquetPlain)),
+	}, compressedDict)
+	levels := []byte{0x03, 0x0d} // ビットパッキング 1 グループ: 1,0,1,1,0
+	nameBody := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	nameBody = append(nameBody, levels...)
+	nameBody = append(nameBody, 1, 0x03, 0x02) // ビット幅 1、インデックス 0,1,0
+	compressedName := s2.EncodeSnappy(nil, nameBody)
+	header := dataPageV1(5, parquetRLEDictionary, nameBody)
+	header[2] = ti32(3, int64(len(compressedName)))
+	nameOffset := p.page(header, compressedName)
+	p.chunk(parquetByteArray, "name", 1, 5, dictOffset, nameOffset)
+
+	// ts: 差分 1s, 2s, 1s, 6s を DELTA_BINARY_PACKED (最小差分 1s、1 つ目のミニブロックだけ 23 ビット) で書く。
+	const base = 1700000000000000
+	deltas := binary.AppendUvarint(nil, 128)
+	deltas = binary.AppendUvarint(deltas, 4)
+	deltas = binary.AppendUvarint(deltas, 5)
+	deltas = binary.AppendVarint(deltas, base)
+	deltas = binary.AppendVarint(deltas, 1000000)
+	deltas = append(deltas, 23, 0, 0, 0)
+	mini := make([]uint64, 32)
+	copy(mini, []uint64{0, 1000000, 0, 5000000})
+	deltas = append(deltas, packLSB(mini, 23)...)
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressedDeltas := enc.EncodeAll(deltas, nil)
+	tsLevels := []byte{0x0a, 0x01} // RLE: 1 を 5 回
+	tsOffset := p.page([]tf{
+		ti32(1, parquetDataPageV2), ti32(2, int64(len(tsLevels)+len(deltas))), ti32(3, int64(len(tsLevels)+len(compressedDeltas))),
+		tstruct(8, ti32(1, 5), ti32(2, 0), ti32(3, 5), ti32(4, parquetDeltaBinaryPacked), ti32(5, 2), ti32(6, 0), tbool(7, true)),
+	}, append(tsLevels, compressedDeltas...))
+	p.chunk(parquetInt64, "ts", 6, 5, 0, tsOffset)
+
+	// price: DECIMAL(9,2) の INT32。
+	var prices []byte
+	for _, v := range []int32{12345, -1, 0, 100, 7} {
+		prices = binary.LittleEndian.AppendUint32(prices, uint32(v))
+	}
+	p.chunk(parquetInt32, "price", 0, 5, 0, p.page(dataPageV1(5, parquetPlain, prices), prices))
+
+	// tags: 読まれない列。メタデータだけを置く。
+	p.chunk(parquetByteArray, "tags.list.element", 0, 0, 0, int64(len(p.buf)))
+
+	// loc.lat: 定義レベル [1, 1, 0, 1, 1]。
+	latBody := binary.LittleEndian.AppendUint32(nil, 2)
+	latBody = append(latBody, 0x03, 0x1b)
+	for _, v := range []float64{1.5, 2.5, 3.5, 4.5} {
+		latBody = binary.LittleEndian.AppendUint64(latBody, math.Float64bits(v))
+	}
+	p.chunk(parquetDouble, "loc.lat", 0, 5, 0, p.page(dataPageV1(5, parquetPlain, latBody), latBody))
+
+	schema := [][]tf{
+		{tstr(4, "schema"), ti32(5, 6)},
+		{ti32(1, parquetInt64), ti32(3, 0), tstr(4, "id")},
+		{ti32(1, parquetByteArray), ti32(3, parquetOptional), tstr(4, "name"), ti32(6, 0), tstruct(10, tstruct(1))},
+		{ti32(1, parquetInt64), ti32(3, parquetOptional), tstr(4, "ts"), tstruct(10, tstruct(8, tbool(1, true), tstruct(2, tstruct(2))))},
+		{ti32(1, parquetInt32), ti32(3, 0), tstr(4, "price"), ti32(6, 5), ti32(7, 2), ti32(8, 9)},
+		{ti32(3, parquetOptional), tstr(4, "tags"), ti32(5, 1), ti32(6, 3)},
+		{ti32(3, parquetRepeated), tstr(4, "list"), ti32(5, 1)},
+		{ti32(1, parquetByteArray), ti32(3, parquetOptional), tstr(4, "element"), ti32(6, 0)},
+		{ti32(3, parquetOptional), tstr(4, "loc"), ti32(5, 1)},
+		{ti32(1, parquetDouble), ti32(3, 0), tstr(4, "lat")},
+	}
+	meta := thriftEncode(nil, []tf{
+		ti32(1, 1),
+		tstructs(2, schema...),
+		ti64(3, 5),
+		tstructs(4, []tf{tstructs(1, p.chunks...), ti64(2, int64(len(p.buf))), ti64(3, 5)}),
+	})
+	file := append(p.buf, meta...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(meta)))
+	return append(file, parquetMagic...)
+}
+
+func TestReadParquet(t *testing.T) {
+	file := buildParquet(t)
+	columns := []Column{
+		{Name: "id", Type: "int64"},
+		{Name: "name", Type: "string"},
+		{Name: "ts", Type: "timestamp"},
+		{Name: "price", Type: "decimal(9,2)"},
+		{Name: "loc.lat", Type: "double"},
+	}
+	rows := [][]any{
+		{int64(1), "alice", "2023-11-14T22:13:20Z", "123.45", 1.5},
+		{int64(2), nil, "2023-11-14T22:13:21Z", "-0.01", 2.5},
+		{int64(3), "bob", "2023-11-14T22:13:23Z", "0.00", nil},
+		{int64(4), "alice", "2023-11-14T22:13:24Z", "1.00", 3.5},
+		{int64(5), nil, "2023-11-14T22:13:30Z", "0.07", 4.5},
+	}
+	for _, limit := range []int{2, 4, 5, 100} {
+		got, err := ReadParquet(bytes.NewReader(file), int64(len(file)), limit)
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		n := min(limit, len(rows))
+		want := &Table{Format: FormatParquet, Columns: columns, Rows: rows[:n], Truncated: n < len(rows), SkippedColumns: []string{"tags"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("limit %d: ReadParquet mismatch (-want +got):\n%s", limit, diff)
+		}
+	}
+
+	t.Run("not parquet", func(t *testing.T) {
+		data := []byte("id,name\n1,alice\n")
+		if _, err := ReadParquet(bytes.NewReader(data), int64(len(data)), 10); !errors.Is(err, ErrInvalid) {
+			t.Errorf("err = %v, want ErrInvalid", err)
+		}
+	})
+	t.Run("truncated", func(t *testing.T) {
+		data := slices.Concat(file[:100], file[len(file)-8:])
+		if _, err := ReadParquet(bytes.NewReader(data), int64(len(data)), 10); !errors.Is(err, ErrInvalid) {
+			t.Errorf("err = %v, want ErrInvalid", err)
+		}
+	})
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		width int
+		count int
+		want  []int
+	}{
+		// Parquet の仕様書の例: 0..7 を 3 ビットでビットパッキング。
+		{name: "bit packed", data: []byte{0x03, 0x88, 0xc6, 0xfa}, width: 3, count: 8, want: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{name: "bit packed partial", data: []byte{0x03, 0x88, 0xc6, 0xfa}, width: 3, count: 3, want: []int{0, 1, 2}},
+		{name: "rle run then bit packed", data: []byte{0x06, 0x05, 0x03, 0x01}, width: 4, count: 5, want: []int{5, 5, 5, 1, 0}},
+		{name: "width 0", data: []byte{0x08}, width: 0, count: 4, want: []int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeRLEHybrid(tt.data, tt.width, tt.count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := decodeRLEHybrid([]byte{0x06}, 8, 3); !errors.Is(err, ErrInvalid) {
+		t.Errorf("truncated run err = %v, want ErrInvalid", err)
+	}
+}
+
+func FuzzReadParquet(f *testing.F) {
+	file := buildParquet(f)
+	f.Add(file)
+	f.Add(slices.Concat(file[:100], file[len(file)-8:]))
+	f.Add([]byte("id,name\n1,alice\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		table, err := ReadParquet(bytes.NewReader(data), int64(len(data)), 3)
+		checkFuzzResult(t, table, err, 3)
+	})
+}
@@ -0,0 +1,105 @@
+package preview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		key             string
+		wantCompression string
+		wantFormat      string
+	}{
+		{key: "data/part-0000.parquet", wantFormat: FormatParquet},
+		{key: "data/PART.ORC", wantFormat: FormatORC},
+		{key: "events.avro", wantFormat: FormatAvro},
+		{key: "logs/app.ndjson.gz", wantCompression: CompressionGzip, wantFormat: FormatNDJSON},
+		{key: "logs/app.jsonl.zst", wantCompression: CompressionZstd, wantFormat: FormatNDJSON},
+		{key: "export.csv", wantFormat: FormatCSV},
+		{key: "export.tsv.gzip", wantCompression: CompressionGzip, wantFormat: FormatTSV},
+		{key: "notes.txt.gz", wantCompression: CompressionGzip, wantFormat: FormatText},
+		{key: "archive.gz", wantCompression: CompressionGzip, wantFormat: FormatText},
+		{key: "csv.d/readme", wantFormat: FormatText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			compression, format := Detect(tt.key)
+			if compression != tt.wantCompression || format != tt.wantFormat {
+				t.Errorf("Detect(%q) = (%q, %q), want (%q, %q)", tt.key, compression, format, tt.wantCompression, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	const text = "id,name\n1,alice\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	io.WriteString(zw, text)
+	zw.Close()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst := enc.EncodeAll([]byte(text), nil)
+
+	for _, tt := range []struct {
+		compression string
+		data        []byte
+	}{
+		{CompressionNone, []byte(text)},
+		{CompressionGzip, gz.Bytes()},
+		{CompressionZstd, zst},
+	} {
+		r, err := Decompress(bytes.NewReader(tt.data), tt.compression)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.compression, err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(got) != text {
+			t.Errorf("%q: got %q, %v, want %q", tt.compression, got, err, text)
+		}
+	}
+
+	if _, err := Decompress(bytes.NewReader([]byte("plain")), CompressionGzip); !errors.Is(err, ErrInvalid) {
+		t.Errorf("gzip of plain text err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestBudgetReaderAt(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 10)
+	b := &budgetReaderAt{r: bytes.NewReader(data), remaining: 6}
+	if n, err := b.ReadAt(make([]byte, 4), 0); n != 4 || err != nil {
+		t.Fatalf("first read = %d, %v", n, err)
+	}
+	if n, err := b.ReadAt(make([]byte, 4), 4); n != 2 || !errors.Is(err, ErrTooLarge) {
+		t.Errorf("read over budget = %d, %v, want 2, ErrTooLarge", n, err)
+	}
+}
+
+// checkFuzzResult は Read* のファズテストの共通の検査。エラーは形式として弾いたものに限り、
+// 表は limit 行以内で各行の長さが列数と一致すること。
+func checkFuzzResult(t *testing.T, table *Table, err error, limit int) {
+	t.Helper()
+	if err != nil {
+		if !errors.Is(err, ErrInvalid) && !errors.Is(err, ErrUnsupported) && !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("err = %v, want ErrInvalid, ErrUnsupported or ErrTooLarge", err)
+		}
+		return
+	}
+	if len(table.Rows) > limit {
+		t.Fatalf("got %d rows, want at most %d", len(table.Rows), limit)
+	}
+	for i, row := range table.Rows {
+		if len(row) != len(table.Columns) {
+			t.Fatalf("row %d has %d values for %d columns", i, len(row), len(table.Columns))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package preview
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pbFields は protobuf のメッセージをフィールド番号ごとの値 (varint は uint64、長さ付きは []byte) にしたもの。
+// ORC のメタデータ (PostScript / Footer / StripeFooter) から必要なフィールドだけを取り出すのに使う。
+type pbFields map[protowire.Number][]any
+
+func parseProtobuf(b []byte) (pbFields, error) {
+	fields := pbFields{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("protobuf: %w: %w", ErrInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v any
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("protobuf: %w: %w", ErrInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+		if v != nil {
+			fields[num] = append(fields[num], v)
+		}
+	}
+	return fields, nil
+}
+
+// uint は varint のフィールドの値 (繰り返された場合は最後の値) を返す。
+func (f pbFields) uint(num protowire.Number) uint64 {
+	vs := f[num]
+	if len(vs) == 0 {
+		return 0
+	}
+	v, _ := vs[len(vs)-1].(uint64)
+	return v
+}
+
+func (f pbFields) has(num protowire.Number) bool {
+	return len(f[num]) > 0
+}
+
+// bytes は長さ付きのフィールドの値を繰り返しの順に返す (文字列と入れ子のメッセージ)。
+func (f pbFields) bytes(num protowire.Number) [][]byte {
+	var out [][]byte
+	for _, v := range f[num] {
+		if b, ok := v.([]byte); ok {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// uints は繰り返しの varint のフィールドを、packed とそうでない書き方のどちらでも読む。
+func (f pbFields) uints(num protowire.Number) ([]uint64, error) {
+	var out []uint64
+	for _, v := range f[num] {
+		switch x := v.(type) {
+		case uint64:
+			out = append(out, x)
+		case []byte:
+			for len(x) > 0 {
+				u, n := protowire.ConsumeVarint(x)
+				if n < 0 {
+					return nil, fmt.Errorf("protobuf: %w: %w", ErrInvalid, protowire.ParseError(n))
+				}
+				out = append(out, u)
+				x = x[n:]
+			}
+		}
+	}
+	return out, nil
+}
+
+// messages は入れ子のメッセージのフィールドを繰り返しの順に解析する。
+func (f pbFields) messages(num protowire.Number) ([]pbFields, error) {
+	var out []pbFields
+	for _, b := range f.bytes(num) {
+		m, err := parseProtobuf(b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
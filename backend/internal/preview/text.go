@@ -0,0 +1,178 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ReadDelimited は CSV (comma = ',') / TSV (comma = '\t') の 1 行目をヘッダーとして、続く最大 limit 行を表にする。
+// 値はすべて文字列のまま返す。ヘッダーより列の多い行があれば column<N> という名前の列を足し、少ない行は nil で埋める。
+func ReadDelimited(data []byte, comma rune, limit int) (*Table, error) {
+	format := FormatCSV
+	if comma == '\t' {
+		format = FormatTSV
+	}
+	table := newTable(format, limit)
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return table, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s header: %w: %w", format, ErrInvalid, err)
+	}
+	for _, name := range header {
+		table.Columns = append(table.Columns, Column{Name: name, Type: "string"})
+	}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w: %w", format, ErrInvalid, err)
+		}
+		if len(table.Rows) == limit {
+			table.Truncated = true
+			break
+		}
+		for len(table.Columns) < len(record) {
+			table.Columns = append(table.Columns, Column{Name: "column" + strconv.Itoa(len(table.Columns)+1), Type: "string"})
+		}
+		row := make([]any, len(table.Columns))
+		for i, v := range record {
+			row[i] = v
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	padRows(table)
+	return table, nil
+}
+
+// ReadNDJSON は 1 行 1 JSON の NDJSON / JSON Lines の先頭 limit 行を表にする。列はオブジェクトのキーを
+// 現れた順に並べ、オブジェクト以外の行は value 列に入れる。ネストした値はそのまま (map / slice) 返す。
+func ReadNDJSON(data []byte, limit int) (*Table, error) {
+	table := newTable(FormatNDJSON, limit)
+	index := map[string]int{}
+	column := func(name string) int {
+		i, ok := index[name]
+		if !ok {
+			i = len(table.Columns)
+			index[name] = i
+			table.Columns = append(table.Columns, Column{Name: name})
+		}
+		return i
+	}
+
+	for line := 1; len(data) > 0; line++ {
+		var text []byte
+		text, data, _ = bytes.Cut(data, []byte("\n"))
+		text = bytes.TrimSpace(text)
+		if len(text) == 0 {
+			continue
+		}
+		if len(table.Rows) == limit {
+			table.Truncated = true
+			break
+		}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("ndjson line %d: %w: %w", line, ErrInvalid, err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("ndjson line %d: %w: multiple JSON values", line, ErrInvalid)
+		}
+
+		var row []any
+		set := func(name string, v any) {
+			i := column(name)
+			for len(row) <= i {
+				row = append(row, nil)
+			}
+			row[i] = v
+			if table.Columns[i].Type == "" && v != nil {
+				table.Columns[i].Type = jsonType(v)
+			}
+		}
+		if obj, ok := v.(map[string]any); ok {
+			// map の順序は不定のため、キーの出現順は行の JSON から取り直す。
+			keys, err := objectKeys(text)
+			if err != nil {
+				return nil, fmt.Errorf("ndjson line %d: %w: %w", line, ErrInvalid, err)
+			}
+			for _, k := range keys {
+				set(k, obj[k])
+			}
+		} else {
+			set("value", v)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	for i := range table.Columns {
+		if table.Columns[i].Type == "" {
+			table.Columns[i].Type = "null"
+		}
+	}
+	padRows(table)
+	return table, nil
+}
+
+// objectKeys は JSON オブジェクトのトップレベルのキーを現れた順に返す。
+func objectKeys(text []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(text))
+	if _, err := dec.Token(); err != nil { // '{'
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// jsonType は JSON の値の型名を返す。
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+// padRows は途中で列が増えた場合に、それより前の行の長さを列数にそろえる。
+func padRows(table *Table) {
+	for i, row := range table.Rows {
+		for len(row) < len(table.Columns) {
+			row = append(row, nil)
+		}
+		table.Rows[i] = row
+	}
+}
@@ -0,0 +1,131 @@
+package preview
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadDelimited(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		comma rune
+		limit int
+		want  *Table
+	}{
+		{
+			name:  "csv with quoted field",
+			data:  "id,name\n1,\"smith, alice\"\n2,bob\n",
+			comma: ',',
+			limit: 10,
+			want: &Table{
+				Format:  FormatCSV,
+				Columns: []Column{{Name: "id", Type: "string"}, {Name: "name", Type: "string"}},
+				Rows:    [][]any{{"1", "smith, alice"}, {"2", "bob"}},
+			},
+		},
+		{
+			name:  "tsv truncated at limit",
+			data:  "a\tb\n1\t2\n3\t4\n",
+			comma: '\t',
+			limit: 1,
+			want: &Table{
+				Format:    FormatTSV,
+				Columns:   []Column{{Name: "a", Type: "string"}, {Name: "b", Type: "string"}},
+				Rows:      [][]any{{"1", "2"}},
+				Truncated: true,
+			},
+		},
+		{
+			name:  "ragged rows",
+			data:  "a,b\n1\n2,3,4\n",
+			comma: ',',
+			limit: 10,
+			want: &Table{
+				Format:  FormatCSV,
+				Columns: []Column{{Name: "a", Type: "string"}, {Name: "b", Type: "string"}, {Name: "column3", Type: "string"}},
+				Rows:    [][]any{{"1", nil, nil}, {"2", "3", "4"}},
+			},
+		},
+		{
+			name:  "empty",
+			data:  "",
+			comma: ',',
+			limit: 10,
+			want:  &Table{Format: FormatCSV, Columns: []Column{}, Rows: [][]any{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadDelimited([]byte(tt.data), tt.comma, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ReadDelimited mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	data := `{"id": 1, "name": "alice", "tags": ["a"]}
+
+{"name": "bob", "id": 2, "active": true}
+"bare string"
+{"id": 3}
+`
+	got, err := ReadNDJSON([]byte(data), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Table{
+		Format: FormatNDJSON,
+		Columns: []Column{
+			{Name: "id", Type: "number"},
+			{Name: "name", Type: "string"},
+			{Name: "tags", Type: "array"},
+			{Name: "active", Type: "boolean"},
+			{Name: "value", Type: "string"},
+		},
+		Rows: [][]any{
+			{json.Number("1"), "alice", []any{"a"}, nil, nil},
+			{json.Number("2"), "bob", nil, true, nil},
+			{nil, nil, nil, nil, "bare string"},
+		},
+		Truncated: true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadNDJSON mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ReadNDJSON([]byte("{\"id\": 1}\n{broken\n"), 10); !errors.Is(err, ErrInvalid) {
+		t.Errorf("broken line err = %v, want ErrInvalid", err)
+	}
+}
+
+func FuzzReadDelimited(f *testing.F) {
+	f.Add("id,name\n1,\"smith, alice\"\n2,bob\n", false)
+	f.Add("a\tb\n1\t2\n3\t4\n", true)
+	f.Add("a,b\n1\n2,3,4\n", false)
+	f.Fuzz(func(t *testing.T, data string, tsv bool) {
+		comma := ','
+		if tsv {
+			comma = '\t'
+		}
+		table, err := ReadDelimited([]byte(data), comma, 3)
+		checkFuzzResult(t, table, err, 3)
+	})
+}
+
+func FuzzReadNDJSON(f *testing.F) {
+	f.Add("{\"id\": 1, \"name\": \"alice\", \"tags\": [\"a\"]}\n\n{\"name\": \"bob\", \"id\": 2, \"active\": true}\n\"bare string\"\n")
+	f.Add("{\"id\": 1}\nnot json\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		table, err := ReadNDJSON([]byte(data), 3)
+		checkFuzzResult(t, table, err, 3)
+	})
+}
@@ -0,0 +1,211 @@
+package preview
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Thrift compact protocol の型 ID。Parquet のメタデータ (FileMetaData / PageHeader) はこの形式で書かれる。
+const (
+	thriftStop   = 0
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// maxThriftDepth は構造体 / リストの入れ子の上限。壊れたメタデータで再帰が深くなりすぎないようにする。
+const maxThriftDepth = 32
+
+// thriftFields は構造体をフィールド ID ごとの値にしたもの。値は bool / int64 / float64 / []byte / []any /
+// thriftFields のいずれか (map は読み捨てる)。生成コードの代わりに、必要なフィールドだけを取り出して使う。
+type thriftFields map[int16]any
+
+func (f thriftFields) int(id int16) int64 {
+	v, _ := f[id].(int64)
+	return v
+}
+
+func (f thriftFields) has(id int16) bool {
+	_, ok := f[id]
+	return ok
+}
+
+func (f thriftFields) bool(id int16) (v, ok bool) {
+	v, ok = f[id].(bool)
+	return v, ok
+}
+
+func (f thriftFields) string(id int16) string {
+	b, _ := f[id].([]byte)
+	return string(b)
+}
+
+func (f thriftFields) fields(id int16) thriftFields {
+	v, _ := f[id].(thriftFields)
+	return v
+}
+
+func (f thriftFields) list(id int16) []any {
+	v, _ := f[id].([]any)
+	return v
+}
+
+// thriftReader は compact protocol の構造体を読む。
+type thriftReader struct {
+	r interface {
+		io.Reader
+		io.ByteReader
+	}
+	depth int
+}
+
+// readThriftStruct は r から構造体を 1 つ読む。
+func readThriftStruct(r interface {
+	io.Reader
+	io.ByteReader
+}) (thriftFields, error) {
+	tr := &thriftReader{r: r}
+	v, err := tr.readStruct()
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("thrift: %w: %w", ErrInvalid, err)
+	}
+	return v, nil
+}
+
+func (t *thriftReader) readStruct() (thriftFields, error) {
+	if t.depth++; t.depth > maxThriftDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	defer func() { t.depth-- }()
+
+	fields := thriftFields{}
+	var last int16
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == thriftStop {
+			return fields, nil
+		}
+		typ := b & 0x0f
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := binary.ReadVarint(t.r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+		var v any
+		switch typ {
+		case thriftTrue:
+			v = true
+		case thriftFalse:
+			v = false
+		default:
+			if v, err = t.readValue(typ); err != nil {
+				return nil, err
+			}
+		}
+		fields[id] = v
+	}
+}
+
+func (t *thriftReader) readValue(typ byte) (any, error) {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// リストの要素の bool は 1 バイトで表す (1 が true)。
+		b, err := t.r.ReadByte()
+		return b == thriftTrue, err
+	case thriftByte:
+		b, err := t.r.ReadByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return binary.ReadVarint(t.r)
+	case thriftDouble:
+		var b [8]byte
+		if _, err := io.ReadFull(t.r, b[:]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case thriftBinary:
+		n, err := binary.ReadUvarint(t.r)
+		if err != nil {
+			return nil, err
+		}
+		if n > MaxReadBytes {
+			return nil, fmt.Errorf("binary of %d bytes", n)
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(t.r, b)
+		return b, err
+	case thriftList, thriftSet:
+		h, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			if n, err = binary.ReadUvarint(t.r); err != nil {
+				return nil, err
+			}
+		}
+		if n > MaxReadBytes {
+			return nil, fmt.Errorf("list of %d elements", n)
+		}
+		if t.depth++; t.depth > maxThriftDepth {
+			return nil, errors.New("nesting too deep")
+		}
+		defer func() { t.depth-- }()
+		items := make([]any, 0, min(n, 1024))
+		for range n {
+			v, err := t.readValue(h & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case thriftMap:
+		n, err := binary.ReadUvarint(t.r)
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		if n > MaxReadBytes {
+			return nil, fmt.Errorf("map of %d entries", n)
+		}
+		kv, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for range n {
+			if _, err := t.readValue(kv >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := t.readValue(kv & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return t.readStruct()
+	}
+	return nil, fmt.Errorf("unknown field type %d", typ)
+}
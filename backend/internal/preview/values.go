@@ -0,0 +1,64 @@
+package preview
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// twosComplement はビッグエンディアンの 2 の補数表現を整数にする (Parquet / Avro の decimal)。
+func twosComplement(b []byte) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	return v
+}
+
+// maxDecimalScale は decimal の scale として受け付ける絶対値の上限。Parquet / Avro / ORC の decimal は
+// decimal256 でも 76 桁のため、これを超える scale は壊れたメタデータとして扱う。
+const maxDecimalScale = 76
+
+// checkDecimalScale は formatDecimal に渡す前に scale が maxDecimalScale に収まることを確かめる。
+func checkDecimalScale(scale int64) error {
+	if scale < -maxDecimalScale || scale > maxDecimalScale {
+		return fmt.Errorf("%w: decimal scale %d", ErrInvalid, scale)
+	}
+	return nil
+}
+
+// formatDecimal は unscaled × 10^-scale を丸めずに 10 進の文字列にする。scale は checkDecimalScale で確かめておく。
+func formatDecimal(unscaled *big.Int, scale int) string {
+	s := unscaled.String()
+	if scale <= 0 {
+		return s + strings.Repeat("0", -scale)
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-scale] + "." + s[len(s)-scale:]
+}
+
+// formatDate は 1970-01-01 からの日数を YYYY-MM-DD にする。
+func formatDate(days int64) string {
+	return time.Unix(days*86400, 0).UTC().Format(time.DateOnly)
+}
+
+// formatTimeOfDay は 0 時からの経過時間を HH:MM:SS[.fraction] にする。
+func formatTimeOfDay(d time.Duration) string {
+	return time.Unix(0, 0).UTC().Add(d).Format("15:04:05.999999999")
+}
+
+// formatTimestamp は UTC に調整済みの時刻なら RFC 3339、タイムゾーンを持たないローカル時刻ならオフセットなしの
+// 日時にする。
+func formatTimestamp(t time.Time, adjustedToUTC bool) string {
+	if adjustedToUTC {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.999999999")
+}
@@ -41,6 +41,11 @@ var validServices = map[string]bool{
 	"rds":             true,
 	"elasticache":     true,
 	"ecs":             true,
+	"kinesis":         true,
+	"waf":             true,
+	"natgw":           true,
+	"ecr":             true,
+	"cwlogs":          true,
 	"compute-sp":      true,
 	"ec2-instance-sp": true,
 	"database-sp":     true,
@@ -20,6 +20,11 @@ func TestValidateService(t *testing.T) {
 		{name: "rds", service: "rds", wantErr: nil},
 		{name: "elasticache", service: "elasticache", wantErr: nil},
 		{name: "ecs", service: "ecs", wantErr: nil},
+		{name: "kinesis", service: "kinesis", wantErr: nil},
+		{name: "waf", service: "waf", wantErr: nil},
+		{name: "natgw", service: "natgw", wantErr: nil},
+		{name: "ecr", service: "ecr", wantErr: nil},
+		{name: "cwlogs", service: "cwlogs", wantErr: nil},
 		{name: "compute-sp", service: "compute-sp", wantErr: nil},
 		{name: "ec2-instance-sp", service: "ec2-instance-sp", wantErr: nil},
 		{name: "database-sp", service: "database-sp", wantErr: nil},
@@ -0,0 +1,252 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicastVersion は asciicast の形式バージョン (https://docs.asciinema.org/manual/asciicast/v2/)。
+const asciicastVersion = 2
+
+// maxLineBytes は記録ファイル 1 行 (ヘッダまたはイベント) の読み取り上限。
+// データチャネルの 1 メッセージ (最大 1MiB) を JSON エスケープしても収まる大きさにする。
+const maxLineBytes = 16 << 20 // 16MiB
+
+// asciicast v2 のイベント種別。
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// Header は asciicast v2 ファイルの 1 行目 (ヘッダ)。
+// 標準フィールドに加え、監査用に Metadata (profile / target 等) を同じオブジェクトに展開して持つ。
+// asciinema 等のプレイヤーは未知のキーを無視するため、そのまま再生できる。
+type Header struct {
+	Version   int               `json:"version"`
+	Width     uint32            `json:"width"`
+	Height    uint32            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Metadata
+}
+
+// Event は asciicast v2 の 1 イベント ([time, code, data])。Time は記録開始からの経過秒。
+type Event struct {
+	Time float64
+	Code string
+	Data string
+}
+
+// MarshalJSON は Event を asciicast v2 の配列形式で出力する。
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Code, e.Data})
+}
+
+// UnmarshalJSON は asciicast v2 の配列形式のイベントを読み取る。
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("asciicast event has %d elements, want 3", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return fmt.Errorf("asciicast event time: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &e.Code); err != nil {
+		return fmt.Errorf("asciicast event code: %w", err)
+	}
+	if err := json.Unmarshal(raw[2], &e.Data); err != nil {
+		return fmt.Errorf("asciicast event data: %w", err)
+	}
+	return nil
+}
+
+// Writer はセッションの入出力を asciicast v2 形式で記録する。session.Recorder を満たす。
+// ブリッジの 2 方向の goroutine から同時に呼ばれるため、書き込みは mu で直列化する。
+//
+// 記録の失敗でセッション自体を止めないよう、Output / Input / Resize はエラーを返さない。
+// 最初の書き込みエラーを保持して以降のイベントを破棄し、Close で返す。
+type Writer struct {
+	mu    sync.Mutex
+	w     io.WriteCloser
+	start time.Time
+	now   func() time.Time
+	err   error
+	// pending はストリーム別 (出力 / 入力) の未完の UTF-8 バイト列。データチャネルのメッセージ境界で
+	// マルチバイト文字が分割されても文字化けしないよう、次のイベントに繰り越す。
+	pending map[string][]byte
+}
+
+// newWriter はヘッダを書き込んだ Writer を返す。
+func newWriter(w io.WriteCloser, header Header, start time.Time, now func() time.Time) (*Writer, error) {
+	header.Version = asciicastVersion
+	header.Timestamp = start.Unix()
+	line, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("marshal asciicast header: %w", err)
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("write asciicast header: %w", err)
+	}
+	return &Writer{w: w, start: start, now: now, pending: map[string][]byte{}}, nil
+}
+
+// Output は端末出力 (agent→ブラウザ) を記録する。
+func (w *Writer) Output(data []byte) { w.writeStream(EventOutput, data) }
+
+// Input は端末入力 (ブラウザ→agent) を記録する。
+func (w *Writer) Input(data []byte) { w.writeStream(EventInput, data) }
+
+// Resize は端末サイズ変更を記録する。
+func (w *Writer) Resize(cols, rows uint32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeEvent(EventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close は未完の UTF-8 バイト列を書き出してファイルを閉じ、記録中に発生した最初のエラーを返す。
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, code := range []string{EventOutput, EventInput} {
+		if rest := w.pending[code]; len(rest) > 0 {
+			w.writeEvent(code, string(rest))
+		}
+	}
+	if err := w.w.Close(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("close recording: %w", err)
+	}
+	return w.err
+}
+
+func (w *Writer) writeStream(code string, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	buf := append(w.pending[code], data...)
+	complete, rest := splitIncompleteUTF8(buf)
+	w.pending[code] = append([]byte(nil), rest...)
+	if len(complete) > 0 {
+		w.writeEvent(code, string(complete))
+	}
+}
+
+// writeEvent は 1 イベントを 1 行として書き込む。mu を保持した状態で呼ぶこと。
+func (w *Writer) writeEvent(code, data string) {
+	if w.err != nil {
+		return
+	}
+	elapsed := w.now().Sub(w.start).Seconds()
+	// asciinema と同様にマイクロ秒精度へ丸めてファイルサイズを抑える。
+	elapsed = math.Round(elapsed*1e6) / 1e6
+	line, err := json.Marshal(Event{Time: elapsed, Code: code, Data: data})
+	if err != nil {
+		w.fail(fmt.Errorf("marshal asciicast event: %w", err))
+		return
+	}
+	if _, err := w.w.Write(append(line, '\n')); err != nil {
+		w.fail(fmt.Errorf("write asciicast event: %w", err))
+	}
+}
+
+func (w *Writer) fail(err error) {
+	w.err = err
+	slog.Warn("session recording failed; further events are discarded", "err", err)
+}
+
+// splitIncompleteUTF8 は b の末尾にある未完のマルチバイト文字を rest として切り出す。
+// 不正なバイト列 (UTF-8 として完結し得ないもの) は complete 側に含め、JSON 化の際に置換文字になる。
+func splitIncompleteUTF8(b []byte) (complete, rest []byte) {
+	// UTF-8 の 1 文字は最大 4 バイトのため、末尾 3 バイト以内に先頭バイトがあるかだけを調べればよい。
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if c < utf8.RuneSelf {
+			// ASCII が見つかればそれ以降に未完の文字はない。
+			return b, nil
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i], b[len(b)-i:]
+			}
+			return b, nil
+		}
+	}
+	return b, nil
+}
+
+// Reader は asciicast v2 ファイルをヘッダ・イベントの順に読み取る。
+type Reader struct {
+	Header Header
+	sc     *bufio.Scanner
+}
+
+// NewReader は r からヘッダを読み取った Reader を返す。
+func NewReader(r io.Reader) (*Reader, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	header, err := readHeader(sc)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{Header: header, sc: sc}, nil
+}
+
+// Next は次のイベントを返す。末尾に達した場合は io.EOF を返す。空行は読み飛ばす。
+func (r *Reader) Next() (Event, error) {
+	for r.sc.Scan() {
+		line := r.sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return Event{}, fmt.Errorf("parse asciicast event: %w", err)
+		}
+		return ev, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return Event{}, fmt.Errorf("read asciicast event: %w", err)
+	}
+	return Event{}, io.EOF
+}
+
+func readHeader(sc *bufio.Scanner) (Header, error) {
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return Header{}, fmt.Errorf("read asciicast header: %w", err)
+		}
+		return Header{}, errors.New("read asciicast header: empty recording")
+	}
+	var h Header
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil {
+		return Header{}, fmt.Errorf("parse asciicast header: %w", err)
+	}
+	if h.Version != asciicastVersion {
+		return Header{}, fmt.Errorf("unsupported asciicast version %d", h.Version)
+	}
+	return h, nil
+}
+
+// readHeaderFile は path の記録ファイルのヘッダのみを読み取る (一覧表示用)。
+func readHeaderFile(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 4096), maxLineBytes)
+	return readHeader(sc)
+}
@@ -0,0 +1,185 @@
+// Package recording はブラウザターミナル (EC2 Session / ECS Exec) のセッション記録を提供する。
+// 記録は監査証跡として使うため、端末出力に加えて入力 ("i") と端末サイズ変更 ("r") も含めた
+// asciicast v2 形式 (<id>.cast) でベースディレクトリ直下に保存する。
+// 標準の asciicast ファイルのため、ダウンロードして asciinema play 等でもそのまま再生できる。
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidID は記録 ID がファイル名として使用できない場合のエラー。
+var ErrInvalidID = errors.New("invalid recording id")
+
+// ErrNotFound は指定 ID の記録が存在しない場合のエラー。
+var ErrNotFound = errors.New("recording not found")
+
+// fileExt は記録ファイルの拡張子。
+const fileExt = ".cast"
+
+// idTimeLayout は記録 ID の先頭に付ける開始日時の書式。ファイル名の辞書順が開始順になる。
+const idTimeLayout = "20060102T150405Z"
+
+// defaultWidth / defaultHeight は記録開始時点の端末サイズ。ブラウザは接続直後にリサイズを送るため、
+// 実際のサイズは最初の "r" イベントで確定する。
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// idPattern は記録 ID として許可する文字種 (パス区切りや先頭ドットを含まない)。
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z_.-]*$`)
+
+// unsafeIDChars は SessionID を記録 ID に埋め込む際に置換する文字。
+var unsafeIDChars = regexp.MustCompile(`[^0-9A-Za-z_.-]`)
+
+// Metadata は記録対象のセッションを識別する情報。asciicast のヘッダに展開して保存する。
+type Metadata struct {
+	// Kind はセッション種別 (ec2-session / ecs-exec)。
+	Kind      string `json:"kind,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Target    string `json:"target,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// Recording は保存済みの記録 1 件の一覧表示用情報。
+type Recording struct {
+	ID string `json:"id"`
+	Metadata
+	StartedAt time.Time `json:"started_at"`
+	// UpdatedAt はファイルの最終更新日時 (記録中でなければ概ねセッション終了時刻)。
+	UpdatedAt time.Time `json:"updated_at"`
+	Size      int64     `json:"size"`
+}
+
+// Store はベースディレクトリ直下の .cast ファイルとして記録を読み書きする。
+type Store struct {
+	dir string
+	now func() time.Time
+}
+
+// NewStore は dir を保存先ディレクトリとする Store を返す。
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, now: time.Now}
+}
+
+func validateID(id string) error {
+	if !idPattern.MatchString(id) || strings.Contains(id, "..") {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+fileExt)
+}
+
+// Create は meta のセッションの記録ファイルを作成し、ヘッダを書き込んだ Writer を返す。
+// 記録 ID は「開始日時_SessionID」。記録は機密情報を含み得るため、ディレクトリは 0700、ファイルは 0600 で作成する。
+func (s *Store) Create(meta Metadata) (*Writer, string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, "", fmt.Errorf("create recordings dir %s: %w", s.dir, err)
+	}
+	start := s.now()
+	id := start.UTC().Format(idTimeLayout)
+	if meta.SessionID != "" {
+		id += "_" + unsafeIDChars.ReplaceAllString(meta.SessionID, "-")
+	}
+	f, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, "", fmt.Errorf("create recording %s: %w", id, err)
+	}
+	header := Header{
+		Width:    defaultWidth,
+		Height:   defaultHeight,
+		Title:    strings.TrimSpace(meta.Kind + " " + meta.Target),
+		Env:      map[string]string{"TERM": "xterm-256color"},
+		Metadata: meta,
+	}
+	w, err := newWriter(f, header, start, s.now)
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return w, id, nil
+}
+
+// List は保存済みの記録を開始日時の降順で返す。ディレクトリが存在しない場合は空リストを返す。
+// ヘッダを読めないファイル (記録開始直後の書き込み途中など) は一覧から除外する。
+func (s *Store) List() ([]Recording, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Recording{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recordings dir %s: %w", s.dir, err)
+	}
+	recordings := make([]Recording, 0, len(entries))
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), fileExt)
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileExt) || validateID(id) != nil {
+			continue
+		}
+		rec, err := s.stat(id)
+		if err != nil {
+			slog.Warn("skip unreadable session recording", "id", id, "err", err)
+			continue
+		}
+		recordings = append(recordings, rec)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		if !recordings[i].StartedAt.Equal(recordings[j].StartedAt) {
+			return recordings[i].StartedAt.After(recordings[j].StartedAt)
+		}
+		return recordings[i].ID > recordings[j].ID
+	})
+	return recordings, nil
+}
+
+// Open は id の記録ファイルを開き、一覧表示用情報とともに返す。呼び出し側で Close すること。
+// 存在しない場合は ErrNotFound を返す。
+func (s *Store) Open(id string) (*os.File, Recording, error) {
+	if err := validateID(id); err != nil {
+		return nil, Recording{}, err
+	}
+	rec, err := s.stat(id)
+	if err != nil {
+		return nil, Recording{}, err
+	}
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		return nil, Recording{}, fmt.Errorf("open recording %s: %w", id, err)
+	}
+	return f, rec, nil
+}
+
+func (s *Store) stat(id string) (Recording, error) {
+	p := s.path(id)
+	info, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return Recording{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Recording{}, fmt.Errorf("stat recording %s: %w", id, err)
+	}
+	header, err := readHeaderFile(p)
+	if err != nil {
+		return Recording{}, fmt.Errorf("recording %s: %w", id, err)
+	}
+	return Recording{
+		ID:        id,
+		Metadata:  header.Metadata,
+		StartedAt: time.Unix(header.Timestamp, 0).UTC(),
+		UpdatedAt: info.ModTime().UTC(),
+		Size:      info.Size(),
+	}, nil
+}
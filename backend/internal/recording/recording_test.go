@@ -0,0 +1,219 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock は呼び出しごとに step ずつ進む時計。
+func fakeClock(start time.Time, step time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		t := now
+		now = now.Add(step)
+		return t
+	}
+}
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "valid", id: "20261016T120000Z_user-0123abc"},
+		{name: "empty", id: "", wantErr: true},
+		{name: "dot prefix", id: ".hidden", wantErr: true},
+		{name: "traversal", id: "a..b", wantErr: true},
+		{name: "slash", id: "a/b", wantErr: true},
+		{name: "backslash", id: `a\b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidID) {
+				t.Errorf("validateID(%q) error = %v, want ErrInvalidID", tt.id, err)
+			}
+		})
+	}
+}
+
+func TestStoreRecordListOpen(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	s.now = fakeClock(start, 500*time.Millisecond)
+
+	meta := Metadata{Kind: "ec2-session", Profile: "prod", Region: "ap-northeast-1", Target: "i-0123", SessionID: "user/0123"}
+	w, id, err := s.Create(meta)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if id != "20261016T120000Z_user-0123" {
+		t.Errorf("id = %q, want %q", id, "20261016T120000Z_user-0123")
+	}
+	w.Resize(120, 40)
+	w.Input([]byte("ls\n"))
+	// "あ" (E3 81 82) がメッセージ境界で分割されても 1 イベントにまとまって記録されること。
+	w.Output([]byte("file\xe3\x81"))
+	w.Output([]byte("\x82\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, id+".cast"))
+	if err != nil {
+		t.Fatalf("stat recording: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("recording perm = %o, want 600", perm)
+	}
+
+	// ヘッダの壊れたファイルや無関係なファイルは一覧から除外される。
+	if err := os.WriteFile(filepath.Join(dir, "broken.cast"), []byte("not json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("List() = %d items, want 1", len(list))
+	}
+	if list[0].ID != id || list[0].Metadata != meta || !list[0].StartedAt.Equal(start) {
+		t.Errorf("List()[0] = %+v, want id %s with metadata and start time", list[0], id)
+	}
+
+	f, rec, err := s.Open(id)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	if rec.Size != info.Size() {
+		t.Errorf("Open() size = %d, want %d", rec.Size, info.Size())
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if r.Header.Version != 2 || r.Header.Profile != "prod" || r.Header.Target != "i-0123" {
+		t.Errorf("header = %+v, want version 2 with profile and target", r.Header)
+	}
+	var got []Event
+	for {
+		ev, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, ev)
+	}
+	want := []Event{
+		{Time: 0.5, Code: EventResize, Data: "120x40"},
+		{Time: 1, Code: EventInput, Data: "ls\n"},
+		{Time: 1.5, Code: EventOutput, Data: "file"},
+		{Time: 2, Code: EventOutput, Data: "あ\n"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStoreOpenErrors(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if _, _, err := s.Open("../etc/passwd"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Open(traversal) error = %v, want ErrInvalidID", err)
+	}
+	if _, _, err := s.Open("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(missing) error = %v, want ErrNotFound", err)
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 0 {
+		t.Errorf("List() on missing dir = %v, %v, want empty", list, err)
+	}
+}
+
+func TestSplitIncompleteUTF8(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		wantComplete string
+		wantRest     string
+	}{
+		{name: "ascii", in: "abc", wantComplete: "abc"},
+		{name: "complete multibyte", in: "aあ", wantComplete: "aあ"},
+		{name: "one byte of three", in: "a\xe3", wantComplete: "a", wantRest: "\xe3"},
+		{name: "two bytes of three", in: "a\xe3\x81", wantComplete: "a", wantRest: "\xe3\x81"},
+		{name: "three bytes of four", in: "\xf0\x9f\x98", wantRest: "\xf0\x9f\x98"},
+		{name: "stray continuation", in: "a\x81", wantComplete: "a\x81"},
+		{name: "empty", in: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			complete, rest := splitIncompleteUTF8([]byte(tt.in))
+			if string(complete) != tt.wantComplete || string(rest) != tt.wantRest {
+				t.Errorf("splitIncompleteUTF8(%q) = %q, %q, want %q, %q", tt.in, complete, rest, tt.wantComplete, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestReplayCapsIdleAndHonorsCancel(t *testing.T) {
+	cast := `{"version":2,"width":80,"height":24,"timestamp":0}
+[0.0,"o","a"]
+[3600.0,"o","b"]
+[3600.01,"r","100x30"]
+`
+	r, err := NewReader(strings.NewReader(cast))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	var got []string
+	started := time.Now()
+	err = Replay(context.Background(), r, ReplayOptions{Speed: 2, MaxIdle: 10 * time.Millisecond}, func(ev Event) error {
+		got = append(got, ev.Code+":"+ev.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Replay took %v, want idle capped by MaxIdle", elapsed)
+	}
+	if strings.Join(got, ",") != "o:a,o:b,r:100x30" {
+		t.Errorf("replayed events = %v", got)
+	}
+
+	r, err = NewReader(strings.NewReader(cast))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	err = Replay(ctx, r, ReplayOptions{}, func(Event) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Replay() after cancel error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,53 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ReplayOptions は Replay の再生速度と待ち時間の上限を指定する。
+type ReplayOptions struct {
+	// Speed は再生倍率 (1 で等速)。0 以下は等速として扱う。
+	Speed float64
+	// MaxIdle はイベント間の待ち時間の上限 (asciinema の idle_time_limit 相当)。0 の場合は上限なし。
+	MaxIdle time.Duration
+}
+
+// Replay は r の記録を元のタイミングで再生し、イベントごとに emit を呼ぶ。
+// ctx のキャンセルまたは emit のエラーで中断する。末尾まで再生した場合は nil を返す。
+func Replay(ctx context.Context, r *Reader, opts ReplayOptions, emit func(Event) error) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var prev float64
+	for {
+		ev, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		wait := time.Duration((ev.Time - prev) / speed * float64(time.Second))
+		if opts.MaxIdle > 0 && wait > opts.MaxIdle {
+			wait = opts.MaxIdle
+		}
+		prev = ev.Time
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if err := emit(ev); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package search は取得済みのリソース一覧をサービス・プロファイル・リージョン横断で検索するインメモリの索引を提供する。
+// 索引はリソース一覧の取得元 (API サーバのリソースキャッシュのエントリ、CLI の一覧結果) ごとに Put で取り込み、
+// 名前 / ID / ARN / タグを大文字小文字を区別せずに部分一致で検索する。
+package search
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Origin はリソース一覧の取得元のサービス・プロファイル・リージョン。
+type Origin struct {
+	Service string `json:"service"`
+	Profile string `json:"profile"`
+	Region  string `json:"region"`
+}
+
+// Document は索引に取り込んだ 1 リソース。
+type Document struct {
+	Origin
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	State string            `json:"state,omitempty"`
+	ARN   string            `json:"arn,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// Result は検索結果の 1 件。Score が大きいほどクエリとよく一致する。
+type Result struct {
+	Document
+	Score int `json:"score"`
+}
+
+// Query は Search の条件。Text は空白区切りの語で、すべての語に一致したリソースを返す。
+// Services / Profiles / Regions は空でなければ取得元をその値に絞る。Limit が正の場合は上位 Limit 件まで返す。
+type Query struct {
+	Text     string
+	Services []string
+	Profiles []string
+	Regions  []string
+	Limit    int
+}
+
+// resource は aws.Resource のうち索引が参照するメソッド。ロード直後の値 (型付きのスライス) の要素が実装していれば
+// JSON のフィールドより優先して使う (ResourceState は正規化済みの状態を返すため)。
+type resource interface {
+	ResourceID() string
+	ResourceName() string
+	ResourceState() string
+}
+
+// source は 1 つの取得元から取り込んだリソース。version が同じ値の再取り込みは省略する。
+type source struct {
+	version time.Time
+	docs    []Document
+}
+
+// Index は取得元のキーごとにリソースを保持する検索索引。ゼロ値ではなく New で作成すること。
+type Index struct {
+	mu      sync.RWMutex
+	sources map[string]source
+}
+
+// New は空の Index を作成する。
+func New() *Index {
+	return &Index{sources: map[string]source{}}
+}
+
+// Put は key の取得元のリソースを value で置き換える。value はリソースのスライス、またはその JSON 表現
+// (json.RawMessage) で、id / name / state / arn / tags フィールドを索引に取り込む。id と name のどちらもない要素や、
+// スライスでない value は取り込まない。key が同じ version で取り込み済みなら何もしない。
+func (x *Index) Put(key string, version time.Time, origin Origin, value any) {
+	x.mu.RLock()
+	src, ok := x.sources[key]
+	x.mu.RUnlock()
+	if ok && src.version.Equal(version) {
+		return
+	}
+
+	docs := documents(origin, value)
+	x.mu.Lock()
+	x.sources[key] = source{version: version, docs: docs}
+	x.mu.Unlock()
+}
+
+// Retain は keep が false を返すキーの取得元を索引から取り除く。
+func (x *Index) Retain(keep func(key string) bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for key := range x.sources {
+		if !keep(key) {
+			delete(x.sources, key)
+		}
+	}
+}
+
+// Len は索引に取り込んだリソースの数を返す。
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	n := 0
+	for _, src := range x.sources {
+		n += len(src.docs)
+	}
+	return n
+}
+
+// Search は q に一致するリソースをスコアの高い順 (同点はサービス・プロファイル・リージョン・名前の順) に返す。
+// Text が空の場合は何も返さない。
+func (x *Index) Search(q Query) []Result {
+	terms := strings.Fields(strings.ToLower(q.Text))
+	if len(terms) == 0 {
+		return []Result{}
+	}
+
+	results := []Result{}
+	x.mu.RLock()
+	for _, src := range x.sources {
+		for _, doc := range src.docs {
+			if !matchesOrigin(q, doc.Origin) {
+				continue
+			}
+			if score := scoreDocument(doc, terms); score > 0 {
+				results = append(results, Result{Document: doc, Score: score})
+			}
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+func matchesOrigin(q Query, o Origin) bool {
+	in := func(values []string, v string) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, want := range values {
+			if want == v {
+				return true
+			}
+		}
+		return false
+	}
+	return in(q.Services, o.Service) && in(q.Profiles, o.Profile) && in(q.Regions, o.Region)
+}
+
+// 一致の種類ごとのスコア。名前と ID / ARN の完全一致を最優先し、前方一致、タグの完全一致、部分一致の順に下げる。
+const (
+	scoreExact        = 100
+	scoreNamePrefix   = 50
+	scoreIDPrefix     = 40
+	scoreTagExact     = 30
+	scoreNameContains = 20
+	scoreIDContains   = 10
+	scoreTagContains  = 5
+)
+
+// scoreDocument は各語の最も良い一致のスコアの合計を返す。一致しない語がある場合は 0。
+func scoreDocument(doc Document, terms []string) int {
+	name := strings.ToLower(doc.Name)
+	ids := []string{strings.ToLower(doc.ID), strings.ToLower(doc.ARN)}
+	total := 0
+	for _, term := range terms {
+		best := 0
+		improve := func(score int) {
+			if score > best {
+				best = score
+			}
+		}
+		switch {
+		case name == term:
+			improve(scoreExact)
+		case strings.HasPrefix(name, term):
+			improve(scoreNamePrefix)
+		case strings.Contains(name, term):
+			improve(scoreNameContains)
+		}
+		for _, id := range ids {
+			switch {
+			case id == "":
+			case id == term:
+				improve(scoreExact)
+			case strings.HasPrefix(id, term):
+				improve(scoreIDPrefix)
+			case strings.Contains(id, term):
+				improve(scoreIDContains)
+			}
+		}
+		for k, v := range doc.Tags {
+			k, v = strings.ToLower(k), strings.ToLower(v)
+			// "key=value" 形式の語はタグの完全一致として扱う。
+			if term == k+"="+v {
+				improve(scoreTagExact)
+			} else if strings.Contains(k, term) || strings.Contains(v, term) {
+				improve(scoreTagContains)
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+// documents は value の要素を Document に変換する。
+func documents(origin Origin, value any) []Document {
+	var elems []any
+	var raws []json.RawMessage
+	switch v := value.(type) {
+	case json.RawMessage:
+		if err := json.Unmarshal(v, &raws); err != nil {
+			return nil
+		}
+	case []byte:
+		if err := json.Unmarshal(v, &raws); err != nil {
+			return nil
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice {
+			return nil
+		}
+		for i := range rv.Len() {
+			elems = append(elems, rv.Index(i).Interface())
+		}
+	}
+
+	var docs []Document
+	for _, raw := range raws {
+		if doc, ok := document(origin, nil, raw); ok {
+			docs = append(docs, doc)
+		}
+	}
+	for _, elem := range elems {
+		raw, err := json.Marshal(elem)
+		if err != nil {
+			continue
+		}
+		if doc, ok := document(origin, elem, raw); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// document は 1 要素を Document に変換する。elem が resource を実装していれば ID / 名前 / 状態はそのメソッドから取る。
+func document(origin Origin, elem any, raw json.RawMessage) (Document, bool) {
+	// 文字列でないフィールド (例えば state がオブジェクトの型) があっても他のフィールドは取り込めるよう any で受ける。
+	var fields struct {
+		ID    any             `json:"id"`
+		Name  any             `json:"name"`
+		State any             `json:"state"`
+		ARN   any             `json:"arn"`
+		Tags  json.RawMessage `json:"tags"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Document{}, false
+	}
+	str := func(v any) string {
+		s, _ := v.(string)
+		return s
+	}
+	doc := Document{
+		Origin: origin,
+		ID:     str(fields.ID),
+		Name:   str(fields.Name),
+		State:  str(fields.State),
+		ARN:    str(fields.ARN),
+		Tags:   parseTags(fields.Tags),
+	}
+	if r, ok := elem.(resource); ok {
+		doc.ID, doc.Name, doc.State = r.ResourceID(), r.ResourceName(), r.ResourceState()
+	}
+	if doc.ARN == "" && strings.HasPrefix(doc.ID, "arn:") {
+		doc.ARN = doc.ID
+	}
+	if doc.ID == "" && doc.Name == "" {
+		return Document{}, false
+	}
+	return doc, true
+}
+
+// parseTags はタグを {"key": "value"} と [{"key": ..., "value": ...}] のどちらの形式からも読み取る。
+func parseTags(raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err == nil {
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	var list []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil || len(list) == 0 {
+		return nil
+	}
+	m = make(map[string]string, len(list))
+	for _, t := range list {
+		m[t.Key] = t.Value
+	}
+	return m
+}
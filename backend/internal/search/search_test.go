@@ -0,0 +1,141 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testResource struct {
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	State string            `json:"state"`
+	Tags  map[string]string `json:"tags"`
+}
+
+func (r testResource) ResourceID() string    { return r.ID }
+func (r testResource) ResourceName() string  { return r.Name }
+func (r testResource) ResourceState() string { return "normalized-" + r.State }
+
+func TestIndexPutDocuments(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []Document
+	}{
+		{
+			name:  "typed slice uses the resource methods",
+			value: []testResource{{ID: "i-1", Name: "web", State: "running", Tags: map[string]string{"env": "prod"}}},
+			want:  []Document{{ID: "i-1", Name: "web", State: "normalized-running", Tags: map[string]string{"env": "prod"}}},
+		},
+		{
+			name:  "json restored from disk",
+			value: json.RawMessage(`[{"id":"arn:aws:sqs:ap-northeast-1:1:q","name":"q","state":{"code":1}}]`),
+			want:  []Document{{ID: "arn:aws:sqs:ap-northeast-1:1:q", Name: "q", ARN: "arn:aws:sqs:ap-northeast-1:1:q"}},
+		},
+		{
+			name:  "tag list and explicit arn",
+			value: json.RawMessage(`[{"name":"stack","arn":"arn:cfn","tags":[{"key":"team","value":"payments"}]}]`),
+			want:  []Document{{Name: "stack", ARN: "arn:cfn", Tags: map[string]string{"team": "payments"}}},
+		},
+		{
+			name:  "elements without id and name are skipped",
+			value: []map[string]string{{"code": "x"}},
+		},
+		{
+			name:  "non-slice values are skipped",
+			value: map[string]string{"id": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := New()
+			x.Put("k", time.Time{}, Origin{}, tt.value)
+			got := x.sources["k"].docs
+			if len(got) != len(tt.want) {
+				t.Fatalf("docs = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.ID != w.ID || g.Name != w.Name || g.State != w.State || g.ARN != w.ARN || len(g.Tags) != len(w.Tags) {
+					t.Errorf("docs[%d] = %+v, want %+v", i, g, w)
+				}
+				for k, v := range w.Tags {
+					if g.Tags[k] != v {
+						t.Errorf("docs[%d].Tags[%q] = %q, want %q", i, k, g.Tags[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIndexPutSkipsSameVersion(t *testing.T) {
+	x := New()
+	v1 := time.Now()
+	x.Put("k", v1, Origin{}, []testResource{{ID: "a"}})
+	x.Put("k", v1, Origin{}, []testResource{{ID: "a"}, {ID: "b"}})
+	if n := x.Len(); n != 1 {
+		t.Errorf("Len = %d after re-putting the same version, want 1", n)
+	}
+	x.Put("k", v1.Add(time.Second), Origin{}, []testResource{{ID: "a"}, {ID: "b"}})
+	if n := x.Len(); n != 2 {
+		t.Errorf("Len = %d after a new version, want 2", n)
+	}
+
+	x.Retain(func(string) bool { return false })
+	if n := x.Len(); n != 0 {
+		t.Errorf("Len = %d after Retain, want 0", n)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	x := New()
+	prod := Origin{Service: "ecs", Profile: "prod", Region: "ap-northeast-1"}
+	dev := Origin{Service: "lambda", Profile: "dev", Region: "us-east-1"}
+	x.Put("ecs:prod:ap-northeast-1", time.Time{}, prod, []testResource{
+		{ID: "arn:aws:ecs:ap-northeast-1:1:service/payment-api", Name: "payment-api"},
+		{ID: "arn:aws:ecs:ap-northeast-1:1:service/payment-api-worker", Name: "payment-api-worker"},
+		{ID: "arn:aws:ecs:ap-northeast-1:1:service/web", Name: "web", Tags: map[string]string{"team": "payments"}},
+	})
+	x.Put("lambda:dev:us-east-1", time.Time{}, dev, []testResource{
+		{ID: "notify-payment-api", Name: "notify-payment-api"},
+		{ID: "other", Name: "other"},
+	})
+
+	names := func(results []Result) []string {
+		out := make([]string, len(results))
+		for i, r := range results {
+			out[i] = r.Name
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{name: "exact before prefix before substring", q: Query{Text: "payment-api"}, want: []string{"payment-api", "payment-api-worker", "notify-payment-api"}},
+		{name: "case insensitive", q: Query{Text: "PAYMENT-API-WORKER"}, want: []string{"payment-api-worker"}},
+		{name: "matches arn", q: Query{Text: "service/web"}, want: []string{"web"}},
+		{name: "tag key=value", q: Query{Text: "team=payments"}, want: []string{"web"}},
+		{name: "all terms must match", q: Query{Text: "payment worker"}, want: []string{"payment-api-worker"}},
+		{name: "origin filter", q: Query{Text: "payment-api", Profiles: []string{"dev"}}, want: []string{"notify-payment-api"}},
+		{name: "limit", q: Query{Text: "payment-api", Limit: 1}, want: []string{"payment-api"}},
+		{name: "empty query", q: Query{Text: " "}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(x.Search(tt.q))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Search = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
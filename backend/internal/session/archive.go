@@ -0,0 +1,152 @@
+package session
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// WriteArchive はローカルの localPath (ファイルまたはディレクトリ) を、ベース名をルートとする tar アーカイブとして w に書き出す。
+// Upload でリモートへ送るアーカイブの作成に使う。シンボリックリンクはリンクとして格納する。
+func WriteArchive(w io.Writer, localPath string) error {
+	root := filepath.Clean(localPath)
+	base := filepath.Dir(root)
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", localPath, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("archive %s: %w", localPath, err)
+	}
+	return nil
+}
+
+// WriteArchiveFile は r の内容を name という 1 ファイルだけを含む tar アーカイブとして w に書き出す。
+// ブラウザからアップロードされたファイルのように、ローカルのファイルシステムにない内容を送る場合に使う。
+func WriteArchiveFile(w io.Writer, name string, r io.Reader, size int64) error {
+	name = path.Base(filepath.ToSlash(name))
+	if name == "." || name == "/" || name == ".." {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// ExtractArchive は Download で取得した tar アーカイブを dir に展開する。リモートから届いたアーカイブは
+// 信頼しないため、dir の外を指すエントリ (絶対パス・..) はエラーとし、通常ファイルとディレクトリ以外は読み飛ばす。
+func ExtractArchive(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("archive entry %q escapes the destination directory", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("create directory %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := extractArchiveFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			slog.Warn("skipping unsupported archive entry", "name", hdr.Name, "type", string(hdr.Typeflag))
+		}
+	}
+}
+
+// extractArchiveFile は tar の現在のエントリを target に書き出す。
+func extractArchiveFile(r io.Reader, target string, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", target, err)
+	}
+	// 既存のシンボリックリンクを辿って dir の外へ書き込まないよう、先に削除してから作成する。
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("replace %s: %w", target, err)
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", target, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndExtractArchive(t *testing.T) {
+	src := t.TempDir()
+	root := filepath.Join(src, "logs")
+	if err := os.MkdirAll(filepath.Join(root, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "app", "app.log"), []byte("started\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := WriteArchive(&archive, root); err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+
+	dst := t.TempDir()
+	if err := ExtractArchive(&archive, dst); err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dst, "logs", "app", "app.log"))
+	if err != nil {
+		t.Fatalf("read extracted file: %v", err)
+	}
+	if string(got) != "started\n" {
+		t.Errorf("extracted content = %q, want %q", got, "started\n")
+	}
+	info, err := os.Stat(filepath.Join(dst, "logs", "app", "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("extracted mode = %v, want 0600", perm)
+	}
+}
+
+func TestExtractArchiveRejectsEscapingEntries(t *testing.T) {
+	for _, name := range []string{"../evil", "/etc/passwd", "a/../../evil"} {
+		t.Run(name, func(t *testing.T) {
+			var archive bytes.Buffer
+			tw := tar.NewWriter(&archive)
+			if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: 4}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte("evil")); err != nil {
+				t.Fatal(err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := t.TempDir()
+			err := ExtractArchive(&archive, filepath.Join(dst, "out"))
+			if err == nil || !strings.Contains(err.Error(), "escapes") {
+				t.Fatalf("ExtractArchive() error = %v, want escape error", err)
+			}
+			if _, err := os.Stat(filepath.Join(dst, "evil")); !os.IsNotExist(err) {
+				t.Errorf("entry %q was written outside the destination", name)
+			}
+		})
+	}
+}
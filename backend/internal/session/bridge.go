@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coder/websocket"
@@ -20,6 +22,18 @@ const terminateTimeout = 5 * time.Second
 // リサイズ制御用の JSON は小さいため、この上限で十分。
 const browserReadLimit = 1 << 20 // 1MiB
 
+// detachedOutputLimit はブラウザの切断中に保持する端末出力の上限バイト数。超えた分は古いものから捨てる。
+const detachedOutputLimit = 1 << 20 // 1MiB
+
+// replacedNoticeTimeout は別のブラウザに接続を奪われた旧ブラウザへ終了を通知する際のタイムアウト。
+const replacedNoticeTimeout = time.Second
+
+// ErrBridgeClosed は終了済みのブリッジに Attach しようとした場合に返る。
+var ErrBridgeClosed = errors.New("session bridge closed")
+
+// errBrowserDisconnected はブラウザ側 WebSocket の読み取りエラー (切断) を、データチャネル側のエラーと区別するために付与する。
+var errBrowserDisconnected = errors.New("browser disconnected")
+
 // controlMessageType はブラウザ backend 間の TEXT (JSON) 制御メッセージの種別。
 type controlMessageType string
 
@@ -45,22 +59,240 @@ type controlMessage struct {
 // TerminateFunc はブリッジ終了時に呼び出すセッション終了処理 (SSM TerminateSession 等)。
 type TerminateFunc func(ctx context.Context) error
 
+// Recorder はブリッジを流れる端末入出力と端末サイズ変更を記録する (recording.Writer 等)。
+// ブリッジの 2 方向の goroutine から同時に呼ばれるため、実装は goroutine セーフであること。
+// 記録の失敗でセッションを止めないよう、メソッドはエラーを返さない。
+type Recorder interface {
+	Output(data []byte)
+	Input(data []byte)
+	Resize(cols, rows uint32)
+}
+
 // Bridge はデータチャネルとブラウザ WebSocket の間で双方向にバイト列を中継する。
+//
+// 中継したバイト数と最終アクティビティ時刻を Stats で公開し、Stop で外部 (セッション一覧 API 等) から
+// 終了させられる。IdleTimeout を指定すると、ブラウザからの入力が途絶えたセッションを自動で終了する。
+// ReattachGrace を指定すると、ブラウザが異常切断してもセッションを維持し、Attach で別の WebSocket に接続し直せる。
 type Bridge struct {
 	DataChannel *DataChannel
 	Browser     *websocket.Conn
 	// Terminate はブリッジ終了時に呼び出す (省略可)。SSM セッションのクリーンアップに使う。
 	Terminate TerminateFunc
+	// Recorder はセッション記録 (省略可)。Close は呼び出し側が Run の終了後に行う。
+	Recorder Recorder
+	// IdleTimeout はブラウザからの入力 (キー入力・リサイズ) が途絶えてから自動終了するまでの時間。0 の場合は無効。
+	// 出力のみが続くセッション (top 等を開いたまま放置されたタブ) も終了させるため、出力はアイドル判定に含めない。
+	IdleTimeout time.Duration
+	// ReattachGrace はブラウザの WebSocket が正常終了 (StatusNormalClosure) 以外で切断されてから、
+	// Attach による再接続を待つ時間。0 の場合は従来どおり切断と同時にセッションを終了する。
+	ReattachGrace time.Duration
+
+	initOnce sync.Once
+	// attachCh は Attach から Run へ新しいブラウザ接続を渡す。done は Run の終了で close される。
+	attachCh chan *browserAttachment
+	done     chan struct{}
+
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	stopped    bool
+	stopReason string
+	// browser は現在接続中のブラウザ (切断中は nil)。
+	browser *browserAttachment
+
+	// writeMu はブラウザへの出力の順序 (切断中に保持した出力 → 以降の出力) を保つ。
+	writeMu       sync.Mutex
+	pendingOutput [][]byte
+	pendingBytes  int
+
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+	lastInput    atomic.Int64 // UnixNano
+	lastActivity atomic.Int64 // UnixNano
+}
+
+// BridgeStats は Bridge の中継状況のスナップショット。
+type BridgeStats struct {
+	// BytesIn はブラウザ→データチャネル方向 (端末入力) のバイト数。
+	BytesIn int64
+	// BytesOut はデータチャネル→ブラウザ方向 (端末出力) のバイト数。
+	BytesOut int64
+	// LastInput はブラウザからの最後の入力時刻 (アイドル判定の基準)。
+	LastInput time.Time
+	// LastActivity は入出力いずれかの最後の時刻。
+	LastActivity time.Time
+	// Attached はブラウザが接続中かどうか (ReattachGrace による再接続待ちの間は false)。
+	Attached bool
+}
+
+// browserAttachment はブリッジに接続したブラウザの WebSocket。done はブリッジがその接続を使い終えたときに close される。
+type browserAttachment struct {
+	conn *websocket.Conn
+	done chan struct{}
+}
+
+func newBrowserAttachment(conn *websocket.Conn) *browserAttachment {
+	conn.SetReadLimit(browserReadLimit)
+	return &browserAttachment{conn: conn, done: make(chan struct{})}
+}
+
+// init は Attach と Run が共有するチャネルを初期化する。
+func (b *Bridge) init() {
+	b.initOnce.Do(func() {
+		b.attachCh = make(chan *browserAttachment)
+		b.done = make(chan struct{})
+	})
+}
+
+// Stats は中継したバイト数と最終アクティビティ時刻を返す。Run と並行して呼んでよい。
+func (b *Bridge) Stats() BridgeStats {
+	return BridgeStats{
+		BytesIn:      b.bytesIn.Load(),
+		BytesOut:     b.bytesOut.Load(),
+		LastInput:    time.Unix(0, b.lastInput.Load()),
+		LastActivity: time.Unix(0, b.lastActivity.Load()),
+		Attached:     b.currentBrowser() != nil,
+	}
+}
+
+// Attach はブラウザの WebSocket を稼働中のブリッジに接続する。別のブラウザが接続中の場合はそちらを切り離す。
+// conn がブリッジから切り離されるか、ブリッジが終了するまでブロックする。ブリッジが既に終了している場合は
+// conn へ終了を通知して閉じ、ErrBridgeClosed を返す。
+func (b *Bridge) Attach(ctx context.Context, conn *websocket.Conn) error {
+	b.init()
+	att := newBrowserAttachment(conn)
+	select {
+	case b.attachCh <- att:
+	case <-b.done:
+		writeExit(ctx, conn, "session already closed")
+		if err := conn.Close(websocket.StatusNormalClosure, ""); err != nil {
+			slog.Debug("failed to close browser websocket", "err", err)
+		}
+		return ErrBridgeClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-att.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// currentBrowser は接続中のブラウザを返す (切断中は nil)。
+func (b *Bridge) currentBrowser() *browserAttachment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.browser
+}
+
+// attachBrowser は切断中に保持した出力を att へ送ってから、att を出力先に設定する。
+func (b *Bridge) attachBrowser(ctx context.Context, att *browserAttachment) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	for len(b.pendingOutput) > 0 {
+		if err := att.conn.Write(ctx, websocket.MessageBinary, b.pendingOutput[0]); err != nil {
+			// 送れなかった出力は保持したまま、次の再接続で送る。
+			slog.Debug("failed to flush detached output to browser", "err", err)
+			break
+		}
+		b.pendingBytes -= len(b.pendingOutput[0])
+		b.pendingOutput = b.pendingOutput[1:]
+	}
+	b.mu.Lock()
+	b.browser = att
+	b.mu.Unlock()
+}
+
+// releaseBrowser は att を出力先から外し、Attach の待機を解除する。
+func (b *Bridge) releaseBrowser(att *browserAttachment) {
+	b.mu.Lock()
+	if b.browser == att {
+		b.browser = nil
+	}
+	b.mu.Unlock()
+	close(att.done)
+}
+
+// writeOutput は端末出力を接続中のブラウザへ送る。ReattachGrace が有効な場合、ブラウザが切断中の出力や
+// 送信に失敗した出力は detachedOutputLimit まで保持し、再接続時に送る。
+func (b *Bridge) writeOutput(ctx context.Context, data []byte) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	if att := b.currentBrowser(); att != nil {
+		err := att.conn.Write(ctx, websocket.MessageBinary, data)
+		if err == nil || b.ReattachGrace <= 0 {
+			return err
+		}
+		slog.Debug("failed to write to browser, holding output for reattach", "err", err)
+	}
+	if b.ReattachGrace <= 0 {
+		return nil
+	}
+	b.pendingOutput = append(b.pendingOutput, data)
+	b.pendingBytes += len(data)
+	for b.pendingBytes > detachedOutputLimit && len(b.pendingOutput) > 1 {
+		b.pendingBytes -= len(b.pendingOutput[0])
+		b.pendingOutput = b.pendingOutput[1:]
+	}
+	return nil
+}
+
+// Stop はブリッジを終了させる。reason は終了通知 (exit) のメッセージとしてブラウザへ送られる。
+// Run の開始前に呼んだ場合は、Run が開始直後に終了する。複数回呼んだ場合は最初の reason を使う。
+func (b *Bridge) Stop(reason string) {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	b.stopped = true
+	b.stopReason = reason
+	cancel := b.cancel
+	b.mu.Unlock()
+
+	if cancel != nil {
+		b.stopWithNotice(cancel, reason)
+	}
+}
+
+// stopWithNotice はブラウザへ終了理由を通知してから Run の ctx をキャンセルする。
+// Read 中の ctx がキャンセルされると WebSocket 接続自体が閉じられるため、通知は必ずキャンセルより先に行う。
+func (b *Bridge) stopWithNotice(cancel context.CancelFunc, reason string) {
+	ctx, notifyCancel := context.WithTimeout(context.Background(), terminateTimeout)
+	defer notifyCancel()
+	b.notifyExit(ctx, reason)
+	cancel()
+}
+
+// isStopped は Stop が呼ばれたかどうかを返す。
+func (b *Bridge) isStopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stopped
 }
 
 // Run はブリッジを開始し、いずれかの方向が終了するまでブロックする。
 // 片方の goroutine が終了すると ctx がキャンセルされ、もう片方も終了する。
 // 戻り値は通信そのもののエラーであり、正常な切断 (channel_closed やクライアント切断) では nil を返す。
 func (b *Bridge) Run(ctx context.Context) error {
-	b.Browser.SetReadLimit(browserReadLimit)
+	b.init()
+	defer close(b.done)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	first := newBrowserAttachment(b.Browser)
+	b.attachBrowser(ctx, first)
+	b.mu.Lock()
+	b.cancel = cancel
+	stopped, reason := b.stopped, b.stopReason
+	b.mu.Unlock()
+	if stopped {
+		b.stopWithNotice(cancel, reason)
+	}
+	now := time.Now().UnixNano()
+	b.lastInput.Store(now)
+	b.lastActivity.Store(now)
 
 	// errgroup は non-nil error を返した場合のみ ctx をキャンセルするため、
 	// 片方が nil で正常終了した場合 (channel_closed 等) はもう片方が読み取りをブロックし続けてしまう。
@@ -72,11 +304,31 @@ func (b *Bridge) Run(ctx context.Context) error {
 	})
 	g.Go(func() error {
 		defer cancel()
-		return b.pumpBrowserToDataChannel(ctx)
+		return b.serveBrowsers(ctx, first)
 	})
+	if b.IdleTimeout > 0 {
+		g.Go(func() error {
+			b.watchIdle(ctx)
+			return nil
+		})
+	}
 
 	err := g.Wait()
 
+	att := b.currentBrowser()
+	if b.isStopped() {
+		// Stop による終了 (終了理由は stopWithNotice で通知済み) は正常終了として扱う。
+		if att != nil {
+			if closeErr := att.conn.Close(websocket.StatusNormalClosure, ""); closeErr != nil {
+				slog.Debug("failed to close browser websocket after stop", "err", closeErr)
+			}
+		}
+		err = nil
+	}
+	if att != nil {
+		b.releaseBrowser(att)
+	}
+
 	b.cleanup()
 
 	if err != nil && !errors.Is(err, context.Canceled) {
@@ -85,6 +337,23 @@ func (b *Bridge) Run(ctx context.Context) error {
 	return nil
 }
 
+// watchIdle はブラウザからの入力が IdleTimeout 以上途絶えたらブリッジを Stop する。ctx の終了で戻る。
+func (b *Bridge) watchIdle(ctx context.Context) {
+	ticker := time.NewTicker(b.IdleTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, b.lastInput.Load())) >= b.IdleTimeout {
+				b.Stop(fmt.Sprintf("session closed after %s of inactivity", b.IdleTimeout))
+				return
+			}
+		}
+	}
+}
+
 // cleanup はブリッジ終了後の後始末を行う。リクエストの ctx がすでにキャンセルされている可能性があるため、
 // 専用の短命 context を使う。
 func (b *Bridge) cleanup() {
@@ -111,7 +380,12 @@ func (b *Bridge) pumpDataChannelToBrowser(ctx context.Context) error {
 		}
 
 		if len(result.Output) > 0 {
-			if err := b.Browser.Write(ctx, websocket.MessageBinary, result.Output); err != nil {
+			b.bytesOut.Add(int64(len(result.Output)))
+			b.lastActivity.Store(time.Now().UnixNano())
+			if b.Recorder != nil {
+				b.Recorder.Output(result.Output)
+			}
+			if err := b.writeOutput(ctx, result.Output); err != nil {
 				return fmt.Errorf("write to browser: %w", err)
 			}
 		}
@@ -123,22 +397,81 @@ func (b *Bridge) pumpDataChannelToBrowser(ctx context.Context) error {
 	}
 }
 
+// serveBrowsers は接続中のブラウザからの入力をデータチャネルへ転送する。Attach で新しいブラウザが接続すると
+// 旧ブラウザを切り離して転送先を切り替え、ReattachGrace が有効な場合はブラウザの異常切断後も猶予期間内の
+// Attach を待つ。ブラウザ側の正常切断 (Drawer やタブを閉じる等) と猶予期間の経過は、ブリッジの正常終了として nil を返す。
+func (b *Bridge) serveBrowsers(ctx context.Context, att *browserAttachment) error {
+	for {
+		readErr := make(chan error, 1)
+		go func() { readErr <- b.pumpBrowserToDataChannel(ctx, att.conn) }()
+
+		var err error
+		select {
+		case err = <-readErr:
+		case next := <-b.attachCh:
+			// 別のブラウザ (リロード後のタブ等) が接続した。旧ブラウザへ通知して切り離す。
+			noticeCtx, cancel := context.WithTimeout(ctx, replacedNoticeTimeout)
+			writeExit(noticeCtx, att.conn, "session attached from another browser")
+			cancel()
+			if closeErr := att.conn.CloseNow(); closeErr != nil {
+				slog.Debug("failed to close replaced browser websocket", "err", closeErr)
+			}
+			<-readErr
+			b.releaseBrowser(att)
+			att = next
+			b.attachBrowser(ctx, att)
+			continue
+		}
+
+		// 接続中のブラウザの後始末 (Close と Attach の待機解除) は Run の終了処理で行う。
+		if ctx.Err() != nil || !errors.Is(err, errBrowserDisconnected) {
+			// ブリッジ自体の終了、またはデータチャネル側のエラー。
+			return err
+		}
+		status := websocket.CloseStatus(err)
+		if status == websocket.StatusNormalClosure || (b.ReattachGrace <= 0 && status == websocket.StatusGoingAway) {
+			return nil
+		}
+		if b.ReattachGrace <= 0 {
+			return err
+		}
+
+		b.releaseBrowser(att)
+		slog.Info("browser disconnected, waiting for reattach", "grace", b.ReattachGrace, "err", err)
+		timer := time.NewTimer(b.ReattachGrace)
+		select {
+		case att = <-b.attachCh:
+			timer.Stop()
+			b.attachBrowser(ctx, att)
+		case <-timer.C:
+			slog.Info("browser did not reattach within grace period, closing session", "grace", b.ReattachGrace)
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
 // pumpBrowserToDataChannel はブラウザからの入力をデータチャネルへ転送する。
 // BINARY メッセージは端末入力バイト列、TEXT メッセージはリサイズ等の JSON 制御として扱う。
-func (b *Bridge) pumpBrowserToDataChannel(ctx context.Context) error {
+// ブラウザ側の読み取りエラーは errBrowserDisconnected でラップして返す。
+func (b *Bridge) pumpBrowserToDataChannel(ctx context.Context, conn *websocket.Conn) error {
 	for {
-		typ, data, err := b.Browser.Read(ctx)
+		typ, data, err := conn.Read(ctx)
 		if err != nil {
-			// ブラウザ側の正常切断 (Drawer やタブを閉じる等) はエラーではなくブリッジの正常終了として扱う。
-			switch websocket.CloseStatus(err) {
-			case websocket.StatusNormalClosure, websocket.StatusGoingAway:
-				return nil
-			}
-			return err
+			return fmt.Errorf("%w: %w", errBrowserDisconnected, err)
 		}
+		now := time.Now().UnixNano()
+		b.lastInput.Store(now)
+		b.lastActivity.Store(now)
 
 		switch typ {
 		case websocket.MessageBinary:
+			b.bytesIn.Add(int64(len(data)))
+			if b.Recorder != nil {
+				b.Recorder.Input(data)
+			}
 			if err := b.DataChannel.SendInput(ctx, PayloadTypeOutput, data); err != nil {
 				return fmt.Errorf("send input to data channel: %w", err)
 			}
@@ -160,6 +493,9 @@ func (b *Bridge) handleBrowserControlMessage(ctx context.Context, data []byte) e
 
 	switch msg.Type {
 	case controlTypeResize:
+		if b.Recorder != nil {
+			b.Recorder.Resize(msg.Cols, msg.Rows)
+		}
 		if err := b.DataChannel.SendSize(ctx, msg.Cols, msg.Rows); err != nil {
 			return fmt.Errorf("send size to data channel: %w", err)
 		}
@@ -169,15 +505,22 @@ func (b *Bridge) handleBrowserControlMessage(ctx context.Context, data []byte) e
 	return nil
 }
 
-// notifyExit はセッション終了をブラウザへ通知する。送信エラーはログに残すのみで処理は継続する
-// (この直後にブリッジ全体が終了するため)。
+// notifyExit はセッション終了を接続中のブラウザへ通知する (切断中は何もしない)。
 func (b *Bridge) notifyExit(ctx context.Context, message string) {
+	if att := b.currentBrowser(); att != nil {
+		writeExit(ctx, att.conn, message)
+	}
+}
+
+// writeExit は exit 制御メッセージを conn へ送る。送信エラーはログに残すのみで処理は継続する
+// (この直後に接続自体を閉じるため)。
+func writeExit(ctx context.Context, conn *websocket.Conn, message string) {
 	payload, err := json.Marshal(controlMessage{Type: controlTypeExit, Message: message})
 	if err != nil {
 		slog.Warn("failed to marshal exit notification", "err", err)
 		return
 	}
-	if err := b.Browser.Write(ctx, websocket.MessageText, payload); err != nil {
+	if err := conn.Write(ctx, websocket.MessageText, payload); err != nil {
 		slog.Warn("failed to notify browser of session exit", "err", err)
 	}
 }
@@ -2,8 +2,14 @@ package session
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -40,6 +46,28 @@ func newTestBrowserPair(ctx context.Context, t *testing.T) (server, client *webs
 	}
 }
 
+// fakeRecorder は Bridge から渡された記録イベントを "<種別>:<データ>" の形で保持する。
+type fakeRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *fakeRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *fakeRecorder) Output(data []byte)       { r.record("o:" + string(data)) }
+func (r *fakeRecorder) Input(data []byte)        { r.record("i:" + string(data)) }
+func (r *fakeRecorder) Resize(cols, rows uint32) { r.record(fmt.Sprintf("r:%dx%d", cols, rows)) }
+
+func (r *fakeRecorder) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
 func TestBridgeRunEndToEnd(t *testing.T) {
 	t.Parallel()
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
@@ -48,7 +76,8 @@ func TestBridgeRunEndToEnd(t *testing.T) {
 	dc, agent := openTestDataChannel(ctx, t)
 	browserServer, browserClient := newTestBrowserPair(ctx, t)
 
-	bridge := &Bridge{DataChannel: dc, Browser: browserServer}
+	recorder := &fakeRecorder{}
+	bridge := &Bridge{DataChannel: dc, Browser: browserServer, Recorder: recorder}
 	runErr := make(chan error, 1)
 	go func() { runErr <- bridge.Run(ctx) }()
 
@@ -100,6 +129,11 @@ func TestBridgeRunEndToEnd(t *testing.T) {
 	case <-ctx.Done():
 		t.Fatal("Run did not finish after browser close")
 	}
+
+	want := []string{"r:120x40", "i:ls\n", "o:file.txt\n"}
+	if got := recorder.snapshot(); !slices.Equal(got, want) {
+		t.Errorf("recorded events = %q, want %q", got, want)
+	}
 }
 
 func TestBridgeRunReturnsNilOnBrowserNormalClose(t *testing.T) {
@@ -129,3 +163,282 @@ func TestBridgeRunReturnsNilOnBrowserNormalClose(t *testing.T) {
 		t.Fatal("Run did not finish after browser close")
 	}
 }
+
+// drainConn は後始末の Close がクローズハンドシェイクを待てるよう、相手側 (agent / ブラウザ) で残りのメッセージを読み捨てる。
+func drainConn(ctx context.Context, conn *websocket.Conn) {
+	go func() {
+		for {
+			if _, _, err := conn.Read(ctx); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// readExitMessage はブラウザ側で exit 制御メッセージが届くまで読み進め、その message を返す。
+func readExitMessage(ctx context.Context, t *testing.T, browser *websocket.Conn) string {
+	t.Helper()
+	for {
+		typ, raw, err := browser.Read(ctx)
+		if err != nil {
+			t.Fatalf("read exit message on browser: %v", err)
+		}
+		if typ != websocket.MessageText {
+			continue
+		}
+		var msg controlMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatalf("unmarshal control message: %v", err)
+		}
+		if msg.Type == controlTypeExit {
+			// ブリッジ側の Close がクローズハンドシェイクを待てるよう、以降のフレームを読み捨てる。
+			drainConn(ctx, browser)
+			return msg.Message
+		}
+	}
+}
+
+func TestBridgeStopNotifiesBrowserAndTerminates(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	drainConn(ctx, agent)
+	browserServer, browserClient := newTestBrowserPair(ctx, t)
+
+	terminated := make(chan struct{})
+	bridge := &Bridge{
+		DataChannel: dc,
+		Browser:     browserServer,
+		Terminate: func(context.Context) error {
+			close(terminated)
+			return nil
+		},
+	}
+	registry := NewRegistry()
+	remove := registry.Add("sess-1", SessionMetadata{Kind: "ec2-session", Profile: "prod", Target: "i-0123"}, bridge)
+	defer remove()
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- bridge.Run(ctx) }()
+
+	if err := browserClient.Write(ctx, websocket.MessageBinary, []byte("ls\n")); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	// 入力が計上されるまで待ってから一覧を確認する。
+	for bridge.Stats().BytesIn == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("input was not counted")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	list := registry.List()
+	if len(list) != 1 || list[0].ID != "sess-1" || list[0].Target != "i-0123" || list[0].BytesIn != 3 {
+		t.Fatalf("List() = %+v, want sess-1 with 3 bytes in", list)
+	}
+
+	if !registry.Stop("sess-1", "terminated by user") {
+		t.Fatal("Stop() = false, want true for registered session")
+	}
+	if registry.Stop("missing", "") {
+		t.Error("Stop() = true, want false for unknown session")
+	}
+	if got := readExitMessage(ctx, t, browserClient); got != "terminated by user" {
+		t.Errorf("exit message = %q, want %q", got, "terminated by user")
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil after Stop", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not finish after Stop")
+	}
+	select {
+	case <-terminated:
+	default:
+		t.Error("Terminate was not called after Stop")
+	}
+
+	remove()
+	if list := registry.List(); len(list) != 0 {
+		t.Errorf("List() after remove = %+v, want empty", list)
+	}
+}
+
+func TestBridgeIdleTimeout(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	drainConn(ctx, agent)
+	browserServer, browserClient := newTestBrowserPair(ctx, t)
+
+	bridge := &Bridge{DataChannel: dc, Browser: browserServer, IdleTimeout: 100 * time.Millisecond}
+	runErr := make(chan error, 1)
+	go func() { runErr <- bridge.Run(ctx) }()
+
+	if got := readExitMessage(ctx, t, browserClient); !strings.Contains(got, "inactivity") {
+		t.Errorf("exit message = %q, want idle timeout notice", got)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil after idle timeout", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not finish after idle timeout")
+	}
+}
+
+// readBinary はブラウザ側で次の BINARY メッセージ (端末出力) を読む。
+func readBinary(ctx context.Context, t *testing.T, browser *websocket.Conn) string {
+	t.Helper()
+	for {
+		typ, raw, err := browser.Read(ctx)
+		if err != nil {
+			t.Fatalf("read output on browser: %v", err)
+		}
+		if typ == websocket.MessageBinary {
+			return string(raw)
+		}
+	}
+}
+
+// waitAttached は Stats().Attached が want になるまで待つ。
+func waitAttached(ctx context.Context, t *testing.T, bridge *Bridge, want bool) {
+	t.Helper()
+	for bridge.Stats().Attached != want {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Stats().Attached did not become %v", want)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestBridgeReattachWithinGrace(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	drainConn(ctx, agent)
+	browserServer, browserClient := newTestBrowserPair(ctx, t)
+
+	bridge := &Bridge{DataChannel: dc, Browser: browserServer, ReattachGrace: 5 * time.Second}
+	runErr := make(chan error, 1)
+	go func() { runErr <- bridge.Run(ctx) }()
+
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeOutput, []byte("one"))
+	if got := readBinary(ctx, t, browserClient); got != "one" {
+		t.Fatalf("output = %q, want %q", got, "one")
+	}
+
+	// ネットワーク断相当の異常切断ではセッションを維持し、切断中の出力を保持する。
+	if err := browserClient.CloseNow(); err != nil {
+		t.Fatalf("close browser: %v", err)
+	}
+	waitAttached(ctx, t, bridge, false)
+	sendOutputStreamData(ctx, t, agent, 1, PayloadTypeOutput, []byte("two"))
+	for bridge.Stats().BytesOut < int64(len("onetwo")) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("output while detached was not relayed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	secondServer, secondClient := newTestBrowserPair(ctx, t)
+	secondDone := make(chan error, 1)
+	go func() { secondDone <- bridge.Attach(ctx, secondServer) }()
+	if got := readBinary(ctx, t, secondClient); got != "two" {
+		t.Fatalf("output after reattach = %q, want held output %q", got, "two")
+	}
+	sendOutputStreamData(ctx, t, agent, 2, PayloadTypeOutput, []byte("three"))
+	if got := readBinary(ctx, t, secondClient); got != "three" {
+		t.Fatalf("output after reattach = %q, want %q", got, "three")
+	}
+
+	// 接続中に別のブラウザが Attach すると、旧ブラウザは exit を受け取って切り離される。
+	thirdServer, thirdClient := newTestBrowserPair(ctx, t)
+	thirdDone := make(chan error, 1)
+	go func() { thirdDone <- bridge.Attach(ctx, thirdServer) }()
+	if got := readExitMessage(ctx, t, secondClient); !strings.Contains(got, "another browser") {
+		t.Errorf("exit message on replaced browser = %q, want attach notice", got)
+	}
+	if err := <-secondDone; err != nil {
+		t.Errorf("Attach() for replaced browser error = %v, want nil", err)
+	}
+	waitAttached(ctx, t, bridge, true)
+
+	if err := thirdClient.Close(websocket.StatusNormalClosure, "done"); err != nil {
+		t.Fatalf("close browser: %v", err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil on normal closure", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not finish after browser close")
+	}
+	if err := <-thirdDone; err != nil {
+		t.Errorf("Attach() error = %v, want nil", err)
+	}
+}
+
+func TestBridgeEndsWhenReattachGraceExpires(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	drainConn(ctx, agent)
+	browserServer, browserClient := newTestBrowserPair(ctx, t)
+
+	terminated := make(chan struct{})
+	bridge := &Bridge{
+		DataChannel:   dc,
+		Browser:       browserServer,
+		ReattachGrace: 100 * time.Millisecond,
+		Terminate: func(context.Context) error {
+			close(terminated)
+			return nil
+		},
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- bridge.Run(ctx) }()
+
+	// Run が読み取りを開始してから異常切断する。
+	time.Sleep(50 * time.Millisecond)
+	if err := browserClient.CloseNow(); err != nil {
+		t.Fatalf("close browser: %v", err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil after grace period", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not finish after grace period")
+	}
+	select {
+	case <-terminated:
+	default:
+		t.Error("Terminate was not called after grace period")
+	}
+
+	lateServer, lateClient := newTestBrowserPair(ctx, t)
+	lateDone := make(chan error, 1)
+	go func() { lateDone <- bridge.Attach(ctx, lateServer) }()
+	if got := readExitMessage(ctx, t, lateClient); got == "" {
+		t.Error("exit message on late browser is empty, want closed notice")
+	}
+	if err := <-lateDone; !errors.Is(err, ErrBridgeClosed) {
+		t.Errorf("Attach() after end error = %v, want ErrBridgeClosed", err)
+	}
+}
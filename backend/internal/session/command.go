@@ -0,0 +1,94 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// commandMarkerPrefix は終了コードを出力するマーカー行の接頭辞。
+const commandMarkerPrefix = "THIEF-EXIT-"
+
+// UnknownExitCode は終了コードを取得できなかった場合 (コマンドの終了前にセッションが閉じられた等) の値。
+const UnknownExitCode = -1
+
+// CommandResult は非対話で実行したコマンドの出力と終了コード。
+type CommandResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Command はデータチャネル上で 1 つのコマンドを非対話で実行し、出力と終了コードを集める。
+//
+// 終了コードについて: 出力ストリームを分離するセッション (separateOutputStream) の agent は
+// PayloadTypeStdErr / PayloadTypeExitCode で標準エラー出力と終了コードを送るが、ECS Exec などの
+// 対話セッションでは PTY に標準出力と標準エラー出力がまとめて流れ、終了コードは送られない。そのため
+// Script はコマンドの後に終了コードをマーカー行として出力し、Run で標準出力から取り除いて使う。
+type Command struct {
+	// Command は実行するシェルコマンド。
+	Command string
+	marker  string
+}
+
+// NewCommand は command を実行する Command を返す。
+func NewCommand(command string) (*Command, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, errors.New("command is required")
+	}
+	marker, err := newMarker(commandMarkerPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Command{Command: command, marker: marker}, nil
+}
+
+// Script はセッションのコマンドとして実行するコマンド (shellCommand で sh -c に渡すシェルスクリプト) を返す。
+// Command 内の exit でも終了コードを出力できるようサブシェルで実行し、出力が改行で終わらない場合に備えて
+// マーカー行の前に改行を 1 つ出力する。
+func (c *Command) Script() string {
+	return shellCommand("(\n" + c.Command + "\n)\n" + fmt.Sprintf(`printf '\n%s:EXIT:%%d\n' "$?"`, c.marker))
+}
+
+// Run はセッションが閉じられるまで出力を読み、標準出力・標準エラー出力・終了コードを返す。
+// PTY が変換した改行 (\r\n) は \n に戻す。
+func (c *Command) Run(ctx context.Context, dc *DataChannel) (*CommandResult, error) {
+	var stdout, stderr bytes.Buffer
+	exitCode := UnknownExitCode
+	for {
+		result, err := dc.Read(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("read from data channel: %w", err)
+		}
+		switch result.PayloadType {
+		case PayloadTypeStdErr:
+			stderr.Write(result.Output)
+		case PayloadTypeExitCode:
+			if code, err := strconv.Atoi(strings.TrimSpace(string(result.Output))); err == nil {
+				exitCode = code
+			}
+		default:
+			stdout.Write(result.Output)
+		}
+		if result.Closed {
+			break
+		}
+	}
+
+	out := strings.ReplaceAll(stdout.String(), "\r\n", "\n")
+	if i := strings.LastIndex(out, "\n"+c.marker+":EXIT:"); i >= 0 {
+		line, _, _ := strings.Cut(out[i+len(c.marker)+len(":EXIT:")+1:], "\n")
+		if code, err := strconv.Atoi(line); err == nil && exitCode == UnknownExitCode {
+			exitCode = code
+		}
+		out = out[:i]
+	}
+	return &CommandResult{
+		Stdout:   out,
+		Stderr:   strings.ReplaceAll(stderr.String(), "\r\n", "\n"),
+		ExitCode: exitCode,
+	}, nil
+}
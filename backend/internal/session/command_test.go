@@ -0,0 +1,98 @@
+package session
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// commandOutput は agent から送る output_stream_data 1 件分。
+type commandOutput struct {
+	payloadType PayloadType
+	data        string
+}
+
+func TestCommandRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		output func(marker string) []commandOutput
+		want   CommandResult
+	}{
+		{
+			name: "exit code from marker line on pty",
+			output: func(marker string) []commandOutput {
+				return []commandOutput{
+					{PayloadTypeOutput, "load average: 0.10\r\nerror: disk"},
+					{PayloadTypeOutput, "\r\n" + marker + ":EXIT:3\r\n"},
+				}
+			},
+			want: CommandResult{Stdout: "load average: 0.10\nerror: disk", ExitCode: 3},
+		},
+		{
+			name: "separate stderr and exit code payloads",
+			output: func(marker string) []commandOutput {
+				return []commandOutput{
+					{PayloadTypeOutput, "ok\n"},
+					{PayloadTypeStdErr, "warning\n"},
+					{PayloadTypeExitCode, "0"},
+				}
+			},
+			want: CommandResult{Stdout: "ok\n", Stderr: "warning\n", ExitCode: 0},
+		},
+		{
+			name: "session closed without exit code",
+			output: func(marker string) []commandOutput {
+				return nil
+			},
+			want: CommandResult{ExitCode: UnknownExitCode},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+
+			dc, agent := openTestDataChannel(ctx, t)
+			c, err := NewCommand("uptime; df -h")
+			if err != nil {
+				t.Fatalf("NewCommand() error = %v", err)
+			}
+			for i, out := range tt.output(c.marker) {
+				sendOutputStreamData(ctx, t, agent, int64(i), out.payloadType, []byte(out.data))
+			}
+			sendChannelClosed(ctx, t, agent, "")
+			drainConn(ctx, agent)
+
+			got, err := c.Run(ctx, dc)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("Run() = %+v, want %+v", *got, tt.want)
+			}
+			_ = dc.Close()
+		})
+	}
+}
+
+// TestCommandScript は Script を手元の sh で実行し、' ・空白・複数行を含むコマンドと exit の終了コードが
+// そのまま伝わることを確かめる。
+func TestCommandScript(t *testing.T) {
+	requireShellTools(t)
+	c, err := NewCommand("echo 'it'\\''s a test'\nexit 3")
+	if err != nil {
+		t.Fatalf("NewCommand() error = %v", err)
+	}
+	out, err := exec.Command("sh", "-c", c.Script()).Output()
+	if err != nil {
+		t.Fatalf("run Script(): %v", err)
+	}
+	if want := "it's a test\n\n" + c.marker + ":EXIT:3\n"; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if script := shellScript(t, c.Script()); !strings.HasPrefix(script, "(\n"+c.Command+"\n)\n") {
+		t.Errorf("script = %q, want the command in a subshell", script)
+	}
+}
@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// consoleReadBufferSize は標準入力から 1 回に読み取るバイト数。raw モードの端末入力は通常 1 キーずつ届く。
+const consoleReadBufferSize = 1024
+
+// TerminalSize はローカル端末の列数・行数を表す。
+type TerminalSize struct {
+	Cols uint32
+	Rows uint32
+}
+
+// Console はデータチャネルとローカル端末 (CLI の標準入出力) の間で双方向にバイト列を中継する。
+// ブラウザ向けの Bridge と同じ DataChannel を使うことで、CLI からも session-manager-plugin なしで
+// 対話セッションを扱えるようにする。端末の raw モード切り替えやリサイズの検知は呼び出し側が行う。
+type Console struct {
+	DataChannel *DataChannel
+	Stdin       io.Reader
+	Stdout      io.Writer
+	// Stderr は PayloadTypeStdErr の出力先 (省略時は Stdout)。
+	Stderr io.Writer
+	// Resize は端末サイズの変更通知 (省略可)。初期サイズも含めて送ること。
+	Resize <-chan TerminalSize
+	// Terminate は終了時に呼び出す (省略可)。SSM セッションのクリーンアップに使う。
+	Terminate TerminateFunc
+}
+
+// Run は中継を開始し、agent 側からセッションが閉じられるか ctx がキャンセルされるまでブロックする。
+// 戻り値は通信そのもののエラーであり、channel_closed や ctx のキャンセルによる終了では nil を返す。
+func (c *Console) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer cancel()
+		return c.pumpDataChannelToStdout(ctx)
+	})
+	if c.Resize != nil {
+		g.Go(func() error {
+			return c.pumpResize(ctx)
+		})
+	}
+
+	// 標準入力の Read は ctx でキャンセルできないため errgroup の外で読み、終了を待たない
+	// (セッション終了後に次のキー入力まで Run が戻らなくなるのを避ける)。
+	// 標準入力の EOF (パイプ入力の終端) ではセッションを閉じず、agent 側の終了を待つ。
+	inputErr := make(chan error, 1)
+	go func() {
+		if err := c.pumpStdinToDataChannel(ctx); err != nil {
+			inputErr <- err
+			cancel()
+		}
+	}()
+
+	err := g.Wait()
+	if err == nil || errors.Is(err, context.Canceled) {
+		select {
+		case err = <-inputErr:
+		default:
+		}
+	}
+
+	c.cleanup()
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("console session: %w", err)
+	}
+	return nil
+}
+
+// pumpDataChannelToStdout はデータチャネルからの出力を標準出力 (StdErr ペイロードは Stderr) へ書き出す。
+func (c *Console) pumpDataChannelToStdout(ctx context.Context) error {
+	for {
+		result, err := c.DataChannel.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if len(result.Output) > 0 {
+			out := c.Stdout
+			if result.PayloadType == PayloadTypeStdErr && c.Stderr != nil {
+				out = c.Stderr
+			}
+			if _, err := out.Write(result.Output); err != nil {
+				return fmt.Errorf("write to terminal: %w", err)
+			}
+		}
+
+		if result.Closed {
+			if result.CloseMessage != "" {
+				if _, err := fmt.Fprintf(c.Stdout, "\r\n%s\r\n", result.CloseMessage); err != nil {
+					slog.Warn("failed to write session close message", "err", err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// pumpStdinToDataChannel は標準入力をデータチャネルへ転送する。EOF では nil を返す。
+func (c *Console) pumpStdinToDataChannel(ctx context.Context) error {
+	buf := make([]byte, consoleReadBufferSize)
+	for {
+		n, err := c.Stdin.Read(buf)
+		if n > 0 {
+			if serr := c.DataChannel.SendInput(ctx, PayloadTypeOutput, buf[:n]); serr != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("send input to data channel: %w", serr)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read from terminal: %w", err)
+		}
+	}
+}
+
+// pumpResize は端末サイズの変更をデータチャネルへ通知する。
+func (c *Console) pumpResize(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case size, ok := <-c.Resize:
+			if !ok {
+				return nil
+			}
+			if err := c.DataChannel.SendSize(ctx, size.Cols, size.Rows); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("send size to data channel: %w", err)
+			}
+		}
+	}
+}
+
+// cleanup は Bridge.cleanup と同様に、データチャネルを閉じてから専用の短命 context で Terminate を呼ぶ。
+func (c *Console) cleanup() {
+	if err := c.DataChannel.Close(); err != nil {
+		slog.Warn("failed to close data channel", "err", err)
+	}
+
+	if c.Terminate == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), terminateTimeout)
+	defer cancel()
+	if err := c.Terminate(ctx); err != nil {
+		slog.Warn("failed to terminate ssm session", "err", err)
+	}
+}
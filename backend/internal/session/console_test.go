@@ -0,0 +1,129 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// lockedBuffer は Console の出力 goroutine とテスト goroutine から安全に読み書きできる bytes.Buffer。
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// sendChannelClosed は agent → client の channel_closed を送信する。
+func sendChannelClosed(ctx context.Context, t *testing.T, agent *websocket.Conn, output string) {
+	t.Helper()
+	msg := &AgentMessage{
+		MessageType:   MessageTypeChannelClosed,
+		SchemaVersion: 1,
+		CreatedDate:   uint64(time.Now().UnixMilli()),
+		Payload:       []byte(`{"Output":"` + output + `"}`),
+	}
+	raw, err := msg.Marshal()
+	if err != nil {
+		t.Fatalf("marshal channel closed: %v", err)
+	}
+	if err := agent.Write(ctx, websocket.MessageBinary, raw); err != nil {
+		t.Fatalf("write channel closed: %v", err)
+	}
+}
+
+func TestConsoleRunEndToEnd(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+
+	// 標準入力は閉じないまま (対話端末相当) にし、Run が入力待ちに関係なく終了できることも検証する。
+	stdinReader, stdinWriter := io.Pipe()
+	t.Cleanup(func() { _ = stdinWriter.Close() })
+	var stdout, stderr lockedBuffer
+	resize := make(chan TerminalSize, 1)
+	resize <- TerminalSize{Cols: 100, Rows: 30}
+	terminated := make(chan struct{})
+
+	console := &Console{
+		DataChannel: dc,
+		Stdin:       stdinReader,
+		Stdout:      &stdout,
+		Stderr:      &stderr,
+		Resize:      resize,
+		Terminate: func(context.Context) error {
+			close(terminated)
+			return nil
+		},
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- console.Run(ctx) }()
+
+	go func() { _, _ = stdinWriter.Write([]byte("ls\n")) }()
+
+	completeHandshake(ctx, t, agent)
+
+	// 初期サイズと入力はそれぞれ別 goroutine から送られるため、順不同で両方が届くことを確認する。
+	got := map[PayloadType]string{}
+	for range 2 {
+		msg := readInputStreamData(ctx, t, agent)
+		got[msg.PayloadType] = string(msg.Payload)
+	}
+	if got[PayloadTypeSize] != `{"cols":100,"rows":30}` {
+		t.Errorf("size payload = %q, want cols 100 rows 30", got[PayloadTypeSize])
+	}
+	if got[PayloadTypeOutput] != "ls\n" {
+		t.Errorf("input payload = %q, want %q", got[PayloadTypeOutput], "ls\n")
+	}
+
+	sendOutputStreamData(ctx, t, agent, 2, PayloadTypeOutput, []byte("file.txt\n"))
+	sendOutputStreamData(ctx, t, agent, 3, PayloadTypeStdErr, []byte("warning\n"))
+	sendChannelClosed(ctx, t, agent, "Exited")
+	// 後始末の Close がクローズハンドシェイクを待てるよう、agent 側で残りのメッセージを読み捨てる。
+	go func() {
+		for {
+			if _, _, err := agent.Read(ctx); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil on channel_closed", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Run did not finish after channel_closed")
+	}
+
+	if out := stdout.String(); !strings.HasPrefix(out, "file.txt\n") || !strings.Contains(out, "Exited") {
+		t.Errorf("stdout = %q, want output followed by close message", out)
+	}
+	if errOut := stderr.String(); errOut != "warning\n" {
+		t.Errorf("stderr = %q, want %q", errOut, "warning\n")
+	}
+	select {
+	case <-terminated:
+	default:
+		t.Error("Terminate was not called after Run returned")
+	}
+}
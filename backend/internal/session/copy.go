@@ -0,0 +1,300 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ファイルコピーの転送形式について: ECS Exec / AWS-StartInteractiveCommand のコマンドは agent 上で
+// PTY 付きで実行されるため、バイナリをそのまま流すと改行変換や制御文字の解釈で壊れる。
+// そこでリモート側のシェルスクリプトで tar アーカイブを base64 の行に変換して送受信し、前後をマーカー行で囲む。
+// PTY の改行は \r\n になるため行末の \r は読み飛ばし、転送後は SHA-256 で内容を検証する。
+// リモートには sh・tar・base64・head と sha256sum (または shasum / openssl) が必要。
+
+const (
+	// copyMarkerPrefix はマーカー行の接頭辞。転送ごとにランダムな値を付け、リモートの通常出力と区別する。
+	copyMarkerPrefix = "THIEF-COPY-"
+	// copyLineBytes はアップロード時に base64 1 行へ含める元データのバイト数 (base64 で 76 文字)。
+	copyLineBytes = 57
+	// copyLinesPerMessage は 1 回の SendInput で送る base64 行数。公式実装のストリームデータ 1 メッセージの
+	// 上限 (1024 バイト) に収まるようにする。
+	copyLinesPerMessage = 13
+	// remoteChecksumFunc は引数のファイルの SHA-256 を 16 進で出力するシェル関数 h を定義する。
+	remoteChecksumFunc = `h() { { sha256sum "$1" || shasum -a 256 "$1" || openssl dgst -sha256 -r "$1"; } 2>/dev/null | cut -d ' ' -f 1; }`
+)
+
+// ErrChecksumMismatch は転送したアーカイブの SHA-256 が送信元と一致しないことを示す。
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Download はリモートのファイル/ディレクトリを tar アーカイブとして取り出す転送を表す。
+// Command をセッションのコマンドとして実行し、開いたデータチャネルで Run を呼ぶ。
+type Download struct {
+	// Path はリモートのコピー元パス。
+	Path   string
+	marker string
+}
+
+// NewDownload は remotePath を取り出す Download を返す。
+func NewDownload(remotePath string) (*Download, error) {
+	if remotePath == "" {
+		return nil, errors.New("remote path is required")
+	}
+	marker, err := newMarker(copyMarkerPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Download{Path: path.Clean(remotePath), marker: marker}, nil
+}
+
+// Command はリモートで実行するコマンド (shellCommand で sh -c に渡すシェルスクリプト) を返す。
+// Path を tar に固めて SHA-256 を出力した後、base64 で出力する。
+func (d *Download) Command() string {
+	return shellCommand(strings.Join([]string{
+		"m=" + d.marker,
+		remoteChecksumFunc,
+		`t=$(mktemp) || exit 1`,
+		`if e=$(tar -C ` + shellQuote(path.Dir(d.Path)) + ` -cf "$t" ` + shellQuote(path.Base(d.Path)) + ` 2>&1); then ` +
+			`echo "$m:SHA256:$(h "$t")"; echo "$m:BEGIN"; base64 "$t"; echo "$m:END"; ` +
+			`else echo "$m:ERROR:$(printf '%s' "$e" | tr '\n' ' ')"; fi`,
+		`rm -f "$t"`,
+	}, "; "))
+}
+
+// Run はリモートの出力から tar アーカイブを復元して w へ書き出し、書き出したバイト数を返す。
+// アーカイブの SHA-256 がリモートで計算した値と一致しない場合は ErrChecksumMismatch を返す。
+// w には検証前のデータが書き込まれるため、エラー時は呼び出し側で破棄すること。
+func (d *Download) Run(ctx context.Context, dc *DataChannel, w io.Writer) (int64, error) {
+	var (
+		want    string
+		inData  bool
+		written int64
+	)
+	hash := sha256.New()
+	out := io.MultiWriter(w, hash)
+	err := readCopyLines(ctx, dc, func(line string) (bool, error) {
+		if !inData {
+			switch {
+			case strings.HasPrefix(line, d.marker+":SHA256:"):
+				want = strings.TrimPrefix(line, d.marker+":SHA256:")
+			case line == d.marker+":BEGIN":
+				inData = true
+			case strings.HasPrefix(line, d.marker+":ERROR:"):
+				return true, fmt.Errorf("archive %s on remote: %s", d.Path, strings.TrimSpace(strings.TrimPrefix(line, d.marker+":ERROR:")))
+			}
+			return false, nil
+		}
+		if line == d.marker+":END" {
+			return true, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return true, fmt.Errorf("decode archive: %w", err)
+		}
+		n, err := out.Write(data)
+		written += int64(n)
+		if err != nil {
+			return true, fmt.Errorf("write archive: %w", err)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	if want == "" {
+		return written, errors.New("remote did not report the archive checksum")
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return written, fmt.Errorf("%w: received %s, remote %s", ErrChecksumMismatch, got, want)
+	}
+	return written, nil
+}
+
+// Upload は tar アーカイブをリモートのディレクトリへ展開する転送を表す。
+// Command をセッションのコマンドとして実行し、開いたデータチャネルで Run を呼ぶ。
+type Upload struct {
+	// Dir はリモートの展開先ディレクトリ。
+	Dir     string
+	archive io.Reader
+	size    int64
+	sum     string
+	marker  string
+}
+
+// NewUpload は archive をリモートの remoteDir に展開する Upload を返す。サイズと SHA-256 をコマンドに
+// 埋め込むため、archive を一度読み切ってから先頭に戻す。
+func NewUpload(remoteDir string, archive io.ReadSeeker) (*Upload, error) {
+	if remoteDir == "" {
+		return nil, errors.New("remote directory is required")
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, archive)
+	if err != nil {
+		return nil, fmt.Errorf("checksum archive: %w", err)
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewind archive: %w", err)
+	}
+	marker, err := newMarker(copyMarkerPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Upload{
+		Dir:     path.Clean(remoteDir),
+		archive: archive,
+		size:    size,
+		sum:     hex.EncodeToString(hash.Sum(nil)),
+		marker:  marker,
+	}, nil
+}
+
+// Command はリモートで実行するコマンド (shellCommand で sh -c に渡すシェルスクリプト) を返す。
+// 端末のエコーを止めて READY を出力した後、base64 の行を決められた行数だけ受け取り、SHA-256 を
+// 検証してから Dir に展開する。
+func (u *Upload) Command() string {
+	lines := (u.size + copyLineBytes - 1) / copyLineBytes
+	return shellCommand(strings.Join([]string{
+		"m=" + u.marker,
+		remoteChecksumFunc,
+		`t=$(mktemp) || exit 1`,
+		`stty -echo 2>/dev/null`,
+		`echo "$m:READY"`,
+		fmt.Sprintf(`head -n %d | base64 -d > "$t"`, lines),
+		`s=$(h "$t")`,
+		`if [ "$s" != ` + u.sum + ` ]; then echo "$m:CHECKSUM:$s"; ` +
+			`elif e=$(tar -C ` + shellQuote(u.Dir) + ` -xf "$t" 2>&1); then echo "$m:OK"; ` +
+			`else echo "$m:ERROR:$(printf '%s' "$e" | tr '\n' ' ')"; fi`,
+		`rm -f "$t"`,
+	}, "; "))
+}
+
+// Run はリモートの READY を待ってアーカイブを base64 の行で送信し、展開の完了を待つ。
+// リモートで計算した SHA-256 が一致しない場合は ErrChecksumMismatch を返す。
+func (u *Upload) Run(ctx context.Context, dc *DataChannel) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// ハンドシェイク応答や acknowledge はデータチャネルの読み取りで処理されるため、送信中も読み続ける。
+	ready := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		readyClosed := false
+		result <- readCopyLines(ctx, dc, func(line string) (bool, error) {
+			switch {
+			case line == u.marker+":READY":
+				if !readyClosed {
+					close(ready)
+					readyClosed = true
+				}
+			case line == u.marker+":OK":
+				return true, nil
+			case strings.HasPrefix(line, u.marker+":CHECKSUM:"):
+				return true, fmt.Errorf("%w: remote %s, sent %s", ErrChecksumMismatch, strings.TrimPrefix(line, u.marker+":CHECKSUM:"), u.sum)
+			case strings.HasPrefix(line, u.marker+":ERROR:"):
+				return true, fmt.Errorf("extract archive into %s on remote: %s", u.Dir, strings.TrimSpace(strings.TrimPrefix(line, u.marker+":ERROR:")))
+			}
+			return false, nil
+		})
+	}()
+
+	select {
+	case <-ready:
+	case err := <-result:
+		if err == nil {
+			err = errors.New("remote finished before accepting the upload")
+		}
+		return err
+	}
+
+	if err := u.send(ctx, dc); err != nil {
+		// セッションが先に閉じられた場合は、送信エラーよりリモート側の結果を優先する。
+		select {
+		case rerr := <-result:
+			if rerr != nil {
+				return rerr
+			}
+		default:
+		}
+		return err
+	}
+	return <-result
+}
+
+// send はアーカイブを copyLineBytes ごとに base64 の行へ変換し、copyLinesPerMessage 行ずつ送信する。
+func (u *Upload) send(ctx context.Context, dc *DataChannel) error {
+	block := make([]byte, copyLineBytes)
+	for {
+		payload := make([]byte, 0, copyLinesPerMessage*(base64.StdEncoding.EncodedLen(copyLineBytes)+1))
+		eof := false
+		for range copyLinesPerMessage {
+			n, err := io.ReadFull(u.archive, block)
+			if n > 0 {
+				payload = base64.StdEncoding.AppendEncode(payload, block[:n])
+				payload = append(payload, '\n')
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				eof = true
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read archive: %w", err)
+			}
+		}
+		if len(payload) > 0 {
+			if err := dc.SendInput(ctx, PayloadTypeOutput, payload); err != nil {
+				return fmt.Errorf("send archive: %w", err)
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// readCopyLines はデータチャネルの出力を行単位 (行末の \r を除く) で handle に渡す。handle が true か
+// エラーを返すまで読み続け、その前にセッションが閉じられた場合はエラーを返す。
+func readCopyLines(ctx context.Context, dc *DataChannel, handle func(line string) (done bool, err error)) error {
+	var buf []byte
+	for {
+		result, err := dc.Read(ctx)
+		if err != nil {
+			return fmt.Errorf("read from data channel: %w", err)
+		}
+		buf = append(buf, result.Output...)
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			line := strings.TrimRight(string(buf[:i]), "\r")
+			buf = buf[i+1:]
+			if done, err := handle(line); done || err != nil {
+				return err
+			}
+		}
+		if result.Closed {
+			if result.CloseMessage != "" {
+				return fmt.Errorf("session closed before the transfer completed: %s", result.CloseMessage)
+			}
+			return errors.New("session closed before the transfer completed")
+		}
+	}
+}
+
+// newMarker は prefix にランダムな値を付けた、転送・実行ごとに一意なマーカーを生成する。
+func newMarker(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate marker: %w", err)
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,213 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// wrapBase64 は data を base64 コマンドと同じ 76 文字ごとの行 (PTY 経由の \r\n 区切り) に変換する。
+func wrapBase64(data []byte) string {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc + "\r\n")
+	return b.String()
+}
+
+func TestDownloadRun(t *testing.T) {
+	var archive bytes.Buffer
+	if err := WriteArchiveFile(&archive, "heap.hprof", strings.NewReader(strings.Repeat("heap", 100)), 400); err != nil {
+		t.Fatalf("WriteArchiveFile() error = %v", err)
+	}
+	sum := sha256.Sum256(archive.Bytes())
+
+	tests := []struct {
+		name    string
+		sum     string
+		wantErr error
+	}{
+		{name: "checksum matches", sum: hex.EncodeToString(sum[:])},
+		{name: "checksum mismatch", sum: strings.Repeat("0", 64), wantErr: ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+
+			dc, agent := openTestDataChannel(ctx, t)
+			d, err := NewDownload("/tmp/heap.hprof/")
+			if err != nil {
+				t.Fatalf("NewDownload() error = %v", err)
+			}
+			if script := shellScript(t, d.Command()); !strings.Contains(script, `tar -C '/tmp' -cf "$t" 'heap.hprof'`) {
+				t.Errorf("Command() script = %q, want tar of /tmp/heap.hprof", script)
+			}
+
+			var got bytes.Buffer
+			runErr := make(chan error, 1)
+			go func() {
+				_, err := d.Run(ctx, dc, &got)
+				runErr <- err
+			}()
+
+			// リモートの通常出力を挟み、行の途中で分割されたメッセージも復元できることを確認する。
+			out := "motd\r\n" + d.marker + ":SHA256:" + tt.sum + "\r\n" + d.marker + ":BEGIN\r\n" + wrapBase64(archive.Bytes()) + d.marker + ":END\r\n"
+			half := len(out) / 2
+			sendOutputStreamData(ctx, t, agent, 0, PayloadTypeOutput, []byte(out[:half]))
+			sendOutputStreamData(ctx, t, agent, 1, PayloadTypeOutput, []byte(out[half:]))
+			drainConn(ctx, agent)
+
+			err = <-runErr
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got.Bytes(), archive.Bytes()) {
+				t.Errorf("downloaded %d bytes, want the %d byte archive", got.Len(), archive.Len())
+			}
+		})
+	}
+}
+
+func TestDownloadRunRemoteError(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	d, err := NewDownload("/missing")
+	if err != nil {
+		t.Fatalf("NewDownload() error = %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeOutput, []byte(d.marker+":ERROR:tar: missing: No such file or directory \r\n"))
+	drainConn(ctx, agent)
+
+	_, err = d.Run(ctx, dc, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "No such file or directory") {
+		t.Fatalf("Run() error = %v, want remote tar error", err)
+	}
+	// agent 側が読み捨てている間に閉じ、後始末でクローズハンドシェイクを待たないようにする。
+	_ = dc.Close()
+}
+
+func TestUploadRun(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+
+	var archive bytes.Buffer
+	if err := WriteArchiveFile(&archive, "app.conf", strings.NewReader(strings.Repeat("key=value\n", 300)), 3000); err != nil {
+		t.Fatalf("WriteArchiveFile() error = %v", err)
+	}
+	want := archive.Bytes()
+	u, err := NewUpload("/etc/app", bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	lines := (len(want) + copyLineBytes - 1) / copyLineBytes
+	if script := shellScript(t, u.Command()); !strings.Contains(script, "head -n "+strconv.Itoa(lines)+" ") || !strings.Contains(script, `tar -C '/etc/app' -xf`) {
+		t.Errorf("Command() script = %q, want %d lines extracted into /etc/app", script, lines)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- u.Run(ctx, dc) }()
+
+	completeHandshake(ctx, t, agent)
+	sendOutputStreamData(ctx, t, agent, 2, PayloadTypeOutput, []byte(u.marker+":READY\r\n"))
+
+	var received strings.Builder
+	for strings.Count(received.String(), "\n") < lines {
+		msg := readInputStreamData(ctx, t, agent)
+		if len(msg.Payload) > 1024 {
+			t.Errorf("input payload = %d bytes, want at most 1024", len(msg.Payload))
+		}
+		received.Write(msg.Payload)
+	}
+	got, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(received.String(), "\n", ""))
+	if err != nil {
+		t.Fatalf("decode uploaded lines: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("uploaded %d bytes, want the %d byte archive", len(got), len(want))
+	}
+
+	sendOutputStreamData(ctx, t, agent, 3, PayloadTypeOutput, []byte(u.marker+":OK\r\n"))
+	drainConn(ctx, agent)
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
+
+func TestUploadRunSessionClosedBeforeReady(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	u, err := NewUpload("/tmp", bytes.NewReader([]byte("archive")))
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	sendChannelClosed(ctx, t, agent, "sh: tar: not found")
+	drainConn(ctx, agent)
+
+	if err := u.Run(ctx, dc); err == nil || !strings.Contains(err.Error(), "tar: not found") {
+		t.Fatalf("Run() error = %v, want session closed error with the close message", err)
+	}
+	_ = dc.Close()
+}
+
+// TestCopyCommandsWithQuotedPaths はコピーのコマンドを手元の sh で実行し、' と空白を含むパスでも
+// アーカイブの取り出しと展開ができることを確かめる。
+func TestCopyCommandsWithQuotedPaths(t *testing.T) {
+	requireShellTools(t, "tar", "base64", "mktemp")
+	dir := filepath.Join(t.TempDir(), "it's a dir")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "heap dump.hprof"), []byte("heap"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDownload(filepath.Join(dir, "heap dump.hprof"))
+	if err != nil {
+		t.Fatalf("NewDownload() error = %v", err)
+	}
+	out, err := exec.Command("sh", "-c", d.Command()).CombinedOutput()
+	if err != nil || !strings.Contains(string(out), d.marker+":BEGIN") || strings.Contains(string(out), d.marker+":ERROR:") {
+		t.Fatalf("download command = %v, output %q, want the archive", err, out)
+	}
+
+	var archive bytes.Buffer
+	if err := WriteArchiveFile(&archive, "app's conf.txt", strings.NewReader("key=value\n"), 10); err != nil {
+		t.Fatalf("WriteArchiveFile() error = %v", err)
+	}
+	u, err := NewUpload(dir, bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	upload := exec.Command("sh", "-c", u.Command())
+	upload.Stdin = strings.NewReader(strings.ReplaceAll(wrapBase64(archive.Bytes()), "\r\n", "\n"))
+	out, err = upload.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), u.marker+":OK") {
+		t.Fatalf("upload command = %v, output %q, want OK", err, out)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "app's conf.txt")); err != nil || string(got) != "key=value\n" {
+		t.Errorf("extracted file = %q, %v, want key=value", got, err)
+	}
+}
@@ -2,10 +2,14 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/google/uuid"
@@ -14,20 +18,36 @@ import (
 // dataChannelReadLimit は 1 メッセージあたりの読み取り上限バイト数。ターミナル出力想定でこの上限を超えることはない。
 const dataChannelReadLimit = 1 << 20 // 1MiB
 
+// 再接続 (ResumeSession) の再試行パラメータ。reconnectTimeout を過ぎても再接続できない場合は Read がエラーを返す。
+const (
+	reconnectTimeout      = 2 * time.Minute
+	reconnectInitialDelay = time.Second
+	reconnectMaxDelay     = 10 * time.Second
+)
+
+// ResumeFunc は切断されたセッションを再開 (SSM ResumeSession) し、データチャネルの新しい StreamUrl とトークンを返す。
+type ResumeFunc func(ctx context.Context) (streamURL, tokenValue string, err error)
+
 // DataChannel は SSM Session Manager / ECS Exec のデータチャネル (agent 側 WebSocket) との接続を表す。
 //
 // シーケンス番号の管理について: sendSequenceNumber は「ブラウザ→データチャネル」方向の goroutine (キー入力・リサイズ) と
 // 「データチャネル→ブラウザ」方向の goroutine (ハンドシェイク応答) の両方から更新されるため、
 // 採番と送信を 1 クリティカルセクションで行う sendMu で保護する (採番順と送信順の一致を保証する)。
-// expectedSequenceNumber は「データチャネル→ブラウザ」方向の goroutine のみが更新するため mutex 不要。
-// また、WebSocket は単一 TCP 接続上の順序保証があり、本実装では再接続・再送を行わないため、
-// AWS 公式実装が持つ IncomingMessageBuffer によるアウトオブオーダー処理は実装しない。
+// expectedSequenceNumber と incoming は「データチャネル→ブラウザ」方向の goroutine のみが更新するため mutex 不要。
+//
+// 順序制御と再接続について: AWS 公式実装の IncomingMessageBuffer と同様、期待より先のシーケンス番号で届いた
+// メッセージは incoming に保持し、欠番が届いた時点で順に処理する (処理済みの番号で再送されたものは acknowledge のみ返す)。
+// EnableReconnect を呼ぶと、公式実装の OutgoingMessageBuffer と同様に送信メッセージを acknowledge まで保持して
+// RTO 経過後に再送し、WebSocket が異常切断された場合は ResumeSession で取得したトークンで再接続して
+// 未 acknowledge のメッセージを送り直す。再接続中の送信はエラーにせず、再接続後の再送に任せる。
 //
 // 入力のゲートについて: AWS 公式 session-manager-plugin と同様、ハンドシェイク完了前にキー入力や
 // リサイズを送信すると agent 側の入力ストリーム処理が乱れるため、SendInput / SendSize は
 // handshakeDone が close されるまでブロックする。ハンドシェイク非対応の agent 向けに、
 // 通常出力の初回受信でも handshakeDone を close する。
 type DataChannel struct {
+	// conn は再接続で差し替わるため connMu で保護し、currentConn で取得する。
+	connMu    sync.Mutex
 	conn      *websocket.Conn
 	clientID  string
 	sessionID string
@@ -35,28 +55,74 @@ type DataChannel struct {
 	sendMu                 sync.Mutex
 	sendSequenceNumber     int64
 	expectedSequenceNumber int64
+	// incoming は期待より先のシーケンス番号で届いた output_stream_data を番号ごとに保持する。
+	incoming map[int64]*AgentMessage
+
+	// resume / outgoing は EnableReconnect で設定する。resume が nil の場合は再接続・再送を行わない。
+	resume   ResumeFunc
+	outgoing *outgoingBuffer
+	// closed は Close で close され、再接続と再送 goroutine を止める。
+	closed    chan struct{}
+	closeOnce sync.Once
+	// fatalErr は再送回数の上限超過など、再接続では回復できないエラー (connMu で保護)。
+	fatalErr error
+
+	// sessionTypes は SessionType ハンドシェイクアクションで許容するセッション種別。
+	sessionTypes map[string]bool
+	// agentVersion は HandshakeRequest で通知された agent のバージョン。
+	// 読み取り goroutine が handshakeDone の close 前に書き込むため、waitHandshake 後であれば mutex なしで読める。
+	agentVersion string
+
+	// targetID / generateDataKey は KMSEncryption ハンドシェイクアクションに使う (EnableKMSEncryption で設定)。
+	// generateDataKey が nil の場合、KMSEncryption は Unsupported として拒否する。
+	targetID        string
+	generateDataKey DataKeyGenerator
+	// encrypter は KMSEncryption が成功した場合に設定される。agentVersion と同様、読み取り goroutine が
+	// handshakeDone の close 前に書き込むため、waitHandshake 後であれば mutex なしで読める。
+	encrypter *encrypter
 
 	handshakeOnce sync.Once
 	handshakeDone chan struct{}
 }
 
 // OpenDataChannel は StreamUrl に WebSocket 接続し、OpenDataChannelInput をテキストメッセージとして
-// 送信することでハンドシェイクを完了する。
+// 送信することでハンドシェイクを完了する。シェル系 (Standard_Stream 等) のセッション用。
 func OpenDataChannel(ctx context.Context, streamURL, tokenValue, sessionID string) (*DataChannel, error) {
-	conn, _, err := websocket.Dial(ctx, streamURL, nil)
+	return openDataChannel(ctx, streamURL, tokenValue, sessionID, shellSessionTypes)
+}
+
+// OpenPortDataChannel はポートフォワーディングセッション (SessionType: Port) 用のデータチャネルを開く。
+func OpenPortDataChannel(ctx context.Context, streamURL, tokenValue, sessionID string) (*DataChannel, error) {
+	return openDataChannel(ctx, streamURL, tokenValue, sessionID, portSessionTypes)
+}
+
+func openDataChannel(ctx context.Context, streamURL, tokenValue, sessionID string, sessionTypes map[string]bool) (*DataChannel, error) {
+	clientID := uuid.NewString()
+	conn, err := dialDataChannel(ctx, streamURL, tokenValue, clientID)
 	if err != nil {
-		return nil, fmt.Errorf("dial data channel websocket: %w", err)
+		return nil, err
 	}
-	conn.SetReadLimit(dataChannelReadLimit)
-
-	dc := &DataChannel{
+	return &DataChannel{
 		conn:          conn,
-		clientID:      uuid.NewString(),
+		clientID:      clientID,
 		sessionID:     sessionID,
+		sessionTypes:  sessionTypes,
+		incoming:      map[int64]*AgentMessage{},
+		closed:        make(chan struct{}),
 		handshakeDone: make(chan struct{}),
+	}, nil
+}
+
+// dialDataChannel は StreamUrl に WebSocket 接続し、OpenDataChannelInput を送信する。
+// 再接続時も同じ clientID で新しいトークンを送る。
+func dialDataChannel(ctx context.Context, streamURL, tokenValue, clientID string) (*websocket.Conn, error) {
+	conn, _, err := websocket.Dial(ctx, streamURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial data channel websocket: %w", err)
 	}
+	conn.SetReadLimit(dataChannelReadLimit)
 
-	input := NewOpenDataChannelInput(dc.clientID, tokenValue)
+	input := NewOpenDataChannelInput(clientID, tokenValue)
 	payload, err := json.Marshal(input)
 	if err != nil {
 		// 呼び出し元へ返すエラーが本質のため、close 失敗はログのみに留める。
@@ -73,21 +139,182 @@ func OpenDataChannel(ctx context.Context, streamURL, tokenValue, sessionID strin
 		}
 		return nil, fmt.Errorf("send open data channel input: %w", err)
 	}
+	return conn, nil
+}
+
+// EnableKMSEncryption は KMSEncryption ハンドシェイクアクション (Session Manager の KMS 暗号化設定) に
+// 応答できるようにする。targetID は KMS の暗号化コンテキストに含める接続先 (インスタンス ID や ECS の
+// ecs:<cluster>_<task>_<runtime-id>)。読み取り goroutine と競合しないよう、Read を開始する前に呼ぶこと。
+func (dc *DataChannel) EnableKMSEncryption(targetID string, generate DataKeyGenerator) {
+	dc.targetID = targetID
+	dc.generateDataKey = generate
+}
 
-	return dc, nil
+// EnableReconnect は送信メッセージの再送と、WebSocket 切断時の再接続を有効にする。resume は切断のたびに呼ばれ、
+// 再接続用の StreamUrl とトークンを返す (SSM ResumeSession)。読み取り goroutine と競合しないよう、
+// Read を開始する前に呼ぶこと。再送 goroutine は Close まで動作する。
+func (dc *DataChannel) EnableReconnect(resume ResumeFunc) {
+	dc.resume = resume
+	dc.outgoing = newOutgoingBuffer()
+	go dc.resendLoop()
 }
 
-// Close はデータチャネルの WebSocket 接続を閉じる。
+// Close はデータチャネルの WebSocket 接続を閉じる。以降は再接続を行わない。
 func (dc *DataChannel) Close() error {
-	return dc.conn.Close(websocket.StatusNormalClosure, "session closed")
+	dc.closeOnce.Do(func() { close(dc.closed) })
+	return dc.currentConn().Close(websocket.StatusNormalClosure, "session closed")
+}
+
+// currentConn は現在の WebSocket 接続を返す。
+func (dc *DataChannel) currentConn() *websocket.Conn {
+	dc.connMu.Lock()
+	defer dc.connMu.Unlock()
+	return dc.conn
+}
+
+// isClosed は Close が呼ばれたかどうかを返す。
+func (dc *DataChannel) isClosed() bool {
+	select {
+	case <-dc.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// canReconnect は接続エラーを再接続で回復させるべきかを返す。ctx の終了・Close 後・回復不能なエラーの発生後・
+// 相手側の正常切断では再接続しない。
+func (dc *DataChannel) canReconnect(ctx context.Context, err error) bool {
+	if dc.resume == nil || ctx.Err() != nil || dc.isClosed() || dc.fatal() != nil {
+		return false
+	}
+	return websocket.CloseStatus(err) != websocket.StatusNormalClosure
+}
+
+// fatal は回復不能なエラーを返す (発生していなければ nil)。
+func (dc *DataChannel) fatal() error {
+	dc.connMu.Lock()
+	defer dc.connMu.Unlock()
+	return dc.fatalErr
+}
+
+// fail は回復不能なエラーを記録し、接続を閉じて Read を終了させる。
+func (dc *DataChannel) fail(err error) {
+	dc.connMu.Lock()
+	if dc.fatalErr == nil {
+		dc.fatalErr = err
+	}
+	conn := dc.conn
+	dc.connMu.Unlock()
+	if closeErr := conn.CloseNow(); closeErr != nil {
+		slog.Debug("failed to close data channel websocket", "err", closeErr)
+	}
+}
+
+// reconnect は ResumeSession で取得したトークンでデータチャネルへ再接続する。reconnectTimeout に達するまで
+// 間隔を広げながら再試行する。
+func (dc *DataChannel) reconnect(ctx context.Context, cause error) error {
+	slog.Warn("data channel disconnected, resuming session", "session_id", dc.sessionID, "err", cause)
+	ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+
+	delay := reconnectInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := dc.resumeOnce(ctx)
+		if err == nil {
+			slog.Info("data channel reconnected", "session_id", dc.sessionID, "attempt", attempt)
+			return nil
+		}
+		slog.Warn("failed to resume session", "session_id", dc.sessionID, "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("resume session %s: %w", dc.sessionID, err)
+		case <-dc.closed:
+			return fmt.Errorf("resume session %s: data channel closed", dc.sessionID)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// resumeOnce は 1 回分の再接続を行い、接続を差し替えた上で未 acknowledge のメッセージを送り直す。
+func (dc *DataChannel) resumeOnce(ctx context.Context) error {
+	streamURL, tokenValue, err := dc.resume(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := dialDataChannel(ctx, streamURL, tokenValue, dc.clientID)
+	if err != nil {
+		return err
+	}
+
+	// 送り直しの途中に新しい入力が割り込まないよう、送信と同じ sendMu の下で接続を差し替える。
+	dc.sendMu.Lock()
+	defer dc.sendMu.Unlock()
+	dc.connMu.Lock()
+	if dc.isClosed() {
+		dc.connMu.Unlock()
+		if closeErr := conn.CloseNow(); closeErr != nil {
+			slog.Debug("failed to close resumed data channel websocket", "err", closeErr)
+		}
+		return errors.New("data channel closed")
+	}
+	old := dc.conn
+	dc.conn = conn
+	dc.connMu.Unlock()
+	if closeErr := old.CloseNow(); closeErr != nil {
+		slog.Debug("failed to close previous data channel websocket", "err", closeErr)
+	}
+
+	for _, raw := range dc.outgoing.pending(time.Now()) {
+		if err := conn.Write(ctx, websocket.MessageBinary, raw); err != nil {
+			return fmt.Errorf("resend data channel message: %w", err)
+		}
+	}
+	return nil
+}
+
+// resendLoop は acknowledge されないまま RTO を過ぎたメッセージを再送する。Close で終了する。
+func (dc *DataChannel) resendLoop() {
+	ticker := time.NewTicker(resendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-dc.closed:
+			return
+		case <-ticker.C:
+		}
+		raws, err := dc.outgoing.due(time.Now())
+		if err != nil {
+			slog.Error("giving up resending data channel message", "session_id", dc.sessionID, "err", err)
+			dc.fail(err)
+			return
+		}
+		for _, raw := range raws {
+			ctx, cancel := context.WithTimeout(context.Background(), maxRetransmissionTimeout)
+			// 送信失敗 (切断中) は読み取り側の再接続と次回の再送に任せる。
+			if err := dc.currentConn().Write(ctx, websocket.MessageBinary, raw); err != nil {
+				slog.Debug("failed to resend data channel message", "err", err)
+			}
+			cancel()
+		}
+	}
 }
 
 // SendInput は端末入力バイト列を input_stream_data メッセージとして送信する。
-// ハンドシェイク完了までブロックする (ctx キャンセルで解除される)。
+// ハンドシェイク完了までブロックする (ctx キャンセルで解除される)。KMS 暗号化が有効な場合、
+// ストリームデータ (PayloadTypeOutput) は暗号化して送信する。
 func (dc *DataChannel) SendInput(ctx context.Context, payloadType PayloadType, payload []byte) error {
 	if err := dc.waitHandshake(ctx); err != nil {
 		return err
 	}
+	if dc.encrypter != nil && payloadType == PayloadTypeOutput {
+		encrypted, err := dc.encrypter.encrypt(payload)
+		if err != nil {
+			return err
+		}
+		payload = encrypted
+	}
 	return dc.sendInputStreamData(ctx, payloadType, payload)
 }
 
@@ -104,6 +331,26 @@ func (dc *DataChannel) SendSize(ctx context.Context, cols, rows uint32) error {
 	return dc.sendInputStreamData(ctx, PayloadTypeSize, payload)
 }
 
+// SendFlag は PayloadTypeFlag の input_stream_data メッセージ (ポートフォワーディングの制御通知) を送信する。
+// ハンドシェイク完了までブロックする (ctx キャンセルで解除される)。
+func (dc *DataChannel) SendFlag(ctx context.Context, flag PayloadFlag) error {
+	if err := dc.waitHandshake(ctx); err != nil {
+		return err
+	}
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(flag))
+	return dc.sendInputStreamData(ctx, PayloadTypeFlag, payload)
+}
+
+// AgentVersion はハンドシェイク完了まで待機し、HandshakeRequest で通知された agent のバージョンを返す。
+// ハンドシェイク非対応の agent の場合は空文字列を返す。
+func (dc *DataChannel) AgentVersion(ctx context.Context) (string, error) {
+	if err := dc.waitHandshake(ctx); err != nil {
+		return "", err
+	}
+	return dc.agentVersion, nil
+}
+
 // waitHandshake はハンドシェイク完了 (またはハンドシェイク非対応 agent の初回出力受信) まで待機する。
 func (dc *DataChannel) waitHandshake(ctx context.Context) error {
 	select {
@@ -121,11 +368,22 @@ func (dc *DataChannel) markHandshakeDone() {
 
 // sendInputStreamData は input_stream_data メッセージに送信シーケンス番号を採番して送信する。
 // 採番順と実際の送信順を一致させるため、メッセージ生成から送信完了までを sendMu で保護する。
+// 再接続が有効な場合は acknowledge まで送信バッファに保持し、送信失敗 (切断中) は再接続後の再送に任せる。
 func (dc *DataChannel) sendInputStreamData(ctx context.Context, payloadType PayloadType, payload []byte) error {
 	dc.sendMu.Lock()
 	defer dc.sendMu.Unlock()
 	msg := NewInputStreamDataMessage(dc.sendSequenceNumber, payloadType, payload)
-	if err := dc.send(ctx, msg); err != nil {
+	raw, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal agent message: %w", err)
+	}
+	if dc.outgoing != nil {
+		// 送信バッファに入れた時点で採番を確定させる (書き込みに失敗しても再送で届ける)。
+		dc.outgoing.add(msg.SequenceNumber, raw, time.Now())
+		dc.sendSequenceNumber++
+		return dc.write(ctx, raw)
+	}
+	if err := dc.write(ctx, raw); err != nil {
 		return err
 	}
 	dc.sendSequenceNumber++
@@ -136,6 +394,9 @@ func (dc *DataChannel) sendInputStreamData(ctx context.Context, payloadType Payl
 type ReadResult struct {
 	// Output は端末に書き出すべきバイト列 (PayloadTypeOutput/StdErr 等)。空の場合は書き出し不要。
 	Output []byte
+	// PayloadType は Output の種別。ポートフォワーディングではストリームデータ (PayloadTypeOutput) と
+	// 制御通知 (PayloadTypeFlag) を区別するために使う。
+	PayloadType PayloadType
 	// Closed は agent 側からセッション終了 (channel_closed) が通知されたことを示す。
 	Closed bool
 	// CloseMessage は Closed が true の場合の終了メッセージ (空の場合もある)。
@@ -144,10 +405,27 @@ type ReadResult struct {
 
 // Read はデータチャネルから 1 メッセージ受信し、ハンドシェイク応答/acknowledge 送信などのプロトコル処理を行った上で、
 // 端末に書き出すべき出力を返す。
+//
+// 再接続が有効な場合、WebSocket の切断を検知すると Read の中で再接続し、空の ReadResult を返す。
 func (dc *DataChannel) Read(ctx context.Context) (ReadResult, error) {
-	typ, raw, err := dc.conn.Read(ctx)
+	// 先行して届いていたメッセージの欠番が埋まっていれば、接続から読む前に処理する。
+	if msg, ok := dc.incoming[dc.expectedSequenceNumber]; ok {
+		delete(dc.incoming, dc.expectedSequenceNumber)
+		return dc.processOutputStreamData(ctx, msg)
+	}
+
+	typ, raw, err := dc.currentConn().Read(ctx)
 	if err != nil {
-		return ReadResult{}, fmt.Errorf("read data channel message: %w", err)
+		if fatal := dc.fatal(); fatal != nil {
+			return ReadResult{}, fmt.Errorf("read data channel message: %w", fatal)
+		}
+		if !dc.canReconnect(ctx, err) {
+			return ReadResult{}, fmt.Errorf("read data channel message: %w", err)
+		}
+		if err := dc.reconnect(ctx, err); err != nil {
+			return ReadResult{}, err
+		}
+		return ReadResult{}, nil
 	}
 	if typ != websocket.MessageBinary {
 		// agent からの通常メッセージは常に BINARY。TEXT が来るのは想定外だが、致命的ではないので無視する。
@@ -164,8 +442,14 @@ func (dc *DataChannel) Read(ctx context.Context) (ReadResult, error) {
 	case MessageTypeOutputStreamData:
 		return dc.handleOutputStreamData(ctx, &msg)
 	case MessageTypeAcknowledge:
-		// 再送を行わないためバッファ管理は不要。ログのみ残す。
-		slog.Debug("received acknowledge message", "sequence_number", msg.SequenceNumber)
+		if dc.outgoing != nil {
+			var ack AcknowledgeContent
+			if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+				slog.Warn("failed to unmarshal acknowledge payload", "err", err)
+				return ReadResult{}, nil
+			}
+			dc.outgoing.ack(ack.SequenceNumber, time.Now())
+		}
 		return ReadResult{}, nil
 	case MessageTypeChannelClosed:
 		var closed struct {
@@ -183,41 +467,59 @@ func (dc *DataChannel) Read(ctx context.Context) (ReadResult, error) {
 	}
 }
 
-// handleOutputStreamData は output_stream_data メッセージを処理する。
-// ハンドシェイク系ペイロード (HandshakeRequest/HandshakeComplete) はここで応答し、
-// 実際の端末出力 (Output/StdErr 等) のみを呼び出し元に返す。
+// handleOutputStreamData は output_stream_data メッセージに acknowledge を返し、シーケンス番号順に処理する。
+// 期待より先の番号のメッセージは incoming に保持して後続の Read で処理し、処理済みの番号 (再送) は読み捨てる。
 func (dc *DataChannel) handleOutputStreamData(ctx context.Context, msg *AgentMessage) (ReadResult, error) {
-	if msg.SequenceNumber != dc.expectedSequenceNumber {
-		// WebSocket は単一 TCP 接続で順序保証されるため、通常は発生しない。
-		// 再送・再接続は実装していないため、ここでは処理をスキップして次のメッセージを待つ。
-		slog.Warn("unexpected sequence number, skipping message",
-			"got", msg.SequenceNumber, "want", dc.expectedSequenceNumber)
-		return ReadResult{}, nil
+	switch {
+	case msg.SequenceNumber < dc.expectedSequenceNumber:
+		// 再接続前後に agent が再送した処理済みメッセージ。agent が再送を止められるよう acknowledge のみ返す。
+		slog.Debug("received duplicate message", "sequence_number", msg.SequenceNumber)
+		return ReadResult{}, dc.sendAcknowledge(ctx, msg)
+	case msg.SequenceNumber > dc.expectedSequenceNumber:
+		if len(dc.incoming) >= incomingBufferCapacity {
+			// acknowledge しなければ agent が再送するため、破棄しても欠落はしない。
+			slog.Warn("incoming message buffer is full, dropping message", "sequence_number", msg.SequenceNumber)
+			return ReadResult{}, nil
+		}
+		dc.incoming[msg.SequenceNumber] = msg
+		return ReadResult{}, dc.sendAcknowledge(ctx, msg)
+	}
+	if err := dc.sendAcknowledge(ctx, msg); err != nil {
+		return ReadResult{}, err
 	}
+	return dc.processOutputStreamData(ctx, msg)
+}
 
+// processOutputStreamData は期待どおりのシーケンス番号の output_stream_data を処理する。
+// ハンドシェイク系ペイロード (HandshakeRequest/HandshakeComplete) はここで応答し、
+// 実際の端末出力 (Output/StdErr 等) のみを呼び出し元に返す。
+func (dc *DataChannel) processOutputStreamData(ctx context.Context, msg *AgentMessage) (ReadResult, error) {
 	switch msg.PayloadType {
 	case PayloadTypeHandshakeRequest:
-		if err := dc.sendAcknowledge(ctx, msg); err != nil {
-			return ReadResult{}, err
-		}
 		if err := dc.handleHandshakeRequest(ctx, msg); err != nil {
 			return ReadResult{}, err
 		}
 	case PayloadTypeHandshakeComplete:
-		if err := dc.sendAcknowledge(ctx, msg); err != nil {
-			return ReadResult{}, err
-		}
 		// HandshakeCompletePayload の内容 (CustomerMessage 等) は特に処理せず、開始通知として扱う。
 		dc.markHandshakeDone()
-	default:
-		if err := dc.sendAcknowledge(ctx, msg); err != nil {
+	case PayloadTypeEncChallengeRequest:
+		if err := dc.handleEncryptionChallenge(ctx, msg); err != nil {
 			return ReadResult{}, err
 		}
+	default:
 		// ハンドシェイク非対応の agent はハンドシェイクなしで出力を送ってくるため、
 		// 初回の通常出力でも入力ゲートを解放する。
 		dc.markHandshakeDone()
 		dc.expectedSequenceNumber++
-		return ReadResult{Output: msg.Payload}, nil
+		output := msg.Payload
+		if dc.encrypter != nil && encryptedPayloadTypes[msg.PayloadType] {
+			plain, err := dc.encrypter.decrypt(output)
+			if err != nil {
+				return ReadResult{}, err
+			}
+			output = plain
+		}
+		return ReadResult{Output: output, PayloadType: msg.PayloadType}, nil
 	}
 
 	dc.expectedSequenceNumber++
@@ -225,12 +527,14 @@ func (dc *DataChannel) handleOutputStreamData(ctx context.Context, msg *AgentMes
 }
 
 // handleHandshakeRequest は HandshakeRequest を処理し、HandshakeResponse を返送する。
-// KMSEncryption アクションは対応しないため常に Unsupported とし、SessionType アクションのみ許可する。
+// 許可するセッション種別はデータチャネルの用途 (シェル / ポートフォワーディング) ごとに異なる。
+// KMSEncryption アクションは EnableKMSEncryption が呼ばれている場合のみ処理し、それ以外は Unsupported とする。
 func (dc *DataChannel) handleHandshakeRequest(ctx context.Context, msg *AgentMessage) error {
 	var req HandshakeRequestPayload
 	if err := json.Unmarshal(msg.Payload, &req); err != nil {
 		return fmt.Errorf("unmarshal handshake request: %w", err)
 	}
+	dc.agentVersion = req.AgentVersion
 
 	resp := HandshakeResponsePayload{
 		ClientVersion:          clientVersion,
@@ -249,15 +553,31 @@ func (dc *DataChannel) handleHandshakeRequest(ctx context.Context, msg *AgentMes
 				errs = append(errs, processed.Error)
 				break
 			}
-			if !shellSessionTypes[sessReq.SessionType] {
+			if !dc.sessionTypes[sessReq.SessionType] {
 				processed.ActionStatus = ActionStatusFailed
 				processed.Error = fmt.Sprintf("unsupported session type %q", sessReq.SessionType)
 				errs = append(errs, processed.Error)
 				break
 			}
 			processed.ActionStatus = ActionStatusSuccess
+		case ActionTypeKMSEncryption:
+			if dc.generateDataKey == nil {
+				processed.ActionStatus = ActionStatusUnsupported
+				processed.Error = fmt.Sprintf("unsupported action %q", action.ActionType)
+				errs = append(errs, processed.Error)
+				break
+			}
+			result, err := dc.processKMSEncryption(ctx, action.ActionParameters)
+			if err != nil {
+				processed.ActionStatus = ActionStatusFailed
+				processed.Error = err.Error()
+				errs = append(errs, processed.Error)
+				break
+			}
+			processed.ActionStatus = ActionStatusSuccess
+			processed.ActionResult = result
 		default:
-			// KMSEncryption を含む未対応アクションは全て Unsupported として拒否する。
+			// 未対応アクションは全て Unsupported として拒否する。
 			processed.ActionStatus = ActionStatusUnsupported
 			processed.Error = fmt.Sprintf("unsupported action %q", action.ActionType)
 			errs = append(errs, processed.Error)
@@ -274,6 +594,57 @@ func (dc *DataChannel) handleHandshakeRequest(ctx context.Context, msg *AgentMes
 	return dc.sendInputStreamData(ctx, PayloadTypeHandshakeResponse, payload)
 }
 
+// processKMSEncryption は KMSEncryption アクションを処理する。指定された KMS キーで 64 バイトのデータキーを生成して
+// encrypter を設定し、agent がデータキーを復号するための暗号化済みデータキーとそのハッシュを返す。
+func (dc *DataChannel) processKMSEncryption(ctx context.Context, params json.RawMessage) (KMSEncryptionResponse, error) {
+	var req KMSEncryptionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return KMSEncryptionResponse{}, fmt.Errorf("unmarshal kms encryption request: %w", err)
+	}
+	encryptionContext := map[string]string{
+		encryptionContextSessionID: dc.sessionID,
+		encryptionContextTargetID:  dc.targetID,
+	}
+	cipherTextKey, plainTextKey, err := dc.generateDataKey(ctx, req.KMSKeyID, encryptionContext, kmsDataKeySize)
+	if err != nil {
+		return KMSEncryptionResponse{}, fmt.Errorf("generate kms data key: %w", err)
+	}
+	enc, err := newEncrypter(cipherTextKey, plainTextKey)
+	if err != nil {
+		return KMSEncryptionResponse{}, err
+	}
+	dc.encrypter = enc
+
+	hash := sha256.Sum256(cipherTextKey)
+	return KMSEncryptionResponse{KMSCipherTextKey: cipherTextKey, KMSCipherTextHash: hash[:]}, nil
+}
+
+// handleEncryptionChallenge は agent からの暗号化チャレンジを agent→client 方向の鍵で復号し、
+// client→agent 方向の鍵で暗号化し直して返送する。agent はこの応答で双方向の鍵が一致することを確認する。
+func (dc *DataChannel) handleEncryptionChallenge(ctx context.Context, msg *AgentMessage) error {
+	if dc.encrypter == nil {
+		return errors.New("received encryption challenge without kms encryption")
+	}
+	var req EncryptionChallengeRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return fmt.Errorf("unmarshal encryption challenge request: %w", err)
+	}
+	challenge, err := dc.encrypter.decrypt(req.Challenge)
+	if err != nil {
+		return fmt.Errorf("decrypt encryption challenge: %w", err)
+	}
+	reencrypted, err := dc.encrypter.encrypt(challenge)
+	if err != nil {
+		return fmt.Errorf("encrypt encryption challenge: %w", err)
+	}
+	payload, err := json.Marshal(EncryptionChallengeResponse{Challenge: reencrypted})
+	if err != nil {
+		return fmt.Errorf("marshal encryption challenge response: %w", err)
+	}
+	// ハンドシェイク完了前に送る応答のため、入力ゲート (waitHandshake) を通さず直接送信する。
+	return dc.sendInputStreamData(ctx, PayloadTypeEncChallengeResponse, payload)
+}
+
 // sendAcknowledge は受信メッセージへの acknowledge を送信する。
 func (dc *DataChannel) sendAcknowledge(ctx context.Context, received *AgentMessage) error {
 	ack, err := NewAcknowledgeMessage(received)
@@ -289,7 +660,17 @@ func (dc *DataChannel) send(ctx context.Context, msg *AgentMessage) error {
 	if err != nil {
 		return fmt.Errorf("marshal agent message: %w", err)
 	}
-	if err := dc.conn.Write(ctx, websocket.MessageBinary, raw); err != nil {
+	return dc.write(ctx, raw)
+}
+
+// write はシリアライズ済みのメッセージを現在の接続へ送信する。再接続が有効で切断中の場合、失敗はエラーにしない
+// (input_stream_data は再接続後に再送され、acknowledge は agent の再送に対して返し直す)。
+func (dc *DataChannel) write(ctx context.Context, raw []byte) error {
+	if err := dc.currentConn().Write(ctx, websocket.MessageBinary, raw); err != nil {
+		if dc.canReconnect(ctx, err) {
+			slog.Debug("failed to write data channel message, waiting for reconnect", "err", err)
+			return nil
+		}
 		return fmt.Errorf("write data channel message: %w", err)
 	}
 	return nil
@@ -354,3 +354,127 @@ func TestDataChannelInputGateOpensOnPlainOutput(t *testing.T) {
 		t.Errorf("payload = %q, want %q", msg.Payload, "ls\n")
 	}
 }
+
+func TestDataChannelReordersOutOfOrderMessages(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	drainConn(ctx, agent)
+	outputs := startReadLoop(ctx, dc)
+
+	// seq 1 が先に届いても seq 0 を待って順に出力し、処理済みの seq 0 の再送は読み捨てる。
+	sendOutputStreamData(ctx, t, agent, 1, PayloadTypeOutput, []byte("b"))
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeOutput, []byte("a"))
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeOutput, []byte("a"))
+	sendOutputStreamData(ctx, t, agent, 2, PayloadTypeOutput, []byte("c"))
+
+	var got string
+	for len(got) < 3 {
+		select {
+		case out := <-outputs:
+			got += string(out)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for output (got %q)", got)
+		}
+	}
+	if got != "abc" {
+		t.Errorf("output = %q, want %q", got, "abc")
+	}
+}
+
+// sendAcknowledge は agent → client の acknowledge を送信する。
+func sendAcknowledge(ctx context.Context, t *testing.T, agent *websocket.Conn, received *AgentMessage) {
+	t.Helper()
+	ack, err := NewAcknowledgeMessage(received)
+	if err != nil {
+		t.Fatalf("new acknowledge message: %v", err)
+	}
+	raw, err := ack.Marshal()
+	if err != nil {
+		t.Fatalf("marshal acknowledge: %v", err)
+	}
+	if err := agent.Write(ctx, websocket.MessageBinary, raw); err != nil {
+		t.Fatalf("write acknowledge: %v", err)
+	}
+}
+
+func TestDataChannelReconnectResendsUnacknowledged(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	fa := newFakeAgent(t)
+	dc, err := OpenDataChannel(ctx, fa.server.URL, "test-token", "test-session-id")
+	if err != nil {
+		t.Fatalf("OpenDataChannel() error = %v", err)
+	}
+	t.Cleanup(func() { _ = dc.Close() })
+	resumed := make(chan struct{}, 1)
+	dc.EnableReconnect(func(context.Context) (string, string, error) {
+		resumed <- struct{}{}
+		return fa.server.URL, "resumed-token", nil
+	})
+
+	first := fa.accept(ctx, t)
+	if _, _, err := first.Read(ctx); err != nil {
+		t.Fatalf("read open data channel input: %v", err)
+	}
+	outputs := startReadLoop(ctx, dc)
+	sendOutputStreamData(ctx, t, first, 0, PayloadTypeOutput, []byte("$ "))
+	<-outputs
+
+	if err := dc.SendInput(ctx, PayloadTypeOutput, []byte("ls\n")); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+	if msg := readInputStreamData(ctx, t, first); msg.SequenceNumber != 0 {
+		t.Fatalf("input sequence number = %d, want 0", msg.SequenceNumber)
+	}
+	// acknowledge を返さないまま接続を異常切断すると、ResumeSession のトークンで再接続する。
+	if err := first.CloseNow(); err != nil {
+		t.Fatalf("close first connection: %v", err)
+	}
+
+	second := fa.accept(ctx, t)
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+		t.Fatal("resume func was not called")
+	}
+	typ, raw, err := second.Read(ctx)
+	if err != nil || typ != websocket.MessageText {
+		t.Fatalf("read open data channel input after reconnect: type %v, err %v", typ, err)
+	}
+	var input OpenDataChannelInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		t.Fatalf("unmarshal open data channel input: %v", err)
+	}
+	if input.TokenValue != "resumed-token" || input.ClientID != dc.clientID {
+		t.Errorf("OpenDataChannelInput = %+v, want resumed token with the same client id", input)
+	}
+
+	// 未 acknowledge の入力が新しい接続で送り直される。
+	resent := readInputStreamData(ctx, t, second)
+	if resent.SequenceNumber != 0 || string(resent.Payload) != "ls\n" {
+		t.Fatalf("resent message seq = %d payload = %q, want seq 0 %q", resent.SequenceNumber, resent.Payload, "ls\n")
+	}
+	sendAcknowledge(ctx, t, second, resent)
+	drainConn(ctx, second)
+	sendOutputStreamData(ctx, t, second, 1, PayloadTypeOutput, []byte("file\n"))
+	select {
+	case out := <-outputs:
+		if string(out) != "file\n" {
+			t.Errorf("output after reconnect = %q, want %q", out, "file\n")
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for output after reconnect")
+	}
+	for dc.outgoing.len() != 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("acknowledged message was not removed from the outgoing buffer")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// kmsDataKeySize は KMSEncryption で生成するデータキーのバイト数。AWS 公式 session-manager-plugin と同様、
+// 64 バイトのデータキーの前半を agent→client 方向の復号鍵、後半を client→agent 方向の暗号鍵 (各 AES-256) として使う。
+const kmsDataKeySize = 64
+
+// Session Manager が KMS の暗号化コンテキストに要求するキー。
+const (
+	encryptionContextSessionID = "aws:ssm:SessionId"
+	encryptionContextTargetID  = "aws:ssm:TargetId"
+)
+
+// DataKeyGenerator は KMSEncryption ハンドシェイクで使うデータキーを KMS (GenerateDataKey) で生成し、
+// 暗号化済みデータキー (CiphertextBlob) と平文のデータキーを返す。numberOfBytes のデータキーを生成すること。
+// session パッケージを AWS SDK に依存させないため、KMS の呼び出しは呼び出し側が注入する。
+type DataKeyGenerator func(ctx context.Context, keyID string, encryptionContext map[string]string, numberOfBytes int32) (ciphertext, plaintext []byte, err error)
+
+// encrypter は KMS データキーから導出した AES-GCM 鍵でペイロードを暗号化・復号する。
+// 暗号文は 12 バイトの nonce の後ろに AES-GCM の Seal 結果 (認証タグ込み) を連結した形式 (公式実装と同じ)。
+type encrypter struct {
+	encryptAEAD   cipher.AEAD
+	decryptAEAD   cipher.AEAD
+	cipherTextKey []byte
+}
+
+// newEncrypter は平文のデータキーを前半 (復号鍵) と後半 (暗号鍵) に分けて encrypter を生成する。
+func newEncrypter(cipherTextKey, plainTextKey []byte) (*encrypter, error) {
+	if len(plainTextKey) != kmsDataKeySize {
+		return nil, fmt.Errorf("kms data key is %d bytes, want %d", len(plainTextKey), kmsDataKeySize)
+	}
+	half := len(plainTextKey) / 2
+	decryptAEAD, err := newGCM(plainTextKey[:half])
+	if err != nil {
+		return nil, err
+	}
+	encryptAEAD, err := newGCM(plainTextKey[half:])
+	if err != nil {
+		return nil, err
+	}
+	return &encrypter{
+		encryptAEAD:   encryptAEAD,
+		decryptAEAD:   decryptAEAD,
+		cipherTextKey: cipherTextKey,
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// encrypt は client→agent 方向のペイロードを暗号化する。
+func (e *encrypter) encrypt(plain []byte) ([]byte, error) {
+	nonce := make([]byte, e.encryptAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return e.encryptAEAD.Seal(nonce, nonce, plain, nil), nil
+}
+
+// decrypt は agent→client 方向のペイロードを復号する。
+func (e *encrypter) decrypt(data []byte) ([]byte, error) {
+	nonceSize := e.decryptAEAD.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("decrypt payload: ciphertext too short")
+	}
+	plain, err := e.decryptAEAD.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt payload: %w", err)
+	}
+	return plain, nil
+}
+
+// encryptedPayloadTypes は KMS 暗号化が有効なセッションで暗号化されるペイロード種別 (ストリームデータ)。
+// Size / Flag / ハンドシェイク系の制御ペイロードは平文のまま送受信する。
+var encryptedPayloadTypes = map[PayloadType]bool{
+	PayloadTypeOutput:   true,
+	PayloadTypeStdErr:   true,
+	PayloadTypeExitCode: true,
+}
@@ -0,0 +1,196 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+)
+
+// testDataKey はテスト用の 64 バイトのデータキー (前半: agent→client、後半: client→agent)。
+func testDataKey() []byte {
+	key := make([]byte, kmsDataKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	return key
+}
+
+// newAgentEncrypter は agent 側の encrypter を生成する。agent は client と逆向きに鍵を使うため、前半と後半を入れ替える。
+func newAgentEncrypter(t *testing.T, plainTextKey []byte) *encrypter {
+	t.Helper()
+	half := len(plainTextKey) / 2
+	swapped := append(append([]byte{}, plainTextKey[half:]...), plainTextKey[:half]...)
+	enc, err := newEncrypter(nil, swapped)
+	if err != nil {
+		t.Fatalf("newEncrypter() error = %v", err)
+	}
+	return enc
+}
+
+func TestEncrypterRoundTrip(t *testing.T) {
+	t.Parallel()
+	client, err := newEncrypter([]byte("blob"), testDataKey())
+	if err != nil {
+		t.Fatalf("newEncrypter() error = %v", err)
+	}
+	agent := newAgentEncrypter(t, testDataKey())
+
+	sealed, err := client.encrypt([]byte("ls\n"))
+	if err != nil {
+		t.Fatalf("encrypt() error = %v", err)
+	}
+	if got, err := agent.decrypt(sealed); err != nil || string(got) != "ls\n" {
+		t.Errorf("agent decrypt = %q, %v, want %q", got, err, "ls\n")
+	}
+	// client→agent 方向の暗号文は client 自身の復号鍵 (agent→client 方向) では復号できない。
+	if _, err := client.decrypt(sealed); err == nil {
+		t.Error("client decrypt of its own ciphertext succeeded, want error")
+	}
+	if _, err := client.decrypt([]byte("short")); err == nil {
+		t.Error("decrypt of short ciphertext succeeded, want error")
+	}
+	if _, err := newEncrypter(nil, make([]byte, 32)); err == nil {
+		t.Error("newEncrypter with 32 byte key succeeded, want error")
+	}
+}
+
+func TestDataChannelKMSEncryption(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	var gotKeyID string
+	var gotContext map[string]string
+	dc.EnableKMSEncryption("i-0123456789abcdef0", func(_ context.Context, keyID string, encryptionContext map[string]string, numberOfBytes int32) ([]byte, []byte, error) {
+		gotKeyID = keyID
+		gotContext = encryptionContext
+		if numberOfBytes != kmsDataKeySize {
+			t.Errorf("numberOfBytes = %d, want %d", numberOfBytes, kmsDataKeySize)
+		}
+		return []byte("ciphertext-blob"), testDataKey(), nil
+	})
+	outputs := startReadLoop(ctx, dc)
+	agentEnc := newAgentEncrypter(t, testDataKey())
+
+	req := HandshakeRequestPayload{
+		AgentVersion: "3.3.0.0",
+		RequestedClientActions: []RequestedClientAction{
+			{ActionType: ActionTypeSessionType, ActionParameters: json.RawMessage(`{"SessionType":"Standard_Stream","Properties":null}`)},
+			{ActionType: ActionTypeKMSEncryption, ActionParameters: json.RawMessage(`{"KMSKeyId":"alias/session"}`)},
+		},
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal handshake request: %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeHandshakeRequest, payload)
+
+	resp := readInputStreamData(ctx, t, agent)
+	var handshake struct {
+		ProcessedClientActions []struct {
+			ActionType   ActionType
+			ActionStatus ActionStatus
+			ActionResult KMSEncryptionResponse
+		}
+	}
+	if err := json.Unmarshal(resp.Payload, &handshake); err != nil {
+		t.Fatalf("unmarshal handshake response: %v", err)
+	}
+	if len(handshake.ProcessedClientActions) != 2 {
+		t.Fatalf("processed actions = %d, want 2", len(handshake.ProcessedClientActions))
+	}
+	kms := handshake.ProcessedClientActions[1]
+	if kms.ActionType != ActionTypeKMSEncryption || kms.ActionStatus != ActionStatusSuccess {
+		t.Fatalf("kms action = %+v, want success", kms)
+	}
+	wantHash := sha256.Sum256([]byte("ciphertext-blob"))
+	if string(kms.ActionResult.KMSCipherTextKey) != "ciphertext-blob" || !bytes.Equal(kms.ActionResult.KMSCipherTextHash, wantHash[:]) {
+		t.Errorf("kms action result = %+v, want ciphertext blob and its sha256", kms.ActionResult)
+	}
+	if gotKeyID != "alias/session" {
+		t.Errorf("generate data key keyID = %q, want %q", gotKeyID, "alias/session")
+	}
+	wantContext := map[string]string{"aws:ssm:SessionId": "test-session-id", "aws:ssm:TargetId": "i-0123456789abcdef0"}
+	if len(gotContext) != len(wantContext) || gotContext["aws:ssm:SessionId"] != wantContext["aws:ssm:SessionId"] || gotContext["aws:ssm:TargetId"] != wantContext["aws:ssm:TargetId"] {
+		t.Errorf("encryption context = %v, want %v", gotContext, wantContext)
+	}
+
+	// チャレンジは agent→client 方向の鍵で暗号化され、client→agent 方向の鍵で暗号化し直して返される。
+	challenge, err := agentEnc.encrypt([]byte("challenge"))
+	if err != nil {
+		t.Fatalf("encrypt challenge: %v", err)
+	}
+	payload, err = json.Marshal(EncryptionChallengeRequest{Challenge: challenge})
+	if err != nil {
+		t.Fatalf("marshal challenge request: %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 1, PayloadTypeEncChallengeRequest, payload)
+	challengeResp := readInputStreamData(ctx, t, agent)
+	if challengeResp.PayloadType != PayloadTypeEncChallengeResponse {
+		t.Fatalf("challenge response payload type = %d, want %d", challengeResp.PayloadType, PayloadTypeEncChallengeResponse)
+	}
+	var respBody EncryptionChallengeResponse
+	if err := json.Unmarshal(challengeResp.Payload, &respBody); err != nil {
+		t.Fatalf("unmarshal challenge response: %v", err)
+	}
+	if got, err := agentEnc.decrypt(respBody.Challenge); err != nil || string(got) != "challenge" {
+		t.Errorf("decrypted challenge response = %q, %v, want %q", got, err, "challenge")
+	}
+
+	sendHandshakeComplete(ctx, t, agent, 2)
+
+	// 入力は暗号化して送信され、出力は復号して返される。
+	if err := dc.SendInput(ctx, PayloadTypeOutput, []byte("ls\n")); err != nil {
+		t.Fatalf("SendInput() error = %v", err)
+	}
+	input := readInputStreamData(ctx, t, agent)
+	if got, err := agentEnc.decrypt(input.Payload); err != nil || string(got) != "ls\n" {
+		t.Errorf("decrypted input = %q, %v, want %q", got, err, "ls\n")
+	}
+
+	sealed, err := agentEnc.encrypt([]byte("file.txt\n"))
+	if err != nil {
+		t.Fatalf("encrypt output: %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 3, PayloadTypeOutput, sealed)
+	select {
+	case got := <-outputs:
+		if string(got) != "file.txt\n" {
+			t.Errorf("output = %q, want %q", got, "file.txt\n")
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for decrypted output")
+	}
+}
+
+func TestDataChannelRejectsKMSEncryptionWhenNotEnabled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestDataChannel(ctx, t)
+	startReadLoop(ctx, dc)
+
+	req := HandshakeRequestPayload{
+		AgentVersion: "3.3.0.0",
+		RequestedClientActions: []RequestedClientAction{
+			{ActionType: ActionTypeKMSEncryption, ActionParameters: json.RawMessage(`{"KMSKeyId":"alias/session"}`)},
+		},
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal handshake request: %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeHandshakeRequest, payload)
+
+	var resp HandshakeResponsePayload
+	if err := json.Unmarshal(readInputStreamData(ctx, t, agent).Payload, &resp); err != nil {
+		t.Fatalf("unmarshal handshake response: %v", err)
+	}
+	if len(resp.ProcessedClientActions) != 1 || resp.ProcessedClientActions[0].ActionStatus != ActionStatusUnsupported {
+		t.Errorf("processed actions = %+v, want KMSEncryption unsupported", resp.ProcessedClientActions)
+	}
+}
@@ -0,0 +1,286 @@
+package session
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SSM agent のポートフォワーディング (MuxPortForwarding) は、データチャネルのストリームデータ上で
+// smux (github.com/xtaci/smux) プロトコル v1 により複数の TCP 接続を多重化する。
+// ここではそのクライアント側に必要な最小限 (SYN / FIN / PSH / NOP) を自前実装する。
+//
+// フレームは 8 バイトのヘッダ (version, cmd, length (uint16 LE), stream id (uint32 LE)) と
+// 最大 muxMaxFrameSize バイトのデータからなる。v1 にはストリーム単位のフロー制御がないため、
+// smux 本体と同様にセッション全体の受信バッファ量 (muxMaxReceiveBuffer) で受信ループを止めて背圧をかける。
+const (
+	muxVersion = 1
+
+	muxCmdSYN byte = 0 // ストリーム開始
+	muxCmdFIN byte = 1 // ストリーム終了
+	muxCmdPSH byte = 2 // データ
+	muxCmdNOP byte = 3 // keep-alive
+
+	muxHeaderSize       = 8
+	muxMaxFrameSize     = 32768
+	muxMaxReceiveBuffer = 4 << 20 // 4MiB (smux.DefaultConfig と同じ)
+	// muxKeepAliveInterval は keep-alive (NOP) の送信間隔 (smux.DefaultConfig と同じ)。
+	muxKeepAliveInterval = 10 * time.Second
+)
+
+// errMuxSessionClosed は閉じた muxSession / muxStream を操作した場合のエラー。
+var errMuxSessionClosed = errors.New("mux session closed")
+
+// muxSession は smux v1 のクライアントセッション。ストリーム ID はクライアント側の規約に従い奇数を採番する。
+type muxSession struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex
+
+	// mu は streams / nextStreamID / buffered / closed を保護する。cond は受信データの到着、
+	// 受信バッファの消費、ストリーム・セッションの終了をまとめて通知する。
+	mu           sync.Mutex
+	cond         *sync.Cond
+	streams      map[uint32]*muxStream
+	nextStreamID uint32
+	buffered     int
+	closed       bool
+
+	die chan struct{}
+}
+
+// newMuxSession は conn 上で smux クライアントセッションを開始する。keepAlive が true の場合は
+// 定期的に NOP を送る (keep-alive を有効にしたまま接続を待つ古い agent 向け)。
+func newMuxSession(conn io.ReadWriteCloser, keepAlive bool) *muxSession {
+	s := &muxSession{
+		conn:         conn,
+		streams:      map[uint32]*muxStream{},
+		nextStreamID: 1,
+		die:          make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.recvLoop()
+	if keepAlive {
+		go s.keepAlive()
+	}
+	return s
+}
+
+// OpenStream は新しいストリームを開き、SYN を送信する。
+func (s *muxSession) OpenStream() (*muxStream, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errMuxSessionClosed
+	}
+	s.nextStreamID += 2
+	st := &muxStream{id: s.nextStreamID, sess: s}
+	s.streams[st.id] = st
+	s.mu.Unlock()
+
+	if err := s.writeFrame(muxCmdSYN, st.id, nil); err != nil {
+		s.removeStream(st)
+		return nil, err
+	}
+	return st, nil
+}
+
+// Close はセッションと全ストリームを閉じる。複数回呼んでも安全。
+func (s *muxSession) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.die)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	return s.conn.Close()
+}
+
+// writeFrame は 1 フレームを送信する。ヘッダとデータを 1 回の Write で書き、フレームの混在を防ぐ。
+func (s *muxSession) writeFrame(cmd byte, sid uint32, data []byte) error {
+	buf := make([]byte, muxHeaderSize+len(data))
+	buf[0] = muxVersion
+	buf[1] = cmd
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(data)))
+	binary.LittleEndian.PutUint32(buf[4:], sid)
+	copy(buf[muxHeaderSize:], data)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	select {
+	case <-s.die:
+		return errMuxSessionClosed
+	default:
+	}
+	if _, err := s.conn.Write(buf); err != nil {
+		return fmt.Errorf("write mux frame: %w", err)
+	}
+	return nil
+}
+
+// recvLoop はフレームを読み取り、PSH のデータを該当ストリームのバッファへ積む。
+// 読み取りエラー (conn のクローズを含む) でセッションを閉じる。
+func (s *muxSession) recvLoop() {
+	defer s.Close()
+
+	header := make([]byte, muxHeaderSize)
+	for {
+		if _, err := io.ReadFull(s.conn, header); err != nil {
+			return
+		}
+		if header[0] != muxVersion {
+			slog.Warn("unexpected mux frame version", "version", header[0])
+			return
+		}
+		cmd := header[1]
+		length := binary.LittleEndian.Uint16(header[2:])
+		sid := binary.LittleEndian.Uint32(header[4:])
+
+		var data []byte
+		if length > 0 {
+			data = make([]byte, length)
+			if _, err := io.ReadFull(s.conn, data); err != nil {
+				return
+			}
+		}
+
+		switch cmd {
+		case muxCmdPSH:
+			s.push(sid, data)
+		case muxCmdFIN:
+			s.mu.Lock()
+			if st, ok := s.streams[sid]; ok {
+				st.eof = true
+				s.cond.Broadcast()
+			}
+			s.mu.Unlock()
+		case muxCmdNOP, muxCmdSYN:
+			// クライアントはサーバからのストリーム開始を受け付けない。
+		default:
+			slog.Warn("unknown mux frame command", "cmd", cmd)
+			return
+		}
+	}
+}
+
+// push は受信データをストリームのバッファへ積む。セッション全体の受信バッファが上限に達している間は
+// ストリームの読み取りで消費されるまで待機し、agent からの読み取りを止めて背圧をかける。
+func (s *muxSession) push(sid uint32, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.buffered >= muxMaxReceiveBuffer && !s.closed {
+		s.cond.Wait()
+	}
+	st, ok := s.streams[sid]
+	if !ok || s.closed {
+		// ローカル側で閉じ済みのストリーム宛てのデータは読み捨てる。
+		return
+	}
+	st.buf = append(st.buf, data...)
+	s.buffered += len(data)
+	s.cond.Broadcast()
+}
+
+// removeStream はストリームを管理対象から外し、未読のバッファ分を受信バッファ量から差し引く。
+func (s *muxSession) removeStream(st *muxStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, st.id)
+	s.buffered -= len(st.buf)
+	st.buf = nil
+	st.closed = true
+	s.cond.Broadcast()
+}
+
+// keepAlive はセッションが閉じるまで定期的に NOP を送信する。
+func (s *muxSession) keepAlive() {
+	ticker := time.NewTicker(muxKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.writeFrame(muxCmdNOP, 0, nil); err != nil {
+				return
+			}
+		case <-s.die:
+			return
+		}
+	}
+}
+
+// muxStream は muxSession 上の 1 ストリーム (ローカルの 1 TCP 接続に対応する)。
+// buf / eof / closed は muxSession.mu で保護する。
+type muxStream struct {
+	id   uint32
+	sess *muxSession
+
+	buf    []byte
+	eof    bool
+	closed bool
+
+	closeOnce sync.Once
+}
+
+// Read は受信済みデータを読み出す。データが無ければ到着まで待機し、agent 側から FIN を受けて
+// バッファが空になった時点で io.EOF を返す。
+func (st *muxStream) Read(p []byte) (int, error) {
+	s := st.sess
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(st.buf) == 0 {
+		switch {
+		case st.closed, s.closed:
+			return 0, errMuxSessionClosed
+		case st.eof:
+			return 0, io.EOF
+		}
+		s.cond.Wait()
+	}
+	n := copy(p, st.buf)
+	st.buf = st.buf[n:]
+	s.buffered -= n
+	s.cond.Broadcast()
+	return n, nil
+}
+
+// Write は p を muxMaxFrameSize 以下の PSH フレームに分割して送信する。
+func (st *muxStream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		s := st.sess
+		s.mu.Lock()
+		closed := st.closed
+		s.mu.Unlock()
+		if closed {
+			return written, errMuxSessionClosed
+		}
+
+		n := min(len(p), muxMaxFrameSize)
+		if err := s.writeFrame(muxCmdPSH, st.id, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close は FIN を送信してストリームを閉じる。複数回呼んでも安全。
+func (st *muxStream) Close() error {
+	var err error
+	st.closeOnce.Do(func() {
+		st.sess.removeStream(st)
+		err = st.sess.writeFrame(muxCmdFIN, st.id, nil)
+		if errors.Is(err, errMuxSessionClosed) {
+			err = nil
+		}
+	})
+	return err
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// readMuxFrame は server 側 (agent 相当) で 1 フレームを読み取る。
+func readMuxFrame(t *testing.T, conn net.Conn) (cmd byte, sid uint32, data []byte) {
+	t.Helper()
+	header := make([]byte, muxHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatalf("read mux header: %v", err)
+	}
+	if header[0] != muxVersion {
+		t.Fatalf("mux version = %d, want %d", header[0], muxVersion)
+	}
+	data = make([]byte, binary.LittleEndian.Uint16(header[2:]))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatalf("read mux data: %v", err)
+	}
+	return header[1], binary.LittleEndian.Uint32(header[4:]), data
+}
+
+// writeMuxFrame は server 側 (agent 相当) から 1 フレームを送信する。
+func writeMuxFrame(t *testing.T, conn net.Conn, cmd byte, sid uint32, data []byte) {
+	t.Helper()
+	buf := make([]byte, muxHeaderSize+len(data))
+	buf[0] = muxVersion
+	buf[1] = cmd
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(data)))
+	binary.LittleEndian.PutUint32(buf[4:], sid)
+	copy(buf[muxHeaderSize:], data)
+	if _, err := conn.Write(buf); err != nil {
+		t.Fatalf("write mux frame: %v", err)
+	}
+}
+
+func TestMuxSessionStreamRoundTrip(t *testing.T) {
+	t.Parallel()
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+
+	sess := newMuxSession(client, false)
+	t.Cleanup(func() { _ = sess.Close() })
+
+	// OpenStream / Write / Close は net.Pipe の同期書き込みでブロックするため、server 側の読み取りと並行させる。
+	type opened struct {
+		stream *muxStream
+		err    error
+	}
+	openCh := make(chan opened, 1)
+	go func() {
+		st, err := sess.OpenStream()
+		openCh <- opened{st, err}
+	}()
+
+	cmd, sid, _ := readMuxFrame(t, server)
+	if cmd != muxCmdSYN {
+		t.Fatalf("first frame cmd = %d, want SYN", cmd)
+	}
+	if sid%2 != 1 {
+		t.Errorf("client stream id = %d, want odd", sid)
+	}
+	o := <-openCh
+	if o.err != nil {
+		t.Fatalf("OpenStream() error = %v", o.err)
+	}
+	stream := o.stream
+
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := stream.Write([]byte("ping"))
+		writeErr <- err
+	}()
+	cmd, gotSID, data := readMuxFrame(t, server)
+	if cmd != muxCmdPSH || gotSID != sid || string(data) != "ping" {
+		t.Fatalf("frame = (cmd %d, sid %d, %q), want (PSH, %d, %q)", cmd, gotSID, data, sid, "ping")
+	}
+	if err := <-writeErr; err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// agent からの応答と FIN はバッファされ、読み切った後に io.EOF になる。
+	writeMuxFrame(t, server, muxCmdPSH, sid, []byte("pong"))
+	writeMuxFrame(t, server, muxCmdFIN, sid, nil)
+
+	got, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(got) != "pong" {
+		t.Errorf("read = %q, want %q", got, "pong")
+	}
+
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- stream.Close() }()
+	cmd, gotSID, _ = readMuxFrame(t, server)
+	if cmd != muxCmdFIN || gotSID != sid {
+		t.Errorf("close frame = (cmd %d, sid %d), want (FIN, %d)", cmd, gotSID, sid)
+	}
+	if err := <-closeErr; err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestMuxSessionWriteSplitsLargePayload(t *testing.T) {
+	t.Parallel()
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+
+	sess := newMuxSession(client, false)
+	t.Cleanup(func() { _ = sess.Close() })
+
+	go func() { _, _ = sess.OpenStream() }()
+	_, sid, _ := readMuxFrame(t, server)
+
+	sess.mu.Lock()
+	stream := sess.streams[sid]
+	sess.mu.Unlock()
+
+	payload := make([]byte, muxMaxFrameSize+10)
+	go func() { _, _ = stream.Write(payload) }()
+
+	_, _, first := readMuxFrame(t, server)
+	_, _, second := readMuxFrame(t, server)
+	if len(first) != muxMaxFrameSize || len(second) != 10 {
+		t.Errorf("frame sizes = %d, %d, want %d, 10", len(first), len(second), muxMaxFrameSize)
+	}
+}
+
+func TestMuxSessionCloseUnblocksRead(t *testing.T) {
+	t.Parallel()
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+
+	sess := newMuxSession(client, false)
+	go func() { _, _ = sess.OpenStream() }()
+	_, sid, _ := readMuxFrame(t, server)
+
+	sess.mu.Lock()
+	stream := sess.streams[sid]
+	sess.mu.Unlock()
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := stream.Read(make([]byte, 1))
+		readErr <- err
+	}()
+	_ = sess.Close()
+
+	if err := <-readErr; err != errMuxSessionClosed {
+		t.Errorf("Read() error = %v, want %v", err, errMuxSessionClosed)
+	}
+}
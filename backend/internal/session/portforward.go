@@ -0,0 +1,250 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// streamDataPayloadSize はローカル接続のデータを 1 つの input_stream_data メッセージに詰める上限バイト数。
+// AWS 公式 session-manager-plugin の StreamDataPayloadSize と同じ値。
+const streamDataPayloadSize = 1024
+
+// agent のバージョンによって使える多重化方式が異なる (session-manager-plugin の sessionutil と同じ閾値)。
+const (
+	// muxSupportedAgentVersion 以降の agent は smux による複数接続の多重化に対応する。
+	muxSupportedAgentVersion = "3.0.196.0"
+	// muxKeepAliveDisabledAgentVersion 以降の agent は smux の keep-alive を無効化して接続する。
+	muxKeepAliveDisabledAgentVersion = "3.1.1511.0"
+)
+
+// PortForwarder はポートフォワーディングセッション (AWS-StartPortForwardingSession(ToRemoteHost)) の
+// データチャネル上で、Listener が受け付けたローカル TCP 接続を smux で多重化して中継する。
+//
+// データチャネルのストリームデータ (PayloadTypeOutput) は smux のフレームそのものであり、
+// net.Pipe でつないだ muxSession (smux v1 クライアントの自前実装) へそのまま流し込む。
+// smux 非対応の古い agent (muxSupportedAgentVersion 未満) は 1 接続ずつしか扱えないため対応しない。
+type PortForwarder struct {
+	DataChannel *DataChannel
+	Listener    net.Listener
+	// Terminate は終了時に呼び出す (省略可)。SSM セッションのクリーンアップに使う。
+	Terminate TerminateFunc
+}
+
+// Run は ctx がキャンセルされるか、agent 側からセッションが閉じられるまでローカル接続を中継する。
+// 戻り値は通信そのもののエラーであり、ctx のキャンセルや channel_closed による終了では nil を返す。
+func (p *PortForwarder) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// muxConn は muxSession 側、agentConn はデータチャネル側の端点。
+	muxConn, agentConn := net.Pipe()
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer cancel()
+		return p.pumpDataChannelToMux(ctx, agentConn)
+	})
+	g.Go(func() error {
+		defer cancel()
+		return p.pumpMuxToDataChannel(ctx, agentConn)
+	})
+	g.Go(func() error {
+		defer cancel()
+		return p.serve(ctx, muxConn)
+	})
+	// Accept と Pipe の読み書きは ctx を受け取らないため、キャンセル時に明示的に閉じて解除する。
+	go func() {
+		<-ctx.Done()
+		if err := p.Listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			slog.Warn("failed to close port forward listener", "err", err)
+		}
+		_ = muxConn.Close()
+		_ = agentConn.Close()
+	}()
+
+	err := g.Wait()
+
+	p.cleanup()
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("port forward: %w", err)
+	}
+	return nil
+}
+
+// serve は agent のバージョンを確認して muxSession を開始し、ローカル接続ごとにストリームを割り当てる。
+func (p *PortForwarder) serve(ctx context.Context, muxConn net.Conn) error {
+	version, err := p.DataChannel.AgentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if !agentVersionAtLeast(version, muxSupportedAgentVersion) {
+		return fmt.Errorf("ssm agent %q does not support multiplexed port forwarding (requires %s or later)", version, muxSupportedAgentVersion)
+	}
+
+	// 終了時は smux セッションを先に閉じて各ストリームの中継を解除してから、その完了を待つ (defer は LIFO)。
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	mux := newMuxSession(muxConn, !agentVersionAtLeast(version, muxKeepAliveDisabledAgentVersion))
+	defer mux.Close()
+
+	for {
+		conn, err := p.Listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept local connection: %w", err)
+		}
+		stream, err := mux.OpenStream()
+		if err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("open mux stream: %w", err)
+		}
+		slog.Debug("port forward connection opened", "remote_addr", conn.RemoteAddr(), "stream_id", stream.id)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proxyConn(conn, stream)
+		}()
+	}
+}
+
+// proxyConn はローカル接続と mux ストリームの間で双方向にコピーし、どちらかが閉じたら両方を閉じる。
+func proxyConn(conn net.Conn, stream *muxStream) {
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			_ = conn.Close()
+			_ = stream.Close()
+		})
+	}
+	defer closeBoth()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer closeBoth()
+		_, _ = io.Copy(stream, conn)
+	}()
+	_, _ = io.Copy(conn, stream)
+	closeBoth()
+	<-done
+}
+
+// pumpDataChannelToMux はデータチャネルから受信したストリームデータを muxSession へ渡す。
+func (p *PortForwarder) pumpDataChannelToMux(ctx context.Context, agentConn net.Conn) error {
+	for {
+		result, err := p.DataChannel.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if result.Closed {
+			if result.CloseMessage != "" {
+				slog.Info("port forward session closed by agent", "message", result.CloseMessage)
+			}
+			return nil
+		}
+		if len(result.Output) == 0 {
+			continue
+		}
+		if result.PayloadType != PayloadTypeOutput {
+			slog.Debug("ignored non-stream payload on port forward session", "payload_type", result.PayloadType)
+			continue
+		}
+		if _, err := agentConn.Write(result.Output); err != nil {
+			return fmt.Errorf("write to mux session: %w", err)
+		}
+	}
+}
+
+// pumpMuxToDataChannel は muxSession が書き出したフレームをデータチャネルへ送信する。
+func (p *PortForwarder) pumpMuxToDataChannel(ctx context.Context, agentConn net.Conn) error {
+	buf := make([]byte, streamDataPayloadSize)
+	for {
+		n, err := agentConn.Read(buf)
+		if n > 0 {
+			// SendInput はペイロードを保持せずシリアライズするため、buf を使い回してよい。
+			if serr := p.DataChannel.SendInput(ctx, PayloadTypeOutput, buf[:n]); serr != nil {
+				return fmt.Errorf("send stream data to data channel: %w", serr)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+				return nil
+			}
+			return fmt.Errorf("read from mux session: %w", err)
+		}
+	}
+}
+
+// cleanup は agent へセッション終了を通知してからデータチャネルを閉じ、Terminate を呼ぶ。
+func (p *PortForwarder) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), terminateTimeout)
+	defer cancel()
+
+	// ハンドシェイク前に終了した場合 SendFlag は ctx のタイムアウトまでブロックするため、完了済みの場合のみ送る。
+	select {
+	case <-p.DataChannel.handshakeDone:
+		if err := p.DataChannel.SendFlag(ctx, PayloadFlagTerminateSession); err != nil {
+			slog.Debug("failed to send terminate session flag", "err", err)
+		}
+	default:
+	}
+
+	if err := p.DataChannel.Close(); err != nil {
+		slog.Warn("failed to close data channel", "err", err)
+	}
+
+	if p.Terminate == nil {
+		return
+	}
+	if err := p.Terminate(ctx); err != nil {
+		slog.Warn("failed to terminate ssm session", "err", err)
+	}
+}
+
+// agentVersionAtLeast は "3.1.1511.0" 形式の agent バージョンが want 以上かを返す。
+// 解析できないバージョン (空文字列を含む) は最も古いものとして扱う。
+func agentVersionAtLeast(version, want string) bool {
+	got, ok := parseAgentVersion(version)
+	if !ok {
+		return false
+	}
+	floor, _ := parseAgentVersion(want)
+	for i := range got {
+		if got[i] != floor[i] {
+			return got[i] > floor[i]
+		}
+	}
+	return true
+}
+
+func parseAgentVersion(version string) ([4]int, bool) {
+	var v [4]int
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > len(v) {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
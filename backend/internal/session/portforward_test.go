@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+func TestAgentVersionAtLeast(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version string
+		want    string
+		ok      bool
+	}{
+		{"3.0.196.0", "3.0.196.0", true},
+		{"3.0.197.0", "3.0.196.0", true},
+		{"3.1.0.0", "3.0.196.0", true},
+		{"3.0.195.9", "3.0.196.0", false},
+		{"2.3.1000.0", "3.0.196.0", false},
+		{"3.3", "3.1.1511.0", true},
+		{"", "3.0.196.0", false},
+		{"latest", "3.0.196.0", false},
+	}
+	for _, tt := range tests {
+		if got := agentVersionAtLeast(tt.version, tt.want); got != tt.ok {
+			t.Errorf("agentVersionAtLeast(%q, %q) = %v, want %v", tt.version, tt.want, got, tt.ok)
+		}
+	}
+}
+
+// openTestPortDataChannel は Port セッション用の DataChannel とフェイク agent 側 conn を確立して返す。
+func openTestPortDataChannel(ctx context.Context, t *testing.T) (*DataChannel, *websocket.Conn) {
+	t.Helper()
+	fa := newFakeAgent(t)
+	dc, err := OpenPortDataChannel(ctx, fa.server.URL, "test-token", "test-session-id")
+	if err != nil {
+		t.Fatalf("OpenPortDataChannel() error = %v", err)
+	}
+	t.Cleanup(func() { _ = dc.Close() })
+
+	agent := fa.accept(ctx, t)
+	if _, _, err := agent.Read(ctx); err != nil {
+		t.Fatalf("read open data channel input: %v", err)
+	}
+	return dc, agent
+}
+
+func TestPortForwarderRelaysLocalConnection(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	dc, agent := openTestPortDataChannel(ctx, t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	terminated := make(chan struct{})
+	forwarder := &PortForwarder{
+		DataChannel: dc,
+		Listener:    listener,
+		Terminate: func(context.Context) error {
+			close(terminated)
+			return nil
+		},
+	}
+	runCtx, stopRun := context.WithCancel(ctx)
+	runErr := make(chan error, 1)
+	go func() { runErr <- forwarder.Run(runCtx) }()
+
+	// agent 側のハンドシェイク (SessionType: Port) を行う。
+	req := HandshakeRequestPayload{
+		AgentVersion: "3.2.0.0",
+		RequestedClientActions: []RequestedClientAction{
+			{ActionType: ActionTypeSessionType, ActionParameters: json.RawMessage(`{"SessionType":"Port","Properties":null}`)},
+		},
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal handshake request: %v", err)
+	}
+	sendOutputStreamData(ctx, t, agent, 0, PayloadTypeHandshakeRequest, payload)
+	resp := readInputStreamData(ctx, t, agent)
+	var hs HandshakeResponsePayload
+	if err := json.Unmarshal(resp.Payload, &hs); err != nil {
+		t.Fatalf("unmarshal handshake response: %v", err)
+	}
+	if len(hs.ProcessedClientActions) != 1 || hs.ProcessedClientActions[0].ActionStatus != ActionStatusSuccess {
+		t.Fatalf("ProcessedClientActions = %+v, want Port session accepted", hs.ProcessedClientActions)
+	}
+	sendHandshakeComplete(ctx, t, agent, 1)
+
+	local, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial forwarded port: %v", err)
+	}
+	defer local.Close()
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf("write to forwarded port: %v", err)
+	}
+
+	// データチャネルに届いたストリームデータを連結し、smux フレームとして解釈する。
+	var stream []byte
+	readFrame := func() (byte, uint32, []byte) {
+		t.Helper()
+		for {
+			if len(stream) >= muxHeaderSize {
+				length := int(binary.LittleEndian.Uint16(stream[2:]))
+				if len(stream) >= muxHeaderSize+length {
+					cmd, sid := stream[1], binary.LittleEndian.Uint32(stream[4:])
+					data := stream[muxHeaderSize : muxHeaderSize+length]
+					stream = stream[muxHeaderSize+length:]
+					return cmd, sid, data
+				}
+			}
+			msg := readInputStreamData(ctx, t, agent)
+			if msg.PayloadType != PayloadTypeOutput {
+				t.Fatalf("payload type = %d, want %d", msg.PayloadType, PayloadTypeOutput)
+			}
+			stream = append(stream, msg.Payload...)
+		}
+	}
+
+	cmd, sid, _ := readFrame()
+	if cmd != muxCmdSYN {
+		t.Fatalf("first frame cmd = %d, want SYN", cmd)
+	}
+	cmd, gotSID, data := readFrame()
+	if cmd != muxCmdPSH || gotSID != sid || string(data) != "ping" {
+		t.Fatalf("frame = (cmd %d, sid %d, %q), want (PSH, %d, %q)", cmd, gotSID, data, sid, "ping")
+	}
+
+	reply := make([]byte, muxHeaderSize+4)
+	reply[0] = muxVersion
+	reply[1] = muxCmdPSH
+	binary.LittleEndian.PutUint16(reply[2:], 4)
+	binary.LittleEndian.PutUint32(reply[4:], sid)
+	copy(reply[muxHeaderSize:], "pong")
+	sendOutputStreamData(ctx, t, agent, 2, PayloadTypeOutput, reply)
+
+	got := make([]byte, 4)
+	if _, err := io.ReadFull(local, got); err != nil {
+		t.Fatalf("read from forwarded port: %v", err)
+	}
+	if string(got) != "pong" {
+		t.Errorf("forwarded reply = %q, want %q", got, "pong")
+	}
+
+	stopRun()
+	if err := <-runErr; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	select {
+	case <-terminated:
+	default:
+		t.Error("Terminate was not called after Run returned")
+	}
+}
@@ -50,6 +50,17 @@ const (
 	PayloadTypeExitCode             PayloadType = 12
 )
 
+// PayloadFlag は PayloadTypeFlag メッセージのペイロード (big endian の uint32) に設定する値。
+// ポートフォワーディングセッションで接続の切断やセッション終了を agent へ通知する。
+type PayloadFlag uint32
+
+// AWS session-manager-plugin の message パッケージが定義するフラグ値。
+const (
+	PayloadFlagDisconnectToPort   PayloadFlag = 1
+	PayloadFlagTerminateSession   PayloadFlag = 2
+	PayloadFlagConnectToPortError PayloadFlag = 3
+)
+
 // AgentMessage のフィールド長 (バイト数)。フィールド順序はワイヤフォーマットの並びと一致する。
 const (
 	headerLengthFieldLen   = 4
@@ -258,6 +269,30 @@ type SessionTypeRequest struct {
 	Properties  any    `json:"Properties"`
 }
 
+// KMSEncryptionRequest は ActionTypeKMSEncryption の ActionParameters を表す。
+type KMSEncryptionRequest struct {
+	KMSKeyID string `json:"KMSKeyId"`
+}
+
+// KMSEncryptionResponse は ActionTypeKMSEncryption の ActionResult を表す。
+// KMSCipherTextKey は GenerateDataKey の CiphertextBlob、KMSCipherTextHash はその SHA-256 ダイジェスト。
+type KMSEncryptionResponse struct {
+	KMSCipherTextKey  []byte `json:"KMSCipherTextKey"`
+	KMSCipherTextHash []byte `json:"KMSCipherTextHash"`
+}
+
+// EncryptionChallengeRequest は agent から送られる暗号化チャレンジ (PayloadType: EncChallengeRequest) を表す。
+// Challenge は agent→client 方向の鍵で暗号化されている。
+type EncryptionChallengeRequest struct {
+	Challenge []byte `json:"Challenge"`
+}
+
+// EncryptionChallengeResponse は暗号化チャレンジへの応答 (PayloadType: EncChallengeResponse) を表す。
+// Challenge は復号したチャレンジを client→agent 方向の鍵で暗号化し直したもの。
+type EncryptionChallengeResponse struct {
+	Challenge []byte `json:"Challenge"`
+}
+
 // HandshakeCompletePayload はハンドシェイク完了通知 (PayloadType: HandshakeComplete) を表す。
 type HandshakeCompletePayload struct {
 	HandshakeTimeToComplete int64  `json:"HandshakeTimeToComplete"` // ナノ秒単位 (agent 側の time.Duration をそのまま JSON 化した値)
@@ -272,6 +307,12 @@ var shellSessionTypes = map[string]bool{
 	"NonInteractiveCommands": true,
 }
 
+// portSessionTypes はポートフォワーディング用データチャネルで許容するセッション種別。
+// AWS-StartPortForwardingSession(ToRemoteHost) ドキュメントは SessionType: Port を要求する。
+var portSessionTypes = map[string]bool{
+	"Port": true,
+}
+
 // OpenDataChannelInput はデータチャネル接続確立時にテキストメッセージとして送信する
 // ハンドシェイクペイロード (JSON) を表す。
 type OpenDataChannelInput struct {
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionMetadata は Registry に登録するセッションの識別情報。
+type SessionMetadata struct {
+	// Kind はセッション種別 (ec2-session / ecs-exec)。
+	Kind    string
+	Profile string
+	Region  string
+	Target  string
+}
+
+// SessionInfo は稼働中セッションの一覧表示用スナップショット。
+type SessionInfo struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	Profile      string    `json:"profile"`
+	Region       string    `json:"region"`
+	Target       string    `json:"target"`
+	StartedAt    time.Time `json:"started_at"`
+	LastActivity time.Time `json:"last_activity"`
+	LastInput    time.Time `json:"last_input"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+	// Attached はブラウザが接続中かどうか。false の場合は再接続 (Attach) 待ち。
+	Attached bool `json:"attached"`
+}
+
+type registryEntry struct {
+	meta      SessionMetadata
+	startedAt time.Time
+	bridge    *Bridge
+}
+
+// Registry は稼働中のブリッジを SSM の SessionID で管理する。API サーバ全体で 1 つ持ち、
+// 各セッションのハンドラが Run の前に Add し、終了時に戻り値の関数で登録を解除する。
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+	now     func() time.Time
+}
+
+// NewRegistry は空の Registry を返す。
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]*registryEntry{}, now: time.Now}
+}
+
+// Add は id のセッションを登録し、登録解除用の関数を返す。
+func (r *Registry) Add(id string, meta SessionMetadata, bridge *Bridge) (remove func()) {
+	entry := &registryEntry{meta: meta, startedAt: r.now(), bridge: bridge}
+	r.mu.Lock()
+	r.entries[id] = entry
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// 同じ ID で再登録された後に古い登録解除が呼ばれても、新しい登録を消さない。
+		if r.entries[id] == entry {
+			delete(r.entries, id)
+		}
+	}
+}
+
+// List は稼働中のセッションを開始日時の降順で返す。
+func (r *Registry) List() []SessionInfo {
+	r.mu.Lock()
+	infos := make([]SessionInfo, 0, len(r.entries))
+	for id, e := range r.entries {
+		stats := e.bridge.Stats()
+		infos = append(infos, SessionInfo{
+			ID:           id,
+			Kind:         e.meta.Kind,
+			Profile:      e.meta.Profile,
+			Region:       e.meta.Region,
+			Target:       e.meta.Target,
+			StartedAt:    e.startedAt.UTC(),
+			LastActivity: latest(e.startedAt, stats.LastActivity).UTC(),
+			LastInput:    latest(e.startedAt, stats.LastInput).UTC(),
+			BytesIn:      stats.BytesIn,
+			BytesOut:     stats.BytesOut,
+			Attached:     stats.Attached,
+		})
+	}
+	r.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].StartedAt.Equal(infos[j].StartedAt) {
+			return infos[i].StartedAt.After(infos[j].StartedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Get は id のセッションのブリッジを返す。ブラウザの再接続 (Bridge.Attach) に使う。
+func (r *Registry) Get(id string) (*Bridge, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return e.bridge, true
+}
+
+// Stop は id のセッションのブリッジを終了させる。ブリッジの後始末で SSM セッションも Terminate される。
+// 登録されていない場合は false を返す。
+func (r *Registry) Stop(id, reason string) bool {
+	r.mu.Lock()
+	e, ok := r.entries[id]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	e.bridge.Stop(reason)
+	return true
+}
+
+// latest は a と b のうち新しい方を返す (Run 開始前の Stats はゼロ値のため開始時刻で補う)。
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
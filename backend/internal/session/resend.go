@@ -0,0 +1,136 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 再送制御のパラメータ。AWS 公式 session-manager-plugin (sessionmanagerplugin/session/config) と同じ値を使う。
+const (
+	// resendInterval は未 acknowledge メッセージの再送要否を確認する間隔。
+	resendInterval = 100 * time.Millisecond
+	// resendMaxAttempts は 1 メッセージあたりの再送回数の上限。超えた場合はデータチャネルを異常終了させる。
+	resendMaxAttempts = 3000
+	// defaultRoundTripTime / defaultRetransmissionTimeout は RTT 計測前の初期値。
+	defaultRoundTripTime         = 100 * time.Millisecond
+	defaultRetransmissionTimeout = 200 * time.Millisecond
+	// maxRetransmissionTimeout は再送タイムアウトの上限。
+	maxRetransmissionTimeout = time.Second
+	// clockGranularity は再送タイムアウト計算 (RFC 6298) のクロック粒度。
+	clockGranularity = 10 * time.Millisecond
+	// rttGain / rttVariationGain は RTT とその揺らぎの平滑化係数 (RFC 6298 の alpha / beta)。
+	rttGain          = 1.0 / 8
+	rttVariationGain = 1.0 / 4
+	// outgoingBufferCapacity / incomingBufferCapacity は送受信バッファに保持するメッセージ数の上限。
+	outgoingBufferCapacity = 10000
+	incomingBufferCapacity = 10000
+)
+
+// outgoingMessage は acknowledge 待ちの送信済み input_stream_data メッセージ。
+type outgoingMessage struct {
+	sequenceNumber int64
+	raw            []byte
+	lastSent       time.Time
+	attempts       int
+}
+
+// outgoingBuffer は acknowledge されていない送信メッセージを保持し、RTT から算出した
+// 再送タイムアウト (RTO) を過ぎたものを再送対象として返す (公式実装の OutgoingMessageBuffer 相当)。
+// 送信 goroutine・読み取り goroutine (acknowledge 受信)・再送 goroutine から呼ばれるため mu で保護する。
+type outgoingBuffer struct {
+	mu       sync.Mutex
+	messages []*outgoingMessage // シーケンス番号の昇順
+
+	roundTripTime          time.Duration
+	roundTripTimeVariation time.Duration
+	retransmissionTimeout  time.Duration
+}
+
+func newOutgoingBuffer() *outgoingBuffer {
+	return &outgoingBuffer{
+		roundTripTime:         defaultRoundTripTime,
+		retransmissionTimeout: defaultRetransmissionTimeout,
+	}
+}
+
+// add は送信したメッセージを acknowledge 待ちとして追加する。上限に達している場合は最も古いメッセージを破棄する。
+func (b *outgoingBuffer) add(sequenceNumber int64, raw []byte, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.messages) >= outgoingBufferCapacity {
+		slog.Warn("outgoing message buffer is full, dropping oldest message", "sequence_number", b.messages[0].sequenceNumber)
+		b.messages = b.messages[1:]
+	}
+	b.messages = append(b.messages, &outgoingMessage{sequenceNumber: sequenceNumber, raw: raw, lastSent: now})
+}
+
+// ack は acknowledge されたメッセージをバッファから取り除き、再送していないメッセージであれば
+// その往復時間で RTO を更新する (再送したメッセージは往復時間が曖昧なため計測しない: Karn のアルゴリズム)。
+func (b *outgoingBuffer) ack(sequenceNumber int64, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, m := range b.messages {
+		if m.sequenceNumber != sequenceNumber {
+			continue
+		}
+		if m.attempts == 0 {
+			b.updateRetransmissionTimeout(now.Sub(m.lastSent))
+		}
+		b.messages = append(b.messages[:i], b.messages[i+1:]...)
+		return
+	}
+}
+
+// updateRetransmissionTimeout は RFC 6298 に従って RTT・RTT の揺らぎ・RTO を更新する。
+func (b *outgoingBuffer) updateRetransmissionTimeout(sample time.Duration) {
+	diff := b.roundTripTime - sample
+	if diff < 0 {
+		diff = -diff
+	}
+	b.roundTripTimeVariation = time.Duration((1-rttVariationGain)*float64(b.roundTripTimeVariation) + rttVariationGain*float64(diff))
+	b.roundTripTime = time.Duration((1-rttGain)*float64(b.roundTripTime) + rttGain*float64(sample))
+	b.retransmissionTimeout = min(b.roundTripTime+max(clockGranularity, 4*b.roundTripTimeVariation), maxRetransmissionTimeout)
+}
+
+// due は RTO を過ぎても acknowledge されていないメッセージをシーケンス番号順に返し、再送したものとして記録する。
+// 再送回数が上限を超えたメッセージがある場合はエラーを返す。
+func (b *outgoingBuffer) due(now time.Time) ([][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var raws [][]byte
+	for _, m := range b.messages {
+		if now.Sub(m.lastSent) < b.retransmissionTimeout {
+			continue
+		}
+		if m.attempts >= resendMaxAttempts {
+			return nil, fmt.Errorf("message %d was not acknowledged after %d resends", m.sequenceNumber, m.attempts)
+		}
+		m.attempts++
+		m.lastSent = now
+		raws = append(raws, m.raw)
+	}
+	return raws, nil
+}
+
+// pending は acknowledge 待ちの全メッセージをシーケンス番号順に返し、再送したものとして記録する。
+// 再接続直後に、旧接続で届いていない可能性のあるメッセージを RTO を待たずに送り直すために使う。
+func (b *outgoingBuffer) pending(now time.Time) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	raws := make([][]byte, 0, len(b.messages))
+	for _, m := range b.messages {
+		m.attempts++
+		m.lastSent = now
+		raws = append(raws, m.raw)
+	}
+	return raws
+}
+
+// len は acknowledge 待ちのメッセージ数を返す。
+func (b *outgoingBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.messages)
+}
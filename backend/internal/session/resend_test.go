@@ -0,0 +1,50 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutgoingBufferRetransmissionTimeout(t *testing.T) {
+	b := newOutgoingBuffer()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	b.add(0, []byte("a"), start)
+	b.add(1, []byte("b"), start)
+
+	if raws, err := b.due(start.Add(100 * time.Millisecond)); err != nil || len(raws) != 0 {
+		t.Fatalf("due() before RTO = %q, %v, want none", raws, err)
+	}
+	raws, err := b.due(start.Add(250 * time.Millisecond))
+	if err != nil || len(raws) != 2 || string(raws[0]) != "a" || string(raws[1]) != "b" {
+		t.Fatalf("due() after RTO = %q, %v, want a, b in order", raws, err)
+	}
+
+	// 再送したメッセージの acknowledge は RTT の計測に使わない。
+	b.ack(0, start.Add(300*time.Millisecond))
+	if b.retransmissionTimeout != defaultRetransmissionTimeout {
+		t.Errorf("RTO after ack of resent message = %v, want unchanged %v", b.retransmissionTimeout, defaultRetransmissionTimeout)
+	}
+
+	// RTT 50ms のサンプル: RTT = 100*7/8 + 50/8 = 93.75ms、揺らぎ = 50/4 = 12.5ms、RTO = 93.75 + 4*12.5 = 143.75ms。
+	sent := start.Add(time.Second)
+	b.add(2, []byte("c"), sent)
+	b.ack(2, sent.Add(50*time.Millisecond))
+	if want := 143750 * time.Microsecond; b.retransmissionTimeout != want {
+		t.Errorf("RTO = %v, want %v", b.retransmissionTimeout, want)
+	}
+	if got := b.len(); got != 1 {
+		t.Errorf("len() = %d, want 1 (only seq 1 unacknowledged)", got)
+	}
+
+	now := sent
+	// seq 1 は上で 1 回再送済み。
+	for range resendMaxAttempts - 1 {
+		now = now.Add(maxRetransmissionTimeout)
+		if _, err := b.due(now); err != nil {
+			t.Fatalf("due() error before reaching max attempts: %v", err)
+		}
+	}
+	if _, err := b.due(now.Add(maxRetransmissionTimeout)); err == nil {
+		t.Error("due() error = nil, want error after max resend attempts")
+	}
+}
@@ -0,0 +1,15 @@
+package session
+
+import "strings"
+
+// shellQuote は s を sh のシングルクォートで囲む。
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellCommand は script を /bin/sh -c の 1 つの引数として実行するコマンドを返す。ECS Exec (ExecuteCommand) は
+// コマンドをシェルを介さずに引数へ分割して実行するため、複数行や ; ・リダイレクトを含むスクリプトはそのままでは
+// 動かない。シェル経由で実行する AWS-StartInteractiveCommand にも同じコマンドを渡せる。
+func shellCommand(script string) string {
+	return "/bin/sh -c " + shellQuote(script)
+}
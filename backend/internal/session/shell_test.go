@@ -0,0 +1,46 @@
+package session
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// requireShellTools は sh と tools がなければテストをスキップする。
+func requireShellTools(t *testing.T, tools ...string) {
+	t.Helper()
+	for _, tool := range append([]string{"sh"}, tools...) {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not available: %v", tool, err)
+		}
+	}
+}
+
+// shellScript は shellCommand が返したコマンドを sh で引数に分割し、/bin/sh -c に渡すスクリプトを返す。
+func shellScript(t *testing.T, command string) string {
+	t.Helper()
+	requireShellTools(t)
+	out, err := exec.Command("sh", "-c", `printf '%s\0' `+command).Output()
+	if err != nil {
+		t.Fatalf("split %q: %v", command, err)
+	}
+	args := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(args) != 3 || !slices.Equal(args[:2], []string{"/bin/sh", "-c"}) {
+		t.Fatalf("command %q = %q, want /bin/sh -c and one script argument", command, args)
+	}
+	return args[2]
+}
+
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("it's here"), `'it'\''s here'`; got != want {
+		t.Errorf("shellQuote() = %q, want %q", got, want)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	script := "m='it'\"'\"'s here'\necho \"$m\" > /dev/null; exit 3"
+	if got := shellScript(t, shellCommand(script)); got != script {
+		t.Errorf("script = %q, want %q", got, script)
+	}
+}
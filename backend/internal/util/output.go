@@ -0,0 +1,141 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Structured output formats. Unlike tab and csv, these emit whole values
+// (using their JSON field names) instead of the ToRow columns.
+const (
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatNDJSON = "ndjson"
+)
+
+// IsStructuredFormat reports whether format is json, yaml or ndjson.
+func IsStructuredFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatYAML, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+// WriteStructured writes v to w in the given structured format.
+// json writes an indented document, yaml a block-style document with the
+// same keys and key order as json, and ndjson one compact line per slice
+// element (or a single line when v is not a slice). A nil slice is written
+// as an empty list.
+func WriteStructured(w io.Writer, format string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = reflect.MakeSlice(rv.Type(), 0, 0).Interface()
+	}
+
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		if rv.Kind() != reflect.Slice {
+			return enc.Encode(v)
+		}
+		for i := range rv.Len() {
+			if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		return writeYAML(w, v)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// writeYAML encodes v via JSON so that the json tags (rather than yaml tags)
+// name the fields. JSON is valid YAML, so the JSON document is decoded into a
+// yaml.Node, which keeps the key order, and re-encoded in block style.
+func writeYAML(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &node); err != nil {
+		return fmt.Errorf("convert to yaml: %w", err)
+	}
+	resetYAMLStyle(&node)
+
+	ye := yaml.NewEncoder(w)
+	ye.SetIndent(2)
+	if err := ye.Encode(&node); err != nil {
+		return err
+	}
+	return ye.Close()
+}
+
+// resetYAMLStyle clears the flow and quoting styles inherited from the JSON
+// source so that the encoder picks the plain block style where possible.
+func resetYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetYAMLStyle(c)
+	}
+}
+
+// Records converts table rows into values keyed by column header, for
+// structured output of tables that are not backed by a struct.
+func Records(columns []Column, rows [][]string) []Record {
+	records := make([]Record, len(rows))
+	for i, row := range rows {
+		records[i] = Record{columns: columns, values: row}
+	}
+	return records
+}
+
+// Record is a table row that marshals to a JSON object whose keys are the
+// column headers, in column order.
+type Record struct {
+	columns []Column
+	values  []string
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(col.Header)
+		if err != nil {
+			return nil, err
+		}
+		var value string
+		if i < len(r.values) {
+			value = r.values[i]
+		}
+		val, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+type outputItem struct {
+	Name   string            `json:"name"`
+	Count  int               `json:"count"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Hidden string            `json:"-"`
+}
+
+func TestWriteStructured(t *testing.T) {
+	items := []outputItem{
+		{Name: "web", Count: 2, Tags: map[string]string{"Env": "prod"}, Hidden: "x"},
+		{Name: "true", Count: 0},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		v      any
+		want   string
+	}{
+		{
+			name:   "json",
+			format: FormatJSON,
+			v:      items,
+			want: `[
+  {
+    "name": "web",
+    "count": 2,
+    "tags": {
+      "Env": "prod"
+    }
+  },
+  {
+    "name": "true",
+    "count": 0
+  }
+]
+`,
+		},
+		{
+			name:   "ndjson",
+			format: FormatNDJSON,
+			v:      items,
+			want: `{"name":"web","count":2,"tags":{"Env":"prod"}}
+{"name":"true","count":0}
+`,
+		},
+		{
+			name:   "yaml keeps json keys and quotes ambiguous strings",
+			format: FormatYAML,
+			v:      items,
+			want: `- name: web
+  count: 2
+  tags:
+    Env: prod
+- name: "true"
+  count: 0
+`,
+		},
+		{
+			name:   "ndjson single value",
+			format: FormatNDJSON,
+			v:      items[1],
+			want:   `{"name":"true","count":0}` + "\n",
+		},
+		{
+			name:   "nil slice is an empty list",
+			format: FormatJSON,
+			v:      []outputItem(nil),
+			want:   "[]\n",
+		},
+		{
+			name:   "records keep column order",
+			format: FormatNDJSON,
+			v:      Records([]Column{{Header: "Zone"}, {Header: "Count"}}, [][]string{{"a", "1"}}),
+			want:   `{"Zone":"a","Count":"1"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteStructured(&buf, tt.format, tt.v); err != nil {
+				t.Fatalf("WriteStructured: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output mismatch\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStructuredUnsupportedFormat(t *testing.T) {
+	if err := WriteStructured(&bytes.Buffer{}, "xml", []outputItem{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestIsStructuredFormat(t *testing.T) {
+	for format, want := range map[string]bool{
+		"json": true, "yaml": true, "ndjson": true, "tab": false, "csv": false, "": false,
+	} {
+		if got := IsStructuredFormat(format); got != want {
+			t.Errorf("IsStructuredFormat(%q) = %v, want %v", format, got, want)
+		}
+	}
+}
@@ -14,6 +14,7 @@ export interface EC2Raw {
   private_ip: string;
   public_ip: string;
   vpc_id: string;
+  platform: string;
   tags: Record<string, string>;
   cost_monthly: number;
   launch_time: string;
@@ -110,7 +111,7 @@ export interface DynamoRaw {
   size_bytes: number;
   gsi_count: number;
   tags: Record<string, string>;
-  cost_monthly: number;
+  cost_monthly: number | null;
 }
 
 export interface DynamoRow {
@@ -236,7 +237,7 @@ export interface LambdaRaw {
   handler: string;
   role: string;
   tags: Record<string, string>;
-  cost_monthly: number;
+  cost_monthly: number | null;
 }
 
 export interface LambdaRow {
@@ -428,7 +429,7 @@ export interface S3Raw {
   created_at: string;
   public: boolean;
   encryption: string;
-  cost_monthly: number;
+  cost_monthly: number | null;
 }
 
 export interface S3Row {
@@ -471,6 +472,7 @@ export interface IAMRaw {
   kind: string;
   mfa_enabled: boolean;
   last_activity: string;
+  create_date: string;
   groups: string[] | null;
   policies: string[] | null;
 }
@@ -684,7 +686,7 @@ export interface SQSRaw {
   in_flight: number;
   retention_days: number;
   tags: Record<string, string>;
-  cost_monthly: number;
+  cost_monthly: number | null;
 }
 
 export interface SQSRow {